@@ -16,8 +16,10 @@ type Handler struct {
 func NewHandler() *Handler {
 
 	cmds := map[string]Command{
-		"server":  &ServerCommand{},
-		"migrate": &MigrateCommand{},
+		"server":    &ServerCommand{},
+		"migrate":   &MigrateCommand{},
+		"processor": &ProcessorCommand{},
+		"simulate":  &SimulateCommand{},
 	}
 
 	return &Handler{
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/config"
+	"github.com/snowlynxsoftware/parallax-game/server"
+)
+
+type ProcessorCommand struct {
+}
+
+func (s *ProcessorCommand) Execute() error {
+	appConfig := config.NewAppConfig()
+
+	server := server.NewAppServer(appConfig)
+	server.RunExpeditionProcessor()
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snowlynxsoftware/parallax-game/server/simulation"
+)
+
+// SimulateCommand runs expeditions for a scenario file against in-memory repositories
+// and prints the loot they brought back. It doesn't need a database or app config.
+type SimulateCommand struct {
+}
+
+func (s *SimulateCommand) Execute() error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	scenarioPath := flags.String("scenario", "", "path to a scenario JSON file (required)")
+	runs := flags.Int("runs", 10000, "number of expeditions to simulate")
+	seed := flags.Int64("seed", 1, "seed for the loot rolls, the same seed gives the same report")
+	format := flags.String("format", "table", "output format: table or json")
+	if err := flags.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if *scenarioPath == "" {
+		return errors.New("-scenario is required")
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown format %q, expected table or json", *format)
+	}
+
+	scenario, err := simulation.LoadScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+	report, err := simulation.NewSimulator(scenario, *seed).Run(*runs)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return report.WriteTable(os.Stdout)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -14,6 +15,7 @@ type IAppConfig interface {
 	GetDBConnectionString() string
 	GetAuthHashPepper() string
 	GetJWTSecretKey() string
+	GetJWTPreviousSecretKeys() []string
 	GetMJAPIKeyPublic() string
 	GetMJAPIKeyPrivate() string
 	GetCorsAllowedOrigin() string
@@ -28,6 +30,7 @@ type AppConfig struct {
 	dBConnectionString string
 	authHashPepper     string
 	jwtSecretKey       string
+	jwtPreviousKeys    []string
 	mjAPIKeyPublic     string
 	mjAPIKeyPrivate    string
 	corsAllowedOrigin  string
@@ -47,6 +50,7 @@ func NewAppConfig() IAppConfig {
 	appConfig.dBConnectionString = ""
 	appConfig.authHashPepper = ""
 	appConfig.jwtSecretKey = ""
+	appConfig.jwtPreviousKeys = []string{}
 	appConfig.mjAPIKeyPublic = ""
 	appConfig.mjAPIKeyPrivate = ""
 	appConfig.corsAllowedOrigin = "http://localhost:3000"
@@ -73,10 +77,17 @@ func NewAppConfig() IAppConfig {
 	if cookieDomain := os.Getenv("COOKIE_DOMAIN"); cookieDomain != "" {
 		appConfig.cookieDomain = cookieDomain
 	}
+	// Keys being rotated out. Tokens they signed are accepted until they expire.
+	for _, key := range strings.Split(os.Getenv("JWT_PREVIOUS_SECRET_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			appConfig.jwtPreviousKeys = append(appConfig.jwtPreviousKeys, key)
+		}
+	}
 
 	errorList := ""
 
-	if appConfig.dBConnectionString == "" {
+	// Running locally without a database uses the in-memory store
+	if appConfig.dBConnectionString == "" && appConfig.cloudEnv != "local" {
 		errorList += "[DB_CONNECTION_STRING]\n"
 	}
 
@@ -132,6 +143,10 @@ func (a *AppConfig) GetJWTSecretKey() string {
 	return a.jwtSecretKey
 }
 
+func (a *AppConfig) GetJWTPreviousSecretKeys() []string {
+	return a.jwtPreviousKeys
+}
+
 func (a *AppConfig) GetMJAPIKeyPublic() string {
 	return a.mjAPIKeyPublic
 }
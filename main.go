@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/snowlynxsoftware/parallax-game/cmd"
 )
@@ -13,5 +14,6 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("done")
+	// stderr so commands that print to stdout, like simulate -format json, can be piped
+	fmt.Fprintln(os.Stderr, "done")
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the tests run the real main() in a child process, so they see
+// exactly what the binary writes to stdout
+func TestMain(m *testing.M) {
+	if os.Getenv("PARALLAX_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runMain(t *testing.T, args ...string) []byte {
+	t.Helper()
+	command := exec.Command(os.Args[0], args...)
+	command.Env = append(os.Environ(), "PARALLAX_RUN_MAIN=1")
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	require.NoError(t, command.Run(), stderr.String())
+	return stdout.Bytes()
+}
+
+func TestSimulate_JSONOutputIsOnlyJSON(t *testing.T) {
+	stdout := runMain(t, "simulate", "-scenario", "scripts/simulations/verdant_overgrowth.json", "-runs", "100", "-format", "json")
+
+	decoder := json.NewDecoder(bytes.NewReader(stdout))
+	var report map[string]any
+	require.NoError(t, decoder.Decode(&report))
+	assert.NotEmpty(t, report)
+
+	// Nothing may follow the report, or tools reading it will fail
+	var extra json.RawMessage
+	assert.ErrorIs(t, decoder.Decode(&extra), io.EOF)
+}
@@ -0,0 +1,16 @@
+-- ############################
+-- Parallax Expedition Power Schema
+--
+-- https://snowlynxsoftware.net 
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Team power is snapshotted when an expedition launches and compared against the
+-- rift's recommended power when loot is rolled. Under-powered teams get less loot
+-- and may partially fail.
+
+ALTER TABLE expeditions
+    ADD COLUMN effective_power INT NOT NULL DEFAULT 0 CHECK (effective_power >= 0),
+    ADD COLUMN recommended_power INT NOT NULL DEFAULT 0 CHECK (recommended_power >= 0),
+    ADD COLUMN partial_failure BOOLEAN NOT NULL DEFAULT false; -- true when the power roll failed and loot was reduced
@@ -0,0 +1,52 @@
+-- ############################
+-- Parallax Expedition Team Lock Schema
+--
+-- https://snowlynxsoftware.net 
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- A team can only be on one expedition at a time. An expedition stays active
+-- until its rewards are claimed.
+
+-- ############################
+-- STEP 1: CLAIM PROCESSED DUPLICATE EXPEDITIONS
+-- ############################
+
+-- Before this was enforced a team could be sent out several times at once.
+-- Keep one active expedition per team, the earliest one still waiting for its loot
+-- if there is one. The other duplicates whose loot was already rolled are claimed
+-- rather than archived, since that loot is in the player's inventory and
+-- expedition_loot and should stay in their history.
+UPDATE expeditions SET claimed = true, modified_at = NOW()
+WHERE processed = true AND id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY team_id ORDER BY processed, start_time, id) AS active_rank
+        FROM expeditions
+        WHERE claimed = false AND is_archived = false
+    ) ranked
+    WHERE ranked.active_rank > 1
+);
+
+-- ############################
+-- STEP 2: ARCHIVE UNPROCESSED DUPLICATE EXPEDITIONS
+-- ############################
+
+-- The duplicates left never had loot rolled, so archiving them takes nothing
+-- the player was given.
+UPDATE expeditions SET is_archived = true, modified_at = NOW()
+WHERE processed = false AND id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY team_id ORDER BY processed, start_time, id) AS active_rank
+        FROM expeditions
+        WHERE claimed = false AND is_archived = false
+    ) ranked
+    WHERE ranked.active_rank > 1
+);
+
+-- ############################
+-- STEP 3: ONE ACTIVE EXPEDITION PER TEAM
+-- ############################
+
+CREATE UNIQUE INDEX idx_expeditions_one_active_per_team ON expeditions(team_id)
+    WHERE claimed = false AND is_archived = false;
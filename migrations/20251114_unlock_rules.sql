@@ -0,0 +1,73 @@
+-- ############################
+-- Parallax Unlock Rules Schema
+--
+-- https://snowlynxsoftware.net 
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- UNLOCK RULES: Data-driven unlock requirements for rifts and teams
+
+-- ############################
+-- STEP 1: CREATE UNLOCK_RULES TABLE
+-- ############################
+
+-- target_key is the rift id for rifts and the team number for teams.
+-- rule is a condition tree, for example:
+--   {"type": "all", "conditions": [
+--     {"type": "completed_expeditions", "count": 25},
+--     {"type": "owns_rarity", "rarity": "epic", "count": 1}
+--   ]}
+-- Supported types: all, any, completed_expeditions, completed_rift, owns_rarity, owns_item
+-- Targets without a rule are always unlocked.
+CREATE TABLE unlock_rules (
+    id SERIAL PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('rift', 'team')),
+    target_key INT NOT NULL,
+    rule JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+    UNIQUE (target_type, target_key)
+);
+
+CREATE INDEX idx_unlock_rules_target_type ON unlock_rules(target_type);
+
+-- ############################
+-- STEP 2: SEED RIFT RULES (GDD 2.3 Rift Requirements)
+-- ############################
+
+-- Crimson Wastes, Frozen Expanse, Neon Sprawl: complete the Tutorial Rift
+INSERT INTO unlock_rules (target_type, target_key, rule)
+SELECT 'rift', r.id, jsonb_build_object(
+    'type', 'completed_rift',
+    'rift_id', (SELECT id FROM rifts WHERE name = 'Tutorial Rift'),
+    'count', 1
+)
+FROM rifts r
+WHERE r.name IN ('Crimson Wastes', 'Frozen Expanse', 'Neon Sprawl');
+
+-- Verdant Overgrowth: 10 total expeditions
+INSERT INTO unlock_rules (target_type, target_key, rule)
+SELECT 'rift', r.id, '{"type": "completed_expeditions", "count": 10}'::jsonb
+FROM rifts r
+WHERE r.name = 'Verdant Overgrowth';
+
+-- Void Confluence, Seat of Heaven: 25 total expeditions and at least 1 epic item
+INSERT INTO unlock_rules (target_type, target_key, rule)
+SELECT 'rift', r.id, '{"type": "all", "conditions": [{"type": "completed_expeditions", "count": 25}, {"type": "owns_rarity", "rarity": "epic", "count": 1}]}'::jsonb
+FROM rifts r
+WHERE r.name IN ('Void Confluence', 'Seat of Heaven');
+
+-- ############################
+-- STEP 3: SEED TEAM RULES (GDD 2.2 Teams)
+-- ############################
+
+INSERT INTO unlock_rules (target_type, target_key, rule) VALUES
+    ('team', 2, '{"type": "completed_expeditions", "count": 1}'),
+    ('team', 3, '{"type": "completed_expeditions", "count": 3}'),
+    ('team', 4, '{"type": "completed_expeditions", "count": 25}'),
+    ('team', 5, '{"type": "completed_expeditions", "count": 50}');
+
+-- rifts.unlock_requirement_text is no longer shown, the API generates requirement
+-- text from these rules instead
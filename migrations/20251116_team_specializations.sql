@@ -0,0 +1,18 @@
+-- ############################
+-- Parallax Team Specializations Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Teams can spend one Epic item on a permanent specialization (Tank, Scout or
+-- Scientist) that boosts them in specific worlds. See GDD Appendix B.
+
+-- ############################
+-- STEP 1: ADD SPECIALIZATION TO TEAMS
+-- ############################
+
+ALTER TABLE teams
+    ADD COLUMN specialization VARCHAR(20) NOT NULL DEFAULT 'none'
+    CHECK (specialization IN ('none', 'tank', 'scout', 'scientist'));
@@ -0,0 +1,64 @@
+-- ############################
+-- Parallax Team Upgrades Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Teams upgrade their base speed and luck by spending loot. Each recipe costs a
+-- number of items per rarity and can only raise its stat up to max_value.
+
+-- ############################
+-- STEP 1: UPGRADE RECIPES
+-- ############################
+
+CREATE TABLE upgrade_recipes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+
+    -- Which team stat the recipe raises and by how much
+    stat VARCHAR(20) NOT NULL CHECK (stat IN ('speed', 'luck')),
+    stat_increase DECIMAL(5,2) NOT NULL CHECK (stat_increase > 0),
+
+    -- The recipe can't raise the stat past this value
+    max_value DECIMAL(5,2) NOT NULL CHECK (max_value > 0),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+-- ############################
+-- STEP 2: UPGRADE RECIPE COSTS
+-- ############################
+
+CREATE TABLE upgrade_recipe_costs (
+    id SERIAL PRIMARY KEY,
+    recipe_id INT NOT NULL REFERENCES upgrade_recipes(id) ON DELETE CASCADE,
+    rarity item_rarity NOT NULL,
+    quantity INT NOT NULL CHECK (quantity >= 1),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    UNIQUE(recipe_id, rarity)
+);
+
+CREATE INDEX idx_upgrade_recipe_costs_recipe ON upgrade_recipe_costs(recipe_id);
+
+-- ############################
+-- STEP 3: SEED RECIPES (GDD: Team Upgrades)
+-- ############################
+
+INSERT INTO upgrade_recipes (name, description, stat, stat_increase, max_value) VALUES
+('Speed Upgrade', 'Streamline the team''s rift gear for faster expeditions.', 'speed', 5.00, 50.00),
+('Luck Upgrade', 'Attune the team to the rifts to find rarer loot.', 'luck', 3.00, 30.00);
+
+INSERT INTO upgrade_recipe_costs (recipe_id, rarity, quantity) VALUES
+((SELECT id FROM upgrade_recipes WHERE name = 'Speed Upgrade'), 'uncommon', 10),
+((SELECT id FROM upgrade_recipes WHERE name = 'Speed Upgrade'), 'rare', 5),
+((SELECT id FROM upgrade_recipes WHERE name = 'Luck Upgrade'), 'uncommon', 15),
+((SELECT id FROM upgrade_recipes WHERE name = 'Luck Upgrade'), 'rare', 3);
@@ -0,0 +1,28 @@
+-- ############################
+-- Parallax Expedition Status Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Expeditions can now be recalled before their timer runs out. The status column
+-- records how an expedition ended so recalled runs aren't counted as completed.
+
+-- ############################
+-- STEP 1: ADD STATUS TO EXPEDITIONS
+-- ############################
+
+ALTER TABLE expeditions
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'completed', 'recalled'));
+
+ALTER TABLE expeditions ADD COLUMN recalled_at TIMESTAMP;
+
+-- ############################
+-- STEP 2: BACKFILL EXISTING EXPEDITIONS
+-- ############################
+
+UPDATE expeditions SET status = 'completed' WHERE completed = true;
+
+CREATE INDEX idx_expeditions_user_status ON expeditions(user_id, status);
@@ -0,0 +1,36 @@
+-- ############################
+-- Parallax Team Launch Queue Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Each team can hold a queue of rift launches. When the team's current expedition
+-- is processed, its rewards are claimed automatically and the next entry launches.
+-- An entry launches repeat_count more times, or until removed when it is NULL.
+
+-- ############################
+-- STEP 1: TEAM LAUNCH QUEUE
+-- ############################
+
+CREATE TABLE team_launch_queue (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    rift_id INT NOT NULL REFERENCES rifts(id) ON DELETE CASCADE,
+
+    -- Entries launch in position order
+    position INT NOT NULL,
+
+    -- Launches left for this entry. NULL repeats until the entry is removed.
+    -- Reaches 0 only as the entry is archived.
+    repeat_count INT CHECK (repeat_count IS NULL OR repeat_count >= 0),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX idx_team_launch_queue_team ON team_launch_queue(team_id, position)
+    WHERE is_archived = false;
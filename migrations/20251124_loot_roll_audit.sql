@@ -0,0 +1,53 @@
+-- ############################
+-- Parallax Loot Roll Audit Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Loot is rolled from a random source seeded per expedition. The seed, the inputs
+-- the rolls depended on and every roll made are stored so support can replay an
+-- expedition's loot and see exactly why a player got a drop.
+
+-- ############################
+-- STEP 1: EXPEDITION SEEDS AND ROLL INPUTS
+-- ############################
+
+-- Existing expeditions get a random seed, new ones get theirs from the server at launch
+ALTER TABLE expeditions
+    ADD COLUMN loot_seed BIGINT NOT NULL DEFAULT (floor(random() * 4611686018427387903))::BIGINT;
+
+ALTER TABLE expeditions ALTER COLUMN loot_seed DROP DEFAULT;
+
+-- Team luck and loot multiplier the loot was rolled with. NULL until loot is rolled.
+ALTER TABLE expeditions
+    ADD COLUMN loot_luck DOUBLE PRECISION,
+    ADD COLUMN loot_multiplier DOUBLE PRECISION;
+
+-- ############################
+-- STEP 2: LOOT ROLL LOG
+-- ############################
+
+CREATE TABLE expedition_loot_rolls (
+    id SERIAL PRIMARY KEY,
+    expedition_id INT NOT NULL REFERENCES expeditions(id) ON DELETE CASCADE,
+
+    -- Order the rolls were drawn from the expedition's random source
+    roll_order INT NOT NULL,
+
+    -- failure: partial failure roll (0-1) against the failure chance
+    -- rarity: drop roll (0-100) against the luck adjusted drop rate
+    -- item: index of the item picked from the rarity's item pool
+    roll_type VARCHAR(20) NOT NULL CHECK (roll_type IN ('failure', 'rarity', 'item')),
+    rarity item_rarity,
+    roll DOUBLE PRECISION NOT NULL,
+    threshold DOUBLE PRECISION,
+    loot_item_id INT REFERENCES loot_items(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    UNIQUE(expedition_id, roll_order)
+);
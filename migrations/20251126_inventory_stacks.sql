@@ -0,0 +1,45 @@
+-- ############################
+-- Parallax Inventory Stacks Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Consumables are kept as one stack per user and item. Two drops of the same
+-- consumable landing at once could each find no stack and both insert one, so
+-- stacks are now marked and made unique, and AddLoot upserts onto them.
+
+-- ############################
+-- STEP 1: STACK MARKER
+-- ############################
+
+ALTER TABLE user_inventory ADD COLUMN is_stack BOOLEAN NOT NULL DEFAULT false;
+
+-- ############################
+-- STEP 2: MERGE DUPLICATE STACKS
+-- ############################
+
+-- Fold every unarchived consumable row into the user's earliest row for that item
+WITH stacks AS (
+    SELECT ui.id,
+        FIRST_VALUE(ui.id) OVER (PARTITION BY ui.user_id, ui.loot_item_id ORDER BY ui.id) AS stack_id,
+        SUM(ui.quantity) OVER (PARTITION BY ui.user_id, ui.loot_item_id) AS total_quantity
+    FROM user_inventory ui
+    JOIN loot_items li ON li.id = ui.loot_item_id
+    WHERE li.item_type = 'consumable' AND ui.is_archived = false
+)
+UPDATE user_inventory ui
+SET quantity = CASE WHEN ui.id = stacks.stack_id THEN stacks.total_quantity ELSE ui.quantity END,
+    is_archived = ui.id <> stacks.stack_id,
+    is_stack = true,
+    modified_at = NOW()
+FROM stacks
+WHERE ui.id = stacks.id;
+
+-- ############################
+-- STEP 3: ONE STACK PER USER AND ITEM
+-- ############################
+
+CREATE UNIQUE INDEX idx_inventory_one_stack ON user_inventory(user_id, loot_item_id)
+    WHERE is_stack = true AND is_archived = false;
@@ -0,0 +1,35 @@
+-- ############################
+-- Parallax Notifications Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- A player's inbox of things that happened while they were away: expeditions
+-- ready to claim, teams and rifts unlocked, and making the top of a leaderboard.
+
+-- ############################
+-- STEP 1: NOTIFICATIONS
+-- ############################
+
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+
+    -- Set when the player first reads the notification
+    is_read BOOLEAN NOT NULL DEFAULT false,
+    read_at TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at DESC)
+    WHERE is_archived = false;
+
+CREATE INDEX idx_notifications_unread ON notifications(user_id)
+    WHERE is_read = false AND is_archived = false;
@@ -0,0 +1,47 @@
+-- ############################
+-- Parallax Echo Encounters Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- When an expedition completes there is a chance its team meets the echoes of
+-- another player's recent expedition to the same rift. Both players get bonus
+-- loot, and each encounter is recorded for the echo encounters stat.
+
+-- ############################
+-- STEP 1: ECHO ENCOUNTERS
+-- ############################
+
+CREATE TABLE echo_encounters (
+    id SERIAL PRIMARY KEY,
+
+    -- The expedition that just completed and the earlier expedition it echoed
+    expedition_id_1 INT NOT NULL REFERENCES expeditions(id) ON DELETE CASCADE,
+    expedition_id_2 INT NOT NULL REFERENCES expeditions(id) ON DELETE CASCADE,
+    user_id_1 INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id_2 INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rift_id INT NOT NULL REFERENCES rifts(id) ON DELETE CASCADE,
+    occurred_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    CONSTRAINT echo_encounters_different_users CHECK (user_id_1 <> user_id_2)
+);
+
+-- An expedition only leaves one echo, so two processors can't both pay out for it
+CREATE UNIQUE INDEX idx_echo_encounters_echoed ON echo_encounters(expedition_id_2);
+CREATE INDEX idx_echo_encounters_expedition ON echo_encounters(expedition_id_1);
+CREATE INDEX idx_echo_encounters_user_1 ON echo_encounters(user_id_1) WHERE is_archived = false;
+CREATE INDEX idx_echo_encounters_user_2 ON echo_encounters(user_id_2) WHERE is_archived = false;
+
+-- ############################
+-- STEP 2: ECHO ENCOUNTERS LEADERBOARD
+-- ############################
+
+INSERT INTO leaderboard_cache (leaderboard_type, last_synced) VALUES
+    ('echoes', '2000-01-01 00:00:00') -- Force initial sync
+ON CONFLICT (leaderboard_type) DO NOTHING;
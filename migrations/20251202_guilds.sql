@@ -0,0 +1,86 @@
+-- ############################
+-- Parallax Guilds Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Guilds group players together. Members join with the guild's invite code, can
+-- deposit loot into a shared treasury, and the guild is ranked on its own
+-- leaderboard by the combined power of its members and treasury.
+
+-- ############################
+-- STEP 1: GUILDS
+-- ############################
+
+CREATE TABLE guilds (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(30) NOT NULL,
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    -- Shared with players so they can join. Officers can regenerate it to stop old codes working.
+    invite_code VARCHAR(16) NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Names are unique among active guilds, ignoring case
+CREATE UNIQUE INDEX idx_guilds_name ON guilds(LOWER(name)) WHERE is_archived = false;
+CREATE UNIQUE INDEX idx_guilds_invite_code ON guilds(invite_code);
+
+-- ############################
+-- STEP 2: GUILD MEMBERS
+-- ############################
+
+CREATE TABLE guild_members (
+    id SERIAL PRIMARY KEY,
+    guild_id INT NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('leader', 'officer', 'member')),
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Members who leave or are kicked are archived, so they can join again later
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+-- A player is in at most one guild at a time
+CREATE UNIQUE INDEX idx_guild_members_user ON guild_members(user_id) WHERE is_archived = false;
+CREATE INDEX idx_guild_members_guild ON guild_members(guild_id) WHERE is_archived = false;
+
+-- ############################
+-- STEP 3: GUILD TREASURY
+-- ############################
+
+-- Loot deposited by members. Items are stacked per loot item, equipment included.
+CREATE TABLE guild_treasury (
+    id SERIAL PRIMARY KEY,
+    guild_id INT NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+    loot_item_id INT NOT NULL REFERENCES loot_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity >= 1),
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    CONSTRAINT unique_guild_treasury_item UNIQUE (guild_id, loot_item_id)
+);
+
+-- ############################
+-- STEP 4: GUILD LEADERBOARD
+-- ############################
+
+-- Guild leaderboards rank guilds rather than users, so a cache item belongs to
+-- exactly one of the two
+ALTER TABLE leaderboard_cache_items ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE leaderboard_cache_items ADD COLUMN guild_id INT REFERENCES guilds(id) ON DELETE CASCADE;
+ALTER TABLE leaderboard_cache_items ADD CONSTRAINT leaderboard_cache_items_owner
+    CHECK ((user_id IS NULL) <> (guild_id IS NULL));
+CREATE UNIQUE INDEX idx_leaderboard_cache_items_guild ON leaderboard_cache_items(leaderboard_type, guild_id);
+
+INSERT INTO leaderboard_cache (leaderboard_type, last_synced) VALUES
+    ('guild_power', '2000-01-01 00:00:00') -- Force initial sync
+ON CONFLICT (leaderboard_type) DO NOTHING;
@@ -0,0 +1,66 @@
+-- ############################
+-- Parallax Trades Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Players can trade equipment with each other. The proposer's items are held in
+-- escrow until the other player accepts, declines, or the trade expires. Accepted
+-- trades move ownership of the inventory rows and are kept as trade history.
+
+-- ############################
+-- STEP 1: TRADES
+-- ############################
+
+CREATE TABLE trades (
+    id SERIAL PRIMARY KEY,
+    proposer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed', 'declined', 'cancelled', 'expired')),
+    -- Pending trades past this are expired by the background processor
+    expires_at TIMESTAMP NOT NULL,
+    -- Set when the trade leaves pending
+    resolved_at TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    CONSTRAINT trades_different_players CHECK (proposer_id <> recipient_id)
+);
+
+CREATE INDEX idx_trades_proposer ON trades(proposer_id, created_at DESC);
+CREATE INDEX idx_trades_recipient ON trades(recipient_id, created_at DESC);
+CREATE INDEX idx_trades_pending_expiry ON trades(expires_at) WHERE status = 'pending';
+
+-- ############################
+-- STEP 2: TRADE ITEMS
+-- ############################
+
+-- The inventory rows on each side of a trade. The loot item is copied so history
+-- still shows what was traded after the row changes hands again.
+CREATE TABLE trade_items (
+    id SERIAL PRIMARY KEY,
+    trade_id INT NOT NULL REFERENCES trades(id) ON DELETE CASCADE,
+    inventory_id INT NOT NULL REFERENCES user_inventory(id) ON DELETE CASCADE,
+    from_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    loot_item_id INT NOT NULL REFERENCES loot_items(id) ON DELETE CASCADE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false,
+
+    CONSTRAINT unique_trade_item UNIQUE (trade_id, inventory_id)
+);
+
+-- ############################
+-- STEP 3: ESCROW
+-- ############################
+
+-- Set while an item is held for a pending trade. Escrowed items can't be equipped,
+-- spent, or offered in another trade.
+ALTER TABLE user_inventory ADD COLUMN escrow_trade_id INT REFERENCES trades(id) ON DELETE SET NULL;
+CREATE INDEX idx_inventory_escrow ON user_inventory(escrow_trade_id) WHERE escrow_trade_id IS NOT NULL;
@@ -0,0 +1,38 @@
+-- ############################
+-- Parallax User Sessions Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Every login starts a session that holds the hash of its refresh token. Refresh
+-- tokens rotate each time they're used, and access tokens name their session, so
+-- revoking a session logs that device out.
+
+-- ############################
+-- STEP 1: USER SESSIONS
+-- ############################
+
+CREATE TABLE user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- SHA-256 of the current refresh token, the token itself is never stored
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    -- The token it replaced. Seeing it again means the refresh token was stolen.
+    previous_token_hash VARCHAR(64),
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    -- Pushed back every time the session is refreshed
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Set on logout, and access tokens for the session stop working
+    revoked_at TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id, last_used_at DESC) WHERE revoked_at IS NULL;
+CREATE INDEX idx_user_sessions_previous_token ON user_sessions(previous_token_hash) WHERE previous_token_hash IS NOT NULL;
@@ -0,0 +1,27 @@
+-- ############################
+-- Parallax Used Tokens Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Login-with-email and password reset links only work once. Each token has a unique
+-- ID (its jti claim), which is recorded here when the token is used. Rows are only
+-- needed until the token would have expired anyway.
+
+-- ############################
+-- STEP 1: USED TOKENS
+-- ############################
+
+CREATE TABLE used_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- The token's sub claim, e.g. loginwithemail_token
+    purpose VARCHAR(40) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_used_tokens_expiry ON used_tokens(expires_at);
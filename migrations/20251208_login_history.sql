@@ -0,0 +1,40 @@
+-- ############################
+-- Parallax Login History Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- The initial schema created user_login_history but nothing wrote to it. Every login
+-- attempt against an account is now recorded with how it was made, where from and
+-- whether it worked, so players can spot logins they don't recognise.
+
+-- ############################
+-- STEP 1: ADD LOGIN DETAILS
+-- ############################
+
+ALTER TABLE user_login_history
+    ADD COLUMN method VARCHAR(20) NOT NULL DEFAULT 'password'
+    CHECK (method IN ('password', 'email_link'));
+
+ALTER TABLE user_login_history
+    ADD COLUMN outcome VARCHAR(20) NOT NULL DEFAULT 'success'
+    CHECK (outcome IN ('success', 'invalid_password', 'link_already_used'));
+
+ALTER TABLE user_login_history ADD COLUMN ip_address VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE user_login_history ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '';
+
+-- ############################
+-- STEP 2: EVERY ROW BELONGS TO A USER
+-- ############################
+
+DELETE FROM user_login_history WHERE user_id IS NULL;
+
+ALTER TABLE user_login_history ALTER COLUMN user_id SET NOT NULL;
+UPDATE user_login_history SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE user_login_history ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX idx_user_login_history_user ON user_login_history(user_id, created_at DESC);
+-- Looked up on every login to tell whether it's from a new device
+CREATE INDEX idx_user_login_history_device ON user_login_history(user_id, user_agent, ip_address) WHERE outcome = 'success';
@@ -0,0 +1,52 @@
+-- ############################
+-- Parallax Two-Factor Authentication Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- Users can turn on TOTP two-factor authentication (RFC 6238). Logging in then also
+-- needs a code from their authenticator app, or one of their one-time recovery codes.
+
+-- ############################
+-- STEP 1: TOTP SECRETS
+-- ############################
+
+CREATE TABLE user_two_factor (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- The TOTP secret, encrypted with a key derived from the auth pepper
+    secret VARCHAR(255) NOT NULL,
+    -- NULL until the user confirms enrollment with a code from their app
+    enabled_at TIMESTAMP,
+    -- The last time step a code was accepted for. Codes for it or earlier steps are
+    -- rejected, so an overheard code can't be replayed.
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- ############################
+-- STEP 2: RECOVERY CODES
+-- ############################
+
+CREATE TABLE user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- HMAC-SHA256 of the code, the code itself is only shown to the user once
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- ############################
+-- STEP 3: LOGIN HISTORY
+-- ############################
+
+ALTER TABLE user_login_history DROP CONSTRAINT user_login_history_outcome_check;
+ALTER TABLE user_login_history ALTER COLUMN outcome TYPE VARCHAR(32);
+ALTER TABLE user_login_history ADD CONSTRAINT user_login_history_outcome_check
+    CHECK (outcome IN ('success', 'invalid_password', 'link_already_used', 'invalid_two_factor_code'));
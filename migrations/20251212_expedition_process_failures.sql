@@ -0,0 +1,19 @@
+-- ############################
+-- Parallax Expedition Processing Failures Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- The background processor picks up due expeditions oldest first. An expedition that
+-- can't be processed, e.g. because its rift was deleted, stays due and used to come back
+-- first on every run. Recording when processing last failed lets the processor try
+-- those after everything else, so a few bad expeditions can't hold up the rest.
+
+-- ############################
+-- STEP 1: LAST PROCESSING FAILURE
+-- ############################
+
+-- NULL until processing fails
+ALTER TABLE expeditions ADD COLUMN process_failed_at TIMESTAMP;
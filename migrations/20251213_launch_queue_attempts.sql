@@ -0,0 +1,19 @@
+-- ############################
+-- Parallax Launch Queue Attempts Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- The background processor launches a batch of idle teams with queued launches each
+-- run. Teams used to be picked in id order, so a full batch of teams whose launches
+-- kept failing stopped every team after them from launching. Recording when a team's
+-- queue was last tried lets the processor try the least recently tried teams first.
+
+-- ############################
+-- STEP 1: LAST LAUNCH ATTEMPT
+-- ############################
+
+-- NULL until the processor first tries to launch the entry's team
+ALTER TABLE team_launch_queue ADD COLUMN attempted_at TIMESTAMP;
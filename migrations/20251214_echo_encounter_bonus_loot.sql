@@ -0,0 +1,31 @@
+-- ############################
+-- Parallax Echo Encounter Bonus Loot Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- The echoed player's bonus loot from an echo encounter used to be written to the
+-- expedition_loot of their earlier expedition, which had usually been claimed
+-- already, so the loot they had claimed changed after the fact. Their bonus is
+-- now recorded against the encounter instead and their expedition is left as is.
+
+-- ############################
+-- STEP 1: ECHO ENCOUNTER BONUS LOOT
+-- ############################
+
+CREATE TABLE echo_encounter_loot (
+    id SERIAL PRIMARY KEY,
+    echo_encounter_id INT NOT NULL REFERENCES echo_encounters(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    loot_item_id INT NOT NULL REFERENCES loot_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 1,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    modified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_archived BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX idx_echo_encounter_loot_encounter ON echo_encounter_loot(echo_encounter_id);
+CREATE INDEX idx_echo_encounter_loot_user ON echo_encounter_loot(user_id);
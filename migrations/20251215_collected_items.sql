@@ -0,0 +1,45 @@
+-- ############################
+-- Parallax Collected Items Schema
+--
+-- https://snowlynxsoftware.net
+--
+-- Copyright 2025. Snow Lynx Software, LLC. All Rights Reserved.
+-- ############################
+
+-- The collection log counted an item as collected if the player had an inventory
+-- row for it, used up or not. Trading an item away moves its row to the other
+-- player, so the player who found it lost the credit. Each item a player gets is
+-- now recorded once when it reaches their inventory, and the record is kept no
+-- matter where the item goes afterwards.
+
+-- ############################
+-- STEP 1: COLLECTED ITEMS
+-- ############################
+
+CREATE TABLE user_collected_items (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    loot_item_id INT NOT NULL REFERENCES loot_items(id) ON DELETE CASCADE,
+    -- When the player first got the item
+    collected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, loot_item_id)
+);
+
+-- ############################
+-- STEP 2: BACKFILL
+-- ############################
+
+-- Every item a player has an inventory row for, including used up and deposited ones
+INSERT INTO user_collected_items (user_id, loot_item_id, collected_at)
+SELECT user_id, loot_item_id, MIN(acquired_at)
+FROM user_inventory
+GROUP BY user_id, loot_item_id
+ON CONFLICT (user_id, loot_item_id) DO NOTHING;
+
+-- Items players gave away in completed trades
+INSERT INTO user_collected_items (user_id, loot_item_id, collected_at)
+SELECT ti.from_user_id, ti.loot_item_id, MIN(ti.created_at)
+FROM trade_items ti
+JOIN trades t ON t.id = ti.trade_id AND t.status = 'completed'
+GROUP BY ti.from_user_id, ti.loot_item_id
+ON CONFLICT (user_id, loot_item_id) DO NOTHING;
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-chi/chi/v5"
 	mid "github.com/go-chi/chi/v5/middleware"
@@ -12,6 +16,7 @@ import (
 	"github.com/snowlynxsoftware/parallax-game/server/controllers"
 	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/memory"
 	"github.com/snowlynxsoftware/parallax-game/server/middleware"
 	"github.com/snowlynxsoftware/parallax-game/server/services"
 	"github.com/snowlynxsoftware/parallax-game/server/util"
@@ -51,39 +56,55 @@ func (s *AppServer) Start() {
 	util.SetupZeroLogger(s.appConfig.IsDebugMode())
 
 	// Connect to DB
-	s.dB = database.NewAppDataSource()
-	s.dB.Connect(s.appConfig.GetDBConnectionString())
-
-	// Configure Repositories
-	userRepository := repositories.NewUserRepository(s.dB)
-	featureFlagRepository := repositories.NewFeatureFlagRepository(s.dB)
+	repos := s.connectRepositories()
+	userRepository := repos.userRepository
+	userSessionRepository := repos.userSessionRepository
+	featureFlagRepository := repos.featureFlagRepository
 
 	// Game Repositories
-	riftRepository := repositories.NewRiftRepository(s.dB)
-	lootItemRepository := repositories.NewLootItemRepository(s.dB)
-	lootDropTableRepository := repositories.NewLootDropTableRepository(s.dB)
-	teamRepository := repositories.NewTeamRepository(s.dB)
-	userInventoryRepository := repositories.NewUserInventoryRepository(s.dB)
-	expeditionRepository := repositories.NewExpeditionRepository(s.dB)
-	expeditionLootRepository := repositories.NewExpeditionLootRepository(s.dB)
-	leaderboardRepository := repositories.NewLeaderboardRepository(s.dB)
+	riftRepository := repos.riftRepository
+	lootItemRepository := repos.lootItemRepository
+	lootDropTableRepository := repos.lootDropTableRepository
+	teamRepository := repos.teamRepository
+	userInventoryRepository := repos.userInventoryRepository
+	expeditionRepository := repos.expeditionRepository
+	expeditionLootRepository := repos.expeditionLootRepository
+	leaderboardRepository := repos.leaderboardRepository
+	unlockRuleRepository := repos.unlockRuleRepository
+	upgradeRecipeRepository := repos.upgradeRecipeRepository
+	launchQueueRepository := repos.launchQueueRepository
+	notificationRepository := repos.notificationRepository
+	echoEncounterRepository := repos.echoEncounterRepository
+	guildRepository := repos.guildRepository
+	tradeRepository := repos.tradeRepository
 
 	// Configure Services
 	featureFlagService := services.NewFeatureFlagService(featureFlagRepository)
 	emailService := services.NewEmailService(s.appConfig.GetMJAPIKeyPublic(), s.appConfig.GetMJAPIKeyPrivate(), services.NewEmailTemplates())
 	cryptoService := services.NewCryptoService(s.appConfig.GetAuthHashPepper())
-	tokenService := services.NewTokenService(s.appConfig.GetJWTSecretKey())
-	authService := services.NewAuthService(userRepository, teamRepository, tokenService, cryptoService, emailService, s.appConfig)
+	tokenService := services.NewTokenService(s.appConfig.GetJWTSecretKey(), s.appConfig.GetJWTPreviousSecretKeys(), repos.usedTokenRepository)
+	sessionService := services.NewSessionService(userSessionRepository, userRepository, tokenService)
+	rateLimitService := services.NewRateLimitService(services.NewMemoryRateLimitStore())
+	loginHistoryService := services.NewLoginHistoryService(repos.userLoginHistoryRepository, userRepository, emailService, s.appConfig)
+	twoFactorService := services.NewTwoFactorService(repos.userTwoFactorRepository, repos.unitOfWork, cryptoService)
+	authService := services.NewAuthService(userRepository, teamRepository, tokenService, sessionService, rateLimitService, loginHistoryService, twoFactorService, cryptoService, emailService, s.appConfig)
 	userService := services.NewUserService(userRepository)
 	templateService := services.NewTemplateService()
 	staticService := services.NewStaticService()
 
 	// Game Services
 	gameCoreService := services.NewGameCoreService(lootItemRepository)
-	riftService := services.NewRiftService(riftRepository, expeditionRepository)
-	teamService := services.NewTeamService(teamRepository, userInventoryRepository, lootItemRepository, expeditionRepository, riftRepository, gameCoreService)
+	randomService := services.NewRandomService()
+	eventHubService := services.NewEventHubService()
+	unlockRuleService := services.NewUnlockRuleService(unlockRuleRepository, expeditionRepository, userInventoryRepository, riftRepository)
+	riftService := services.NewRiftService(riftRepository, unlockRuleService)
+	teamService := services.NewTeamService(teamRepository, userInventoryRepository, lootItemRepository, expeditionRepository, riftRepository, upgradeRecipeRepository, gameCoreService, unlockRuleService, repos.unitOfWork, eventHubService)
 	inventoryService := services.NewInventoryService(userInventoryRepository, lootItemRepository, teamRepository)
-	leaderboardService := services.NewLeaderboardService(leaderboardRepository)
+	leaderboardService := services.NewLeaderboardService(leaderboardRepository, eventHubService)
+	guildService := services.NewGuildService(guildRepository, userInventoryRepository, lootItemRepository, teamRepository, repos.unitOfWork)
+	tradeService := services.NewTradeService(tradeRepository, userInventoryRepository, lootItemRepository, teamRepository, userRepository, repos.unitOfWork, eventHubService)
+	notificationService := services.NewNotificationService(notificationRepository, riftRepository, userRepository, eventHubService)
+	eventHubService.AddListener(notificationService.HandleEvent)
 	expeditionService := services.NewExpeditionService(
 		expeditionRepository,
 		expeditionLootRepository,
@@ -92,27 +113,218 @@ func (s *AppServer) Start() {
 		userInventoryRepository,
 		lootItemRepository,
 		lootDropTableRepository,
+		echoEncounterRepository,
 		gameCoreService,
+		repos.unitOfWork,
+		riftService,
+		randomService,
+		eventHubService,
 	)
+	launchQueueService := services.NewLaunchQueueService(
+		launchQueueRepository,
+		teamRepository,
+		riftRepository,
+		expeditionRepository,
+		expeditionService,
+		riftService,
+		gameCoreService,
+		repos.unitOfWork,
+	)
+
+	// Background Workers
+	expeditionProcessorService := services.NewExpeditionProcessorService(expeditionService, launchQueueService, tradeService, services.ExpeditionProcessorInterval, services.ExpeditionProcessorBatchSize)
+	go expeditionProcessorService.Run(context.Background())
 
 	// Configure Middleware
-	authMiddleware := middleware.NewAuthMiddleware(userRepository, tokenService, s.appConfig.GetSystemAPIKey())
+	authMiddleware := middleware.NewAuthMiddleware(userRepository, tokenService, sessionService, s.appConfig.GetSystemAPIKey())
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(rateLimitService)
 
 	// Configure API Controllers (behind /api prefix)
 	s.router.Mount("/api/health", controllers.NewHealthController().MapController())
-	s.router.Mount("/api/auth", controllers.NewAuthController(authMiddleware, authService, isProductionMode, s.appConfig.GetCookieDomain()).MapController())
-	s.router.Mount("/api/users", controllers.NewUserController(userService, authMiddleware).MapController())
+	s.router.Mount("/api/auth", controllers.NewAuthController(authMiddleware, rateLimitMiddleware, authService, sessionService, twoFactorService, isProductionMode, s.appConfig.GetCookieDomain()).MapController())
+	s.router.Mount("/api/users", controllers.NewUserController(userService, loginHistoryService, authMiddleware).MapController())
 
 	// Game API Controllers
-	s.router.Mount("/api/rifts", controllers.NewRiftController(riftService, authMiddleware).MapController())
-	s.router.Mount("/api/teams", controllers.NewTeamController(teamService, authMiddleware).MapController())
+	s.router.Mount("/api/rifts", controllers.NewRiftController(riftService, expeditionService, authMiddleware).MapController())
+	s.router.Mount("/api/teams", controllers.NewTeamController(teamService, launchQueueService, authMiddleware).MapController())
 	s.router.Mount("/api/inventory", controllers.NewInventoryController(inventoryService, authMiddleware).MapController())
 	s.router.Mount("/api/expeditions", controllers.NewExpeditionController(expeditionService, authMiddleware).MapController())
 	s.router.Mount("/api/leaderboards", controllers.NewLeaderboardController(leaderboardService, authMiddleware).MapController())
+	s.router.Mount("/api/guilds", controllers.NewGuildController(guildService, authMiddleware).MapController())
+	s.router.Mount("/api/trades", controllers.NewTradeController(tradeService, authMiddleware).MapController())
+	s.router.Mount("/api/events", controllers.NewEventController(eventHubService, authMiddleware, services.EventHeartbeatInterval).MapController())
+	s.router.Mount("/api/notifications", controllers.NewNotificationController(notificationService, authMiddleware).MapController())
+
+	// Admin API Controllers (system API key only)
+	s.router.Mount("/api/admin", controllers.NewAdminController(expeditionService, authMiddleware).MapController())
 
 	// Configure UI Controller (at root level)
-	s.router.Mount("/", controllers.NewUIController(templateService, staticService, authMiddleware, featureFlagService, teamService, riftService, inventoryService, leaderboardService).MapController())
+	s.router.Mount("/", controllers.NewUIController(templateService, staticService, authMiddleware, featureFlagService, teamService, riftService, inventoryService, leaderboardService, expeditionService).MapController())
 
 	util.LogInfo("Starting server on localhost:3000")
 	log.Fatal(http.ListenAndServe("0.0.0.0:3000", s.router))
 }
+
+// RunExpeditionProcessor runs only the background expedition processor (no HTTP server)
+// and blocks until the process receives SIGINT or SIGTERM.
+func (s *AppServer) RunExpeditionProcessor() {
+
+	// Setup logger
+	util.SetupZeroLogger(s.appConfig.IsDebugMode())
+
+	// Connect to DB
+	repos := s.connectRepositories()
+	riftRepository := repos.riftRepository
+	lootItemRepository := repos.lootItemRepository
+	lootDropTableRepository := repos.lootDropTableRepository
+	teamRepository := repos.teamRepository
+	userInventoryRepository := repos.userInventoryRepository
+	expeditionRepository := repos.expeditionRepository
+	expeditionLootRepository := repos.expeditionLootRepository
+	unlockRuleRepository := repos.unlockRuleRepository
+	launchQueueRepository := repos.launchQueueRepository
+	notificationRepository := repos.notificationRepository
+	echoEncounterRepository := repos.echoEncounterRepository
+	tradeRepository := repos.tradeRepository
+
+	// Configure Services
+	gameCoreService := services.NewGameCoreService(lootItemRepository)
+	randomService := services.NewRandomService()
+	// No event streams connect to this process, so completed expeditions aren't pushed,
+	// but players still find them in their notifications
+	eventHubService := services.NewEventHubService()
+	notificationService := services.NewNotificationService(notificationRepository, riftRepository, repos.userRepository, eventHubService)
+	eventHubService.AddListener(notificationService.HandleEvent)
+	tradeService := services.NewTradeService(tradeRepository, userInventoryRepository, lootItemRepository, teamRepository, repos.userRepository, repos.unitOfWork, eventHubService)
+	unlockRuleService := services.NewUnlockRuleService(unlockRuleRepository, expeditionRepository, userInventoryRepository, riftRepository)
+	riftService := services.NewRiftService(riftRepository, unlockRuleService)
+	expeditionService := services.NewExpeditionService(
+		expeditionRepository,
+		expeditionLootRepository,
+		teamRepository,
+		riftRepository,
+		userInventoryRepository,
+		lootItemRepository,
+		lootDropTableRepository,
+		echoEncounterRepository,
+		gameCoreService,
+		repos.unitOfWork,
+		riftService,
+		randomService,
+		eventHubService,
+	)
+	launchQueueService := services.NewLaunchQueueService(
+		launchQueueRepository,
+		teamRepository,
+		riftRepository,
+		expeditionRepository,
+		expeditionService,
+		riftService,
+		gameCoreService,
+		repos.unitOfWork,
+	)
+	expeditionProcessorService := services.NewExpeditionProcessorService(expeditionService, launchQueueService, tradeService, services.ExpeditionProcessorInterval, services.ExpeditionProcessorBatchSize)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	expeditionProcessorService.Run(ctx)
+}
+
+// localSeedPath is the game data loaded into the in-memory store
+const localSeedPath = "scripts/local/game_data.json"
+
+// appRepositories are the repositories the services are built on, along with the unit
+// of work they run their transactions in
+type appRepositories struct {
+	userRepository             repositories.IUserRepository
+	userSessionRepository      repositories.IUserSessionRepository
+	usedTokenRepository        repositories.IUsedTokenRepository
+	userLoginHistoryRepository repositories.IUserLoginHistoryRepository
+	userTwoFactorRepository    repositories.IUserTwoFactorRepository
+	featureFlagRepository      repositories.IFeatureFlagRepository
+	riftRepository             repositories.IRiftRepository
+	lootItemRepository         repositories.ILootItemRepository
+	lootDropTableRepository    repositories.ILootDropTableRepository
+	teamRepository             repositories.ITeamRepository
+	userInventoryRepository    repositories.IUserInventoryRepository
+	expeditionRepository       repositories.IExpeditionRepository
+	expeditionLootRepository   repositories.IExpeditionLootRepository
+	leaderboardRepository      repositories.ILeaderboardRepository
+	unlockRuleRepository       repositories.IUnlockRuleRepository
+	upgradeRecipeRepository    repositories.IUpgradeRecipeRepository
+	launchQueueRepository      repositories.ILaunchQueueRepository
+	notificationRepository     repositories.INotificationRepository
+	echoEncounterRepository    repositories.IEchoEncounterRepository
+	guildRepository            repositories.IGuildRepository
+	tradeRepository            repositories.ITradeRepository
+	unitOfWork                 database.IUnitOfWork
+}
+
+// connectRepositories connects to Postgres, or when running locally without a database
+// connection string, creates an in-memory store seeded with the game data. Nothing in
+// the in-memory store survives a restart.
+func (s *AppServer) connectRepositories() *appRepositories {
+	if s.appConfig.GetCloudEnv() == "local" && s.appConfig.GetDBConnectionString() == "" {
+		seed, err := memory.LoadSeed(localSeedPath)
+		if err != nil {
+			panic(err)
+		}
+		store := memory.NewStore()
+		store.Load(seed)
+		util.LogInfo("Using in-memory database")
+
+		return &appRepositories{
+			userRepository:             memory.NewUserRepository(store),
+			userSessionRepository:      memory.NewUserSessionRepository(store),
+			usedTokenRepository:        memory.NewUsedTokenRepository(store),
+			userLoginHistoryRepository: memory.NewUserLoginHistoryRepository(store),
+			userTwoFactorRepository:    memory.NewUserTwoFactorRepository(store),
+			featureFlagRepository:      memory.NewFeatureFlagRepository(store),
+			riftRepository:             memory.NewRiftRepository(store),
+			lootItemRepository:         memory.NewLootItemRepository(store),
+			lootDropTableRepository:    memory.NewLootDropTableRepository(store),
+			teamRepository:             memory.NewTeamRepository(store),
+			userInventoryRepository:    memory.NewUserInventoryRepository(store),
+			expeditionRepository:       memory.NewExpeditionRepository(store),
+			expeditionLootRepository:   memory.NewExpeditionLootRepository(store),
+			leaderboardRepository:      memory.NewLeaderboardRepository(store),
+			unlockRuleRepository:       memory.NewUnlockRuleRepository(store),
+			upgradeRecipeRepository:    memory.NewUpgradeRecipeRepository(store),
+			launchQueueRepository:      memory.NewLaunchQueueRepository(store),
+			notificationRepository:     memory.NewNotificationRepository(store),
+			echoEncounterRepository:    memory.NewEchoEncounterRepository(store),
+			guildRepository:            memory.NewGuildRepository(store),
+			tradeRepository:            memory.NewTradeRepository(store),
+			unitOfWork:                 memory.NewUnitOfWork(store),
+		}
+	}
+
+	s.dB = database.NewAppDataSource()
+	s.dB.Connect(s.appConfig.GetDBConnectionString())
+
+	return &appRepositories{
+		userRepository:             repositories.NewUserRepository(s.dB),
+		userSessionRepository:      repositories.NewUserSessionRepository(s.dB),
+		usedTokenRepository:        repositories.NewUsedTokenRepository(s.dB),
+		userLoginHistoryRepository: repositories.NewUserLoginHistoryRepository(s.dB),
+		userTwoFactorRepository:    repositories.NewUserTwoFactorRepository(s.dB),
+		featureFlagRepository:      repositories.NewFeatureFlagRepository(s.dB),
+		riftRepository:             repositories.NewRiftRepository(s.dB),
+		lootItemRepository:         repositories.NewLootItemRepository(s.dB),
+		lootDropTableRepository:    repositories.NewLootDropTableRepository(s.dB),
+		teamRepository:             repositories.NewTeamRepository(s.dB),
+		userInventoryRepository:    repositories.NewUserInventoryRepository(s.dB),
+		expeditionRepository:       repositories.NewExpeditionRepository(s.dB),
+		expeditionLootRepository:   repositories.NewExpeditionLootRepository(s.dB),
+		leaderboardRepository:      repositories.NewLeaderboardRepository(s.dB),
+		unlockRuleRepository:       repositories.NewUnlockRuleRepository(s.dB),
+		upgradeRecipeRepository:    repositories.NewUpgradeRecipeRepository(s.dB),
+		launchQueueRepository:      repositories.NewLaunchQueueRepository(s.dB),
+		notificationRepository:     repositories.NewNotificationRepository(s.dB),
+		echoEncounterRepository:    repositories.NewEchoEncounterRepository(s.dB),
+		guildRepository:            repositories.NewGuildRepository(s.dB),
+		tradeRepository:            repositories.NewTradeRepository(s.dB),
+		unitOfWork:                 s.dB,
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/middleware"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+// AdminController serves support tooling. Every route requires the system API key.
+type AdminController struct {
+	expeditionService services.IExpeditionService
+	authMiddleware    middleware.IAuthMiddleware
+}
+
+func NewAdminController(expeditionService services.IExpeditionService, authMiddleware middleware.IAuthMiddleware) *AdminController {
+	return &AdminController{
+		expeditionService: expeditionService,
+		authMiddleware:    authMiddleware,
+	}
+}
+
+func (c *AdminController) MapController() *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/expeditions/{expeditionId}/loot-replay", c.replayExpeditionLoot)
+	return r
+}
+
+func (c *AdminController) replayExpeditionLoot(w http.ResponseWriter, r *http.Request) {
+	err := c.authMiddleware.ValidateSystemAPIKey(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expeditionIdStr := chi.URLParam(r, "expeditionId")
+	expeditionId, err := strconv.ParseInt(expeditionIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid expedition ID", http.StatusBadRequest)
+		return
+	}
+
+	replay, err := c.expeditionService.ReplayExpeditionLoot(expeditionId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeExpeditionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(replay)
+}
@@ -2,9 +2,13 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/snowlynxsoftware/parallax-game/server/middleware"
@@ -13,44 +17,199 @@ import (
 	"github.com/snowlynxsoftware/parallax-game/server/util"
 )
 
+const (
+	// The refresh token cookie is only sent to the auth routes that use it
+	refreshCookiePath   = "/api/auth"
+	accessCookieMaxAge  = 59 * 60
+	refreshCookieMaxAge = int(services.SessionExpiry / time.Second)
+)
+
+// Rate limits for the public routes that check a password or send an email
+var (
+	loginIPRateLimit    = services.RateLimit{Burst: 20, Interval: 30 * time.Second}
+	loginEmailRateLimit = services.RateLimit{Burst: 10, Interval: 1 * time.Minute}
+	emailIPRateLimit    = services.RateLimit{Burst: 5, Interval: 1 * time.Minute}
+	emailRateLimit      = services.RateLimit{Burst: 3, Interval: 5 * time.Minute}
+)
+
 type AuthController struct {
-	authMiddleware    middleware.IAuthMiddleware
-	authService       services.IAuthService
-	shouldEnableHTTPS bool
-	cookieDomain      string
+	authMiddleware      middleware.IAuthMiddleware
+	rateLimitMiddleware middleware.IRateLimitMiddleware
+	authService         services.IAuthService
+	sessionService      services.ISessionService
+	twoFactorService    services.ITwoFactorService
+	shouldEnableHTTPS   bool
+	cookieDomain        string
 }
 
-func NewAuthController(authMiddleware middleware.IAuthMiddleware, authService services.IAuthService, shouldEnableHTTPS bool, cookieDomain string) IController {
+func NewAuthController(authMiddleware middleware.IAuthMiddleware, rateLimitMiddleware middleware.IRateLimitMiddleware, authService services.IAuthService, sessionService services.ISessionService, twoFactorService services.ITwoFactorService, shouldEnableHTTPS bool, cookieDomain string) IController {
 	return &AuthController{
-		authMiddleware:    authMiddleware,
-		authService:       authService,
-		shouldEnableHTTPS: shouldEnableHTTPS,
-		cookieDomain:      cookieDomain,
+		authMiddleware:      authMiddleware,
+		rateLimitMiddleware: rateLimitMiddleware,
+		authService:         authService,
+		sessionService:      sessionService,
+		twoFactorService:    twoFactorService,
+		shouldEnableHTTPS:   shouldEnableHTTPS,
+		cookieDomain:        cookieDomain,
 	}
 }
 
 func (c *AuthController) MapController() *chi.Mux {
 	router := chi.NewRouter()
 	// Public Routes
-	router.Post("/login", c.login)
+	router.With(c.rateLimitMiddleware.LimitByIP("login", loginIPRateLimit), c.rateLimitMiddleware.LimitByEmail("login", loginEmailRateLimit)).Post("/login", c.login)
+	router.With(c.rateLimitMiddleware.LimitByIP("login-2fa", loginIPRateLimit)).Post("/login/2fa", c.loginTwoFactor)
 	router.Post("/logout", c.logout)
+	router.Post("/refresh", c.refresh)
 	router.Post("/register", c.register)
 	router.Get("/verify", c.verify)
-	router.Post("/send-login-email", c.sendLoginEmail)
-	router.Post("/send-reset-password-email", c.sendResetPasswordEmail)
+	router.With(c.rateLimitMiddleware.LimitByIP("login-email", emailIPRateLimit), c.rateLimitMiddleware.LimitByEmail("login-email", emailRateLimit)).Post("/send-login-email", c.sendLoginEmail)
+	router.With(c.rateLimitMiddleware.LimitByIP("reset-email", emailIPRateLimit), c.rateLimitMiddleware.LimitByEmail("reset-email", emailRateLimit)).Post("/send-reset-password-email", c.sendResetPasswordEmail)
 	router.Post("/reset-password", c.resetPassword)
 	router.Get("/login-with-email", c.loginWithEmail)
 
 	// Protected Routes
 	router.Get("/token", c.tokenInfo)
+	router.Post("/logout-all", c.logoutAll)
+	router.Get("/sessions", c.getSessions)
+	router.Delete("/sessions/{sessionId}", c.revokeSession)
+	router.Get("/2fa", c.getTwoFactorStatus)
+	router.Post("/2fa/enroll", c.enrollTwoFactor)
+	router.Post("/2fa/confirm", c.confirmTwoFactor)
+	router.With(c.rateLimitMiddleware.LimitByIP("2fa-disable", loginIPRateLimit)).Post("/2fa/disable", c.disableTwoFactor)
 	return router
 }
 
 func (c *AuthController) logout(w http.ResponseWriter, r *http.Request) {
-	c.clearCookie(w, "access_token")
+	// End the session this device is logged in with. The access token may already
+	// have expired, in which case the refresh token still says which session it was.
+	if user, err := c.authMiddleware.Authorize(r); err == nil {
+		if err := c.sessionService.RevokeSession(user.Id, user.SessionID); err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+			util.LogErrorWithStackTrace(err)
+		}
+	} else if cookie, err := r.Cookie("refresh_token"); err == nil {
+		if err := c.sessionService.RevokeSessionByRefreshToken(cookie.Value); err != nil {
+			util.LogErrorWithStackTrace(err)
+		}
+	}
+
+	c.clearSessionCookies(w)
 	http.Redirect(w, r, "/welcome", http.StatusSeeOther)
 }
 
+func (c *AuthController) logoutAll(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.sessionService.RevokeAllSessions(user.Id)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	c.clearSessionCookies(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+// refresh swaps a refresh token for a new pair of tokens. Browsers send the refresh
+// token cookie, other clients can send it in the body instead.
+func (c *AuthController) refresh(w http.ResponseWriter, r *http.Request) {
+	refreshToken := ""
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		refreshToken = cookie.Value
+	} else if r.Body != nil {
+		var dto models.RefreshTokenDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err == nil {
+			refreshToken = dto.RefreshToken
+		}
+	}
+	if refreshToken == "" {
+		http.Error(w, "refresh token is required", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := c.sessionService.RefreshSession(refreshToken, c.clientInfo(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.clearSessionCookies(w)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	returnStr, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to create response", http.StatusInternalServerError)
+		return
+	}
+
+	c.setSessionCookies(w, response)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(returnStr)
+}
+
+func (c *AuthController) getSessions(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := c.sessionService.GetSessions(user.Id, user.SessionID)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (c *AuthController) revokeSession(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionIdStr := chi.URLParam(r, "sessionId")
+	sessionId, err := strconv.ParseInt(sessionIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	err = c.sessionService.RevokeSession(user.Id, sessionId)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Logging out this device also means dropping its cookies
+	if sessionId == user.SessionID {
+		c.clearSessionCookies(w)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 func (c *AuthController) login(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
@@ -59,8 +218,13 @@ func (c *AuthController) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := c.authService.Login(&authHeader)
+	response, err := c.authService.Login(&authHeader, c.clientInfo(r))
 	if err != nil {
+		var retryErr *services.RetryAfterError
+		if errors.As(err, &retryErr) {
+			middleware.WriteTooManyRequests(w, retryErr)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
@@ -73,33 +237,184 @@ func (c *AuthController) login(w http.ResponseWriter, r *http.Request) {
 
 	// log.Info().Str("Access Token: ", response.AccessToken).Msg("")
 
-	c.setCookie(w, "access_token", response.AccessToken)
+	// Users with two-factor authentication aren't logged in until they enter a code
+	if response.TwoFactorToken == "" {
+		c.setSessionCookies(w, response)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(returnStr)
 }
 
-func (c *AuthController) resetPassword(w http.ResponseWriter, r *http.Request) {
+// loginTwoFactor is the second step of logging in for users with two-factor authentication
+func (c *AuthController) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var dto models.TwoFactorLoginDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
 
-	verificationToken := r.URL.Query().Get("token")
+	if dto.TwoFactorToken == "" || dto.Code == "" {
+		http.Error(w, "two_factor_token and code are required", http.StatusBadRequest)
+		return
+	}
 
-	userId, err := c.authService.VerifyNewUser(&verificationToken)
+	response, err := c.authService.CompleteTwoFactorLogin(&dto.TwoFactorToken, dto.Code, c.clientInfo(r))
 	if err != nil {
-		util.LogErrorWithStackTrace(err)
+		var retryErr *services.RetryAfterError
+		if errors.As(err, &retryErr) {
+			middleware.WriteTooManyRequests(w, retryErr)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	returnStr, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to create response", http.StatusInternalServerError)
+		return
+	}
+
+	c.setSessionCookies(w, response)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(returnStr)
+}
+
+func (c *AuthController) getTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := c.twoFactorService.GetStatus(user.Id)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// enrollTwoFactor starts turning on two-factor authentication, returning the secret
+// for the user's authenticator app
+func (c *AuthController) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := c.twoFactorService.StartEnrollment(user.Id, user.Email)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// confirmTwoFactor turns on two-factor authentication with a code from the user's app,
+// and returns their recovery codes
+func (c *AuthController) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.TwoFactorCodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := c.twoFactorService.ConfirmEnrollment(user.Id, dto.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled), errors.Is(err, services.ErrTwoFactorNotEnrolling):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			util.LogErrorWithStackTrace(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodes)
+}
+
+func (c *AuthController) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.TwoFactorDisableDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	err = c.authService.DisableTwoFactor(user.Id, dto.Password, dto.Code)
+	if err != nil {
+		var retryErr *services.RetryAfterError
+		switch {
+		case errors.As(err, &retryErr):
+			middleware.WriteTooManyRequests(w, retryErr)
+		case errors.Is(err, services.ErrInvalidPassword), errors.Is(err, services.ErrInvalidTwoFactorCode):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			util.LogErrorWithStackTrace(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *AuthController) resetPassword(w http.ResponseWriter, r *http.Request) {
+
+	resetToken := r.URL.Query().Get("token")
+
 	var userUpdatePasswordDto models.UserUpdatePasswordDTO
-	err = json.NewDecoder(r.Body).Decode(&userUpdatePasswordDto)
+	err := json.NewDecoder(r.Body).Decode(&userUpdatePasswordDto)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	_, err = c.authService.UpdateUserPassword(userId, userUpdatePasswordDto.Password)
+	_, err = c.authService.ResetPassword(&resetToken, userUpdatePasswordDto.Password)
 	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		if errors.Is(err, services.ErrTokenNotVerified) || errors.Is(err, services.ErrTokenAlreadyUsed) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -188,23 +503,23 @@ func (c *AuthController) register(w http.ResponseWriter, r *http.Request) {
 
 func (c *AuthController) loginWithEmail(w http.ResponseWriter, r *http.Request) {
 
-	verificationToken := r.URL.Query().Get("token")
+	loginToken := r.URL.Query().Get("token")
 
-	userId, err := c.authService.VerifyNewUser(&verificationToken)
+	response, err := c.authService.LoginWithEmailLink(&loginToken, c.clientInfo(r))
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	response, err := c.authService.LoginWithEmailLink(userId)
-	if err != nil {
-		util.LogErrorWithStackTrace(err)
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	// The login page asks for the code. The token goes in the fragment so it isn't sent
+	// back to the server or logged along with the URL.
+	if response.TwoFactorToken != "" {
+		http.Redirect(w, r, "/login#two_factor_token="+url.QueryEscape(response.TwoFactorToken), http.StatusSeeOther)
 		return
 	}
 
-	c.setCookie(w, "access_token", response.AccessToken)
+	c.setSessionCookies(w, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -359,25 +674,43 @@ func (c *AuthController) tokenInfo(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (c *AuthController) setCookie(w http.ResponseWriter, name string, value string) {
+// clientInfo describes the device a request came from
+func (c *AuthController) clientInfo(r *http.Request) *models.ClientInfoDTO {
+	return &models.ClientInfoDTO{
+		UserAgent: r.UserAgent(),
+		IPAddress: middleware.ClientIP(r),
+	}
+}
+
+func (c *AuthController) setSessionCookies(w http.ResponseWriter, tokens *models.UserLoginResponseDTO) {
+	c.setCookie(w, "access_token", tokens.AccessToken, "/", accessCookieMaxAge)
+	c.setCookie(w, "refresh_token", tokens.RefreshToken, refreshCookiePath, refreshCookieMaxAge)
+}
+
+func (c *AuthController) clearSessionCookies(w http.ResponseWriter) {
+	c.clearCookie(w, "access_token", "/")
+	c.clearCookie(w, "refresh_token", refreshCookiePath)
+}
+
+func (c *AuthController) setCookie(w http.ResponseWriter, name string, value string, path string, maxAge int) {
 	http.SetCookie(w, &http.Cookie{
 		Domain:   c.cookieDomain,
 		Name:     name,
 		Value:    value,
-		Path:     "/",
+		Path:     path,
 		HttpOnly: true,
 		Secure:   c.shouldEnableHTTPS, // Set to true if using HTTPS
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   59 * 60,
+		MaxAge:   maxAge,
 	})
 }
 
-func (c *AuthController) clearCookie(w http.ResponseWriter, name string) {
+func (c *AuthController) clearCookie(w http.ResponseWriter, name string, path string) {
 	http.SetCookie(w, &http.Cookie{
 		Domain:   c.cookieDomain,
 		Name:     name,
 		Value:    "",
-		Path:     "/",
+		Path:     path,
 		HttpOnly: true,
 		Secure:   c.shouldEnableHTTPS, // Set to true if using HTTPS
 		SameSite: http.SameSiteLaxMode,
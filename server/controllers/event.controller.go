@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/middleware"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+// eventRetryMilliseconds is how long the browser waits before reconnecting a dropped stream
+const eventRetryMilliseconds = 3000
+
+type EventController struct {
+	eventHubService   services.IEventHubService
+	authMiddleware    middleware.IAuthMiddleware
+	heartbeatInterval time.Duration
+}
+
+func NewEventController(eventHubService services.IEventHubService, authMiddleware middleware.IAuthMiddleware, heartbeatInterval time.Duration) *EventController {
+	return &EventController{
+		eventHubService:   eventHubService,
+		authMiddleware:    authMiddleware,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+func (c *EventController) MapController() *chi.Mux {
+	r := chi.NewRouter()
+
+	// API routes
+	r.Get("/", c.streamEvents)
+
+	return r
+}
+
+// streamEvents holds the request open and writes the player's game events as
+// server-sent events until the client disconnects. Browsers reconnect on their own and
+// send Last-Event-ID, so any events missed in between are replayed first.
+func (c *EventController) streamEvents(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventId int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		lastEventId, err = strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	subscription := c.eventHubService.Subscribe(int64(user.Id), lastEventId)
+	defer c.eventHubService.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetryMilliseconds)
+	for _, event := range subscription.Missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(c.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Dropped for falling behind, the client reconnects and replays
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a single event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, event *models.GameEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		util.LogError(err)
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -30,6 +31,7 @@ func (c *ExpeditionController) MapController() *chi.Mux {
 	r.Get("/active", c.getActiveExpeditions)
 	r.Get("/history", c.getExpeditionHistory)
 	r.Post("/{expeditionId}/claim", c.claimRewards)
+	r.Post("/{expeditionId}/recall", c.recallExpedition)
 	return r
 }
 
@@ -50,7 +52,7 @@ func (c *ExpeditionController) startExpedition(w http.ResponseWriter, r *http.Re
 	expedition, err := c.expeditionService.StartExpedition(int64(user.Id), dto.TeamID, dto.RiftID)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeExpeditionError(w, err)
 		return
 	}
 
@@ -125,10 +127,59 @@ func (c *ExpeditionController) claimRewards(w http.ResponseWriter, r *http.Reque
 	rewards, err := c.expeditionService.ClaimExpeditionRewards(int64(user.Id), expeditionId)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeExpeditionError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rewards)
 }
+
+func (c *ExpeditionController) recallExpedition(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expeditionIdStr := chi.URLParam(r, "expeditionId")
+	expeditionId, err := strconv.ParseInt(expeditionIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid expedition ID", http.StatusBadRequest)
+		return
+	}
+
+	rewards, err := c.expeditionService.RecallExpedition(int64(user.Id), expeditionId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeExpeditionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rewards)
+}
+
+// writeExpeditionError maps expedition service errors to HTTP status codes
+func writeExpeditionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTeamNotFound),
+		errors.Is(err, services.ErrRiftNotFound),
+		errors.Is(err, services.ErrExpeditionNotFound),
+		errors.Is(err, services.ErrNoLootRollLog):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrTeamNotOwned),
+		errors.Is(err, services.ErrTeamLocked),
+		errors.Is(err, services.ErrRiftLocked),
+		errors.Is(err, services.ErrExpeditionNotOwned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrTeamBusy),
+		errors.Is(err, services.ErrRewardsAlreadyClaimed),
+		errors.Is(err, services.ErrExpeditionNotComplete),
+		errors.Is(err, services.ErrExpeditionFinished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
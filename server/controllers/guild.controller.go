@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/middleware"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+type GuildController struct {
+	guildService   services.IGuildService
+	authMiddleware middleware.IAuthMiddleware
+}
+
+func NewGuildController(guildService services.IGuildService, authMiddleware middleware.IAuthMiddleware) *GuildController {
+	return &GuildController{
+		guildService:   guildService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *GuildController) MapController() *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/mine", c.getMyGuild)
+	r.Post("/", c.createGuild)
+	r.Post("/join", c.joinGuild)
+	r.Post("/leave", c.leaveGuild)
+	r.Post("/invite-code", c.regenerateInviteCode)
+	r.Post("/treasury/deposit", c.depositLoot)
+	r.Post("/members/{userId}/kick", c.kickMember)
+	r.Post("/members/{userId}/role", c.setMemberRole)
+	return r
+}
+
+func (c *GuildController) getMyGuild(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	guild, err := c.guildService.GetMyGuild(int64(user.Id))
+	if err != nil {
+		if !errors.Is(err, services.ErrNotInGuild) {
+			util.LogErrorWithStackTrace(err)
+		}
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) createGuild(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.CreateGuildDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	guild, err := c.guildService.CreateGuild(int64(user.Id), dto.Name, dto.Description)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) joinGuild(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.JoinGuildDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	guild, err := c.guildService.JoinGuild(int64(user.Id), dto.InviteCode)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) leaveGuild(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := c.guildService.LeaveGuild(int64(user.Id)); err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("left guild"))
+}
+
+func (c *GuildController) regenerateInviteCode(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	guild, err := c.guildService.RegenerateInviteCode(int64(user.Id))
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) depositLoot(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.GuildDepositDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	guild, err := c.guildService.DepositLoot(int64(user.Id), dto.InventoryID, dto.Quantity)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) kickMember(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	memberUserId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	guild, err := c.guildService.KickMember(int64(user.Id), memberUserId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func (c *GuildController) setMemberRole(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	memberUserId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto models.SetGuildRoleDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	guild, err := c.guildService.SetMemberRole(int64(user.Id), memberUserId, dto.Role)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeGuildError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guild)
+}
+
+func writeGuildError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGuildName),
+		errors.Is(err, services.ErrInvalidGuildDescription),
+		errors.Is(err, services.ErrInvalidInviteCode),
+		errors.Is(err, services.ErrInvalidGuildRole),
+		errors.Is(err, services.ErrInvalidDepositQuantity),
+		errors.Is(err, services.ErrNotEnoughToDeposit),
+		errors.Is(err, services.ErrCannotDepositEquipped):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotInGuild),
+		errors.Is(err, services.ErrGuildMemberNotFound),
+		errors.Is(err, services.ErrInventoryItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrGuildPermission),
+		errors.Is(err, services.ErrInventoryItemNotOwned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrAlreadyInGuild),
+		errors.Is(err, services.ErrItemInEscrow),
+		errors.Is(err, services.ErrGuildFull),
+		errors.Is(err, services.ErrGuildNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
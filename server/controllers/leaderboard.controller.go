@@ -46,6 +46,8 @@ func (c *LeaderboardController) getLeaderboard(w http.ResponseWriter, r *http.Re
 		"legendary":   true,
 		"power":       true,
 		"expeditions": true,
+		"echoes":      true,
+		"guild_power": true,
 	}
 	if !validTypes[leaderboardType] {
 		http.Error(w, "Invalid leaderboard type", http.StatusBadRequest)
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/middleware"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+type NotificationController struct {
+	notificationService services.INotificationService
+	authMiddleware      middleware.IAuthMiddleware
+}
+
+func NewNotificationController(notificationService services.INotificationService, authMiddleware middleware.IAuthMiddleware) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+		authMiddleware:      authMiddleware,
+	}
+}
+
+func (c *NotificationController) MapController() *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/", c.getNotifications)
+	r.Get("/unread-count", c.getUnreadCount)
+	r.Post("/read-all", c.markAllRead)
+	r.Post("/{notificationId}/read", c.markRead)
+	return r
+}
+
+func (c *NotificationController) getNotifications(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get query parameters
+	pageSize := services.DefaultNotificationsPageSize
+	page := 1
+	if ps := r.URL.Query().Get("page_size"); ps != "" {
+		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 {
+			pageSize = psInt
+		}
+	}
+	if p := r.URL.Query().Get("page"); p != "" {
+		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
+			page = pInt
+		}
+	}
+
+	results, err := c.notificationService.GetNotifications(int64(user.Id), pageSize, page)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+func (c *NotificationController) getUnreadCount(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := c.notificationService.GetUnreadCount(int64(user.Id))
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (c *NotificationController) markRead(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationIdStr := chi.URLParam(r, "notificationId")
+	notificationId, err := strconv.ParseInt(notificationIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	err = c.notificationService.MarkRead(int64(user.Id), notificationId)
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *NotificationController) markAllRead(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.notificationService.MarkAllRead(int64(user.Id))
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
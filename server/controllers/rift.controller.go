@@ -12,14 +12,16 @@ import (
 )
 
 type RiftController struct {
-	riftService    services.IRiftService
-	authMiddleware middleware.IAuthMiddleware
+	riftService       services.IRiftService
+	expeditionService services.IExpeditionService
+	authMiddleware    middleware.IAuthMiddleware
 }
 
-func NewRiftController(riftService services.IRiftService, authMiddleware middleware.IAuthMiddleware) *RiftController {
+func NewRiftController(riftService services.IRiftService, expeditionService services.IExpeditionService, authMiddleware middleware.IAuthMiddleware) *RiftController {
 	return &RiftController{
-		riftService:    riftService,
-		authMiddleware: authMiddleware,
+		riftService:       riftService,
+		expeditionService: expeditionService,
+		authMiddleware:    authMiddleware,
 	}
 }
 
@@ -27,6 +29,7 @@ func (c *RiftController) MapController() *chi.Mux {
 	r := chi.NewRouter()
 	r.Get("/", c.getAllRifts)
 	r.Get("/{riftId}", c.getRiftById)
+	r.Get("/{riftId}/loot-table", c.getRiftLootTable)
 	return r
 }
 
@@ -79,3 +82,40 @@ func (c *RiftController) getRiftById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rift)
 }
+
+func (c *RiftController) getRiftLootTable(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	riftIdStr := chi.URLParam(r, "riftId")
+	riftId, err := strconv.ParseInt(riftIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rift ID", http.StatusBadRequest)
+		return
+	}
+
+	// Without a team the base odds are returned
+	var teamId *int64
+	if teamIdStr := r.URL.Query().Get("team_id"); teamIdStr != "" {
+		parsedTeamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamId = &parsedTeamId
+	}
+
+	lootTable, err := c.expeditionService.GetRiftLootTable(int64(user.Id), riftId, teamId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeExpeditionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lootTable)
+}
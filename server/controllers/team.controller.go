@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -13,14 +14,16 @@ import (
 )
 
 type TeamController struct {
-	teamService    services.ITeamService
-	authMiddleware middleware.IAuthMiddleware
+	teamService        services.ITeamService
+	launchQueueService services.ILaunchQueueService
+	authMiddleware     middleware.IAuthMiddleware
 }
 
-func NewTeamController(teamService services.ITeamService, authMiddleware middleware.IAuthMiddleware) *TeamController {
+func NewTeamController(teamService services.ITeamService, launchQueueService services.ILaunchQueueService, authMiddleware middleware.IAuthMiddleware) *TeamController {
 	return &TeamController{
-		teamService:    teamService,
-		authMiddleware: authMiddleware,
+		teamService:        teamService,
+		launchQueueService: launchQueueService,
+		authMiddleware:     authMiddleware,
 	}
 }
 
@@ -32,6 +35,13 @@ func (c *TeamController) MapController() *chi.Mux {
 	r.Post("/unequip", c.unequipItem)
 	r.Post("/consume", c.consumeItem)
 	r.Post("/{teamId}/unlock", c.unlockTeam)
+	r.Post("/{teamId}/specialize", c.specializeTeam)
+	r.Get("/{teamId}/upgrades", c.getUpgradePreviews)
+	r.Post("/{teamId}/upgrade", c.upgradeTeam)
+	r.Get("/{teamId}/queue", c.getTeamQueue)
+	r.Post("/{teamId}/queue", c.queueLaunch)
+	r.Delete("/{teamId}/queue", c.clearTeamQueue)
+	r.Delete("/{teamId}/queue/{entryId}", c.removeQueueEntry)
 	return r
 }
 
@@ -180,3 +190,239 @@ func (c *TeamController) unlockTeam(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("team unlocked"))
 }
+
+func (c *TeamController) specializeTeam(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto models.SpecializeTeamDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	team, err := c.teamService.SpecializeTeam(int64(user.Id), teamId, dto.Specialization, dto.InventoryID)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+func (c *TeamController) getUpgradePreviews(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	previews, err := c.teamService.GetUpgradePreviews(int64(user.Id), teamId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(previews)
+}
+
+func (c *TeamController) upgradeTeam(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto models.UpgradeTeamDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	team, err := c.teamService.UpgradeTeam(int64(user.Id), teamId, dto.RecipeID)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+// writeTeamError maps team service errors to HTTP status codes
+func (c *TeamController) getTeamQueue(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	queue, err := c.launchQueueService.GetTeamQueue(int64(user.Id), teamId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+func (c *TeamController) queueLaunch(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var dto models.QueueLaunchDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	queue, err := c.launchQueueService.QueueLaunch(int64(user.Id), teamId, &dto)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+func (c *TeamController) clearTeamQueue(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	err = c.launchQueueService.ClearTeamQueue(int64(user.Id), teamId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *TeamController) removeQueueEntry(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teamIdStr := chi.URLParam(r, "teamId")
+	teamId, err := strconv.ParseInt(teamIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	entryIdStr := chi.URLParam(r, "entryId")
+	entryId, err := strconv.ParseInt(entryIdStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid queue entry ID", http.StatusBadRequest)
+		return
+	}
+
+	queue, err := c.launchQueueService.RemoveQueueEntry(int64(user.Id), teamId, entryId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTeamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+func writeTeamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSpecialization),
+		errors.Is(err, services.ErrSpecializationNeedsEpic),
+		errors.Is(err, services.ErrCannotConsumeEquipped),
+		errors.Is(err, services.ErrNotEnoughItems),
+		errors.Is(err, services.ErrInvalidRepeatCount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTeamNotFound),
+		errors.Is(err, services.ErrInventoryItemNotFound),
+		errors.Is(err, services.ErrUpgradeRecipeNotFound),
+		errors.Is(err, services.ErrRiftNotFound),
+		errors.Is(err, services.ErrQueueEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrTeamNotOwned),
+		errors.Is(err, services.ErrTeamLocked),
+		errors.Is(err, services.ErrInventoryItemNotOwned),
+		errors.Is(err, services.ErrRiftLocked):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrTeamAlreadySpecialized),
+		errors.Is(err, services.ErrItemInEscrow),
+		errors.Is(err, services.ErrStatAtCap),
+		errors.Is(err, services.ErrLaunchQueueFull):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/middleware"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+type TradeController struct {
+	tradeService   services.ITradeService
+	authMiddleware middleware.IAuthMiddleware
+}
+
+func NewTradeController(tradeService services.ITradeService, authMiddleware middleware.IAuthMiddleware) *TradeController {
+	return &TradeController{
+		tradeService:   tradeService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *TradeController) MapController() *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/", c.getTrades)
+	r.Post("/", c.proposeTrade)
+	r.Get("/players/{userId}/items", c.getTradeableItems)
+	r.Get("/{tradeId}", c.getTrade)
+	r.Post("/{tradeId}/accept", c.acceptTrade)
+	r.Post("/{tradeId}/decline", c.declineTrade)
+	r.Post("/{tradeId}/cancel", c.cancelTrade)
+	return r
+}
+
+func (c *TradeController) getTrades(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	trades, err := c.tradeService.GetTrades(int64(user.Id))
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTradeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trades)
+}
+
+func (c *TradeController) getTrade(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tradeId, err := strconv.ParseInt(chi.URLParam(r, "tradeId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	trade, err := c.tradeService.GetTrade(int64(user.Id), tradeId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTradeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trade)
+}
+
+func (c *TradeController) getTradeableItems(w http.ResponseWriter, r *http.Request) {
+	_, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	partner, err := c.tradeService.GetTradeableItems(userId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTradeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(partner)
+}
+
+func (c *TradeController) proposeTrade(w http.ResponseWriter, r *http.Request) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dto models.ProposeTradeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	trade, err := c.tradeService.ProposeTrade(int64(user.Id), &dto)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTradeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(trade)
+}
+
+func (c *TradeController) acceptTrade(w http.ResponseWriter, r *http.Request) {
+	c.resolveTrade(w, r, c.tradeService.AcceptTrade)
+}
+
+func (c *TradeController) declineTrade(w http.ResponseWriter, r *http.Request) {
+	c.resolveTrade(w, r, c.tradeService.DeclineTrade)
+}
+
+func (c *TradeController) cancelTrade(w http.ResponseWriter, r *http.Request) {
+	c.resolveTrade(w, r, c.tradeService.CancelTrade)
+}
+
+// resolveTrade handles the endpoints that close a trade, which only differ in the
+// service call
+func (c *TradeController) resolveTrade(w http.ResponseWriter, r *http.Request, resolve func(userId, tradeId int64) (*models.TradeResponseDTO, error)) {
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tradeId, err := strconv.ParseInt(chi.URLParam(r, "tradeId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	trade, err := resolve(int64(user.Id), tradeId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		writeTradeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trade)
+}
+
+func writeTradeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCannotTradeWithSelf),
+		errors.Is(err, services.ErrEmptyTrade),
+		errors.Is(err, services.ErrTooManyTradeItems),
+		errors.Is(err, services.ErrDuplicateTradeItem),
+		errors.Is(err, services.ErrItemNotTradeable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTradeNotFound),
+		errors.Is(err, services.ErrTradeRecipientNotFound),
+		errors.Is(err, services.ErrInventoryItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrTradePermission),
+		errors.Is(err, services.ErrInventoryItemNotOwned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrItemInEscrow),
+		errors.Is(err, services.ErrTradeItemUnavailable),
+		errors.Is(err, services.ErrTradeNotPending),
+		errors.Is(err, services.ErrTradeExpired),
+		errors.Is(err, services.ErrTooManyPendingTrades):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -23,9 +23,10 @@ type UIController struct {
 	riftService        services.IRiftService
 	inventoryService   services.IInventoryService
 	leaderboardService services.ILeaderboardService
+	expeditionService  services.IExpeditionService
 }
 
-func NewUIController(templateService services.ITemplateService, staticService services.IStaticService, authMiddleware middleware.IAuthMiddleware, featureFlagService services.IFeatureFlagService, teamService services.ITeamService, riftService services.IRiftService, inventoryService services.IInventoryService, leaderboardService services.ILeaderboardService) IController {
+func NewUIController(templateService services.ITemplateService, staticService services.IStaticService, authMiddleware middleware.IAuthMiddleware, featureFlagService services.IFeatureFlagService, teamService services.ITeamService, riftService services.IRiftService, inventoryService services.IInventoryService, leaderboardService services.ILeaderboardService, expeditionService services.IExpeditionService) IController {
 	return &UIController{
 		templateService:    templateService,
 		staticService:      staticService,
@@ -35,6 +36,7 @@ func NewUIController(templateService services.ITemplateService, staticService se
 		riftService:        riftService,
 		inventoryService:   inventoryService,
 		leaderboardService: leaderboardService,
+		expeditionService:  expeditionService,
 	}
 }
 
@@ -53,6 +55,8 @@ func (c *UIController) MapController() *chi.Mux {
 	router.Get("/inventory", c.inventory)
 	router.Get("/dungeons", c.dungeons)
 	router.Get("/leaderboards", c.leaderboards)
+	router.Get("/guild", c.guild)
+	router.Get("/trades", c.trades)
 	router.Get("/account", c.account)
 	router.Get("/reset-password", c.resetPassword)
 	router.Get("/terms", c.terms)
@@ -157,15 +161,21 @@ func (c *UIController) account(w http.ResponseWriter, r *http.Request) {
 		navbarState = make(map[string]bool) // Fallback to all unlocked false
 	}
 
+	echoEncounters, err := c.expeditionService.GetEchoEncounterCount(int64(authUser.Id))
+	if err != nil {
+		util.LogError(err)
+	}
+
 	// Prepare page data with authenticated user context
 	pageData := services.PageData{
 		Title:       "Account Settings",
 		Description: "Manage your Parallax account settings",
 		Data: map[string]interface{}{
-			"Username":    authUser.Username,
-			"Email":       authUser.Email,     // Added Email field
-			"CreatedAt":   authUser.CreatedAt, // Added CreatedAt field
-			"NavbarState": navbarState,
+			"Username":       authUser.Username,
+			"Email":          authUser.Email,     // Added Email field
+			"CreatedAt":      authUser.CreatedAt, // Added CreatedAt field
+			"EchoEncounters": echoEncounters,
+			"NavbarState":    navbarState,
 		},
 	}
 
@@ -505,6 +515,78 @@ func (c *UIController) leaderboards(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (c *UIController) guild(w http.ResponseWriter, r *http.Request) {
+	util.LogDebug("Serving guild page")
+
+	// Get authenticated user
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil || user == nil {
+		util.LogDebug("User not authenticated, redirecting to login")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Get navbar unlock state
+	navbarState, err := c.getNavbarUnlockState(int64(user.Id))
+	if err != nil {
+		util.LogError(err)
+		navbarState = make(map[string]bool)
+	}
+
+	pageData := services.PageData{
+		Title:       "Guild",
+		Description: "Band together with other explorers and share your spoils",
+		Data: map[string]interface{}{
+			"Username":    user.Username,
+			"UserID":      user.Id,
+			"NavbarState": navbarState,
+		},
+	}
+
+	err = c.templateService.RenderTemplate(w, "guild", pageData)
+	if err != nil {
+		util.LogError(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *UIController) trades(w http.ResponseWriter, r *http.Request) {
+	util.LogDebug("Serving trades page")
+
+	// Get authenticated user
+	user, err := c.authMiddleware.Authorize(r)
+	if err != nil || user == nil {
+		util.LogDebug("User not authenticated, redirecting to login")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Get navbar unlock state
+	navbarState, err := c.getNavbarUnlockState(int64(user.Id))
+	if err != nil {
+		util.LogError(err)
+		navbarState = make(map[string]bool)
+	}
+
+	pageData := services.PageData{
+		Title:       "Trading Post",
+		Description: "Swap spare equipment with other explorers",
+		Data: map[string]interface{}{
+			"Username":    user.Username,
+			"UserID":      user.Id,
+			"NavbarState": navbarState,
+		},
+	}
+
+	err = c.templateService.RenderTemplate(w, "trades", pageData)
+	if err != nil {
+		util.LogError(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
 // fetchExternalContent fetches HTML from a URL and extracts script and style tags
 func (c *UIController) fetchExternalContent(url string) (string, []string, []string, error) {
 	resp, err := http.Get(url)
@@ -13,26 +13,56 @@ import (
 )
 
 type UserController struct {
-	userService    services.IUserService
-	authMiddleware middleware.IAuthMiddleware
+	userService         services.IUserService
+	loginHistoryService services.ILoginHistoryService
+	authMiddleware      middleware.IAuthMiddleware
 }
 
-func NewUserController(userService services.IUserService, authMiddleware middleware.IAuthMiddleware) *UserController {
+func NewUserController(userService services.IUserService, loginHistoryService services.ILoginHistoryService, authMiddleware middleware.IAuthMiddleware) *UserController {
 	return &UserController{
-		userService:    userService,
-		authMiddleware: authMiddleware,
+		userService:         userService,
+		loginHistoryService: loginHistoryService,
+		authMiddleware:      authMiddleware,
 	}
 }
 
 func (c *UserController) MapController() *chi.Mux {
 	r := chi.NewRouter()
 	r.Get("/", c.getUsers)
+	r.Get("/me/logins", c.getMyLogins)
 	r.Get("/{id}", c.getUserById)
 	r.Put("/{id}", c.updateUser)
 	r.Patch("/{id}/archived", c.toggleUserArchived)
 	return r
 }
 
+// getMyLogins lists the logged in user's recent login attempts
+func (c *UserController) getMyLogins(w http.ResponseWriter, r *http.Request) {
+	userContext, err := c.authMiddleware.Authorize(r)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "you must be logged in to perform this request", http.StatusUnauthorized)
+		return
+	}
+
+	logins, err := c.loginHistoryService.GetRecentLogins(userContext.Id)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		http.Error(w, "failed to retrieve login history", http.StatusInternalServerError)
+		return
+	}
+
+	returnStr, err := json.Marshal(logins)
+	if err != nil {
+		http.Error(w, "failed to create response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(returnStr)
+}
+
 func (c *UserController) toggleUserArchived(w http.ResponseWriter, r *http.Request) {
 	_, err := c.authMiddleware.Authorize(r)
 	if err != nil {
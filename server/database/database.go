@@ -1,13 +1,37 @@
 package database
 
 import (
+	"database/sql"
+	"fmt"
+
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/snowlynxsoftware/parallax-game/server/util"
 )
 
+// DBTX is the set of sqlx operations repositories use. Both *sqlx.DB and *sqlx.Tx
+// satisfy it, which lets the same repository code run inside or outside a transaction.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Get(dest any, query string, args ...any) error
+	Select(dest any, query string, args ...any) error
+	NamedExec(query string, arg any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+	QueryRowx(query string, args ...any) *sqlx.Row
+}
+
+// IUnitOfWork runs a function inside a single database transaction.
+// Repositories join the transaction through their WithTx methods.
+type IUnitOfWork interface {
+	WithinTransaction(fn func(tx *AppDataSource) error) error
+}
+
 type AppDataSource struct {
-	DB *sqlx.DB
+	// DB is the connection pool, or the open transaction for a data source
+	// handed out by WithinTransaction
+	DB   DBTX
+	pool *sqlx.DB
+	inTx bool
 }
 
 func NewAppDataSource() *AppDataSource {
@@ -20,9 +44,49 @@ func (d *AppDataSource) Connect(dbConnectionString string) {
 		panic(err)
 	}
 	d.DB = db
-	err = d.DB.Ping()
+	d.pool = db
+	err = d.pool.Ping()
 	if err != nil {
 		panic(err)
 	}
 	util.LogInfo("Connected to database")
 }
+
+// WithinTransaction begins a transaction and passes a data source bound to it to fn.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// Calling it on a data source that is already in a transaction joins that transaction.
+func (d *AppDataSource) WithinTransaction(fn func(tx *AppDataSource) error) error {
+	if d.inTx {
+		return fn(d)
+	}
+
+	tx, err := d.pool.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	txDataSource := &AppDataSource{
+		DB:   tx,
+		pool: d.pool,
+		inTx: true,
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(txDataSource); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			util.LogError(rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
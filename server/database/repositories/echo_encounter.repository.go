@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// EchoEncounterEntity records an expedition meeting the echoes of another player's
+// expedition. Expedition 1 is the one that completed, expedition 2 the one it echoed.
+type EchoEncounterEntity struct {
+	ID            int64      `json:"id" db:"id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt    *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived    bool       `json:"is_archived" db:"is_archived"`
+	ExpeditionID1 int64      `json:"expedition_id_1" db:"expedition_id_1"`
+	ExpeditionID2 int64      `json:"expedition_id_2" db:"expedition_id_2"`
+	UserID1       int64      `json:"user_id_1" db:"user_id_1"`
+	UserID2       int64      `json:"user_id_2" db:"user_id_2"`
+	RiftID        int64      `json:"rift_id" db:"rift_id"`
+	OccurredAt    time.Time  `json:"occurred_at" db:"occurred_at"`
+}
+
+// EchoEncounterLootEntity is a bonus item the echoed player got from an encounter. The
+// completing player's bonus is part of their expedition's loot instead.
+type EchoEncounterLootEntity struct {
+	ID              int64      `json:"id" db:"id"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt      *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived      bool       `json:"is_archived" db:"is_archived"`
+	EchoEncounterID int64      `json:"echo_encounter_id" db:"echo_encounter_id"`
+	UserID          int64      `json:"user_id" db:"user_id"`
+	LootItemID      int64      `json:"loot_item_id" db:"loot_item_id"`
+	Quantity        int        `json:"quantity" db:"quantity"`
+}
+
+type IEchoEncounterRepository interface {
+	GetEchoExpedition(userId, riftId int64, withinHours int) (*ExpeditionEntity, error)
+	CreateEchoEncounter(expeditionId1, expeditionId2, userId1, userId2, riftId int64) (*EchoEncounterEntity, error)
+	CreateEchoEncounterLoot(encounterId, userId, lootItemId int64, quantity int) error
+	GetEchoEncounterLoot(encounterId int64) ([]*EchoEncounterLootEntity, error)
+	GetEchoEncounterCount(userId int64) (int, error)
+	WithTx(tx *database.AppDataSource) IEchoEncounterRepository
+}
+
+type EchoEncounterRepository struct {
+	db *database.AppDataSource
+}
+
+func NewEchoEncounterRepository(db *database.AppDataSource) IEchoEncounterRepository {
+	return &EchoEncounterRepository{
+		db: db,
+	}
+}
+
+// GetEchoExpedition returns the most recent expedition another active player completed in
+// the rift within the last withinHours, or nil if there is none. Expeditions that are
+// already part of an encounter are skipped, so each one only pays out once.
+func (r *EchoEncounterRepository) GetEchoExpedition(userId, riftId int64, withinHours int) (*ExpeditionEntity, error) {
+	expedition := &ExpeditionEntity{}
+	query := `SELECT e.* FROM expeditions e
+			JOIN users u ON u.id = e.user_id AND u.is_archived = false
+			WHERE e.user_id <> $1 AND e.rift_id = $2 AND e.status = 'completed' AND e.is_archived = false
+			AND e.start_time + (e.duration_minutes * INTERVAL '1 minute') >= NOW() - ($3 * INTERVAL '1 hour')
+			AND NOT EXISTS (
+				SELECT 1 FROM echo_encounters ee
+				WHERE ee.expedition_id_1 = e.id OR ee.expedition_id_2 = e.id
+			)
+			ORDER BY e.start_time + (e.duration_minutes * INTERVAL '1 minute') DESC, e.id DESC
+			LIMIT 1`
+	err := r.db.DB.Get(expedition, query, userId, riftId, withinHours)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return expedition, nil
+}
+
+func (r *EchoEncounterRepository) CreateEchoEncounter(expeditionId1, expeditionId2, userId1, userId2, riftId int64) (*EchoEncounterEntity, error) {
+	encounter := &EchoEncounterEntity{}
+	query := `INSERT INTO echo_encounters (expedition_id_1, expedition_id_2, user_id_1, user_id_2, rift_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *`
+	err := r.db.DB.Get(encounter, query, expeditionId1, expeditionId2, userId1, userId2, riftId)
+	if err != nil {
+		return nil, err
+	}
+	return encounter, nil
+}
+
+// CreateEchoEncounterLoot records a bonus item the echoed player got from an encounter
+func (r *EchoEncounterRepository) CreateEchoEncounterLoot(encounterId, userId, lootItemId int64, quantity int) error {
+	query := `INSERT INTO echo_encounter_loot (echo_encounter_id, user_id, loot_item_id, quantity) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.DB.Exec(query, encounterId, userId, lootItemId, quantity)
+	return err
+}
+
+func (r *EchoEncounterRepository) GetEchoEncounterLoot(encounterId int64) ([]*EchoEncounterLootEntity, error) {
+	loot := []*EchoEncounterLootEntity{}
+	query := `SELECT * FROM echo_encounter_loot WHERE echo_encounter_id = $1 AND is_archived = false ORDER BY id`
+	err := r.db.DB.Select(&loot, query, encounterId)
+	if err != nil {
+		return nil, err
+	}
+	return loot, nil
+}
+
+// GetEchoEncounterCount returns how many encounters the user has been part of, on either side
+func (r *EchoEncounterRepository) GetEchoEncounterCount(userId int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM echo_encounters
+			WHERE (user_id_1 = $1 OR user_id_2 = $1) AND is_archived = false`
+	err := r.db.DB.Get(&count, query, userId)
+	return count, err
+}
+
+func (r *EchoEncounterRepository) WithTx(tx *database.AppDataSource) IEchoEncounterRepository {
+	return &EchoEncounterRepository{
+		db: tx,
+	}
+}
@@ -1,20 +1,36 @@
 package repositories
 
 import (
+	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/snowlynxsoftware/parallax-game/server/database"
 )
 
+// ErrTeamHasActiveExpedition is returned by CreateExpedition when the team already has an
+// unclaimed expedition (enforced by idx_expeditions_one_active_per_team)
+var ErrTeamHasActiveExpedition = errors.New("team already has an active expedition")
+
 type IExpeditionRepository interface {
-	CreateExpedition(userId, teamId, riftId int64, durationMinutes int) (*ExpeditionEntity, error)
+	CreateExpedition(userId, teamId, riftId int64, durationMinutes, effectivePower, recommendedPower int, lootLuck float64, lootSeed int64) (*ExpeditionEntity, error)
 	GetExpeditionById(expeditionId int64) (*ExpeditionEntity, error)
 	GetActiveExpeditionsByUserId(userId int64) ([]*ExpeditionEntity, error)
-	GetCompletedExpeditionsByUserId(userId int64, limit int) ([]*ExpeditionEntity, error)
+	GetActiveExpeditionByTeamId(teamId int64) (*ExpeditionEntity, error)
+	GetFinishedExpeditionsByUserId(userId int64, limit int) ([]*ExpeditionEntity, error)
 	GetCompletedExpeditionsCount(userId int64) (int, error)
+	GetCompletedExpeditionsCountByRift(userId, riftId int64) (int, error)
 	MarkCompleted(expeditionId int64) error
-	MarkProcessed(expeditionId int64) error
+	MarkProcessed(expeditionId int64, partialFailure bool) error
 	MarkClaimed(expeditionId int64) error
+	MarkRecalled(expeditionId int64) error
+	SaveLootRollInputs(expeditionId int64, luck, lootMultiplier float64) error
+	GetDueExpeditionIds(limit int, skipIds []int64) ([]int64, error)
+	MarkProcessFailed(expeditionId int64) error
+	LockDueExpeditionById(expeditionId int64) (*ExpeditionEntity, error)
+	GetExpeditionByIdForUpdate(expeditionId int64) (*ExpeditionEntity, error)
+	WithTx(tx *database.AppDataSource) IExpeditionRepository
 }
 
 type ExpeditionRepository struct {
@@ -27,13 +43,17 @@ func NewExpeditionRepository(db *database.AppDataSource) IExpeditionRepository {
 	}
 }
 
-func (r *ExpeditionRepository) CreateExpedition(userId, teamId, riftId int64, durationMinutes int) (*ExpeditionEntity, error) {
+func (r *ExpeditionRepository) CreateExpedition(userId, teamId, riftId int64, durationMinutes, effectivePower, recommendedPower int, lootLuck float64, lootSeed int64) (*ExpeditionEntity, error) {
 	expedition := &ExpeditionEntity{}
-	sql := `INSERT INTO expeditions (user_id, team_id, rift_id, start_time, duration_minutes, completed, processed, claimed)
-			VALUES ($1, $2, $3, NOW(), $4, false, false, false)
-			RETURNING id, created_at, modified_at, is_archived, user_id, team_id, rift_id, start_time, duration_minutes, completed, processed, claimed`
-	err := r.db.DB.QueryRowx(sql, userId, teamId, riftId, durationMinutes).StructScan(expedition)
+	sql := `INSERT INTO expeditions (user_id, team_id, rift_id, start_time, duration_minutes, effective_power, recommended_power, loot_luck, loot_seed, completed, processed, claimed)
+			VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, $8, false, false, false)
+			RETURNING id, created_at, modified_at, is_archived, user_id, team_id, rift_id, start_time, duration_minutes, completed, processed, claimed, effective_power, recommended_power, partial_failure, status, recalled_at, loot_seed, loot_luck, loot_multiplier, process_failed_at`
+	err := r.db.DB.QueryRowx(sql, userId, teamId, riftId, durationMinutes, effectivePower, recommendedPower, lootLuck, lootSeed).StructScan(expedition)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_expeditions_one_active_per_team" {
+			return nil, ErrTeamHasActiveExpedition
+		}
 		return nil, err
 	}
 	return expedition, nil
@@ -52,7 +72,7 @@ func (r *ExpeditionRepository) GetExpeditionById(expeditionId int64) (*Expeditio
 func (r *ExpeditionRepository) GetActiveExpeditionsByUserId(userId int64) ([]*ExpeditionEntity, error) {
 	expeditions := []*ExpeditionEntity{}
 	sql := `SELECT * FROM expeditions 
-			WHERE user_id = $1 AND completed = false AND is_archived = false
+			WHERE user_id = $1 AND claimed = false AND is_archived = false
 			ORDER BY start_time DESC`
 	err := r.db.DB.Select(&expeditions, sql, userId)
 	if err != nil {
@@ -61,10 +81,27 @@ func (r *ExpeditionRepository) GetActiveExpeditionsByUserId(userId int64) ([]*Ex
 	return expeditions, nil
 }
 
-func (r *ExpeditionRepository) GetCompletedExpeditionsByUserId(userId int64, limit int) ([]*ExpeditionEntity, error) {
+// GetActiveExpeditionByTeamId returns the team's unclaimed expedition, or nil if the team is free
+func (r *ExpeditionRepository) GetActiveExpeditionByTeamId(teamId int64) (*ExpeditionEntity, error) {
+	expedition := &ExpeditionEntity{}
+	query := `SELECT * FROM expeditions
+			WHERE team_id = $1 AND claimed = false AND is_archived = false
+			LIMIT 1`
+	err := r.db.DB.Get(expedition, query, teamId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return expedition, nil
+}
+
+// GetFinishedExpeditionsByUserId returns the user's completed and recalled expeditions, newest first
+func (r *ExpeditionRepository) GetFinishedExpeditionsByUserId(userId int64, limit int) ([]*ExpeditionEntity, error) {
 	expeditions := []*ExpeditionEntity{}
 	sql := `SELECT * FROM expeditions 
-			WHERE user_id = $1 AND completed = true AND is_archived = false
+			WHERE user_id = $1 AND status IN ('completed', 'recalled') AND is_archived = false
 			ORDER BY start_time DESC
 			LIMIT $2`
 	err := r.db.DB.Select(&expeditions, sql, userId, limit)
@@ -81,15 +118,22 @@ func (r *ExpeditionRepository) GetCompletedExpeditionsCount(userId int64) (int,
 	return count, err
 }
 
+func (r *ExpeditionRepository) GetCompletedExpeditionsCountByRift(userId, riftId int64) (int, error) {
+	var count int
+	sql := `SELECT COUNT(*) FROM expeditions WHERE user_id = $1 AND rift_id = $2 AND completed = true AND is_archived = false`
+	err := r.db.DB.Get(&count, sql, userId, riftId)
+	return count, err
+}
+
 func (r *ExpeditionRepository) MarkCompleted(expeditionId int64) error {
-	sql := `UPDATE expeditions SET completed = true, modified_at = NOW() WHERE id = $1`
+	sql := `UPDATE expeditions SET completed = true, status = 'completed', modified_at = NOW() WHERE id = $1`
 	_, err := r.db.DB.Exec(sql, expeditionId)
 	return err
 }
 
-func (r *ExpeditionRepository) MarkProcessed(expeditionId int64) error {
-	sql := `UPDATE expeditions SET processed = true, modified_at = NOW() WHERE id = $1`
-	_, err := r.db.DB.Exec(sql, expeditionId)
+func (r *ExpeditionRepository) MarkProcessed(expeditionId int64, partialFailure bool) error {
+	sql := `UPDATE expeditions SET processed = true, partial_failure = $2, modified_at = NOW() WHERE id = $1`
+	_, err := r.db.DB.Exec(sql, expeditionId, partialFailure)
 	return err
 }
 
@@ -113,3 +157,89 @@ func (r *ExpeditionRepository) MarkClaimed(expeditionId int64) error {
 	_, err = r.db.DB.Exec(sql, expeditionId)
 	return err
 }
+
+// MarkRecalled ends an expedition early. Recalled expeditions are processed and claimed in
+// one go, which frees the team, but are never marked completed.
+func (r *ExpeditionRepository) MarkRecalled(expeditionId int64) error {
+	sql := `UPDATE expeditions
+			SET status = 'recalled', processed = true, claimed = true, recalled_at = NOW(), modified_at = NOW()
+			WHERE id = $1`
+	_, err := r.db.DB.Exec(sql, expeditionId)
+	return err
+}
+
+// SaveLootRollInputs records the team luck and loot multiplier an expedition's loot was
+// rolled with, so the rolls can be replayed from the expedition's seed later
+func (r *ExpeditionRepository) SaveLootRollInputs(expeditionId int64, luck, lootMultiplier float64) error {
+	sql := `UPDATE expeditions SET loot_luck = $2, loot_multiplier = $3, modified_at = NOW() WHERE id = $1`
+	_, err := r.db.DB.Exec(sql, expeditionId, luck, lootMultiplier)
+	return err
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *ExpeditionRepository) WithTx(tx *database.AppDataSource) IExpeditionRepository {
+	return &ExpeditionRepository{
+		db: tx,
+	}
+}
+
+// GetDueExpeditionIds returns up to limit expeditions whose timers have run out but
+// have not been processed yet, leaving out skipIds. Expeditions that have never failed
+// to process come first, oldest first, then the ones whose last failure was longest ago.
+func (r *ExpeditionRepository) GetDueExpeditionIds(limit int, skipIds []int64) ([]int64, error) {
+	// pq sends a nil slice as NULL, which would leave out every expedition
+	if skipIds == nil {
+		skipIds = []int64{}
+	}
+
+	ids := []int64{}
+	sql := `SELECT id FROM expeditions
+			WHERE completed = false AND processed = false AND is_archived = false
+			AND start_time + (duration_minutes * INTERVAL '1 minute') <= NOW()
+			AND NOT (id = ANY($2))
+			ORDER BY process_failed_at NULLS FIRST, start_time
+			LIMIT $1`
+	err := r.db.DB.Select(&ids, sql, limit, pq.Array(skipIds))
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// MarkProcessFailed records that processing the expedition failed, which sends it to the
+// back of the due expeditions
+func (r *ExpeditionRepository) MarkProcessFailed(expeditionId int64) error {
+	sql := `UPDATE expeditions SET process_failed_at = NOW(), modified_at = NOW() WHERE id = $1`
+	_, err := r.db.DB.Exec(sql, expeditionId)
+	return err
+}
+
+// LockDueExpeditionById locks an unprocessed expedition for the rest of the transaction.
+// Uses SKIP LOCKED so that when several processors race for the same row only one wins;
+// the others (and callers asking for an already processed expedition) get nil.
+func (r *ExpeditionRepository) LockDueExpeditionById(expeditionId int64) (*ExpeditionEntity, error) {
+	expeditions := []*ExpeditionEntity{}
+	sql := `SELECT * FROM expeditions
+			WHERE id = $1 AND completed = false AND processed = false AND is_archived = false
+			FOR UPDATE SKIP LOCKED`
+	err := r.db.DB.Select(&expeditions, sql, expeditionId)
+	if err != nil {
+		return nil, err
+	}
+	if len(expeditions) == 0 {
+		return nil, nil
+	}
+	return expeditions[0], nil
+}
+
+// GetExpeditionByIdForUpdate loads an expedition and locks it for the rest of the transaction.
+// Concurrent callers block until the lock holder commits, so they always see its changes.
+func (r *ExpeditionRepository) GetExpeditionByIdForUpdate(expeditionId int64) (*ExpeditionEntity, error) {
+	expedition := &ExpeditionEntity{}
+	sql := `SELECT * FROM expeditions WHERE id = $1 AND is_archived = false FOR UPDATE`
+	err := r.db.DB.Get(expedition, sql, expeditionId)
+	if err != nil {
+		return nil, err
+	}
+	return expedition, nil
+}
@@ -7,6 +7,9 @@ import (
 type IExpeditionLootRepository interface {
 	CreateExpeditionLoot(expeditionId, lootItemId int64, quantity int) error
 	GetLootByExpeditionId(expeditionId int64) ([]*ExpeditionLootEntity, error)
+	CreateLootRolls(expeditionId int64, rolls []*ExpeditionLootRollEntity) error
+	GetLootRollsByExpeditionId(expeditionId int64) ([]*ExpeditionLootRollEntity, error)
+	WithTx(tx *database.AppDataSource) IExpeditionLootRepository
 }
 
 type ExpeditionLootRepository struct {
@@ -34,3 +37,33 @@ func (r *ExpeditionLootRepository) GetLootByExpeditionId(expeditionId int64) ([]
 	}
 	return loot, nil
 }
+
+// CreateLootRolls saves the roll log for an expedition's loot
+func (r *ExpeditionLootRepository) CreateLootRolls(expeditionId int64, rolls []*ExpeditionLootRollEntity) error {
+	sql := `INSERT INTO expedition_loot_rolls (expedition_id, roll_order, roll_type, rarity, roll, threshold, loot_item_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, roll := range rolls {
+		_, err := r.db.DB.Exec(sql, expeditionId, roll.RollOrder, roll.RollType, roll.Rarity, roll.Roll, roll.Threshold, roll.LootItemID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ExpeditionLootRepository) GetLootRollsByExpeditionId(expeditionId int64) ([]*ExpeditionLootRollEntity, error) {
+	rolls := []*ExpeditionLootRollEntity{}
+	sql := `SELECT * FROM expedition_loot_rolls WHERE expedition_id = $1 AND is_archived = false ORDER BY roll_order`
+	err := r.db.DB.Select(&rolls, sql, expeditionId)
+	if err != nil {
+		return nil, err
+	}
+	return rolls, nil
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *ExpeditionLootRepository) WithTx(tx *database.AppDataSource) IExpeditionLootRepository {
+	return &ExpeditionLootRepository{
+		db: tx,
+	}
+}
@@ -65,6 +65,7 @@ type TeamEntity struct {
 	LuckBonus             float64    `json:"luck_bonus" db:"luck_bonus"`
 	PowerBonus            int        `json:"power_bonus" db:"power_bonus"`
 	IsUnlocked            bool       `json:"is_unlocked" db:"is_unlocked"`
+	Specialization        string     `json:"specialization" db:"specialization"`
 	EquippedWeaponSlot    *int64     `json:"equipped_weapon_slot" db:"equipped_weapon_slot"`
 	EquippedArmorSlot     *int64     `json:"equipped_armor_slot" db:"equipped_armor_slot"`
 	EquippedAccessorySlot *int64     `json:"equipped_accessory_slot" db:"equipped_accessory_slot"`
@@ -82,22 +83,42 @@ type UserInventoryEntity struct {
 	LootItemID int64      `json:"loot_item_id" db:"loot_item_id"`
 	Quantity   int        `json:"quantity" db:"quantity"`
 	AcquiredAt time.Time  `json:"acquired_at" db:"acquired_at"`
+	// IsStack marks a consumable's stack. A user has at most one unarchived stack per item.
+	IsStack bool `json:"is_stack" db:"is_stack"`
+	// EscrowTradeID is the pending trade holding the item, if any
+	EscrowTradeID *int64 `json:"escrow_trade_id" db:"escrow_trade_id"`
+}
+
+// UserCollectedItemEntity records that a player has owned a loot item at some point
+type UserCollectedItemEntity struct {
+	UserID      int64     `json:"user_id" db:"user_id"`
+	LootItemID  int64     `json:"loot_item_id" db:"loot_item_id"`
+	CollectedAt time.Time `json:"collected_at" db:"collected_at"`
 }
 
 // ExpeditionEntity represents an expedition instance
 type ExpeditionEntity struct {
-	ID              int64      `json:"id" db:"id"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	ModifiedAt      *time.Time `json:"modified_at" db:"modified_at"`
-	IsArchived      bool       `json:"is_archived" db:"is_archived"`
-	UserID          int64      `json:"user_id" db:"user_id"`
-	TeamID          int64      `json:"team_id" db:"team_id"`
-	RiftID          int64      `json:"rift_id" db:"rift_id"`
-	StartTime       time.Time  `json:"start_time" db:"start_time"`
-	DurationMinutes int        `json:"duration_minutes" db:"duration_minutes"`
-	Completed       bool       `json:"completed" db:"completed"`
-	Processed       bool       `json:"processed" db:"processed"`
-	Claimed         bool       `json:"claimed" db:"claimed"`
+	ID               int64      `json:"id" db:"id"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt       *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived       bool       `json:"is_archived" db:"is_archived"`
+	UserID           int64      `json:"user_id" db:"user_id"`
+	TeamID           int64      `json:"team_id" db:"team_id"`
+	RiftID           int64      `json:"rift_id" db:"rift_id"`
+	StartTime        time.Time  `json:"start_time" db:"start_time"`
+	DurationMinutes  int        `json:"duration_minutes" db:"duration_minutes"`
+	Completed        bool       `json:"completed" db:"completed"`
+	Processed        bool       `json:"processed" db:"processed"`
+	Claimed          bool       `json:"claimed" db:"claimed"`
+	EffectivePower   int        `json:"effective_power" db:"effective_power"`
+	RecommendedPower int        `json:"recommended_power" db:"recommended_power"`
+	PartialFailure   bool       `json:"partial_failure" db:"partial_failure"`
+	Status           string     `json:"status" db:"status"`
+	RecalledAt       *time.Time `json:"recalled_at" db:"recalled_at"`
+	LootSeed         int64      `json:"loot_seed" db:"loot_seed"`
+	LootLuck         *float64   `json:"loot_luck" db:"loot_luck"`
+	LootMultiplier   *float64   `json:"loot_multiplier" db:"loot_multiplier"`
+	ProcessFailedAt  *time.Time `json:"process_failed_at" db:"process_failed_at"`
 }
 
 // ExpeditionLootEntity represents loot audit trail for an expedition
@@ -110,3 +131,70 @@ type ExpeditionLootEntity struct {
 	LootItemID   int64      `json:"loot_item_id" db:"loot_item_id"`
 	Quantity     int        `json:"quantity" db:"quantity"`
 }
+
+// ExpeditionLootRollEntity represents one roll made while rolling an expedition's loot.
+// Rarity, Threshold and LootItemID are only set for the roll types that use them.
+type ExpeditionLootRollEntity struct {
+	ID           int64      `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt   *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived   bool       `json:"is_archived" db:"is_archived"`
+	ExpeditionID int64      `json:"expedition_id" db:"expedition_id"`
+	RollOrder    int        `json:"roll_order" db:"roll_order"`
+	RollType     string     `json:"roll_type" db:"roll_type"`
+	Rarity       *string    `json:"rarity" db:"rarity"`
+	Roll         float64    `json:"roll" db:"roll"`
+	Threshold    *float64   `json:"threshold" db:"threshold"`
+	LootItemID   *int64     `json:"loot_item_id" db:"loot_item_id"`
+}
+
+// UnlockRuleEntity represents the unlock requirement for a rift or team.
+// Rule holds a JSON encoded models.UnlockCondition tree.
+type UnlockRuleEntity struct {
+	ID         int64      `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived bool       `json:"is_archived" db:"is_archived"`
+	TargetType string     `json:"target_type" db:"target_type"`
+	TargetKey  int64      `json:"target_key" db:"target_key"`
+	Rule       []byte     `json:"rule" db:"rule"`
+}
+
+// UpgradeRecipeEntity represents a team upgrade that can be bought with loot
+type UpgradeRecipeEntity struct {
+	ID           int64      `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt   *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived   bool       `json:"is_archived" db:"is_archived"`
+	Name         string     `json:"name" db:"name"`
+	Description  string     `json:"description" db:"description"`
+	Stat         string     `json:"stat" db:"stat"`
+	StatIncrease float64    `json:"stat_increase" db:"stat_increase"`
+	MaxValue     float64    `json:"max_value" db:"max_value"`
+}
+
+// UpgradeRecipeCostEntity represents how many items of a rarity an upgrade recipe costs
+type UpgradeRecipeCostEntity struct {
+	ID         int64      `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived bool       `json:"is_archived" db:"is_archived"`
+	RecipeID   int64      `json:"recipe_id" db:"recipe_id"`
+	Rarity     string     `json:"rarity" db:"rarity"`
+	Quantity   int        `json:"quantity" db:"quantity"`
+}
+
+// LaunchQueueEntryEntity represents a rift launch waiting in a team's queue.
+// RepeatCount is the number of launches left, nil repeats until the entry is removed.
+type LaunchQueueEntryEntity struct {
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt  *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived  bool       `json:"is_archived" db:"is_archived"`
+	UserID      int64      `json:"user_id" db:"user_id"`
+	TeamID      int64      `json:"team_id" db:"team_id"`
+	RiftID      int64      `json:"rift_id" db:"rift_id"`
+	Position    int        `json:"position" db:"position"`
+	RepeatCount *int       `json:"repeat_count" db:"repeat_count"`
+	AttemptedAt *time.Time `json:"attempted_at" db:"attempted_at"`
+}
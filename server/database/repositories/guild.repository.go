@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+var (
+	// ErrGuildNameTaken is returned by CreateGuild when an active guild already has the name
+	ErrGuildNameTaken = errors.New("guild name is already taken")
+	// ErrAlreadyInGuild is returned by AddMember when the user is already in a guild
+	ErrAlreadyInGuild = errors.New("user is already in a guild")
+)
+
+type GuildEntity struct {
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt  *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived  bool       `json:"is_archived" db:"is_archived"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	InviteCode  string     `json:"invite_code" db:"invite_code"`
+}
+
+// GuildMemberEntity is a user's membership of a guild. DisplayName is joined from users
+// when members are listed.
+type GuildMemberEntity struct {
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt  *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived  bool       `json:"is_archived" db:"is_archived"`
+	GuildID     int64      `json:"guild_id" db:"guild_id"`
+	UserID      int64      `json:"user_id" db:"user_id"`
+	Role        string     `json:"role" db:"role"`
+	JoinedAt    time.Time  `json:"joined_at" db:"joined_at"`
+	DisplayName string     `json:"display_name" db:"display_name"`
+}
+
+type GuildTreasuryItemEntity struct {
+	ID         int64      `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived bool       `json:"is_archived" db:"is_archived"`
+	GuildID    int64      `json:"guild_id" db:"guild_id"`
+	LootItemID int64      `json:"loot_item_id" db:"loot_item_id"`
+	Quantity   int        `json:"quantity" db:"quantity"`
+}
+
+// GuildRiftProgressEntity is how many expeditions a guild's current members have
+// completed in a rift, and how many of them have
+type GuildRiftProgressEntity struct {
+	RiftID               int64  `json:"rift_id" db:"rift_id"`
+	RiftName             string `json:"rift_name" db:"rift_name"`
+	CompletedExpeditions int    `json:"completed_expeditions" db:"completed_expeditions"`
+	MembersCompleted     int    `json:"members_completed" db:"members_completed"`
+}
+
+type IGuildRepository interface {
+	CreateGuild(name, description, inviteCode string) (*GuildEntity, error)
+	GetGuildById(guildId int64) (*GuildEntity, error)
+	GetGuildByIdForUpdate(guildId int64) (*GuildEntity, error)
+	GetGuildByInviteCode(inviteCode string) (*GuildEntity, error)
+	UpdateInviteCode(guildId int64, inviteCode string) error
+	ArchiveGuild(guildId int64) error
+	AddMember(guildId, userId int64, role string) (*GuildMemberEntity, error)
+	GetMemberByUserId(userId int64) (*GuildMemberEntity, error)
+	GetMembers(guildId int64) ([]*GuildMemberEntity, error)
+	GetMemberCount(guildId int64) (int, error)
+	UpdateMemberRole(guildId, userId int64, role string) error
+	RemoveMember(guildId, userId int64) (bool, error)
+	AddToTreasury(guildId, lootItemId int64, quantity int) (*GuildTreasuryItemEntity, error)
+	GetTreasury(guildId int64) ([]*GuildTreasuryItemEntity, error)
+	GetRiftProgress(guildId int64) ([]*GuildRiftProgressEntity, error)
+	WithTx(tx *database.AppDataSource) IGuildRepository
+}
+
+type GuildRepository struct {
+	db *database.AppDataSource
+}
+
+func NewGuildRepository(db *database.AppDataSource) IGuildRepository {
+	return &GuildRepository{
+		db: db,
+	}
+}
+
+// CreateGuild returns ErrGuildNameTaken if an active guild already has the name, ignoring case
+func (r *GuildRepository) CreateGuild(name, description, inviteCode string) (*GuildEntity, error) {
+	guild := &GuildEntity{}
+	query := `INSERT INTO guilds (name, description, invite_code)
+			VALUES ($1, $2, $3)
+			RETURNING *`
+	err := r.db.DB.Get(guild, query, name, description, inviteCode)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_guilds_name" {
+			return nil, ErrGuildNameTaken
+		}
+		return nil, err
+	}
+	return guild, nil
+}
+
+// GetGuildById returns the active guild, or nil if there isn't one
+func (r *GuildRepository) GetGuildById(guildId int64) (*GuildEntity, error) {
+	guild := &GuildEntity{}
+	query := `SELECT * FROM guilds WHERE id = $1 AND is_archived = false`
+	err := r.db.DB.Get(guild, query, guildId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return guild, nil
+}
+
+// GetGuildByIdForUpdate is GetGuildById, locking the guild for the rest of the transaction.
+// Membership changes take the lock so the member limit can't be raced past.
+func (r *GuildRepository) GetGuildByIdForUpdate(guildId int64) (*GuildEntity, error) {
+	guild := &GuildEntity{}
+	query := `SELECT * FROM guilds WHERE id = $1 AND is_archived = false FOR UPDATE`
+	err := r.db.DB.Get(guild, query, guildId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return guild, nil
+}
+
+// GetGuildByInviteCode returns the active guild with the invite code, or nil if there isn't one
+func (r *GuildRepository) GetGuildByInviteCode(inviteCode string) (*GuildEntity, error) {
+	guild := &GuildEntity{}
+	query := `SELECT * FROM guilds WHERE invite_code = $1 AND is_archived = false`
+	err := r.db.DB.Get(guild, query, inviteCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return guild, nil
+}
+
+func (r *GuildRepository) UpdateInviteCode(guildId int64, inviteCode string) error {
+	query := `UPDATE guilds SET invite_code = $1, modified_at = NOW() WHERE id = $2`
+	_, err := r.db.DB.Exec(query, inviteCode, guildId)
+	return err
+}
+
+func (r *GuildRepository) ArchiveGuild(guildId int64) error {
+	query := `UPDATE guilds SET is_archived = true, modified_at = NOW() WHERE id = $1`
+	_, err := r.db.DB.Exec(query, guildId)
+	return err
+}
+
+// AddMember returns ErrAlreadyInGuild if the user is already a member of any guild
+func (r *GuildRepository) AddMember(guildId, userId int64, role string) (*GuildMemberEntity, error) {
+	member := &GuildMemberEntity{}
+	query := `INSERT INTO guild_members (guild_id, user_id, role)
+			VALUES ($1, $2, $3)
+			RETURNING *`
+	err := r.db.DB.Get(member, query, guildId, userId, role)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_guild_members_user" {
+			return nil, ErrAlreadyInGuild
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// GetMemberByUserId returns the user's membership, or nil if they aren't in a guild
+func (r *GuildRepository) GetMemberByUserId(userId int64) (*GuildMemberEntity, error) {
+	member := &GuildMemberEntity{}
+	query := `SELECT gm.*, u.display_name FROM guild_members gm
+			JOIN users u ON u.id = gm.user_id
+			WHERE gm.user_id = $1 AND gm.is_archived = false`
+	err := r.db.DB.Get(member, query, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// GetMembers returns the guild's members, leader first, then officers, then members,
+// longest standing first within each role
+func (r *GuildRepository) GetMembers(guildId int64) ([]*GuildMemberEntity, error) {
+	members := []*GuildMemberEntity{}
+	query := `SELECT gm.*, u.display_name FROM guild_members gm
+			JOIN users u ON u.id = gm.user_id
+			WHERE gm.guild_id = $1 AND gm.is_archived = false
+			ORDER BY CASE gm.role WHEN 'leader' THEN 0 WHEN 'officer' THEN 1 ELSE 2 END, gm.joined_at, gm.id`
+	err := r.db.DB.Select(&members, query, guildId)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *GuildRepository) GetMemberCount(guildId int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM guild_members WHERE guild_id = $1 AND is_archived = false`
+	err := r.db.DB.Get(&count, query, guildId)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *GuildRepository) UpdateMemberRole(guildId, userId int64, role string) error {
+	query := `UPDATE guild_members SET role = $1, modified_at = NOW()
+			WHERE guild_id = $2 AND user_id = $3 AND is_archived = false`
+	_, err := r.db.DB.Exec(query, role, guildId, userId)
+	return err
+}
+
+// RemoveMember archives the membership. Returns false if the user wasn't a member of the guild.
+func (r *GuildRepository) RemoveMember(guildId, userId int64) (bool, error) {
+	query := `UPDATE guild_members SET is_archived = true, modified_at = NOW()
+			WHERE guild_id = $1 AND user_id = $2 AND is_archived = false`
+	result, err := r.db.DB.Exec(query, guildId, userId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// AddToTreasury adds quantity of a loot item to the guild's stack of it, starting the stack
+// if the guild doesn't have one
+func (r *GuildRepository) AddToTreasury(guildId, lootItemId int64, quantity int) (*GuildTreasuryItemEntity, error) {
+	item := &GuildTreasuryItemEntity{}
+	query := `INSERT INTO guild_treasury (guild_id, loot_item_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, loot_item_id)
+			DO UPDATE SET quantity = guild_treasury.quantity + EXCLUDED.quantity, modified_at = NOW()
+			RETURNING *`
+	err := r.db.DB.Get(item, query, guildId, lootItemId, quantity)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *GuildRepository) GetTreasury(guildId int64) ([]*GuildTreasuryItemEntity, error) {
+	items := []*GuildTreasuryItemEntity{}
+	query := `SELECT * FROM guild_treasury WHERE guild_id = $1 AND is_archived = false ORDER BY id`
+	err := r.db.DB.Select(&items, query, guildId)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetRiftProgress returns the completed expeditions of the guild's current members in each
+// rift they've completed, in rift order
+func (r *GuildRepository) GetRiftProgress(guildId int64) ([]*GuildRiftProgressEntity, error) {
+	progress := []*GuildRiftProgressEntity{}
+	query := `SELECT
+			r.id AS rift_id,
+			r.name AS rift_name,
+			COUNT(e.id) AS completed_expeditions,
+			COUNT(DISTINCT e.user_id) AS members_completed
+			FROM guild_members gm
+			JOIN expeditions e ON e.user_id = gm.user_id AND e.status = 'completed' AND e.is_archived = false
+			JOIN rifts r ON r.id = e.rift_id AND r.is_archived = false
+			WHERE gm.guild_id = $1 AND gm.is_archived = false
+			GROUP BY r.id, r.name
+			ORDER BY r.id`
+	err := r.db.DB.Select(&progress, query, guildId)
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r *GuildRepository) WithTx(tx *database.AppDataSource) IGuildRepository {
+	return &GuildRepository{
+		db: tx,
+	}
+}
@@ -0,0 +1,206 @@
+package integration
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// These tests run their calls at the same time on separate connections, so their
+// writes are committed. Each creates its own user and only looks at its own rows.
+
+const concurrentCalls = 20
+
+// runConcurrently calls fn concurrentCalls times at once and returns every error
+func runConcurrently(fn func(i int) error) []error {
+	errs := make([]error, concurrentCalls)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range concurrentCalls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// inTransaction runs fn in its own transaction, committing if it returns nil
+func inTransaction(fn func(tx *database.AppDataSource) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := fn(&database.AppDataSource{DB: tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestConcurrentAddLoot(t *testing.T) {
+	ds := newPool(t)
+	inventoryRepository := repositories.NewUserInventoryRepository(ds)
+	consumable := seededLootItemOfType(t, ds, models.ItemRarityUncommon, models.ItemTypeConsumable)
+	equipment := seededLootItemOfType(t, ds, models.ItemRarityUncommon, models.ItemTypeEquipment)
+
+	t.Run("Consumables", func(t *testing.T) {
+		user := createUser(t, ds, "concurrent-consumables")
+
+		// Half the drops are claimed on their own, half inside a transaction like ClaimLoot
+		errs := runConcurrently(func(i int) error {
+			if i%2 == 0 {
+				_, err := inventoryRepository.AddLoot(user.ID, consumable.ID, consumable.ItemType)
+				return err
+			}
+			return inTransaction(func(tx *database.AppDataSource) error {
+				_, err := inventoryRepository.WithTx(tx).AddLoot(user.ID, consumable.ID, consumable.ItemType)
+				return err
+			})
+		})
+		if err := errors.Join(errs...); err != nil {
+			t.Fatal(err)
+		}
+
+		stacks, err := inventoryRepository.GetConsumablesByUserId(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stacks) != 1 || stacks[0].Quantity != concurrentCalls {
+			t.Errorf("GetConsumablesByUserId() = %v, want one stack of %d", stacks, concurrentCalls)
+		}
+	})
+
+	t.Run("Equipment", func(t *testing.T) {
+		user := createUser(t, ds, "concurrent-equipment")
+
+		errs := runConcurrently(func(i int) error {
+			_, err := inventoryRepository.AddLoot(user.ID, equipment.ID, equipment.ItemType)
+			return err
+		})
+		if err := errors.Join(errs...); err != nil {
+			t.Fatal(err)
+		}
+
+		items, err := inventoryRepository.GetEquipmentByUserId(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != concurrentCalls {
+			t.Errorf("GetEquipmentByUserId() returned %d rows, want %d, equipment never stacks", len(items), concurrentCalls)
+		}
+	})
+}
+
+func TestConcurrentCreateExpedition(t *testing.T) {
+	ds := newPool(t)
+	expeditionRepository := repositories.NewExpeditionRepository(ds)
+	user := createUser(t, ds, "concurrent-expeditions")
+	team := createTeams(t, ds, user.ID)[0]
+	rift := seededRift(t, ds, "Tutorial Rift")
+
+	errs := runConcurrently(func(i int) error {
+		_, err := expeditionRepository.CreateExpedition(user.ID, team.ID, rift.ID, rift.DurationMinutes, 10, 10, 0, int64(i))
+		return err
+	})
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, repositories.ErrTeamHasActiveExpedition):
+			t.Errorf("CreateExpedition() error = %v, want ErrTeamHasActiveExpedition", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d simultaneous launches of one team succeeded, want 1", created)
+	}
+}
+
+func TestConcurrentLockDueExpedition(t *testing.T) {
+	ds := newPool(t)
+	user := createUser(t, ds, "concurrent-processing")
+	team := createTeams(t, ds, user.ID)[0]
+	rift := seededRift(t, ds, "Tutorial Rift")
+	expedition, err := repositories.NewExpeditionRepository(ds).CreateExpedition(user.ID, team.ID, rift.ID, 0, 10, 10, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every processor holds its transaction open until all of them have tried the lock
+	var attempted sync.WaitGroup
+	attempted.Add(concurrentCalls)
+	var mutex sync.Mutex
+	locked := 0
+	errs := runConcurrently(func(i int) error {
+		return inTransaction(func(tx *database.AppDataSource) error {
+			due, err := repositories.NewExpeditionRepository(tx).LockDueExpeditionById(expedition.ID)
+			attempted.Done()
+			if err != nil {
+				return err
+			}
+			if due != nil {
+				mutex.Lock()
+				locked++
+				mutex.Unlock()
+			}
+			attempted.Wait()
+			return nil
+		})
+	})
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if locked != 1 {
+		t.Errorf("%d processors locked the same due expedition, want 1", locked)
+	}
+
+	if err := repositories.NewExpeditionRepository(ds).MarkProcessed(expedition.ID, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentConsumeItemsByRarity(t *testing.T) {
+	ds := newPool(t)
+	inventoryRepository := repositories.NewUserInventoryRepository(ds)
+	user := createUser(t, ds, "concurrent-upgrades")
+	item := seededLootItemOfType(t, ds, models.ItemRarityEpic, models.ItemTypeEquipment)
+	for range 3 {
+		if _, err := inventoryRepository.AddLoot(user.ID, item.ID, item.ItemType); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each upgrade costs every epic the user owns, so only one can be paid for
+	errs := runConcurrently(func(i int) error {
+		return inTransaction(func(tx *database.AppDataSource) error {
+			return inventoryRepository.WithTx(tx).ConsumeItemsByRarity(user.ID, item.Rarity, 3)
+		})
+	})
+
+	paid := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			paid++
+		case !errors.Is(err, repositories.ErrNotEnoughItems):
+			t.Errorf("ConsumeItemsByRarity() error = %v, want ErrNotEnoughItems", err)
+		}
+	}
+	if paid != 1 {
+		t.Errorf("%d simultaneous upgrades were paid for, want 1", paid)
+	}
+	count, err := inventoryRepository.CountItemsByRarity(user.ID, item.Rarity)
+	if err != nil || count != 0 {
+		t.Errorf("CountItemsByRarity() = %d, %v, want 0", count, err)
+	}
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/repositorytest"
+)
+
+// TestConformance runs the repository conformance tests against the Postgres
+// repositories. Each test runs in its own rolled back transaction.
+func TestConformance(t *testing.T) {
+	requireDatabase(t)
+
+	repositorytest.Run(t, func(t *testing.T) *repositorytest.Repositories {
+		dataSource, tx := beginTx(t)
+		return &repositorytest.Repositories{
+			Users:          repositories.NewUserRepository(dataSource),
+			FeatureFlags:   repositories.NewFeatureFlagRepository(dataSource),
+			Teams:          repositories.NewTeamRepository(dataSource),
+			Inventory:      repositories.NewUserInventoryRepository(dataSource),
+			Expeditions:    repositories.NewExpeditionRepository(dataSource),
+			LaunchQueue:    repositories.NewLaunchQueueRepository(dataSource),
+			Leaderboards:   repositories.NewLeaderboardRepository(dataSource),
+			Notifications:  repositories.NewNotificationRepository(dataSource),
+			EchoEncounters: repositories.NewEchoEncounterRepository(dataSource),
+			Guilds:         repositories.NewGuildRepository(dataSource),
+			Trades:         repositories.NewTradeRepository(dataSource),
+			Sessions:       repositories.NewUserSessionRepository(dataSource),
+			UsedTokens:     repositories.NewUsedTokenRepository(dataSource),
+			LoginHistory:   repositories.NewUserLoginHistoryRepository(dataSource),
+			TwoFactor:      repositories.NewUserTwoFactorRepository(dataSource),
+			Seeder:         &postgresSeeder{t: t, tx: tx},
+		}
+	})
+}
+
+// postgresSeeder inserts game data the way the migrations do
+type postgresSeeder struct {
+	t  *testing.T
+	tx *sqlx.Tx
+}
+
+func (s *postgresSeeder) AddRift(rift *repositories.RiftEntity) *repositories.RiftEntity {
+	row := &repositories.RiftEntity{}
+	query := `INSERT INTO rifts (name, description, world_type, duration_minutes, difficulty, weak_to_element, unlock_requirement_text, icon)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`
+	err := s.tx.Get(row, query, rift.Name, rift.Description, rift.WorldType, rift.DurationMinutes, rift.Difficulty, rift.WeakToElement, rift.UnlockRequirementText, rift.Icon)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return row
+}
+
+func (s *postgresSeeder) AddLootItem(item *repositories.LootItemEntity) *repositories.LootItemEntity {
+	row := &repositories.LootItemEntity{}
+	query := `INSERT INTO loot_items (name, description, rarity, world_type, item_type, equipment_slot, speed_bonus, luck_bonus, power_bonus, elemental_affinity, power_value, icon)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING *`
+	err := s.tx.Get(row, query, item.Name, item.Description, item.Rarity, item.WorldType, item.ItemType, item.EquipmentSlot,
+		item.SpeedBonus, item.LuckBonus, item.PowerBonus, item.ElementalAffinity, item.PowerValue, item.Icon)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return row
+}
+
+func (s *postgresSeeder) AddLeaderboardCache(leaderboardType string, lastSynced time.Time) {
+	query := `INSERT INTO leaderboard_cache (leaderboard_type, last_synced) VALUES ($1, $2)`
+	if _, err := s.tx.Exec(query, leaderboardType, lastSynced); err != nil {
+		s.t.Fatal(err)
+	}
+}
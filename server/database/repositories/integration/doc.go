@@ -0,0 +1,8 @@
+// Package integration tests the Postgres repositories against a real database.
+//
+// The tests are opt-in. Set TEST_DB_CONNECTION_STRING to the URL of a Postgres server
+// the tests may create databases on, e.g. the docker-compose one, or run
+// `make test-integration`. The tests create a throwaway database, apply
+// migrations/*.sql to it the same way the migrate command does, and drop it when
+// they finish. Without the variable every test is skipped.
+package integration
@@ -0,0 +1,180 @@
+package integration
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+func TestExpeditionLifecycle(t *testing.T) {
+	ds, _ := beginTx(t)
+	expeditionRepository := repositories.NewExpeditionRepository(ds)
+	user := createUser(t, ds, "expeditions")
+	teams := createTeams(t, ds, user.ID)
+	rift := seededRift(t, ds, "Tutorial Rift")
+
+	// NOW() doesn't move inside a transaction, so a zero minute expedition is due at once
+	due, err := expeditionRepository.CreateExpedition(user.ID, teams[0].ID, rift.ID, 0, 10, 10, 0, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due.Status != string(models.ExpeditionStatusActive) || due.LootSeed != 42 {
+		t.Errorf("CreateExpedition() = %+v, want an active expedition with seed 42", due)
+	}
+
+	dueIds, err := expeditionRepository.GetDueExpeditionIds(1000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(dueIds, due.ID) {
+		t.Errorf("GetDueExpeditionIds() = %v, want it to include %d", dueIds, due.ID)
+	}
+	locked, err := expeditionRepository.LockDueExpeditionById(due.ID)
+	if err != nil || locked == nil {
+		t.Fatalf("LockDueExpeditionById() = %v, %v, want the expedition", locked, err)
+	}
+
+	if err := expeditionRepository.SaveLootRollInputs(due.ID, 12.5, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := expeditionRepository.MarkCompleted(due.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := expeditionRepository.MarkProcessed(due.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := expeditionRepository.GetExpeditionByIdForUpdate(due.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !processed.Processed || !processed.PartialFailure || processed.LootLuck == nil || *processed.LootLuck != 12.5 ||
+		processed.LootMultiplier == nil || *processed.LootMultiplier != 1.5 {
+		t.Errorf("GetExpeditionByIdForUpdate() = %+v, want it processed with its roll inputs", processed)
+	}
+	if relocked, err := expeditionRepository.LockDueExpeditionById(due.ID); err != nil || relocked != nil {
+		t.Errorf("LockDueExpeditionById() for a processed expedition = %v, %v, want nil, nil", relocked, err)
+	}
+
+	recalled, err := expeditionRepository.CreateExpedition(user.ID, teams[1].ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := expeditionRepository.GetActiveExpeditionsByUserId(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 2 {
+		t.Errorf("GetActiveExpeditionsByUserId() returned %d expeditions, want 2 unclaimed", len(active))
+	}
+	if err := expeditionRepository.MarkRecalled(recalled.ID); err != nil {
+		t.Fatal(err)
+	}
+	byId, err := expeditionRepository.GetExpeditionById(recalled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byId.Status != string(models.ExpeditionStatusRecalled) || !byId.Claimed || byId.Completed || byId.RecalledAt == nil {
+		t.Errorf("GetExpeditionById() after MarkRecalled() = %+v, want it recalled and claimed but not completed", byId)
+	}
+
+	finished, err := expeditionRepository.GetFinishedExpeditionsByUserId(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished) != 2 {
+		t.Errorf("GetFinishedExpeditionsByUserId() returned %d expeditions, want the completed and the recalled one", len(finished))
+	}
+	completed, err := expeditionRepository.GetCompletedExpeditionsCount(user.ID)
+	if err != nil || completed != 1 {
+		t.Errorf("GetCompletedExpeditionsCount() = %d, %v, want 1, recalled runs don't count", completed, err)
+	}
+
+	if _, err := expeditionRepository.GetExpeditionById(-1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetExpeditionById() for a missing expedition error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestExpeditionLoot(t *testing.T) {
+	ds, _ := beginTx(t)
+	expeditionLootRepository := repositories.NewExpeditionLootRepository(ds)
+	user := createUser(t, ds, "expedition-loot")
+	teams := createTeams(t, ds, user.ID)
+	rift := seededRift(t, ds, "Tutorial Rift")
+	item := seededLootItemOfType(t, ds, models.ItemRarityCommon, models.ItemTypeEquipment)
+
+	expedition, err := repositories.NewExpeditionRepository(ds).CreateExpedition(user.ID, teams[0].ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := expeditionLootRepository.CreateExpeditionLoot(expedition.ID, item.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	loot, err := expeditionLootRepository.GetLootByExpeditionId(expedition.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loot) != 1 || loot[0].LootItemID != item.ID || loot[0].Quantity != 2 {
+		t.Errorf("GetLootByExpeditionId() = %v, want 2 of %s", loot, item.Name)
+	}
+
+	rarity := item.Rarity
+	threshold := 70.0
+	rolls := []*repositories.ExpeditionLootRollEntity{
+		{RollOrder: 2, RollType: string(models.LootRollTypeItem), Rarity: &rarity, Roll: 3, LootItemID: &item.ID},
+		{RollOrder: 1, RollType: string(models.LootRollTypeRarity), Rarity: &rarity, Roll: 12.5, Threshold: &threshold},
+		{RollOrder: 0, RollType: string(models.LootRollTypeFailure), Roll: 0.9},
+	}
+	if err := expeditionLootRepository.CreateLootRolls(expedition.ID, rolls); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := expeditionLootRepository.GetLootRollsByExpeditionId(expedition.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != len(rolls) {
+		t.Fatalf("GetLootRollsByExpeditionId() returned %d rolls, want %d", len(saved), len(rolls))
+	}
+	for i, roll := range saved {
+		if roll.RollOrder != i {
+			t.Errorf("GetLootRollsByExpeditionId()[%d] has roll order %d, want rolls in order", i, roll.RollOrder)
+		}
+	}
+	if saved[0].Rarity != nil || saved[0].Threshold != nil || saved[0].LootItemID != nil {
+		t.Errorf("failure roll = %+v, want no rarity, threshold or item", saved[0])
+	}
+	if saved[2].LootItemID == nil || *saved[2].LootItemID != item.ID {
+		t.Errorf("item roll = %+v, want loot item %d", saved[2], item.ID)
+	}
+}
+
+func TestLaunchQueueRemoveEntry(t *testing.T) {
+	ds, _ := beginTx(t)
+	launchQueueRepository := repositories.NewLaunchQueueRepository(ds)
+	user := createUser(t, ds, "launch-queue")
+	teams := createTeams(t, ds, user.ID)
+	rift := seededRift(t, ds, "Tutorial Rift")
+
+	first, err := launchQueueRepository.AddEntry(user.ID, teams[0].ID, rift.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := launchQueueRepository.AddEntry(user.ID, teams[0].ID, rift.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := launchQueueRepository.RemoveEntry(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := launchQueueRepository.GetEntriesByTeamId(teams[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != second.ID {
+		t.Errorf("GetEntriesByTeamId() after RemoveEntry() = %v, want only entry %d", entries, second.ID)
+	}
+}
@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/memory"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// seedPath is the game data the migrations insert, as the local in-memory server loads it
+const seedPath = "../../../../scripts/local/game_data.json"
+
+func loadSeed(t *testing.T) *memory.Seed {
+	t.Helper()
+	seed, err := memory.LoadSeed(seedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seed
+}
+
+// createUser creates a user with an email no other test, or earlier run, will use
+func createUser(t *testing.T, ds *database.AppDataSource, name string) *repositories.UserEntity {
+	t.Helper()
+	user, err := repositories.NewUserRepository(ds).CreateNewUser(&models.UserCreateDTO{
+		Email:       fmt.Sprintf("%s-%d@integration.test", name, time.Now().UnixNano()),
+		DisplayName: "Integration " + name,
+		Password:    "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createTeams(t *testing.T, ds *database.AppDataSource, userId int64) []*repositories.TeamEntity {
+	t.Helper()
+	teamRepository := repositories.NewTeamRepository(ds)
+	if err := teamRepository.CreateTeamsForUser(userId); err != nil {
+		t.Fatal(err)
+	}
+	teams, err := teamRepository.GetTeamsByUserId(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return teams
+}
+
+// seededRift returns one of the rifts the migrations insert
+func seededRift(t *testing.T, ds *database.AppDataSource, name string) *repositories.RiftEntity {
+	t.Helper()
+	rifts, err := repositories.NewRiftRepository(ds).GetAllRifts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rift := range rifts {
+		if rift.Name == name {
+			return rift
+		}
+	}
+	t.Fatalf("no seeded rift %q", name)
+	return nil
+}
+
+// seededLootItemOfType returns the first seeded loot item of a rarity and item type
+func seededLootItemOfType(t *testing.T, ds *database.AppDataSource, rarity models.ItemRarity, itemType models.ItemType) *repositories.LootItemEntity {
+	t.Helper()
+	items, err := repositories.NewLootItemRepository(ds).GetAllLootItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.Rarity == string(rarity) && item.ItemType == string(itemType) {
+			return item
+		}
+	}
+	t.Fatalf("no seeded %s %s", rarity, itemType)
+	return nil
+}
@@ -0,0 +1,269 @@
+package integration
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+// rarities in the order Postgres sorts the item_rarity enum
+var rarities = []string{"common", "uncommon", "rare", "epic", "legendary"}
+
+// The game data tests also check the local seed file still matches what the
+// migrations insert, since the in-memory server is loaded from it
+
+func TestRifts(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+	riftRepository := repositories.NewRiftRepository(ds)
+
+	rifts, err := riftRepository.GetAllRifts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, rift := range rifts {
+		names = append(names, rift.Name)
+	}
+	seedNames := []string{}
+	for _, rift := range seed.Rifts {
+		seedNames = append(seedNames, rift.Name)
+	}
+	slices.Sort(names)
+	slices.Sort(seedNames)
+	if !slices.Equal(names, seedNames) {
+		t.Errorf("GetAllRifts() = %v, want the seeded rifts %v", names, seedNames)
+	}
+
+	rift, err := riftRepository.GetRiftById(rifts[0].ID)
+	if err != nil || rift.Name != rifts[0].Name {
+		t.Errorf("GetRiftById() = %v, %v, want %s", rift, err, rifts[0].Name)
+	}
+	if _, err := riftRepository.GetRiftById(-1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRiftById() for a missing rift error = %v, want sql.ErrNoRows", err)
+	}
+
+	medium, err := riftRepository.GetRiftsByDifficulty("medium")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMedium := 0
+	for _, rift := range seed.Rifts {
+		if rift.Difficulty == "medium" {
+			wantMedium++
+		}
+	}
+	if len(medium) != wantMedium {
+		t.Errorf("GetRiftsByDifficulty(medium) returned %d rifts, want %d", len(medium), wantMedium)
+	}
+}
+
+func TestLootItems(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+	lootItemRepository := repositories.NewLootItemRepository(ds)
+
+	items, err := lootItemRepository.GetAllLootItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(seed.LootItems) {
+		t.Errorf("GetAllLootItems() returned %d items, want the %d seeded items", len(items), len(seed.LootItems))
+	}
+	for i := 1; i < len(items); i++ {
+		if slices.Index(rarities, items[i-1].Rarity) > slices.Index(rarities, items[i].Rarity) {
+			t.Fatalf("GetAllLootItems() should sort by rarity, %s came before %s", items[i-1].Name, items[i].Name)
+		}
+	}
+
+	item, err := lootItemRepository.GetLootItemById(items[0].ID)
+	if err != nil || item.Name != items[0].Name {
+		t.Errorf("GetLootItemById() = %v, %v, want %s", item, err, items[0].Name)
+	}
+	if _, err := lootItemRepository.GetLootItemById(-1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetLootItemById() for a missing item error = %v, want sql.ErrNoRows", err)
+	}
+
+	wantFire, wantRareFire := 0, 0
+	for _, item := range seed.LootItems {
+		if item.WorldType == "fire" {
+			wantFire++
+			if item.Rarity == "rare" {
+				wantRareFire++
+			}
+		}
+	}
+	fire, err := lootItemRepository.GetLootItemsByWorldType("fire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fire) != wantFire {
+		t.Errorf("GetLootItemsByWorldType(fire) returned %d items, want %d", len(fire), wantFire)
+	}
+	rareFire, err := lootItemRepository.GetLootItemsByRarityAndWorldType("rare", "fire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rareFire) != wantRareFire {
+		t.Errorf("GetLootItemsByRarityAndWorldType(rare, fire) returned %d items, want %d", len(rareFire), wantRareFire)
+	}
+}
+
+func TestDropTables(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+	dropTableRepository := repositories.NewLootDropTableRepository(ds)
+
+	// Seed drop tables refer to rifts by their position in the seed
+	for i, seedRift := range seed.Rifts {
+		want := []*repositories.LootDropTableEntity{}
+		for _, dropTable := range seed.DropTables {
+			if dropTable.RiftID == int64(i+1) {
+				want = append(want, dropTable)
+			}
+		}
+		slices.SortStableFunc(want, func(a, b *repositories.LootDropTableEntity) int {
+			return slices.Index(rarities, a.Rarity) - slices.Index(rarities, b.Rarity)
+		})
+
+		rift := seededRift(t, ds, seedRift.Name)
+		dropTables, err := dropTableRepository.GetDropTablesByRiftId(rift.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dropTables) != len(want) {
+			t.Errorf("GetDropTablesByRiftId(%s) returned %d rarities, want %d", rift.Name, len(dropTables), len(want))
+			continue
+		}
+		for j, dropTable := range dropTables {
+			if dropTable.Rarity != want[j].Rarity || dropTable.DropRatePercent != want[j].DropRatePercent ||
+				dropTable.MinQuantity != want[j].MinQuantity || dropTable.MaxQuantity != want[j].MaxQuantity {
+				t.Errorf("GetDropTablesByRiftId(%s)[%d] = %+v, want %+v", rift.Name, j, dropTable, want[j])
+			}
+		}
+	}
+}
+
+func TestUnlockRules(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+	unlockRuleRepository := repositories.NewUnlockRuleRepository(ds)
+
+	riftRules, err := unlockRuleRepository.GetRulesByTargetType("rift")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRiftRules := 0
+	for _, rule := range seed.UnlockRules {
+		if rule.TargetType == "rift" {
+			wantRiftRules++
+		}
+	}
+	if len(riftRules) != wantRiftRules {
+		t.Errorf("GetRulesByTargetType(rift) returned %d rules, want %d", len(riftRules), wantRiftRules)
+	}
+
+	for _, seedRule := range seed.UnlockRules {
+		rule, err := unlockRuleRepository.GetRuleByTarget(seedRule.TargetType, seedRule.TargetKey)
+		if err != nil || rule == nil {
+			t.Errorf("GetRuleByTarget(%s, %d) = %v, %v, want a rule", seedRule.TargetType, seedRule.TargetKey, rule, err)
+			continue
+		}
+		// JSONB doesn't keep the formatting the rule was written with
+		var got, want any
+		if err := json.Unmarshal(rule.Rule, &got); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(seedRule.Rule, &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetRuleByTarget(%s, %d) rule = %s, want %s", seedRule.TargetType, seedRule.TargetKey, rule.Rule, seedRule.Rule)
+		}
+	}
+
+	missing, err := unlockRuleRepository.GetRuleByTarget("rift", -1)
+	if err != nil || missing != nil {
+		t.Errorf("GetRuleByTarget() for a target without a rule = %v, %v, want nil, nil", missing, err)
+	}
+}
+
+func TestUpgradeRecipes(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+	upgradeRecipeRepository := repositories.NewUpgradeRecipeRepository(ds)
+
+	recipes, err := upgradeRecipeRepository.GetRecipes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipes) != len(seed.UpgradeRecipes) {
+		t.Fatalf("GetRecipes() returned %d recipes, want %d", len(recipes), len(seed.UpgradeRecipes))
+	}
+	for i, recipe := range recipes {
+		want := seed.UpgradeRecipes[i]
+		if recipe.Name != want.Name || recipe.Stat != want.Stat || recipe.StatIncrease != want.StatIncrease || recipe.MaxValue != want.MaxValue {
+			t.Errorf("GetRecipes()[%d] = %+v, want %+v", i, recipe, want.UpgradeRecipeEntity)
+		}
+
+		byId, err := upgradeRecipeRepository.GetRecipeById(recipe.ID)
+		if err != nil || byId.Name != recipe.Name {
+			t.Errorf("GetRecipeById() = %v, %v, want %s", byId, err, recipe.Name)
+		}
+
+		costs, err := upgradeRecipeRepository.GetCostsByRecipeId(recipe.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(costs) != len(want.Costs) {
+			t.Errorf("GetCostsByRecipeId(%s) returned %d costs, want %d", recipe.Name, len(costs), len(want.Costs))
+			continue
+		}
+		for j, cost := range costs {
+			if cost.Rarity != want.Costs[j].Rarity || cost.Quantity != want.Costs[j].Quantity {
+				t.Errorf("GetCostsByRecipeId(%s)[%d] = %s x%d, want %s x%d", recipe.Name, j, cost.Rarity, cost.Quantity, want.Costs[j].Rarity, want.Costs[j].Quantity)
+			}
+		}
+	}
+
+	if _, err := upgradeRecipeRepository.GetRecipeById(-1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRecipeById() for a missing recipe error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSeededFlagsAndLeaderboards(t *testing.T) {
+	ds, _ := beginTx(t)
+	seed := loadSeed(t)
+
+	featureFlagRepository := repositories.NewFeatureFlagRepository(ds)
+	for _, seedFlag := range seed.FeatureFlags {
+		flag, err := featureFlagRepository.GetFlagByKey(seedFlag.Key)
+		if err != nil || flag == nil || flag.Enabled != seedFlag.Enabled {
+			t.Errorf("GetFlagByKey(%s) = %v, %v, want enabled = %v", seedFlag.Key, flag, err, seedFlag.Enabled)
+		}
+	}
+
+	leaderboardRepository := repositories.NewLeaderboardRepository(ds)
+	for _, leaderboardType := range seed.Leaderboards {
+		before, err := leaderboardRepository.GetCacheMetadata(leaderboardType)
+		if err != nil {
+			t.Errorf("GetCacheMetadata(%s) error = %v", leaderboardType, err)
+			continue
+		}
+		if err := leaderboardRepository.UpdateLastSynced(leaderboardType); err != nil {
+			t.Fatal(err)
+		}
+		after, err := leaderboardRepository.GetCacheMetadata(leaderboardType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !after.LastSynced.After(before.LastSynced) {
+			t.Errorf("UpdateLastSynced(%s) left last_synced at %v", leaderboardType, after.LastSynced)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+func TestInventoryQueries(t *testing.T) {
+	ds, _ := beginTx(t)
+	inventoryRepository := repositories.NewUserInventoryRepository(ds)
+	user := createUser(t, ds, "inventory")
+	equipment := seededLootItemOfType(t, ds, models.ItemRarityRare, models.ItemTypeEquipment)
+	consumable := seededLootItemOfType(t, ds, models.ItemRarityRare, models.ItemTypeConsumable)
+
+	for range 2 {
+		if _, err := inventoryRepository.AddLoot(user.ID, equipment.ID, equipment.ItemType); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stack *repositories.UserInventoryEntity
+	for range 3 {
+		added, err := inventoryRepository.AddLoot(user.ID, consumable.ID, consumable.ItemType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stack = added
+	}
+	if stack.Quantity != 3 || !stack.IsStack {
+		t.Errorf("AddLoot() of a consumable three times = %+v, want one stack of 3", stack)
+	}
+
+	all, err := inventoryRepository.GetInventoryByUserId(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("GetInventoryByUserId() returned %d rows, want 2 equipment and 1 stack", len(all))
+	}
+	equipped, err := inventoryRepository.GetEquipmentByUserId(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(equipped) != 2 {
+		t.Errorf("GetEquipmentByUserId() returned %d rows, want 2", len(equipped))
+	}
+	consumables, err := inventoryRepository.GetConsumablesByUserId(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumables) != 1 || consumables[0].ID != stack.ID {
+		t.Errorf("GetConsumablesByUserId() = %v, want only the stack", consumables)
+	}
+
+	byItem, err := inventoryRepository.GetInventoryByUserAndItem(user.ID, consumable.ID)
+	if err != nil || byItem == nil || byItem.ID != stack.ID {
+		t.Errorf("GetInventoryByUserAndItem() = %v, %v, want the stack", byItem, err)
+	}
+	other := createUser(t, ds, "inventory-other")
+	missing, err := inventoryRepository.GetInventoryByUserAndItem(other.ID, consumable.ID)
+	if err != nil || missing != nil {
+		t.Errorf("GetInventoryByUserAndItem() for an item the user doesn't own = %v, %v, want nil, nil", missing, err)
+	}
+
+	// Using up a stack archives it, and the next drop starts a new one
+	for range 3 {
+		if err := inventoryRepository.ConsumeLoot(stack.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restarted, err := inventoryRepository.AddLoot(user.ID, consumable.ID, consumable.ItemType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.ID == stack.ID || restarted.Quantity != 1 {
+		t.Errorf("AddLoot() after using up a stack = %+v, want a new stack of 1", restarted)
+	}
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/morpheuszero/go-heimdall"
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+const migrationsPath = "../../../../migrations"
+
+// db is the pool for the migrated throwaway database, nil when the tests are skipped
+var db *sqlx.DB
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	serverURL := os.Getenv("TEST_DB_CONNECTION_STRING")
+	if serverURL == "" {
+		return m.Run()
+	}
+
+	name := fmt.Sprintf("parallax_test_%d", time.Now().UnixNano())
+	databaseURL, err := createDatabase(serverURL, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create test database:", err)
+		return 1
+	}
+	defer func() {
+		if err := dropDatabase(serverURL, name); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to drop test database:", err)
+		}
+	}()
+
+	h := heimdall.NewHeimdall(databaseURL, "migration_history", migrationsPath, false)
+	if err := h.RunMigrations(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to migrate test database:", err)
+		return 1
+	}
+
+	db, err = sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to test database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	return m.Run()
+}
+
+// createDatabase creates an empty database on the server and returns its URL
+func createDatabase(serverURL, name string) (string, error) {
+	server, err := sqlx.Connect("postgres", serverURL)
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+
+	if _, err := server.Exec(`CREATE DATABASE ` + pq.QuoteIdentifier(name)); err != nil {
+		return "", err
+	}
+
+	databaseURL, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	databaseURL.Path = "/" + name
+	return databaseURL.String(), nil
+}
+
+func dropDatabase(serverURL, name string) error {
+	server, err := sqlx.Connect("postgres", serverURL)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	_, err = server.Exec(`DROP DATABASE IF EXISTS ` + pq.QuoteIdentifier(name) + ` WITH (FORCE)`)
+	return err
+}
+
+// requireDatabase skips the test unless a Postgres server was configured
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if db == nil {
+		t.Skip("TEST_DB_CONNECTION_STRING is not set")
+	}
+}
+
+// beginTx starts a transaction that is rolled back when the test ends, so the test's
+// writes are never seen by other tests
+func beginTx(t *testing.T) (*database.AppDataSource, *sqlx.Tx) {
+	t.Helper()
+	requireDatabase(t)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = tx.Rollback()
+	})
+	return &database.AppDataSource{DB: tx}, tx
+}
+
+// newPool returns a data source on the connection pool, for tests that need several
+// connections at once. Its writes are committed, so those tests create their own users
+// and only look at rows they created.
+func newPool(t *testing.T) *database.AppDataSource {
+	t.Helper()
+	requireDatabase(t)
+	return &database.AppDataSource{DB: db}
+}
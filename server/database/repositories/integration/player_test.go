@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+func TestUserAdminQueries(t *testing.T) {
+	ds, _ := beginTx(t)
+	userRepository := repositories.NewUserRepository(ds)
+
+	active := createUser(t, ds, "admin-active")
+	archived := createUser(t, ds, "admin-archived")
+	archivedId := int(archived.ID)
+	if err := userRepository.ToggleUserArchived(&archivedId); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both users' display names start with "Integration admin-". They were created in
+	// the same transaction, so have the same created_at and no set order.
+	search := "Integration admin-"
+	for _, test := range []struct {
+		statusFilter string
+		want         []int64
+	}{
+		{"", []int64{active.ID, archived.ID}},
+		{"active", []int64{active.ID}},
+		{"archived", []int64{archived.ID}},
+	} {
+		count, err := userRepository.GetUsersCount(search, test.statusFilter, "")
+		if err != nil {
+			t.Fatalf("GetUsersCount(%q) error = %v", test.statusFilter, err)
+		}
+		if *count != len(test.want) {
+			t.Errorf("GetUsersCount(%q) = %d, want %d", test.statusFilter, *count, len(test.want))
+		}
+
+		users, err := userRepository.GetUsers(10, 0, search, test.statusFilter, "")
+		if err != nil {
+			t.Fatalf("GetUsers(%q) error = %v", test.statusFilter, err)
+		}
+		if ids := userIds(users); !slices.Equal(ids, test.want) {
+			t.Errorf("GetUsers(%q) = %v, want %v", test.statusFilter, ids, test.want)
+		}
+	}
+
+	firstPage, err := userRepository.GetUsers(1, 0, search, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondPage, err := userRepository.GetUsers(1, 1, search, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := userIds(append(firstPage, secondPage...)); !slices.Equal(ids, []int64{active.ID, archived.ID}) {
+		t.Errorf("GetUsers() pages of one = %v, want both users once", ids)
+	}
+}
+
+// userIds returns the users' ids in ascending order
+func userIds(users []*repositories.UserEntity) []int64 {
+	ids := []int64{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestUserUpdates(t *testing.T) {
+	ds, _ := beginTx(t)
+	userRepository := repositories.NewUserRepository(ds)
+	user := createUser(t, ds, "updates")
+	id := int(user.ID)
+
+	profileText := "Rift runner"
+	updated, err := userRepository.UpdateUser(&models.UserUpdateDTO{
+		Email:       user.Email,
+		DisplayName: "Renamed",
+		ProfileText: &profileText,
+		UserTypeKey: "admin",
+	}, &id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.DisplayName != "Renamed" || updated.ProfileText == nil || *updated.ProfileText != profileText {
+		t.Errorf("UpdateUser() = %+v, want the new display name and profile text", updated)
+	}
+
+	if _, err := userRepository.UpdateUserLastLogin(&id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userRepository.UpdateUserPassword(&id, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := userRepository.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.LastLogin == nil {
+		t.Error("UpdateUserLastLogin() should set last_login")
+	}
+	if reloaded.PasswordHash == nil || *reloaded.PasswordHash != "new-hash" {
+		t.Errorf("UpdateUserPassword() left the hash at %v", reloaded.PasswordHash)
+	}
+}
+
+func TestTeamUpdates(t *testing.T) {
+	ds, _ := beginTx(t)
+	teamRepository := repositories.NewTeamRepository(ds)
+	user := createUser(t, ds, "teams")
+	teams := createTeams(t, ds, user.ID)
+	locked := teams[1]
+
+	if locked.IsUnlocked {
+		t.Fatalf("CreateTeamsForUser() should only unlock the first team")
+	}
+	if err := teamRepository.UnlockTeam(locked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stat bonuses add up
+	for range 2 {
+		if err := teamRepository.UpdateTeamStats(locked.ID, 2.5, 1.5, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	team, err := teamRepository.GetTeamByIdForUpdate(locked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !team.IsUnlocked || team.SpeedBonus != locked.SpeedBonus+5 || team.LuckBonus != locked.LuckBonus+3 || team.PowerBonus != locked.PowerBonus+6 {
+		t.Errorf("GetTeamByIdForUpdate() = %+v, want the team unlocked with its bonuses raised", team)
+	}
+
+	set, err := teamRepository.SetSpecialization(team.ID, string(models.SpecializationScout))
+	if err != nil || !set {
+		t.Fatalf("SetSpecialization() = %v, %v, want true", set, err)
+	}
+	set, err = teamRepository.SetSpecialization(team.ID, string(models.SpecializationTank))
+	if err != nil || set {
+		t.Errorf("SetSpecialization() on a specialized team = %v, %v, want false", set, err)
+	}
+	team, err = teamRepository.GetTeamById(team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if team.Specialization != string(models.SpecializationScout) {
+		t.Errorf("specialization = %s, want %s", team.Specialization, models.SpecializationScout)
+	}
+}
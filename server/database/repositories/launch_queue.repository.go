@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+type ILaunchQueueRepository interface {
+	GetEntriesByTeamId(teamId int64) ([]*LaunchQueueEntryEntity, error)
+	AddEntry(userId, teamId, riftId int64, repeatCount *int) (*LaunchQueueEntryEntity, error)
+	ConsumeEntry(entryId int64) error
+	RemoveEntry(entryId int64) error
+	ClearTeamQueue(teamId int64) error
+	GetIdleQueuedTeamIds(limit int) ([]int64, error)
+	MarkLaunchAttempted(teamId int64) error
+	WithTx(tx *database.AppDataSource) ILaunchQueueRepository
+}
+
+type LaunchQueueRepository struct {
+	db *database.AppDataSource
+}
+
+func NewLaunchQueueRepository(db *database.AppDataSource) ILaunchQueueRepository {
+	return &LaunchQueueRepository{
+		db: db,
+	}
+}
+
+// GetEntriesByTeamId returns the team's queued launches in the order they will run
+func (r *LaunchQueueRepository) GetEntriesByTeamId(teamId int64) ([]*LaunchQueueEntryEntity, error) {
+	entries := []*LaunchQueueEntryEntity{}
+	sql := `SELECT * FROM team_launch_queue
+			WHERE team_id = $1 AND is_archived = false
+			ORDER BY position, id`
+	err := r.db.DB.Select(&entries, sql, teamId)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AddEntry appends a launch to the end of the team's queue
+func (r *LaunchQueueRepository) AddEntry(userId, teamId, riftId int64, repeatCount *int) (*LaunchQueueEntryEntity, error) {
+	entry := &LaunchQueueEntryEntity{}
+	sql := `INSERT INTO team_launch_queue (user_id, team_id, rift_id, position, repeat_count)
+			VALUES ($1, $2, $3, (
+				SELECT COALESCE(MAX(position), 0) + 1 FROM team_launch_queue
+				WHERE team_id = $2 AND is_archived = false
+			), $4)
+			RETURNING *`
+	err := r.db.DB.Get(entry, sql, userId, teamId, riftId, repeatCount)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ConsumeEntry records one launch of an entry. Entries with a repeat count are archived
+// once their last launch is used up, entries that repeat until stopped are left alone.
+func (r *LaunchQueueRepository) ConsumeEntry(entryId int64) error {
+	sql := `UPDATE team_launch_queue
+			SET repeat_count = repeat_count - 1, is_archived = (repeat_count <= 1), modified_at = NOW()
+			WHERE id = $1 AND repeat_count IS NOT NULL AND is_archived = false`
+	_, err := r.db.DB.Exec(sql, entryId)
+	return err
+}
+
+func (r *LaunchQueueRepository) RemoveEntry(entryId int64) error {
+	sql := `UPDATE team_launch_queue SET is_archived = true, modified_at = NOW() WHERE id = $1`
+	_, err := r.db.DB.Exec(sql, entryId)
+	return err
+}
+
+func (r *LaunchQueueRepository) ClearTeamQueue(teamId int64) error {
+	sql := `UPDATE team_launch_queue SET is_archived = true, modified_at = NOW()
+			WHERE team_id = $1 AND is_archived = false`
+	_, err := r.db.DB.Exec(sql, teamId)
+	return err
+}
+
+// GetIdleQueuedTeamIds returns up to limit teams that have queued launches and are not
+// out on an unprocessed expedition, so their next launch can start. Teams that have never
+// been tried come first, then the ones tried longest ago, so teams that can't launch
+// don't hold up the rest.
+func (r *LaunchQueueRepository) GetIdleQueuedTeamIds(limit int) ([]int64, error) {
+	ids := []int64{}
+	sql := `SELECT q.team_id FROM team_launch_queue q
+			WHERE q.is_archived = false
+			AND NOT EXISTS (
+				SELECT 1 FROM expeditions e
+				WHERE e.team_id = q.team_id AND e.processed = false AND e.is_archived = false
+			)
+			GROUP BY q.team_id
+			ORDER BY MAX(q.attempted_at) NULLS FIRST, q.team_id
+			LIMIT $1`
+	err := r.db.DB.Select(&ids, sql, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// MarkLaunchAttempted records that the processor tried to launch the team's queue, which
+// sends the team to the back of GetIdleQueuedTeamIds
+func (r *LaunchQueueRepository) MarkLaunchAttempted(teamId int64) error {
+	sql := `UPDATE team_launch_queue SET attempted_at = NOW(), modified_at = NOW()
+			WHERE team_id = $1 AND is_archived = false`
+	_, err := r.db.DB.Exec(sql, teamId)
+	return err
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *LaunchQueueRepository) WithTx(tx *database.AppDataSource) ILaunchQueueRepository {
+	return &LaunchQueueRepository{
+		db: tx,
+	}
+}
@@ -15,10 +15,14 @@ type LeaderboardCacheEntity struct {
 	UpdatedAt       time.Time `db:"updated_at"`
 }
 
+// LeaderboardCacheItemEntity is one ranked entry. Player leaderboards rank users and
+// guild leaderboards rank guilds, so exactly one of UserID and GuildID is set and the
+// other is 0. Username holds the guild's name for guild entries.
 type LeaderboardCacheItemEntity struct {
 	ID              int       `db:"id"`
 	LeaderboardType string    `db:"leaderboard_type"`
 	UserID          int       `db:"user_id"`
+	GuildID         int       `db:"guild_id"`
 	Username        string    `db:"username"`
 	Score           int64     `db:"score"`
 	Rank            int       `db:"rank"`
@@ -34,6 +38,7 @@ type ILeaderboardRepository interface {
 	// Cache items
 	GetTopRankings(leaderboardType string, limit int) ([]*LeaderboardCacheItemEntity, error)
 	GetUserRank(leaderboardType string, userID int) (*LeaderboardCacheItemEntity, error)
+	GetUserGuildRank(leaderboardType string, userID int) (*LeaderboardCacheItemEntity, error)
 	TruncateCache(leaderboardType string) error
 	InsertCacheItems(items []*LeaderboardCacheItemEntity) error
 
@@ -41,6 +46,8 @@ type ILeaderboardRepository interface {
 	GetLegendaryItemCounts() ([]*LeaderboardCacheItemEntity, error)
 	GetPowerScores() ([]*LeaderboardCacheItemEntity, error)
 	GetExpeditionCounts() ([]*LeaderboardCacheItemEntity, error)
+	GetEchoEncounterCounts() ([]*LeaderboardCacheItemEntity, error)
+	GetGuildPowerScores() ([]*LeaderboardCacheItemEntity, error)
 }
 
 type LeaderboardRepository struct {
@@ -95,7 +102,8 @@ func (r *LeaderboardRepository) UpdateLastSynced(leaderboardType string) error {
 // GetTopRankings retrieves the top N ranked players for a leaderboard type
 func (r *LeaderboardRepository) GetTopRankings(leaderboardType string, limit int) ([]*LeaderboardCacheItemEntity, error) {
 	items := []*LeaderboardCacheItemEntity{}
-	sql := `SELECT id, leaderboard_type, user_id, username, score, rank, created_at
+	sql := `SELECT id, leaderboard_type, COALESCE(user_id, 0) as user_id, COALESCE(guild_id, 0) as guild_id,
+	        username, score, rank, created_at
 	        FROM leaderboard_cache_items
 	        WHERE leaderboard_type = $1
 	        ORDER BY rank ASC
@@ -110,7 +118,7 @@ func (r *LeaderboardRepository) GetTopRankings(leaderboardType string, limit int
 // GetUserRank retrieves the rank entry for a specific user on a leaderboard type
 func (r *LeaderboardRepository) GetUserRank(leaderboardType string, userID int) (*LeaderboardCacheItemEntity, error) {
 	entity := &LeaderboardCacheItemEntity{}
-	sql := `SELECT id, leaderboard_type, user_id, username, score, rank, created_at
+	sql := `SELECT id, leaderboard_type, user_id, 0 as guild_id, username, score, rank, created_at
 	        FROM leaderboard_cache_items
 	        WHERE leaderboard_type = $1 AND user_id = $2`
 	err := r.db.DB.Get(entity, sql, leaderboardType, userID)
@@ -120,6 +128,20 @@ func (r *LeaderboardRepository) GetUserRank(leaderboardType string, userID int)
 	return entity, nil
 }
 
+// GetUserGuildRank retrieves the rank entry of the guild a user is in on a guild leaderboard type
+func (r *LeaderboardRepository) GetUserGuildRank(leaderboardType string, userID int) (*LeaderboardCacheItemEntity, error) {
+	entity := &LeaderboardCacheItemEntity{}
+	sql := `SELECT lci.id, lci.leaderboard_type, 0 as user_id, lci.guild_id, lci.username, lci.score, lci.rank, lci.created_at
+	        FROM leaderboard_cache_items lci
+	        JOIN guild_members gm ON gm.guild_id = lci.guild_id AND gm.is_archived = false
+	        WHERE lci.leaderboard_type = $1 AND gm.user_id = $2`
+	err := r.db.DB.Get(entity, sql, leaderboardType, userID)
+	if err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
 // TruncateCache removes all cache items for a specific leaderboard type
 func (r *LeaderboardRepository) TruncateCache(leaderboardType string) error {
 	sql := `DELETE FROM leaderboard_cache_items WHERE leaderboard_type = $1`
@@ -133,8 +155,9 @@ func (r *LeaderboardRepository) InsertCacheItems(items []*LeaderboardCacheItemEn
 		return nil
 	}
 
-	sql := `INSERT INTO leaderboard_cache_items (leaderboard_type, user_id, username, score, rank)
-	        VALUES (:leaderboard_type, :user_id, :username, :score, :rank)
+	// Whichever of user_id and guild_id doesn't apply is 0, and stored as NULL
+	sql := `INSERT INTO leaderboard_cache_items (leaderboard_type, user_id, guild_id, username, score, rank)
+	        VALUES (:leaderboard_type, NULLIF(:user_id, 0), NULLIF(:guild_id, 0), :username, :score, :rank)
 	        ON CONFLICT (leaderboard_type, user_id) DO UPDATE SET
 	            score = EXCLUDED.score,
 	            rank = EXCLUDED.rank`
@@ -154,13 +177,13 @@ func (r *LeaderboardRepository) GetLegendaryItemCounts() ([]*LeaderboardCacheIte
 	sql := `SELECT
 	        u.id as user_id,
 	        u.display_name as username,
-	        COALESCE(SUM(ui.quantity), 0)::bigint as score
+	        COALESCE(SUM(ui.quantity) FILTER (WHERE li.rarity = 'legendary'), 0)::bigint as score
 	        FROM users u
 	        LEFT JOIN user_inventory ui ON ui.user_id = u.id
-	        LEFT JOIN loot_items li ON li.id = ui.loot_item_id AND li.rarity = 'legendary'
+	        LEFT JOIN loot_items li ON li.id = ui.loot_item_id
 	        WHERE u.is_archived = false
 	        GROUP BY u.id, u.display_name
-	        HAVING COALESCE(SUM(ui.quantity), 0) > 0
+	        HAVING COALESCE(SUM(ui.quantity) FILTER (WHERE li.rarity = 'legendary'), 0) > 0
 	        ORDER BY score DESC`
 
 	err := r.db.DB.Select(&items, sql)
@@ -170,8 +193,28 @@ func (r *LeaderboardRepository) GetLegendaryItemCounts() ([]*LeaderboardCacheIte
 	return items, nil
 }
 
+// PowerScoreRarityWeights is how much one item of each rarity adds to a user's power score.
+// Keep in sync with powerScoreWeightCase.
+var PowerScoreRarityWeights = map[string]int64{
+	"common":    1,
+	"uncommon":  5,
+	"rare":      25,
+	"epic":      125,
+	"legendary": 1000,
+}
+
+// powerScoreWeightCase is PowerScoreRarityWeights for the loot item aliased li
+const powerScoreWeightCase = `CASE li.rarity
+	                WHEN 'common' THEN 1
+	                WHEN 'uncommon' THEN 5
+	                WHEN 'rare' THEN 25
+	                WHEN 'epic' THEN 125
+	                WHEN 'legendary' THEN 1000
+	                ELSE 0
+	            END`
+
 // GetPowerScores calculates weighted inventory value per user
-// Rarity weights: Common=1, Uncommon=5, Rare=25, Epic=125, Legendary=1000
+// Rarity weights are PowerScoreRarityWeights
 // Returns users sorted by score descending
 func (r *LeaderboardRepository) GetPowerScores() ([]*LeaderboardCacheItemEntity, error) {
 	items := []*LeaderboardCacheItemEntity{}
@@ -180,14 +223,7 @@ func (r *LeaderboardRepository) GetPowerScores() ([]*LeaderboardCacheItemEntity,
 	        u.display_name as username,
 	        COALESCE(SUM(
 	            ui.quantity *
-	            CASE li.rarity
-	                WHEN 'common' THEN 1
-	                WHEN 'uncommon' THEN 5
-	                WHEN 'rare' THEN 25
-	                WHEN 'epic' THEN 125
-	                WHEN 'legendary' THEN 1000
-	                ELSE 0
-	            END
+	            ` + powerScoreWeightCase + `
 	        ), 0)::bigint as score
 	        FROM users u
 	        LEFT JOIN user_inventory ui ON ui.user_id = u.id
@@ -204,7 +240,7 @@ func (r *LeaderboardRepository) GetPowerScores() ([]*LeaderboardCacheItemEntity,
 	return items, nil
 }
 
-// GetExpeditionCounts calculates total completed expeditions per user
+// GetExpeditionCounts calculates total completed expeditions per user (recalled runs don't count)
 // Returns users sorted by count descending
 func (r *LeaderboardRepository) GetExpeditionCounts() ([]*LeaderboardCacheItemEntity, error) {
 	items := []*LeaderboardCacheItemEntity{}
@@ -213,7 +249,7 @@ func (r *LeaderboardRepository) GetExpeditionCounts() ([]*LeaderboardCacheItemEn
 	        u.display_name as username,
 	        COUNT(e.id)::bigint as score
 	        FROM users u
-	        LEFT JOIN expeditions e ON e.user_id = u.id AND e.completed = true AND e.is_archived = false
+	        LEFT JOIN expeditions e ON e.user_id = u.id AND e.status = 'completed' AND e.is_archived = false
 	        WHERE u.is_archived = false
 	        GROUP BY u.id, u.display_name
 	        HAVING COUNT(e.id) > 0
@@ -225,3 +261,61 @@ func (r *LeaderboardRepository) GetExpeditionCounts() ([]*LeaderboardCacheItemEn
 	}
 	return items, nil
 }
+
+// GetEchoEncounterCounts calculates how many echo encounters each user has been part of,
+// on either side of the encounter
+// Returns users sorted by count descending
+func (r *LeaderboardRepository) GetEchoEncounterCounts() ([]*LeaderboardCacheItemEntity, error) {
+	items := []*LeaderboardCacheItemEntity{}
+	sql := `SELECT
+	        u.id as user_id,
+	        u.display_name as username,
+	        COUNT(ee.id)::bigint as score
+	        FROM users u
+	        LEFT JOIN echo_encounters ee ON (ee.user_id_1 = u.id OR ee.user_id_2 = u.id) AND ee.is_archived = false
+	        WHERE u.is_archived = false
+	        GROUP BY u.id, u.display_name
+	        HAVING COUNT(ee.id) > 0
+	        ORDER BY score DESC`
+
+	err := r.db.DB.Select(&items, sql)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetGuildPowerScores calculates the combined power score of each guild's active members
+// and its treasury. Deposits move items from a member to the treasury, so they don't
+// change the guild's score.
+// Returns guilds sorted by score descending
+func (r *LeaderboardRepository) GetGuildPowerScores() ([]*LeaderboardCacheItemEntity, error) {
+	items := []*LeaderboardCacheItemEntity{}
+	sql := `SELECT
+	        g.id as guild_id,
+	        g.name as username,
+	        SUM(scores.score)::bigint as score
+	        FROM (
+	            SELECT gm.guild_id, ui.quantity * ` + powerScoreWeightCase + ` as score
+	            FROM guild_members gm
+	            JOIN users u ON u.id = gm.user_id AND u.is_archived = false
+	            JOIN user_inventory ui ON ui.user_id = gm.user_id AND ui.is_archived = false
+	            JOIN loot_items li ON li.id = ui.loot_item_id
+	            WHERE gm.is_archived = false
+	            UNION ALL
+	            SELECT gt.guild_id, gt.quantity * ` + powerScoreWeightCase + ` as score
+	            FROM guild_treasury gt
+	            JOIN loot_items li ON li.id = gt.loot_item_id
+	            WHERE gt.is_archived = false
+	        ) scores
+	        JOIN guilds g ON g.id = scores.guild_id AND g.is_archived = false
+	        GROUP BY g.id, g.name
+	        HAVING SUM(scores.score) > 0
+	        ORDER BY score DESC`
+
+	err := r.db.DB.Select(&items, sql)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,32 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/memory"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) *repositorytest.Repositories {
+		store := memory.NewStore()
+		return &repositorytest.Repositories{
+			Users:          memory.NewUserRepository(store),
+			FeatureFlags:   memory.NewFeatureFlagRepository(store),
+			Teams:          memory.NewTeamRepository(store),
+			Inventory:      memory.NewUserInventoryRepository(store),
+			Expeditions:    memory.NewExpeditionRepository(store),
+			LaunchQueue:    memory.NewLaunchQueueRepository(store),
+			Leaderboards:   memory.NewLeaderboardRepository(store),
+			Notifications:  memory.NewNotificationRepository(store),
+			EchoEncounters: memory.NewEchoEncounterRepository(store),
+			Guilds:         memory.NewGuildRepository(store),
+			Trades:         memory.NewTradeRepository(store),
+			Sessions:       memory.NewUserSessionRepository(store),
+			UsedTokens:     memory.NewUsedTokenRepository(store),
+			LoginHistory:   memory.NewUserLoginHistoryRepository(store),
+			TwoFactor:      memory.NewUserTwoFactorRepository(store),
+			Seeder:         store,
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type EchoEncounterRepository struct {
+	store *Store
+}
+
+func NewEchoEncounterRepository(store *Store) repositories.IEchoEncounterRepository {
+	return &EchoEncounterRepository{
+		store: store,
+	}
+}
+
+// GetEchoExpedition returns the most recent expedition another active player completed in
+// the rift within the last withinHours by the store's clock, or nil if there is none.
+// Expeditions that are already part of an encounter are skipped.
+func (r *EchoEncounterRepository) GetEchoExpedition(userId, riftId int64, withinHours int) (*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	since := r.store.now().Add(-time.Duration(withinHours) * time.Hour)
+	echoes := selectRows(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.UserID != userId && expedition.RiftID == riftId &&
+			expedition.Status == string(models.ExpeditionStatusCompleted) && !expedition.IsArchived &&
+			!completedAt(expedition).Before(since) && r.isActiveUser(expedition.UserID) && !r.isEchoed(expedition.ID)
+	})
+	if len(echoes) == 0 {
+		return nil, nil
+	}
+	sortRows(echoes, func(a, b *repositories.ExpeditionEntity) bool {
+		if completedAt(a).Equal(completedAt(b)) {
+			return a.ID > b.ID
+		}
+		return completedAt(a).After(completedAt(b))
+	})
+	return echoes[0], nil
+}
+
+// CreateEchoEncounter fails if the echoed expedition already left an echo, the same as
+// idx_echo_encounters_echoed
+func (r *EchoEncounterRepository) CreateEchoEncounter(expeditionId1, expeditionId2, userId1, userId2, riftId int64) (*repositories.EchoEncounterEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if findRow(r.store.echoEncounters, func(encounter *repositories.EchoEncounterEntity) bool {
+		return encounter.ExpeditionID2 == expeditionId2
+	}) != nil {
+		return nil, fmt.Errorf("expedition %d has already been echoed", expeditionId2)
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	encounter := &repositories.EchoEncounterEntity{
+		ID:            r.store.nextId("echo_encounters"),
+		CreatedAt:     createdAt,
+		ModifiedAt:    modifiedAt,
+		ExpeditionID1: expeditionId1,
+		ExpeditionID2: expeditionId2,
+		UserID1:       userId1,
+		UserID2:       userId2,
+		RiftID:        riftId,
+		OccurredAt:    createdAt,
+	}
+	r.store.echoEncounters = append(r.store.echoEncounters, encounter)
+	return clone(encounter), nil
+}
+
+func (r *EchoEncounterRepository) CreateEchoEncounterLoot(encounterId, userId, lootItemId int64, quantity int) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	createdAt, modifiedAt := r.store.timestamp()
+	r.store.echoEncounterLoot = append(r.store.echoEncounterLoot, &repositories.EchoEncounterLootEntity{
+		ID:              r.store.nextId("echo_encounter_loot"),
+		CreatedAt:       createdAt,
+		ModifiedAt:      modifiedAt,
+		EchoEncounterID: encounterId,
+		UserID:          userId,
+		LootItemID:      lootItemId,
+		Quantity:        quantity,
+	})
+	return nil
+}
+
+func (r *EchoEncounterRepository) GetEchoEncounterLoot(encounterId int64) ([]*repositories.EchoEncounterLootEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return selectRows(r.store.echoEncounterLoot, func(loot *repositories.EchoEncounterLootEntity) bool {
+		return loot.EchoEncounterID == encounterId && !loot.IsArchived
+	}), nil
+}
+
+func (r *EchoEncounterRepository) GetEchoEncounterCount(userId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.store.countEchoEncounters(userId), nil
+}
+
+func (r *EchoEncounterRepository) WithTx(tx *database.AppDataSource) repositories.IEchoEncounterRepository {
+	return r
+}
+
+// isActiveUser reports whether the user exists and isn't archived. Must be called with the mutex held.
+func (r *EchoEncounterRepository) isActiveUser(userId int64) bool {
+	return findRow(r.store.users, func(user *repositories.UserEntity) bool {
+		return user.ID == userId && !user.IsArchived
+	}) != nil
+}
+
+// isEchoed reports whether the expedition is on either side of an encounter. Must be called with the mutex held.
+func (r *EchoEncounterRepository) isEchoed(expeditionId int64) bool {
+	return findRow(r.store.echoEncounters, func(encounter *repositories.EchoEncounterEntity) bool {
+		return encounter.ExpeditionID1 == expeditionId || encounter.ExpeditionID2 == expeditionId
+	}) != nil
+}
+
+// completedAt is when the expedition's timer ran out
+func completedAt(expedition *repositories.ExpeditionEntity) time.Time {
+	return expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
+}
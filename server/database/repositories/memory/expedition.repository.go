@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type ExpeditionRepository struct {
+	store *Store
+}
+
+func NewExpeditionRepository(store *Store) repositories.IExpeditionRepository {
+	return &ExpeditionRepository{
+		store: store,
+	}
+}
+
+// CreateExpedition returns repositories.ErrTeamHasActiveExpedition if the team already has an
+// unclaimed expedition, the same as idx_expeditions_one_active_per_team
+func (r *ExpeditionRepository) CreateExpedition(userId, teamId, riftId int64, durationMinutes, effectivePower, recommendedPower int, lootLuck float64, lootSeed int64) (*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if r.findActiveByTeam(teamId) != nil {
+		return nil, repositories.ErrTeamHasActiveExpedition
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	expedition := &repositories.ExpeditionEntity{
+		ID:               r.store.nextId("expeditions"),
+		CreatedAt:        createdAt,
+		ModifiedAt:       modifiedAt,
+		UserID:           userId,
+		TeamID:           teamId,
+		RiftID:           riftId,
+		StartTime:        createdAt,
+		DurationMinutes:  durationMinutes,
+		EffectivePower:   effectivePower,
+		RecommendedPower: recommendedPower,
+		LootLuck:         &lootLuck,
+		Status:           string(models.ExpeditionStatusActive),
+		LootSeed:         lootSeed,
+	}
+	r.store.expeditions = append(r.store.expeditions, expedition)
+	return clone(expedition), nil
+}
+
+func (r *ExpeditionRepository) GetExpeditionById(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expedition := r.findExpedition(expeditionId)
+	if expedition == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(expedition), nil
+}
+
+func (r *ExpeditionRepository) GetActiveExpeditionsByUserId(userId int64) ([]*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expeditions := selectRows(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.UserID == userId && !expedition.Claimed && !expedition.IsArchived
+	})
+	sortRows(expeditions, newestFirst)
+	return expeditions, nil
+}
+
+// GetActiveExpeditionByTeamId returns the team's unclaimed expedition, or nil if the team is free
+func (r *ExpeditionRepository) GetActiveExpeditionByTeamId(teamId int64) (*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expedition := r.findActiveByTeam(teamId)
+	if expedition == nil {
+		return nil, nil
+	}
+	return clone(expedition), nil
+}
+
+func (r *ExpeditionRepository) GetFinishedExpeditionsByUserId(userId int64, limit int) ([]*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expeditions := selectRows(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		finished := expedition.Status == string(models.ExpeditionStatusCompleted) ||
+			expedition.Status == string(models.ExpeditionStatusRecalled)
+		return expedition.UserID == userId && finished && !expedition.IsArchived
+	})
+	sortRows(expeditions, newestFirst)
+	return expeditions[:min(limit, len(expeditions))], nil
+}
+
+func (r *ExpeditionRepository) GetCompletedExpeditionsCount(userId int64) (int, error) {
+	return r.countCompleted(func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.UserID == userId
+	}), nil
+}
+
+func (r *ExpeditionRepository) GetCompletedExpeditionsCountByRift(userId, riftId int64) (int, error) {
+	return r.countCompleted(func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.UserID == userId && expedition.RiftID == riftId
+	}), nil
+}
+
+func (r *ExpeditionRepository) MarkCompleted(expeditionId int64) error {
+	return r.updateExpedition(expeditionId, func(expedition *repositories.ExpeditionEntity, now time.Time) {
+		expedition.Completed = true
+		expedition.Status = string(models.ExpeditionStatusCompleted)
+	})
+}
+
+func (r *ExpeditionRepository) MarkProcessed(expeditionId int64, partialFailure bool) error {
+	return r.updateExpedition(expeditionId, func(expedition *repositories.ExpeditionEntity, now time.Time) {
+		expedition.Processed = true
+		expedition.PartialFailure = partialFailure
+	})
+}
+
+// MarkClaimed also completes the expedition if its timer has run out, using the store's clock
+func (r *ExpeditionRepository) MarkClaimed(expeditionId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expedition := r.findExpedition(expeditionId)
+	if expedition == nil {
+		return sql.ErrNoRows
+	}
+
+	now := r.store.now()
+	completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
+	if now.After(completionTime) && !expedition.Completed {
+		expedition.Completed = true
+		expedition.Status = string(models.ExpeditionStatusCompleted)
+	}
+	expedition.Claimed = true
+	expedition.ModifiedAt = &now
+	return nil
+}
+
+func (r *ExpeditionRepository) MarkRecalled(expeditionId int64) error {
+	return r.updateExpedition(expeditionId, func(expedition *repositories.ExpeditionEntity, now time.Time) {
+		expedition.Status = string(models.ExpeditionStatusRecalled)
+		expedition.Processed = true
+		expedition.Claimed = true
+		expedition.RecalledAt = &now
+	})
+}
+
+func (r *ExpeditionRepository) SaveLootRollInputs(expeditionId int64, luck, lootMultiplier float64) error {
+	return r.updateExpedition(expeditionId, func(expedition *repositories.ExpeditionEntity, now time.Time) {
+		expedition.LootLuck = &luck
+		expedition.LootMultiplier = &lootMultiplier
+	})
+}
+
+// GetDueExpeditionIds returns up to limit expeditions whose timers have run out by the
+// store's clock but have not been processed yet, leaving out skipIds. Expeditions that
+// have never failed to process come first, oldest first, then the ones whose last
+// failure was longest ago.
+func (r *ExpeditionRepository) GetDueExpeditionIds(limit int, skipIds []int64) ([]int64, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	due := selectRows(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
+		return r.isUnprocessed(expedition) && !completionTime.After(now) && !slices.Contains(skipIds, expedition.ID)
+	})
+	sortRows(due, func(a, b *repositories.ExpeditionEntity) bool {
+		if (a.ProcessFailedAt == nil) != (b.ProcessFailedAt == nil) {
+			return a.ProcessFailedAt == nil
+		}
+		if a.ProcessFailedAt != nil && !a.ProcessFailedAt.Equal(*b.ProcessFailedAt) {
+			return a.ProcessFailedAt.Before(*b.ProcessFailedAt)
+		}
+		return a.StartTime.Before(b.StartTime)
+	})
+
+	ids := []int64{}
+	for _, expedition := range due[:min(limit, len(due))] {
+		ids = append(ids, expedition.ID)
+	}
+	return ids, nil
+}
+
+func (r *ExpeditionRepository) MarkProcessFailed(expeditionId int64) error {
+	return r.updateExpedition(expeditionId, func(expedition *repositories.ExpeditionEntity, now time.Time) {
+		expedition.ProcessFailedAt = &now
+	})
+}
+
+// LockDueExpeditionById returns nil for expeditions that are already processed. There are
+// no row locks, the UnitOfWork keeps processors from racing.
+func (r *ExpeditionRepository) LockDueExpeditionById(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expedition := findRow(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.ID == expeditionId && r.isUnprocessed(expedition)
+	})
+	if expedition == nil {
+		return nil, nil
+	}
+	return clone(expedition), nil
+}
+
+func (r *ExpeditionRepository) GetExpeditionByIdForUpdate(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	return r.GetExpeditionById(expeditionId)
+}
+
+func (r *ExpeditionRepository) WithTx(tx *database.AppDataSource) repositories.IExpeditionRepository {
+	return r
+}
+
+// findExpedition returns the stored row for an unarchived expedition. Must be called with the mutex held.
+func (r *ExpeditionRepository) findExpedition(expeditionId int64) *repositories.ExpeditionEntity {
+	return findRow(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.ID == expeditionId && !expedition.IsArchived
+	})
+}
+
+// findActiveByTeam returns the stored row for the team's unclaimed expedition. Must be called with the mutex held.
+func (r *ExpeditionRepository) findActiveByTeam(teamId int64) *repositories.ExpeditionEntity {
+	return findRow(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.TeamID == teamId && !expedition.Claimed && !expedition.IsArchived
+	})
+}
+
+func (r *ExpeditionRepository) isUnprocessed(expedition *repositories.ExpeditionEntity) bool {
+	return !expedition.Completed && !expedition.Processed && !expedition.IsArchived
+}
+
+func (r *ExpeditionRepository) countCompleted(match func(expedition *repositories.ExpeditionEntity) bool) int {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	count := 0
+	for _, expedition := range r.store.expeditions {
+		if expedition.Completed && !expedition.IsArchived && match(expedition) {
+			count++
+		}
+	}
+	return count
+}
+
+// updateExpedition applies update to an expedition row. Like an UPDATE, a missing expedition is not an error.
+func (r *ExpeditionRepository) updateExpedition(expeditionId int64, update func(expedition *repositories.ExpeditionEntity, now time.Time)) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	expedition := findRow(r.store.expeditions, func(expedition *repositories.ExpeditionEntity) bool {
+		return expedition.ID == expeditionId
+	})
+	if expedition == nil {
+		return nil
+	}
+	now := r.store.now()
+	update(expedition, now)
+	expedition.ModifiedAt = &now
+	return nil
+}
+
+func newestFirst(a, b *repositories.ExpeditionEntity) bool {
+	return a.StartTime.After(b.StartTime)
+}
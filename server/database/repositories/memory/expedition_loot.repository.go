@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type ExpeditionLootRepository struct {
+	store *Store
+}
+
+func NewExpeditionLootRepository(store *Store) repositories.IExpeditionLootRepository {
+	return &ExpeditionLootRepository{
+		store: store,
+	}
+}
+
+func (r *ExpeditionLootRepository) CreateExpeditionLoot(expeditionId, lootItemId int64, quantity int) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	createdAt, modifiedAt := r.store.timestamp()
+	r.store.expeditionLoot = append(r.store.expeditionLoot, &repositories.ExpeditionLootEntity{
+		ID:           r.store.nextId("expedition_loot"),
+		CreatedAt:    createdAt,
+		ModifiedAt:   modifiedAt,
+		ExpeditionID: expeditionId,
+		LootItemID:   lootItemId,
+		Quantity:     quantity,
+	})
+	return nil
+}
+
+func (r *ExpeditionLootRepository) GetLootByExpeditionId(expeditionId int64) ([]*repositories.ExpeditionLootEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return selectRows(r.store.expeditionLoot, func(loot *repositories.ExpeditionLootEntity) bool {
+		return loot.ExpeditionID == expeditionId && !loot.IsArchived
+	}), nil
+}
+
+func (r *ExpeditionLootRepository) CreateLootRolls(expeditionId int64, rolls []*repositories.ExpeditionLootRollEntity) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, roll := range rolls {
+		row := clone(roll)
+		row.ID = r.store.nextId("expedition_loot_rolls")
+		row.CreatedAt, row.ModifiedAt = r.store.timestamp()
+		row.ExpeditionID = expeditionId
+		r.store.lootRolls = append(r.store.lootRolls, row)
+	}
+	return nil
+}
+
+func (r *ExpeditionLootRepository) GetLootRollsByExpeditionId(expeditionId int64) ([]*repositories.ExpeditionLootRollEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rolls := selectRows(r.store.lootRolls, func(roll *repositories.ExpeditionLootRollEntity) bool {
+		return roll.ExpeditionID == expeditionId && !roll.IsArchived
+	})
+	sortRows(rolls, func(a, b *repositories.ExpeditionLootRollEntity) bool {
+		return a.RollOrder < b.RollOrder
+	})
+	return rolls, nil
+}
+
+func (r *ExpeditionLootRepository) WithTx(tx *database.AppDataSource) repositories.IExpeditionLootRepository {
+	return r
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type FeatureFlagRepository struct {
+	store *Store
+}
+
+func NewFeatureFlagRepository(store *Store) repositories.IFeatureFlagRepository {
+	return &FeatureFlagRepository{
+		store: store,
+	}
+}
+
+// GetAllFlags retrieves all non-archived feature flags ordered by key
+func (r *FeatureFlagRepository) GetAllFlags() ([]*repositories.FeatureFlagEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	flags := selectRows(r.store.featureFlags, func(flag *repositories.FeatureFlagEntity) bool {
+		return !flag.IsArchived
+	})
+	sortRows(flags, func(a, b *repositories.FeatureFlagEntity) bool {
+		return a.Key < b.Key
+	})
+	return flags, nil
+}
+
+// GetFlagByKey returns nil, nil if the flag doesn't exist
+func (r *FeatureFlagRepository) GetFlagByKey(key string) (*repositories.FeatureFlagEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	flag := r.findFlag(key)
+	if flag == nil {
+		return nil, nil
+	}
+	return clone(flag), nil
+}
+
+func (r *FeatureFlagRepository) CreateFlag(key string, enabled bool, description string) (*repositories.FeatureFlagEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// feature_flags.key is UNIQUE, archived flags included
+	existing := findRow(r.store.featureFlags, func(flag *repositories.FeatureFlagEntity) bool {
+		return flag.Key == key
+	})
+	if existing != nil {
+		return nil, fmt.Errorf("feature flag %s already exists", key)
+	}
+
+	flag := &repositories.FeatureFlagEntity{
+		ID:          r.store.nextId("feature_flags"),
+		CreatedAt:   r.store.now(),
+		Key:         key,
+		Enabled:     enabled,
+		Description: description,
+	}
+	r.store.featureFlags = append(r.store.featureFlags, flag)
+	return clone(flag), nil
+}
+
+// UpdateFlag returns nil, nil if the flag doesn't exist
+func (r *FeatureFlagRepository) UpdateFlag(key string, enabled bool, description string) (*repositories.FeatureFlagEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	flag := r.findFlag(key)
+	if flag == nil {
+		return nil, nil
+	}
+	flag.Enabled = enabled
+	flag.Description = description
+	_, flag.ModifiedAt = r.store.timestamp()
+	return clone(flag), nil
+}
+
+// ArchiveFlag archives a feature flag (soft delete)
+func (r *FeatureFlagRepository) ArchiveFlag(key string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, flag := range r.store.featureFlags {
+		if flag.Key == key {
+			flag.IsArchived = true
+			_, flag.ModifiedAt = r.store.timestamp()
+		}
+	}
+	return nil
+}
+
+// findFlag returns the stored row for an unarchived flag. Must be called with the mutex held.
+func (r *FeatureFlagRepository) findFlag(key string) *repositories.FeatureFlagEntity {
+	return findRow(r.store.featureFlags, func(flag *repositories.FeatureFlagEntity) bool {
+		return flag.Key == key && !flag.IsArchived
+	})
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"database/sql"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+// The static game data tables (rifts, loot items, drop tables, unlock rules and upgrade
+// recipes) are read-only through their repositories and are filled with the Store's Add methods.
+
+type RiftRepository struct {
+	store *Store
+}
+
+func NewRiftRepository(store *Store) repositories.IRiftRepository {
+	return &RiftRepository{
+		store: store,
+	}
+}
+
+func (r *RiftRepository) GetAllRifts() ([]*repositories.RiftEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rifts := selectRows(r.store.rifts, func(rift *repositories.RiftEntity) bool {
+		return !rift.IsArchived
+	})
+	sortRows(rifts, func(a, b *repositories.RiftEntity) bool {
+		if a.Difficulty != b.Difficulty {
+			return difficultyOrder[a.Difficulty] < difficultyOrder[b.Difficulty]
+		}
+		return a.DurationMinutes < b.DurationMinutes
+	})
+	return rifts, nil
+}
+
+func (r *RiftRepository) GetRiftById(id int64) (*repositories.RiftEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rift := findRow(r.store.rifts, func(rift *repositories.RiftEntity) bool {
+		return rift.ID == id && !rift.IsArchived
+	})
+	if rift == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(rift), nil
+}
+
+func (r *RiftRepository) GetRiftsByDifficulty(difficulty string) ([]*repositories.RiftEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rifts := selectRows(r.store.rifts, func(rift *repositories.RiftEntity) bool {
+		return rift.Difficulty == difficulty && !rift.IsArchived
+	})
+	sortRows(rifts, func(a, b *repositories.RiftEntity) bool {
+		return a.DurationMinutes < b.DurationMinutes
+	})
+	return rifts, nil
+}
+
+type LootItemRepository struct {
+	store *Store
+}
+
+func NewLootItemRepository(store *Store) repositories.ILootItemRepository {
+	return &LootItemRepository{
+		store: store,
+	}
+}
+
+func (r *LootItemRepository) GetAllLootItems() ([]*repositories.LootItemEntity, error) {
+	return r.selectItems(func(item *repositories.LootItemEntity) bool {
+		return true
+	}, true), nil
+}
+
+func (r *LootItemRepository) GetLootItemById(id int64) (*repositories.LootItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.lootItems, func(item *repositories.LootItemEntity) bool {
+		return item.ID == id && !item.IsArchived
+	})
+	if item == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(item), nil
+}
+
+func (r *LootItemRepository) GetLootItemsByWorldType(worldType string) ([]*repositories.LootItemEntity, error) {
+	return r.selectItems(func(item *repositories.LootItemEntity) bool {
+		return item.WorldType == worldType
+	}, true), nil
+}
+
+func (r *LootItemRepository) GetLootItemsByRarityAndWorldType(rarity string, worldType string) ([]*repositories.LootItemEntity, error) {
+	return r.selectItems(func(item *repositories.LootItemEntity) bool {
+		return item.Rarity == rarity && item.WorldType == worldType
+	}, false), nil
+}
+
+// selectItems returns the unarchived items that match, ordered by name and, if
+// byRarity is set, by rarity first
+func (r *LootItemRepository) selectItems(match func(item *repositories.LootItemEntity) bool, byRarity bool) []*repositories.LootItemEntity {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	items := selectRows(r.store.lootItems, func(item *repositories.LootItemEntity) bool {
+		return !item.IsArchived && match(item)
+	})
+	sortRows(items, func(a, b *repositories.LootItemEntity) bool {
+		if byRarity && a.Rarity != b.Rarity {
+			return rarityOrder[a.Rarity] < rarityOrder[b.Rarity]
+		}
+		return a.Name < b.Name
+	})
+	return items
+}
+
+type LootDropTableRepository struct {
+	store *Store
+}
+
+func NewLootDropTableRepository(store *Store) repositories.ILootDropTableRepository {
+	return &LootDropTableRepository{
+		store: store,
+	}
+}
+
+func (r *LootDropTableRepository) GetDropTablesByRiftId(riftId int64) ([]*repositories.LootDropTableEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	dropTables := selectRows(r.store.dropTables, func(dropTable *repositories.LootDropTableEntity) bool {
+		return dropTable.RiftID == riftId && !dropTable.IsArchived
+	})
+	sortRows(dropTables, func(a, b *repositories.LootDropTableEntity) bool {
+		return rarityOrder[a.Rarity] < rarityOrder[b.Rarity]
+	})
+	return dropTables, nil
+}
+
+type UnlockRuleRepository struct {
+	store *Store
+}
+
+func NewUnlockRuleRepository(store *Store) repositories.IUnlockRuleRepository {
+	return &UnlockRuleRepository{
+		store: store,
+	}
+}
+
+func (r *UnlockRuleRepository) GetRulesByTargetType(targetType string) ([]*repositories.UnlockRuleEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rules := selectRows(r.store.unlockRules, func(rule *repositories.UnlockRuleEntity) bool {
+		return rule.TargetType == targetType && !rule.IsArchived
+	})
+	sortRows(rules, func(a, b *repositories.UnlockRuleEntity) bool {
+		return a.TargetKey < b.TargetKey
+	})
+	return rules, nil
+}
+
+// GetRuleByTarget returns nil if the target has no rule, which means it is always unlocked
+func (r *UnlockRuleRepository) GetRuleByTarget(targetType string, targetKey int64) (*repositories.UnlockRuleEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	rule := findRow(r.store.unlockRules, func(rule *repositories.UnlockRuleEntity) bool {
+		return rule.TargetType == targetType && rule.TargetKey == targetKey && !rule.IsArchived
+	})
+	if rule == nil {
+		return nil, nil
+	}
+	return clone(rule), nil
+}
+
+type UpgradeRecipeRepository struct {
+	store *Store
+}
+
+func NewUpgradeRecipeRepository(store *Store) repositories.IUpgradeRecipeRepository {
+	return &UpgradeRecipeRepository{
+		store: store,
+	}
+}
+
+func (r *UpgradeRecipeRepository) GetRecipes() ([]*repositories.UpgradeRecipeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return selectRows(r.store.upgradeRecipes, func(recipe *repositories.UpgradeRecipeEntity) bool {
+		return !recipe.IsArchived
+	}), nil
+}
+
+func (r *UpgradeRecipeRepository) GetRecipeById(recipeId int64) (*repositories.UpgradeRecipeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	recipe := findRow(r.store.upgradeRecipes, func(recipe *repositories.UpgradeRecipeEntity) bool {
+		return recipe.ID == recipeId && !recipe.IsArchived
+	})
+	if recipe == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(recipe), nil
+}
+
+func (r *UpgradeRecipeRepository) GetCostsByRecipeId(recipeId int64) ([]*repositories.UpgradeRecipeCostEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	costs := selectRows(r.store.upgradeRecipeCosts, func(cost *repositories.UpgradeRecipeCostEntity) bool {
+		return cost.RecipeID == recipeId && !cost.IsArchived
+	})
+	sortRows(costs, func(a, b *repositories.UpgradeRecipeCostEntity) bool {
+		return rarityOrder[a.Rarity] < rarityOrder[b.Rarity]
+	})
+	return costs, nil
+}
@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// guildRoleOrder sorts members the way GetMembers' ORDER BY CASE does
+var guildRoleOrder = map[string]int{
+	string(models.GuildRoleLeader):  0,
+	string(models.GuildRoleOfficer): 1,
+	string(models.GuildRoleMember):  2,
+}
+
+type GuildRepository struct {
+	store *Store
+}
+
+func NewGuildRepository(store *Store) repositories.IGuildRepository {
+	return &GuildRepository{
+		store: store,
+	}
+}
+
+// CreateGuild returns repositories.ErrGuildNameTaken if an active guild already has the
+// name, ignoring case, the same as idx_guilds_name
+func (r *GuildRepository) CreateGuild(name, description, inviteCode string) (*repositories.GuildEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if findRow(r.store.guilds, func(guild *repositories.GuildEntity) bool {
+		return strings.EqualFold(guild.Name, name) && !guild.IsArchived
+	}) != nil {
+		return nil, repositories.ErrGuildNameTaken
+	}
+	// UNIQUE idx_guilds_invite_code
+	if findRow(r.store.guilds, func(guild *repositories.GuildEntity) bool {
+		return guild.InviteCode == inviteCode
+	}) != nil {
+		return nil, fmt.Errorf("invite code %s is already in use", inviteCode)
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	guild := &repositories.GuildEntity{
+		ID:          r.store.nextId("guilds"),
+		CreatedAt:   createdAt,
+		ModifiedAt:  modifiedAt,
+		Name:        name,
+		Description: description,
+		InviteCode:  inviteCode,
+	}
+	r.store.guilds = append(r.store.guilds, guild)
+	return clone(guild), nil
+}
+
+func (r *GuildRepository) GetGuildById(guildId int64) (*repositories.GuildEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	guild := r.findGuild(func(guild *repositories.GuildEntity) bool {
+		return guild.ID == guildId
+	})
+	if guild == nil {
+		return nil, nil
+	}
+	return clone(guild), nil
+}
+
+// GetGuildByIdForUpdate doesn't need to lock anything, UnitOfWork already runs one
+// transaction at a time
+func (r *GuildRepository) GetGuildByIdForUpdate(guildId int64) (*repositories.GuildEntity, error) {
+	return r.GetGuildById(guildId)
+}
+
+func (r *GuildRepository) GetGuildByInviteCode(inviteCode string) (*repositories.GuildEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	guild := r.findGuild(func(guild *repositories.GuildEntity) bool {
+		return guild.InviteCode == inviteCode
+	})
+	if guild == nil {
+		return nil, nil
+	}
+	return clone(guild), nil
+}
+
+func (r *GuildRepository) UpdateInviteCode(guildId int64, inviteCode string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if guild := findRow(r.store.guilds, func(guild *repositories.GuildEntity) bool {
+		return guild.ID == guildId
+	}); guild != nil {
+		guild.InviteCode = inviteCode
+		_, guild.ModifiedAt = r.store.timestamp()
+	}
+	return nil
+}
+
+func (r *GuildRepository) ArchiveGuild(guildId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if guild := findRow(r.store.guilds, func(guild *repositories.GuildEntity) bool {
+		return guild.ID == guildId
+	}); guild != nil {
+		guild.IsArchived = true
+		_, guild.ModifiedAt = r.store.timestamp()
+	}
+	return nil
+}
+
+// AddMember returns repositories.ErrAlreadyInGuild if the user is already a member of any
+// guild, the same as idx_guild_members_user
+func (r *GuildRepository) AddMember(guildId, userId int64, role string) (*repositories.GuildMemberEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if r.findMember(userId) != nil {
+		return nil, repositories.ErrAlreadyInGuild
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	member := &repositories.GuildMemberEntity{
+		ID:         r.store.nextId("guild_members"),
+		CreatedAt:  createdAt,
+		ModifiedAt: modifiedAt,
+		GuildID:    guildId,
+		UserID:     userId,
+		Role:       role,
+		JoinedAt:   createdAt,
+	}
+	r.store.guildMembers = append(r.store.guildMembers, member)
+	return clone(member), nil
+}
+
+func (r *GuildRepository) GetMemberByUserId(userId int64) (*repositories.GuildMemberEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	member := r.findMember(userId)
+	if member == nil {
+		return nil, nil
+	}
+	return r.withDisplayName(clone(member)), nil
+}
+
+// GetMembers returns the guild's members, leader first, then officers, then members,
+// longest standing first within each role
+func (r *GuildRepository) GetMembers(guildId int64) ([]*repositories.GuildMemberEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	members := selectRows(r.store.guildMembers, func(member *repositories.GuildMemberEntity) bool {
+		return member.GuildID == guildId && !member.IsArchived
+	})
+	sortRows(members, func(a, b *repositories.GuildMemberEntity) bool {
+		if guildRoleOrder[a.Role] != guildRoleOrder[b.Role] {
+			return guildRoleOrder[a.Role] < guildRoleOrder[b.Role]
+		}
+		return a.JoinedAt.Before(b.JoinedAt)
+	})
+	for _, member := range members {
+		r.withDisplayName(member)
+	}
+	return members, nil
+}
+
+func (r *GuildRepository) GetMemberCount(guildId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return len(selectRows(r.store.guildMembers, func(member *repositories.GuildMemberEntity) bool {
+		return member.GuildID == guildId && !member.IsArchived
+	})), nil
+}
+
+func (r *GuildRepository) UpdateMemberRole(guildId, userId int64, role string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if member := r.findMember(userId); member != nil && member.GuildID == guildId {
+		member.Role = role
+		_, member.ModifiedAt = r.store.timestamp()
+	}
+	return nil
+}
+
+func (r *GuildRepository) RemoveMember(guildId, userId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	member := r.findMember(userId)
+	if member == nil || member.GuildID != guildId {
+		return false, nil
+	}
+	member.IsArchived = true
+	_, member.ModifiedAt = r.store.timestamp()
+	return true, nil
+}
+
+func (r *GuildRepository) AddToTreasury(guildId, lootItemId int64, quantity int) (*repositories.GuildTreasuryItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// UNIQUE (guild_id, loot_item_id)
+	if item := findRow(r.store.guildTreasury, func(item *repositories.GuildTreasuryItemEntity) bool {
+		return item.GuildID == guildId && item.LootItemID == lootItemId
+	}); item != nil {
+		item.Quantity += quantity
+		_, item.ModifiedAt = r.store.timestamp()
+		return clone(item), nil
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	item := &repositories.GuildTreasuryItemEntity{
+		ID:         r.store.nextId("guild_treasury"),
+		CreatedAt:  createdAt,
+		ModifiedAt: modifiedAt,
+		GuildID:    guildId,
+		LootItemID: lootItemId,
+		Quantity:   quantity,
+	}
+	r.store.guildTreasury = append(r.store.guildTreasury, item)
+	return clone(item), nil
+}
+
+func (r *GuildRepository) GetTreasury(guildId int64) ([]*repositories.GuildTreasuryItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return selectRows(r.store.guildTreasury, func(item *repositories.GuildTreasuryItemEntity) bool {
+		return item.GuildID == guildId && !item.IsArchived
+	}), nil
+}
+
+// GetRiftProgress returns the completed expeditions of the guild's current members in each
+// rift they've completed, in rift order
+func (r *GuildRepository) GetRiftProgress(guildId int64) ([]*repositories.GuildRiftProgressEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	members := make(map[int64]bool)
+	for _, member := range r.store.guildMembers {
+		if member.GuildID == guildId && !member.IsArchived {
+			members[member.UserID] = true
+		}
+	}
+
+	progress := []*repositories.GuildRiftProgressEntity{}
+	for _, rift := range r.store.rifts {
+		if rift.IsArchived {
+			continue
+		}
+		completed := 0
+		completedBy := make(map[int64]bool)
+		for _, expedition := range r.store.expeditions {
+			if expedition.RiftID == rift.ID && members[expedition.UserID] &&
+				expedition.Status == string(models.ExpeditionStatusCompleted) && !expedition.IsArchived {
+				completed++
+				completedBy[expedition.UserID] = true
+			}
+		}
+		if completed > 0 {
+			progress = append(progress, &repositories.GuildRiftProgressEntity{
+				RiftID:               rift.ID,
+				RiftName:             rift.Name,
+				CompletedExpeditions: completed,
+				MembersCompleted:     len(completedBy),
+			})
+		}
+	}
+	return progress, nil
+}
+
+func (r *GuildRepository) WithTx(tx *database.AppDataSource) repositories.IGuildRepository {
+	return r
+}
+
+// findGuild returns the stored active guild that matches, or nil. Must be called with the mutex held.
+func (r *GuildRepository) findGuild(match func(guild *repositories.GuildEntity) bool) *repositories.GuildEntity {
+	return findRow(r.store.guilds, func(guild *repositories.GuildEntity) bool {
+		return !guild.IsArchived && match(guild)
+	})
+}
+
+// findMember returns the stored active membership of the user, or nil. Must be called with the mutex held.
+func (r *GuildRepository) findMember(userId int64) *repositories.GuildMemberEntity {
+	return findRow(r.store.guildMembers, func(member *repositories.GuildMemberEntity) bool {
+		return member.UserID == userId && !member.IsArchived
+	})
+}
+
+// withDisplayName fills in the member's display name the way the users join does.
+// Must be called with the mutex held.
+func (r *GuildRepository) withDisplayName(member *repositories.GuildMemberEntity) *repositories.GuildMemberEntity {
+	if user := findRow(r.store.users, func(user *repositories.UserEntity) bool {
+		return user.ID == member.UserID
+	}); user != nil {
+		member.DisplayName = user.DisplayName
+	}
+	return member
+}
@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type LaunchQueueRepository struct {
+	store *Store
+}
+
+func NewLaunchQueueRepository(store *Store) repositories.ILaunchQueueRepository {
+	return &LaunchQueueRepository{
+		store: store,
+	}
+}
+
+// GetEntriesByTeamId returns the team's queued launches in the order they will run
+func (r *LaunchQueueRepository) GetEntriesByTeamId(teamId int64) ([]*repositories.LaunchQueueEntryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	entries := r.selectQueued(teamId)
+	sortRows(entries, func(a, b *repositories.LaunchQueueEntryEntity) bool {
+		return a.Position < b.Position
+	})
+	return entries, nil
+}
+
+// AddEntry appends a launch to the end of the team's queue
+func (r *LaunchQueueRepository) AddEntry(userId, teamId, riftId int64, repeatCount *int) (*repositories.LaunchQueueEntryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	position := 0
+	for _, entry := range r.selectQueued(teamId) {
+		position = max(position, entry.Position)
+	}
+
+	var repeats *int
+	if repeatCount != nil {
+		count := *repeatCount
+		repeats = &count
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	entry := &repositories.LaunchQueueEntryEntity{
+		ID:          r.store.nextId("team_launch_queue"),
+		CreatedAt:   createdAt,
+		ModifiedAt:  modifiedAt,
+		UserID:      userId,
+		TeamID:      teamId,
+		RiftID:      riftId,
+		Position:    position + 1,
+		RepeatCount: repeats,
+	}
+	r.store.launchQueue = append(r.store.launchQueue, entry)
+	return clone(entry), nil
+}
+
+// ConsumeEntry records one launch of an entry. Entries with a repeat count are archived
+// once their last launch is used up, entries that repeat until stopped are left alone.
+func (r *LaunchQueueRepository) ConsumeEntry(entryId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	entry := findRow(r.store.launchQueue, func(entry *repositories.LaunchQueueEntryEntity) bool {
+		return entry.ID == entryId && entry.RepeatCount != nil && !entry.IsArchived
+	})
+	if entry == nil {
+		return nil
+	}
+	remaining := *entry.RepeatCount - 1
+	entry.RepeatCount = &remaining
+	entry.IsArchived = remaining <= 0
+	_, entry.ModifiedAt = r.store.timestamp()
+	return nil
+}
+
+func (r *LaunchQueueRepository) RemoveEntry(entryId int64) error {
+	r.archiveEntries(func(entry *repositories.LaunchQueueEntryEntity) bool {
+		return entry.ID == entryId
+	})
+	return nil
+}
+
+func (r *LaunchQueueRepository) ClearTeamQueue(teamId int64) error {
+	r.archiveEntries(func(entry *repositories.LaunchQueueEntryEntity) bool {
+		return entry.TeamID == teamId && !entry.IsArchived
+	})
+	return nil
+}
+
+// GetIdleQueuedTeamIds returns up to limit teams that have queued launches and are not
+// out on an unprocessed expedition, so their next launch can start. Teams that have never
+// been tried come first, then the ones tried longest ago, so teams that can't launch
+// don't hold up the rest.
+func (r *LaunchQueueRepository) GetIdleQueuedTeamIds(limit int) ([]int64, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	busy := make(map[int64]bool)
+	for _, expedition := range r.store.expeditions {
+		if !expedition.Processed && !expedition.IsArchived {
+			busy[expedition.TeamID] = true
+		}
+	}
+
+	ids := []int64{}
+	attemptedAt := make(map[int64]*time.Time)
+	for _, entry := range r.store.launchQueue {
+		if entry.IsArchived || busy[entry.TeamID] {
+			continue
+		}
+		latest, seen := attemptedAt[entry.TeamID]
+		if !seen {
+			ids = append(ids, entry.TeamID)
+		}
+		if entry.AttemptedAt != nil && (latest == nil || entry.AttemptedAt.After(*latest)) {
+			latest = entry.AttemptedAt
+		}
+		attemptedAt[entry.TeamID] = latest
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := attemptedAt[ids[i]], attemptedAt[ids[j]]
+		if (a == nil) != (b == nil) {
+			return a == nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *LaunchQueueRepository) MarkLaunchAttempted(teamId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	for _, entry := range r.store.launchQueue {
+		if entry.TeamID == teamId && !entry.IsArchived {
+			entry.AttemptedAt = &now
+			entry.ModifiedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *LaunchQueueRepository) WithTx(tx *database.AppDataSource) repositories.ILaunchQueueRepository {
+	return r
+}
+
+// selectQueued returns copies of the team's unarchived entries, in id order. Must be called with the mutex held.
+func (r *LaunchQueueRepository) selectQueued(teamId int64) []*repositories.LaunchQueueEntryEntity {
+	return selectRows(r.store.launchQueue, func(entry *repositories.LaunchQueueEntryEntity) bool {
+		return entry.TeamID == teamId && !entry.IsArchived
+	})
+}
+
+func (r *LaunchQueueRepository) archiveEntries(match func(entry *repositories.LaunchQueueEntryEntity) bool) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, entry := range r.store.launchQueue {
+		if match(entry) {
+			entry.IsArchived = true
+			_, entry.ModifiedAt = r.store.timestamp()
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"database/sql"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type LeaderboardRepository struct {
+	store *Store
+}
+
+func NewLeaderboardRepository(store *Store) repositories.ILeaderboardRepository {
+	return &LeaderboardRepository{
+		store: store,
+	}
+}
+
+// GetCacheMetadata retrieves the metadata for a specific leaderboard type
+func (r *LeaderboardRepository) GetCacheMetadata(leaderboardType string) (*repositories.LeaderboardCacheEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	cache := r.findCache(leaderboardType)
+	if cache == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(cache), nil
+}
+
+// SetSyncInProgress sets or clears the is_syncing flag for a leaderboard type
+func (r *LeaderboardRepository) SetSyncInProgress(leaderboardType string, inProgress bool) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if cache := r.findCache(leaderboardType); cache != nil {
+		cache.IsSyncing = inProgress
+		cache.UpdatedAt = r.store.now()
+	}
+	return nil
+}
+
+// UpdateLastSynced updates the last_synced timestamp for a leaderboard type
+func (r *LeaderboardRepository) UpdateLastSynced(leaderboardType string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if cache := r.findCache(leaderboardType); cache != nil {
+		now := r.store.now()
+		cache.LastSynced = now
+		cache.UpdatedAt = now
+	}
+	return nil
+}
+
+// GetTopRankings retrieves the top N ranked players for a leaderboard type
+func (r *LeaderboardRepository) GetTopRankings(leaderboardType string, limit int) ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	items := selectRows(r.store.leaderboardItems, func(item *repositories.LeaderboardCacheItemEntity) bool {
+		return item.LeaderboardType == leaderboardType
+	})
+	sortRows(items, func(a, b *repositories.LeaderboardCacheItemEntity) bool {
+		return a.Rank < b.Rank
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// GetUserRank retrieves the rank entry for a specific user on a leaderboard type
+func (r *LeaderboardRepository) GetUserRank(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := r.findItem(leaderboardType, userID, 0)
+	if item == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(item), nil
+}
+
+// GetUserGuildRank retrieves the rank entry of the guild a user is in on a guild leaderboard type
+func (r *LeaderboardRepository) GetUserGuildRank(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	member := findRow(r.store.guildMembers, func(member *repositories.GuildMemberEntity) bool {
+		return member.UserID == int64(userID) && !member.IsArchived
+	})
+	if member == nil {
+		return nil, sql.ErrNoRows
+	}
+	item := r.findItem(leaderboardType, 0, int(member.GuildID))
+	if item == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(item), nil
+}
+
+// TruncateCache removes all cache items for a specific leaderboard type
+func (r *LeaderboardRepository) TruncateCache(leaderboardType string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	kept := []*repositories.LeaderboardCacheItemEntity{}
+	for _, item := range r.store.leaderboardItems {
+		if item.LeaderboardType != leaderboardType {
+			kept = append(kept, item)
+		}
+	}
+	r.store.leaderboardItems = kept
+	return nil
+}
+
+// InsertCacheItems inserts the items, updating the score and rank of users and guilds
+// that are already on the leaderboard
+func (r *LeaderboardRepository) InsertCacheItems(items []*repositories.LeaderboardCacheItemEntity) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, item := range items {
+		// UNIQUE (leaderboard_type, user_id) and idx_leaderboard_cache_items_guild
+		if existing := r.findItem(item.LeaderboardType, item.UserID, item.GuildID); existing != nil {
+			existing.Score = item.Score
+			existing.Rank = item.Rank
+			continue
+		}
+		r.store.leaderboardItems = append(r.store.leaderboardItems, &repositories.LeaderboardCacheItemEntity{
+			ID:              int(r.store.nextId("leaderboard_cache_items")),
+			LeaderboardType: item.LeaderboardType,
+			UserID:          item.UserID,
+			GuildID:         item.GuildID,
+			Username:        item.Username,
+			Score:           item.Score,
+			Rank:            item.Rank,
+			CreatedAt:       r.store.now(),
+		})
+	}
+	return nil
+}
+
+// GetLegendaryItemCounts calculates total legendary items per user
+// Returns users sorted by count descending
+func (r *LeaderboardRepository) GetLegendaryItemCounts() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.scoreUsers(func(userId int64) (int64, bool) {
+		score := int64(0)
+		for _, item := range r.store.inventory {
+			if item.UserID != userId {
+				continue
+			}
+			if lootItem := r.store.findLootItem(item.LootItemID); lootItem != nil && lootItem.Rarity == string(models.ItemRarityLegendary) {
+				score += int64(item.Quantity)
+			}
+		}
+		return score, score > 0
+	}), nil
+}
+
+// GetPowerScores calculates weighted inventory value per user
+// Rarity weights are repositories.PowerScoreRarityWeights
+// Returns users sorted by score descending
+func (r *LeaderboardRepository) GetPowerScores() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.scoreUsers(func(userId int64) (int64, bool) {
+		score, quantity := int64(0), 0
+		for _, item := range r.store.inventory {
+			if item.UserID != userId {
+				continue
+			}
+			quantity += item.Quantity
+			if lootItem := r.store.findLootItem(item.LootItemID); lootItem != nil {
+				score += int64(item.Quantity) * repositories.PowerScoreRarityWeights[lootItem.Rarity]
+			}
+		}
+		return score, quantity > 0
+	}), nil
+}
+
+// GetExpeditionCounts calculates total completed expeditions per user (recalled runs don't count)
+// Returns users sorted by count descending
+func (r *LeaderboardRepository) GetExpeditionCounts() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.scoreUsers(func(userId int64) (int64, bool) {
+		score := int64(0)
+		for _, expedition := range r.store.expeditions {
+			if expedition.UserID == userId && expedition.Status == string(models.ExpeditionStatusCompleted) && !expedition.IsArchived {
+				score++
+			}
+		}
+		return score, score > 0
+	}), nil
+}
+
+// GetEchoEncounterCounts calculates how many echo encounters each user has been part of
+// Returns users sorted by count descending
+func (r *LeaderboardRepository) GetEchoEncounterCounts() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.scoreUsers(func(userId int64) (int64, bool) {
+		score := int64(r.store.countEchoEncounters(userId))
+		return score, score > 0
+	}), nil
+}
+
+// GetGuildPowerScores calculates the combined power score of each guild's active members
+// and its treasury
+// Returns guilds sorted by score descending
+func (r *LeaderboardRepository) GetGuildPowerScores() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	itemScore := func(lootItemId int64, quantity int) int64 {
+		if lootItem := r.store.findLootItem(lootItemId); lootItem != nil {
+			return int64(quantity) * repositories.PowerScoreRarityWeights[lootItem.Rarity]
+		}
+		return 0
+	}
+
+	items := []*repositories.LeaderboardCacheItemEntity{}
+	for _, guild := range r.store.guilds {
+		if guild.IsArchived {
+			continue
+		}
+		score := int64(0)
+		for _, member := range r.store.guildMembers {
+			if member.GuildID != guild.ID || member.IsArchived {
+				continue
+			}
+			if user := findRow(r.store.users, func(user *repositories.UserEntity) bool {
+				return user.ID == member.UserID
+			}); user == nil || user.IsArchived {
+				continue
+			}
+			for _, item := range r.store.inventory {
+				if item.UserID == member.UserID && !item.IsArchived {
+					score += itemScore(item.LootItemID, item.Quantity)
+				}
+			}
+		}
+		for _, item := range r.store.guildTreasury {
+			if item.GuildID == guild.ID && !item.IsArchived {
+				score += itemScore(item.LootItemID, item.Quantity)
+			}
+		}
+		if score > 0 {
+			items = append(items, &repositories.LeaderboardCacheItemEntity{
+				GuildID:  int(guild.ID),
+				Username: guild.Name,
+				Score:    score,
+			})
+		}
+	}
+	sortRows(items, func(a, b *repositories.LeaderboardCacheItemEntity) bool {
+		return a.Score > b.Score
+	})
+	return items, nil
+}
+
+// scoreUsers scores every unarchived user, keeping the ones score says to include, highest
+// score first. Must be called with the mutex held.
+func (r *LeaderboardRepository) scoreUsers(score func(userId int64) (int64, bool)) []*repositories.LeaderboardCacheItemEntity {
+	items := []*repositories.LeaderboardCacheItemEntity{}
+	for _, user := range r.store.users {
+		if user.IsArchived {
+			continue
+		}
+		if userScore, include := score(user.ID); include {
+			items = append(items, &repositories.LeaderboardCacheItemEntity{
+				UserID:   int(user.ID),
+				Username: user.DisplayName,
+				Score:    userScore,
+			})
+		}
+	}
+	sortRows(items, func(a, b *repositories.LeaderboardCacheItemEntity) bool {
+		return a.Score > b.Score
+	})
+	return items
+}
+
+// findCache returns the stored metadata row, or nil. Must be called with the mutex held.
+func (r *LeaderboardRepository) findCache(leaderboardType string) *repositories.LeaderboardCacheEntity {
+	return findRow(r.store.leaderboardCaches, func(cache *repositories.LeaderboardCacheEntity) bool {
+		return cache.LeaderboardType == leaderboardType
+	})
+}
+
+// findItem returns the stored cache item of the user, or of the guild when userID is 0, or nil.
+// Must be called with the mutex held.
+func (r *LeaderboardRepository) findItem(leaderboardType string, userID, guildID int) *repositories.LeaderboardCacheItemEntity {
+	return findRow(r.store.leaderboardItems, func(item *repositories.LeaderboardCacheItemEntity) bool {
+		return item.LeaderboardType == leaderboardType && item.UserID == userID && item.GuildID == guildID
+	})
+}
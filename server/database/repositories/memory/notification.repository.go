@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type NotificationRepository struct {
+	store *Store
+}
+
+func NewNotificationRepository(store *Store) repositories.INotificationRepository {
+	return &NotificationRepository{
+		store: store,
+	}
+}
+
+func (r *NotificationRepository) CreateNotification(userId int64, notificationType string, message string) (*repositories.NotificationEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	createdAt, modifiedAt := r.store.timestamp()
+	notification := &repositories.NotificationEntity{
+		ID:         r.store.nextId("notifications"),
+		CreatedAt:  createdAt,
+		ModifiedAt: modifiedAt,
+		UserID:     userId,
+		Type:       notificationType,
+		Message:    message,
+	}
+	r.store.notifications = append(r.store.notifications, notification)
+	return clone(notification), nil
+}
+
+// GetNotificationsByUserId returns a page of the user's notifications, newest first
+func (r *NotificationRepository) GetNotificationsByUserId(userId int64, limit int, offset int) ([]*repositories.NotificationEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	notifications := r.selectNotifications(userId, false)
+	sortRows(notifications, func(a, b *repositories.NotificationEntity) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID > b.ID
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+
+	if offset >= len(notifications) {
+		return []*repositories.NotificationEntity{}, nil
+	}
+	notifications = notifications[offset:]
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) GetNotificationsCount(userId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return len(r.selectNotifications(userId, false)), nil
+}
+
+func (r *NotificationRepository) GetUnreadCount(userId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return len(r.selectNotifications(userId, true)), nil
+}
+
+// MarkRead marks one of the user's notifications read. Returns false if the user has no
+// such notification. Marking a read notification again keeps its first read_at.
+func (r *NotificationRepository) MarkRead(userId, notificationId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	notification := findRow(r.store.notifications, func(notification *repositories.NotificationEntity) bool {
+		return notification.ID == notificationId && notification.UserID == userId && !notification.IsArchived
+	})
+	if notification == nil {
+		return false, nil
+	}
+	r.markRead(notification)
+	return true, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(userId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, notification := range r.store.notifications {
+		if notification.UserID == userId && !notification.IsRead && !notification.IsArchived {
+			r.markRead(notification)
+		}
+	}
+	return nil
+}
+
+func (r *NotificationRepository) WithTx(tx *database.AppDataSource) repositories.INotificationRepository {
+	return r
+}
+
+// selectNotifications returns copies of the user's unarchived notifications, in id order.
+// Must be called with the mutex held.
+func (r *NotificationRepository) selectNotifications(userId int64, unreadOnly bool) []*repositories.NotificationEntity {
+	return selectRows(r.store.notifications, func(notification *repositories.NotificationEntity) bool {
+		return notification.UserID == userId && !notification.IsArchived && (!unreadOnly || !notification.IsRead)
+	})
+}
+
+// markRead sets a stored notification read. Must be called with the mutex held.
+func (r *NotificationRepository) markRead(notification *repositories.NotificationEntity) {
+	now, modifiedAt := r.store.timestamp()
+	if notification.ReadAt == nil {
+		notification.ReadAt = &now
+	}
+	notification.IsRead = true
+	notification.ModifiedAt = modifiedAt
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+// Seed is the game data the migrations insert, for stores that stand in for a migrated
+// database. Rows use the same JSON fields as the database entities. IDs are assigned in
+// order when the seed is loaded into an empty store, so drop tables and unlock rules can
+// refer to rifts by the same ids the migrations give them.
+type Seed struct {
+	Rifts          []*repositories.RiftEntity          `json:"rifts"`
+	LootItems      []*repositories.LootItemEntity      `json:"loot_items"`
+	DropTables     []*repositories.LootDropTableEntity `json:"drop_tables"`
+	UnlockRules    []*SeedUnlockRule                   `json:"unlock_rules"`
+	UpgradeRecipes []*SeedUpgradeRecipe                `json:"upgrade_recipes"`
+	FeatureFlags   []*repositories.FeatureFlagEntity   `json:"feature_flags"`
+	Leaderboards   []string                            `json:"leaderboards"`
+}
+
+// SeedUnlockRule is an unlock rule with its condition tree written out as JSON
+type SeedUnlockRule struct {
+	TargetType string          `json:"target_type"`
+	TargetKey  int64           `json:"target_key"`
+	Rule       json.RawMessage `json:"rule"`
+}
+
+// SeedUpgradeRecipe is an upgrade recipe with its costs
+type SeedUpgradeRecipe struct {
+	repositories.UpgradeRecipeEntity
+	Costs []*repositories.UpgradeRecipeCostEntity `json:"costs"`
+}
+
+// leaderboardsNeverSynced is the last_synced the migrations give each leaderboard so it
+// is rebuilt on first use
+var leaderboardsNeverSynced = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// LoadSeed reads a seed JSON file
+func LoadSeed(path string) (*Seed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := &Seed{}
+	if err := json.Unmarshal(data, seed); err != nil {
+		return nil, fmt.Errorf("invalid seed %s: %w", path, err)
+	}
+	return seed, nil
+}
+
+// Load adds every row of the seed to the store
+func (s *Store) Load(seed *Seed) {
+	for _, rift := range seed.Rifts {
+		s.AddRift(rift)
+	}
+	for _, item := range seed.LootItems {
+		s.AddLootItem(item)
+	}
+	for _, dropTable := range seed.DropTables {
+		s.AddDropTable(dropTable)
+	}
+	for _, rule := range seed.UnlockRules {
+		s.AddUnlockRule(&repositories.UnlockRuleEntity{
+			TargetType: rule.TargetType,
+			TargetKey:  rule.TargetKey,
+			Rule:       rule.Rule,
+		})
+	}
+	for _, recipe := range seed.UpgradeRecipes {
+		s.AddUpgradeRecipe(&recipe.UpgradeRecipeEntity, recipe.Costs)
+	}
+	for _, flag := range seed.FeatureFlags {
+		s.AddFeatureFlag(flag)
+	}
+	for _, leaderboardType := range seed.Leaderboards {
+		s.AddLeaderboardCache(leaderboardType, leaderboardsNeverSynced)
+	}
+}
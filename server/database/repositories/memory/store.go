@@ -0,0 +1,345 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// Store is an in-memory stand-in for the Postgres database. Repositories created from
+// the same store share its tables, so a write through one is seen by all the others.
+// Rows are handed out as copies, the same as rows scanned from a query.
+type Store struct {
+	mutex sync.Mutex
+	// txMutex lets one UnitOfWork transaction run at a time
+	txMutex sync.Mutex
+	now     func() time.Time
+	tables
+}
+
+// tables holds every row in the store. It is copied whole to snapshot the store.
+type tables struct {
+	lastIds map[string]int64
+
+	users              []*repositories.UserEntity
+	featureFlags       []*repositories.FeatureFlagEntity
+	rifts              []*repositories.RiftEntity
+	lootItems          []*repositories.LootItemEntity
+	dropTables         []*repositories.LootDropTableEntity
+	unlockRules        []*repositories.UnlockRuleEntity
+	upgradeRecipes     []*repositories.UpgradeRecipeEntity
+	upgradeRecipeCosts []*repositories.UpgradeRecipeCostEntity
+	teams              []*repositories.TeamEntity
+	inventory          []*repositories.UserInventoryEntity
+	collectedItems     []*repositories.UserCollectedItemEntity
+	expeditions        []*repositories.ExpeditionEntity
+	expeditionLoot     []*repositories.ExpeditionLootEntity
+	lootRolls          []*repositories.ExpeditionLootRollEntity
+	launchQueue        []*repositories.LaunchQueueEntryEntity
+	leaderboardCaches  []*repositories.LeaderboardCacheEntity
+	leaderboardItems   []*repositories.LeaderboardCacheItemEntity
+	notifications      []*repositories.NotificationEntity
+	echoEncounters     []*repositories.EchoEncounterEntity
+	echoEncounterLoot  []*repositories.EchoEncounterLootEntity
+	guilds             []*repositories.GuildEntity
+	guildMembers       []*repositories.GuildMemberEntity
+	guildTreasury      []*repositories.GuildTreasuryItemEntity
+	trades             []*repositories.TradeEntity
+	tradeItems         []*repositories.TradeItemEntity
+	userSessions       []*repositories.UserSessionEntity
+	usedTokens         []*repositories.UsedTokenEntity
+	userLoginHistory   []*repositories.UserLoginHistoryEntity
+	userTwoFactor      []*repositories.UserTwoFactorEntity
+	userRecoveryCodes  []*repositories.UserRecoveryCodeEntity
+}
+
+func NewStore() *Store {
+	return &Store{
+		now: time.Now,
+		tables: tables{
+			lastIds: make(map[string]int64),
+		},
+	}
+}
+
+// SetClock replaces the store's NOW(). Used to move time forward without waiting,
+// e.g. to make an expedition due.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.now = now
+}
+
+// AddRift seeds a rift and returns it with its new ID
+func (s *Store) AddRift(rift *repositories.RiftEntity) *repositories.RiftEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(rift)
+	row.ID = s.nextId("rifts")
+	row.CreatedAt = s.now()
+	s.rifts = append(s.rifts, row)
+	return clone(row)
+}
+
+// AddLootItem seeds a loot item and returns it with its new ID
+func (s *Store) AddLootItem(item *repositories.LootItemEntity) *repositories.LootItemEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(item)
+	row.ID = s.nextId("loot_items")
+	row.CreatedAt = s.now()
+	s.lootItems = append(s.lootItems, row)
+	return clone(row)
+}
+
+// AddDropTable seeds one rarity of a rift's drop table and returns it with its new ID
+func (s *Store) AddDropTable(dropTable *repositories.LootDropTableEntity) *repositories.LootDropTableEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(dropTable)
+	row.ID = s.nextId("loot_drop_tables")
+	row.CreatedAt = s.now()
+	s.dropTables = append(s.dropTables, row)
+	return clone(row)
+}
+
+// AddUnlockRule seeds an unlock rule and returns it with its new ID
+func (s *Store) AddUnlockRule(rule *repositories.UnlockRuleEntity) *repositories.UnlockRuleEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(rule)
+	row.ID = s.nextId("unlock_rules")
+	row.CreatedAt = s.now()
+	s.unlockRules = append(s.unlockRules, row)
+	return clone(row)
+}
+
+// AddUpgradeRecipe seeds an upgrade recipe and its costs and returns the recipe with its new ID
+func (s *Store) AddUpgradeRecipe(recipe *repositories.UpgradeRecipeEntity, costs []*repositories.UpgradeRecipeCostEntity) *repositories.UpgradeRecipeEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(recipe)
+	row.ID = s.nextId("upgrade_recipes")
+	row.CreatedAt, row.ModifiedAt = s.timestamp()
+	s.upgradeRecipes = append(s.upgradeRecipes, row)
+
+	for _, cost := range costs {
+		costRow := clone(cost)
+		costRow.ID = s.nextId("upgrade_recipe_costs")
+		costRow.CreatedAt, costRow.ModifiedAt = s.timestamp()
+		costRow.RecipeID = row.ID
+		s.upgradeRecipeCosts = append(s.upgradeRecipeCosts, costRow)
+	}
+	return clone(row)
+}
+
+// AddFeatureFlag seeds a feature flag and returns it with its new ID
+func (s *Store) AddFeatureFlag(flag *repositories.FeatureFlagEntity) *repositories.FeatureFlagEntity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	row := clone(flag)
+	row.ID = s.nextId("feature_flags")
+	row.CreatedAt = s.now()
+	s.featureFlags = append(s.featureFlags, row)
+	return clone(row)
+}
+
+// AddLeaderboardCache seeds the sync metadata for a leaderboard type
+func (s *Store) AddLeaderboardCache(leaderboardType string, lastSynced time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.leaderboardCaches = append(s.leaderboardCaches, &repositories.LeaderboardCacheEntity{
+		ID:              int(s.nextId("leaderboard_cache")),
+		LeaderboardType: leaderboardType,
+		LastSynced:      lastSynced,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+}
+
+// snapshot copies every row so the store can be rolled back with restore
+func (s *Store) snapshot() tables {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lastIds := make(map[string]int64, len(s.lastIds))
+	for table, id := range s.lastIds {
+		lastIds[table] = id
+	}
+	return tables{
+		lastIds:            lastIds,
+		users:              cloneRows(s.users),
+		featureFlags:       cloneRows(s.featureFlags),
+		rifts:              cloneRows(s.rifts),
+		lootItems:          cloneRows(s.lootItems),
+		dropTables:         cloneRows(s.dropTables),
+		unlockRules:        cloneRows(s.unlockRules),
+		upgradeRecipes:     cloneRows(s.upgradeRecipes),
+		upgradeRecipeCosts: cloneRows(s.upgradeRecipeCosts),
+		teams:              cloneRows(s.teams),
+		inventory:          cloneRows(s.inventory),
+		collectedItems:     cloneRows(s.collectedItems),
+		expeditions:        cloneRows(s.expeditions),
+		expeditionLoot:     cloneRows(s.expeditionLoot),
+		lootRolls:          cloneRows(s.lootRolls),
+		launchQueue:        cloneRows(s.launchQueue),
+		leaderboardCaches:  cloneRows(s.leaderboardCaches),
+		leaderboardItems:   cloneRows(s.leaderboardItems),
+		notifications:      cloneRows(s.notifications),
+		echoEncounters:     cloneRows(s.echoEncounters),
+		echoEncounterLoot:  cloneRows(s.echoEncounterLoot),
+		guilds:             cloneRows(s.guilds),
+		guildMembers:       cloneRows(s.guildMembers),
+		guildTreasury:      cloneRows(s.guildTreasury),
+		trades:             cloneRows(s.trades),
+		tradeItems:         cloneRows(s.tradeItems),
+		userSessions:       cloneRows(s.userSessions),
+		usedTokens:         cloneRows(s.usedTokens),
+		userLoginHistory:   cloneRows(s.userLoginHistory),
+		userTwoFactor:      cloneRows(s.userTwoFactor),
+		userRecoveryCodes:  cloneRows(s.userRecoveryCodes),
+	}
+}
+
+func (s *Store) restore(snapshot tables) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tables = snapshot
+}
+
+// nextId hands out SERIAL ids per table. Must be called with the mutex held.
+func (s *Store) nextId(table string) int64 {
+	s.lastIds[table]++
+	return s.lastIds[table]
+}
+
+// timestamp returns NOW() for created_at/modified_at columns. Must be called with the mutex held.
+func (s *Store) timestamp() (time.Time, *time.Time) {
+	now := s.now()
+	return now, &now
+}
+
+// findLootItem returns the stored loot item row, or nil. Must be called with the mutex held.
+func (s *Store) findLootItem(lootItemId int64) *repositories.LootItemEntity {
+	return findRow(s.lootItems, func(item *repositories.LootItemEntity) bool {
+		return item.ID == lootItemId
+	})
+}
+
+// lootItemName is used to sort inventory rows by item name. Must be called with the mutex held.
+func (s *Store) lootItemName(lootItemId int64) string {
+	if item := s.findLootItem(lootItemId); item != nil {
+		return item.Name
+	}
+	return ""
+}
+
+// equippedInventoryIds returns the inventory rows equipped on any of the user's teams.
+// Must be called with the mutex held.
+func (s *Store) equippedInventoryIds(userId int64) map[int64]bool {
+	equipped := make(map[int64]bool)
+	for _, team := range s.teams {
+		if team.UserID != userId || team.IsArchived {
+			continue
+		}
+		for _, slot := range equipmentSlots {
+			if id := *teamSlot(team, slot); id != nil {
+				equipped[*id] = true
+			}
+		}
+	}
+	return equipped
+}
+
+// countEchoEncounters counts the encounters the user is on either side of. Must be called with the mutex held.
+func (s *Store) countEchoEncounters(userId int64) int {
+	count := 0
+	for _, encounter := range s.echoEncounters {
+		if (encounter.UserID1 == userId || encounter.UserID2 == userId) && !encounter.IsArchived {
+			count++
+		}
+	}
+	return count
+}
+
+// spend takes quantity off an inventory row. Like the quantity >= 1 CHECK in Postgres, a
+// row is archived rather than going to zero. Must be called with the mutex held.
+func (s *Store) spend(item *repositories.UserInventoryEntity, quantity int) {
+	if item.Quantity <= quantity {
+		item.IsArchived = true
+	} else {
+		item.Quantity -= quantity
+	}
+	_, item.ModifiedAt = s.timestamp()
+}
+
+func clone[T any](row *T) *T {
+	copied := *row
+	return &copied
+}
+
+// cloneRows copies every row of a table
+func cloneRows[T any](rows []*T) []*T {
+	copied := make([]*T, len(rows))
+	for i, row := range rows {
+		copied[i] = clone(row)
+	}
+	return copied
+}
+
+// findRow returns the first row that matches, or nil
+func findRow[T any](rows []*T, match func(row *T) bool) *T {
+	for _, row := range rows {
+		if match(row) {
+			return row
+		}
+	}
+	return nil
+}
+
+// selectRows returns copies of every row that matches, in id order
+func selectRows[T any](rows []*T, match func(row *T) bool) []*T {
+	selected := []*T{}
+	for _, row := range rows {
+		if match(row) {
+			selected = append(selected, clone(row))
+		}
+	}
+	return selected
+}
+
+// sortRows sorts rows by less, keeping id order between rows that compare equal
+func sortRows[T any](rows []*T, less func(a, b *T) bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+}
+
+// Postgres sorts enum columns in the order their values were declared, not alphabetically
+
+var rarityOrder = map[string]int{
+	string(models.ItemRarityCommon):    0,
+	string(models.ItemRarityUncommon):  1,
+	string(models.ItemRarityRare):      2,
+	string(models.ItemRarityEpic):      3,
+	string(models.ItemRarityLegendary): 4,
+}
+
+var difficultyOrder = map[string]int{
+	string(models.DifficultyTutorial):  0,
+	string(models.DifficultyEasy):      1,
+	string(models.DifficultyMedium):    2,
+	string(models.DifficultyHard):      3,
+	string(models.DifficultyLegendary): 4,
+}
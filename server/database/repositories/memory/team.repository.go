@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type TeamRepository struct {
+	store *Store
+}
+
+func NewTeamRepository(store *Store) repositories.ITeamRepository {
+	return &TeamRepository{
+		store: store,
+	}
+}
+
+func (r *TeamRepository) GetTeamsByUserId(userId int64) ([]*repositories.TeamEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	teams := selectRows(r.store.teams, func(team *repositories.TeamEntity) bool {
+		return team.UserID == userId && !team.IsArchived
+	})
+	sortRows(teams, func(a, b *repositories.TeamEntity) bool {
+		return a.TeamNumber < b.TeamNumber
+	})
+	return teams, nil
+}
+
+func (r *TeamRepository) GetTeamById(teamId int64) (*repositories.TeamEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	team := r.findTeam(teamId)
+	if team == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(team), nil
+}
+
+// GetTeamByIdForUpdate is the same as GetTeamById, the UnitOfWork already runs one
+// transaction at a time
+func (r *TeamRepository) GetTeamByIdForUpdate(teamId int64) (*repositories.TeamEntity, error) {
+	return r.GetTeamById(teamId)
+}
+
+// CreateTeamsForUser creates teams 1-5 with only team 1 unlocked
+func (r *TeamRepository) CreateTeamsForUser(userId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// UNIQUE(user_id, team_number)
+	existing := findRow(r.store.teams, func(team *repositories.TeamEntity) bool {
+		return team.UserID == userId
+	})
+	if existing != nil {
+		return fmt.Errorf("teams already exist for user %d", userId)
+	}
+
+	for teamNumber := 1; teamNumber <= 5; teamNumber++ {
+		createdAt, modifiedAt := r.store.timestamp()
+		r.store.teams = append(r.store.teams, &repositories.TeamEntity{
+			ID:             r.store.nextId("teams"),
+			CreatedAt:      createdAt,
+			ModifiedAt:     modifiedAt,
+			UserID:         userId,
+			TeamNumber:     teamNumber,
+			IsUnlocked:     teamNumber == 1,
+			Specialization: string(models.SpecializationNone),
+		})
+	}
+	return nil
+}
+
+func (r *TeamRepository) UpdateTeamStats(teamId int64, speedBonus, luckBonus float64, powerBonus int) error {
+	return r.updateTeam(teamId, func(team *repositories.TeamEntity) {
+		team.SpeedBonus += speedBonus
+		team.LuckBonus += luckBonus
+		team.PowerBonus += powerBonus
+	})
+}
+
+func (r *TeamRepository) EquipItem(teamId int64, slot string, inventoryId *int64) error {
+	if !slices.Contains(equipmentSlots, slot) {
+		return fmt.Errorf("invalid equipment slot: %s", slot)
+	}
+
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// The slot columns are foreign keys to user_inventory
+	if inventoryId != nil {
+		item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+			return item.ID == *inventoryId
+		})
+		if item == nil {
+			return fmt.Errorf("inventory item %d does not exist", *inventoryId)
+		}
+	}
+
+	team := findRow(r.store.teams, func(team *repositories.TeamEntity) bool {
+		return team.ID == teamId
+	})
+	if team == nil {
+		return nil
+	}
+
+	var equipped *int64
+	if inventoryId != nil {
+		id := *inventoryId
+		equipped = &id
+	}
+	*teamSlot(team, slot) = equipped
+	_, team.ModifiedAt = r.store.timestamp()
+	return nil
+}
+
+func (r *TeamRepository) UnequipItem(teamId int64, slot string) error {
+	return r.EquipItem(teamId, slot, nil)
+}
+
+func (r *TeamRepository) UnlockTeam(teamId int64) error {
+	return r.updateTeam(teamId, func(team *repositories.TeamEntity) {
+		team.IsUnlocked = true
+	})
+}
+
+// GetTeamsByUserIdWithSlot returns the team and slot an inventory item is equipped in,
+// or nils if it isn't equipped anywhere
+func (r *TeamRepository) GetTeamsByUserIdWithSlot(userId int64, inventoryId int64) (*repositories.TeamEntity, *string, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, team := range r.store.teams {
+		if team.UserID != userId || team.IsArchived {
+			continue
+		}
+		for _, slot := range equipmentSlots {
+			equipped := *teamSlot(team, slot)
+			if equipped != nil && *equipped == inventoryId {
+				slotName := slot
+				return clone(team), &slotName, nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// SetSpecialization only updates teams that don't have a specialization yet.
+// Returns false if the team was already specialized.
+func (r *TeamRepository) SetSpecialization(teamId int64, specialization string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	team := r.findTeam(teamId)
+	if team == nil || team.Specialization != string(models.SpecializationNone) {
+		return false, nil
+	}
+	team.Specialization = specialization
+	_, team.ModifiedAt = r.store.timestamp()
+	return true, nil
+}
+
+func (r *TeamRepository) WithTx(tx *database.AppDataSource) repositories.ITeamRepository {
+	return r
+}
+
+// findTeam returns the stored row for an unarchived team. Must be called with the mutex held.
+func (r *TeamRepository) findTeam(teamId int64) *repositories.TeamEntity {
+	return findRow(r.store.teams, func(team *repositories.TeamEntity) bool {
+		return team.ID == teamId && !team.IsArchived
+	})
+}
+
+// updateTeam applies update to a team row. Like an UPDATE, a missing team is not an error.
+func (r *TeamRepository) updateTeam(teamId int64, update func(team *repositories.TeamEntity)) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	team := findRow(r.store.teams, func(team *repositories.TeamEntity) bool {
+		return team.ID == teamId
+	})
+	if team == nil {
+		return nil
+	}
+	update(team)
+	_, team.ModifiedAt = r.store.timestamp()
+	return nil
+}
+
+var equipmentSlots = []string{
+	string(models.EquipmentSlotWeapon),
+	string(models.EquipmentSlotArmor),
+	string(models.EquipmentSlotAccessory),
+	string(models.EquipmentSlotArtifact),
+	string(models.EquipmentSlotRelic),
+}
+
+// teamSlot returns the team's column for an equipment slot, or nil if the slot doesn't exist
+func teamSlot(team *repositories.TeamEntity, slot string) **int64 {
+	switch models.EquipmentSlotType(slot) {
+	case models.EquipmentSlotWeapon:
+		return &team.EquippedWeaponSlot
+	case models.EquipmentSlotArmor:
+		return &team.EquippedArmorSlot
+	case models.EquipmentSlotAccessory:
+		return &team.EquippedAccessorySlot
+	case models.EquipmentSlotArtifact:
+		return &team.EquippedArtifactSlot
+	case models.EquipmentSlotRelic:
+		return &team.EquippedRelicSlot
+	}
+	return nil
+}
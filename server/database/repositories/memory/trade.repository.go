@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type TradeRepository struct {
+	store *Store
+}
+
+func NewTradeRepository(store *Store) repositories.ITradeRepository {
+	return &TradeRepository{
+		store: store,
+	}
+}
+
+func (r *TradeRepository) CreateTrade(proposerId, recipientId int64, expiresAt time.Time) (*repositories.TradeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// CHECK trades_different_players
+	if proposerId == recipientId {
+		return nil, fmt.Errorf("user %d can't trade with themselves", proposerId)
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	trade := &repositories.TradeEntity{
+		ID:          r.store.nextId("trades"),
+		CreatedAt:   createdAt,
+		ModifiedAt:  modifiedAt,
+		ProposerID:  proposerId,
+		RecipientID: recipientId,
+		Status:      string(models.TradeStatusPending),
+		ExpiresAt:   expiresAt,
+	}
+	r.store.trades = append(r.store.trades, trade)
+	return clone(trade), nil
+}
+
+// AddTradeItem returns an error if the inventory row is already in the trade, the same
+// as unique_trade_item
+func (r *TradeRepository) AddTradeItem(tradeId, inventoryId, fromUserId, lootItemId int64) (*repositories.TradeItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if findRow(r.store.tradeItems, func(item *repositories.TradeItemEntity) bool {
+		return item.TradeID == tradeId && item.InventoryID == inventoryId
+	}) != nil {
+		return nil, fmt.Errorf("inventory item %d is already in trade %d", inventoryId, tradeId)
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	item := &repositories.TradeItemEntity{
+		ID:          r.store.nextId("trade_items"),
+		CreatedAt:   createdAt,
+		ModifiedAt:  modifiedAt,
+		TradeID:     tradeId,
+		InventoryID: inventoryId,
+		FromUserID:  fromUserId,
+		LootItemID:  lootItemId,
+	}
+	r.store.tradeItems = append(r.store.tradeItems, item)
+	return clone(item), nil
+}
+
+func (r *TradeRepository) GetTradeById(tradeId int64) (*repositories.TradeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	trade := r.findTrade(tradeId)
+	if trade == nil {
+		return nil, nil
+	}
+	return r.withNames(clone(trade)), nil
+}
+
+// GetTradeByIdForUpdate doesn't need to lock anything, UnitOfWork already runs one
+// transaction at a time
+func (r *TradeRepository) GetTradeByIdForUpdate(tradeId int64) (*repositories.TradeEntity, error) {
+	return r.GetTradeById(tradeId)
+}
+
+func (r *TradeRepository) GetTradeItems(tradeId int64) ([]*repositories.TradeItemEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return selectRows(r.store.tradeItems, func(item *repositories.TradeItemEntity) bool {
+		return item.TradeID == tradeId && !item.IsArchived
+	}), nil
+}
+
+// GetPendingTradesByUserId returns the pending trades the user proposed or was offered, newest first
+func (r *TradeRepository) GetPendingTradesByUserId(userId int64) ([]*repositories.TradeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	trades := r.selectUserTrades(userId, func(trade *repositories.TradeEntity) bool {
+		return trade.Status == string(models.TradeStatusPending)
+	})
+	sortRows(trades, func(a, b *repositories.TradeEntity) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return trades, nil
+}
+
+// GetTradeHistoryByUserId returns the user's resolved trades, most recently resolved first
+func (r *TradeRepository) GetTradeHistoryByUserId(userId int64, limit int) ([]*repositories.TradeEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	trades := r.selectUserTrades(userId, func(trade *repositories.TradeEntity) bool {
+		return trade.Status != string(models.TradeStatusPending)
+	})
+	sortRows(trades, func(a, b *repositories.TradeEntity) bool {
+		if !a.ResolvedAt.Equal(*b.ResolvedAt) {
+			return a.ResolvedAt.After(*b.ResolvedAt)
+		}
+		return a.ID > b.ID
+	})
+	return trades[:min(limit, len(trades))], nil
+}
+
+func (r *TradeRepository) CountPendingTradesByProposer(proposerId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return len(selectRows(r.store.trades, func(trade *repositories.TradeEntity) bool {
+		return trade.ProposerID == proposerId && trade.Status == string(models.TradeStatusPending) && !trade.IsArchived
+	})), nil
+}
+
+// GetExpiredTradeIds returns pending trades whose time is up by the store's clock, oldest first
+func (r *TradeRepository) GetExpiredTradeIds(limit int) ([]int64, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	expired := selectRows(r.store.trades, func(trade *repositories.TradeEntity) bool {
+		return trade.Status == string(models.TradeStatusPending) && !trade.ExpiresAt.After(now) && !trade.IsArchived
+	})
+	sortRows(expired, func(a, b *repositories.TradeEntity) bool {
+		return a.ExpiresAt.Before(b.ExpiresAt)
+	})
+
+	ids := []int64{}
+	for _, trade := range expired[:min(limit, len(expired))] {
+		ids = append(ids, trade.ID)
+	}
+	return ids, nil
+}
+
+func (r *TradeRepository) ResolveTrade(tradeId int64, status string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	trade := r.findTrade(tradeId)
+	if trade == nil || trade.Status != string(models.TradeStatusPending) {
+		return false, nil
+	}
+	trade.Status = status
+	now, modifiedAt := r.store.timestamp()
+	trade.ResolvedAt = &now
+	trade.ModifiedAt = modifiedAt
+	return true, nil
+}
+
+func (r *TradeRepository) WithTx(tx *database.AppDataSource) repositories.ITradeRepository {
+	return r
+}
+
+// findTrade returns the stored unarchived trade, or nil. Must be called with the mutex held.
+func (r *TradeRepository) findTrade(tradeId int64) *repositories.TradeEntity {
+	return findRow(r.store.trades, func(trade *repositories.TradeEntity) bool {
+		return trade.ID == tradeId && !trade.IsArchived
+	})
+}
+
+// selectUserTrades returns copies of the unarchived trades the user is on either side of
+// that match, with both players' names. Must be called with the mutex held.
+func (r *TradeRepository) selectUserTrades(userId int64, match func(trade *repositories.TradeEntity) bool) []*repositories.TradeEntity {
+	trades := selectRows(r.store.trades, func(trade *repositories.TradeEntity) bool {
+		return (trade.ProposerID == userId || trade.RecipientID == userId) && !trade.IsArchived && match(trade)
+	})
+	for _, trade := range trades {
+		r.withNames(trade)
+	}
+	return trades
+}
+
+// withNames fills in both players' display names the way the users joins do.
+// Must be called with the mutex held.
+func (r *TradeRepository) withNames(trade *repositories.TradeEntity) *repositories.TradeEntity {
+	for _, user := range r.store.users {
+		switch user.ID {
+		case trade.ProposerID:
+			trade.ProposerName = user.DisplayName
+		case trade.RecipientID:
+			trade.RecipientName = user.DisplayName
+		}
+	}
+	return trade
+}
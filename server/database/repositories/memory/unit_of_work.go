@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// UnitOfWork runs transactions one at a time, which stands in for the row locks the
+// Postgres transactions take. If fn fails the store is rolled back to how it was before
+// the transaction started. Writes made outside a transaction while it runs are rolled
+// back with it, and reads outside it see its writes straight away.
+//
+// Memory repositories ignore the data source they are given by WithTx, so fn is passed nil.
+type UnitOfWork struct {
+	store *Store
+}
+
+func NewUnitOfWork(store *Store) database.IUnitOfWork {
+	return &UnitOfWork{
+		store: store,
+	}
+}
+
+func (u *UnitOfWork) WithinTransaction(fn func(tx *database.AppDataSource) error) (err error) {
+	u.store.txMutex.Lock()
+	defer u.store.txMutex.Unlock()
+
+	snapshot := u.store.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			u.store.restore(snapshot)
+			panic(p)
+		}
+	}()
+
+	if err := fn(nil); err != nil {
+		u.store.restore(snapshot)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+func TestUnitOfWorkRollsBackOnError(t *testing.T) {
+	store := NewStore()
+	users := NewUserRepository(store)
+	unitOfWork := NewUnitOfWork(store)
+
+	failed := errors.New("failed")
+	err := unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		if _, err := users.CreateNewUser(&models.UserCreateDTO{Email: "rollback@test"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithinTransaction() error = %v, want %v", err, failed)
+	}
+	if _, err := users.GetUserByEmail("rollback@test"); err == nil {
+		t.Error("the user created in the failed transaction should be rolled back")
+	}
+
+	err = unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		_, err := users.CreateNewUser(&models.UserCreateDTO{Email: "commit@test"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetUserByEmail("commit@test"); err != nil {
+		t.Errorf("the user created in the committed transaction should be kept, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type UsedTokenRepository struct {
+	store *Store
+}
+
+func NewUsedTokenRepository(store *Store) repositories.IUsedTokenRepository {
+	return &UsedTokenRepository{
+		store: store,
+	}
+}
+
+func (r *UsedTokenRepository) MarkTokenUsed(jti string, userId int64, purpose string, expiresAt time.Time) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if findRow(r.store.usedTokens, func(token *repositories.UsedTokenEntity) bool {
+		return token.JTI == jti
+	}) != nil {
+		return false, nil
+	}
+
+	r.store.usedTokens = append(r.store.usedTokens, &repositories.UsedTokenEntity{
+		JTI:       jti,
+		UserID:    userId,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+		CreatedAt: r.store.now(),
+	})
+	return true, nil
+}
+
+// DeleteExpiredTokens forgets tokens that have expired by the store's clock
+func (r *UsedTokenRepository) DeleteExpiredTokens() error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	r.store.usedTokens = slices.DeleteFunc(r.store.usedTokens, func(token *repositories.UsedTokenEntity) bool {
+		return !token.ExpiresAt.After(now)
+	})
+	return nil
+}
+
+func (r *UsedTokenRepository) WithTx(tx *database.AppDataSource) repositories.IUsedTokenRepository {
+	return r
+}
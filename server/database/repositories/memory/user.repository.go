@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) repositories.IUserRepository {
+	return &UserRepository{
+		store: store,
+	}
+}
+
+// ToggleUserArchived toggles the archived status of a user and clears the password hash.
+func (r *UserRepository) ToggleUserArchived(userId *int) error {
+	return r.updateUser(userId, func(user *repositories.UserEntity) {
+		cleared := ""
+		user.IsArchived = !user.IsArchived
+		user.PasswordHash = &cleared
+		_, user.ModifiedAt = r.store.timestamp()
+	})
+}
+
+func (r *UserRepository) GetUsersCount(searchString string, statusFilter string, userTypeFilter string) (*int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	count := len(r.selectUsers(searchString, statusFilter, userTypeFilter))
+	return &count, nil
+}
+
+func (r *UserRepository) GetUsers(pageSize int, offset int, searchString string, statusFilter string, userTypeFilter string) ([]*repositories.UserEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	users := r.selectUsers(searchString, statusFilter, userTypeFilter)
+	sortRows(users, func(a, b *repositories.UserEntity) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	if offset >= len(users) {
+		return []*repositories.UserEntity{}, nil
+	}
+	users = users[offset:]
+	if len(users) > pageSize {
+		users = users[:pageSize]
+	}
+	return users, nil
+}
+
+func (r *UserRepository) GetUserById(id int) (*repositories.UserEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	user := findRow(r.store.users, func(user *repositories.UserEntity) bool {
+		return user.ID == int64(id)
+	})
+	if user == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(user), nil
+}
+
+func (r *UserRepository) GetUserByEmail(email string) (*repositories.UserEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	user := r.findByEmail(email)
+	if user == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(user), nil
+}
+
+func (r *UserRepository) CreateNewUser(dto *models.UserCreateDTO) (*repositories.UserEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	// users.email is UNIQUE
+	if r.findByEmail(dto.Email) != nil {
+		return nil, fmt.Errorf("user with email %s already exists", dto.Email)
+	}
+
+	password := dto.Password
+	user := &repositories.UserEntity{
+		ID:           r.store.nextId("users"),
+		CreatedAt:    r.store.now(),
+		Email:        dto.Email,
+		DisplayName:  dto.DisplayName,
+		PasswordHash: &password,
+	}
+	r.store.users = append(r.store.users, user)
+	return clone(user), nil
+}
+
+func (r *UserRepository) MarkUserVerified(userId *int) (bool, error) {
+	err := r.updateUser(userId, func(user *repositories.UserEntity) {
+		user.IsVerified = true
+		_, user.ModifiedAt = r.store.timestamp()
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateUser ignores UserTypeKey, the users table has no user type column
+func (r *UserRepository) UpdateUser(dto *models.UserUpdateDTO, userId *int) (*repositories.UserEntity, error) {
+	err := r.updateUser(userId, func(user *repositories.UserEntity) {
+		user.Email = dto.Email
+		user.DisplayName = dto.DisplayName
+		user.AvatarURL = dto.AvatarURL
+		user.ProfileText = dto.ProfileText
+		_, user.ModifiedAt = r.store.timestamp()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetUserById(*userId)
+}
+
+func (r *UserRepository) UpdateUserLastLogin(userId *int) (bool, error) {
+	err := r.updateUser(userId, func(user *repositories.UserEntity) {
+		now := r.store.now()
+		user.LastLogin = &now
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *UserRepository) UpdateUserPassword(userId *int, password string) (bool, error) {
+	err := r.updateUser(userId, func(user *repositories.UserEntity) {
+		user.PasswordHash = &password
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// selectUsers returns copies of the users matching the admin list filters, in id order.
+// Like LIKE, the search is case sensitive. Must be called with the mutex held.
+func (r *UserRepository) selectUsers(searchString string, statusFilter string, userTypeFilter string) []*repositories.UserEntity {
+	return selectRows(r.store.users, func(user *repositories.UserEntity) bool {
+		if !strings.Contains(user.Email, searchString) && !strings.Contains(user.DisplayName, searchString) {
+			return false
+		}
+		// Users can't be banned or given a user type yet, so only the archived filters match anyone
+		switch statusFilter {
+		case "active":
+			if user.IsArchived {
+				return false
+			}
+		case "archived":
+			if !user.IsArchived {
+				return false
+			}
+		case "banned":
+			return false
+		}
+		return userTypeFilter == ""
+	})
+}
+
+// findByEmail returns the stored user row, or nil. Must be called with the mutex held.
+func (r *UserRepository) findByEmail(email string) *repositories.UserEntity {
+	return findRow(r.store.users, func(user *repositories.UserEntity) bool {
+		return user.Email == email
+	})
+}
+
+// updateUser applies update to a user row. Like an UPDATE, a missing user is not an error.
+func (r *UserRepository) updateUser(userId *int, update func(user *repositories.UserEntity)) error {
+	if userId == nil {
+		return nil
+	}
+
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	user := findRow(r.store.users, func(user *repositories.UserEntity) bool {
+		return user.ID == int64(*userId)
+	})
+	if user == nil {
+		return nil
+	}
+	update(user)
+	return nil
+}
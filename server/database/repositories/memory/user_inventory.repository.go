@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"database/sql"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type UserInventoryRepository struct {
+	store *Store
+}
+
+func NewUserInventoryRepository(store *Store) repositories.IUserInventoryRepository {
+	return &UserInventoryRepository{
+		store: store,
+	}
+}
+
+func (r *UserInventoryRepository) GetInventoryByUserId(userId int64) ([]*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	inventory := selectRows(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.UserID == userId && !item.IsArchived
+	})
+	sortRows(inventory, func(a, b *repositories.UserInventoryEntity) bool {
+		return a.AcquiredAt.After(b.AcquiredAt)
+	})
+	return inventory, nil
+}
+
+func (r *UserInventoryRepository) GetInventoryById(inventoryId int64) (*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.ID == inventoryId && !item.IsArchived
+	})
+	if item == nil {
+		return nil, sql.ErrNoRows
+	}
+	return clone(item), nil
+}
+
+func (r *UserInventoryRepository) GetEquipmentByUserId(userId int64) ([]*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	inventory := r.selectOwned(userId, func(lootItem *repositories.LootItemEntity) bool {
+		return lootItem.ItemType == string(models.ItemTypeEquipment)
+	})
+	sortRows(inventory, func(a, b *repositories.UserInventoryEntity) bool {
+		return a.AcquiredAt.After(b.AcquiredAt)
+	})
+	return inventory, nil
+}
+
+func (r *UserInventoryRepository) GetConsumablesByUserId(userId int64) ([]*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	inventory := r.selectOwned(userId, func(lootItem *repositories.LootItemEntity) bool {
+		return lootItem.ItemType == string(models.ItemTypeConsumable)
+	})
+	sortRows(inventory, func(a, b *repositories.UserInventoryEntity) bool {
+		return r.store.lootItemName(a.LootItemID) < r.store.lootItemName(b.LootItemID)
+	})
+	return inventory, nil
+}
+
+// GetInventoryByUserAndItem returns nil if the user doesn't own the item
+func (r *UserInventoryRepository) GetInventoryByUserAndItem(userId int64, lootItemId int64) (*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := r.findStack(userId, lootItemId)
+	if item == nil {
+		return nil, nil
+	}
+	return clone(item), nil
+}
+
+// AddLoot always adds a new row for equipment, consumables stack onto the user's existing row
+func (r *UserInventoryRepository) AddLoot(userId int64, lootItemId int64, itemType string) (*repositories.UserInventoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	r.collect(userId, lootItemId)
+	if itemType != string(models.ItemTypeEquipment) {
+		if existing := r.findStack(userId, lootItemId); existing != nil {
+			existing.Quantity++
+			_, existing.ModifiedAt = r.store.timestamp()
+			return clone(existing), nil
+		}
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	item := &repositories.UserInventoryEntity{
+		ID:         r.store.nextId("user_inventory"),
+		CreatedAt:  createdAt,
+		ModifiedAt: modifiedAt,
+		UserID:     userId,
+		LootItemID: lootItemId,
+		Quantity:   1,
+		AcquiredAt: createdAt,
+		IsStack:    itemType != string(models.ItemTypeEquipment),
+	}
+	r.store.inventory = append(r.store.inventory, item)
+	return clone(item), nil
+}
+
+// ConsumeLoot decrements quantity and archives the item when the last one is used
+func (r *UserInventoryRepository) ConsumeLoot(inventoryId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.ID == inventoryId
+	})
+	if item == nil {
+		return nil
+	}
+	r.store.spend(item, 1)
+	return nil
+}
+
+// SpendLoot takes quantity off an inventory row. Returns repositories.ErrNotEnoughItems
+// without spending anything if the row doesn't hold that many, or is equipped or escrowed.
+func (r *UserInventoryRepository) SpendLoot(inventoryId int64, quantity int) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.ID == inventoryId && !item.IsArchived && item.EscrowTradeID == nil
+	})
+	if item == nil || item.Quantity < quantity || r.store.equippedInventoryIds(item.UserID)[item.ID] {
+		return repositories.ErrNotEnoughItems
+	}
+	r.store.spend(item, quantity)
+	return nil
+}
+
+func (r *UserInventoryRepository) HasItemByName(userId int64, itemName string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	owned := r.selectOwned(userId, func(lootItem *repositories.LootItemEntity) bool {
+		return lootItem.Name == itemName
+	})
+	return len(owned) > 0, nil
+}
+
+// GetCollectedLootItemIds returns every loot item the user has ever owned, including ones
+// used up or traded away
+func (r *UserInventoryRepository) GetCollectedLootItemIds(userId int64) ([]int64, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	ids := []int64{}
+	for _, collected := range r.store.collectedItems {
+		if collected.UserID == userId {
+			ids = append(ids, collected.LootItemID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *UserInventoryRepository) CountItemsByRarity(userId int64, rarity string) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return sumQuantity(r.selectOwned(userId, func(lootItem *repositories.LootItemEntity) bool {
+		return lootItem.Rarity == rarity
+	})), nil
+}
+
+func (r *UserInventoryRepository) CountUnequippedItemsByRarity(userId int64, rarity string) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return sumQuantity(r.selectUnequippedByRarity(userId, rarity)), nil
+}
+
+// ConsumeItemsByRarity spends quantity unequipped items of a rarity, oldest first.
+// Returns repositories.ErrNotEnoughItems without consuming anything if the user doesn't own enough.
+func (r *UserInventoryRepository) ConsumeItemsByRarity(userId int64, rarity string, quantity int) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	items := r.selectUnequippedByRarity(userId, rarity)
+	if sumQuantity(items) < quantity {
+		return repositories.ErrNotEnoughItems
+	}
+
+	remaining := quantity
+	for _, item := range items {
+		if remaining == 0 {
+			break
+		}
+		row := findRow(r.store.inventory, func(row *repositories.UserInventoryEntity) bool {
+			return row.ID == item.ID
+		})
+		spent := min(item.Quantity, remaining)
+		r.store.spend(row, spent)
+		remaining -= spent
+	}
+	return nil
+}
+
+func (r *UserInventoryRepository) EscrowItem(inventoryId, userId, tradeId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.ID == inventoryId && item.UserID == userId && !item.IsArchived && item.EscrowTradeID == nil
+	})
+	if item == nil {
+		return false, nil
+	}
+	item.EscrowTradeID = &tradeId
+	_, item.ModifiedAt = r.store.timestamp()
+	return true, nil
+}
+
+func (r *UserInventoryRepository) ReleaseEscrow(tradeId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, item := range r.store.inventory {
+		if item.EscrowTradeID != nil && *item.EscrowTradeID == tradeId {
+			item.EscrowTradeID = nil
+			_, item.ModifiedAt = r.store.timestamp()
+		}
+	}
+	return nil
+}
+
+func (r *UserInventoryRepository) TransferEscrowedItem(inventoryId, tradeId, toUserId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	item := findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.ID == inventoryId && item.EscrowTradeID != nil && *item.EscrowTradeID == tradeId && !item.IsArchived
+	})
+	if item == nil {
+		return false, nil
+	}
+	item.UserID = toUserId
+	item.EscrowTradeID = nil
+	r.collect(toUserId, item.LootItemID)
+	_, item.ModifiedAt = r.store.timestamp()
+	return true, nil
+}
+
+func (r *UserInventoryRepository) WithTx(tx *database.AppDataSource) repositories.IUserInventoryRepository {
+	return r
+}
+
+// collect records the item in the user's collection unless it is already there, the
+// same as the user_collected_items primary key. Must be called with the mutex held.
+func (r *UserInventoryRepository) collect(userId, lootItemId int64) {
+	if findRow(r.store.collectedItems, func(collected *repositories.UserCollectedItemEntity) bool {
+		return collected.UserID == userId && collected.LootItemID == lootItemId
+	}) != nil {
+		return
+	}
+	r.store.collectedItems = append(r.store.collectedItems, &repositories.UserCollectedItemEntity{
+		UserID:      userId,
+		LootItemID:  lootItemId,
+		CollectedAt: r.store.now(),
+	})
+}
+
+// findStack returns the stored row for the user's unarchived stack of an item. Must be called with the mutex held.
+func (r *UserInventoryRepository) findStack(userId int64, lootItemId int64) *repositories.UserInventoryEntity {
+	return findRow(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		return item.UserID == userId && item.LootItemID == lootItemId && item.IsStack && !item.IsArchived
+	})
+}
+
+// selectOwned returns copies of the user's unarchived inventory rows whose unarchived loot
+// item matches, in id order. Must be called with the mutex held.
+func (r *UserInventoryRepository) selectOwned(userId int64, match func(lootItem *repositories.LootItemEntity) bool) []*repositories.UserInventoryEntity {
+	return selectRows(r.store.inventory, func(item *repositories.UserInventoryEntity) bool {
+		if item.UserID != userId || item.IsArchived {
+			return false
+		}
+		lootItem := r.store.findLootItem(item.LootItemID)
+		return lootItem != nil && !lootItem.IsArchived && match(lootItem)
+	})
+}
+
+// selectUnequippedByRarity returns the user's items of a rarity that aren't equipped on a team
+// or held in escrow, oldest first. Must be called with the mutex held.
+func (r *UserInventoryRepository) selectUnequippedByRarity(userId int64, rarity string) []*repositories.UserInventoryEntity {
+	equipped := r.store.equippedInventoryIds(userId)
+	items := r.selectOwned(userId, func(lootItem *repositories.LootItemEntity) bool {
+		return lootItem.Rarity == rarity
+	})
+	items = selectRows(items, func(item *repositories.UserInventoryEntity) bool {
+		return !equipped[item.ID] && item.EscrowTradeID == nil
+	})
+	sortRows(items, func(a, b *repositories.UserInventoryEntity) bool {
+		return a.AcquiredAt.Before(b.AcquiredAt)
+	})
+	return items
+}
+
+func sumQuantity(items []*repositories.UserInventoryEntity) int {
+	total := 0
+	for _, item := range items {
+		total += item.Quantity
+	}
+	return total
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type UserLoginHistoryRepository struct {
+	store *Store
+}
+
+func NewUserLoginHistoryRepository(store *Store) repositories.IUserLoginHistoryRepository {
+	return &UserLoginHistoryRepository{
+		store: store,
+	}
+}
+
+func (r *UserLoginHistoryRepository) CreateLoginHistory(userId int64, method, outcome, ipAddress, userAgent string) (*repositories.UserLoginHistoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	login := &repositories.UserLoginHistoryEntity{
+		ID:        r.store.nextId("user_login_history"),
+		CreatedAt: r.store.now(),
+		UserID:    userId,
+		Method:    method,
+		Outcome:   outcome,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	r.store.userLoginHistory = append(r.store.userLoginHistory, login)
+	return clone(login), nil
+}
+
+// GetRecentLoginHistory returns the user's most recent login attempts, newest first
+func (r *UserLoginHistoryRepository) GetRecentLoginHistory(userId int64, limit int) ([]*repositories.UserLoginHistoryEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	logins := selectRows(r.store.userLoginHistory, func(login *repositories.UserLoginHistoryEntity) bool {
+		return login.UserID == userId && !login.IsArchived
+	})
+	sortRows(logins, func(a, b *repositories.UserLoginHistoryEntity) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return logins[:min(limit, len(logins))], nil
+}
+
+func (r *UserLoginHistoryRepository) HasSuccessfulLogin(userId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.findSuccessfulLogin(func(login *repositories.UserLoginHistoryEntity) bool {
+		return login.UserID == userId
+	}) != nil, nil
+}
+
+func (r *UserLoginHistoryRepository) HasSuccessfulLoginFrom(userId int64, userAgent, ipAddress string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.findSuccessfulLogin(func(login *repositories.UserLoginHistoryEntity) bool {
+		return login.UserID == userId && login.UserAgent == userAgent && login.IPAddress == ipAddress
+	}) != nil, nil
+}
+
+func (r *UserLoginHistoryRepository) WithTx(tx *database.AppDataSource) repositories.IUserLoginHistoryRepository {
+	return r
+}
+
+// findSuccessfulLogin returns the first stored unarchived successful login that
+// matches, or nil. Must be called with the mutex held.
+func (r *UserLoginHistoryRepository) findSuccessfulLogin(match func(login *repositories.UserLoginHistoryEntity) bool) *repositories.UserLoginHistoryEntity {
+	return findRow(r.store.userLoginHistory, func(login *repositories.UserLoginHistoryEntity) bool {
+		return login.Outcome == string(models.LoginOutcomeSuccess) && !login.IsArchived && match(login)
+	})
+}
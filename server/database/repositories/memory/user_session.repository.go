@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type UserSessionRepository struct {
+	store *Store
+}
+
+func NewUserSessionRepository(store *Store) repositories.IUserSessionRepository {
+	return &UserSessionRepository{
+		store: store,
+	}
+}
+
+// CreateSession returns an error if the token hash is already in use, the same as the
+// unique constraint on refresh_token_hash
+func (r *UserSessionRepository) CreateSession(userId int64, refreshTokenHash, userAgent, ipAddress string, expiresAt time.Time) (*repositories.UserSessionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if r.findSession(func(session *repositories.UserSessionEntity) bool {
+		return session.RefreshTokenHash == refreshTokenHash
+	}) != nil {
+		return nil, fmt.Errorf("refresh token hash is already in use")
+	}
+
+	createdAt, modifiedAt := r.store.timestamp()
+	session := &repositories.UserSessionEntity{
+		ID:               r.store.nextId("user_sessions"),
+		CreatedAt:        createdAt,
+		ModifiedAt:       modifiedAt,
+		UserID:           userId,
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		ExpiresAt:        expiresAt,
+		LastUsedAt:       createdAt,
+	}
+	r.store.userSessions = append(r.store.userSessions, session)
+	return clone(session), nil
+}
+
+func (r *UserSessionRepository) GetSessionById(sessionId int64) (*repositories.UserSessionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.getSession(func(session *repositories.UserSessionEntity) bool {
+		return session.ID == sessionId
+	}), nil
+}
+
+func (r *UserSessionRepository) GetSessionByTokenHash(refreshTokenHash string) (*repositories.UserSessionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.getSession(func(session *repositories.UserSessionEntity) bool {
+		return session.RefreshTokenHash == refreshTokenHash
+	}), nil
+}
+
+func (r *UserSessionRepository) GetSessionByPreviousTokenHash(refreshTokenHash string) (*repositories.UserSessionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return r.getSession(func(session *repositories.UserSessionEntity) bool {
+		return session.PreviousTokenHash != nil && *session.PreviousTokenHash == refreshTokenHash
+	}), nil
+}
+
+func (r *UserSessionRepository) RotateSession(sessionId int64, oldTokenHash, newTokenHash, userAgent, ipAddress string, expiresAt time.Time) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	session := r.findSession(func(session *repositories.UserSessionEntity) bool {
+		return session.ID == sessionId && session.RefreshTokenHash == oldTokenHash && session.RevokedAt == nil
+	})
+	if session == nil {
+		return false, nil
+	}
+	session.PreviousTokenHash = &oldTokenHash
+	session.RefreshTokenHash = newTokenHash
+	session.UserAgent = userAgent
+	session.IPAddress = ipAddress
+	session.ExpiresAt = expiresAt
+	session.LastUsedAt, session.ModifiedAt = r.store.timestamp()
+	return true, nil
+}
+
+// GetActiveSessionsByUserId returns the user's sessions that haven't been revoked or
+// expired by the store's clock, most recently used first
+func (r *UserSessionRepository) GetActiveSessionsByUserId(userId int64) ([]*repositories.UserSessionEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	sessions := selectRows(r.store.userSessions, func(session *repositories.UserSessionEntity) bool {
+		return session.UserID == userId && session.RevokedAt == nil && session.ExpiresAt.After(now) && !session.IsArchived
+	})
+	sortRows(sessions, func(a, b *repositories.UserSessionEntity) bool {
+		if !a.LastUsedAt.Equal(b.LastUsedAt) {
+			return a.LastUsedAt.After(b.LastUsedAt)
+		}
+		return a.ID > b.ID
+	})
+	return sessions, nil
+}
+
+func (r *UserSessionRepository) RevokeSession(userId, sessionId int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	session := r.findSession(func(session *repositories.UserSessionEntity) bool {
+		return session.ID == sessionId && session.UserID == userId && session.RevokedAt == nil
+	})
+	if session == nil {
+		return false, nil
+	}
+	r.revoke(session)
+	return true, nil
+}
+
+func (r *UserSessionRepository) RevokeAllSessions(userId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for _, session := range r.store.userSessions {
+		if session.UserID == userId && session.RevokedAt == nil {
+			r.revoke(session)
+		}
+	}
+	return nil
+}
+
+func (r *UserSessionRepository) WithTx(tx *database.AppDataSource) repositories.IUserSessionRepository {
+	return r
+}
+
+// findSession returns the first stored unarchived session that matches, or nil.
+// Must be called with the mutex held.
+func (r *UserSessionRepository) findSession(match func(session *repositories.UserSessionEntity) bool) *repositories.UserSessionEntity {
+	return findRow(r.store.userSessions, func(session *repositories.UserSessionEntity) bool {
+		return !session.IsArchived && match(session)
+	})
+}
+
+// getSession returns a copy of the first unarchived session that matches, or nil.
+// Must be called with the mutex held.
+func (r *UserSessionRepository) getSession(match func(session *repositories.UserSessionEntity) bool) *repositories.UserSessionEntity {
+	session := r.findSession(match)
+	if session == nil {
+		return nil
+	}
+	return clone(session)
+}
+
+// revoke marks the stored session revoked. Must be called with the mutex held.
+func (r *UserSessionRepository) revoke(session *repositories.UserSessionEntity) {
+	now, modifiedAt := r.store.timestamp()
+	session.RevokedAt = &now
+	session.ModifiedAt = modifiedAt
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"slices"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+type UserTwoFactorRepository struct {
+	store *Store
+}
+
+func NewUserTwoFactorRepository(store *Store) repositories.IUserTwoFactorRepository {
+	return &UserTwoFactorRepository{
+		store: store,
+	}
+}
+
+func (r *UserTwoFactorRepository) GetTwoFactor(userId int64) (*repositories.UserTwoFactorEntity, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if twoFactor := r.findTwoFactor(userId); twoFactor != nil {
+		return clone(twoFactor), nil
+	}
+	return nil, nil
+}
+
+func (r *UserTwoFactorRepository) SaveTwoFactorSecret(userId int64, secret string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	now := r.store.now()
+	twoFactor := r.findTwoFactor(userId)
+	if twoFactor == nil {
+		r.store.userTwoFactor = append(r.store.userTwoFactor, &repositories.UserTwoFactorEntity{
+			UserID:     userId,
+			Secret:     secret,
+			CreatedAt:  now,
+			ModifiedAt: now,
+		})
+		return true, nil
+	}
+	if twoFactor.EnabledAt != nil {
+		return false, nil
+	}
+
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+	twoFactor.CreatedAt = now
+	twoFactor.ModifiedAt = now
+	return true, nil
+}
+
+func (r *UserTwoFactorRepository) EnableTwoFactor(userId int64, step int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	twoFactor := r.findTwoFactor(userId)
+	if twoFactor == nil || twoFactor.EnabledAt != nil {
+		return false, nil
+	}
+
+	now := r.store.now()
+	twoFactor.EnabledAt = &now
+	twoFactor.LastUsedStep = step
+	twoFactor.ModifiedAt = now
+	return true, nil
+}
+
+func (r *UserTwoFactorRepository) UseTwoFactorStep(userId int64, step int64) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	twoFactor := r.findTwoFactor(userId)
+	if twoFactor == nil || twoFactor.EnabledAt == nil || twoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+
+	twoFactor.LastUsedStep = step
+	twoFactor.ModifiedAt = r.store.now()
+	return true, nil
+}
+
+func (r *UserTwoFactorRepository) DeleteTwoFactor(userId int64) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	r.store.userTwoFactor = slices.DeleteFunc(r.store.userTwoFactor, func(twoFactor *repositories.UserTwoFactorEntity) bool {
+		return twoFactor.UserID == userId
+	})
+	r.deleteRecoveryCodes(userId)
+	return nil
+}
+
+func (r *UserTwoFactorRepository) ReplaceRecoveryCodes(userId int64, codeHashes []string) error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	r.deleteRecoveryCodes(userId)
+	for _, codeHash := range codeHashes {
+		r.store.userRecoveryCodes = append(r.store.userRecoveryCodes, &repositories.UserRecoveryCodeEntity{
+			ID:        r.store.nextId("user_recovery_codes"),
+			UserID:    userId,
+			CodeHash:  codeHash,
+			CreatedAt: r.store.now(),
+		})
+	}
+	return nil
+}
+
+func (r *UserTwoFactorRepository) UseRecoveryCode(userId int64, codeHash string) (bool, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	code := findRow(r.store.userRecoveryCodes, func(code *repositories.UserRecoveryCodeEntity) bool {
+		return code.UserID == userId && code.CodeHash == codeHash && code.UsedAt == nil
+	})
+	if code == nil {
+		return false, nil
+	}
+
+	now := r.store.now()
+	code.UsedAt = &now
+	return true, nil
+}
+
+func (r *UserTwoFactorRepository) CountRecoveryCodes(userId int64) (int, error) {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	return len(selectRows(r.store.userRecoveryCodes, func(code *repositories.UserRecoveryCodeEntity) bool {
+		return code.UserID == userId && code.UsedAt == nil
+	})), nil
+}
+
+func (r *UserTwoFactorRepository) WithTx(tx *database.AppDataSource) repositories.IUserTwoFactorRepository {
+	return r
+}
+
+// findTwoFactor returns the stored row, or nil. Must be called with the mutex held.
+func (r *UserTwoFactorRepository) findTwoFactor(userId int64) *repositories.UserTwoFactorEntity {
+	return findRow(r.store.userTwoFactor, func(twoFactor *repositories.UserTwoFactorEntity) bool {
+		return twoFactor.UserID == userId
+	})
+}
+
+// deleteRecoveryCodes must be called with the mutex held
+func (r *UserTwoFactorRepository) deleteRecoveryCodes(userId int64) {
+	r.store.userRecoveryCodes = slices.DeleteFunc(r.store.userRecoveryCodes, func(code *repositories.UserRecoveryCodeEntity) bool {
+		return code.UserID == userId
+	})
+}
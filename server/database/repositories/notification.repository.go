@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// NotificationEntity is one message in a player's notification inbox
+type NotificationEntity struct {
+	ID         int64      `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived bool       `json:"is_archived" db:"is_archived"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Type       string     `json:"type" db:"type"`
+	Message    string     `json:"message" db:"message"`
+	IsRead     bool       `json:"is_read" db:"is_read"`
+	ReadAt     *time.Time `json:"read_at" db:"read_at"`
+}
+
+type INotificationRepository interface {
+	CreateNotification(userId int64, notificationType string, message string) (*NotificationEntity, error)
+	GetNotificationsByUserId(userId int64, limit int, offset int) ([]*NotificationEntity, error)
+	GetNotificationsCount(userId int64) (int, error)
+	GetUnreadCount(userId int64) (int, error)
+	MarkRead(userId, notificationId int64) (bool, error)
+	MarkAllRead(userId int64) error
+	WithTx(tx *database.AppDataSource) INotificationRepository
+}
+
+type NotificationRepository struct {
+	db *database.AppDataSource
+}
+
+func NewNotificationRepository(db *database.AppDataSource) INotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (r *NotificationRepository) CreateNotification(userId int64, notificationType string, message string) (*NotificationEntity, error) {
+	notification := &NotificationEntity{}
+	sql := `INSERT INTO notifications (user_id, type, message)
+			VALUES ($1, $2, $3)
+			RETURNING *`
+	err := r.db.DB.Get(notification, sql, userId, notificationType, message)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// GetNotificationsByUserId returns a page of the user's notifications, newest first
+func (r *NotificationRepository) GetNotificationsByUserId(userId int64, limit int, offset int) ([]*NotificationEntity, error) {
+	notifications := []*NotificationEntity{}
+	sql := `SELECT * FROM notifications
+			WHERE user_id = $1 AND is_archived = false
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2 OFFSET $3`
+	err := r.db.DB.Select(&notifications, sql, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) GetNotificationsCount(userId int64) (int, error) {
+	var count int
+	sql := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_archived = false`
+	err := r.db.DB.Get(&count, sql, userId)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *NotificationRepository) GetUnreadCount(userId int64) (int, error) {
+	var count int
+	sql := `SELECT COUNT(*) FROM notifications
+			WHERE user_id = $1 AND is_read = false AND is_archived = false`
+	err := r.db.DB.Get(&count, sql, userId)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications read. Returns false if the user has no
+// such notification. Marking a read notification again keeps its first read_at.
+func (r *NotificationRepository) MarkRead(userId, notificationId int64) (bool, error) {
+	sql := `UPDATE notifications
+			SET is_read = true, read_at = COALESCE(read_at, NOW()), modified_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND is_archived = false`
+	result, err := r.db.DB.Exec(sql, notificationId, userId)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(userId int64) error {
+	sql := `UPDATE notifications
+			SET is_read = true, read_at = NOW(), modified_at = NOW()
+			WHERE user_id = $1 AND is_read = false AND is_archived = false`
+	_, err := r.db.DB.Exec(sql, userId)
+	return err
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *NotificationRepository) WithTx(tx *database.AppDataSource) INotificationRepository {
+	return &NotificationRepository{
+		db: tx,
+	}
+}
@@ -0,0 +1,1294 @@
+// Package repositorytest holds the conformance tests every implementation of the
+// repository interfaces has to pass, so the in-memory repositories can be trusted to
+// behave like the Postgres ones.
+package repositorytest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// Repositories is one implementation of every repository, all sharing the same data
+type Repositories struct {
+	Users          repositories.IUserRepository
+	FeatureFlags   repositories.IFeatureFlagRepository
+	Teams          repositories.ITeamRepository
+	Inventory      repositories.IUserInventoryRepository
+	Expeditions    repositories.IExpeditionRepository
+	LaunchQueue    repositories.ILaunchQueueRepository
+	Leaderboards   repositories.ILeaderboardRepository
+	Notifications  repositories.INotificationRepository
+	EchoEncounters repositories.IEchoEncounterRepository
+	Guilds         repositories.IGuildRepository
+	Trades         repositories.ITradeRepository
+	Sessions       repositories.IUserSessionRepository
+	UsedTokens     repositories.IUsedTokenRepository
+	LoginHistory   repositories.IUserLoginHistoryRepository
+	TwoFactor      repositories.IUserTwoFactorRepository
+	Seeder         Seeder
+}
+
+// Seeder adds the static game data the repositories can only read
+type Seeder interface {
+	AddRift(rift *repositories.RiftEntity) *repositories.RiftEntity
+	AddLootItem(item *repositories.LootItemEntity) *repositories.LootItemEntity
+	AddLeaderboardCache(leaderboardType string, lastSynced time.Time)
+}
+
+// Run runs every conformance test. newRepositories is called once per test and has to
+// return repositories that don't see writes from other tests. The data they start with
+// may be shared, e.g. a migrated database, so tests only look at rows they created.
+// A failed statement aborts a Postgres transaction, so tests make any call they expect
+// to fail last.
+func Run(t *testing.T, newRepositories func(t *testing.T) *Repositories) {
+	tests := map[string]func(t *testing.T, r *Repositories){
+		"Users":          testUsers,
+		"FeatureFlags":   testFeatureFlags,
+		"Inventory":      testInventory,
+		"EquipmentSlot":  testEquipmentSlots,
+		"Expeditions":    testExpeditions,
+		"LaunchQueue":    testLaunchQueue,
+		"Leaderboards":   testLeaderboards,
+		"Notifications":  testNotifications,
+		"EchoEncounters": testEchoEncounters,
+		"Guilds":         testGuilds,
+		"Trades":         testTrades,
+		"Sessions":       testSessions,
+		"UsedTokens":     testUsedTokens,
+		"LoginHistory":   testLoginHistory,
+		"TwoFactor":      testTwoFactor,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepositories(t))
+		})
+	}
+}
+
+func testUsers(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "users")
+
+	byEmail, err := r.Users.GetUserByEmail(user.Email)
+	if err != nil || byEmail.ID != user.ID {
+		t.Fatalf("GetUserByEmail() = %v, %v, want user %d", byEmail, err, user.ID)
+	}
+	if _, err := r.Users.GetUserByEmail("missing@conformance.test"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserByEmail() for a missing user error = %v, want sql.ErrNoRows", err)
+	}
+
+	id := int(user.ID)
+	if _, err := r.Users.MarkUserVerified(&id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Users.ToggleUserArchived(&id); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := r.Users.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !archived.IsVerified || !archived.IsArchived {
+		t.Errorf("user verified = %v, archived = %v, want both", archived.IsVerified, archived.IsArchived)
+	}
+	if archived.PasswordHash == nil || *archived.PasswordHash != "" {
+		t.Errorf("archiving should clear the password hash, got %v", archived.PasswordHash)
+	}
+
+	if _, err := r.Users.CreateNewUser(&models.UserCreateDTO{Email: user.Email, DisplayName: "Copy"}); err == nil {
+		t.Error("CreateNewUser() with a taken email should fail")
+	}
+}
+
+func testFeatureFlags(t *testing.T, r *Repositories) {
+	key := "conformance_flag"
+	if _, err := r.FeatureFlags.CreateFlag(key, false, "Conformance"); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := r.FeatureFlags.UpdateFlag(key, true, "Updated")
+	if err != nil || updated == nil || !updated.Enabled || updated.Description != "Updated" {
+		t.Fatalf("UpdateFlag() = %v, %v", updated, err)
+	}
+	if missing, err := r.FeatureFlags.UpdateFlag("conformance_missing", true, ""); missing != nil || err != nil {
+		t.Errorf("UpdateFlag() for a missing flag = %v, %v, want nil, nil", missing, err)
+	}
+
+	if err := r.FeatureFlags.ArchiveFlag(key); err != nil {
+		t.Fatal(err)
+	}
+	if flag, err := r.FeatureFlags.GetFlagByKey(key); flag != nil || err != nil {
+		t.Errorf("GetFlagByKey() for an archived flag = %v, %v, want nil, nil", flag, err)
+	}
+	flags, err := r.FeatureFlags.GetAllFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, flag := range flags {
+		if flag.Key == key {
+			t.Error("GetAllFlags() returned an archived flag")
+		}
+	}
+	// The key stays taken after the flag is archived
+	if _, err := r.FeatureFlags.CreateFlag(key, true, "Copy"); err == nil {
+		t.Error("CreateFlag() with a taken key should fail")
+	}
+}
+
+func testInventory(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "inventory")
+	sword := addLootItem(r, "Sword", models.ItemRarityCommon, models.ItemTypeEquipment)
+	potion := addLootItem(r, "Potion", models.ItemRarityCommon, models.ItemTypeConsumable)
+
+	first := addLoot(t, r, user.ID, sword)
+	second := addLoot(t, r, user.ID, sword)
+	if first.ID == second.ID || second.Quantity != 1 {
+		t.Errorf("equipment should get a new row each time, got rows %d and %d", first.ID, second.ID)
+	}
+
+	stack := addLoot(t, r, user.ID, potion)
+	stacked := addLoot(t, r, user.ID, potion)
+	if stacked.ID != stack.ID || stacked.Quantity != 2 {
+		t.Errorf("consumables should stack, got row %d with quantity %d, want row %d with quantity 2", stacked.ID, stacked.Quantity, stack.ID)
+	}
+	if count, _ := r.Inventory.CountItemsByRarity(user.ID, string(models.ItemRarityCommon)); count != 4 {
+		t.Errorf("CountItemsByRarity() = %d, want 4", count)
+	}
+
+	// Using the last one archives the row instead of going to zero
+	for i := 0; i < 2; i++ {
+		if err := r.Inventory.ConsumeLoot(stack.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Inventory.GetInventoryById(stack.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetInventoryById() for a used up row error = %v, want sql.ErrNoRows", err)
+	}
+	if owned, _ := r.Inventory.HasItemByName(user.ID, potion.Name); owned {
+		t.Error("HasItemByName() should skip used up rows")
+	}
+	collected, err := r.Inventory.GetCollectedLootItemIds(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(collected, potion.ID) {
+		t.Error("GetCollectedLootItemIds() should include used up items")
+	}
+
+	restocked := addLoot(t, r, user.ID, potion)
+	if restocked.ID == stack.ID || restocked.Quantity != 1 {
+		t.Errorf("a used up stack should not be reused, got row %d with quantity %d", restocked.ID, restocked.Quantity)
+	}
+}
+
+func testEquipmentSlots(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "slots")
+	teams := createTeams(t, r, user.ID)
+	if len(teams) != 5 || !teams[0].IsUnlocked || teams[1].IsUnlocked {
+		t.Fatalf("CreateTeamsForUser() should create 5 teams with only team 1 unlocked")
+	}
+	team := teams[0]
+
+	blade := addLootItem(r, "Blade", models.ItemRarityRare, models.ItemTypeEquipment)
+	equipped := addLoot(t, r, user.ID, blade)
+	spare := addLoot(t, r, user.ID, blade)
+
+	slot := string(models.EquipmentSlotWeapon)
+	if err := r.Teams.EquipItem(team.ID, "cape", &equipped.ID); err == nil {
+		t.Error("EquipItem() should reject an unknown slot")
+	}
+	if err := r.Teams.EquipItem(team.ID, slot, &equipped.ID); err != nil {
+		t.Fatal(err)
+	}
+	equippedTeam, equippedSlot, err := r.Teams.GetTeamsByUserIdWithSlot(user.ID, equipped.ID)
+	if err != nil || equippedTeam == nil || equippedSlot == nil || *equippedSlot != slot {
+		t.Fatalf("GetTeamsByUserIdWithSlot() = %v, %v, %v, want team %d in %s", equippedTeam, equippedSlot, err, team.ID, slot)
+	}
+	if notEquipped, _, _ := r.Teams.GetTeamsByUserIdWithSlot(user.ID, spare.ID); notEquipped != nil {
+		t.Error("GetTeamsByUserIdWithSlot() found a team for an item that isn't equipped")
+	}
+
+	rarity := string(models.ItemRarityRare)
+	if count, _ := r.Inventory.CountUnequippedItemsByRarity(user.ID, rarity); count != 1 {
+		t.Errorf("CountUnequippedItemsByRarity() = %d, want 1", count)
+	}
+	if err := r.Inventory.ConsumeItemsByRarity(user.ID, rarity, 2); !errors.Is(err, repositories.ErrNotEnoughItems) {
+		t.Errorf("ConsumeItemsByRarity() error = %v, want ErrNotEnoughItems", err)
+	}
+	if count, _ := r.Inventory.CountItemsByRarity(user.ID, rarity); count != 2 {
+		t.Errorf("a failed ConsumeItemsByRarity() should not consume anything, %d items left", count)
+	}
+	if err := r.Inventory.SpendLoot(equipped.ID, 1); !errors.Is(err, repositories.ErrNotEnoughItems) {
+		t.Errorf("SpendLoot() on an equipped item error = %v, want ErrNotEnoughItems", err)
+	}
+
+	if err := r.Teams.UnequipItem(team.ID, slot); err != nil {
+		t.Fatal(err)
+	}
+	unequipped, err := r.Teams.GetTeamById(team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unequipped.EquippedWeaponSlot != nil {
+		t.Errorf("UnequipItem() should clear the slot, got %d", *unequipped.EquippedWeaponSlot)
+	}
+}
+
+func testExpeditions(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "expeditions")
+	teams := createTeams(t, r, user.ID)
+	team := teams[0]
+	rift := addRift(r, "Expedition")
+
+	expedition, err := r.Expeditions.CreateExpedition(user.ID, team.ID, rift.ID, rift.DurationMinutes, 10, 10, 2.5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expedition.Status != string(models.ExpeditionStatusActive) {
+		t.Errorf("new expedition status = %s, want active", expedition.Status)
+	}
+	if expedition.LootLuck == nil || *expedition.LootLuck != 2.5 {
+		t.Errorf("new expedition loot luck = %v, want the 2.5 it launched with", expedition.LootLuck)
+	}
+
+	if err := r.Expeditions.MarkCompleted(expedition.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := r.Expeditions.GetCompletedExpeditionsCountByRift(user.ID, rift.ID); count != 1 {
+		t.Errorf("GetCompletedExpeditionsCountByRift() = %d, want 1", count)
+	}
+	if err := r.Expeditions.MarkClaimed(expedition.ID); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := r.Expeditions.GetActiveExpeditionByTeamId(team.ID); active != nil {
+		t.Error("a claimed expedition should free up the team")
+	}
+	if _, err := r.Expeditions.CreateExpedition(user.ID, team.ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 2); err != nil {
+		t.Fatalf("CreateExpedition() after claiming error = %v", err)
+	}
+
+	// Zero minute expeditions are due at once. One that failed to process goes behind the
+	// ones that haven't, and skipped ones are left out.
+	failing, err := r.Expeditions.CreateExpedition(user.ID, teams[1].ID, rift.ID, 0, 10, 10, 0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthy, err := r.Expeditions.CreateExpedition(user.ID, teams[2].ID, rift.ID, 0, 10, 10, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Expeditions.MarkProcessFailed(failing.ID); err != nil {
+		t.Fatal(err)
+	}
+	due, err := r.Expeditions.GetDueExpeditionIds(1000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failingIndex, healthyIndex := slices.Index(due, failing.ID), slices.Index(due, healthy.ID)
+	if failingIndex < 0 || healthyIndex < 0 || failingIndex < healthyIndex {
+		t.Errorf("GetDueExpeditionIds() = %v, want %d before %d", due, healthy.ID, failing.ID)
+	}
+	due, err = r.Expeditions.GetDueExpeditionIds(1000, []int64{failing.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(due, failing.ID) || !slices.Contains(due, healthy.ID) {
+		t.Errorf("GetDueExpeditionIds() skipping %d = %v, want %d and not %d", failing.ID, due, healthy.ID, failing.ID)
+	}
+
+	if _, err := r.Expeditions.CreateExpedition(user.ID, team.ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 3); !errors.Is(err, repositories.ErrTeamHasActiveExpedition) {
+		t.Errorf("CreateExpedition() for a busy team error = %v, want ErrTeamHasActiveExpedition", err)
+	}
+}
+
+func testLaunchQueue(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "queue")
+	teams := createTeams(t, r, user.ID)
+	busyTeam, idleTeam := teams[0], teams[1]
+	rift := addRift(r, "Queue")
+
+	twice := 2
+	limited, err := r.LaunchQueue.AddEntry(user.ID, idleTeam.ID, rift.ID, &twice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endless, err := r.LaunchQueue.AddEntry(user.ID, idleTeam.ID, rift.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited.Position != 1 || endless.Position != 2 {
+		t.Errorf("entry positions = %d, %d, want 1, 2", limited.Position, endless.Position)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := r.LaunchQueue.ConsumeEntry(limited.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.LaunchQueue.ConsumeEntry(endless.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := r.LaunchQueue.GetEntriesByTeamId(idleTeam.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != endless.ID || entries[0].RepeatCount != nil {
+		t.Fatalf("after using up the limited entry the queue should only hold the endless one, got %d entries", len(entries))
+	}
+
+	if _, err := r.LaunchQueue.AddEntry(user.ID, busyTeam.ID, rift.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Expeditions.CreateExpedition(user.ID, busyTeam.ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	idle, err := r.LaunchQueue.GetIdleQueuedTeamIds(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(idle, idleTeam.ID) || slices.Contains(idle, busyTeam.ID) {
+		t.Errorf("GetIdleQueuedTeamIds() = %v, want team %d and not team %d", idle, idleTeam.ID, busyTeam.ID)
+	}
+
+	// Teams that have been tried go behind the ones that haven't
+	untriedTeam := teams[2]
+	if _, err := r.LaunchQueue.AddEntry(user.ID, untriedTeam.ID, rift.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LaunchQueue.MarkLaunchAttempted(idleTeam.ID); err != nil {
+		t.Fatal(err)
+	}
+	idle, err = r.LaunchQueue.GetIdleQueuedTeamIds(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triedIndex, untriedIndex := slices.Index(idle, idleTeam.ID), slices.Index(idle, untriedTeam.ID)
+	if triedIndex < 0 || untriedIndex < 0 || triedIndex < untriedIndex {
+		t.Errorf("GetIdleQueuedTeamIds() = %v, want team %d before team %d", idle, untriedTeam.ID, idleTeam.ID)
+	}
+
+	if err := r.LaunchQueue.ClearTeamQueue(idleTeam.ID); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := r.LaunchQueue.GetEntriesByTeamId(idleTeam.ID); len(entries) != 0 {
+		t.Errorf("ClearTeamQueue() left %d entries", len(entries))
+	}
+}
+
+func testLeaderboards(t *testing.T, r *Repositories) {
+	collector := createUser(t, r, "collector")
+	empty := createUser(t, r, "empty")
+	legendary := addLootItem(r, "Crown", models.ItemRarityLegendary, models.ItemTypeEquipment)
+	common := addLootItem(r, "Pebble", models.ItemRarityCommon, models.ItemTypeConsumable)
+	addLoot(t, r, collector.ID, legendary)
+	addLoot(t, r, collector.ID, legendary)
+	addLoot(t, r, collector.ID, common)
+
+	legendaryCounts, err := r.Leaderboards.GetLegendaryItemCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScore(t, "GetLegendaryItemCounts()", legendaryCounts, collector, 2)
+	assertScore(t, "GetLegendaryItemCounts()", legendaryCounts, empty, -1)
+
+	powerScores, err := r.Leaderboards.GetPowerScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := repositories.PowerScoreRarityWeights
+	assertScore(t, "GetPowerScores()", powerScores, collector, 2*weights["legendary"]+weights["common"])
+
+	team := createTeams(t, r, collector.ID)[0]
+	rift := addRift(r, "Leaderboard")
+	expedition, err := r.Expeditions.CreateExpedition(collector.ID, team.ID, rift.ID, rift.DurationMinutes, 10, 10, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Expeditions.MarkCompleted(expedition.ID); err != nil {
+		t.Fatal(err)
+	}
+	expeditionCounts, err := r.Leaderboards.GetExpeditionCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScore(t, "GetExpeditionCounts()", expeditionCounts, collector, 1)
+
+	// Archived users drop off every leaderboard
+	collectorId := int(collector.ID)
+	if err := r.Users.ToggleUserArchived(&collectorId); err != nil {
+		t.Fatal(err)
+	}
+	powerScores, err = r.Leaderboards.GetPowerScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScore(t, "GetPowerScores() after archiving", powerScores, collector, -1)
+
+	leaderboardType := "conformance"
+	r.Seeder.AddLeaderboardCache(leaderboardType, time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err := r.Leaderboards.SetSyncInProgress(leaderboardType, true); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := r.Leaderboards.GetCacheMetadata(leaderboardType)
+	if err != nil || !metadata.IsSyncing {
+		t.Fatalf("GetCacheMetadata() = %v, %v, want syncing", metadata, err)
+	}
+
+	items := []*repositories.LeaderboardCacheItemEntity{
+		{LeaderboardType: leaderboardType, UserID: int(collector.ID), Username: collector.DisplayName, Score: 5, Rank: 2},
+		{LeaderboardType: leaderboardType, UserID: int(empty.ID), Username: empty.DisplayName, Score: 9, Rank: 1},
+	}
+	if err := r.Leaderboards.InsertCacheItems(items); err != nil {
+		t.Fatal(err)
+	}
+	rerank := []*repositories.LeaderboardCacheItemEntity{
+		{LeaderboardType: leaderboardType, UserID: int(collector.ID), Username: collector.DisplayName, Score: 12, Rank: 1},
+		{LeaderboardType: leaderboardType, UserID: int(empty.ID), Username: empty.DisplayName, Score: 9, Rank: 2},
+	}
+	if err := r.Leaderboards.InsertCacheItems(rerank); err != nil {
+		t.Fatal(err)
+	}
+	top, err := r.Leaderboards.GetTopRankings(leaderboardType, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].UserID != int(collector.ID) || top[0].Score != 12 {
+		t.Errorf("GetTopRankings() should return the re-ranked collector first, got %v", top)
+	}
+
+	if err := r.Leaderboards.TruncateCache(leaderboardType); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Leaderboards.GetUserRank(leaderboardType, int(empty.ID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserRank() after TruncateCache() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testNotifications(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "notifications")
+	other := createUser(t, r, "notifications-other")
+
+	created := []*repositories.NotificationEntity{}
+	for i := range 3 {
+		notification, err := r.Notifications.CreateNotification(user.ID, "test", fmt.Sprintf("Message %d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, notification)
+	}
+	if created[0].IsRead || created[0].ReadAt != nil || created[0].Message != "Message 0" {
+		t.Errorf("CreateNotification() = %+v, want an unread notification", created[0])
+	}
+	if _, err := r.Notifications.CreateNotification(other.ID, "test", "Someone else's"); err != nil {
+		t.Fatal(err)
+	}
+
+	firstPage, err := r.Notifications.GetNotificationsByUserId(user.ID, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondPage, err := r.Notifications.GetNotificationsByUserId(user.ID, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(firstPage) != 2 || firstPage[0].ID != created[2].ID || firstPage[1].ID != created[1].ID ||
+		len(secondPage) != 1 || secondPage[0].ID != created[0].ID {
+		t.Errorf("GetNotificationsByUserId() pages = %v, %v, want newest first", firstPage, secondPage)
+	}
+	if count, err := r.Notifications.GetNotificationsCount(user.ID); err != nil || count != 3 {
+		t.Errorf("GetNotificationsCount() = %d, %v, want 3", count, err)
+	}
+
+	marked, err := r.Notifications.MarkRead(user.ID, created[0].ID)
+	if err != nil || !marked {
+		t.Fatalf("MarkRead() = %v, %v, want true", marked, err)
+	}
+	if marked, err := r.Notifications.MarkRead(other.ID, created[1].ID); err != nil || marked {
+		t.Errorf("MarkRead() of another user's notification = %v, %v, want false", marked, err)
+	}
+	if unread, err := r.Notifications.GetUnreadCount(user.ID); err != nil || unread != 2 {
+		t.Errorf("GetUnreadCount() = %d, %v, want 2", unread, err)
+	}
+
+	if err := r.Notifications.MarkAllRead(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if unread, err := r.Notifications.GetUnreadCount(user.ID); err != nil || unread != 0 {
+		t.Errorf("GetUnreadCount() after MarkAllRead() = %d, %v, want 0", unread, err)
+	}
+	if unread, err := r.Notifications.GetUnreadCount(other.ID); err != nil || unread != 1 {
+		t.Errorf("MarkAllRead() should leave other users' notifications alone, got %d unread, %v", unread, err)
+	}
+	notifications, err := r.Notifications.GetNotificationsByUserId(user.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, notification := range notifications {
+		if !notification.IsRead || notification.ReadAt == nil {
+			t.Errorf("notification %d = %+v, want it read", notification.ID, notification)
+		}
+	}
+}
+
+func testEchoEncounters(t *testing.T, r *Repositories) {
+	player := createUser(t, r, "echo-player")
+	echoed := createUser(t, r, "echo-echoed")
+	rift := addRift(r, "Echo")
+	otherRift := addRift(r, "Echo Other")
+
+	echoedExpedition := createCompletedExpedition(t, r, echoed.ID, rift.ID)
+	found, err := r.EchoEncounters.GetEchoExpedition(player.ID, rift.ID, 24)
+	if err != nil || found == nil || found.ID != echoedExpedition.ID {
+		t.Fatalf("GetEchoExpedition() = %v, %v, want expedition %d", found, err, echoedExpedition.ID)
+	}
+	if found, err := r.EchoEncounters.GetEchoExpedition(echoed.ID, rift.ID, 24); err != nil || found != nil {
+		t.Errorf("GetEchoExpedition() should skip the player's own expeditions, got %v, %v", found, err)
+	}
+	if found, err := r.EchoEncounters.GetEchoExpedition(player.ID, otherRift.ID, 24); err != nil || found != nil {
+		t.Errorf("GetEchoExpedition() for another rift = %v, %v, want nil", found, err)
+	}
+
+	playerExpedition := createCompletedExpedition(t, r, player.ID, rift.ID)
+	encounter, err := r.EchoEncounters.CreateEchoEncounter(playerExpedition.ID, echoedExpedition.ID, player.ID, echoed.ID, rift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encounter.UserID1 != player.ID || encounter.UserID2 != echoed.ID || encounter.ExpeditionID2 != echoedExpedition.ID {
+		t.Errorf("CreateEchoEncounter() = %+v, want the player meeting the echoed expedition", encounter)
+	}
+	lootItem := addLootItem(r, "Echo Shard", "common", "consumable")
+	if err := r.EchoEncounters.CreateEchoEncounterLoot(encounter.ID, echoed.ID, lootItem.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if loot, err := r.EchoEncounters.GetEchoEncounterLoot(encounter.ID); err != nil || len(loot) != 1 || loot[0].UserID != echoed.ID || loot[0].LootItemID != lootItem.ID {
+		t.Errorf("GetEchoEncounterLoot() = %v, %v, want the echoed player's bonus item", loot, err)
+	}
+	for _, user := range []*repositories.UserEntity{player, echoed} {
+		if count, err := r.EchoEncounters.GetEchoEncounterCount(user.ID); err != nil || count != 1 {
+			t.Errorf("GetEchoEncounterCount(%d) = %d, %v, want 1", user.ID, count, err)
+		}
+	}
+	if found, err := r.EchoEncounters.GetEchoExpedition(player.ID, rift.ID, 24); err != nil || found != nil {
+		t.Errorf("GetEchoExpedition() should skip expeditions already in an encounter, got %v, %v", found, err)
+	}
+
+	echoCounts, err := r.Leaderboards.GetEchoEncounterCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScore(t, "GetEchoEncounterCounts()", echoCounts, player, 1)
+	assertScore(t, "GetEchoEncounterCounts()", echoCounts, echoed, 1)
+
+	// An expedition only leaves one echo
+	another := createCompletedExpedition(t, r, player.ID, rift.ID)
+	if _, err := r.EchoEncounters.CreateEchoEncounter(another.ID, echoedExpedition.ID, player.ID, echoed.ID, rift.ID); err == nil {
+		t.Error("CreateEchoEncounter() for an already echoed expedition should fail")
+	}
+}
+
+func testGuilds(t *testing.T, r *Repositories) {
+	leader := createUser(t, r, "guild-leader")
+	member := createUser(t, r, "guild-member")
+	outsider := createUser(t, r, "guild-outsider")
+	crown := addLootItem(r, "Guild Crown", models.ItemRarityLegendary, models.ItemTypeEquipment)
+	pebble := addLootItem(r, "Guild Pebble", models.ItemRarityCommon, models.ItemTypeConsumable)
+
+	guild, err := r.Guilds.CreateGuild("Conformance Guild", "For testing", "CONF2345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.Guilds.GetGuildByInviteCode("CONF2345"); err != nil || found == nil || found.ID != guild.ID {
+		t.Fatalf("GetGuildByInviteCode() = %v, %v, want guild %d", found, err, guild.ID)
+	}
+	if found, err := r.Guilds.GetGuildByInviteCode("NOPE2345"); err != nil || found != nil {
+		t.Errorf("GetGuildByInviteCode() for an unknown code = %v, %v, want nil", found, err)
+	}
+	if err := r.Guilds.UpdateInviteCode(guild.ID, "CONF6789"); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.Guilds.GetGuildByIdForUpdate(guild.ID); err != nil || found == nil || found.InviteCode != "CONF6789" {
+		t.Errorf("GetGuildByIdForUpdate() after UpdateInviteCode() = %v, %v, want code CONF6789", found, err)
+	}
+
+	if _, err := r.Guilds.AddMember(guild.ID, leader.ID, string(models.GuildRoleLeader)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Guilds.AddMember(guild.ID, member.ID, string(models.GuildRoleMember)); err != nil {
+		t.Fatal(err)
+	}
+	members, err := r.Guilds.GetMembers(guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].UserID != leader.ID || members[1].UserID != member.ID {
+		t.Fatalf("GetMembers() = %v, want the leader then the member", members)
+	}
+	if members[0].DisplayName != leader.DisplayName {
+		t.Errorf("GetMembers() display name = %q, want %q", members[0].DisplayName, leader.DisplayName)
+	}
+	if count, err := r.Guilds.GetMemberCount(guild.ID); err != nil || count != 2 {
+		t.Errorf("GetMemberCount() = %d, %v, want 2", count, err)
+	}
+	if err := r.Guilds.UpdateMemberRole(guild.ID, member.ID, string(models.GuildRoleOfficer)); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.Guilds.GetMemberByUserId(member.ID); err != nil || found == nil || found.Role != string(models.GuildRoleOfficer) {
+		t.Errorf("GetMemberByUserId() after UpdateMemberRole() = %v, %v, want an officer", found, err)
+	}
+	if found, err := r.Guilds.GetMemberByUserId(outsider.ID); err != nil || found != nil {
+		t.Errorf("GetMemberByUserId() for a user outside any guild = %v, %v, want nil", found, err)
+	}
+
+	// Deposits stack in the treasury and leave the member's inventory
+	addLoot(t, r, leader.ID, crown)
+	addLoot(t, r, member.ID, pebble)
+	stack := addLoot(t, r, member.ID, pebble)
+	if err := r.Inventory.SpendLoot(stack.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Inventory.GetInventoryById(stack.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetInventoryById() after spending the whole stack error = %v, want sql.ErrNoRows", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Guilds.AddToTreasury(guild.ID, pebble.ID, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	treasury, err := r.Guilds.GetTreasury(guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(treasury) != 1 || treasury[0].LootItemID != pebble.ID || treasury[0].Quantity != 2 {
+		t.Errorf("GetTreasury() = %v, want one stack of 2 pebbles", treasury)
+	}
+
+	rift := addRift(r, "Guild")
+	createCompletedExpedition(t, r, leader.ID, rift.ID)
+	createCompletedExpedition(t, r, leader.ID, rift.ID)
+	createCompletedExpedition(t, r, outsider.ID, rift.ID)
+	progress, err := r.Guilds.GetRiftProgress(guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 1 || progress[0].RiftID != rift.ID || progress[0].CompletedExpeditions != 2 || progress[0].MembersCompleted != 1 {
+		t.Errorf("GetRiftProgress() = %v, want 2 expeditions by 1 member in rift %d", progress, rift.ID)
+	}
+
+	weights := repositories.PowerScoreRarityWeights
+	scores, err := r.Leaderboards.GetGuildPowerScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGuildScore(t, scores, guild, weights["legendary"]+2*weights["common"])
+
+	leaderboardType := "conformance_guild"
+	r.Seeder.AddLeaderboardCache(leaderboardType, time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	items := []*repositories.LeaderboardCacheItemEntity{
+		{LeaderboardType: leaderboardType, GuildID: int(guild.ID), Username: guild.Name, Score: 7, Rank: 1},
+	}
+	if err := r.Leaderboards.InsertCacheItems(items); err != nil {
+		t.Fatal(err)
+	}
+	if rank, err := r.Leaderboards.GetUserGuildRank(leaderboardType, int(member.ID)); err != nil || rank.GuildID != int(guild.ID) || rank.Rank != 1 {
+		t.Errorf("GetUserGuildRank() = %v, %v, want guild %d ranked 1", rank, err, guild.ID)
+	}
+	top, err := r.Leaderboards.GetTopRankings(leaderboardType, 10)
+	if err != nil || len(top) != 1 || top[0].GuildID != int(guild.ID) || top[0].UserID != 0 {
+		t.Errorf("GetTopRankings() = %v, %v, want only guild %d", top, err, guild.ID)
+	}
+
+	// Removing the last member and archiving drops the guild off the leaderboard
+	if removed, err := r.Guilds.RemoveMember(guild.ID, member.ID); err != nil || !removed {
+		t.Errorf("RemoveMember() = %v, %v, want true", removed, err)
+	}
+	if removed, err := r.Guilds.RemoveMember(guild.ID, member.ID); err != nil || removed {
+		t.Errorf("RemoveMember() twice = %v, %v, want false", removed, err)
+	}
+	if _, err := r.Guilds.RemoveMember(guild.ID, leader.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Guilds.ArchiveGuild(guild.ID); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.Guilds.GetGuildByInviteCode("CONF6789"); err != nil || found != nil {
+		t.Errorf("GetGuildByInviteCode() for an archived guild = %v, %v, want nil", found, err)
+	}
+	scores, err = r.Leaderboards.GetGuildPowerScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGuildScore(t, scores, guild, -1)
+
+	// A member can rejoin after leaving, but only one guild at a time
+	other, err := r.Guilds.CreateGuild("Conformance Other Guild", "", "CONF2346")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Guilds.AddMember(other.ID, member.ID, string(models.GuildRoleLeader)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Inventory.SpendLoot(stack.ID, 1); !errors.Is(err, repositories.ErrNotEnoughItems) {
+		t.Errorf("SpendLoot() on an empty stack error = %v, want ErrNotEnoughItems", err)
+	}
+	if _, err := r.Guilds.AddMember(other.ID, member.ID, string(models.GuildRoleMember)); !errors.Is(err, repositories.ErrAlreadyInGuild) {
+		t.Errorf("AddMember() for a member of another guild error = %v, want ErrAlreadyInGuild", err)
+	}
+}
+
+func testTrades(t *testing.T, r *Repositories) {
+	proposer := createUser(t, r, "trade-proposer")
+	recipient := createUser(t, r, "trade-recipient")
+	bystander := createUser(t, r, "trade-bystander")
+	blade := addLootItem(r, "Trade Blade", models.ItemRarityEpic, models.ItemTypeEquipment)
+	shield := addLootItem(r, "Trade Shield", models.ItemRarityEpic, models.ItemTypeEquipment)
+
+	offered := addLoot(t, r, proposer.ID, blade)
+	asked := addLoot(t, r, recipient.ID, shield)
+
+	trade, err := r.Trades.CreateTrade(proposer.ID, recipient.ID, time.Now().UTC().Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trade.Status != string(models.TradeStatusPending) || trade.ResolvedAt != nil {
+		t.Errorf("CreateTrade() = %v, want a pending trade", trade)
+	}
+
+	// Escrow only holds an item its owner has free
+	if escrowed, err := r.Inventory.EscrowItem(asked.ID, proposer.ID, trade.ID); err != nil || escrowed {
+		t.Errorf("EscrowItem() for someone else's item = %v, %v, want false", escrowed, err)
+	}
+	if escrowed, err := r.Inventory.EscrowItem(offered.ID, proposer.ID, trade.ID); err != nil || !escrowed {
+		t.Fatalf("EscrowItem() = %v, %v, want true", escrowed, err)
+	}
+	if escrowed, err := r.Inventory.EscrowItem(offered.ID, proposer.ID, trade.ID); err != nil || escrowed {
+		t.Errorf("EscrowItem() twice = %v, %v, want false", escrowed, err)
+	}
+	held, err := r.Inventory.GetInventoryById(offered.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held.EscrowTradeID == nil || *held.EscrowTradeID != trade.ID {
+		t.Errorf("escrowed item trade = %v, want %d", held.EscrowTradeID, trade.ID)
+	}
+	if err := r.Inventory.SpendLoot(offered.ID, 1); !errors.Is(err, repositories.ErrNotEnoughItems) {
+		t.Errorf("SpendLoot() on an escrowed item error = %v, want ErrNotEnoughItems", err)
+	}
+	if count, _ := r.Inventory.CountUnequippedItemsByRarity(proposer.ID, string(models.ItemRarityEpic)); count != 0 {
+		t.Errorf("CountUnequippedItemsByRarity() = %d, want escrowed items skipped", count)
+	}
+
+	if _, err := r.Trades.AddTradeItem(trade.ID, offered.ID, proposer.ID, blade.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Trades.AddTradeItem(trade.ID, asked.ID, recipient.ID, shield.ID); err != nil {
+		t.Fatal(err)
+	}
+	items, err := r.Trades.GetTradeItems(trade.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].InventoryID != offered.ID || items[1].FromUserID != recipient.ID {
+		t.Errorf("GetTradeItems() = %v, want the offered then the asked item", items)
+	}
+
+	locked, err := r.Trades.GetTradeByIdForUpdate(trade.ID)
+	if err != nil || locked == nil {
+		t.Fatalf("GetTradeByIdForUpdate() = %v, %v", locked, err)
+	}
+	if locked.ProposerName != proposer.DisplayName || locked.RecipientName != recipient.DisplayName {
+		t.Errorf("trade names = %q, %q, want %q, %q", locked.ProposerName, locked.RecipientName, proposer.DisplayName, recipient.DisplayName)
+	}
+	if count, err := r.Trades.CountPendingTradesByProposer(proposer.ID); err != nil || count != 1 {
+		t.Errorf("CountPendingTradesByProposer() = %d, %v, want 1", count, err)
+	}
+	for _, user := range []*repositories.UserEntity{proposer, recipient} {
+		if pending, err := r.Trades.GetPendingTradesByUserId(user.ID); err != nil || len(pending) != 1 || pending[0].ID != trade.ID {
+			t.Errorf("GetPendingTradesByUserId(%d) = %v, %v, want trade %d", user.ID, pending, err, trade.ID)
+		}
+	}
+	if pending, err := r.Trades.GetPendingTradesByUserId(bystander.ID); err != nil || len(pending) != 0 {
+		t.Errorf("GetPendingTradesByUserId() for a bystander = %v, %v, want none", pending, err)
+	}
+
+	// Accepting swaps the rows and releases them
+	if escrowed, err := r.Inventory.EscrowItem(asked.ID, recipient.ID, trade.ID); err != nil || !escrowed {
+		t.Fatalf("EscrowItem() for the recipient = %v, %v, want true", escrowed, err)
+	}
+	if transferred, err := r.Inventory.TransferEscrowedItem(offered.ID, trade.ID, recipient.ID); err != nil || !transferred {
+		t.Fatalf("TransferEscrowedItem() = %v, %v, want true", transferred, err)
+	}
+	if transferred, err := r.Inventory.TransferEscrowedItem(offered.ID, trade.ID, bystander.ID); err != nil || transferred {
+		t.Errorf("TransferEscrowedItem() twice = %v, %v, want false", transferred, err)
+	}
+	if _, err := r.Inventory.TransferEscrowedItem(asked.ID, trade.ID, proposer.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Both players keep credit for the item they gave away and get it for the one they received
+	for _, user := range []*repositories.UserEntity{proposer, recipient} {
+		collected, err := r.Inventory.GetCollectedLootItemIds(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(collected, offered.LootItemID) || !slices.Contains(collected, asked.LootItemID) {
+			t.Errorf("GetCollectedLootItemIds(%d) = %v, want items %d and %d", user.ID, collected, offered.LootItemID, asked.LootItemID)
+		}
+	}
+	for _, want := range []struct {
+		inventoryId, userId int64
+	}{{offered.ID, recipient.ID}, {asked.ID, proposer.ID}} {
+		row, err := r.Inventory.GetInventoryById(want.inventoryId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.UserID != want.userId || row.EscrowTradeID != nil {
+			t.Errorf("traded item %d owner = %d, escrow = %v, want user %d and no escrow", want.inventoryId, row.UserID, row.EscrowTradeID, want.userId)
+		}
+	}
+	if resolved, err := r.Trades.ResolveTrade(trade.ID, string(models.TradeStatusCompleted)); err != nil || !resolved {
+		t.Fatalf("ResolveTrade() = %v, %v, want true", resolved, err)
+	}
+	if resolved, err := r.Trades.ResolveTrade(trade.ID, string(models.TradeStatusDeclined)); err != nil || resolved {
+		t.Errorf("ResolveTrade() twice = %v, %v, want false", resolved, err)
+	}
+
+	// An expired trade gives its items back
+	stale, err := r.Trades.CreateTrade(recipient.ID, proposer.ID, time.Now().UTC().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Inventory.EscrowItem(offered.ID, recipient.ID, stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := r.Trades.GetExpiredTradeIds(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(expired, stale.ID) || slices.Contains(expired, trade.ID) {
+		t.Errorf("GetExpiredTradeIds() = %v, want trade %d and not the resolved trade %d", expired, stale.ID, trade.ID)
+	}
+	if err := r.Inventory.ReleaseEscrow(stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	if released, err := r.Inventory.GetInventoryById(offered.ID); err != nil || released.EscrowTradeID != nil {
+		t.Errorf("ReleaseEscrow() left item %v, %v in escrow", released, err)
+	}
+	if _, err := r.Trades.ResolveTrade(stale.ID, string(models.TradeStatusExpired)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := r.Trades.GetPendingTradesByUserId(proposer.ID); err != nil || len(pending) != 0 {
+		t.Errorf("GetPendingTradesByUserId() after resolving = %v, %v, want none", pending, err)
+	}
+	history, err := r.Trades.GetTradeHistoryByUserId(proposer.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ID != stale.ID || history[1].ID != trade.ID || history[1].ResolvedAt == nil {
+		t.Errorf("GetTradeHistoryByUserId() = %v, want trades %d then %d", history, stale.ID, trade.ID)
+	}
+	if limited, err := r.Trades.GetTradeHistoryByUserId(proposer.ID, 1); err != nil || len(limited) != 1 {
+		t.Errorf("GetTradeHistoryByUserId() with a limit of 1 = %v, %v", limited, err)
+	}
+
+	if _, err := r.Trades.AddTradeItem(trade.ID, offered.ID, proposer.ID, blade.ID); err == nil {
+		t.Error("AddTradeItem() for an item already in the trade should fail")
+	}
+}
+
+func testSessions(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "sessions")
+	otherUser := createUser(t, r, "sessions-other")
+	expiresAt := time.Now().UTC().Add(24 * time.Hour)
+
+	session, err := r.Sessions.CreateSession(user.ID, "conformance-hash-1", "Firefox", "203.0.113.7", expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != user.ID || session.RevokedAt != nil || session.PreviousTokenHash != nil {
+		t.Errorf("CreateSession() = %+v", session)
+	}
+	other, err := r.Sessions.CreateSession(user.ID, "conformance-hash-2", "Safari", "198.51.100.2", expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Sessions.CreateSession(user.ID, "conformance-hash-stale", "Chrome", "198.51.100.3", time.Now().UTC().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if byId, err := r.Sessions.GetSessionById(session.ID); err != nil || byId == nil || byId.RefreshTokenHash != "conformance-hash-1" {
+		t.Errorf("GetSessionById() = %v, %v", byId, err)
+	}
+	if missing, err := r.Sessions.GetSessionByTokenHash("conformance-hash-missing"); err != nil || missing != nil {
+		t.Errorf("GetSessionByTokenHash() for an unknown hash = %v, %v, want nil", missing, err)
+	}
+
+	// Rotating swaps the hash, and only works once per token
+	rotated, err := r.Sessions.RotateSession(session.ID, "conformance-hash-1", "conformance-hash-3", "Firefox 2", "203.0.113.8", expiresAt.Add(time.Hour))
+	if err != nil || !rotated {
+		t.Fatalf("RotateSession() = %v, %v, want true", rotated, err)
+	}
+	if again, err := r.Sessions.RotateSession(session.ID, "conformance-hash-1", "conformance-hash-4", "Firefox 2", "203.0.113.8", expiresAt); err != nil || again {
+		t.Errorf("RotateSession() with a rotated token = %v, %v, want false", again, err)
+	}
+	current, err := r.Sessions.GetSessionByTokenHash("conformance-hash-3")
+	if err != nil || current == nil || current.ID != session.ID || current.UserAgent != "Firefox 2" || current.IPAddress != "203.0.113.8" {
+		t.Fatalf("GetSessionByTokenHash() after rotating = %v, %v", current, err)
+	}
+	if old, err := r.Sessions.GetSessionByTokenHash("conformance-hash-1"); err != nil || old != nil {
+		t.Errorf("GetSessionByTokenHash() for the rotated token = %v, %v, want nil", old, err)
+	}
+	if previous, err := r.Sessions.GetSessionByPreviousTokenHash("conformance-hash-1"); err != nil || previous == nil || previous.ID != session.ID {
+		t.Errorf("GetSessionByPreviousTokenHash() = %v, %v, want session %d", previous, err, session.ID)
+	}
+
+	active, err := r.Sessions.GetActiveSessionsByUserId(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOW() doesn't move inside a Postgres transaction, so the order can't be relied on here
+	activeIds := []int64{}
+	for _, row := range active {
+		activeIds = append(activeIds, row.ID)
+	}
+	if len(activeIds) != 2 || !slices.Contains(activeIds, session.ID) || !slices.Contains(activeIds, other.ID) {
+		t.Errorf("GetActiveSessionsByUserId() = %v, want sessions %d and %d", activeIds, session.ID, other.ID)
+	}
+
+	// Revoking only works on the user's own sessions, and only once
+	if revoked, err := r.Sessions.RevokeSession(otherUser.ID, session.ID); err != nil || revoked {
+		t.Errorf("RevokeSession() for another user = %v, %v, want false", revoked, err)
+	}
+	if revoked, err := r.Sessions.RevokeSession(user.ID, session.ID); err != nil || !revoked {
+		t.Fatalf("RevokeSession() = %v, %v, want true", revoked, err)
+	}
+	if revoked, err := r.Sessions.RevokeSession(user.ID, session.ID); err != nil || revoked {
+		t.Errorf("RevokeSession() twice = %v, %v, want false", revoked, err)
+	}
+	if revokedSession, err := r.Sessions.GetSessionById(session.ID); err != nil || revokedSession == nil || revokedSession.RevokedAt == nil {
+		t.Errorf("GetSessionById() after revoking = %v, %v, want revoked_at set", revokedSession, err)
+	}
+	if rotated, err := r.Sessions.RotateSession(session.ID, "conformance-hash-3", "conformance-hash-5", "Firefox", "203.0.113.7", expiresAt); err != nil || rotated {
+		t.Errorf("RotateSession() on a revoked session = %v, %v, want false", rotated, err)
+	}
+
+	if _, err := r.Sessions.CreateSession(otherUser.ID, "conformance-hash-other", "Edge", "192.0.2.1", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sessions.RevokeAllSessions(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := r.Sessions.GetActiveSessionsByUserId(user.ID); err != nil || len(active) != 0 {
+		t.Errorf("GetActiveSessionsByUserId() after RevokeAllSessions() = %v, %v, want none", active, err)
+	}
+	if active, err := r.Sessions.GetActiveSessionsByUserId(otherUser.ID); err != nil || len(active) != 1 {
+		t.Errorf("RevokeAllSessions() revoked another user's sessions: %v, %v", active, err)
+	}
+
+	if _, err := r.Sessions.CreateSession(otherUser.ID, "conformance-hash-other", "Edge", "192.0.2.1", expiresAt); err == nil {
+		t.Error("CreateSession() with a hash already in use should fail")
+	}
+}
+
+// createUser creates a user whose email is unique to the test
+func testUsedTokens(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "used-tokens")
+	expiresAt := time.Now().UTC().Add(time.Hour)
+
+	// A token can only be marked used once
+	if marked, err := r.UsedTokens.MarkTokenUsed("conformance-jti-1", user.ID, "password_reset_token", expiresAt); err != nil || !marked {
+		t.Fatalf("MarkTokenUsed() = %v, %v, want true", marked, err)
+	}
+	if again, err := r.UsedTokens.MarkTokenUsed("conformance-jti-1", user.ID, "password_reset_token", expiresAt); err != nil || again {
+		t.Errorf("MarkTokenUsed() for a used token = %v, %v, want false", again, err)
+	}
+	if _, err := r.UsedTokens.MarkTokenUsed("conformance-jti-expired", user.ID, "loginwithemail_token", time.Now().UTC().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting expired tokens forgets them, and keeps the ones that haven't expired
+	if err := r.UsedTokens.DeleteExpiredTokens(); err != nil {
+		t.Fatal(err)
+	}
+	if marked, err := r.UsedTokens.MarkTokenUsed("conformance-jti-expired", user.ID, "loginwithemail_token", expiresAt); err != nil || !marked {
+		t.Errorf("MarkTokenUsed() for a deleted token = %v, %v, want true", marked, err)
+	}
+	if again, err := r.UsedTokens.MarkTokenUsed("conformance-jti-1", user.ID, "password_reset_token", expiresAt); err != nil || again {
+		t.Errorf("MarkTokenUsed() for an unexpired used token = %v, %v, want false", again, err)
+	}
+}
+
+func testLoginHistory(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "login-history")
+	other := createUser(t, r, "login-history-other")
+
+	if seen, err := r.LoginHistory.HasSuccessfulLogin(user.ID); err != nil || seen {
+		t.Errorf("HasSuccessfulLogin() before any login = %v, %v, want false", seen, err)
+	}
+
+	// Failed logins don't count as having logged in from a device
+	failed, err := r.LoginHistory.CreateLoginHistory(user.ID, "password", "invalid_password", "10.0.0.1", "Firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.UserID != user.ID || failed.Method != "password" || failed.Outcome != "invalid_password" || failed.IPAddress != "10.0.0.1" || failed.UserAgent != "Firefox" {
+		t.Errorf("CreateLoginHistory() = %+v, want the values it was given", failed)
+	}
+	if seen, err := r.LoginHistory.HasSuccessfulLogin(user.ID); err != nil || seen {
+		t.Errorf("HasSuccessfulLogin() after a failed login = %v, %v, want false", seen, err)
+	}
+
+	if _, err := r.LoginHistory.CreateLoginHistory(user.ID, "email_link", "success", "10.0.0.1", "Firefox"); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := r.LoginHistory.CreateLoginHistory(user.ID, "password", "success", "10.0.0.2", "Chrome")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.LoginHistory.CreateLoginHistory(other.ID, "password", "success", "10.0.0.3", "Safari"); err != nil {
+		t.Fatal(err)
+	}
+
+	if seen, err := r.LoginHistory.HasSuccessfulLogin(user.ID); err != nil || !seen {
+		t.Errorf("HasSuccessfulLogin() = %v, %v, want true", seen, err)
+	}
+	if seen, err := r.LoginHistory.HasSuccessfulLoginFrom(user.ID, "Firefox", "10.0.0.1"); err != nil || !seen {
+		t.Errorf("HasSuccessfulLoginFrom() for a known device = %v, %v, want true", seen, err)
+	}
+	// Another user's devices aren't this user's
+	if seen, err := r.LoginHistory.HasSuccessfulLoginFrom(user.ID, "Safari", "10.0.0.3"); err != nil || seen {
+		t.Errorf("HasSuccessfulLoginFrom() for another user's device = %v, %v, want false", seen, err)
+	}
+	if seen, err := r.LoginHistory.HasSuccessfulLoginFrom(user.ID, "Firefox", "10.0.0.2"); err != nil || seen {
+		t.Errorf("HasSuccessfulLoginFrom() for a new IP = %v, %v, want false", seen, err)
+	}
+
+	logins, err := r.LoginHistory.GetRecentLoginHistory(user.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logins) != 2 || logins[0].ID != latest.ID {
+		t.Fatalf("GetRecentLoginHistory() = %d logins, want the 2 newest starting with %d", len(logins), latest.ID)
+	}
+	for _, login := range logins {
+		if login.UserID != user.ID {
+			t.Errorf("GetRecentLoginHistory() returned user %d's login", login.UserID)
+		}
+		if login.ID == failed.ID {
+			t.Errorf("GetRecentLoginHistory() returned the oldest login past its limit")
+		}
+	}
+}
+
+func testTwoFactor(t *testing.T, r *Repositories) {
+	user := createUser(t, r, "two-factor")
+
+	if twoFactor, err := r.TwoFactor.GetTwoFactor(user.ID); err != nil || twoFactor != nil {
+		t.Fatalf("GetTwoFactor() before enrolling = %v, %v, want nil", twoFactor, err)
+	}
+
+	// Enrollment can be restarted with a new secret until it's confirmed
+	if saved, err := r.TwoFactor.SaveTwoFactorSecret(user.ID, "first-secret"); err != nil || !saved {
+		t.Fatalf("SaveTwoFactorSecret() = %v, %v, want true", saved, err)
+	}
+	if saved, err := r.TwoFactor.SaveTwoFactorSecret(user.ID, "second-secret"); err != nil || !saved {
+		t.Fatalf("SaveTwoFactorSecret() while enrolling = %v, %v, want true", saved, err)
+	}
+	if used, err := r.TwoFactor.UseTwoFactorStep(user.ID, 100); err != nil || used {
+		t.Errorf("UseTwoFactorStep() before enabling = %v, %v, want false", used, err)
+	}
+	if enabled, err := r.TwoFactor.EnableTwoFactor(user.ID, 100); err != nil || !enabled {
+		t.Fatalf("EnableTwoFactor() = %v, %v, want true", enabled, err)
+	}
+	if enabled, err := r.TwoFactor.EnableTwoFactor(user.ID, 101); err != nil || enabled {
+		t.Errorf("EnableTwoFactor() when already enabled = %v, %v, want false", enabled, err)
+	}
+	if saved, err := r.TwoFactor.SaveTwoFactorSecret(user.ID, "third-secret"); err != nil || saved {
+		t.Errorf("SaveTwoFactorSecret() when enabled = %v, %v, want false", saved, err)
+	}
+
+	twoFactor, err := r.TwoFactor.GetTwoFactor(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twoFactor == nil || twoFactor.Secret != "second-secret" || twoFactor.EnabledAt == nil || twoFactor.LastUsedStep != 100 {
+		t.Fatalf("GetTwoFactor() = %+v, want the second secret enabled at step 100", twoFactor)
+	}
+
+	// Each time step can only be used once, and never an earlier one
+	if used, err := r.TwoFactor.UseTwoFactorStep(user.ID, 100); err != nil || used {
+		t.Errorf("UseTwoFactorStep() for the confirmed step = %v, %v, want false", used, err)
+	}
+	if used, err := r.TwoFactor.UseTwoFactorStep(user.ID, 102); err != nil || !used {
+		t.Errorf("UseTwoFactorStep() for a new step = %v, %v, want true", used, err)
+	}
+	if used, err := r.TwoFactor.UseTwoFactorStep(user.ID, 101); err != nil || used {
+		t.Errorf("UseTwoFactorStep() for an earlier step = %v, %v, want false", used, err)
+	}
+
+	// Recovery codes work once each, and replacing them forgets the old ones
+	if err := r.TwoFactor.ReplaceRecoveryCodes(user.ID, []string{"hash-1", "hash-2", "hash-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := r.TwoFactor.UseRecoveryCode(user.ID, "hash-2"); err != nil || !used {
+		t.Errorf("UseRecoveryCode() = %v, %v, want true", used, err)
+	}
+	if used, err := r.TwoFactor.UseRecoveryCode(user.ID, "hash-2"); err != nil || used {
+		t.Errorf("UseRecoveryCode() for a used code = %v, %v, want false", used, err)
+	}
+	if count, err := r.TwoFactor.CountRecoveryCodes(user.ID); err != nil || count != 2 {
+		t.Errorf("CountRecoveryCodes() = %d, %v, want 2", count, err)
+	}
+	if err := r.TwoFactor.ReplaceRecoveryCodes(user.ID, []string{"hash-2", "hash-4"}); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := r.TwoFactor.UseRecoveryCode(user.ID, "hash-1"); err != nil || used {
+		t.Errorf("UseRecoveryCode() for a replaced code = %v, %v, want false", used, err)
+	}
+	if used, err := r.TwoFactor.UseRecoveryCode(user.ID, "hash-2"); err != nil || !used {
+		t.Errorf("UseRecoveryCode() for a reissued code = %v, %v, want true", used, err)
+	}
+
+	// Turning two-factor off forgets everything
+	if err := r.TwoFactor.DeleteTwoFactor(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if twoFactor, err := r.TwoFactor.GetTwoFactor(user.ID); err != nil || twoFactor != nil {
+		t.Errorf("GetTwoFactor() after deleting = %v, %v, want nil", twoFactor, err)
+	}
+	if count, err := r.TwoFactor.CountRecoveryCodes(user.ID); err != nil || count != 0 {
+		t.Errorf("CountRecoveryCodes() after deleting = %d, %v, want 0", count, err)
+	}
+}
+
+func createUser(t *testing.T, r *Repositories, name string) *repositories.UserEntity {
+	t.Helper()
+	user, err := r.Users.CreateNewUser(&models.UserCreateDTO{
+		Email:       fmt.Sprintf("%s@conformance.test", name),
+		DisplayName: "Conformance " + name,
+		Password:    "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createTeams(t *testing.T, r *Repositories, userId int64) []*repositories.TeamEntity {
+	t.Helper()
+	if err := r.Teams.CreateTeamsForUser(userId); err != nil {
+		t.Fatal(err)
+	}
+	teams, err := r.Teams.GetTeamsByUserId(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return teams
+}
+
+// createCompletedExpedition sends one of the user's teams to the rift and completes it
+func createCompletedExpedition(t *testing.T, r *Repositories, userId, riftId int64) *repositories.ExpeditionEntity {
+	t.Helper()
+	teams, err := r.Teams.GetTeamsByUserId(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) == 0 {
+		teams = createTeams(t, r, userId)
+	}
+	expedition, err := r.Expeditions.CreateExpedition(userId, teams[0].ID, riftId, 5, 10, 10, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Expeditions.MarkCompleted(expedition.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Expeditions.MarkClaimed(expedition.ID); err != nil {
+		t.Fatal(err)
+	}
+	return expedition
+}
+
+func addRift(r *Repositories, name string) *repositories.RiftEntity {
+	return r.Seeder.AddRift(&repositories.RiftEntity{
+		Name:            "Conformance " + name,
+		Description:     "Conformance rift",
+		WorldType:       string(models.WorldTypeTutorial),
+		DurationMinutes: 5,
+		Difficulty:      string(models.DifficultyTutorial),
+		WeakToElement:   string(models.ElementalTypeNone),
+		Icon:            "fa-vial",
+	})
+}
+
+func addLootItem(r *Repositories, name string, rarity models.ItemRarity, itemType models.ItemType) *repositories.LootItemEntity {
+	var slot *string
+	if itemType == models.ItemTypeEquipment {
+		weapon := string(models.EquipmentSlotWeapon)
+		slot = &weapon
+	}
+	return r.Seeder.AddLootItem(&repositories.LootItemEntity{
+		Name:              "Conformance " + name,
+		Description:       "Conformance item",
+		Rarity:            string(rarity),
+		WorldType:         string(models.WorldTypeTutorial),
+		ItemType:          string(itemType),
+		EquipmentSlot:     slot,
+		ElementalAffinity: string(models.ElementalTypeNone),
+		PowerValue:        1,
+		Icon:              "fa-vial",
+	})
+}
+
+func addLoot(t *testing.T, r *Repositories, userId int64, item *repositories.LootItemEntity) *repositories.UserInventoryEntity {
+	t.Helper()
+	row, err := r.Inventory.AddLoot(userId, item.ID, item.ItemType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return row
+}
+
+// assertScore checks the user's score on a leaderboard. A want of -1 means the user
+// shouldn't be on it.
+func assertScore(t *testing.T, name string, items []*repositories.LeaderboardCacheItemEntity, user *repositories.UserEntity, want int64) {
+	t.Helper()
+	for _, item := range items {
+		if item.UserID != int(user.ID) {
+			continue
+		}
+		if want < 0 {
+			t.Errorf("%s should not include user %d", name, user.ID)
+		} else if item.Score != want {
+			t.Errorf("%s score for user %d = %d, want %d", name, user.ID, item.Score, want)
+		}
+		return
+	}
+	if want >= 0 {
+		t.Errorf("%s is missing user %d", name, user.ID)
+	}
+}
+
+// assertGuildScore checks a guild's score on a guild leaderboard. A want of -1 means the
+// guild shouldn't be on it.
+func assertGuildScore(t *testing.T, items []*repositories.LeaderboardCacheItemEntity, guild *repositories.GuildEntity, want int64) {
+	t.Helper()
+	for _, item := range items {
+		if item.GuildID != int(guild.ID) {
+			continue
+		}
+		if want < 0 {
+			t.Errorf("GetGuildPowerScores() should not include guild %d", guild.ID)
+		} else if item.Score != want {
+			t.Errorf("GetGuildPowerScores() score for guild %d = %d, want %d", guild.ID, item.Score, want)
+		}
+		return
+	}
+	if want >= 0 {
+		t.Errorf("GetGuildPowerScores() is missing guild %d", guild.ID)
+	}
+}
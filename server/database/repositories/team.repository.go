@@ -10,12 +10,15 @@ import (
 type ITeamRepository interface {
 	GetTeamsByUserId(userId int64) ([]*TeamEntity, error)
 	GetTeamById(teamId int64) (*TeamEntity, error)
+	GetTeamByIdForUpdate(teamId int64) (*TeamEntity, error)
 	CreateTeamsForUser(userId int64) error
 	UpdateTeamStats(teamId int64, speedBonus, luckBonus float64, powerBonus int) error
 	EquipItem(teamId int64, slot string, inventoryId *int64) error
 	UnequipItem(teamId int64, slot string) error
 	UnlockTeam(teamId int64) error
 	GetTeamsByUserIdWithSlot(userId int64, inventoryId int64) (*TeamEntity, *string, error)
+	SetSpecialization(teamId int64, specialization string) (bool, error)
+	WithTx(tx *database.AppDataSource) ITeamRepository
 }
 
 type TeamRepository struct {
@@ -48,6 +51,17 @@ func (r *TeamRepository) GetTeamById(teamId int64) (*TeamEntity, error) {
 	return team, nil
 }
 
+// GetTeamByIdForUpdate loads a team and locks its row until the surrounding transaction ends
+func (r *TeamRepository) GetTeamByIdForUpdate(teamId int64) (*TeamEntity, error) {
+	team := &TeamEntity{}
+	sql := `SELECT * FROM teams WHERE id = $1 AND is_archived = false FOR UPDATE`
+	err := r.db.DB.Get(team, sql, teamId)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
 func (r *TeamRepository) CreateTeamsForUser(userId int64) error {
 	// Create Team 1 (unlocked)
 	sql := `INSERT INTO teams (user_id, team_number, is_unlocked) VALUES ($1, 1, true)`
@@ -117,7 +131,7 @@ func (r *TeamRepository) GetTeamsByUserIdWithSlot(userId int64, inventoryId int6
 	var slot sql.NullString
 
 	sqlQuery := `SELECT t.id, t.created_at, t.modified_at, t.is_archived, t.user_id, t.team_number,
-			t.speed_bonus, t.luck_bonus, t.power_bonus, t.is_unlocked,
+			t.speed_bonus, t.luck_bonus, t.power_bonus, t.is_unlocked, t.specialization,
 			t.equipped_weapon_slot, t.equipped_armor_slot, t.equipped_accessory_slot,
 			t.equipped_artifact_slot, t.equipped_relic_slot,
 			CASE
@@ -139,7 +153,7 @@ func (r *TeamRepository) GetTeamsByUserIdWithSlot(userId int64, inventoryId int6
 
 	err := r.db.DB.QueryRowx(sqlQuery, userId, inventoryId).Scan(
 		&team.ID, &team.CreatedAt, &team.ModifiedAt, &team.IsArchived, &team.UserID, &team.TeamNumber,
-		&team.SpeedBonus, &team.LuckBonus, &team.PowerBonus, &team.IsUnlocked,
+		&team.SpeedBonus, &team.LuckBonus, &team.PowerBonus, &team.IsUnlocked, &team.Specialization,
 		&team.EquippedWeaponSlot, &team.EquippedArmorSlot, &team.EquippedAccessorySlot,
 		&team.EquippedArtifactSlot, &team.EquippedRelicSlot,
 		&slot,
@@ -160,3 +174,26 @@ func (r *TeamRepository) GetTeamsByUserIdWithSlot(userId int64, inventoryId int6
 
 	return nil, nil, nil // Not equipped anywhere
 }
+
+// SetSpecialization gives a team its specialization. Specializations are permanent, so
+// this only updates teams that don't have one yet. Returns false if the team was already specialized.
+func (r *TeamRepository) SetSpecialization(teamId int64, specialization string) (bool, error) {
+	sql := `UPDATE teams SET specialization = $1, modified_at = NOW()
+			WHERE id = $2 AND specialization = 'none' AND is_archived = false`
+	result, err := r.db.DB.Exec(sql, specialization, teamId)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *TeamRepository) WithTx(tx *database.AppDataSource) ITeamRepository {
+	return &TeamRepository{
+		db: tx,
+	}
+}
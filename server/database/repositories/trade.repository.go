@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// TradeEntity is a trade between two players. ProposerName and RecipientName are joined
+// from users when trades are read back.
+type TradeEntity struct {
+	ID            int64      `json:"id" db:"id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt    *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived    bool       `json:"is_archived" db:"is_archived"`
+	ProposerID    int64      `json:"proposer_id" db:"proposer_id"`
+	RecipientID   int64      `json:"recipient_id" db:"recipient_id"`
+	Status        string     `json:"status" db:"status"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	ResolvedAt    *time.Time `json:"resolved_at" db:"resolved_at"`
+	ProposerName  string     `json:"proposer_name" db:"proposer_name"`
+	RecipientName string     `json:"recipient_name" db:"recipient_name"`
+}
+
+// TradeItemEntity is one inventory row on one side of a trade
+type TradeItemEntity struct {
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt  *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived  bool       `json:"is_archived" db:"is_archived"`
+	TradeID     int64      `json:"trade_id" db:"trade_id"`
+	InventoryID int64      `json:"inventory_id" db:"inventory_id"`
+	FromUserID  int64      `json:"from_user_id" db:"from_user_id"`
+	LootItemID  int64      `json:"loot_item_id" db:"loot_item_id"`
+}
+
+type ITradeRepository interface {
+	CreateTrade(proposerId, recipientId int64, expiresAt time.Time) (*TradeEntity, error)
+	AddTradeItem(tradeId, inventoryId, fromUserId, lootItemId int64) (*TradeItemEntity, error)
+	GetTradeById(tradeId int64) (*TradeEntity, error)
+	GetTradeByIdForUpdate(tradeId int64) (*TradeEntity, error)
+	GetTradeItems(tradeId int64) ([]*TradeItemEntity, error)
+	GetPendingTradesByUserId(userId int64) ([]*TradeEntity, error)
+	GetTradeHistoryByUserId(userId int64, limit int) ([]*TradeEntity, error)
+	CountPendingTradesByProposer(proposerId int64) (int, error)
+	GetExpiredTradeIds(limit int) ([]int64, error)
+	ResolveTrade(tradeId int64, status string) (bool, error)
+	WithTx(tx *database.AppDataSource) ITradeRepository
+}
+
+type TradeRepository struct {
+	db *database.AppDataSource
+}
+
+func NewTradeRepository(db *database.AppDataSource) ITradeRepository {
+	return &TradeRepository{
+		db: db,
+	}
+}
+
+// tradeSelect reads trades (aliased tr) with both players' display names
+const tradeSelect = `SELECT tr.*, p.display_name AS proposer_name, rc.display_name AS recipient_name
+			FROM trades tr
+			JOIN users p ON p.id = tr.proposer_id
+			JOIN users rc ON rc.id = tr.recipient_id`
+
+func (r *TradeRepository) CreateTrade(proposerId, recipientId int64, expiresAt time.Time) (*TradeEntity, error) {
+	trade := &TradeEntity{}
+	query := `INSERT INTO trades (proposer_id, recipient_id, expires_at)
+			VALUES ($1, $2, $3)
+			RETURNING *`
+	err := r.db.DB.Get(trade, query, proposerId, recipientId, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+func (r *TradeRepository) AddTradeItem(tradeId, inventoryId, fromUserId, lootItemId int64) (*TradeItemEntity, error) {
+	item := &TradeItemEntity{}
+	query := `INSERT INTO trade_items (trade_id, inventory_id, from_user_id, loot_item_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *`
+	err := r.db.DB.Get(item, query, tradeId, inventoryId, fromUserId, lootItemId)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetTradeById returns the trade, or nil if there isn't one
+func (r *TradeRepository) GetTradeById(tradeId int64) (*TradeEntity, error) {
+	trade := &TradeEntity{}
+	query := tradeSelect + ` WHERE tr.id = $1 AND tr.is_archived = false`
+	err := r.db.DB.Get(trade, query, tradeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return trade, nil
+}
+
+// GetTradeByIdForUpdate is GetTradeById, locking the trade for the rest of the transaction.
+// Everything that resolves a trade takes the lock, so a trade is only resolved once.
+func (r *TradeRepository) GetTradeByIdForUpdate(tradeId int64) (*TradeEntity, error) {
+	trade := &TradeEntity{}
+	query := tradeSelect + ` WHERE tr.id = $1 AND tr.is_archived = false FOR UPDATE OF tr`
+	err := r.db.DB.Get(trade, query, tradeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return trade, nil
+}
+
+func (r *TradeRepository) GetTradeItems(tradeId int64) ([]*TradeItemEntity, error) {
+	items := []*TradeItemEntity{}
+	query := `SELECT * FROM trade_items WHERE trade_id = $1 AND is_archived = false ORDER BY id`
+	err := r.db.DB.Select(&items, query, tradeId)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetPendingTradesByUserId returns the pending trades the user proposed or was offered, newest first
+func (r *TradeRepository) GetPendingTradesByUserId(userId int64) ([]*TradeEntity, error) {
+	trades := []*TradeEntity{}
+	query := tradeSelect + ` WHERE (tr.proposer_id = $1 OR tr.recipient_id = $1)
+			AND tr.status = 'pending' AND tr.is_archived = false
+			ORDER BY tr.created_at DESC, tr.id DESC`
+	err := r.db.DB.Select(&trades, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetTradeHistoryByUserId returns the user's resolved trades, most recently resolved first
+func (r *TradeRepository) GetTradeHistoryByUserId(userId int64, limit int) ([]*TradeEntity, error) {
+	trades := []*TradeEntity{}
+	query := tradeSelect + ` WHERE (tr.proposer_id = $1 OR tr.recipient_id = $1)
+			AND tr.status <> 'pending' AND tr.is_archived = false
+			ORDER BY tr.resolved_at DESC, tr.id DESC
+			LIMIT $2`
+	err := r.db.DB.Select(&trades, query, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+func (r *TradeRepository) CountPendingTradesByProposer(proposerId int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM trades WHERE proposer_id = $1 AND status = 'pending' AND is_archived = false`
+	err := r.db.DB.Get(&count, query, proposerId)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetExpiredTradeIds returns pending trades whose time is up, oldest first
+func (r *TradeRepository) GetExpiredTradeIds(limit int) ([]int64, error) {
+	ids := []int64{}
+	query := `SELECT id FROM trades
+			WHERE status = 'pending' AND expires_at <= NOW() AND is_archived = false
+			ORDER BY expires_at, id
+			LIMIT $1`
+	err := r.db.DB.Select(&ids, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ResolveTrade moves a pending trade to its final status.
+// Returns false if the trade was no longer pending.
+func (r *TradeRepository) ResolveTrade(tradeId int64, status string) (bool, error) {
+	query := `UPDATE trades SET status = $2, resolved_at = NOW(), modified_at = NOW()
+			WHERE id = $1 AND status = 'pending'`
+	result, err := r.db.DB.Exec(query, tradeId, status)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *TradeRepository) WithTx(tx *database.AppDataSource) ITradeRepository {
+	return &TradeRepository{
+		db: tx,
+	}
+}
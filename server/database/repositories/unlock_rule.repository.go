@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+type IUnlockRuleRepository interface {
+	GetRulesByTargetType(targetType string) ([]*UnlockRuleEntity, error)
+	GetRuleByTarget(targetType string, targetKey int64) (*UnlockRuleEntity, error)
+}
+
+type UnlockRuleRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUnlockRuleRepository(db *database.AppDataSource) IUnlockRuleRepository {
+	return &UnlockRuleRepository{
+		db: db,
+	}
+}
+
+func (r *UnlockRuleRepository) GetRulesByTargetType(targetType string) ([]*UnlockRuleEntity, error) {
+	rules := []*UnlockRuleEntity{}
+	query := `SELECT * FROM unlock_rules WHERE target_type = $1 AND is_archived = false ORDER BY target_key`
+	err := r.db.DB.Select(&rules, query, targetType)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRuleByTarget returns the rule for a single rift or team
+// Returns nil, nil if the target has no rule (it is always unlocked)
+func (r *UnlockRuleRepository) GetRuleByTarget(targetType string, targetKey int64) (*UnlockRuleEntity, error) {
+	rule := &UnlockRuleEntity{}
+	query := `SELECT * FROM unlock_rules WHERE target_type = $1 AND target_key = $2 AND is_archived = false`
+	err := r.db.DB.Get(rule, query, targetType, targetKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+type IUpgradeRecipeRepository interface {
+	GetRecipes() ([]*UpgradeRecipeEntity, error)
+	GetRecipeById(recipeId int64) (*UpgradeRecipeEntity, error)
+	GetCostsByRecipeId(recipeId int64) ([]*UpgradeRecipeCostEntity, error)
+}
+
+type UpgradeRecipeRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUpgradeRecipeRepository(db *database.AppDataSource) IUpgradeRecipeRepository {
+	return &UpgradeRecipeRepository{
+		db: db,
+	}
+}
+
+func (r *UpgradeRecipeRepository) GetRecipes() ([]*UpgradeRecipeEntity, error) {
+	recipes := []*UpgradeRecipeEntity{}
+	sql := `SELECT * FROM upgrade_recipes WHERE is_archived = false ORDER BY id`
+	err := r.db.DB.Select(&recipes, sql)
+	if err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+func (r *UpgradeRecipeRepository) GetRecipeById(recipeId int64) (*UpgradeRecipeEntity, error) {
+	recipe := &UpgradeRecipeEntity{}
+	sql := `SELECT * FROM upgrade_recipes WHERE id = $1 AND is_archived = false`
+	err := r.db.DB.Get(recipe, sql, recipeId)
+	if err != nil {
+		return nil, err
+	}
+	return recipe, nil
+}
+
+func (r *UpgradeRecipeRepository) GetCostsByRecipeId(recipeId int64) ([]*UpgradeRecipeCostEntity, error) {
+	costs := []*UpgradeRecipeCostEntity{}
+	sql := `SELECT * FROM upgrade_recipe_costs WHERE recipe_id = $1 AND is_archived = false ORDER BY rarity`
+	err := r.db.DB.Select(&costs, sql, recipeId)
+	if err != nil {
+		return nil, err
+	}
+	return costs, nil
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// UsedTokenEntity is a single-use token that has already been used
+type UsedTokenEntity struct {
+	JTI       string    `json:"jti" db:"jti"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Purpose   string    `json:"purpose" db:"purpose"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type IUsedTokenRepository interface {
+	MarkTokenUsed(jti string, userId int64, purpose string, expiresAt time.Time) (bool, error)
+	DeleteExpiredTokens() error
+	WithTx(tx *database.AppDataSource) IUsedTokenRepository
+}
+
+type UsedTokenRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUsedTokenRepository(db *database.AppDataSource) IUsedTokenRepository {
+	return &UsedTokenRepository{
+		db: db,
+	}
+}
+
+// MarkTokenUsed records the token as used.
+// Returns false if it had already been used.
+func (r *UsedTokenRepository) MarkTokenUsed(jti string, userId int64, purpose string, expiresAt time.Time) (bool, error) {
+	query := `INSERT INTO used_tokens (jti, user_id, purpose, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (jti) DO NOTHING`
+	result, err := r.db.DB.Exec(query, jti, userId, purpose, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// DeleteExpiredTokens forgets tokens that have expired, they can't be used again anyway
+func (r *UsedTokenRepository) DeleteExpiredTokens() error {
+	query := `DELETE FROM used_tokens WHERE expires_at <= NOW()`
+	_, err := r.db.DB.Exec(query)
+	return err
+}
+
+func (r *UsedTokenRepository) WithTx(tx *database.AppDataSource) IUsedTokenRepository {
+	return &UsedTokenRepository{
+		db: tx,
+	}
+}
@@ -69,7 +69,7 @@ func (r *UserRepository) GetUsersCount(searchString string, statusFilter string,
 	if statusFilter != "" {
 		switch statusFilter {
 		case "active":
-			sql += ` AND is_archived = false AND is_banned = false`
+			sql += ` AND is_archived = false`
 		case "archived":
 			sql += ` AND is_archived = true`
 		case "banned":
@@ -104,7 +104,7 @@ func (r *UserRepository) GetUsers(pageSize int, offset int, searchString string,
 	if statusFilter != "" {
 		switch statusFilter {
 		case "active":
-			sql += ` AND is_archived = false AND is_banned = false`
+			sql += ` AND is_archived = false`
 		case "archived":
 			sql += ` AND is_archived = true`
 		case "banned":
@@ -182,6 +182,7 @@ func (r *UserRepository) MarkUserVerified(userId *int) (bool, error) {
 	return true, nil
 }
 
+// UpdateUser ignores UserTypeKey, the users table has no user type column
 func (r *UserRepository) UpdateUser(dto *models.UserUpdateDTO, userId *int) (*UserEntity, error) {
 	sql := `UPDATE users
 		SET
@@ -189,10 +190,9 @@ func (r *UserRepository) UpdateUser(dto *models.UserUpdateDTO, userId *int) (*Us
 			display_name = $2,
 			avatar_url = $3,
 			profile_text = $4,
-			user_type_key = $5,
 			modified_at = NOW()
-		WHERE id = $6;`
-	_, err := r.db.DB.Exec(sql, dto.Email, dto.DisplayName, dto.AvatarURL, dto.ProfileText, dto.UserTypeKey, &userId)
+		WHERE id = $5;`
+	_, err := r.db.DB.Exec(sql, dto.Email, dto.DisplayName, dto.AvatarURL, dto.ProfileText, &userId)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,33 @@
 package repositories
 
 import (
+	"errors"
+
 	"github.com/snowlynxsoftware/parallax-game/server/database"
 )
 
+// ErrNotEnoughItems is returned by ConsumeItemsByRarity when the user doesn't own enough
+// unequipped items of the rarity
+var ErrNotEnoughItems = errors.New("not enough items")
+
+// notEquippedClause filters user_inventory (aliased ui) down to items that aren't equipped on a team
+const notEquippedClause = `NOT EXISTS (
+				SELECT 1 FROM teams t
+				WHERE t.user_id = ui.user_id AND t.is_archived = false AND ui.id IN (
+					t.equipped_weapon_slot, t.equipped_armor_slot, t.equipped_accessory_slot,
+					t.equipped_artifact_slot, t.equipped_relic_slot
+				)
+			)`
+
+// collectItemClause is a CTE that records the user_id and loot_item_id rows returned by
+// the item CTE in user_collected_items, so giving a user an item and crediting it to
+// their collection happen in the same statement
+const collectItemClause = `collected AS (
+				INSERT INTO user_collected_items (user_id, loot_item_id)
+				SELECT user_id, loot_item_id FROM item
+				ON CONFLICT (user_id, loot_item_id) DO NOTHING
+			)`
+
 type IUserInventoryRepository interface {
 	GetInventoryByUserId(userId int64) ([]*UserInventoryEntity, error)
 	GetInventoryById(inventoryId int64) (*UserInventoryEntity, error)
@@ -11,8 +35,17 @@ type IUserInventoryRepository interface {
 	GetConsumablesByUserId(userId int64) ([]*UserInventoryEntity, error)
 	AddLoot(userId int64, lootItemId int64, itemType string) (*UserInventoryEntity, error)
 	ConsumeLoot(inventoryId int64) error
+	SpendLoot(inventoryId int64, quantity int) error
 	GetInventoryByUserAndItem(userId int64, lootItemId int64) (*UserInventoryEntity, error)
 	HasItemByName(userId int64, itemName string) (bool, error)
+	GetCollectedLootItemIds(userId int64) ([]int64, error)
+	CountItemsByRarity(userId int64, rarity string) (int, error)
+	CountUnequippedItemsByRarity(userId int64, rarity string) (int, error)
+	ConsumeItemsByRarity(userId int64, rarity string, quantity int) error
+	EscrowItem(inventoryId, userId, tradeId int64) (bool, error)
+	ReleaseEscrow(tradeId int64) error
+	TransferEscrowedItem(inventoryId, tradeId, toUserId int64) (bool, error)
+	WithTx(tx *database.AppDataSource) IUserInventoryRepository
 }
 
 type UserInventoryRepository struct {
@@ -88,9 +121,12 @@ func (r *UserInventoryRepository) GetInventoryByUserAndItem(userId int64, lootIt
 func (r *UserInventoryRepository) AddLoot(userId int64, lootItemId int64, itemType string) (*UserInventoryEntity, error) {
 	if itemType == "equipment" {
 		// Equipment: Always insert new row with quantity = 1
-		sql := `INSERT INTO user_inventory (user_id, loot_item_id, quantity, acquired_at)
-				VALUES ($1, $2, 1, NOW())
-				RETURNING id, created_at, modified_at, is_archived, user_id, loot_item_id, quantity, acquired_at`
+		sql := `WITH item AS (
+					INSERT INTO user_inventory (user_id, loot_item_id, quantity, acquired_at)
+					VALUES ($1, $2, 1, NOW())
+					RETURNING id, created_at, modified_at, is_archived, user_id, loot_item_id, quantity, acquired_at, is_stack, escrow_trade_id
+				), ` + collectItemClause + `
+				SELECT * FROM item`
 		item := &UserInventoryEntity{}
 		err := r.db.DB.QueryRowx(sql, userId, lootItemId).StructScan(item)
 		if err != nil {
@@ -98,33 +134,21 @@ func (r *UserInventoryRepository) AddLoot(userId int64, lootItemId int64, itemTy
 		}
 		return item, nil
 	} else {
-		// Consumable: Upsert (insert or increment quantity)
-		sql := `INSERT INTO user_inventory (user_id, loot_item_id, quantity, acquired_at)
-				VALUES ($1, $2, 1, NOW())
-				ON CONFLICT ON CONSTRAINT user_inventory_pkey DO NOTHING
-				RETURNING id, created_at, modified_at, is_archived, user_id, loot_item_id, quantity, acquired_at`
-
+		// Consumable: start the user's stack, or increment it if they already have one.
+		// The upsert is a single statement, so simultaneous drops can't both start a stack.
+		sql := `WITH item AS (
+					INSERT INTO user_inventory (user_id, loot_item_id, quantity, acquired_at, is_stack)
+					VALUES ($1, $2, 1, NOW(), true)
+					ON CONFLICT (user_id, loot_item_id) WHERE is_stack = true AND is_archived = false
+					DO UPDATE SET quantity = user_inventory.quantity + 1, modified_at = NOW()
+					RETURNING id, created_at, modified_at, is_archived, user_id, loot_item_id, quantity, acquired_at, is_stack, escrow_trade_id
+				), ` + collectItemClause + `
+				SELECT * FROM item`
 		item := &UserInventoryEntity{}
 		err := r.db.DB.QueryRowx(sql, userId, lootItemId).StructScan(item)
-
 		if err != nil {
-			// If conflict, increment existing
-			existing, err := r.GetInventoryByUserAndItem(userId, lootItemId)
-			if err != nil {
-				return nil, err
-			}
-			if existing != nil {
-				sql := `UPDATE user_inventory SET quantity = quantity + 1, modified_at = NOW() WHERE id = $1
-						RETURNING id, created_at, modified_at, is_archived, user_id, loot_item_id, quantity, acquired_at`
-				err = r.db.DB.QueryRowx(sql, existing.ID).StructScan(item)
-				if err != nil {
-					return nil, err
-				}
-				return item, nil
-			}
 			return nil, err
 		}
-
 		return item, nil
 	}
 }
@@ -150,6 +174,30 @@ func (r *UserInventoryRepository) ConsumeLoot(inventoryId int64) error {
 	return err
 }
 
+// SpendLoot takes quantity off an inventory row, archiving it when all of it is spent.
+// Returns ErrNotEnoughItems without spending anything if the row doesn't hold that many,
+// or is equipped on a team or held in escrow.
+func (r *UserInventoryRepository) SpendLoot(inventoryId int64, quantity int) error {
+	sql := `UPDATE user_inventory ui
+			SET quantity = CASE WHEN ui.quantity > $2 THEN ui.quantity - $2 ELSE ui.quantity END,
+			is_archived = (ui.quantity = $2),
+			modified_at = NOW()
+			WHERE ui.id = $1 AND ui.is_archived = false AND ui.escrow_trade_id IS NULL AND ui.quantity >= $2
+			AND ` + notEquippedClause
+	result, err := r.db.DB.Exec(sql, inventoryId, quantity)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotEnoughItems
+	}
+	return nil
+}
+
 // HasItemByName checks if a user has a specific item by its name
 func (r *UserInventoryRepository) HasItemByName(userId int64, itemName string) (bool, error) {
 	var count int
@@ -162,3 +210,143 @@ func (r *UserInventoryRepository) HasItemByName(userId int64, itemName string) (
 	}
 	return count > 0, nil
 }
+
+// GetCollectedLootItemIds returns every loot item the user has ever owned. It reads the
+// record made when each item reached their inventory, so items that were used up or
+// traded away still count as collected.
+func (r *UserInventoryRepository) GetCollectedLootItemIds(userId int64) ([]int64, error) {
+	ids := []int64{}
+	sql := `SELECT loot_item_id FROM user_collected_items WHERE user_id = $1`
+	err := r.db.DB.Select(&ids, sql, userId)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CountItemsByRarity returns how many items of a rarity a user owns, counting stacked quantities
+func (r *UserInventoryRepository) CountItemsByRarity(userId int64, rarity string) (int, error) {
+	var count int
+	sql := `SELECT COALESCE(SUM(ui.quantity), 0) FROM user_inventory ui
+			JOIN loot_items li ON li.id = ui.loot_item_id
+			WHERE ui.user_id = $1 AND li.rarity = $2 AND ui.is_archived = false AND li.is_archived = false`
+	err := r.db.DB.Get(&count, sql, userId, rarity)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUnequippedItemsByRarity is like CountItemsByRarity but skips items equipped on a
+// team or held in escrow
+func (r *UserInventoryRepository) CountUnequippedItemsByRarity(userId int64, rarity string) (int, error) {
+	var count int
+	sql := `SELECT COALESCE(SUM(ui.quantity), 0) FROM user_inventory ui
+			JOIN loot_items li ON li.id = ui.loot_item_id
+			WHERE ui.user_id = $1 AND li.rarity = $2 AND ui.is_archived = false AND li.is_archived = false
+			AND ui.escrow_trade_id IS NULL AND ` + notEquippedClause
+	err := r.db.DB.Get(&count, sql, userId, rarity)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ConsumeItemsByRarity spends quantity unequipped items of a rarity, oldest first.
+// Items held in escrow are skipped.
+// Returns ErrNotEnoughItems without consuming anything if the user doesn't own enough,
+// but should be run inside a transaction so partial updates are rolled back on other errors.
+func (r *UserInventoryRepository) ConsumeItemsByRarity(userId int64, rarity string, quantity int) error {
+	items := []*UserInventoryEntity{}
+	sql := `SELECT ui.* FROM user_inventory ui
+			JOIN loot_items li ON li.id = ui.loot_item_id
+			WHERE ui.user_id = $1 AND li.rarity = $2 AND ui.is_archived = false AND li.is_archived = false
+			AND ui.escrow_trade_id IS NULL AND ` + notEquippedClause + `
+			ORDER BY ui.acquired_at, ui.id
+			FOR UPDATE OF ui`
+	err := r.db.DB.Select(&items, sql, userId, rarity)
+	if err != nil {
+		return err
+	}
+
+	owned := 0
+	for _, item := range items {
+		owned += item.Quantity
+	}
+	if owned < quantity {
+		return ErrNotEnoughItems
+	}
+
+	remaining := quantity
+	for _, item := range items {
+		if remaining == 0 {
+			break
+		}
+
+		// Quantity can't go below 1, so fully spent stacks are archived instead
+		if item.Quantity <= remaining {
+			sql := `UPDATE user_inventory SET is_archived = true, modified_at = NOW() WHERE id = $1`
+			if _, err := r.db.DB.Exec(sql, item.ID); err != nil {
+				return err
+			}
+			remaining -= item.Quantity
+		} else {
+			sql := `UPDATE user_inventory SET quantity = quantity - $1, modified_at = NOW() WHERE id = $2`
+			if _, err := r.db.DB.Exec(sql, remaining, item.ID); err != nil {
+				return err
+			}
+			remaining = 0
+		}
+	}
+
+	return nil
+}
+
+// EscrowItem holds an item the user owns for a trade.
+// Returns false without changing anything if the user doesn't own the item or it is
+// already held for another trade.
+func (r *UserInventoryRepository) EscrowItem(inventoryId, userId, tradeId int64) (bool, error) {
+	sql := `UPDATE user_inventory SET escrow_trade_id = $3, modified_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND is_archived = false AND escrow_trade_id IS NULL`
+	result, err := r.db.DB.Exec(sql, inventoryId, userId, tradeId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ReleaseEscrow gives back every item held for a trade
+func (r *UserInventoryRepository) ReleaseEscrow(tradeId int64) error {
+	sql := `UPDATE user_inventory SET escrow_trade_id = NULL, modified_at = NOW() WHERE escrow_trade_id = $1`
+	_, err := r.db.DB.Exec(sql, tradeId)
+	return err
+}
+
+// TransferEscrowedItem hands an item held for a trade to another user, adds it to their
+// collection and releases it. Returns false if the item is no longer held for the trade,
+// e.g. it was spent.
+func (r *UserInventoryRepository) TransferEscrowedItem(inventoryId, tradeId, toUserId int64) (bool, error) {
+	var transferred int
+	sql := `WITH item AS (
+				UPDATE user_inventory SET user_id = $3, escrow_trade_id = NULL, modified_at = NOW()
+				WHERE id = $1 AND escrow_trade_id = $2 AND is_archived = false
+				RETURNING user_id, loot_item_id
+			), ` + collectItemClause + `
+			SELECT COUNT(*) FROM item`
+	err := r.db.DB.Get(&transferred, sql, inventoryId, tradeId, toUserId)
+	if err != nil {
+		return false, err
+	}
+	return transferred > 0, nil
+}
+
+// WithTx returns a copy of the repository that runs its queries inside the given transaction
+func (r *UserInventoryRepository) WithTx(tx *database.AppDataSource) IUserInventoryRepository {
+	return &UserInventoryRepository{
+		db: tx,
+	}
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// UserLoginHistoryEntity is one login attempt against a user's account
+type UserLoginHistoryEntity struct {
+	ID         int64      `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived bool       `json:"is_archived" db:"is_archived"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Method     string     `json:"method" db:"method"`
+	Outcome    string     `json:"outcome" db:"outcome"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+}
+
+type IUserLoginHistoryRepository interface {
+	CreateLoginHistory(userId int64, method, outcome, ipAddress, userAgent string) (*UserLoginHistoryEntity, error)
+	GetRecentLoginHistory(userId int64, limit int) ([]*UserLoginHistoryEntity, error)
+	HasSuccessfulLogin(userId int64) (bool, error)
+	HasSuccessfulLoginFrom(userId int64, userAgent, ipAddress string) (bool, error)
+	WithTx(tx *database.AppDataSource) IUserLoginHistoryRepository
+}
+
+type UserLoginHistoryRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUserLoginHistoryRepository(db *database.AppDataSource) IUserLoginHistoryRepository {
+	return &UserLoginHistoryRepository{
+		db: db,
+	}
+}
+
+func (r *UserLoginHistoryRepository) CreateLoginHistory(userId int64, method, outcome, ipAddress, userAgent string) (*UserLoginHistoryEntity, error) {
+	login := &UserLoginHistoryEntity{}
+	query := `INSERT INTO user_login_history (user_id, method, outcome, ip_address, user_agent)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, modified_at, is_archived, user_id, method, outcome, ip_address, user_agent`
+	err := r.db.DB.Get(login, query, userId, method, outcome, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return login, nil
+}
+
+// GetRecentLoginHistory returns the user's most recent login attempts, newest first
+func (r *UserLoginHistoryRepository) GetRecentLoginHistory(userId int64, limit int) ([]*UserLoginHistoryEntity, error) {
+	logins := []*UserLoginHistoryEntity{}
+	query := `SELECT id, created_at, modified_at, is_archived, user_id, method, outcome, ip_address, user_agent
+			FROM user_login_history
+			WHERE user_id = $1 AND is_archived = false
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2`
+	err := r.db.DB.Select(&logins, query, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return logins, nil
+}
+
+// HasSuccessfulLogin returns whether the user has ever logged in
+func (r *UserLoginHistoryRepository) HasSuccessfulLogin(userId int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (
+				SELECT 1 FROM user_login_history
+				WHERE user_id = $1 AND outcome = 'success' AND is_archived = false
+			)`
+	err := r.db.DB.Get(&exists, query, userId)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// HasSuccessfulLoginFrom returns whether the user has logged in before from the same
+// user agent and IP address
+func (r *UserLoginHistoryRepository) HasSuccessfulLoginFrom(userId int64, userAgent, ipAddress string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (
+				SELECT 1 FROM user_login_history
+				WHERE user_id = $1 AND user_agent = $2 AND ip_address = $3
+					AND outcome = 'success' AND is_archived = false
+			)`
+	err := r.db.DB.Get(&exists, query, userId, userAgent, ipAddress)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *UserLoginHistoryRepository) WithTx(tx *database.AppDataSource) IUserLoginHistoryRepository {
+	return &UserLoginHistoryRepository{
+		db: tx,
+	}
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// UserSessionEntity is one logged in device. Only the hash of its refresh token is kept.
+type UserSessionEntity struct {
+	ID                int64      `json:"id" db:"id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt        *time.Time `json:"modified_at" db:"modified_at"`
+	IsArchived        bool       `json:"is_archived" db:"is_archived"`
+	UserID            int64      `json:"user_id" db:"user_id"`
+	RefreshTokenHash  string     `json:"-" db:"refresh_token_hash"`
+	PreviousTokenHash *string    `json:"-" db:"previous_token_hash"`
+	UserAgent         string     `json:"user_agent" db:"user_agent"`
+	IPAddress         string     `json:"ip_address" db:"ip_address"`
+	ExpiresAt         time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at" db:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+type IUserSessionRepository interface {
+	CreateSession(userId int64, refreshTokenHash, userAgent, ipAddress string, expiresAt time.Time) (*UserSessionEntity, error)
+	GetSessionById(sessionId int64) (*UserSessionEntity, error)
+	GetSessionByTokenHash(refreshTokenHash string) (*UserSessionEntity, error)
+	GetSessionByPreviousTokenHash(refreshTokenHash string) (*UserSessionEntity, error)
+	RotateSession(sessionId int64, oldTokenHash, newTokenHash, userAgent, ipAddress string, expiresAt time.Time) (bool, error)
+	GetActiveSessionsByUserId(userId int64) ([]*UserSessionEntity, error)
+	RevokeSession(userId, sessionId int64) (bool, error)
+	RevokeAllSessions(userId int64) error
+	WithTx(tx *database.AppDataSource) IUserSessionRepository
+}
+
+type UserSessionRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUserSessionRepository(db *database.AppDataSource) IUserSessionRepository {
+	return &UserSessionRepository{
+		db: db,
+	}
+}
+
+func (r *UserSessionRepository) CreateSession(userId int64, refreshTokenHash, userAgent, ipAddress string, expiresAt time.Time) (*UserSessionEntity, error) {
+	session := &UserSessionEntity{}
+	query := `INSERT INTO user_sessions (user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *`
+	err := r.db.DB.Get(session, query, userId, refreshTokenHash, userAgent, ipAddress, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSessionById returns the session, revoked or not, or nil if there isn't one
+func (r *UserSessionRepository) GetSessionById(sessionId int64) (*UserSessionEntity, error) {
+	return r.getSession(`SELECT * FROM user_sessions WHERE id = $1 AND is_archived = false`, sessionId)
+}
+
+// GetSessionByTokenHash returns the session the refresh token currently belongs to, or nil
+func (r *UserSessionRepository) GetSessionByTokenHash(refreshTokenHash string) (*UserSessionEntity, error) {
+	return r.getSession(`SELECT * FROM user_sessions WHERE refresh_token_hash = $1 AND is_archived = false`, refreshTokenHash)
+}
+
+// GetSessionByPreviousTokenHash returns the session whose last refresh replaced the
+// token, or nil
+func (r *UserSessionRepository) GetSessionByPreviousTokenHash(refreshTokenHash string) (*UserSessionEntity, error) {
+	return r.getSession(`SELECT * FROM user_sessions WHERE previous_token_hash = $1 AND is_archived = false`, refreshTokenHash)
+}
+
+// RotateSession swaps the session's refresh token for a new one and pushes back its
+// expiry. Returns false if the old token was already rotated or the session revoked.
+func (r *UserSessionRepository) RotateSession(sessionId int64, oldTokenHash, newTokenHash, userAgent, ipAddress string, expiresAt time.Time) (bool, error) {
+	query := `UPDATE user_sessions
+			SET refresh_token_hash = $3, previous_token_hash = $2, user_agent = $4, ip_address = $5,
+				expires_at = $6, last_used_at = NOW(), modified_at = NOW()
+			WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL`
+	result, err := r.db.DB.Exec(query, sessionId, oldTokenHash, newTokenHash, userAgent, ipAddress, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetActiveSessionsByUserId returns the user's sessions that haven't been revoked or
+// expired, most recently used first
+func (r *UserSessionRepository) GetActiveSessionsByUserId(userId int64) ([]*UserSessionEntity, error) {
+	sessions := []*UserSessionEntity{}
+	query := `SELECT * FROM user_sessions
+			WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND is_archived = false
+			ORDER BY last_used_at DESC, id DESC`
+	err := r.db.DB.Select(&sessions, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions.
+// Returns false if the user has no such session or it was already revoked.
+func (r *UserSessionRepository) RevokeSession(userId, sessionId int64) (bool, error) {
+	query := `UPDATE user_sessions SET revoked_at = NOW(), modified_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	result, err := r.db.DB.Exec(query, sessionId, userId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// RevokeAllSessions ends every session the user has, logging out all their devices
+func (r *UserSessionRepository) RevokeAllSessions(userId int64) error {
+	query := `UPDATE user_sessions SET revoked_at = NOW(), modified_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.DB.Exec(query, userId)
+	return err
+}
+
+func (r *UserSessionRepository) WithTx(tx *database.AppDataSource) IUserSessionRepository {
+	return &UserSessionRepository{
+		db: tx,
+	}
+}
+
+func (r *UserSessionRepository) getSession(query string, arg any) (*UserSessionEntity, error) {
+	session := &UserSessionEntity{}
+	err := r.db.DB.Get(session, query, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+)
+
+// UserTwoFactorEntity is a user's TOTP secret. EnabledAt is nil while the user is
+// still enrolling.
+type UserTwoFactorEntity struct {
+	UserID       int64      `json:"user_id" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`
+	EnabledAt    *time.Time `json:"enabled_at" db:"enabled_at"`
+	LastUsedStep int64      `json:"last_used_step" db:"last_used_step"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	ModifiedAt   time.Time  `json:"modified_at" db:"modified_at"`
+}
+
+// UserRecoveryCodeEntity is one of a user's one-time recovery codes
+type UserRecoveryCodeEntity struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type IUserTwoFactorRepository interface {
+	GetTwoFactor(userId int64) (*UserTwoFactorEntity, error)
+	SaveTwoFactorSecret(userId int64, secret string) (bool, error)
+	EnableTwoFactor(userId int64, step int64) (bool, error)
+	UseTwoFactorStep(userId int64, step int64) (bool, error)
+	DeleteTwoFactor(userId int64) error
+	ReplaceRecoveryCodes(userId int64, codeHashes []string) error
+	UseRecoveryCode(userId int64, codeHash string) (bool, error)
+	CountRecoveryCodes(userId int64) (int, error)
+	WithTx(tx *database.AppDataSource) IUserTwoFactorRepository
+}
+
+type UserTwoFactorRepository struct {
+	db *database.AppDataSource
+}
+
+func NewUserTwoFactorRepository(db *database.AppDataSource) IUserTwoFactorRepository {
+	return &UserTwoFactorRepository{
+		db: db,
+	}
+}
+
+// GetTwoFactor returns the user's TOTP secret, or nil if they've never enrolled
+func (r *UserTwoFactorRepository) GetTwoFactor(userId int64) (*UserTwoFactorEntity, error) {
+	twoFactor := &UserTwoFactorEntity{}
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at, modified_at
+			FROM user_two_factor
+			WHERE user_id = $1`
+	err := r.db.DB.Get(twoFactor, query, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return twoFactor, nil
+}
+
+// SaveTwoFactorSecret starts, or restarts, enrollment with a new secret.
+// Returns false if two-factor is already enabled, which leaves the secret alone.
+func (r *UserTwoFactorRepository) SaveTwoFactorSecret(userId int64, secret string) (bool, error) {
+	query := `INSERT INTO user_two_factor (user_id, secret)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW(), modified_at = NOW()
+			WHERE user_two_factor.enabled_at IS NULL`
+	return r.execAffectsRow(query, userId, secret)
+}
+
+// EnableTwoFactor finishes enrollment. step is the time step of the code the user
+// confirmed with, so it can't be used again to log in.
+// Returns false if the user isn't enrolling.
+func (r *UserTwoFactorRepository) EnableTwoFactor(userId int64, step int64) (bool, error) {
+	query := `UPDATE user_two_factor
+			SET enabled_at = NOW(), last_used_step = $2, modified_at = NOW()
+			WHERE user_id = $1 AND enabled_at IS NULL`
+	return r.execAffectsRow(query, userId, step)
+}
+
+// UseTwoFactorStep records that a code for step has been accepted.
+// Returns false if a code for that step or a later one already has been.
+func (r *UserTwoFactorRepository) UseTwoFactorStep(userId int64, step int64) (bool, error) {
+	query := `UPDATE user_two_factor
+			SET last_used_step = $2, modified_at = NOW()
+			WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2`
+	return r.execAffectsRow(query, userId, step)
+}
+
+// DeleteTwoFactor turns two-factor off, forgetting the secret and recovery codes
+func (r *UserTwoFactorRepository) DeleteTwoFactor(userId int64) error {
+	query := `WITH deleted_codes AS (
+				DELETE FROM user_recovery_codes WHERE user_id = $1
+			)
+			DELETE FROM user_two_factor WHERE user_id = $1`
+	_, err := r.db.DB.Exec(query, userId)
+	return err
+}
+
+// ReplaceRecoveryCodes swaps all of the user's recovery codes for new ones
+func (r *UserTwoFactorRepository) ReplaceRecoveryCodes(userId int64, codeHashes []string) error {
+	query := `WITH deleted_codes AS (
+				DELETE FROM user_recovery_codes WHERE user_id = $1
+			)
+			INSERT INTO user_recovery_codes (user_id, code_hash)
+			SELECT $1, unnest($2::varchar[])`
+	_, err := r.db.DB.Exec(query, userId, pq.Array(codeHashes))
+	return err
+}
+
+// UseRecoveryCode marks the recovery code as used.
+// Returns false if the user has no unused code with that hash.
+func (r *UserTwoFactorRepository) UseRecoveryCode(userId int64, codeHash string) (bool, error) {
+	query := `UPDATE user_recovery_codes
+			SET used_at = NOW()
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	return r.execAffectsRow(query, userId, codeHash)
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *UserTwoFactorRepository) CountRecoveryCodes(userId int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	err := r.db.DB.Get(&count, query, userId)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *UserTwoFactorRepository) WithTx(tx *database.AppDataSource) IUserTwoFactorRepository {
+	return &UserTwoFactorRepository{
+		db: tx,
+	}
+}
+
+func (r *UserTwoFactorRepository) execAffectsRow(query string, args ...any) (bool, error) {
+	result, err := r.db.DB.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -17,13 +17,15 @@ type IAuthMiddleware interface {
 type AuthMiddleware struct {
 	userRepository repositories.IUserRepository
 	tokenService   services.ITokenService
+	sessionService services.ISessionService
 	systemAPIKey   string
 }
 
-func NewAuthMiddleware(userRepository repositories.IUserRepository, tokenService services.ITokenService, systemAPIKey string) IAuthMiddleware {
+func NewAuthMiddleware(userRepository repositories.IUserRepository, tokenService services.ITokenService, sessionService services.ISessionService, systemAPIKey string) IAuthMiddleware {
 	return &AuthMiddleware{
 		userRepository: userRepository,
 		tokenService:   tokenService,
+		sessionService: sessionService,
 		systemAPIKey:   systemAPIKey,
 	}
 }
@@ -35,6 +37,7 @@ type AuthorizedUserContext struct {
 	Email     string `json:"email"`
 	Username  string `json:"username"`
 	CreatedAt string `json:"created_at"`
+	SessionID int64  `json:"session_id"`
 }
 
 func (m *AuthMiddleware) Authorize(r *http.Request) (*AuthorizedUserContext, error) {
@@ -45,13 +48,19 @@ func (m *AuthMiddleware) Authorize(r *http.Request) (*AuthorizedUserContext, err
 		return nil, errors.New("access token not found in request")
 	}
 
-	userId, err := m.tokenService.ValidateToken(&cookie.Value)
+	claims, err := m.tokenService.ValidateToken(&cookie.Value, services.TokenPurposeAccess)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		return nil, err
 	}
 
-	userEntity, err := m.userRepository.GetUserById(*userId)
+	// Tokens stop working as soon as their session is logged out
+	err = m.sessionService.ValidateSession(claims.UserID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	userEntity, err := m.userRepository.GetUserById(claims.UserID)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		return nil, err
@@ -70,6 +79,7 @@ func (m *AuthMiddleware) Authorize(r *http.Request) (*AuthorizedUserContext, err
 		Email:     userEntity.Email,
 		Username:  userEntity.DisplayName,
 		CreatedAt: userEntity.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		SessionID: claims.SessionID,
 	}, nil
 
 }
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+// maxEmailBodyBytes is as much of a request body as is read looking for an email
+const maxEmailBodyBytes = 4096
+
+type IRateLimitMiddleware interface {
+	LimitByIP(name string, limit services.RateLimit) func(http.Handler) http.Handler
+	LimitByEmail(name string, limit services.RateLimit) func(http.Handler) http.Handler
+}
+
+type RateLimitMiddleware struct {
+	rateLimitService services.IRateLimitService
+}
+
+func NewRateLimitMiddleware(rateLimitService services.IRateLimitService) IRateLimitMiddleware {
+	return &RateLimitMiddleware{
+		rateLimitService: rateLimitService,
+	}
+}
+
+// LimitByIP limits how often each client IP can call the route. name keeps each
+// route's limit separate.
+func (m *RateLimitMiddleware) LimitByIP(name string, limit services.RateLimit) func(http.Handler) http.Handler {
+	return m.limit(name, limit, func(r *http.Request) string {
+		return ClientIP(r)
+	})
+}
+
+// LimitByEmail limits how often the route can be called for each email, whichever IP
+// the requests come from. The email is read from the Basic auth header or the JSON body.
+func (m *RateLimitMiddleware) LimitByEmail(name string, limit services.RateLimit) func(http.Handler) http.Handler {
+	return m.limit(name, limit, requestEmail)
+}
+
+func (m *RateLimitMiddleware) limit(name string, limit services.RateLimit, keyOf func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Requests without a key are left for the handler to reject
+			key := keyOf(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := m.rateLimitService.Allow(fmt.Sprintf("%s:%s", name, key), limit)
+			var retryErr *services.RetryAfterError
+			if errors.As(err, &retryErr) {
+				util.LogWarning(fmt.Sprintf("Rate limited %s for %s", name, key))
+				WriteTooManyRequests(w, retryErr)
+				return
+			}
+			// Don't lock everyone out when the store is unavailable
+			if err != nil {
+				util.LogError(err)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteTooManyRequests refuses a request with a 429 and a Retry-After header in whole seconds
+func WriteTooManyRequests(w http.ResponseWriter, err *services.RetryAfterError) {
+	seconds := max(int(math.Ceil(err.RetryAfter.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// ClientIP is the address a request came from. Behind the nginx proxy that is the
+// X-Real-IP header, which nginx sets to the address that connected to it. X-Forwarded-For
+// isn't used, nginx appends to whatever the client sent so its first entry can be forged.
+func ClientIP(r *http.Request) string {
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// requestEmail returns the email in the request's Basic auth header or JSON body, or
+// "" if it has none. The body is put back for the handler to read.
+func requestEmail(r *http.Request) string {
+	if email, _, ok := r.BasicAuth(); ok {
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEmailBodyBytes))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(request.Email))
+}
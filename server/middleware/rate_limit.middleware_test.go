@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingRateLimitService allows every request and records the keys it was asked about
+type recordingRateLimitService struct {
+	keys []string
+}
+
+func (s *recordingRateLimitService) Allow(key string, limit services.RateLimit) error {
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *recordingRateLimitService) CheckLockout(email string) error      { return nil }
+func (s *recordingRateLimitService) RecordFailedLogin(email string) error { return nil }
+func (s *recordingRateLimitService) ClearFailedLogins(email string)       {}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		realIP     string
+		forwardFor string
+		expected   string
+	}{
+		{"behind the proxy", "203.0.113.7", "203.0.113.7", "203.0.113.7"},
+		{"forged X-Forwarded-For", "203.0.113.7", "198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"X-Forwarded-For without the proxy", "", "198.51.100.1", "192.0.2.1"},
+		{"invalid X-Real-IP", "not-an-ip", "", "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/login", nil)
+			r.RemoteAddr = "192.0.2.1:54321"
+			if test.realIP != "" {
+				r.Header.Set("X-Real-IP", test.realIP)
+			}
+			if test.forwardFor != "" {
+				r.Header.Set("X-Forwarded-For", test.forwardFor)
+			}
+
+			assert.Equal(t, test.expected, ClientIP(r))
+		})
+	}
+}
+
+func TestRateLimitMiddleware_LimitByIP_IgnoresForgedForwardedFor(t *testing.T) {
+	// Arrange
+	rateLimitService := &recordingRateLimitService{}
+	handler := NewRateLimitMiddleware(rateLimitService).LimitByIP("login", services.RateLimit{Burst: 1})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	// Act
+	for _, forged := range []string{"198.51.100.1", "198.51.100.2"} {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.Header.Set("X-Real-IP", "203.0.113.7")
+		r.Header.Set("X-Forwarded-For", forged+", 203.0.113.7")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// Assert
+	assert.Equal(t, []string{"login:203.0.113.7", "login:203.0.113.7"}, rateLimitService.keys)
+}
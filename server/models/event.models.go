@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// GameEventType is the kind of event pushed to a player's event stream. It is sent as
+// the SSE event name, so clients listen for each type separately.
+type GameEventType string
+
+const (
+	GameEventExpeditionCompleted    GameEventType = "expedition_completed"
+	GameEventTeamUnlocked           GameEventType = "team_unlocked"
+	GameEventRiftUnlocked           GameEventType = "rift_unlocked"
+	GameEventLeaderboardRankChanged GameEventType = "leaderboard_rank_changed"
+	GameEventEchoEncounter          GameEventType = "echo_encounter"
+	GameEventTradeUpdated           GameEventType = "trade_updated"
+	GameEventNotification           GameEventType = "notification"
+)
+
+// GameEvent is a single event for one player. IDs increase across all players, so a
+// client reconnecting with Last-Event-ID only replays what it missed.
+type GameEvent struct {
+	ID        int64         `json:"id"`
+	Type      GameEventType `json:"type"`
+	UserID    int64         `json:"user_id"`
+	Data      any           `json:"data"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// ExpeditionCompletedEventDTO is sent once an expedition's loot has been rolled and it
+// is ready to claim
+type ExpeditionCompletedEventDTO struct {
+	ExpeditionID int64 `json:"expedition_id"`
+	TeamID       int64 `json:"team_id"`
+	RiftID       int64 `json:"rift_id"`
+}
+
+// TeamUnlockedEventDTO is sent when a team is unlocked. Automatic is set when the team
+// unlocked itself by the player meeting its unlock rule.
+type TeamUnlockedEventDTO struct {
+	TeamID     int64 `json:"team_id"`
+	TeamNumber int   `json:"team_number"`
+	Automatic  bool  `json:"automatic"`
+}
+
+// RiftUnlockedEventDTO is sent when the loot or progress from an expedition meets a
+// rift's unlock rule
+type RiftUnlockedEventDTO struct {
+	RiftID   int64  `json:"rift_id"`
+	RiftName string `json:"rift_name"`
+}
+
+// EchoEncounterEventDTO is sent to both players in an echo encounter once their bonus
+// loot is committed. OtherUserID is the player whose expedition was met.
+type EchoEncounterEventDTO struct {
+	EncounterID  int64  `json:"encounter_id"`
+	ExpeditionID int64  `json:"expedition_id"`
+	RiftID       int64  `json:"rift_id"`
+	RiftName     string `json:"rift_name"`
+	OtherUserID  int64  `json:"other_user_id"`
+	BonusItems   int    `json:"bonus_items"`
+}
+
+// LeaderboardRankChangedEventDTO is sent when a leaderboard rebuild moves a player who
+// is, or was, in the top players. OldRank is nil for players new to the top, NewRank is
+// nil for players no longer ranked.
+type LeaderboardRankChangedEventDTO struct {
+	LeaderboardType string `json:"leaderboard_type"`
+	OldRank         *int   `json:"old_rank"`
+	NewRank         *int   `json:"new_rank"`
+	Score           int64  `json:"score"`
+}
+
+// TradeUpdatedEventDTO is sent to a player when someone offers them a trade, or a trade
+// they're part of is resolved by the other player or expires
+type TradeUpdatedEventDTO struct {
+	TradeID       int64  `json:"trade_id"`
+	Status        string `json:"status"`
+	OtherUserID   int64  `json:"other_user_id"`
+	OtherUserName string `json:"other_user_name"`
+}
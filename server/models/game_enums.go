@@ -66,3 +66,54 @@ const (
 	EquipmentSlotArtifact  EquipmentSlotType = "artifact"
 	EquipmentSlotRelic     EquipmentSlotType = "relic"
 )
+
+// UnlockTargetType represents what an unlock rule gates
+type UnlockTargetType string
+
+const (
+	UnlockTargetRift UnlockTargetType = "rift"
+	UnlockTargetTeam UnlockTargetType = "team"
+)
+
+// UnlockConditionType represents a condition in an unlock rule tree
+type UnlockConditionType string
+
+const (
+	// Combinators
+	UnlockConditionAll UnlockConditionType = "all"
+	UnlockConditionAny UnlockConditionType = "any"
+
+	// Leaf conditions
+	UnlockConditionCompletedExpeditions UnlockConditionType = "completed_expeditions"
+	UnlockConditionCompletedRift        UnlockConditionType = "completed_rift"
+	UnlockConditionOwnsRarity           UnlockConditionType = "owns_rarity"
+	UnlockConditionOwnsItem             UnlockConditionType = "owns_item"
+)
+
+// Specialization represents a team's permanent specialization
+type Specialization string
+
+const (
+	SpecializationNone      Specialization = "none"
+	SpecializationTank      Specialization = "tank"
+	SpecializationScout     Specialization = "scout"
+	SpecializationScientist Specialization = "scientist"
+)
+
+// ExpeditionStatus represents how far an expedition has got, or how it ended
+type ExpeditionStatus string
+
+const (
+	ExpeditionStatusActive    ExpeditionStatus = "active"
+	ExpeditionStatusCompleted ExpeditionStatus = "completed"
+	ExpeditionStatusRecalled  ExpeditionStatus = "recalled"
+)
+
+// LootRollType identifies what a logged loot roll decided
+type LootRollType string
+
+const (
+	LootRollTypeFailure LootRollType = "failure"
+	LootRollTypeRarity  LootRollType = "rarity"
+	LootRollTypeItem    LootRollType = "item"
+)
@@ -23,10 +23,27 @@ type ConsumeItemDTO struct {
 	InventoryID int64 `json:"inventory_id"`
 }
 
+type SpecializeTeamDTO struct {
+	Specialization string `json:"specialization"`
+	InventoryID    int64  `json:"inventory_id"`
+}
+
+type UpgradeTeamDTO struct {
+	RecipeID int64 `json:"recipe_id"`
+}
+
 type UnlockTeamDTO struct {
 	TeamID int64 `json:"team_id"`
 }
 
+// QueueLaunchDTO adds a rift launch to a team's queue. RepeatCount defaults to a single
+// launch, UntilStopped keeps launching the rift until the entry is removed.
+type QueueLaunchDTO struct {
+	RiftID       int64 `json:"rift_id"`
+	RepeatCount  int   `json:"repeat_count"`
+	UntilStopped bool  `json:"until_stopped"`
+}
+
 // Response DTOs
 
 type TeamStatsDTO struct {
@@ -48,19 +65,22 @@ type EquippedItemDTO struct {
 }
 
 type TeamResponseDTO struct {
-	ID                int64                  `json:"id"`
-	TeamNumber        int                    `json:"team_number"`
-	IsUnlocked        bool                   `json:"is_unlocked"`
-	BaseStats         TeamStatsDTO           `json:"base_stats"`
-	TotalStats        TeamStatsDTO           `json:"total_stats"`
-	EquippedWeapon    *EquippedItemDTO       `json:"equipped_weapon"`
-	EquippedArmor     *EquippedItemDTO       `json:"equipped_armor"`
-	EquippedAccessory *EquippedItemDTO       `json:"equipped_accessory"`
-	EquippedArtifact  *EquippedItemDTO       `json:"equipped_artifact"`
-	EquippedRelic     *EquippedItemDTO       `json:"equipped_relic"`
-	OnExpedition      bool                   `json:"on_expedition"`
-	ExpeditionData    *ExpeditionResponseDTO `json:"expedition_data,omitempty"`
-	UnlockRequirement *string                `json:"unlock_requirement,omitempty"`
+	ID                    int64                     `json:"id"`
+	TeamNumber            int                       `json:"team_number"`
+	IsUnlocked            bool                      `json:"is_unlocked"`
+	Specialization        string                    `json:"specialization"`
+	SpecializationBonuses []*SpecializationBonusDTO `json:"specialization_bonuses"`
+	BaseStats             TeamStatsDTO              `json:"base_stats"`
+	TotalStats            TeamStatsDTO              `json:"total_stats"`
+	EquippedWeapon        *EquippedItemDTO          `json:"equipped_weapon"`
+	EquippedArmor         *EquippedItemDTO          `json:"equipped_armor"`
+	EquippedAccessory     *EquippedItemDTO          `json:"equipped_accessory"`
+	EquippedArtifact      *EquippedItemDTO          `json:"equipped_artifact"`
+	EquippedRelic         *EquippedItemDTO          `json:"equipped_relic"`
+	OnExpedition          bool                      `json:"on_expedition"`
+	ExpeditionData        *ExpeditionResponseDTO    `json:"expedition_data,omitempty"`
+	UnlockRequirement     *string                   `json:"unlock_requirement,omitempty"`
+	UnlockProgress        *string                   `json:"unlock_progress,omitempty"`
 }
 
 type LootItemResponseDTO struct {
@@ -85,6 +105,7 @@ type InventoryItemResponseDTO struct {
 	AcquiredAt           string              `json:"acquired_at"`
 	IsEquipped           bool                `json:"is_equipped"`
 	EquippedByTeamNumber *int                `json:"equipped_by_team_number,omitempty"`
+	IsInEscrow           bool                `json:"is_in_escrow"` // Held for a pending trade
 	LootItem             LootItemResponseDTO `json:"loot_item"`
 }
 
@@ -104,24 +125,143 @@ type RiftResponseDTO struct {
 	UnlockRequirementText *string `json:"unlock_requirement_text"`
 	Icon                  string  `json:"icon"`
 	IsUnlocked            bool    `json:"is_unlocked"`
+	UnlockProgress        *string `json:"unlock_progress,omitempty"`
 }
 
 type ExpeditionResponseDTO struct {
-	ID              int64                  `json:"id"`
-	TeamID          int64                  `json:"team_id"`
-	TeamNumber      int                    `json:"team_number"`
-	RiftID          int64                  `json:"rift_id"`
-	RiftName        string                 `json:"rift_name"`
-	StartTime       string                 `json:"start_time"`
-	DurationMinutes int                    `json:"duration_minutes"`
-	CompletionTime  string                 `json:"completion_time"`
-	TimeRemaining   *int                   `json:"time_remaining"` // seconds, null if completed
-	IsCompleted     bool                   `json:"is_completed"`
-	IsClaimed       bool                   `json:"is_claimed"`
-	Loot            *[]LootItemResponseDTO `json:"loot,omitempty"` // Only if claimed
+	ID               int64                  `json:"id"`
+	TeamID           int64                  `json:"team_id"`
+	TeamNumber       int                    `json:"team_number"`
+	RiftID           int64                  `json:"rift_id"`
+	RiftName         string                 `json:"rift_name"`
+	StartTime        string                 `json:"start_time"`
+	DurationMinutes  int                    `json:"duration_minutes"`
+	CompletionTime   string                 `json:"completion_time"`
+	TimeRemaining    *int                   `json:"time_remaining"` // seconds, null if completed
+	IsCompleted      bool                   `json:"is_completed"`
+	IsClaimed        bool                   `json:"is_claimed"`
+	EffectivePower   int                    `json:"effective_power"`
+	RecommendedPower int                    `json:"recommended_power"`
+	IsUnderPowered   bool                   `json:"is_under_powered"`
+	PartialFailure   bool                   `json:"partial_failure"` // Only meaningful once processed
+	Status           string                 `json:"status"`
+	Loot             *[]LootItemResponseDTO `json:"loot,omitempty"` // Only if claimed
+}
+
+// SpecializationBonusDTO is the stat bonus a specialization gives in one world.
+// A WorldType of "all" applies in every world.
+type SpecializationBonusDTO struct {
+	WorldType    string  `json:"world_type"`
+	SpeedBonus   float64 `json:"speed_bonus"`
+	LuckBonus    float64 `json:"luck_bonus"`
+	PowerPercent float64 `json:"power_percent"`
+}
+
+type UpgradeCostDTO struct {
+	Rarity   string `json:"rarity"`
+	Quantity int    `json:"quantity"`
+	Owned    int    `json:"owned"`
+}
+
+// UpgradePreviewDTO shows what an upgrade recipe would do for a team and whether the player can afford it
+type UpgradePreviewDTO struct {
+	RecipeID     int64             `json:"recipe_id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Stat         string            `json:"stat"`
+	StatIncrease float64           `json:"stat_increase"`
+	MaxValue     float64           `json:"max_value"`
+	CurrentValue float64           `json:"current_value"`
+	NewValue     float64           `json:"new_value"`
+	IsMaxed      bool              `json:"is_maxed"`
+	CanAfford    bool              `json:"can_afford"`
+	Costs        []*UpgradeCostDTO `json:"costs"`
+}
+
+// PowerOutcomeDTO describes how a team's power compares to a rift's recommended power
+type PowerOutcomeDTO struct {
+	PowerRatio     float64 `json:"power_ratio"`
+	LootMultiplier float64 `json:"loot_multiplier"`
+	FailureChance  float64 `json:"failure_chance"`
+}
+
+// LootTableRarityDTO is one rarity tier of a rift's drop table. The base values come straight
+// from the drop table, the adjusted ones include the team's luck and power.
+type LootTableRarityDTO struct {
+	Rarity              string  `json:"rarity"`
+	BaseDropRatePercent float64 `json:"base_drop_rate_percent"`
+	DropRatePercent     float64 `json:"drop_rate_percent"`
+	MinQuantity         int     `json:"min_quantity"`
+	MaxQuantity         int     `json:"max_quantity"`
+	AdjustedMinQuantity int     `json:"adjusted_min_quantity"`
+	AdjustedMaxQuantity int     `json:"adjusted_max_quantity"`
+}
+
+type LootTableItemDTO struct {
+	LootItem  LootItemResponseDTO `json:"loot_item"`
+	Collected bool                `json:"collected"`
+}
+
+// RiftLootTableDTO previews what a rift can drop. Without a team the chances are the
+// rift's base odds, with one they are the odds that team would launch with.
+type RiftLootTableDTO struct {
+	RiftID           int64                 `json:"rift_id"`
+	RiftName         string                `json:"rift_name"`
+	WorldType        string                `json:"world_type"`
+	WeakToElement    string                `json:"weak_to_element"`
+	TeamID           *int64                `json:"team_id"`
+	TeamLuck         float64               `json:"team_luck"`
+	EffectivePower   int                   `json:"effective_power"`
+	RecommendedPower int                   `json:"recommended_power"`
+	ElementalBonus   float64               `json:"elemental_bonus"`
+	LootMultiplier   float64               `json:"loot_multiplier"`
+	FailureChance    float64               `json:"failure_chance"`
+	Rarities         []*LootTableRarityDTO `json:"rarities"`
+	Items            []*LootTableItemDTO   `json:"items"`
+	CollectedCount   int                   `json:"collected_count"`
 }
 
 type ExpeditionRewardsDTO struct {
 	ExpeditionID int64                 `json:"expedition_id"`
 	Loot         []LootItemResponseDTO `json:"loot"`
 }
+
+// LootRollDTO is one roll from an expedition's loot roll log
+type LootRollDTO struct {
+	Order      int      `json:"order"`
+	Type       string   `json:"type"`
+	Rarity     *string  `json:"rarity"`
+	Roll       float64  `json:"roll"`
+	Threshold  *float64 `json:"threshold"`
+	LootItemID *int64   `json:"loot_item_id"`
+}
+
+// LootReplayDTO compares the loot rolls recorded for an expedition with the rolls
+// made by replaying them from the expedition's seed
+type LootReplayDTO struct {
+	ExpeditionID   int64          `json:"expedition_id"`
+	Seed           int64          `json:"seed"`
+	Luck           float64        `json:"luck"`
+	LootMultiplier float64        `json:"loot_multiplier"`
+	Matches        bool           `json:"matches"`
+	Recorded       []*LootRollDTO `json:"recorded"`
+	Replayed       []*LootRollDTO `json:"replayed"`
+}
+
+// LaunchQueueEntryDTO is one queued rift launch. RepeatCount is the number of launches
+// left and is null when the entry repeats until stopped.
+type LaunchQueueEntryDTO struct {
+	ID           int64  `json:"id"`
+	RiftID       int64  `json:"rift_id"`
+	RiftName     string `json:"rift_name"`
+	Position     int    `json:"position"`
+	RepeatCount  *int   `json:"repeat_count"`
+	UntilStopped bool   `json:"until_stopped"`
+}
+
+// TeamQueueDTO is a team's launch queue in the order the launches will run
+type TeamQueueDTO struct {
+	TeamID     int64                  `json:"team_id"`
+	MaxEntries int                    `json:"max_entries"`
+	Entries    []*LaunchQueueEntryDTO `json:"entries"`
+}
@@ -0,0 +1,75 @@
+package models
+
+// GuildRole is a member's rank within their guild
+type GuildRole string
+
+const (
+	// GuildRoleLeader runs the guild. Every guild has exactly one.
+	GuildRoleLeader GuildRole = "leader"
+	// GuildRoleOfficer can kick members and manage the invite code
+	GuildRoleOfficer GuildRole = "officer"
+	GuildRoleMember  GuildRole = "member"
+)
+
+// Request DTOs
+
+type CreateGuildDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type JoinGuildDTO struct {
+	InviteCode string `json:"invite_code"`
+}
+
+// SetGuildRoleDTO changes a member's role. Giving someone the leader role hands them
+// the guild, and the old leader becomes an officer.
+type SetGuildRoleDTO struct {
+	Role string `json:"role"`
+}
+
+// GuildDepositDTO moves quantity of an inventory row into the guild treasury
+type GuildDepositDTO struct {
+	InventoryID int64 `json:"inventory_id"`
+	Quantity    int   `json:"quantity"`
+}
+
+// Response DTOs
+
+type GuildMemberDTO struct {
+	UserID      int64  `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"`
+	JoinedAt    string `json:"joined_at"` // RFC3339
+}
+
+type GuildTreasuryItemDTO struct {
+	LootItemID int64  `json:"loot_item_id"`
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	Rarity     string `json:"rarity"`
+	Quantity   int    `json:"quantity"`
+}
+
+// GuildRiftProgressDTO is the guild's combined progress in one rift
+type GuildRiftProgressDTO struct {
+	RiftID               int64  `json:"rift_id"`
+	RiftName             string `json:"rift_name"`
+	CompletedExpeditions int    `json:"completed_expeditions"`
+	MembersCompleted     int    `json:"members_completed"`
+}
+
+// GuildResponseDTO is the guild as seen by one of its members. InviteCode is only
+// included for members who can manage it.
+type GuildResponseDTO struct {
+	ID           int64                   `json:"id"`
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description"`
+	InviteCode   *string                 `json:"invite_code,omitempty"`
+	MaxMembers   int                     `json:"max_members"`
+	MyRole       string                  `json:"my_role"`
+	Members      []*GuildMemberDTO       `json:"members"`
+	Treasury     []*GuildTreasuryItemDTO `json:"treasury"`
+	RiftProgress []*GuildRiftProgressDTO `json:"rift_progress"`
+	CreatedAt    string                  `json:"created_at"` // RFC3339
+}
@@ -4,6 +4,7 @@ package models
 type LeaderboardEntry struct {
 	Rank          int    `json:"rank"`
 	UserID        int    `json:"user_id"`
+	GuildID       int    `json:"guild_id,omitempty"` // Set instead of UserID on guild leaderboards
 	Username      string `json:"username"`
 	Score         int64  `json:"score"`
 	IsCurrentUser bool   `json:"is_current_user"`
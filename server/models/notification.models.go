@@ -0,0 +1,29 @@
+package models
+
+// NotificationType is what a notification in a player's inbox is about
+type NotificationType string
+
+const (
+	NotificationExpeditionReady NotificationType = "expedition_ready"
+	NotificationTeamUnlocked    NotificationType = "team_unlocked"
+	NotificationRiftAvailable   NotificationType = "rift_available"
+	NotificationLeaderboardTop  NotificationType = "leaderboard_top"
+	NotificationEchoEncounter   NotificationType = "echo_encounter"
+	NotificationTradeOffered    NotificationType = "trade_offered"
+	NotificationTradeCompleted  NotificationType = "trade_completed"
+	NotificationTradeDeclined   NotificationType = "trade_declined"
+	NotificationTradeExpired    NotificationType = "trade_expired"
+)
+
+type NotificationResponseDTO struct {
+	ID        int64   `json:"id"`
+	Type      string  `json:"type"`
+	Message   string  `json:"message"`
+	IsRead    bool    `json:"is_read"`
+	CreatedAt string  `json:"created_at"`        // RFC3339
+	ReadAt    *string `json:"read_at,omitempty"` // RFC3339, nil until read
+}
+
+type UnreadNotificationsDTO struct {
+	UnreadCount int `json:"unread_count"`
+}
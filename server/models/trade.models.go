@@ -0,0 +1,64 @@
+package models
+
+// TradeStatus is where a trade is in its lifecycle. Every trade starts pending and
+// ends in one of the other statuses.
+type TradeStatus string
+
+const (
+	TradeStatusPending   TradeStatus = "pending"
+	TradeStatusCompleted TradeStatus = "completed"
+	TradeStatusDeclined  TradeStatus = "declined"
+	TradeStatusCancelled TradeStatus = "cancelled"
+	TradeStatusExpired   TradeStatus = "expired"
+)
+
+// Request DTOs
+
+// ProposeTradeDTO offers the proposer's equipment for the recipient's. Either side may
+// be empty, but not both.
+type ProposeTradeDTO struct {
+	RecipientID           int64   `json:"recipient_id"`
+	OfferedInventoryIDs   []int64 `json:"offered_inventory_ids"`
+	RequestedInventoryIDs []int64 `json:"requested_inventory_ids"`
+}
+
+// Response DTOs
+
+type TradeItemDTO struct {
+	InventoryID   int64   `json:"inventory_id"`
+	LootItemID    int64   `json:"loot_item_id"`
+	Name          string  `json:"name"`
+	Icon          string  `json:"icon"`
+	Rarity        string  `json:"rarity"`
+	EquipmentSlot *string `json:"equipment_slot"`
+}
+
+// TradeResponseDTO is a trade as seen by one of its players. Offered items come from
+// the proposer and requested items from the recipient.
+type TradeResponseDTO struct {
+	ID             int64           `json:"id"`
+	Status         string          `json:"status"`
+	ProposerID     int64           `json:"proposer_id"`
+	ProposerName   string          `json:"proposer_name"`
+	RecipientID    int64           `json:"recipient_id"`
+	RecipientName  string          `json:"recipient_name"`
+	IsProposer     bool            `json:"is_proposer"`
+	OfferedItems   []*TradeItemDTO `json:"offered_items"`
+	RequestedItems []*TradeItemDTO `json:"requested_items"`
+	CreatedAt      string          `json:"created_at"`            // RFC3339
+	ExpiresAt      string          `json:"expires_at"`            // RFC3339
+	ResolvedAt     *string         `json:"resolved_at,omitempty"` // RFC3339, nil while pending
+}
+
+// TradeListDTO is a player's open trades and their most recent resolved ones
+type TradeListDTO struct {
+	Pending []*TradeResponseDTO `json:"pending"`
+	History []*TradeResponseDTO `json:"history"`
+}
+
+// TradePartnerDTO is another player and the equipment they can be asked for
+type TradePartnerDTO struct {
+	UserID      int64           `json:"user_id"`
+	DisplayName string          `json:"display_name"`
+	Items       []*TradeItemDTO `json:"items"`
+}
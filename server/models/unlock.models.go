@@ -0,0 +1,27 @@
+package models
+
+// UnlockCondition is a node in an unlock rule tree. "all" and "any" nodes combine their
+// child Conditions, every other type is a leaf that checks one piece of player progress.
+//
+// Examples:
+//
+//	{"type": "completed_expeditions", "count": 10}
+//	{"type": "completed_rift", "rift_id": 1, "count": 1}
+//	{"type": "owns_rarity", "rarity": "epic", "count": 1}
+//	{"type": "owns_item", "item_name": "Explorers Compass"}
+//	{"type": "all", "conditions": [...]}
+type UnlockCondition struct {
+	Type       UnlockConditionType `json:"type"`
+	Count      int                 `json:"count,omitempty"`
+	RiftID     int64               `json:"rift_id,omitempty"`
+	Rarity     string              `json:"rarity,omitempty"`
+	ItemName   string              `json:"item_name,omitempty"`
+	Conditions []*UnlockCondition  `json:"conditions,omitempty"`
+}
+
+// UnlockStatusDTO is the result of evaluating an unlock rule for a player
+type UnlockStatusDTO struct {
+	IsUnlocked      bool    `json:"is_unlocked"`
+	RequirementText *string `json:"requirement_text,omitempty"` // nil when there is no rule
+	ProgressText    *string `json:"progress_text,omitempty"`    // e.g. "3/5 expeditions"
+}
@@ -6,9 +6,12 @@ type UserCreateDTO struct {
 	Password    string `json:"password"`
 }
 
+// UserLoginResponseDTO holds the tokens for a new session. Users with two-factor
+// authentication get a TwoFactorToken instead, and finish logging in with a code.
 type UserLoginResponseDTO struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken    string `json:"access_token"`
+	RefreshToken   string `json:"refresh_token"`
+	TwoFactorToken string `json:"two_factor_token,omitempty"`
 }
 
 type UserUpdatePasswordDTO struct {
@@ -26,3 +29,86 @@ type UserUpdateDTO struct {
 type UserBanDTO struct {
 	Reason string `json:"reason"`
 }
+
+// ClientInfoDTO is the device a request came from, stored with its session
+type ClientInfoDTO struct {
+	UserAgent string
+	IPAddress string
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserSessionDTO struct {
+	ID         int64  `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`   // RFC3339
+	LastUsedAt string `json:"last_used_at"` // RFC3339
+	ExpiresAt  string `json:"expires_at"`   // RFC3339
+	IsCurrent  bool   `json:"is_current"`
+}
+
+// LoginMethod is how a login was attempted
+type LoginMethod string
+
+const (
+	LoginMethodPassword  LoginMethod = "password"
+	LoginMethodEmailLink LoginMethod = "email_link"
+)
+
+// LoginOutcome is whether a login attempt worked, and if not, why
+type LoginOutcome string
+
+const (
+	LoginOutcomeSuccess         LoginOutcome = "success"
+	LoginOutcomeInvalidPassword LoginOutcome = "invalid_password"
+	LoginOutcomeLinkAlreadyUsed LoginOutcome = "link_already_used"
+	// The password or email link was fine, but not the two-factor code
+	LoginOutcomeInvalidTwoFactorCode LoginOutcome = "invalid_two_factor_code"
+)
+
+type UserLoginDTO struct {
+	ID        int64        `json:"id"`
+	Method    LoginMethod  `json:"method"`
+	Outcome   LoginOutcome `json:"outcome"`
+	IPAddress string       `json:"ip_address"`
+	UserAgent string       `json:"user_agent"`
+	CreatedAt string       `json:"created_at"` // RFC3339
+}
+
+// TwoFactorLoginDTO finishes a login for a user with two-factor authentication. Code is
+// from their authenticator app, or one of their recovery codes.
+type TwoFactorLoginDTO struct {
+	TwoFactorToken string `json:"two_factor_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorDisableDTO re-authenticates the user, turning two-factor off needs both factors
+type TwoFactorDisableDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorStatusDTO struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorEnrollmentDTO is what the user needs to add their account to an
+// authenticator app. QRCode is a PNG data URI of OTPAuthURI.
+type TwoFactorEnrollmentDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+// TwoFactorRecoveryCodesDTO is only ever returned once, the codes are stored hashed
+type TwoFactorRecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -11,34 +11,51 @@ import (
 	"github.com/snowlynxsoftware/parallax-game/server/util"
 )
 
+// Errors returned by AuthService that callers can check with errors.Is
+var (
+	ErrTokenNotVerified = errors.New("the token could not be verified")
+	ErrInvalidPassword  = errors.New("the password is incorrect")
+)
+
 type IAuthService interface {
 	RegisterNewUser(dto *models.UserCreateDTO) (*repositories.UserEntity, error)
-	Login(authHeaderStr *string) (*models.UserLoginResponseDTO, error)
+	Login(authHeaderStr *string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error)
 	VerifyNewUser(verificationToken *string) (*int, error)
 	SendLoginEmail(email string) (*repositories.UserEntity, error)
-	LoginWithEmailLink(userId *int) (*models.UserLoginResponseDTO, error)
+	LoginWithEmailLink(loginToken *string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error)
+	CompleteTwoFactorLogin(twoFactorToken *string, code string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error)
+	DisableTwoFactor(userId int, password string, code string) error
 	UpdateUserPassword(userId *int, password string) (*int, error)
+	ResetPassword(resetToken *string, password string) (*int, error)
 	SendResetPasswordEmail(email string) (*repositories.UserEntity, error)
 }
 
 type AuthService struct {
-	userRepository repositories.IUserRepository
-	teamRepository repositories.ITeamRepository
-	tokenService   ITokenService
-	cryptoService  ICryptoService
-	emailService   IEmailService
-	configService  config.IAppConfig
+	userRepository      repositories.IUserRepository
+	teamRepository      repositories.ITeamRepository
+	tokenService        ITokenService
+	sessionService      ISessionService
+	rateLimitService    IRateLimitService
+	loginHistoryService ILoginHistoryService
+	twoFactorService    ITwoFactorService
+	cryptoService       ICryptoService
+	emailService        IEmailService
+	configService       config.IAppConfig
 }
 
 func NewAuthService(
 	userRepository repositories.IUserRepository,
 	teamRepository repositories.ITeamRepository,
 	tokenService ITokenService,
+	sessionService ISessionService,
+	rateLimitService IRateLimitService,
+	loginHistoryService ILoginHistoryService,
+	twoFactorService ITwoFactorService,
 	cryptoService ICryptoService,
 	emailService IEmailService,
 	configService config.IAppConfig,
 ) IAuthService {
-	return &AuthService{userRepository: userRepository, teamRepository: teamRepository, tokenService: tokenService, cryptoService: cryptoService, emailService: emailService, configService: configService}
+	return &AuthService{userRepository: userRepository, teamRepository: teamRepository, tokenService: tokenService, sessionService: sessionService, rateLimitService: rateLimitService, loginHistoryService: loginHistoryService, twoFactorService: twoFactorService, cryptoService: cryptoService, emailService: emailService, configService: configService}
 }
 
 func (s *AuthService) RegisterNewUser(dto *models.UserCreateDTO) (*repositories.UserEntity, error) {
@@ -117,7 +134,7 @@ func (s *AuthService) SendResetPasswordEmail(email string) (*repositories.UserEn
 		return nil, err
 	}
 
-	verificationToken, err := s.tokenService.GenerateVerificationToken(int(user.ID))
+	resetToken, err := s.tokenService.GeneratePasswordResetToken(int(user.ID))
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +143,7 @@ func (s *AuthService) SendResetPasswordEmail(email string) (*repositories.UserEn
 	emailOptions.FromEmail = "do-not-reply@snowlynxsoftware.net"
 	emailOptions.ToEmail = user.Email
 	emailOptions.Subject = "Parallax - Password Reset Request"
-	emailOptions.HTMLContent = s.emailService.GetTemplates().GetPasswordResetEmailTemplate(s.configService.GetBaseURL(), *verificationToken)
+	emailOptions.HTMLContent = s.emailService.GetTemplates().GetPasswordResetEmailTemplate(s.configService.GetBaseURL(), *resetToken)
 	var isEmailSuccess = s.emailService.SendEmail(emailOptions)
 	if isEmailSuccess {
 		return user, nil
@@ -135,34 +152,126 @@ func (s *AuthService) SendResetPasswordEmail(email string) (*repositories.UserEn
 	}
 }
 
-func (s *AuthService) LoginWithEmailLink(userId *int) (*models.UserLoginResponseDTO, error) {
+// LoginWithEmailLink logs the user in with the token from a login email. Each link
+// only works once. Users with two-factor authentication still need to enter a code.
+func (s *AuthService) LoginWithEmailLink(loginToken *string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+
+	claims, err := s.tokenService.ValidateToken(loginToken, TokenPurposeLoginWithEmail)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		return nil, ErrTokenNotVerified
+	}
+
+	err = s.tokenService.ConsumeToken(claims)
+	if err != nil {
+		if errors.Is(err, ErrTokenAlreadyUsed) {
+			s.loginHistoryService.RecordLogin(claims.UserID, models.LoginMethodEmailLink, models.LoginOutcomeLinkAlreadyUsed, client)
+		}
+		return nil, err
+	}
+
+	return s.passFirstFactor(claims.UserID, models.LoginMethodEmailLink, client)
+}
+
+// CompleteTwoFactorLogin finishes logging in a user with two-factor authentication,
+// with the token they got for their password or email link and a code. A wrong code
+// counts as a failed login, but the token can be used again until it expires.
+func (s *AuthService) CompleteTwoFactorLogin(twoFactorToken *string, code string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
 
-	accessToken, err := s.tokenService.GenerateAccessToken(*userId)
+	claims, err := s.tokenService.ValidateToken(twoFactorToken, TokenPurposeTwoFactor)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		return nil, ErrTokenNotVerified
+	}
+
+	user, err := s.userRepository.GetUserById(claims.UserID)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		return nil, errors.New("there was an issue trying to log this user in")
 	}
 
-	// Update user's last login timestamp
-	_, err = s.userRepository.UpdateUserLastLogin(userId)
+	err = s.rateLimitService.CheckLockout(user.Email)
+	if err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			return nil, err
+		}
+		util.LogError(err)
+	}
+
+	err = s.twoFactorService.VerifyCode(claims.UserID, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.loginHistoryService.RecordLogin(claims.UserID, claims.LoginMethod, models.LoginOutcomeInvalidTwoFactorCode, client)
+		if lockErr := s.failedLogin(user.Email); errors.Is(lockErr, ErrAccountLocked) {
+			return nil, lockErr
+		}
+		return nil, err
+	}
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		return nil, errors.New("there was an issue trying to log this user in")
 	}
 
-	return &models.UserLoginResponseDTO{
-		AccessToken:  *accessToken,
-		RefreshToken: "",
-	}, nil
+	err = s.tokenService.ConsumeToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	s.rateLimitService.ClearFailedLogins(user.Email)
+
+	return s.startSession(claims.UserID, claims.LoginMethod, client)
+}
+
+// DisableTwoFactor turns off two-factor authentication. The user has to enter their
+// password and a code again, so a device left logged in can't turn it off.
+func (s *AuthService) DisableTwoFactor(userId int, password string, code string) error {
+
+	user, err := s.userRepository.GetUserById(userId)
+	if err != nil {
+		return err
+	}
+
+	// Wrong passwords and codes count against the same lockout as logins, otherwise a stolen
+	// session could guess the password here without ever being locked out
+	err = s.rateLimitService.CheckLockout(user.Email)
+	if err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			return err
+		}
+		util.LogError(err)
+	}
+
+	if user.PasswordHash == nil {
+		return ErrInvalidPassword
+	}
+	isValid, err := s.cryptoService.ValidatePassword(password, *user.PasswordHash)
+	if err != nil || !isValid {
+		if lockErr := s.failedLogin(user.Email); errors.Is(lockErr, ErrAccountLocked) {
+			return lockErr
+		}
+		return ErrInvalidPassword
+	}
+
+	err = s.twoFactorService.VerifyCode(userId, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if lockErr := s.failedLogin(user.Email); errors.Is(lockErr, ErrAccountLocked) {
+			return lockErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.twoFactorService.Disable(userId)
 }
 
 func (s *AuthService) VerifyNewUser(verificationToken *string) (*int, error) {
 
-	var userId, err = s.tokenService.ValidateToken(verificationToken)
+	claims, err := s.tokenService.ValidateToken(verificationToken, TokenPurposeVerification)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
-		return nil, errors.New("the token could not be verified")
+		return nil, ErrTokenNotVerified
 	}
+	userId := &claims.UserID
 
 	_, err = s.userRepository.MarkUserVerified(userId)
 	if err != nil {
@@ -188,7 +297,44 @@ func (s *AuthService) UpdateUserPassword(userId *int, password string) (*int, er
 	return userId, nil
 }
 
-func (s *AuthService) Login(authHeaderStr *string) (*models.UserLoginResponseDTO, error) {
+// ResetPassword sets a new password with the token from a password reset email and logs
+// the user out everywhere, since whoever had the old password may still hold a session.
+// Each link only works once.
+func (s *AuthService) ResetPassword(resetToken *string, password string) (*int, error) {
+
+	claims, err := s.tokenService.ValidateToken(resetToken, TokenPurposePasswordReset)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		return nil, ErrTokenNotVerified
+	}
+
+	// Hash the password before using up the link. Hashing rejects passwords that are too
+	// short, and a rejected password can then be retried with the same link.
+	hashedPassword, err := s.cryptoService.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tokenService.ConsumeToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := &claims.UserID
+	_, err = s.userRepository.UpdateUserPassword(userId, *hashedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sessionService.RevokeAllSessions(*userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return userId, nil
+}
+
+func (s *AuthService) Login(authHeaderStr *string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
 
 	encodedCredentials := strings.TrimPrefix(*authHeaderStr, "Basic ")
 	decodedCredentials, err := base64.StdEncoding.DecodeString(encodedCredentials)
@@ -204,31 +350,87 @@ func (s *AuthService) Login(authHeaderStr *string) (*models.UserLoginResponseDTO
 	email := credentials[0]
 	password := credentials[1]
 
+	// Locked out emails don't get their password checked at all
+	err = s.rateLimitService.CheckLockout(email)
+	if err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			return nil, err
+		}
+		util.LogError(err)
+	}
+
 	user, err := s.userRepository.GetUserByEmail(email)
 	if err != nil {
-		return nil, errors.New("there was an issue trying to log this user in")
+		return nil, s.failedLogin(email)
 	}
 
 	isValid, err := s.cryptoService.ValidatePassword(password, *user.PasswordHash)
 	if err != nil || !isValid {
+		s.loginHistoryService.RecordLogin(int(user.ID), models.LoginMethodPassword, models.LoginOutcomeInvalidPassword, client)
+		return nil, s.failedLogin(email)
+	}
+
+	tokens, err := s.passFirstFactor(int(user.ID), models.LoginMethodPassword, client)
+	if err != nil {
+		return nil, err
+	}
+	// Failures are only forgotten once the user is logged in. Users with two-factor
+	// authentication still need a code, and wrong codes count against the same email,
+	// so clearing here would let a correct password reset the count between guesses.
+	if tokens.TwoFactorToken == "" {
+		s.rateLimitService.ClearFailedLogins(email)
+	}
+	return tokens, nil
+}
+
+// passFirstFactor logs in a user whose password or email link checked out. Users with
+// two-factor authentication get a token to finish logging in with a code instead.
+func (s *AuthService) passFirstFactor(userId int, method models.LoginMethod, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	isEnabled, err := s.twoFactorService.IsEnabled(userId)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		return nil, errors.New("there was an issue trying to log this user in")
+	}
+	if !isEnabled {
+		return s.startSession(userId, method, client)
+	}
+
+	twoFactorToken, err := s.tokenService.GenerateTwoFactorToken(userId, method)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
 		return nil, errors.New("there was an issue trying to log this user in")
 	}
+	return &models.UserLoginResponseDTO{TwoFactorToken: *twoFactorToken}, nil
+}
 
-	accessToken, err := s.tokenService.GenerateAccessToken(int(user.ID))
+// startSession logs in a user who has proven who they are
+func (s *AuthService) startSession(userId int, method models.LoginMethod, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	tokens, err := s.sessionService.StartSession(userId, client)
 	if err != nil {
+		util.LogErrorWithStackTrace(err)
 		return nil, errors.New("there was an issue trying to log this user in")
 	}
 
 	// Update user's last login timestamp
-	userId := int(user.ID)
 	_, err = s.userRepository.UpdateUserLastLogin(&userId)
 	if err != nil {
 		util.LogErrorWithStackTrace(err)
 		return nil, errors.New("there was an issue trying to log this user in")
 	}
+	s.loginHistoryService.RecordLogin(userId, method, models.LoginOutcomeSuccess, client)
+
+	return tokens, nil
+}
 
-	return &models.UserLoginResponseDTO{
-		AccessToken:  *accessToken,
-		RefreshToken: "",
-	}, nil
+// failedLogin counts a failed password against the email. If that locks the email out,
+// the lockout is returned so the caller knows when to try again.
+func (s *AuthService) failedLogin(email string) error {
+	err := s.rateLimitService.RecordFailedLogin(email)
+	if errors.Is(err, ErrAccountLocked) {
+		return err
+	}
+	if err != nil {
+		util.LogError(err)
+	}
+	return errors.New("there was an issue trying to log this user in")
 }
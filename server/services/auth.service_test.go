@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/snowlynxsoftware/parallax-game/config"
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/memory"
 	"github.com/snowlynxsoftware/parallax-game/server/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -24,20 +26,25 @@ type MockTokenService struct {
 	mock.Mock
 }
 
-func (m *MockTokenService) GenerateAccessToken(userID int) (*string, error) {
-	args := m.Called(userID)
+func (m *MockTokenService) GenerateAccessToken(userID int, sessionID int64) (*string, error) {
+	args := m.Called(userID, sessionID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*string), args.Error(1)
 }
 
-func (m *MockTokenService) ValidateToken(token *string) (*int, error) {
-	args := m.Called(token)
+func (m *MockTokenService) ValidateToken(token *string, expectedPurpose TokenPurpose) (*TokenClaims, error) {
+	args := m.Called(token, expectedPurpose)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*int), args.Error(1)
+	return args.Get(0).(*TokenClaims), args.Error(1)
+}
+
+func (m *MockTokenService) ConsumeToken(claims *TokenClaims) error {
+	args := m.Called(claims)
+	return args.Error(0)
 }
 
 func (m *MockTokenService) GenerateVerificationToken(userID int) (*string, error) {
@@ -72,7 +79,15 @@ func (m *MockTokenService) ValidateLoginWithEmailToken(token *string) (*int, err
 	return args.Get(0).(*int), args.Error(1)
 }
 
-func (m *MockTokenService) GenerateRefreshToken(userID int) (*string, error) {
+func (m *MockTokenService) GenerateRefreshToken() (*string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*string), args.Error(1)
+}
+
+func (m *MockTokenService) GeneratePasswordResetToken(userID int) (*string, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -80,6 +95,151 @@ func (m *MockTokenService) GenerateRefreshToken(userID int) (*string, error) {
 	return args.Get(0).(*string), args.Error(1)
 }
 
+func (m *MockTokenService) GenerateTwoFactorToken(userID int, method models.LoginMethod) (*string, error) {
+	args := m.Called(userID, method)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*string), args.Error(1)
+}
+
+// MockSessionService is a mock implementation of ISessionService
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) StartSession(userId int, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	args := m.Called(userId, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserLoginResponseDTO), args.Error(1)
+}
+
+func (m *MockSessionService) RefreshSession(refreshToken string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	args := m.Called(refreshToken, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserLoginResponseDTO), args.Error(1)
+}
+
+func (m *MockSessionService) ValidateSession(userId int, sessionId int64) error {
+	args := m.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) GetSessions(userId int, currentSessionId int64) ([]*models.UserSessionDTO, error) {
+	args := m.Called(userId, currentSessionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserSessionDTO), args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(userId int, sessionId int64) error {
+	args := m.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeSessionByRefreshToken(refreshToken string) error {
+	args := m.Called(refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeAllSessions(userId int) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+// MockRateLimitService is a mock implementation of IRateLimitService
+type MockRateLimitService struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitService) Allow(key string, limit RateLimit) error {
+	args := m.Called(key, limit)
+	return args.Error(0)
+}
+
+func (m *MockRateLimitService) CheckLockout(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockRateLimitService) RecordFailedLogin(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockRateLimitService) ClearFailedLogins(email string) {
+	m.Called(email)
+}
+
+// MockLoginHistoryService is a mock implementation of ILoginHistoryService
+type MockLoginHistoryService struct {
+	mock.Mock
+}
+
+func (m *MockLoginHistoryService) RecordLogin(userId int, method models.LoginMethod, outcome models.LoginOutcome, client *models.ClientInfoDTO) {
+	m.Called(userId, method, outcome, client)
+}
+
+func (m *MockLoginHistoryService) GetRecentLogins(userId int) ([]*models.UserLoginDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserLoginDTO), args.Error(1)
+}
+
+// MockTwoFactorService is a mock implementation of ITwoFactorService
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) GetStatus(userId int) (*models.TwoFactorStatusDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorStatusDTO), args.Error(1)
+}
+
+func (m *MockTwoFactorService) IsEnabled(userId int) (bool, error) {
+	args := m.Called(userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorService) StartEnrollment(userId int, email string) (*models.TwoFactorEnrollmentDTO, error) {
+	args := m.Called(userId, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrollmentDTO), args.Error(1)
+}
+
+func (m *MockTwoFactorService) ConfirmEnrollment(userId int, code string) (*models.TwoFactorRecoveryCodesDTO, error) {
+	args := m.Called(userId, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorRecoveryCodesDTO), args.Error(1)
+}
+
+func (m *MockTwoFactorService) VerifyCode(userId int, code string) error {
+	args := m.Called(userId, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) Disable(userId int) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+// testClient is the device the login tests log in from
+var testClient = &models.ClientInfoDTO{UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.7"}
+
 // MockCryptoService is a mock implementation of ICryptoService
 type MockCryptoService struct {
 	mock.Mock
@@ -98,6 +258,21 @@ func (m *MockCryptoService) ValidatePassword(password string, hashedPassword str
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockCryptoService) HashRecoveryCode(code string) string {
+	args := m.Called(code)
+	return args.String(0)
+}
+
+func (m *MockCryptoService) EncryptSecret(secret string) (string, error) {
+	args := m.Called(secret)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockCryptoService) DecryptSecret(encrypted string) (string, error) {
+	args := m.Called(encrypted)
+	return args.String(0), args.Error(1)
+}
+
 // MockTeamRepository is a mock implementation of ITeamRepository
 type MockTeamRepository struct {
 	mock.Mock
@@ -124,6 +299,14 @@ func (m *MockTeamRepository) GetTeamById(teamId int64) (*repositories.TeamEntity
 	return args.Get(0).(*repositories.TeamEntity), args.Error(1)
 }
 
+func (m *MockTeamRepository) GetTeamByIdForUpdate(teamId int64) (*repositories.TeamEntity, error) {
+	args := m.Called(teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.TeamEntity), args.Error(1)
+}
+
 func (m *MockTeamRepository) UpdateTeamStats(teamId int64, speedBonus, luckBonus float64, powerBonus int) error {
 	args := m.Called(teamId, speedBonus, luckBonus, powerBonus)
 	return args.Error(0)
@@ -155,6 +338,15 @@ func (m *MockTeamRepository) GetTeamsByUserIdWithSlot(userId int64, inventoryId
 	return args.Get(0).(*repositories.TeamEntity), args.Get(1).(*string), args.Error(2)
 }
 
+func (m *MockTeamRepository) SetSpecialization(teamId int64, specialization string) (bool, error) {
+	args := m.Called(teamId, specialization)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTeamRepository) WithTx(tx *database.AppDataSource) repositories.ITeamRepository {
+	return m
+}
+
 // MockEmailService is a mock implementation of IEmailService
 type MockEmailService struct {
 	mock.Mock
@@ -190,6 +382,11 @@ func (m *MockEmailTemplates) GetPasswordResetEmailTemplate(baseURL string, verif
 	return args.String(0)
 }
 
+func (m *MockEmailTemplates) GetNewDeviceLoginEmailTemplate(baseURL string, userAgent string, ipAddress string, loginTime time.Time) string {
+	args := m.Called(baseURL, userAgent, ipAddress, loginTime)
+	return args.String(0)
+}
+
 // MockConfigService is a mock implementation of IAppConfig
 type MockConfigService struct {
 	mock.Mock
@@ -225,6 +422,11 @@ func (m *MockConfigService) GetJWTSecretKey() string {
 	return args.String(0)
 }
 
+func (m *MockConfigService) GetJWTPreviousSecretKeys() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
 func (m *MockConfigService) GetMJAPIKeyPublic() string {
 	args := m.Called()
 	return args.String(0)
@@ -255,6 +457,10 @@ func TestAuthService_RegisterNewUser_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 	mockEmailTemplates := new(MockEmailTemplates)
@@ -262,7 +468,7 @@ func TestAuthService_RegisterNewUser_Success(t *testing.T) {
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "test@example.com",
@@ -315,13 +521,17 @@ func TestAuthService_RegisterNewUser_UserAlreadyExists(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "existing@example.com",
@@ -351,13 +561,17 @@ func TestAuthService_RegisterNewUser_PasswordHashingError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "test@example.com",
@@ -384,6 +598,10 @@ func TestAuthService_RegisterNewUser_EmailSendFailure(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 	mockEmailTemplates := new(MockEmailTemplates)
@@ -391,7 +609,7 @@ func TestAuthService_RegisterNewUser_EmailSendFailure(t *testing.T) {
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "test@example.com",
@@ -437,6 +655,10 @@ func TestAuthService_SendLoginEmail_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 	mockEmailTemplates := new(MockEmailTemplates)
@@ -444,7 +666,7 @@ func TestAuthService_SendLoginEmail_Success(t *testing.T) {
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	email := "test@example.com"
 	loginToken := "login_token_123"
@@ -483,13 +705,17 @@ func TestAuthService_SendLoginEmail_UserNotFound(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	email := "nonexistent@example.com"
 
@@ -510,57 +736,77 @@ func TestAuthService_LoginWithEmailLink_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
 	accessToken := "access_token_123"
+	loginToken := "login_token_123"
+	claims := &TokenClaims{UserID: userId}
 
-	mockTokenService.On("GenerateAccessToken", userId).Return(&accessToken, nil)
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeLoginWithEmail).Return(claims, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockSessionService.On("StartSession", userId, testClient).Return(&models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: "refresh_token_123"}, nil)
+	mockTwoFactorService.On("IsEnabled", userId).Return(false, nil)
 	mockUserRepo.On("UpdateUserLastLogin", &userId).Return(true, nil)
+	mockLoginHistoryService.On("RecordLogin", userId, models.LoginMethodEmailLink, models.LoginOutcomeSuccess, testClient).Return()
 
 	// Act
-	result, err := authService.LoginWithEmailLink(&userId)
+	result, err := authService.LoginWithEmailLink(&loginToken, testClient)
 
 	// Assert
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, accessToken, result.AccessToken)
-	assert.Equal(t, "", result.RefreshToken)
-	mockTokenService.AssertExpectations(t)
+	assert.Equal(t, "refresh_token_123", result.RefreshToken)
+	mockSessionService.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
+	mockLoginHistoryService.AssertExpectations(t)
 }
 
-// Test LoginWithEmailLink - Token Generation Error
-func TestAuthService_LoginWithEmailLink_TokenGenerationError(t *testing.T) {
+// Test LoginWithEmailLink - Session Error
+func TestAuthService_LoginWithEmailLink_SessionError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
+	loginToken := "login_token_123"
+	claims := &TokenClaims{UserID: userId}
 
-	mockTokenService.On("GenerateAccessToken", userId).Return(nil, errors.New("token generation failed"))
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeLoginWithEmail).Return(claims, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockSessionService.On("StartSession", userId, testClient).Return(nil, errors.New("session creation failed"))
+	mockTwoFactorService.On("IsEnabled", userId).Return(false, nil)
 
 	// Act
-	result, err := authService.LoginWithEmailLink(&userId)
+	result, err := authService.LoginWithEmailLink(&loginToken, testClient)
 
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
-	mockTokenService.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
 }
 
 // Test LoginWithEmailLink - Last Login Update Error
@@ -568,48 +814,126 @@ func TestAuthService_LoginWithEmailLink_LastLoginUpdateError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
 	accessToken := "access_token_123"
+	loginToken := "login_token_123"
+	claims := &TokenClaims{UserID: userId}
 
-	mockTokenService.On("GenerateAccessToken", userId).Return(&accessToken, nil)
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeLoginWithEmail).Return(claims, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockSessionService.On("StartSession", userId, testClient).Return(&models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: "refresh_token_123"}, nil)
+	mockTwoFactorService.On("IsEnabled", userId).Return(false, nil)
 	mockUserRepo.On("UpdateUserLastLogin", &userId).Return(false, errors.New("database error"))
 
 	// Act
-	result, err := authService.LoginWithEmailLink(&userId)
+	result, err := authService.LoginWithEmailLink(&loginToken, testClient)
 
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
-	mockTokenService.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 }
 
+// Test LoginWithEmailLink - Wrong Token Purpose
+func TestAuthService_LoginWithEmailLink_WrongTokenPurpose(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	verificationToken := "verification_token_123"
+
+	mockTokenService.On("ValidateToken", &verificationToken, TokenPurposeLoginWithEmail).Return(nil, ErrWrongTokenPurpose)
+
+	// Act
+	result, err := authService.LoginWithEmailLink(&verificationToken, testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTokenNotVerified)
+	assert.Nil(t, result)
+	mockTokenService.AssertNotCalled(t, "ConsumeToken", mock.Anything)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+}
+
+// Test LoginWithEmailLink - Link Already Used
+func TestAuthService_LoginWithEmailLink_AlreadyUsed(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	loginToken := "login_token_123"
+	claims := &TokenClaims{UserID: 123}
+
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeLoginWithEmail).Return(claims, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(ErrTokenAlreadyUsed)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodEmailLink, models.LoginOutcomeLinkAlreadyUsed, testClient).Return()
+
+	// Act
+	result, err := authService.LoginWithEmailLink(&loginToken, testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTokenAlreadyUsed)
+	assert.Nil(t, result)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+	mockLoginHistoryService.AssertExpectations(t)
+}
+
 // Test VerifyNewUser - Success
 func TestAuthService_VerifyNewUser_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	verificationToken := "verification_token_123"
 	userId := 123
 
-	mockTokenService.On("ValidateToken", &verificationToken).Return(&userId, nil)
+	mockTokenService.On("ValidateToken", &verificationToken, TokenPurposeVerification).Return(&TokenClaims{UserID: userId}, nil)
 	mockUserRepo.On("MarkUserVerified", &userId).Return(true, nil)
 
 	// Act
@@ -628,17 +952,21 @@ func TestAuthService_VerifyNewUser_InvalidToken(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	verificationToken := "invalid_token"
 
-	mockTokenService.On("ValidateToken", &verificationToken).Return(nil, errors.New("token validation failed"))
+	mockTokenService.On("ValidateToken", &verificationToken, TokenPurposeVerification).Return(nil, errors.New("token validation failed"))
 
 	// Act
 	result, err := authService.VerifyNewUser(&verificationToken)
@@ -655,18 +983,22 @@ func TestAuthService_VerifyNewUser_DatabaseError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	verificationToken := "verification_token_123"
 	userId := 123
 
-	mockTokenService.On("ValidateToken", &verificationToken).Return(&userId, nil)
+	mockTokenService.On("ValidateToken", &verificationToken, TokenPurposeVerification).Return(&TokenClaims{UserID: userId}, nil)
 	mockUserRepo.On("MarkUserVerified", &userId).Return(false, errors.New("database error"))
 
 	// Act
@@ -685,13 +1017,17 @@ func TestAuthService_UpdateUserPassword_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
 	password := "newPassword123"
@@ -716,13 +1052,17 @@ func TestAuthService_UpdateUserPassword_HashingError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
 	password := "newPassword123"
@@ -744,13 +1084,17 @@ func TestAuthService_UpdateUserPassword_DatabaseError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userId := 123
 	password := "newPassword123"
@@ -770,170 +1114,381 @@ func TestAuthService_UpdateUserPassword_DatabaseError(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 }
 
-// Test Login - Success
-func TestAuthService_Login_Success(t *testing.T) {
+// Test ResetPassword - Success
+func TestAuthService_ResetPassword_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
-
-	email := "test@example.com"
-	password := "password123"
-	hashedPassword := "hashed_password_123"
-	accessToken := "access_token_123"
-
-	// Create valid Basic Auth header
-	credentials := email + ":" + password
-	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(credentials))
-	authHeader := "Basic " + encodedCredentials
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
-	user := &repositories.UserEntity{
-		ID:           123,
-		Email:        email,
-		PasswordHash: &hashedPassword,
-		IsVerified:   true,
-	}
+	userId := 123
+	resetToken := "reset_token_123"
+	password := "newPassword123"
+	hashedPassword := "hashed_new_password"
+	claims := &TokenClaims{UserID: userId}
 
-	mockUserRepo.On("GetUserByEmail", email).Return(user, nil)
-	mockCryptoService.On("ValidatePassword", password, hashedPassword).Return(true, nil)
-	mockTokenService.On("GenerateAccessToken", 123).Return(&accessToken, nil)
-	mockUserRepo.On("UpdateUserLastLogin", mock.MatchedBy(func(userId *int) bool { return *userId == 123 })).Return(true, nil)
+	mockTokenService.On("ValidateToken", &resetToken, TokenPurposePasswordReset).Return(claims, nil)
+	mockCryptoService.On("HashPassword", password).Return(&hashedPassword, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockUserRepo.On("UpdateUserPassword", &userId, hashedPassword).Return(true, nil)
+	mockSessionService.On("RevokeAllSessions", userId).Return(nil)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.ResetPassword(&resetToken, password)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, accessToken, result.AccessToken)
-	assert.Equal(t, "", result.RefreshToken)
-	mockUserRepo.AssertExpectations(t)
+	assert.Equal(t, userId, *result)
 	mockTokenService.AssertExpectations(t)
-	mockCryptoService.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
 }
 
-// Test Login - Malformed Authorization Header (No Basic Prefix)
-func TestAuthService_Login_MalformedHeaderNoBasic(t *testing.T) {
+// Test ResetPassword - Old Refresh Tokens Stop Working
+func TestAuthService_ResetPassword_RevokesRefreshTokens(t *testing.T) {
 	// Arrange
-	mockUserRepo := new(MockUserRepository)
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
 	mockTokenService := new(MockTokenService)
 	mockCryptoService := new(MockCryptoService)
-	mockEmailService := new(MockEmailService)
+	sessionService := NewSessionService(memory.NewUserSessionRepository(store), userRepo, mockTokenService)
+	authService := NewAuthService(userRepo, new(MockTeamRepository), mockTokenService, sessionService, new(MockRateLimitService), new(MockLoginHistoryService), new(MockTwoFactorService), mockCryptoService, new(MockEmailService), new(MockConfigService))
 
-	mockConfigService := new(MockConfigService)
-	mockTeamRepo := new(MockTeamRepository)
-	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	user, err := userRepo.CreateNewUser(&models.UserCreateDTO{Email: "reset@example.com", DisplayName: "Reset", Password: "hashed_old_password"})
+	assert.NoError(t, err)
+	userId := int(user.ID)
+	_, err = userRepo.MarkUserVerified(&userId)
+	assert.NoError(t, err)
 
-	authHeader := "Bearer some-token"
+	refreshToken := "old-refresh-token"
+	accessToken := "access-token"
+	mockTokenService.On("GenerateRefreshToken").Return(&refreshToken, nil)
+	mockTokenService.On("GenerateAccessToken", userId, mock.Anything).Return(&accessToken, nil)
+	client := &models.ClientInfoDTO{UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.7"}
+	login, err := sessionService.StartSession(userId, client)
+	assert.NoError(t, err)
+
+	resetToken := "reset_token_123"
+	password := "newPassword123"
+	hashedPassword := "hashed_new_password"
+	claims := &TokenClaims{UserID: userId}
+	mockTokenService.On("ValidateToken", &resetToken, TokenPurposePasswordReset).Return(claims, nil)
+	mockCryptoService.On("HashPassword", password).Return(&hashedPassword, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	_, err = authService.ResetPassword(&resetToken, password)
 
 	// Assert
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	result, err := sessionService.RefreshSession(login.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "failed to decode authorization header")
 }
 
-// Test Login - Invalid Base64 Encoding
-func TestAuthService_Login_InvalidBase64(t *testing.T) {
+// Test ResetPassword - Verification Token Can't Reset a Password
+func TestAuthService_ResetPassword_WrongTokenPurpose(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
-	authHeader := "Basic invalid-base64!"
+	verificationToken := "verification_token_123"
+
+	mockTokenService.On("ValidateToken", &verificationToken, TokenPurposePasswordReset).Return(nil, ErrWrongTokenPurpose)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.ResetPassword(&verificationToken, "newPassword123")
 
 	// Assert
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrTokenNotVerified)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "failed to decode authorization header")
+	mockUserRepo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything)
 }
 
-// Test Login - Missing Colon Separator
-func TestAuthService_Login_MissingColonSeparator(t *testing.T) {
+// Test ResetPassword - Link Already Used
+func TestAuthService_ResetPassword_AlreadyUsed(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
-	// "usernamepassword" without colon, base64 encoded
-	authHeader := "Basic dXNlcm5hbWVwYXNzd29yZA=="
+	resetToken := "reset_token_123"
+	password := "newPassword123"
+	hashedPassword := "hashed_new_password"
+	claims := &TokenClaims{UserID: 123}
+
+	mockTokenService.On("ValidateToken", &resetToken, TokenPurposePasswordReset).Return(claims, nil)
+	mockCryptoService.On("HashPassword", password).Return(&hashedPassword, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(ErrTokenAlreadyUsed)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.ResetPassword(&resetToken, password)
 
 	// Assert
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrTokenAlreadyUsed)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "invalid authorization header format")
+	mockUserRepo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything)
 }
 
-// Test Login - User Not Found
-func TestAuthService_Login_UserNotFound(t *testing.T) {
+// Test ResetPassword - Rejected Password Leaves the Link Usable
+func TestAuthService_ResetPassword_InvalidPasswordKeepsToken(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
-	// "user@example.com:password123" base64 encoded
-	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTpwYXNzd29yZDEyMw=="
+	resetToken := "reset_token_123"
+	claims := &TokenClaims{UserID: 123}
 
-	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(nil, errors.New("user not found"))
+	mockTokenService.On("ValidateToken", &resetToken, TokenPurposePasswordReset).Return(claims, nil)
+	mockCryptoService.On("HashPassword", "short").Return(nil, errors.New("password must be at least 10 characters long"))
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.ResetPassword(&resetToken, "short")
 
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
-	mockUserRepo.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "ConsumeToken", mock.Anything)
 }
 
-// Test Login - Invalid Password
-func TestAuthService_Login_InvalidPassword(t *testing.T) {
+// Test Login - Success
+func TestAuthService_Login_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
-	// "user@example.com:wrongpassword" base64 encoded
-	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTp3cm9uZ3Bhc3N3b3Jk"
+	email := "test@example.com"
+	password := "password123"
+	hashedPassword := "hashed_password_123"
+	accessToken := "access_token_123"
+
+	// Create valid Basic Auth header
+	credentials := email + ":" + password
+	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(credentials))
+	authHeader := "Basic " + encodedCredentials
+
+	user := &repositories.UserEntity{
+		ID:           123,
+		Email:        email,
+		PasswordHash: &hashedPassword,
+		IsVerified:   true,
+	}
+
+	mockUserRepo.On("GetUserByEmail", email).Return(user, nil)
+	mockCryptoService.On("ValidatePassword", password, hashedPassword).Return(true, nil)
+	mockSessionService.On("StartSession", 123, testClient).Return(&models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: "refresh_token_123"}, nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(false, nil)
+	mockUserRepo.On("UpdateUserLastLogin", mock.MatchedBy(func(userId *int) bool { return *userId == 123 })).Return(true, nil)
+	mockRateLimitService.On("CheckLockout", email).Return(nil)
+	mockRateLimitService.On("ClearFailedLogins", email).Return()
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeSuccess, testClient).Return()
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, accessToken, result.AccessToken)
+	assert.Equal(t, "refresh_token_123", result.RefreshToken)
+	mockUserRepo.AssertExpectations(t)
+	mockRateLimitService.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
+	mockCryptoService.AssertExpectations(t)
+	mockLoginHistoryService.AssertExpectations(t)
+}
+
+// Test Login - Malformed Authorization Header (No Basic Prefix)
+func TestAuthService_Login_MalformedHeaderNoBasic(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	authHeader := "Bearer some-token"
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "failed to decode authorization header")
+}
+
+// Test Login - Invalid Base64 Encoding
+func TestAuthService_Login_InvalidBase64(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	authHeader := "Basic invalid-base64!"
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "failed to decode authorization header")
+}
+
+// Test Login - Missing Colon Separator
+func TestAuthService_Login_MissingColonSeparator(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	// "usernamepassword" without colon, base64 encoded
+	authHeader := "Basic dXNlcm5hbWVwYXNzd29yZA=="
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "invalid authorization header format")
+}
+
+// Test Login - User Not Found
+func TestAuthService_Login_UserNotFound(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	// "user@example.com:password123" base64 encoded
+	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTpwYXNzd29yZDEyMw=="
+
+	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(nil, errors.New("user not found"))
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
+	mockUserRepo.AssertExpectations(t)
+}
+
+// Test Login - Invalid Password
+func TestAuthService_Login_InvalidPassword(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	// "user@example.com:wrongpassword" base64 encoded
+	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTp3cm9uZ3Bhc3N3b3Jk"
 	hashedPassword := "correct_hashed_password"
 
 	user := &repositories.UserEntity{
@@ -944,9 +1499,12 @@ func TestAuthService_Login_InvalidPassword(t *testing.T) {
 
 	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
 	mockCryptoService.On("ValidatePassword", "wrongpassword", hashedPassword).Return(false, errors.New("password mismatch"))
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidPassword, testClient).Return()
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.Login(&authHeader, testClient)
 
 	// Assert
 	assert.Error(t, err)
@@ -954,6 +1512,7 @@ func TestAuthService_Login_InvalidPassword(t *testing.T) {
 	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
 	mockUserRepo.AssertExpectations(t)
 	mockCryptoService.AssertExpectations(t)
+	mockLoginHistoryService.AssertExpectations(t)
 }
 
 // Test Login - Password Validation Returns False
@@ -961,13 +1520,17 @@ func TestAuthService_Login_PasswordValidationFalse(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	// "user@example.com:wrongpassword" base64 encoded
 	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTp3cm9uZ3Bhc3N3b3Jk"
@@ -981,9 +1544,12 @@ func TestAuthService_Login_PasswordValidationFalse(t *testing.T) {
 
 	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
 	mockCryptoService.On("ValidatePassword", "wrongpassword", hashedPassword).Return(false, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidPassword, testClient).Return()
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.Login(&authHeader, testClient)
 
 	// Assert
 	assert.Error(t, err)
@@ -993,18 +1559,99 @@ func TestAuthService_Login_PasswordValidationFalse(t *testing.T) {
 	mockCryptoService.AssertExpectations(t)
 }
 
-// Test Login - Token Generation Error
-func TestAuthService_Login_TokenGenerationError(t *testing.T) {
+// Test Login - Locked Out Email Isn't Checked
+func TestAuthService_Login_AccountLocked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	// "user@example.com:password123" base64 encoded
+	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTpwYXNzd29yZDEyMw=="
+
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(&RetryAfterError{Err: ErrAccountLocked, RetryAfter: time.Minute})
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	var retryErr *RetryAfterError
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, time.Minute, retryErr.RetryAfter)
+	assert.Nil(t, result)
+	mockUserRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything)
+	mockCryptoService.AssertNotCalled(t, "ValidatePassword", mock.Anything, mock.Anything)
+}
+
+// Test Login - Failed Password Locks the Email
+func TestAuthService_Login_FailureLocksAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	// "user@example.com:wrongpassword" base64 encoded
+	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTp3cm9uZ3Bhc3N3b3Jk"
+	hashedPassword := "correct_hashed_password"
+
+	user := &repositories.UserEntity{
+		ID:           123,
+		Email:        "user@example.com",
+		PasswordHash: &hashedPassword,
+	}
+
+	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
+	mockCryptoService.On("ValidatePassword", "wrongpassword", hashedPassword).Return(false, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(&RetryAfterError{Err: ErrAccountLocked, RetryAfter: time.Minute})
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidPassword, testClient).Return()
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.Nil(t, result)
+	mockRateLimitService.AssertNotCalled(t, "ClearFailedLogins", mock.Anything)
+}
+
+// Test Login - Session Error
+func TestAuthService_Login_SessionError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	// "user@example.com:password123" base64 encoded
 	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTpwYXNzd29yZDEyMw=="
@@ -1018,10 +1665,12 @@ func TestAuthService_Login_TokenGenerationError(t *testing.T) {
 
 	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
 	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
-	mockTokenService.On("GenerateAccessToken", 123).Return(nil, errors.New("token generation failed"))
+	mockSessionService.On("StartSession", 123, testClient).Return(nil, errors.New("session creation failed"))
+	mockTwoFactorService.On("IsEnabled", 123).Return(false, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.Login(&authHeader, testClient)
 
 	// Assert
 	assert.Error(t, err)
@@ -1029,7 +1678,7 @@ func TestAuthService_Login_TokenGenerationError(t *testing.T) {
 	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
 	mockUserRepo.AssertExpectations(t)
 	mockCryptoService.AssertExpectations(t)
-	mockTokenService.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
 }
 
 // Test Login - Last Login Update Error
@@ -1037,13 +1686,17 @@ func TestAuthService_Login_LastLoginUpdateError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	// "user@example.com:password123" base64 encoded
 	authHeader := "Basic dXNlckBleGFtcGxlLmNvbTpwYXNzd29yZDEyMw=="
@@ -1060,11 +1713,13 @@ func TestAuthService_Login_LastLoginUpdateError(t *testing.T) {
 
 	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
 	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
-	mockTokenService.On("GenerateAccessToken", 123).Return(&accessToken, nil)
+	mockSessionService.On("StartSession", 123, testClient).Return(&models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: "refresh_token_123"}, nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(false, nil)
 	mockUserRepo.On("UpdateUserLastLogin", &userId).Return(false, errors.New("last login update failed"))
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
 
 	// Act
-	result, err := authService.Login(&authHeader)
+	result, err := authService.Login(&authHeader, testClient)
 
 	// Assert
 	assert.Error(t, err)
@@ -1072,7 +1727,461 @@ func TestAuthService_Login_LastLoginUpdateError(t *testing.T) {
 	assert.Contains(t, err.Error(), "there was an issue trying to log this user in")
 	mockUserRepo.AssertExpectations(t)
 	mockCryptoService.AssertExpectations(t)
+	mockSessionService.AssertExpectations(t)
+}
+
+// Test Login - Two-Factor Required
+func TestAuthService_Login_TwoFactorRequired(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	twoFactorToken := "two_factor_token_123"
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("user@example.com:password123"))
+	user := &repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}
+
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
+	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(true, nil)
+	mockTokenService.On("GenerateTwoFactorToken", 123, models.LoginMethodPassword).Return(&twoFactorToken, nil)
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, twoFactorToken, result.TwoFactorToken)
+	assert.Empty(t, result.AccessToken)
+	assert.Empty(t, result.RefreshToken)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "UpdateUserLastLogin", mock.Anything)
+	mockLoginHistoryService.AssertNotCalled(t, "RecordLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	// Failures still count until the code is entered
+	mockRateLimitService.AssertNotCalled(t, "ClearFailedLogins", mock.Anything)
+}
+
+// Test LoginWithEmailLink - Two-Factor Required
+func TestAuthService_LoginWithEmailLink_TwoFactorRequired(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	loginToken := "login_token_123"
+	twoFactorToken := "two_factor_token_123"
+	claims := &TokenClaims{UserID: 123}
+
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeLoginWithEmail).Return(claims, nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(true, nil)
+	mockTokenService.On("GenerateTwoFactorToken", 123, models.LoginMethodEmailLink).Return(&twoFactorToken, nil)
+
+	// Act
+	result, err := authService.LoginWithEmailLink(&loginToken, testClient)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, twoFactorToken, result.TwoFactorToken)
+	mockTokenService.AssertExpectations(t)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+}
+
+// Test Login - Two-Factor Check Fails Closed
+func TestAuthService_Login_TwoFactorCheckError(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("user@example.com:password123"))
+	user := &repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}
+
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
+	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(false, errors.New("database error"))
+
+	// Act
+	result, err := authService.Login(&authHeader, testClient)
+
+	// Assert
+	assert.EqualError(t, err, "there was an issue trying to log this user in")
+	assert.Nil(t, result)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+}
+
+// Test CompleteTwoFactorLogin - Success
+func TestAuthService_CompleteTwoFactorLogin_Success(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	userId := 123
+	twoFactorToken := "two_factor_token_123"
+	claims := &TokenClaims{UserID: userId, LoginMethod: models.LoginMethodEmailLink}
+
+	mockTokenService.On("ValidateToken", &twoFactorToken, TokenPurposeTwoFactor).Return(claims, nil)
+	mockUserRepo.On("GetUserById", userId).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com"}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockTwoFactorService.On("VerifyCode", userId, "123456").Return(nil)
+	mockTokenService.On("ConsumeToken", claims).Return(nil)
+	mockRateLimitService.On("ClearFailedLogins", "user@example.com").Return()
+	mockSessionService.On("StartSession", userId, testClient).Return(&models.UserLoginResponseDTO{AccessToken: "access_token_123", RefreshToken: "refresh_token_123"}, nil)
+	mockUserRepo.On("UpdateUserLastLogin", &userId).Return(true, nil)
+	mockLoginHistoryService.On("RecordLogin", userId, models.LoginMethodEmailLink, models.LoginOutcomeSuccess, testClient).Return()
+
+	// Act
+	result, err := authService.CompleteTwoFactorLogin(&twoFactorToken, "123456", testClient)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token_123", result.AccessToken)
+	assert.Equal(t, "refresh_token_123", result.RefreshToken)
 	mockTokenService.AssertExpectations(t)
+	mockRateLimitService.AssertExpectations(t)
+	mockLoginHistoryService.AssertExpectations(t)
+}
+
+// Test CompleteTwoFactorLogin - Invalid Code
+func TestAuthService_CompleteTwoFactorLogin_InvalidCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	twoFactorToken := "two_factor_token_123"
+	claims := &TokenClaims{UserID: 123, LoginMethod: models.LoginMethodPassword}
+
+	mockTokenService.On("ValidateToken", &twoFactorToken, TokenPurposeTwoFactor).Return(claims, nil)
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com"}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockTwoFactorService.On("VerifyCode", 123, "000000").Return(ErrInvalidTwoFactorCode)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidTwoFactorCode, testClient).Return()
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+
+	// Act
+	result, err := authService.CompleteTwoFactorLogin(&twoFactorToken, "000000", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	assert.Nil(t, result)
+	mockRateLimitService.AssertExpectations(t)
+	mockLoginHistoryService.AssertExpectations(t)
+	// The token isn't used up, so the user can try another code
+	mockTokenService.AssertNotCalled(t, "ConsumeToken", mock.Anything)
+	mockSessionService.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything)
+}
+
+// Test CompleteTwoFactorLogin - Invalid Code Locks Account
+func TestAuthService_CompleteTwoFactorLogin_InvalidCodeLocksAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	twoFactorToken := "two_factor_token_123"
+	claims := &TokenClaims{UserID: 123, LoginMethod: models.LoginMethodPassword}
+	lockedErr := &RetryAfterError{Err: ErrAccountLocked, RetryAfter: time.Minute}
+
+	mockTokenService.On("ValidateToken", &twoFactorToken, TokenPurposeTwoFactor).Return(claims, nil)
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com"}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockTwoFactorService.On("VerifyCode", 123, "000000").Return(ErrInvalidTwoFactorCode)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidTwoFactorCode, testClient).Return()
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(lockedErr)
+
+	// Act
+	result, err := authService.CompleteTwoFactorLogin(&twoFactorToken, "000000", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.Nil(t, result)
+}
+
+// Test CompleteTwoFactorLogin - Correct Passwords Between Wrong Codes Don't Reset The Lockout
+func TestAuthService_CompleteTwoFactorLogin_PasswordLoginsDontResetLockout(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	rateLimitService := NewRateLimitService(NewMemoryRateLimitStore())
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, rateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	twoFactorToken := "two_factor_token_123"
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("user@example.com:password123"))
+	user := &repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}
+	claims := &TokenClaims{UserID: 123, LoginMethod: models.LoginMethodPassword}
+
+	mockUserRepo.On("GetUserByEmail", "user@example.com").Return(user, nil)
+	mockUserRepo.On("GetUserById", 123).Return(user, nil)
+	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
+	mockTwoFactorService.On("IsEnabled", 123).Return(true, nil)
+	mockTokenService.On("GenerateTwoFactorToken", 123, models.LoginMethodPassword).Return(&twoFactorToken, nil)
+	mockTokenService.On("ValidateToken", &twoFactorToken, TokenPurposeTwoFactor).Return(claims, nil)
+	mockTwoFactorService.On("VerifyCode", 123, "000000").Return(ErrInvalidTwoFactorCode)
+	mockLoginHistoryService.On("RecordLogin", 123, models.LoginMethodPassword, models.LoginOutcomeInvalidTwoFactorCode, testClient).Return()
+
+	// Act - log in with the right password before every guess
+	var err error
+	for i := 0; i < lockoutThreshold; i++ {
+		_, loginErr := authService.Login(&authHeader, testClient)
+		assert.NoError(t, loginErr)
+		_, err = authService.CompleteTwoFactorLogin(&twoFactorToken, "000000", testClient)
+	}
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	_, err = authService.Login(&authHeader, testClient)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+}
+
+// Test CompleteTwoFactorLogin - Wrong Token Purpose
+func TestAuthService_CompleteTwoFactorLogin_WrongTokenPurpose(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	loginToken := "login_token_123"
+	mockTokenService.On("ValidateToken", &loginToken, TokenPurposeTwoFactor).Return(nil, ErrWrongTokenPurpose)
+
+	// Act
+	result, err := authService.CompleteTwoFactorLogin(&loginToken, "123456", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTokenNotVerified)
+	assert.Nil(t, result)
+	mockTwoFactorService.AssertNotCalled(t, "VerifyCode", mock.Anything, mock.Anything)
+}
+
+// Test DisableTwoFactor - Success
+func TestAuthService_DisableTwoFactor_Success(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
+	mockTwoFactorService.On("VerifyCode", 123, "123456").Return(nil)
+	mockTwoFactorService.On("Disable", 123).Return(nil)
+
+	// Act
+	err := authService.DisableTwoFactor(123, "password123", "123456")
+
+	// Assert
+	assert.NoError(t, err)
+	mockTwoFactorService.AssertExpectations(t)
+}
+
+// Test DisableTwoFactor - Wrong Password
+func TestAuthService_DisableTwoFactor_WrongPassword(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockCryptoService.On("ValidatePassword", "wrong_password", hashedPassword).Return(false, errors.New("mismatched hash"))
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+
+	// Act
+	err := authService.DisableTwoFactor(123, "wrong_password", "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	mockRateLimitService.AssertCalled(t, "RecordFailedLogin", "user@example.com")
+	mockTwoFactorService.AssertNotCalled(t, "VerifyCode", mock.Anything, mock.Anything)
+	mockTwoFactorService.AssertNotCalled(t, "Disable", mock.Anything)
+}
+
+// Test DisableTwoFactor - Invalid Code
+func TestAuthService_DisableTwoFactor_InvalidCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockCryptoService.On("ValidatePassword", "password123", hashedPassword).Return(true, nil)
+	mockTwoFactorService.On("VerifyCode", 123, "000000").Return(ErrInvalidTwoFactorCode)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(nil)
+
+	// Act
+	err := authService.DisableTwoFactor(123, "password123", "000000")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	mockRateLimitService.AssertCalled(t, "RecordFailedLogin", "user@example.com")
+	mockTwoFactorService.AssertNotCalled(t, "Disable", mock.Anything)
+}
+
+// Test DisableTwoFactor - Locked Account
+func TestAuthService_DisableTwoFactor_AccountLocked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(&RetryAfterError{Err: ErrAccountLocked, RetryAfter: time.Minute})
+
+	// Act
+	err := authService.DisableTwoFactor(123, "password123", "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	mockCryptoService.AssertNotCalled(t, "ValidatePassword", mock.Anything, mock.Anything)
+	mockTwoFactorService.AssertNotCalled(t, "Disable", mock.Anything)
+}
+
+// Test DisableTwoFactor - Wrong Password Locks Account
+func TestAuthService_DisableTwoFactor_WrongPasswordLocksAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockCryptoService := new(MockCryptoService)
+	mockEmailService := new(MockEmailService)
+
+	mockConfigService := new(MockConfigService)
+	mockTeamRepo := new(MockTeamRepository)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
+
+	hashedPassword := "hashed_password_123"
+	mockUserRepo.On("GetUserById", 123).Return(&repositories.UserEntity{ID: 123, Email: "user@example.com", PasswordHash: &hashedPassword}, nil)
+	mockRateLimitService.On("CheckLockout", "user@example.com").Return(nil)
+	mockCryptoService.On("ValidatePassword", "wrong_password", hashedPassword).Return(false, nil)
+	mockRateLimitService.On("RecordFailedLogin", "user@example.com").Return(&RetryAfterError{Err: ErrAccountLocked, RetryAfter: time.Minute})
+
+	// Act
+	err := authService.DisableTwoFactor(123, "wrong_password", "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	mockTwoFactorService.AssertNotCalled(t, "Disable", mock.Anything)
 }
 
 // Test RegisterNewUser - User Creation Error
@@ -1080,13 +2189,17 @@ func TestAuthService_RegisterNewUser_UserCreationError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "test@example.com",
@@ -1118,13 +2231,17 @@ func TestAuthService_RegisterNewUser_VerificationTokenError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	userDTO := &models.UserCreateDTO{
 		Email:       "test@example.com",
@@ -1165,13 +2282,17 @@ func TestAuthService_SendLoginEmail_TokenGenerationError(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	email := "test@example.com"
 
@@ -1199,6 +2320,10 @@ func TestAuthService_SendLoginEmail_EmailSendFailure(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockTokenService := new(MockTokenService)
+	mockSessionService := new(MockSessionService)
+	mockRateLimitService := new(MockRateLimitService)
+	mockLoginHistoryService := new(MockLoginHistoryService)
+	mockTwoFactorService := new(MockTwoFactorService)
 	mockCryptoService := new(MockCryptoService)
 	mockEmailService := new(MockEmailService)
 	mockEmailTemplates := new(MockEmailTemplates)
@@ -1206,7 +2331,7 @@ func TestAuthService_SendLoginEmail_EmailSendFailure(t *testing.T) {
 	mockConfigService := new(MockConfigService)
 	mockTeamRepo := new(MockTeamRepository)
 	mockTeamRepo.On("CreateTeamsForUser", mock.Anything).Return(nil)
-	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockCryptoService, mockEmailService, mockConfigService)
+	authService := NewAuthService(mockUserRepo, mockTeamRepo, mockTokenService, mockSessionService, mockRateLimitService, mockLoginHistoryService, mockTwoFactorService, mockCryptoService, mockEmailService, mockConfigService)
 
 	email := "test@example.com"
 	loginToken := "login_token_123"
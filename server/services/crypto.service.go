@@ -1,7 +1,13 @@
 package services
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 
 	"golang.org/x/crypto/bcrypt"
@@ -12,15 +18,22 @@ var bcryptCost = 13
 type ICryptoService interface {
 	HashPassword(password string) (*string, error)
 	ValidatePassword(password string, hash string) (bool, error)
+	HashRecoveryCode(code string) string
+	EncryptSecret(secret string) (string, error)
+	DecryptSecret(encrypted string) (string, error)
 }
 
 type CryptoService struct {
 	pepper string
+	// secretKey encrypts secrets that have to be read back, like TOTP secrets
+	secretKey []byte
 }
 
 func NewCryptoService(pepper string) ICryptoService {
+	secretKey := sha256.Sum256([]byte("parallax-secret-key:" + pepper))
 	return &CryptoService{
-		pepper: pepper,
+		pepper:    pepper,
+		secretKey: secretKey[:],
 	}
 }
 
@@ -59,3 +72,56 @@ func (s *CryptoService) ValidatePassword(password string, hash string) (bool, er
 	}
 	return true, nil
 }
+
+// HashRecoveryCode hashes a two-factor recovery code. The codes are random enough that
+// bcrypt isn't needed, and a keyed hash lets a code be looked up by its hash.
+func (s *CryptoService) HashRecoveryCode(code string) string {
+	mac := hmac.New(sha256.New, []byte(s.pepper))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptSecret encrypts a secret with AES-GCM, for storing secrets the server needs
+// to read back
+func (s *CryptoService) EncryptSecret(secret string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	encrypted := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawStdEncoding.EncodeToString(encrypted), nil
+}
+
+func (s *CryptoService) DecryptSecret(encrypted string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s *CryptoService) secretCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
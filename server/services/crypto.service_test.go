@@ -120,3 +120,52 @@ func TestHashPassword_NineCharacters(t *testing.T) {
 		t.Fatalf("expected error '%s', got '%s'", expectedError, err.Error())
 	}
 }
+
+func TestHashRecoveryCode(t *testing.T) {
+	service := NewCryptoService("testPepper")
+
+	hash := service.HashRecoveryCode("abcde-12345")
+	if hash != service.HashRecoveryCode("abcde-12345") {
+		t.Fatalf("expected the same code to always hash the same")
+	}
+	if hash == service.HashRecoveryCode("abcde-12346") {
+		t.Fatalf("expected different codes to hash differently")
+	}
+
+	// The pepper keys the hash, so a leaked hash can't be checked without it
+	if hash == NewCryptoService("otherPepper").HashRecoveryCode("abcde-12345") {
+		t.Fatalf("expected the hash to depend on the pepper")
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	service := NewCryptoService("testPepper")
+
+	encrypted, err := service.EncryptSecret("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if encrypted == "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("expected the secret to be encrypted")
+	}
+
+	decrypted, err := service.DecryptSecret(encrypted)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decrypted != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("expected the decrypted secret to match, got '%s'", decrypted)
+	}
+}
+
+func TestDecryptSecret_WrongPepper(t *testing.T) {
+	encrypted, err := NewCryptoService("testPepper").EncryptSecret("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = NewCryptoService("otherPepper").DecryptSecret(encrypted)
+	if err == nil {
+		t.Fatalf("expected an error decrypting with a different pepper, got nil")
+	}
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"fmt"
+	"html"
 	"strings"
+	"time"
 )
 
 const (
@@ -14,6 +16,7 @@ type IEmailTemplates interface {
 	GetNewUserEmailTemplate(baseURL string, verificationToken string) string
 	GetLoginEmailTemplate(baseURL string, verificationToken string) string
 	GetPasswordResetEmailTemplate(baseURL string, verificationToken string) string
+	GetNewDeviceLoginEmailTemplate(baseURL string, userAgent string, ipAddress string, loginTime time.Time) string
 }
 
 type EmailTemplates struct {
@@ -122,6 +125,45 @@ func (e *EmailTemplates) GetPasswordResetEmailTemplate(baseURL string, verificat
 	return e.wrapEmail(sanitizedBaseURL, mainContent)
 }
 
+// GetNewDeviceLoginEmailTemplate tells the user their account was logged in to from a
+// device it hasn't been used on before. The user agent comes from the request, so it's
+// escaped.
+func (e *EmailTemplates) GetNewDeviceLoginEmailTemplate(baseURL string, userAgent string, ipAddress string, loginTime time.Time) string {
+	sanitizedBaseURL := e.normalizeBaseURL(baseURL)
+	accountURL := fmt.Sprintf("%s/account", sanitizedBaseURL)
+
+	if userAgent == "" {
+		userAgent = "Unknown device"
+	}
+	if ipAddress == "" {
+		ipAddress = "Unknown IP"
+	}
+
+	mainContent := fmt.Sprintf(`
+		<h1 style="margin:0 0 16px 0; font-size:28px; font-weight:700; color:#0f172a;">New login to your account</h1>
+		<p style="margin:0 0 24px 0; font-size:16px; line-height:1.6; color:#1f2937;">
+			Hello! Your Parallax account was just logged in to from a device we haven't seen before.
+		</p>
+		<table role="presentation" cellpadding="0" cellspacing="0" style="margin:0 0 24px 0; font-size:14px; line-height:1.6; color:#1f2937;">
+			<tr><td style="padding:0 16px 4px 0; font-weight:600;">Device</td><td style="padding:0 0 4px 0; word-break:break-all;">%s</td></tr>
+			<tr><td style="padding:0 16px 4px 0; font-weight:600;">IP address</td><td style="padding:0 0 4px 0;">%s</td></tr>
+			<tr><td style="padding:0 16px 0 0; font-weight:600;">Time</td><td>%s</td></tr>
+		</table>
+		<table role="presentation" cellpadding="0" cellspacing="0" style="margin:0 auto 24px auto;">
+			<tr>
+				<td align="center" style="border-radius:999px; background-color:#2563eb;">
+					<a href="%s" style="display:inline-block; padding:14px 32px; font-size:16px; font-weight:600; color:#ffffff; text-decoration:none; border-radius:999px;">Review Your Devices</a>
+				</td>
+			</tr>
+		</table>
+		<p style="margin:0; font-size:14px; line-height:1.6; color:#475569;">
+			If this was you, there's nothing to do. If it wasn't, log out that device from your account page and reset your password.
+		</p>
+	`, html.EscapeString(userAgent), html.EscapeString(ipAddress), loginTime.UTC().Format("2 Jan 2006 15:04 MST"), accountURL)
+
+	return e.wrapEmail(sanitizedBaseURL, mainContent)
+}
+
 func (e *EmailTemplates) wrapEmail(baseURL string, mainContent string) string {
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
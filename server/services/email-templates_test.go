@@ -2,6 +2,7 @@ package services
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -171,6 +172,38 @@ func TestEmailTemplates_GetPasswordResetEmailTemplate_Success(t *testing.T) {
 	assert.Contains(t, result, "Reset Password")
 }
 
+// Test GetNewDeviceLoginEmailTemplate - Success
+func TestEmailTemplates_GetNewDeviceLoginEmailTemplate_Success(t *testing.T) {
+	// Arrange
+	emailTemplates := NewEmailTemplates()
+	baseURL := "https://example.com"
+	loginTime := time.Date(2025, 12, 8, 14, 30, 0, 0, time.UTC)
+
+	// Act
+	result := emailTemplates.GetNewDeviceLoginEmailTemplate(baseURL, "Mozilla/5.0 (X11; Linux x86_64)", "203.0.113.7", loginTime)
+
+	// Assert
+	assert.Contains(t, result, "New login to your account")
+	assert.Contains(t, result, "Mozilla/5.0 (X11; Linux x86_64)")
+	assert.Contains(t, result, "203.0.113.7")
+	assert.Contains(t, result, "8 Dec 2025 14:30 UTC")
+	assert.Contains(t, result, "https://example.com/account")
+}
+
+// Test GetNewDeviceLoginEmailTemplate - User Agent Is Escaped
+func TestEmailTemplates_GetNewDeviceLoginEmailTemplate_EscapesUserAgent(t *testing.T) {
+	// Arrange
+	emailTemplates := NewEmailTemplates()
+
+	// Act
+	result := emailTemplates.GetNewDeviceLoginEmailTemplate("https://example.com", "<script>alert(1)</script>", "", time.Now())
+
+	// Assert
+	assert.NotContains(t, result, "<script>")
+	assert.Contains(t, result, "&lt;script&gt;")
+	assert.Contains(t, result, "Unknown IP")
+}
+
 // Test GetPasswordResetEmailTemplate - With Empty BaseURL
 func TestEmailTemplates_GetPasswordResetEmailTemplate_EmptyBaseURL(t *testing.T) {
 	// Arrange
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+const (
+	// EventHistorySize is how many recent events are kept per player for clients that
+	// reconnect with Last-Event-ID
+	EventHistorySize = 50
+
+	// EventHistoryRetention is how long a player with no open streams keeps their history
+	// after their last event
+	EventHistoryRetention = time.Hour
+
+	// EventSubscriptionBuffer is how many events a stream can fall behind by before it
+	// is dropped
+	EventSubscriptionBuffer = 16
+
+	// EventHeartbeatInterval is how often an idle stream sends a comment so proxies
+	// don't close it
+	EventHeartbeatInterval = 15 * time.Second
+
+	// eventHistoryPruneInterval is how often the hub forgets idle players' history
+	eventHistoryPruneInterval = 5 * time.Minute
+)
+
+// IEventHubService fans game events out to every open event stream of a player.
+// Events only reach streams in the same process, so events published by a standalone
+// expedition processor are not delivered.
+type IEventHubService interface {
+	// Publish sends an event to all of a player's streams and keeps it for replay
+	Publish(userId int64, eventType models.GameEventType, data any)
+
+	// Subscribe opens a stream for a player. Events after lastEventId that are still in
+	// the player's history are returned in Missed, pass 0 to skip the replay.
+	Subscribe(userId int64, lastEventId int64) *EventSubscription
+
+	// Unsubscribe closes a stream. It is safe to call more than once.
+	Unsubscribe(subscription *EventSubscription)
+
+	// AddListener calls listener with every event published for any player. Listeners
+	// run on the publisher's goroutine once the event has been sent to the streams, so
+	// they may publish events of their own.
+	AddListener(listener func(event *models.GameEvent))
+}
+
+// EventSubscription is one open event stream. Events is closed when the stream is
+// unsubscribed, or when it falls too far behind, in which case the client should
+// reconnect and replay what it missed.
+type EventSubscription struct {
+	UserID int64
+	Missed []*models.GameEvent
+	Events <-chan *models.GameEvent
+
+	events chan *models.GameEvent
+}
+
+// EventHubService keeps each player's recent events and open streams in memory. History
+// of players with no open streams is pruned every few minutes once it is old enough.
+type EventHubService struct {
+	mutex         sync.Mutex
+	now           func() time.Time
+	lastPrune     time.Time
+	lastEventId   int64
+	history       map[int64][]*models.GameEvent
+	subscriptions map[int64]map[*EventSubscription]struct{}
+	listeners     []func(event *models.GameEvent)
+}
+
+func NewEventHubService() IEventHubService {
+	return newEventHubService(time.Now)
+}
+
+func newEventHubService(now func() time.Time) *EventHubService {
+	return &EventHubService{
+		// Start from the clock so IDs keep increasing across restarts and a reconnecting
+		// client's Last-Event-ID never skips new events
+		lastEventId:   now().UnixMilli(),
+		now:           now,
+		lastPrune:     now(),
+		history:       make(map[int64][]*models.GameEvent),
+		subscriptions: make(map[int64]map[*EventSubscription]struct{}),
+	}
+}
+
+func (s *EventHubService) Publish(userId int64, eventType models.GameEventType, data any) {
+	event, listeners := s.send(userId, eventType, data)
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// send records an event and sends it to the player's streams. Returns the listeners to
+// call with it once the mutex is released.
+func (s *EventHubService) send(userId int64, eventType models.GameEventType, data any) (*models.GameEvent, []func(event *models.GameEvent)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.prune(now)
+
+	s.lastEventId++
+	event := &models.GameEvent{
+		ID:        s.lastEventId,
+		Type:      eventType,
+		UserID:    userId,
+		Data:      data,
+		CreatedAt: now.UTC(),
+	}
+
+	history := append(s.history[userId], event)
+	if len(history) > EventHistorySize {
+		history = history[len(history)-EventHistorySize:]
+	}
+	s.history[userId] = history
+
+	for subscription := range s.subscriptions[userId] {
+		select {
+		case subscription.events <- event:
+		default:
+			// Don't let one slow client hold up the publisher, it replays on reconnect
+			s.unsubscribe(subscription)
+		}
+	}
+
+	return event, s.listeners
+}
+
+func (s *EventHubService) Subscribe(userId int64, lastEventId int64) *EventSubscription {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	events := make(chan *models.GameEvent, EventSubscriptionBuffer)
+	subscription := &EventSubscription{
+		UserID: userId,
+		Missed: []*models.GameEvent{},
+		Events: events,
+		events: events,
+	}
+
+	if lastEventId > 0 {
+		for _, event := range s.history[userId] {
+			if event.ID > lastEventId {
+				subscription.Missed = append(subscription.Missed, event)
+			}
+		}
+	}
+
+	if s.subscriptions[userId] == nil {
+		s.subscriptions[userId] = make(map[*EventSubscription]struct{})
+	}
+	s.subscriptions[userId][subscription] = struct{}{}
+
+	return subscription
+}
+
+func (s *EventHubService) Unsubscribe(subscription *EventSubscription) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.unsubscribe(subscription)
+}
+
+func (s *EventHubService) AddListener(listener func(event *models.GameEvent)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// unsubscribe removes a stream and closes its channel.
+// Must be called with the mutex held.
+func (s *EventHubService) unsubscribe(subscription *EventSubscription) {
+	subscriptions := s.subscriptions[subscription.UserID]
+	if _, ok := subscriptions[subscription]; !ok {
+		return
+	}
+
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(s.subscriptions, subscription.UserID)
+	}
+	close(subscription.events)
+}
+
+// prune forgets the history of players with no open streams whose last event is older
+// than EventHistoryRetention. Must be called with the mutex held.
+func (s *EventHubService) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < eventHistoryPruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	for userId, history := range s.history {
+		if len(s.subscriptions[userId]) > 0 {
+			continue
+		}
+		if now.Sub(history[len(history)-1].CreatedAt) >= EventHistoryRetention {
+			delete(s.history, userId)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// receive returns the next event on a subscription without blocking
+func receive(t *testing.T, subscription *EventSubscription) *models.GameEvent {
+	t.Helper()
+	select {
+	case event, ok := <-subscription.Events:
+		if !ok {
+			t.Fatal("subscription was closed")
+		}
+		return event
+	default:
+		t.Fatal("no event was delivered")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, subscription *EventSubscription) {
+	t.Helper()
+	select {
+	case event := <-subscription.Events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestEventHubService_Publish_FansOutPerUser(t *testing.T) {
+	hub := NewEventHubService()
+	first := hub.Subscribe(1, 0)
+	second := hub.Subscribe(1, 0)
+	other := hub.Subscribe(2, 0)
+
+	data := &models.TeamUnlockedEventDTO{TeamID: 10, TeamNumber: 2}
+	hub.Publish(1, models.GameEventTeamUnlocked, data)
+
+	for _, subscription := range []*EventSubscription{first, second} {
+		event := receive(t, subscription)
+		if event.Type != models.GameEventTeamUnlocked || event.UserID != 1 || event.Data != data {
+			t.Errorf("received %+v, want the team unlocked event", event)
+		}
+	}
+	assertNoEvent(t, other)
+}
+
+func TestEventHubService_Publish_IdsIncrease(t *testing.T) {
+	hub := NewEventHubService()
+	subscription := hub.Subscribe(1, 0)
+
+	hub.Publish(1, models.GameEventNotification, nil)
+	hub.Publish(2, models.GameEventNotification, nil)
+	hub.Publish(1, models.GameEventNotification, nil)
+
+	first := receive(t, subscription)
+	second := receive(t, subscription)
+	if second.ID <= first.ID {
+		t.Errorf("event ids %d then %d, want them to increase", first.ID, second.ID)
+	}
+}
+
+func TestEventHubService_Subscribe_ReplaysMissedEvents(t *testing.T) {
+	hub := NewEventHubService()
+	first := hub.Subscribe(1, 0)
+	hub.Publish(1, models.GameEventNotification, "one")
+	seen := receive(t, first)
+	hub.Unsubscribe(first)
+
+	hub.Publish(1, models.GameEventNotification, "two")
+	hub.Publish(1, models.GameEventNotification, "three")
+	hub.Publish(2, models.GameEventNotification, "other player")
+
+	reconnected := hub.Subscribe(1, seen.ID)
+	if len(reconnected.Missed) != 2 || reconnected.Missed[0].Data != "two" || reconnected.Missed[1].Data != "three" {
+		t.Errorf("Missed = %v, want the two events after %d", reconnected.Missed, seen.ID)
+	}
+
+	fresh := hub.Subscribe(1, 0)
+	if len(fresh.Missed) != 0 {
+		t.Errorf("Missed without Last-Event-ID = %v, want none", fresh.Missed)
+	}
+}
+
+func TestEventHubService_Subscribe_HistoryIsCapped(t *testing.T) {
+	hub := NewEventHubService()
+	for i := range EventHistorySize + 10 {
+		hub.Publish(1, models.GameEventNotification, i)
+	}
+
+	subscription := hub.Subscribe(1, 1)
+	if len(subscription.Missed) != EventHistorySize {
+		t.Fatalf("replayed %d events, want the last %d", len(subscription.Missed), EventHistorySize)
+	}
+	if subscription.Missed[0].Data != 10 {
+		t.Errorf("oldest replayed event = %v, want 10", subscription.Missed[0].Data)
+	}
+}
+
+func TestEventHubService_Unsubscribe_ClosesStream(t *testing.T) {
+	hub := NewEventHubService()
+	subscription := hub.Subscribe(1, 0)
+
+	hub.Unsubscribe(subscription)
+	hub.Unsubscribe(subscription)
+	hub.Publish(1, models.GameEventNotification, nil)
+
+	if _, ok := <-subscription.Events; ok {
+		t.Error("Events should be closed after Unsubscribe()")
+	}
+}
+
+func TestEventHubService_Publish_DropsSlowSubscriber(t *testing.T) {
+	hub := NewEventHubService()
+	slow := hub.Subscribe(1, 0)
+
+	for range EventSubscriptionBuffer + 1 {
+		hub.Publish(1, models.GameEventNotification, nil)
+	}
+
+	received := 0
+	for range slow.Events {
+		received++
+	}
+	if received != EventSubscriptionBuffer {
+		t.Errorf("received %d events before the stream closed, want %d", received, EventSubscriptionBuffer)
+	}
+
+	// The dropped client reconnects and replays what it missed
+	reconnected := hub.Subscribe(1, 1)
+	if len(reconnected.Missed) != EventSubscriptionBuffer+1 {
+		t.Errorf("replayed %d events, want %d", len(reconnected.Missed), EventSubscriptionBuffer+1)
+	}
+}
+
+func TestEventHubService_AddListener_SeesEveryPlayer(t *testing.T) {
+	hub := NewEventHubService()
+	subscription := hub.Subscribe(1, 0)
+
+	// A listener that publishes its own event, the way notifications are made
+	heard := []int64{}
+	hub.AddListener(func(event *models.GameEvent) {
+		heard = append(heard, event.UserID)
+		if event.Type == models.GameEventTeamUnlocked {
+			hub.Publish(event.UserID, models.GameEventNotification, nil)
+		}
+	})
+
+	hub.Publish(1, models.GameEventTeamUnlocked, nil)
+	hub.Publish(2, models.GameEventRiftUnlocked, nil)
+
+	if len(heard) != 3 || heard[0] != 1 || heard[1] != 1 || heard[2] != 2 {
+		t.Errorf("listener heard events for users %v, want [1 1 2]", heard)
+	}
+	if event := receive(t, subscription); event.Type != models.GameEventTeamUnlocked {
+		t.Errorf("first event = %s, want %s", event.Type, models.GameEventTeamUnlocked)
+	}
+	if event := receive(t, subscription); event.Type != models.GameEventNotification {
+		t.Errorf("second event = %s, want %s", event.Type, models.GameEventNotification)
+	}
+}
+
+func TestEventHubService_Publish_PrunesIdleHistory(t *testing.T) {
+	now := time.Now()
+	hub := newEventHubService(func() time.Time { return now })
+
+	hub.Publish(1, models.GameEventNotification, "idle")
+	hub.Publish(2, models.GameEventNotification, "streaming")
+	subscription := hub.Subscribe(2, 0)
+	defer hub.Unsubscribe(subscription)
+
+	now = now.Add(EventHistoryRetention)
+	hub.Publish(3, models.GameEventNotification, "recent")
+
+	if _, ok := hub.history[1]; ok {
+		t.Error("history of a player with no streams and no recent events was kept")
+	}
+	if _, ok := hub.history[2]; !ok {
+		t.Error("history of a player with an open stream was pruned")
+	}
+	if _, ok := hub.history[3]; !ok {
+		t.Error("history of a player with a recent event was pruned")
+	}
+}
+
+func TestEventHubService_Publish_KeepsHistoryUntilRetentionPasses(t *testing.T) {
+	now := time.Now()
+	hub := newEventHubService(func() time.Time { return now })
+
+	hub.Publish(1, models.GameEventNotification, "first")
+	now = now.Add(EventHistoryRetention - time.Minute)
+	hub.Publish(2, models.GameEventNotification, "second")
+
+	// A client reconnecting within the retention still gets what it missed
+	subscription := hub.Subscribe(1, 1)
+	if len(subscription.Missed) != 1 {
+		t.Errorf("replayed %d events, want 1", len(subscription.Missed))
+	}
+}
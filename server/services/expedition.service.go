@@ -1,19 +1,55 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math"
 	"time"
 
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
 	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// EchoEncounterChance is the chance a completed expedition meets the echoes of another
+	// player's recent expedition to the same rift
+	EchoEncounterChance = 0.10
+	// EchoEncounterWindowHours is how recently the other player's expedition must have completed
+	EchoEncounterWindowHours = 24
+	// EchoEncounterLootBonus is the extra share of their loot both players get from an encounter
+	EchoEncounterLootBonus = 0.25
+)
+
+// Errors returned by ExpeditionService that callers can check with errors.Is
+var (
+	ErrTeamNotFound          = errors.New("team not found")
+	ErrTeamNotOwned          = errors.New("team does not belong to user")
+	ErrTeamLocked            = errors.New("team is not unlocked")
+	ErrTeamBusy              = errors.New("team is already on an expedition")
+	ErrRiftNotFound          = errors.New("rift not found")
+	ErrRiftLocked            = errors.New("rift is not unlocked")
+	ErrExpeditionNotFound    = errors.New("expedition not found")
+	ErrExpeditionNotOwned    = errors.New("expedition does not belong to user")
+	ErrRewardsAlreadyClaimed = errors.New("rewards already claimed")
+	ErrExpeditionNotComplete = errors.New("expedition not yet complete")
+	ErrExpeditionFinished    = errors.New("expedition has already finished")
+	ErrNoLootRollLog         = errors.New("expedition has no loot roll log")
 )
 
 type IExpeditionService interface {
 	StartExpedition(userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error)
+	StartExpeditionWithTx(tx *database.AppDataSource, userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error)
 	GetActiveExpeditions(userId int64) ([]*models.ExpeditionResponseDTO, error)
 	GetExpeditionHistory(userId int64, limit int) ([]*models.ExpeditionResponseDTO, error)
 	ClaimExpeditionRewards(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error)
+	RecallExpedition(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error)
+	ProcessDueExpeditions(limit int, skipIds []int64) (int, []int64, error)
+	ReplayExpeditionLoot(expeditionId int64) (*models.LootReplayDTO, error)
+	GetRiftLootTable(userId, riftId int64, teamId *int64) (*models.RiftLootTableDTO, error)
+	GetEchoEncounterCount(userId int64) (int, error)
 }
 
 type ExpeditionService struct {
@@ -24,7 +60,12 @@ type ExpeditionService struct {
 	inventoryRepository      repositories.IUserInventoryRepository
 	lootItemRepository       repositories.ILootItemRepository
 	lootDropTableRepository  repositories.ILootDropTableRepository
+	echoEncounterRepository  repositories.IEchoEncounterRepository
 	gameCoreService          IGameCoreService
+	unitOfWork               database.IUnitOfWork
+	riftService              IRiftService
+	randomService            IRandomService
+	eventHubService          IEventHubService
 }
 
 func NewExpeditionService(
@@ -35,7 +76,12 @@ func NewExpeditionService(
 	inventoryRepository repositories.IUserInventoryRepository,
 	lootItemRepository repositories.ILootItemRepository,
 	lootDropTableRepository repositories.ILootDropTableRepository,
+	echoEncounterRepository repositories.IEchoEncounterRepository,
 	gameCoreService IGameCoreService,
+	unitOfWork database.IUnitOfWork,
+	riftService IRiftService,
+	randomService IRandomService,
+	eventHubService IEventHubService,
 ) IExpeditionService {
 	return &ExpeditionService{
 		expeditionRepository:     expeditionRepository,
@@ -45,52 +91,88 @@ func NewExpeditionService(
 		inventoryRepository:      inventoryRepository,
 		lootItemRepository:       lootItemRepository,
 		lootDropTableRepository:  lootDropTableRepository,
+		echoEncounterRepository:  echoEncounterRepository,
 		gameCoreService:          gameCoreService,
+		unitOfWork:               unitOfWork,
+		riftService:              riftService,
+		randomService:            randomService,
+		eventHubService:          eventHubService,
 	}
 }
 
 func (s *ExpeditionService) StartExpedition(userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error) {
+	return s.startExpedition(s.expeditionRepository, userId, teamId, riftId)
+}
+
+// StartExpeditionWithTx starts an expedition inside the caller's transaction, so the
+// team's current expedition is checked against the transaction's own writes
+func (s *ExpeditionService) StartExpeditionWithTx(tx *database.AppDataSource, userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error) {
+	return s.startExpedition(s.expeditionRepository.WithTx(tx), userId, teamId, riftId)
+}
+
+func (s *ExpeditionService) startExpedition(expeditionRepository repositories.IExpeditionRepository, userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error) {
 	// Validate team belongs to user
 	team, err := s.teamRepository.GetTeamById(teamId)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
 		return nil, err
 	}
 	if team.UserID != userId {
-		return nil, fmt.Errorf("team does not belong to user")
+		return nil, ErrTeamNotOwned
 	}
 	if !team.IsUnlocked {
-		return nil, fmt.Errorf("team is not unlocked")
+		return nil, ErrTeamLocked
 	}
 
-	// Get rift details
+	// Get rift details (archived rifts are not returned)
 	rift, err := s.riftRepository.GetRiftById(riftId)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRiftNotFound
+		}
 		return nil, err
 	}
+	if rift.IsArchived {
+		return nil, ErrRiftNotFound
+	}
 
-	// Get equipped items for team
-	equippedItems := make(map[string]*repositories.LootItemEntity)
-	s.loadEquippedItems(team, equippedItems)
-
-	// Calculate team stats
-	totalStats := s.gameCoreService.CalculateTeamStats(team, equippedItems)
+	// Make sure the player has earned access to this rift
+	isUnlocked, err := s.riftService.IsRiftUnlockedForUser(userId, riftId)
+	if err != nil {
+		return nil, err
+	}
+	if !isUnlocked {
+		return nil, ErrRiftLocked
+	}
 
-	// Apply elemental bonus if relic matches rift weakness
-	var relicAffinity string
-	if relic, exists := equippedItems["relic"]; exists && relic != nil {
-		relicAffinity = relic.ElementalAffinity
-		bonus := s.gameCoreService.GetElementalBonus(relicAffinity, rift.WeakToElement)
-		if bonus > 0 {
-			totalStats.Power = int(float64(totalStats.Power) * (1.0 + bonus))
-		}
+	// A team can only be on one expedition at a time
+	activeExpedition, err := expeditionRepository.GetActiveExpeditionByTeamId(teamId)
+	if err != nil {
+		return nil, err
+	}
+	if activeExpedition != nil {
+		return nil, ErrTeamBusy
 	}
 
+	totalStats, _ := s.calculateLaunchStats(team, rift)
+
 	// Calculate actual expedition duration
 	duration := s.gameCoreService.CalculateExpeditionDuration(totalStats, rift.DurationMinutes)
 
+	// Snapshot power and luck at launch so later gear changes don't affect this expedition's loot
+	recommendedPower := s.gameCoreService.GetRecommendedPower(rift.Difficulty)
+
 	// Create expedition
-	expedition, err := s.expeditionRepository.CreateExpedition(userId, teamId, riftId, duration)
+	// The seed decides every loot roll, so storing it now lets the loot be replayed later
+	lootSeed := s.randomService.NewSeed()
+	expedition, err := expeditionRepository.CreateExpedition(userId, teamId, riftId, duration, totalStats.Power, recommendedPower, totalStats.Luck, lootSeed)
 	if err != nil {
+		// Lost a race with another launch for the same team
+		if errors.Is(err, repositories.ErrTeamHasActiveExpedition) {
+			return nil, ErrTeamBusy
+		}
 		return nil, err
 	}
 
@@ -126,7 +208,7 @@ func (s *ExpeditionService) GetActiveExpeditions(userId int64) ([]*models.Expedi
 }
 
 func (s *ExpeditionService) GetExpeditionHistory(userId int64, limit int) ([]*models.ExpeditionResponseDTO, error) {
-	expeditions, err := s.expeditionRepository.GetCompletedExpeditionsByUserId(userId, limit)
+	expeditions, err := s.expeditionRepository.GetFinishedExpeditionsByUserId(userId, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -156,27 +238,234 @@ func (s *ExpeditionService) GetExpeditionHistory(userId int64, limit int) ([]*mo
 }
 
 func (s *ExpeditionService) ClaimExpeditionRewards(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error) {
-	// Get expedition
-	expedition, err := s.expeditionRepository.GetExpeditionById(expeditionId)
+	var lootEntities []*repositories.ExpeditionLootEntity
+	var lockedRifts []*models.RiftResponseDTO
+	var echo *echoEncounter
+
+	// Lock the expedition, make sure its loot exists and mark it claimed in one transaction.
+	// A concurrent claim blocks on the row lock and then sees claimed = true.
+	err := s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		expeditionRepository := s.expeditionRepository.WithTx(tx)
+
+		expedition, err := expeditionRepository.GetExpeditionByIdForUpdate(expeditionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrExpeditionNotFound
+			}
+			return err
+		}
+		if expedition.UserID != userId {
+			return ErrExpeditionNotOwned
+		}
+
+		// Check if already claimed
+		if expedition.Claimed {
+			return ErrRewardsAlreadyClaimed
+		}
+
+		// Check if expedition is complete
+		completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
+		if time.Now().Before(completionTime) {
+			return ErrExpeditionNotComplete
+		}
+
+		// The background processor normally rolls loot before the player gets here,
+		// but process it now if the player beat the processor to it
+		if !expedition.Processed {
+			lockedRifts = s.getLockedRifts(userId)
+			echo, err = s.processExpedition(tx, expedition)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Reveal the loot that was rolled when the expedition was processed
+		lootEntities, err = s.expeditionLootRepository.WithTx(tx).GetLootByExpeditionId(expeditionId)
+		if err != nil {
+			return err
+		}
+
+		return expeditionRepository.MarkClaimed(expeditionId)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if expedition.UserID != userId {
-		return nil, fmt.Errorf("expedition does not belong to user")
+	s.publishEchoEncounter(echo)
+	s.publishUnlockedRifts(userId, lockedRifts)
+
+	return s.mapRewardsToDTO(expeditionId, lootEntities)
+}
+
+// RecallExpedition ends an active expedition early and frees its team. Teams recalled
+// late enough bring back a prorated share of the loot, which is granted immediately.
+func (s *ExpeditionService) RecallExpedition(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error) {
+	var lootEntities []*repositories.ExpeditionLootEntity
+	var lockedRifts []*models.RiftResponseDTO
+
+	err := s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		expeditionRepository := s.expeditionRepository.WithTx(tx)
+
+		// Lock the expedition so a recall can't race the processor or a claim
+		expedition, err := expeditionRepository.GetExpeditionByIdForUpdate(expeditionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrExpeditionNotFound
+			}
+			return err
+		}
+		if expedition.UserID != userId {
+			return ErrExpeditionNotOwned
+		}
+
+		// Finished expeditions should be claimed instead
+		completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
+		if expedition.Processed || expedition.Claimed || !time.Now().Before(completionTime) {
+			return ErrExpeditionFinished
+		}
+
+		progress := time.Since(expedition.StartTime).Minutes() / float64(expedition.DurationMinutes)
+		recallMultiplier := s.gameCoreService.CalculateRecallLootMultiplier(progress)
+		if recallMultiplier > 0 {
+			team, err := s.teamRepository.GetTeamById(expedition.TeamID)
+			if err != nil {
+				return err
+			}
+
+			rift, err := s.riftRepository.GetRiftById(expedition.RiftID)
+			if err != nil {
+				return err
+			}
+
+			// Recalls skip the partial failure roll, the team came home before anything could go wrong
+			outcome := s.gameCoreService.CalculatePowerOutcome(expedition.EffectivePower, expedition.RecommendedPower)
+			roller := s.newLootRoller(expedition)
+			lockedRifts = s.getLockedRifts(userId)
+			err = s.awardLoot(tx, expedition, team, rift, roller, outcome.LootMultiplier*recallMultiplier)
+			if err != nil {
+				return err
+			}
+		}
+
+		lootEntities, err = s.expeditionLootRepository.WithTx(tx).GetLootByExpeditionId(expeditionId)
+		if err != nil {
+			return err
+		}
+
+		return expeditionRepository.MarkRecalled(expeditionId)
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.publishUnlockedRifts(userId, lockedRifts)
 
-	// Check if already claimed
-	if expedition.Claimed {
-		return nil, fmt.Errorf("rewards already claimed")
+	return s.mapRewardsToDTO(expeditionId, lootEntities)
+}
+
+// ProcessDueExpeditions rolls loot for up to limit expeditions whose timers have run out,
+// leaving out skipIds. Each expedition is processed in its own transaction so a failure
+// only affects that one. Returns the number of expeditions that were processed
+// successfully and the ids of the ones that failed.
+func (s *ExpeditionService) ProcessDueExpeditions(limit int, skipIds []int64) (int, []int64, error) {
+	expeditionIds, err := s.expeditionRepository.GetDueExpeditionIds(limit, skipIds)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	// Check if expedition is complete
-	completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
-	if time.Now().Before(completionTime) {
-		return nil, fmt.Errorf("expedition not yet complete")
+	processed := 0
+	var failedIds []int64
+	for _, expeditionId := range expeditionIds {
+		var expedition *repositories.ExpeditionEntity
+		var lockedRifts []*models.RiftResponseDTO
+		var echo *echoEncounter
+		err := s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+			// Another replica (or the player claiming) may already hold this expedition
+			due, err := s.expeditionRepository.WithTx(tx).LockDueExpeditionById(expeditionId)
+			if err != nil || due == nil {
+				return err
+			}
+			expedition = due
+			lockedRifts = s.getLockedRifts(expedition.UserID)
+			echo, err = s.processExpedition(tx, expedition)
+			return err
+		})
+		if err != nil {
+			// Keep going so one bad expedition doesn't stall the rest of the batch, and send
+			// it to the back of the queue so it doesn't stall the next batch either
+			util.LogError(fmt.Errorf("failed to process expedition %d: %w", expeditionId, err))
+			failedIds = append(failedIds, expeditionId)
+			if err := s.expeditionRepository.MarkProcessFailed(expeditionId); err != nil {
+				util.LogError(fmt.Errorf("failed to record processing failure for expedition %d: %w", expeditionId, err))
+			}
+			continue
+		}
+		if expedition != nil {
+			processed++
+
+			// Only tell the player once the loot is committed and ready to claim
+			s.eventHubService.Publish(expedition.UserID, models.GameEventExpeditionCompleted, &models.ExpeditionCompletedEventDTO{
+				ExpeditionID: expedition.ID,
+				TeamID:       expedition.TeamID,
+				RiftID:       expedition.RiftID,
+			})
+			s.publishEchoEncounter(echo)
+			s.publishUnlockedRifts(expedition.UserID, lockedRifts)
+		}
+	}
+
+	return processed, failedIds, nil
+}
+
+// getLockedRifts returns the rifts the player has yet to unlock, read before loot is
+// awarded so publishUnlockedRifts can tell which ones the loot unlocked. Returns nil if
+// they can't be read, which only skips the rift unlocked events.
+func (s *ExpeditionService) getLockedRifts(userId int64) []*models.RiftResponseDTO {
+	rifts, err := s.riftService.GetAllRifts(userId)
+	if err != nil {
+		util.LogError(fmt.Errorf("failed to get rifts for user %d: %w", userId, err))
+		return nil
 	}
 
-	// Get team and rift for loot generation
+	var lockedRifts []*models.RiftResponseDTO
+	for _, rift := range rifts {
+		if !rift.IsUnlocked {
+			lockedRifts = append(lockedRifts, rift)
+		}
+	}
+	return lockedRifts
+}
+
+// publishUnlockedRifts re-checks the locked rifts read by getLockedRifts and tells the
+// player about each one they have since unlocked. Must be called after the loot has
+// been committed.
+func (s *ExpeditionService) publishUnlockedRifts(userId int64, lockedRifts []*models.RiftResponseDTO) {
+	if len(lockedRifts) == 0 {
+		return
+	}
+
+	riftIds := make([]int64, len(lockedRifts))
+	for i, rift := range lockedRifts {
+		riftIds[i] = rift.ID
+	}
+	unlocked, err := s.riftService.GetUnlockedRiftIds(userId, riftIds)
+	if err != nil {
+		util.LogError(fmt.Errorf("failed to get rifts for user %d: %w", userId, err))
+		return
+	}
+	for _, rift := range lockedRifts {
+		if unlocked[rift.ID] {
+			s.eventHubService.Publish(userId, models.GameEventRiftUnlocked, &models.RiftUnlockedEventDTO{
+				RiftID:   rift.ID,
+				RiftName: rift.Name,
+			})
+		}
+	}
+}
+
+// processExpedition rolls loot for a locked expedition, writes it to the player's
+// inventory and the expedition_loot audit table, checks for an echo encounter and marks
+// the expedition processed. Returns the echo encounter, if there was one.
+// Must be called inside a transaction so all of it is written or none of it is.
+func (s *ExpeditionService) processExpedition(tx *database.AppDataSource, expedition *repositories.ExpeditionEntity) (*echoEncounter, error) {
 	team, err := s.teamRepository.GetTeamById(expedition.TeamID)
 	if err != nil {
 		return nil, err
@@ -187,75 +476,429 @@ func (s *ExpeditionService) ClaimExpeditionRewards(userId, expeditionId int64) (
 		return nil, err
 	}
 
-	// Generate loot
-	loot, err := s.generateLoot(expedition, team, rift)
+	// Weaker teams bring back less and may partially fail, stronger teams bring back more
+	outcome := s.gameCoreService.CalculatePowerOutcome(expedition.EffectivePower, expedition.RecommendedPower)
+	lootMultiplier := outcome.LootMultiplier
+	roller := s.newLootRoller(expedition)
+	partialFailure := roller.rollFailure(outcome.FailureChance)
+	if partialFailure {
+		lootMultiplier *= PartialFailureLootMultiplier
+	}
+
+	err = s.awardLoot(tx, expedition, team, rift, roller, lootMultiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	echo, err := s.checkEchoEncounter(tx, expedition, team, rift, lootMultiplier)
 	if err != nil {
 		return nil, err
 	}
 
-	// Mark expedition as claimed
-	err = s.expeditionRepository.MarkClaimed(expeditionId)
+	expeditionRepository := s.expeditionRepository.WithTx(tx)
+	err = expeditionRepository.MarkCompleted(expedition.ID)
 	if err != nil {
 		return nil, err
 	}
+	err = expeditionRepository.MarkProcessed(expedition.ID, partialFailure)
+	if err != nil {
+		return nil, err
+	}
+	return echo, nil
+}
+
+// awardLoot rolls loot for an expedition and writes it to the player's inventory and
+// the expedition_loot audit table, along with the roll log and the inputs needed to
+// replay it. Must be called inside a transaction.
+func (s *ExpeditionService) awardLoot(
+	tx *database.AppDataSource,
+	expedition *repositories.ExpeditionEntity,
+	team *repositories.TeamEntity,
+	rift *repositories.RiftEntity,
+	roller *lootRoller,
+	lootMultiplier float64,
+) error {
+	dropTables, err := s.lootDropTableRepository.GetDropTablesByRiftId(rift.ID)
+	if err != nil {
+		return err
+	}
 
-	// Map loot to response DTOs
-	lootDTOs := make([]models.LootItemResponseDTO, len(loot))
-	for i, item := range loot {
-		lootDTOs[i] = models.LootItemResponseDTO{
-			ID:                item.ID,
-			Name:              item.Name,
-			Description:       item.Description,
-			Rarity:            item.Rarity,
-			WorldType:         item.WorldType,
-			ItemType:          item.ItemType,
-			EquipmentSlot:     item.EquipmentSlot,
-			SpeedBonus:        item.SpeedBonus,
-			LuckBonus:         item.LuckBonus,
-			PowerBonus:        item.PowerBonus,
-			ElementalAffinity: item.ElementalAffinity,
-			PowerValue:        item.PowerValue,
-			Icon:              item.Icon,
+	luck := s.lootLuck(expedition, team, rift)
+	loot := s.generateLoot(roller, rift, dropTables, luck, lootMultiplier)
+
+	err = s.grantLoot(tx, expedition, loot)
+	if err != nil {
+		return err
+	}
+
+	err = s.expeditionLootRepository.WithTx(tx).CreateLootRolls(expedition.ID, roller.rolls)
+	if err != nil {
+		return err
+	}
+	return s.expeditionRepository.WithTx(tx).SaveLootRollInputs(expedition.ID, luck, lootMultiplier)
+}
+
+// grantLoot adds loot to the expedition's player's inventory and to the expedition_loot
+// audit table. Must be called inside a transaction.
+func (s *ExpeditionService) grantLoot(tx *database.AppDataSource, expedition *repositories.ExpeditionEntity, loot []*repositories.LootItemEntity) error {
+	inventoryRepository := s.inventoryRepository.WithTx(tx)
+	expeditionLootRepository := s.expeditionLootRepository.WithTx(tx)
+	for _, item := range loot {
+		// Add to user inventory
+		_, err := inventoryRepository.AddLoot(expedition.UserID, item.ID, item.ItemType)
+		if err != nil {
+			return err
+		}
+
+		// Create expedition_loot audit record
+		err = expeditionLootRepository.CreateExpeditionLoot(expedition.ID, item.ID, 1)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return &models.ExpeditionRewardsDTO{
-		ExpeditionID: expeditionId,
-		Loot:         lootDTOs,
+// echoEncounter is an encounter found while processing an expedition, along with how
+// many bonus items each side got. It is published once the transaction commits.
+type echoEncounter struct {
+	encounter      *repositories.EchoEncounterEntity
+	riftName       string
+	bonusItems     int
+	echoBonusItems int
+}
+
+// checkEchoEncounter rolls for the expedition's team meeting the echoes of another
+// player's recent expedition to the same rift. When they do, both players get bonus
+// loot worth EchoEncounterLootBonus of what their own expedition brought back. Returns
+// nil if there was no encounter. Must be called inside a transaction.
+func (s *ExpeditionService) checkEchoEncounter(
+	tx *database.AppDataSource,
+	expedition *repositories.ExpeditionEntity,
+	team *repositories.TeamEntity,
+	rift *repositories.RiftEntity,
+	lootMultiplier float64,
+) (*echoEncounter, error) {
+	// Echoes draw from their own random source so they never change the expedition's
+	// logged loot rolls, which have to replay exactly
+	roller := &lootRoller{
+		random: s.randomService.NewRandom(s.randomService.NewSeed()),
+	}
+	if roller.random.Float64() >= EchoEncounterChance {
+		return nil, nil
+	}
+
+	echoEncounterRepository := s.echoEncounterRepository.WithTx(tx)
+	echoExpedition, err := echoEncounterRepository.GetEchoExpedition(expedition.UserID, rift.ID, EchoEncounterWindowHours)
+	if err != nil || echoExpedition == nil {
+		return nil, err
+	}
+
+	dropTables, err := s.lootDropTableRepository.GetDropTablesByRiftId(rift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	bonusLoot := s.generateLoot(roller, rift, dropTables, s.lootLuck(expedition, team, rift), lootMultiplier*EchoEncounterLootBonus)
+	err = s.grantLoot(tx, expedition, bonusLoot)
+	if err != nil {
+		return nil, err
+	}
+
+	encounter, err := echoEncounterRepository.CreateEchoEncounter(expedition.ID, echoExpedition.ID, expedition.UserID, echoExpedition.UserID, rift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The other player's bonus is rolled with the luck and multiplier their own loot was
+	// rolled with, so it is in proportion to what they brought back. Their expedition has
+	// usually been claimed already, so the bonus is recorded against the encounter
+	// rather than added to that expedition's loot.
+	echoLuck, echoMultiplier := 0.0, 1.0
+	if echoExpedition.LootLuck != nil {
+		echoLuck = *echoExpedition.LootLuck
+	}
+	if echoExpedition.LootMultiplier != nil {
+		echoMultiplier = *echoExpedition.LootMultiplier
+	}
+	echoBonusLoot := s.generateLoot(roller, rift, dropTables, echoLuck, echoMultiplier*EchoEncounterLootBonus)
+	inventoryRepository := s.inventoryRepository.WithTx(tx)
+	for _, item := range echoBonusLoot {
+		_, err = inventoryRepository.AddLoot(echoExpedition.UserID, item.ID, item.ItemType)
+		if err != nil {
+			return nil, err
+		}
+		err = echoEncounterRepository.CreateEchoEncounterLoot(encounter.ID, echoExpedition.UserID, item.ID, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &echoEncounter{
+		encounter:      encounter,
+		riftName:       rift.Name,
+		bonusItems:     len(bonusLoot),
+		echoBonusItems: len(echoBonusLoot),
+	}, nil
+}
+
+// publishEchoEncounter tells both players about an echo encounter. Must be called after
+// the bonus loot has been committed.
+func (s *ExpeditionService) publishEchoEncounter(echo *echoEncounter) {
+	if echo == nil {
+		return
+	}
+
+	encounter := echo.encounter
+	s.eventHubService.Publish(encounter.UserID1, models.GameEventEchoEncounter, &models.EchoEncounterEventDTO{
+		EncounterID:  encounter.ID,
+		ExpeditionID: encounter.ExpeditionID1,
+		RiftID:       encounter.RiftID,
+		RiftName:     echo.riftName,
+		OtherUserID:  encounter.UserID2,
+		BonusItems:   echo.bonusItems,
+	})
+	s.eventHubService.Publish(encounter.UserID2, models.GameEventEchoEncounter, &models.EchoEncounterEventDTO{
+		EncounterID:  encounter.ID,
+		ExpeditionID: encounter.ExpeditionID2,
+		RiftID:       encounter.RiftID,
+		RiftName:     echo.riftName,
+		OtherUserID:  encounter.UserID1,
+		BonusItems:   echo.echoBonusItems,
+	})
+}
+
+// GetEchoEncounterCount returns how many echo encounters the player has been part of
+func (s *ExpeditionService) GetEchoEncounterCount(userId int64) (int, error) {
+	return s.echoEncounterRepository.GetEchoEncounterCount(userId)
+}
+
+// ReplayExpeditionLoot re-runs an expedition's loot rolls from its stored seed and inputs
+// and compares them with the rolls that were logged when its loot was awarded
+func (s *ExpeditionService) ReplayExpeditionLoot(expeditionId int64) (*models.LootReplayDTO, error) {
+	expedition, err := s.expeditionRepository.GetExpeditionById(expeditionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExpeditionNotFound
+		}
+		return nil, err
+	}
+	if expedition.LootLuck == nil || expedition.LootMultiplier == nil {
+		return nil, ErrNoLootRollLog
+	}
+
+	recorded, err := s.expeditionLootRepository.GetLootRollsByExpeditionId(expeditionId)
+	if err != nil {
+		return nil, err
+	}
+
+	rift, err := s.riftRepository.GetRiftById(expedition.RiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	dropTables, err := s.lootDropTableRepository.GetDropTablesByRiftId(rift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recalled expeditions never rolled for partial failure, so only completed ones
+	// draw that roll first
+	roller := s.newLootRoller(expedition)
+	if expedition.Status != string(models.ExpeditionStatusRecalled) {
+		outcome := s.gameCoreService.CalculatePowerOutcome(expedition.EffectivePower, expedition.RecommendedPower)
+		roller.rollFailure(outcome.FailureChance)
+	}
+
+	s.generateLoot(roller, rift, dropTables, *expedition.LootLuck, *expedition.LootMultiplier)
+
+	return &models.LootReplayDTO{
+		ExpeditionID:   expedition.ID,
+		Seed:           expedition.LootSeed,
+		Luck:           *expedition.LootLuck,
+		LootMultiplier: *expedition.LootMultiplier,
+		Matches:        lootRollsMatch(recorded, roller.rolls),
+		Recorded:       mapLootRollsToDTOs(recorded),
+		Replayed:       mapLootRollsToDTOs(roller.rolls),
 	}, nil
 }
 
-// generateLoot creates loot based on drop tables and team stats
-func (s *ExpeditionService) generateLoot(expedition *repositories.ExpeditionEntity, team *repositories.TeamEntity, rift *repositories.RiftEntity) ([]*repositories.LootItemEntity, error) {
-	// Get drop tables for this rift
+// GetRiftLootTable previews each rarity a rift can drop and the items in its world,
+// marking the ones the player has already collected. When a team is given the drop
+// chances and quantities are adjusted for the stats that team would launch with.
+func (s *ExpeditionService) GetRiftLootTable(userId, riftId int64, teamId *int64) (*models.RiftLootTableDTO, error) {
+	rift, err := s.riftRepository.GetRiftById(riftId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRiftNotFound
+		}
+		return nil, err
+	}
+	if rift.IsArchived {
+		return nil, ErrRiftNotFound
+	}
+
 	dropTables, err := s.lootDropTableRepository.GetDropTablesByRiftId(rift.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate team luck
+	dto := &models.RiftLootTableDTO{
+		RiftID:           rift.ID,
+		RiftName:         rift.Name,
+		WorldType:        rift.WorldType,
+		WeakToElement:    rift.WeakToElement,
+		TeamID:           teamId,
+		RecommendedPower: s.gameCoreService.GetRecommendedPower(rift.Difficulty),
+		LootMultiplier:   1.0,
+	}
+
+	adjustedTables := dropTables
+	if teamId != nil {
+		team, err := s.teamRepository.GetTeamById(*teamId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrTeamNotFound
+			}
+			return nil, err
+		}
+		if team.UserID != userId {
+			return nil, ErrTeamNotOwned
+		}
+
+		totalStats, elementalBonus := s.calculateLaunchStats(team, rift)
+		outcome := s.gameCoreService.CalculatePowerOutcome(totalStats.Power, dto.RecommendedPower)
+		adjustedTables = s.gameCoreService.AdjustDropRates(dropTables, totalStats.Luck)
+
+		dto.TeamLuck = totalStats.Luck
+		dto.EffectivePower = totalStats.Power
+		dto.ElementalBonus = elementalBonus
+		dto.LootMultiplier = outcome.LootMultiplier
+		dto.FailureChance = outcome.FailureChance
+	}
+
+	droppedRarities := make(map[string]bool, len(dropTables))
+	dto.Rarities = make([]*models.LootTableRarityDTO, len(dropTables))
+	for i, dropTable := range dropTables {
+		minQuantity, maxQuantity := adjustQuantityRange(dropTable.MinQuantity, dropTable.MaxQuantity, dto.LootMultiplier, dto.FailureChance)
+		dto.Rarities[i] = &models.LootTableRarityDTO{
+			Rarity:              dropTable.Rarity,
+			BaseDropRatePercent: dropTable.DropRatePercent,
+			DropRatePercent:     adjustedTables[i].DropRatePercent,
+			MinQuantity:         dropTable.MinQuantity,
+			MaxQuantity:         dropTable.MaxQuantity,
+			AdjustedMinQuantity: minQuantity,
+			AdjustedMaxQuantity: maxQuantity,
+		}
+		droppedRarities[dropTable.Rarity] = true
+	}
+
+	items, err := s.lootItemRepository.GetLootItemsByWorldType(rift.WorldType)
+	if err != nil {
+		return nil, err
+	}
+
+	collectedIds, err := s.inventoryRepository.GetCollectedLootItemIds(userId)
+	if err != nil {
+		return nil, err
+	}
+	collected := make(map[int64]bool, len(collectedIds))
+	for _, id := range collectedIds {
+		collected[id] = true
+	}
+
+	// Only list items from rarities this rift actually drops
+	dto.Items = make([]*models.LootTableItemDTO, 0, len(items))
+	for _, item := range items {
+		if !droppedRarities[item.Rarity] {
+			continue
+		}
+		dto.Items = append(dto.Items, &models.LootTableItemDTO{
+			LootItem:  s.mapLootItemDTO(item),
+			Collected: collected[item.ID],
+		})
+		if collected[item.ID] {
+			dto.CollectedCount++
+		}
+	}
+
+	return dto, nil
+}
+
+// calculateLaunchStats returns the stats a team launches into a rift with: its base stats
+// and equipment, the specialization bonus for the rift's world and the elemental power
+// bonus when its relic matches the rift's weakness. The elemental bonus is also returned.
+func (s *ExpeditionService) calculateLaunchStats(team *repositories.TeamEntity, rift *repositories.RiftEntity) (*models.TeamStatsDTO, float64) {
 	equippedItems := make(map[string]*repositories.LootItemEntity)
 	s.loadEquippedItems(team, equippedItems)
+
 	totalStats := s.gameCoreService.CalculateTeamStats(team, equippedItems)
-	// TODO: Apply luck modifier to shift drop rates in future enhancement
-	_ = totalStats.Luck
+	totalStats = s.gameCoreService.ApplySpecialization(totalStats, team.Specialization, rift.WorldType)
+
+	elementalBonus := 0.0
+	if relic, exists := equippedItems["relic"]; exists && relic != nil {
+		elementalBonus = s.gameCoreService.GetElementalBonus(relic.ElementalAffinity, rift.WeakToElement)
+		if elementalBonus > 0 {
+			totalStats.Power = int(float64(totalStats.Power) * (1.0 + elementalBonus))
+		}
+	}
+
+	return totalStats, elementalBonus
+}
+
+// adjustQuantityRange returns the fewest and most items a rarity can drop once the loot
+// multiplier is applied. A chance of partial failure lowers the minimum further.
+func adjustQuantityRange(minQuantity, maxQuantity int, lootMultiplier float64, failureChance float64) (int, int) {
+	minMultiplier := lootMultiplier
+	if failureChance > 0 {
+		minMultiplier *= PartialFailureLootMultiplier
+	}
+	return int(math.Floor(float64(minQuantity) * minMultiplier)), int(math.Ceil(float64(maxQuantity) * lootMultiplier))
+}
+
+// lootLuck returns the luck the expedition's team launched with. Expeditions launched
+// before luck was stored at launch fall back to the team's current luck.
+func (s *ExpeditionService) lootLuck(expedition *repositories.ExpeditionEntity, team *repositories.TeamEntity, rift *repositories.RiftEntity) float64 {
+	if expedition.LootLuck != nil {
+		return *expedition.LootLuck
+	}
+	return s.calculateLootLuck(team, rift)
+}
+
+// calculateLootLuck returns the team luck used to adjust the rift's drop rates,
+// including the specialization bonus for the rift's world
+func (s *ExpeditionService) calculateLootLuck(team *repositories.TeamEntity, rift *repositories.RiftEntity) float64 {
+	equippedItems := make(map[string]*repositories.LootItemEntity)
+	s.loadEquippedItems(team, equippedItems)
+	totalStats := s.gameCoreService.CalculateTeamStats(team, equippedItems)
+	totalStats = s.gameCoreService.ApplySpecialization(totalStats, team.Specialization, rift.WorldType)
+	return totalStats.Luck
+}
+
+// generateLoot rolls loot from the rift's drop tables and team luck. It does not write anything.
+// The quantity rolled for each rarity is scaled by lootMultiplier. Every roll is drawn
+// from roller, so the same seed and inputs always give the same loot.
+func (s *ExpeditionService) generateLoot(
+	roller *lootRoller,
+	rift *repositories.RiftEntity,
+	dropTables []*repositories.LootDropTableEntity,
+	luck float64,
+	lootMultiplier float64,
+) []*repositories.LootItemEntity {
+	// Shift drop rates toward higher rarities based on team luck
+	dropTables = s.gameCoreService.AdjustDropRates(dropTables, luck)
 
 	var generatedLoot []*repositories.LootItemEntity
 
 	// Roll for each rarity tier
 	for _, dropTable := range dropTables {
-		// Base drop rate (luck modifier could be applied here)
-		adjustedRate := dropTable.DropRatePercent
-
-		// Roll 0-100
-		roll := rand.Float64() * 100.0
-
-		if roll < adjustedRate {
+		if roller.rollRarity(dropTable.Rarity, dropTable.DropRatePercent) {
 			// This rarity drops!
 			quantity := dropTable.MinQuantity
 			if dropTable.MaxQuantity > dropTable.MinQuantity {
-				quantity = dropTable.MinQuantity + rand.Intn(dropTable.MaxQuantity-dropTable.MinQuantity+1)
+				quantity = dropTable.MinQuantity + roller.random.Intn(dropTable.MaxQuantity-dropTable.MinQuantity+1)
 			}
+			quantity = scaleQuantity(roller.random, quantity, lootMultiplier)
 
 			// Roll specific items for this rarity
 			for i := 0; i < quantity; i++ {
@@ -265,25 +908,107 @@ func (s *ExpeditionService) generateLoot(expedition *repositories.ExpeditionEnti
 				}
 
 				// Pick random item from this rarity/world
-				item := items[rand.Intn(len(items))]
+				item := roller.pickItem(dropTable.Rarity, items)
 				generatedLoot = append(generatedLoot, item)
+			}
+		}
+	}
 
-				// Add to user inventory
-				_, err = s.inventoryRepository.AddLoot(expedition.UserID, item.ID, item.ItemType)
-				if err != nil {
-					return nil, err
-				}
+	return generatedLoot
+}
 
-				// Create expedition_loot audit record
-				err = s.expeditionLootRepository.CreateExpeditionLoot(expedition.ID, item.ID, 1)
-				if err != nil {
-					return nil, err
-				}
-			}
+// scaleQuantity multiplies a rolled quantity, rounding the fractional part up or down
+// at random so that e.g. 1 x 0.5 gives one item half of the time
+func scaleQuantity(random IRandom, quantity int, multiplier float64) int {
+	scaled := float64(quantity) * multiplier
+	whole := int(scaled)
+	if random.Float64() < scaled-float64(whole) {
+		whole++
+	}
+	return whole
+}
+
+func (s *ExpeditionService) newLootRoller(expedition *repositories.ExpeditionEntity) *lootRoller {
+	return &lootRoller{
+		random: s.randomService.NewRandom(expedition.LootSeed),
+	}
+}
+
+// lootRoller draws an expedition's loot rolls from its seeded random source and logs
+// the rolls that decide what drops
+type lootRoller struct {
+	random IRandom
+	rolls  []*repositories.ExpeditionLootRollEntity
+}
+
+// rollFailure rolls 0-1 against the expedition's partial failure chance
+func (r *lootRoller) rollFailure(failureChance float64) bool {
+	roll := r.random.Float64()
+	r.record(models.LootRollTypeFailure, nil, roll, &failureChance, nil)
+	return roll < failureChance
+}
+
+// rollRarity rolls 0-100 against a rarity's drop rate
+func (r *lootRoller) rollRarity(rarity string, dropRatePercent float64) bool {
+	roll := r.random.Float64() * 100.0
+	r.record(models.LootRollTypeRarity, &rarity, roll, &dropRatePercent, nil)
+	return roll < dropRatePercent
+}
+
+// pickItem picks one item from a rarity's item pool
+func (r *lootRoller) pickItem(rarity string, items []*repositories.LootItemEntity) *repositories.LootItemEntity {
+	index := r.random.Intn(len(items))
+	item := items[index]
+	r.record(models.LootRollTypeItem, &rarity, float64(index), nil, &item.ID)
+	return item
+}
+
+func (r *lootRoller) record(rollType models.LootRollType, rarity *string, roll float64, threshold *float64, lootItemId *int64) {
+	r.rolls = append(r.rolls, &repositories.ExpeditionLootRollEntity{
+		RollOrder:  len(r.rolls) + 1,
+		RollType:   string(rollType),
+		Rarity:     rarity,
+		Roll:       roll,
+		Threshold:  threshold,
+		LootItemID: lootItemId,
+	})
+}
+
+// lootRollsMatch reports whether two roll logs made exactly the same rolls
+func lootRollsMatch(recorded, replayed []*repositories.ExpeditionLootRollEntity) bool {
+	if len(recorded) != len(replayed) {
+		return false
+	}
+	for i := range recorded {
+		a, b := recorded[i], replayed[i]
+		if a.RollOrder != b.RollOrder || a.RollType != b.RollType || a.Roll != b.Roll ||
+			!equalPtr(a.Rarity, b.Rarity) || !equalPtr(a.Threshold, b.Threshold) || !equalPtr(a.LootItemID, b.LootItemID) {
+			return false
 		}
 	}
+	return true
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
 
-	return generatedLoot, nil
+func mapLootRollsToDTOs(rolls []*repositories.ExpeditionLootRollEntity) []*models.LootRollDTO {
+	dtos := make([]*models.LootRollDTO, len(rolls))
+	for i, roll := range rolls {
+		dtos[i] = &models.LootRollDTO{
+			Order:      roll.RollOrder,
+			Type:       roll.RollType,
+			Rarity:     roll.Rarity,
+			Roll:       roll.Roll,
+			Threshold:  roll.Threshold,
+			LootItemID: roll.LootItemID,
+		}
+	}
+	return dtos
 }
 
 func (s *ExpeditionService) loadEquippedItems(team *repositories.TeamEntity, equippedItems map[string]*repositories.LootItemEntity) {
@@ -311,11 +1036,50 @@ func (s *ExpeditionService) loadEquippedItems(team *repositories.TeamEntity, equ
 	}
 }
 
+func (s *ExpeditionService) mapLootItemDTO(lootItem *repositories.LootItemEntity) models.LootItemResponseDTO {
+	return models.LootItemResponseDTO{
+		ID:                lootItem.ID,
+		Name:              lootItem.Name,
+		Description:       lootItem.Description,
+		Rarity:            lootItem.Rarity,
+		WorldType:         lootItem.WorldType,
+		ItemType:          lootItem.ItemType,
+		EquipmentSlot:     lootItem.EquipmentSlot,
+		SpeedBonus:        lootItem.SpeedBonus,
+		LuckBonus:         lootItem.LuckBonus,
+		PowerBonus:        lootItem.PowerBonus,
+		ElementalAffinity: lootItem.ElementalAffinity,
+		PowerValue:        lootItem.PowerValue,
+		Icon:              lootItem.Icon,
+	}
+}
+
+// mapRewardsToDTO expands expedition_loot rows into one response item per unit of quantity
+func (s *ExpeditionService) mapRewardsToDTO(expeditionId int64, lootEntities []*repositories.ExpeditionLootEntity) (*models.ExpeditionRewardsDTO, error) {
+	lootDTOs := make([]models.LootItemResponseDTO, 0, len(lootEntities))
+	for _, lootEntity := range lootEntities {
+		item, err := s.lootItemRepository.GetLootItemById(lootEntity.LootItemID)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < lootEntity.Quantity; i++ {
+			lootDTOs = append(lootDTOs, s.mapLootItemDTO(item))
+		}
+	}
+
+	return &models.ExpeditionRewardsDTO{
+		ExpeditionID: expeditionId,
+		Loot:         lootDTOs,
+	}, nil
+}
+
 func (s *ExpeditionService) mapExpeditionToDTO(expedition *repositories.ExpeditionEntity, riftName string, teamNumber int, includeLoot bool) (*models.ExpeditionResponseDTO, error) {
 	completionTime := expedition.StartTime.Add(time.Duration(expedition.DurationMinutes) * time.Minute)
 
+	isRecalled := expedition.Status == string(models.ExpeditionStatusRecalled)
+
 	var timeRemaining *int
-	if !expedition.Completed {
+	if !expedition.Completed && !isRecalled {
 		remaining := int(time.Until(completionTime).Seconds())
 		if remaining < 0 {
 			remaining = 0
@@ -324,17 +1088,22 @@ func (s *ExpeditionService) mapExpeditionToDTO(expedition *repositories.Expediti
 	}
 
 	dto := &models.ExpeditionResponseDTO{
-		ID:              expedition.ID,
-		TeamID:          expedition.TeamID,
-		TeamNumber:      teamNumber,
-		RiftID:          expedition.RiftID,
-		RiftName:        riftName,
-		StartTime:       expedition.StartTime.Format("2006-01-02T15:04:05Z"),
-		DurationMinutes: expedition.DurationMinutes,
-		CompletionTime:  completionTime.Format("2006-01-02T15:04:05Z"),
-		TimeRemaining:   timeRemaining,
-		IsCompleted:     expedition.Completed || time.Now().After(completionTime),
-		IsClaimed:       expedition.Claimed,
+		ID:               expedition.ID,
+		TeamID:           expedition.TeamID,
+		TeamNumber:       teamNumber,
+		RiftID:           expedition.RiftID,
+		RiftName:         riftName,
+		StartTime:        expedition.StartTime.Format("2006-01-02T15:04:05Z"),
+		DurationMinutes:  expedition.DurationMinutes,
+		CompletionTime:   completionTime.Format("2006-01-02T15:04:05Z"),
+		TimeRemaining:    timeRemaining,
+		IsCompleted:      expedition.Completed || (!isRecalled && time.Now().After(completionTime)),
+		IsClaimed:        expedition.Claimed,
+		EffectivePower:   expedition.EffectivePower,
+		RecommendedPower: expedition.RecommendedPower,
+		IsUnderPowered:   expedition.EffectivePower < expedition.RecommendedPower,
+		PartialFailure:   expedition.PartialFailure,
+		Status:           expedition.Status,
 	}
 
 	if includeLoot && expedition.Claimed {
@@ -344,21 +1113,7 @@ func (s *ExpeditionService) mapExpeditionToDTO(expedition *repositories.Expediti
 			for _, lootEntity := range lootEntities {
 				lootItem, err := s.lootItemRepository.GetLootItemById(lootEntity.LootItemID)
 				if err == nil {
-					lootDTOs = append(lootDTOs, models.LootItemResponseDTO{
-						ID:                lootItem.ID,
-						Name:              lootItem.Name,
-						Description:       lootItem.Description,
-						Rarity:            lootItem.Rarity,
-						WorldType:         lootItem.WorldType,
-						ItemType:          lootItem.ItemType,
-						EquipmentSlot:     lootItem.EquipmentSlot,
-						SpeedBonus:        lootItem.SpeedBonus,
-						LuckBonus:         lootItem.LuckBonus,
-						PowerBonus:        lootItem.PowerBonus,
-						ElementalAffinity: lootItem.ElementalAffinity,
-						PowerValue:        lootItem.PowerValue,
-						Icon:              lootItem.Icon,
-					})
+					lootDTOs = append(lootDTOs, s.mapLootItemDTO(lootItem))
 				}
 			}
 			dto.Loot = &lootDTOs
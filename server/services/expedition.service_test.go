@@ -1,10 +1,13 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
 	"github.com/snowlynxsoftware/parallax-game/server/models"
 	"github.com/stretchr/testify/assert"
@@ -16,8 +19,8 @@ type MockExpeditionRepositoryForExpedition struct {
 	mock.Mock
 }
 
-func (m *MockExpeditionRepositoryForExpedition) CreateExpedition(userId, teamId, riftId int64, duration int) (*repositories.ExpeditionEntity, error) {
-	args := m.Called(userId, teamId, riftId, duration)
+func (m *MockExpeditionRepositoryForExpedition) CreateExpedition(userId, teamId, riftId int64, duration, effectivePower, recommendedPower int, lootLuck float64, lootSeed int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(userId, teamId, riftId, duration, effectivePower, recommendedPower, lootLuck, lootSeed)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -40,7 +43,7 @@ func (m *MockExpeditionRepositoryForExpedition) GetActiveExpeditionsByUserId(use
 	return args.Get(0).([]*repositories.ExpeditionEntity), args.Error(1)
 }
 
-func (m *MockExpeditionRepositoryForExpedition) GetCompletedExpeditionsByUserId(userId int64, limit int) ([]*repositories.ExpeditionEntity, error) {
+func (m *MockExpeditionRepositoryForExpedition) GetFinishedExpeditionsByUserId(userId int64, limit int) ([]*repositories.ExpeditionEntity, error) {
 	args := m.Called(userId, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -81,11 +84,67 @@ func (m *MockExpeditionRepositoryForExpedition) MarkCompleted(expeditionId int64
 	return args.Error(0)
 }
 
-func (m *MockExpeditionRepositoryForExpedition) MarkProcessed(expeditionId int64) error {
+func (m *MockExpeditionRepositoryForExpedition) MarkRecalled(expeditionId int64) error {
 	args := m.Called(expeditionId)
 	return args.Error(0)
 }
 
+func (m *MockExpeditionRepositoryForExpedition) SaveLootRollInputs(expeditionId int64, luck, lootMultiplier float64) error {
+	args := m.Called(expeditionId, luck, lootMultiplier)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) GetCompletedExpeditionsCountByRift(userId, riftId int64) (int, error) {
+	args := m.Called(userId, riftId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) GetActiveExpeditionByTeamId(teamId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) MarkProcessed(expeditionId int64, partialFailure bool) error {
+	args := m.Called(expeditionId, partialFailure)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) GetDueExpeditionIds(limit int, skipIds []int64) ([]int64, error) {
+	args := m.Called(limit, skipIds)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) MarkProcessFailed(expeditionId int64) error {
+	args := m.Called(expeditionId)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) LockDueExpeditionById(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) GetExpeditionByIdForUpdate(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
+
+func (m *MockExpeditionRepositoryForExpedition) WithTx(tx *database.AppDataSource) repositories.IExpeditionRepository {
+	return m
+}
+
 // Mock ExpeditionLootRepository
 type MockExpeditionLootRepository struct {
 	mock.Mock
@@ -104,6 +163,85 @@ func (m *MockExpeditionLootRepository) GetLootByExpeditionId(expeditionId int64)
 	return args.Get(0).([]*repositories.ExpeditionLootEntity), args.Error(1)
 }
 
+func (m *MockExpeditionLootRepository) CreateLootRolls(expeditionId int64, rolls []*repositories.ExpeditionLootRollEntity) error {
+	args := m.Called(expeditionId, rolls)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionLootRepository) GetLootRollsByExpeditionId(expeditionId int64) ([]*repositories.ExpeditionLootRollEntity, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.ExpeditionLootRollEntity), args.Error(1)
+}
+
+func (m *MockExpeditionLootRepository) WithTx(tx *database.AppDataSource) repositories.IExpeditionLootRepository {
+	return m
+}
+
+// testRandomSeed seeds the random service in ExpeditionService tests so loot rolls are repeatable
+const testRandomSeed int64 = 42
+
+// testEchoRandomSeed seeds the random service so the first echo encounter roll succeeds
+const testEchoRandomSeed int64 = 5
+
+// testLootSeed is the first expedition seed handed out by a random service seeded with testRandomSeed
+var testLootSeed = NewSeededRandomService(testRandomSeed).NewSeed()
+
+// MockUnitOfWork runs the transaction function directly against the mocks.
+// Transactions are serialized, which mirrors the row lock the real claim path takes.
+type MockUnitOfWork struct {
+	mutex sync.Mutex
+}
+
+func (m *MockUnitOfWork) WithinTransaction(fn func(tx *database.AppDataSource) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return fn(nil)
+}
+
+// Mock RiftService
+// newAllRiftsUnlockedService returns a rift service for a player with every rift unlocked
+func newAllRiftsUnlockedService() *MockRiftService {
+	riftService := new(MockRiftService)
+	riftService.On("GetAllRifts", mock.Anything).Return([]*models.RiftResponseDTO{}, nil)
+	return riftService
+}
+
+type MockRiftService struct {
+	mock.Mock
+}
+
+func (m *MockRiftService) GetAllRifts(userId int64) ([]*models.RiftResponseDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.RiftResponseDTO), args.Error(1)
+}
+
+func (m *MockRiftService) GetRiftById(riftId int64) (*models.RiftResponseDTO, error) {
+	args := m.Called(riftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RiftResponseDTO), args.Error(1)
+}
+
+func (m *MockRiftService) IsRiftUnlockedForUser(userId, riftId int64) (bool, error) {
+	args := m.Called(userId, riftId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRiftService) GetUnlockedRiftIds(userId int64, riftIds []int64) (map[int64]bool, error) {
+	args := m.Called(userId, riftIds)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64]bool), args.Error(1)
+}
+
 // Mock LootDropTableRepository
 type MockLootDropTableRepository struct {
 	mock.Mock
@@ -117,6 +255,49 @@ func (m *MockLootDropTableRepository) GetDropTablesByRiftId(riftId int64) ([]*re
 	return args.Get(0).([]*repositories.LootDropTableEntity), args.Error(1)
 }
 
+// Mock EchoEncounterRepository
+type MockEchoEncounterRepository struct {
+	mock.Mock
+}
+
+func (m *MockEchoEncounterRepository) GetEchoExpedition(userId, riftId int64, withinHours int) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(userId, riftId, withinHours)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
+
+func (m *MockEchoEncounterRepository) CreateEchoEncounter(expeditionId1, expeditionId2, userId1, userId2, riftId int64) (*repositories.EchoEncounterEntity, error) {
+	args := m.Called(expeditionId1, expeditionId2, userId1, userId2, riftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.EchoEncounterEntity), args.Error(1)
+}
+
+func (m *MockEchoEncounterRepository) CreateEchoEncounterLoot(encounterId, userId, lootItemId int64, quantity int) error {
+	args := m.Called(encounterId, userId, lootItemId, quantity)
+	return args.Error(0)
+}
+
+func (m *MockEchoEncounterRepository) GetEchoEncounterLoot(encounterId int64) ([]*repositories.EchoEncounterLootEntity, error) {
+	args := m.Called(encounterId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.EchoEncounterLootEntity), args.Error(1)
+}
+
+func (m *MockEchoEncounterRepository) GetEchoEncounterCount(userId int64) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockEchoEncounterRepository) WithTx(tx *database.AppDataSource) repositories.IEchoEncounterRepository {
+	return m
+}
+
 // Tests for StartExpedition
 func TestExpeditionService_StartExpedition_Success(t *testing.T) {
 	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
@@ -125,6 +306,7 @@ func TestExpeditionService_StartExpedition_Success(t *testing.T) {
 	mockInventoryRepo := new(MockUserInventoryRepository)
 	mockLootItemRepo := new(MockLootItemRepository)
 	mockGameCoreService := new(MockGameCoreService)
+	mockRiftService := new(MockRiftService)
 
 	service := NewExpeditionService(
 		mockExpeditionRepo,
@@ -134,7 +316,12 @@ func TestExpeditionService_StartExpedition_Success(t *testing.T) {
 		mockInventoryRepo,
 		mockLootItemRepo,
 		nil, // drop table not needed for start
+		nil,
 		mockGameCoreService,
+		new(MockUnitOfWork),
+		mockRiftService,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	team := &repositories.TeamEntity{
@@ -149,24 +336,31 @@ func TestExpeditionService_StartExpedition_Success(t *testing.T) {
 		WorldType:       "desert",
 		WeakToElement:   "water",
 		DurationMinutes: 60,
+		Difficulty:      "medium",
 	}
 	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20}
 	expedition := &repositories.ExpeditionEntity{
-		ID:              1,
-		UserID:          1,
-		TeamID:          1,
-		RiftID:          1,
-		StartTime:       time.Now(),
-		DurationMinutes: 50,
-		Completed:       false,
-		Claimed:         false,
+		ID:               1,
+		UserID:           1,
+		TeamID:           1,
+		RiftID:           1,
+		StartTime:        time.Now(),
+		DurationMinutes:  50,
+		Completed:        false,
+		Claimed:          false,
+		EffectivePower:   20,
+		RecommendedPower: 25,
 	}
 
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockRiftService.On("IsRiftUnlockedForUser", int64(1), int64(1)).Return(true, nil)
+	mockExpeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
 	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
 	mockGameCoreService.On("CalculateExpeditionDuration", stats, 60).Return(50)
-	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 50).Return(expedition, nil)
+	mockGameCoreService.On("GetRecommendedPower", "medium").Return(25)
+	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 50, 20, 25, 5.0, testLootSeed).Return(expedition, nil)
 
 	result, err := service.StartExpedition(1, 1, 1)
 
@@ -174,6 +368,9 @@ func TestExpeditionService_StartExpedition_Success(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, int64(1), result.ID)
 	assert.Equal(t, "Test Rift", result.RiftName)
+	assert.Equal(t, 20, result.EffectivePower)
+	assert.Equal(t, 25, result.RecommendedPower)
+	assert.True(t, result.IsUnderPowered)
 	mockTeamRepo.AssertExpectations(t)
 	mockRiftRepo.AssertExpectations(t)
 	mockExpeditionRepo.AssertExpectations(t)
@@ -192,6 +389,11 @@ func TestExpeditionService_StartExpedition_TeamDoesntBelongToUser(t *testing.T)
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	team := &repositories.TeamEntity{
@@ -205,7 +407,7 @@ func TestExpeditionService_StartExpedition_TeamDoesntBelongToUser(t *testing.T)
 
 	result, err := service.StartExpedition(1, 1, 1)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrTeamNotOwned)
 	assert.Nil(t, result)
 	mockTeamRepo.AssertExpectations(t)
 }
@@ -223,6 +425,11 @@ func TestExpeditionService_StartExpedition_TeamNotUnlocked(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	team := &repositories.TeamEntity{
@@ -236,7 +443,7 @@ func TestExpeditionService_StartExpedition_TeamNotUnlocked(t *testing.T) {
 
 	result, err := service.StartExpedition(1, 1, 1)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrTeamLocked)
 	assert.Nil(t, result)
 	mockTeamRepo.AssertExpectations(t)
 }
@@ -254,6 +461,11 @@ func TestExpeditionService_StartExpedition_TeamRepositoryError(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(nil, errors.New("database error"))
@@ -279,6 +491,11 @@ func TestExpeditionService_StartExpedition_RiftRepositoryError(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	team := &repositories.TeamEntity{
@@ -306,6 +523,7 @@ func TestExpeditionService_StartExpedition_CreateExpeditionError(t *testing.T) {
 	mockInventoryRepo := new(MockUserInventoryRepository)
 	mockLootItemRepo := new(MockLootItemRepository)
 	mockGameCoreService := new(MockGameCoreService)
+	mockRiftService := new(MockRiftService)
 
 	service := NewExpeditionService(
 		mockExpeditionRepo,
@@ -315,7 +533,12 @@ func TestExpeditionService_StartExpedition_CreateExpeditionError(t *testing.T) {
 		mockInventoryRepo,
 		mockLootItemRepo,
 		nil,
+		nil,
 		mockGameCoreService,
+		new(MockUnitOfWork),
+		mockRiftService,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	team := &repositories.TeamEntity{
@@ -330,14 +553,19 @@ func TestExpeditionService_StartExpedition_CreateExpeditionError(t *testing.T) {
 		WorldType:       "desert",
 		WeakToElement:   "water",
 		DurationMinutes: 60,
+		Difficulty:      "medium",
 	}
 	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20}
 
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockRiftService.On("IsRiftUnlockedForUser", int64(1), int64(1)).Return(true, nil)
+	mockExpeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
 	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
 	mockGameCoreService.On("CalculateExpeditionDuration", stats, 60).Return(50)
-	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 50).Return(nil, errors.New("database error"))
+	mockGameCoreService.On("GetRecommendedPower", "medium").Return(25)
+	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 50, 20, 25, 5.0, testLootSeed).Return(nil, errors.New("database error"))
 
 	result, err := service.StartExpedition(1, 1, 1)
 
@@ -347,6 +575,139 @@ func TestExpeditionService_StartExpedition_CreateExpeditionError(t *testing.T) {
 }
 
 // Tests for GetActiveExpeditions
+// newStartExpeditionTestService builds an ExpeditionService wired for StartExpedition validation tests
+func newStartExpeditionTestService() (IExpeditionService, *MockExpeditionRepositoryForExpedition, *MockTeamRepository, *MockRiftRepository, *MockRiftService) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockRiftService := new(MockRiftService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		mockTeamRepo,
+		mockRiftRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		mockRiftService,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	return service, mockExpeditionRepo, mockTeamRepo, mockRiftRepo, mockRiftService
+}
+
+func TestExpeditionService_StartExpedition_RiftLocked(t *testing.T) {
+	service, mockExpeditionRepo, mockTeamRepo, mockRiftRepo, mockRiftService := newStartExpeditionTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+	rift := &repositories.RiftEntity{ID: 6, Name: "Void Confluence", Difficulty: "legendary"}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(6)).Return(rift, nil)
+	mockRiftService.On("IsRiftUnlockedForUser", int64(1), int64(6)).Return(false, nil)
+
+	result, err := service.StartExpedition(1, 1, 6)
+
+	assert.ErrorIs(t, err, ErrRiftLocked)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "CreateExpedition", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_StartExpedition_RiftArchived(t *testing.T) {
+	service, mockExpeditionRepo, mockTeamRepo, mockRiftRepo, mockRiftService := newStartExpeditionTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	// Archived rifts are filtered out by the repository
+	mockRiftRepo.On("GetRiftById", int64(2)).Return(nil, sql.ErrNoRows)
+
+	result, err := service.StartExpedition(1, 1, 2)
+
+	assert.ErrorIs(t, err, ErrRiftNotFound)
+	assert.Nil(t, result)
+	mockRiftService.AssertNotCalled(t, "IsRiftUnlockedForUser", mock.Anything, mock.Anything)
+	mockExpeditionRepo.AssertNotCalled(t, "CreateExpedition", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_StartExpedition_TeamNotFound(t *testing.T) {
+	service, _, mockTeamRepo, _, _ := newStartExpeditionTestService()
+
+	mockTeamRepo.On("GetTeamById", int64(99)).Return(nil, sql.ErrNoRows)
+
+	result, err := service.StartExpedition(1, 99, 1)
+
+	assert.ErrorIs(t, err, ErrTeamNotFound)
+	assert.Nil(t, result)
+}
+
+func TestExpeditionService_StartExpedition_TeamBusy(t *testing.T) {
+	service, mockExpeditionRepo, mockTeamRepo, mockRiftRepo, mockRiftService := newStartExpeditionTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial"}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockRiftService.On("IsRiftUnlockedForUser", int64(1), int64(1)).Return(true, nil)
+	mockExpeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(&repositories.ExpeditionEntity{ID: 5, TeamID: 1}, nil)
+
+	result, err := service.StartExpedition(1, 1, 1)
+
+	assert.ErrorIs(t, err, ErrTeamBusy)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "CreateExpedition", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_StartExpedition_ConcurrentLaunchHitsConstraint(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockRiftService := new(MockRiftService)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		mockTeamRepo,
+		mockRiftRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		mockRiftService,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", DurationMinutes: 5}
+	stats := &models.TeamStatsDTO{}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockRiftService.On("IsRiftUnlockedForUser", int64(1), int64(1)).Return(true, nil)
+	// Both launches pass the busy check, the unique index stops the second insert
+	mockExpeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	mockGameCoreService.On("CalculateExpeditionDuration", stats, 5).Return(5)
+	mockGameCoreService.On("GetRecommendedPower", "tutorial").Return(0)
+	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 5, 0, 0, 0.0, testLootSeed).Return(nil, repositories.ErrTeamHasActiveExpedition)
+
+	result, err := service.StartExpedition(1, 1, 1)
+
+	assert.ErrorIs(t, err, ErrTeamBusy)
+	assert.Nil(t, result)
+}
+
 func TestExpeditionService_GetActiveExpeditions_Success(t *testing.T) {
 	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
 	mockTeamRepo := new(MockTeamRepository)
@@ -361,6 +722,11 @@ func TestExpeditionService_GetActiveExpeditions_Success(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expeditions := []*repositories.ExpeditionEntity{
@@ -405,6 +771,11 @@ func TestExpeditionService_GetActiveExpeditions_EmptyList(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expeditions := []*repositories.ExpeditionEntity{}
@@ -431,6 +802,11 @@ func TestExpeditionService_GetActiveExpeditions_RepositoryError(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	mockExpeditionRepo.On("GetActiveExpeditionsByUserId", int64(1)).Return(nil, errors.New("database error"))
@@ -455,6 +831,11 @@ func TestExpeditionService_GetActiveExpeditions_TeamRepositoryError(t *testing.T
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expeditions := []*repositories.ExpeditionEntity{
@@ -495,6 +876,11 @@ func TestExpeditionService_GetActiveExpeditions_RiftRepositoryError(t *testing.T
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expeditions := []*repositories.ExpeditionEntity{
@@ -541,6 +927,11 @@ func TestExpeditionService_GetExpeditionHistory_Success(t *testing.T) {
 		mockLootItemRepo,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expeditions := []*repositories.ExpeditionEntity{
@@ -563,7 +954,7 @@ func TestExpeditionService_GetExpeditionHistory_Success(t *testing.T) {
 	}
 	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Test Item", Rarity: "common"}
 
-	mockExpeditionRepo.On("GetCompletedExpeditionsByUserId", int64(1), 10).Return(expeditions, nil)
+	mockExpeditionRepo.On("GetFinishedExpeditionsByUserId", int64(1), 10).Return(expeditions, nil)
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
 	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return(lootEntities, nil)
@@ -595,9 +986,14 @@ func TestExpeditionService_GetExpeditionHistory_RepositoryError(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
-	mockExpeditionRepo.On("GetCompletedExpeditionsByUserId", int64(1), 10).Return(nil, errors.New("database error"))
+	mockExpeditionRepo.On("GetFinishedExpeditionsByUserId", int64(1), 10).Return(nil, errors.New("database error"))
 
 	result, err := service.GetExpeditionHistory(1, 10)
 
@@ -619,6 +1015,11 @@ func TestExpeditionService_ClaimExpeditionRewards_ExpeditionDoesntBelongToUser(t
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -632,11 +1033,11 @@ func TestExpeditionService_ClaimExpeditionRewards_ExpeditionDoesntBelongToUser(t
 		Claimed:         false,
 	}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrExpeditionNotOwned)
 	assert.Nil(t, result)
 	mockExpeditionRepo.AssertExpectations(t)
 }
@@ -653,6 +1054,11 @@ func TestExpeditionService_ClaimExpeditionRewards_AlreadyClaimed(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -666,11 +1072,11 @@ func TestExpeditionService_ClaimExpeditionRewards_AlreadyClaimed(t *testing.T) {
 		Claimed:         true, // Already claimed
 	}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrRewardsAlreadyClaimed)
 	assert.Nil(t, result)
 	mockExpeditionRepo.AssertExpectations(t)
 }
@@ -687,6 +1093,11 @@ func TestExpeditionService_ClaimExpeditionRewards_NotYetComplete(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -700,11 +1111,11 @@ func TestExpeditionService_ClaimExpeditionRewards_NotYetComplete(t *testing.T) {
 		Claimed:         false,
 	}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrExpeditionNotComplete)
 	assert.Nil(t, result)
 	mockExpeditionRepo.AssertExpectations(t)
 }
@@ -721,9 +1132,14 @@ func TestExpeditionService_ClaimExpeditionRewards_ExpeditionRepositoryError(t *t
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(nil, errors.New("database error"))
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(nil, errors.New("database error"))
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
 
@@ -745,6 +1161,11 @@ func TestExpeditionService_ClaimExpeditionRewards_TeamRepositoryError(t *testing
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -758,7 +1179,7 @@ func TestExpeditionService_ClaimExpeditionRewards_TeamRepositoryError(t *testing
 		Claimed:         false,
 	}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(nil, errors.New("database error"))
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
@@ -783,6 +1204,11 @@ func TestExpeditionService_ClaimExpeditionRewards_RiftRepositoryError(t *testing
 		nil,
 		nil,
 		nil,
+		nil,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -797,7 +1223,7 @@ func TestExpeditionService_ClaimExpeditionRewards_RiftRepositoryError(t *testing
 	}
 	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(nil, errors.New("database error"))
 
@@ -827,7 +1253,12 @@ func TestExpeditionService_ClaimExpeditionRewards_DropTableRepositoryError(t *te
 		mockInventoryRepo,
 		mockLootItemRepo,
 		mockDropTableRepo,
+		nil,
 		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -843,9 +1274,10 @@ func TestExpeditionService_ClaimExpeditionRewards_DropTableRepositoryError(t *te
 	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
 	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "desert"}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
 	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(nil, errors.New("database error"))
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
@@ -860,22 +1292,67 @@ func TestExpeditionService_ClaimExpeditionRewards_DropTableRepositoryError(t *te
 
 func TestExpeditionService_ClaimExpeditionRewards_MarkClaimedError(t *testing.T) {
 	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
-	mockTeamRepo := new(MockTeamRepository)
-	mockRiftRepo := new(MockRiftRepository)
-	mockDropTableRepo := new(MockLootDropTableRepository)
-	mockInventoryRepo := new(MockUserInventoryRepository)
-	mockLootItemRepo := new(MockLootItemRepository)
-	mockGameCoreService := new(MockGameCoreService)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
 
 	service := NewExpeditionService(
 		mockExpeditionRepo,
+		mockExpeditionLootRepo,
 		nil,
-		mockTeamRepo,
-		mockRiftRepo,
-		mockInventoryRepo,
-		mockLootItemRepo,
-		mockDropTableRepo,
-		mockGameCoreService,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-2 * time.Hour),
+		DurationMinutes: 50,
+		Completed:       true,
+		Processed:       true,
+		Claimed:         false,
+	}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return([]*repositories.ExpeditionLootEntity{}, nil)
+	mockExpeditionRepo.On("MarkClaimed", int64(1)).Return(errors.New("database error"))
+
+	result, err := service.ClaimExpeditionRewards(1, 1)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertExpectations(t)
+}
+
+func TestExpeditionService_ClaimExpeditionRewards_RevealsProcessedLoot(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		nil,
+		nil,
+		nil,
+		mockLootItemRepo,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
 	)
 
 	expedition := &repositories.ExpeditionEntity{
@@ -886,23 +1363,1502 @@ func TestExpeditionService_ClaimExpeditionRewards_MarkClaimedError(t *testing.T)
 		StartTime:       time.Now().Add(-2 * time.Hour),
 		DurationMinutes: 50,
 		Completed:       true,
+		Processed:       true,
 		Claimed:         false,
 	}
+	lootEntities := []*repositories.ExpeditionLootEntity{
+		{ID: 1, ExpeditionID: 1, LootItemID: 100, Quantity: 2},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Test Item", Rarity: "common"}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return(lootEntities, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockExpeditionRepo.On("MarkClaimed", int64(1)).Return(nil)
+
+	result, err := service.ClaimExpeditionRewards(1, 1)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Len(t, result.Loot, 2)
+	assert.Equal(t, "Test Item", result.Loot[0].Name)
+	// Loot was already rolled by the processor, so claiming must not roll it again
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertExpectations(t)
+}
+
+func TestExpeditionService_ClaimExpeditionRewards_ProcessesUnprocessedExpedition(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-2 * time.Hour),
+		DurationMinutes: 50,
+	}
 	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
-	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "desert"}
-	dropTables := []*repositories.LootDropTableEntity{} // Empty drop tables for simplicity
-	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 1, MaxQuantity: 1},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+	stats := &models.TeamStatsDTO{}
 
-	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
 	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
 	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
-	mockExpeditionRepo.On("MarkClaimed", int64(1)).Return(errors.New("database error"))
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil)
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), int64(100), 1).Return(nil)
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), mock.Anything, mock.Anything).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), false).Return(nil)
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return([]*repositories.ExpeditionLootEntity{
+		{ExpeditionID: 1, LootItemID: 100, Quantity: 1},
+	}, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockExpeditionRepo.On("MarkClaimed", int64(1)).Return(nil)
+
+	result, err := service.ClaimExpeditionRewards(1, 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Loot, 1)
+	assert.Equal(t, "Ember Shard", result.Loot[0].Name)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertExpectations(t)
+}
+
+func TestExpeditionService_ClaimExpeditionRewards_LootWriteErrorSkipsClaim(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-2 * time.Hour),
+		DurationMinutes: 50,
+	}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 2, MaxQuantity: 2},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	// First item goes in, second one fails partway through
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil).Once()
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(nil, errors.New("database error")).Once()
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), int64(100), 1).Return(nil)
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), mock.Anything, mock.Anything).Return(nil)
 
 	result, err := service.ClaimExpeditionRewards(1, 1)
 
+	// The error surfaces from inside the transaction, so the partial loot is rolled back
+	// and the expedition is never marked processed or claimed
 	assert.Error(t, err)
 	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkClaimed", mock.Anything)
+}
+
+// claimTrackingExpeditionRepository is a stateful fake that records claims so the
+// concurrent claim test can check how many times loot was handed out
+type claimTrackingExpeditionRepository struct {
+	MockExpeditionRepositoryForExpedition
+	mutex      sync.Mutex
+	expedition repositories.ExpeditionEntity
+	claimCount int
+}
+
+func (r *claimTrackingExpeditionRepository) GetExpeditionByIdForUpdate(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	expedition := r.expedition
+	return &expedition, nil
+}
+
+func (r *claimTrackingExpeditionRepository) MarkClaimed(expeditionId int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expedition.Claimed = true
+	r.claimCount++
+	return nil
+}
+
+func (r *claimTrackingExpeditionRepository) WithTx(tx *database.AppDataSource) repositories.IExpeditionRepository {
+	return r
+}
+
+func TestExpeditionService_ClaimExpeditionRewards_ConcurrentClaimsOnlySucceedOnce(t *testing.T) {
+	expeditionRepo := &claimTrackingExpeditionRepository{
+		expedition: repositories.ExpeditionEntity{
+			ID:              1,
+			UserID:          1,
+			TeamID:          1,
+			RiftID:          1,
+			StartTime:       time.Now().Add(-2 * time.Hour),
+			DurationMinutes: 50,
+			Completed:       true,
+			Processed:       true,
+		},
+	}
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+
+	service := NewExpeditionService(
+		expeditionRepo,
+		mockExpeditionLootRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return([]*repositories.ExpeditionLootEntity{}, nil)
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	successes := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.ClaimExpeditionRewards(1, 1)
+			if err == nil {
+				mutex.Lock()
+				successes++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, successes)
+	assert.Equal(t, 1, expeditionRepo.claimCount)
+}
+
+// Tests for ProcessDueExpeditions
+func TestExpeditionService_ProcessDueExpeditions_Success(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	eventHub := NewEventHubService()
+	subscription := eventHub.Subscribe(1, 0)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		nil,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		eventHub,
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "desert"}
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20}
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return([]*repositories.LootDropTableEntity{}, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 5.0).Return([]*repositories.LootDropTableEntity{})
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), 5.0, 1.0).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), false).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockDropTableRepo.AssertExpectations(t)
+
+	event := receive(t, subscription)
+	assert.Equal(t, models.GameEventExpeditionCompleted, event.Type)
+	assert.Equal(t, &models.ExpeditionCompletedEventDTO{ExpeditionID: 1, TeamID: 1, RiftID: 1}, event.Data)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_UsesLuckFromLaunch(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	riftService := newAllRiftsUnlockedService()
+	riftService.On("IsRiftUnlockedForUser", int64(1), int64(1)).Return(true, nil)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		new(MockUserInventoryRepository),
+		nil,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		riftService,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "desert", DurationMinutes: 60, Difficulty: "medium"}
+	launchStats := &models.TeamStatsDTO{Speed: 10.0, Luck: 2.0, Power: 20}
+	// Luck gear swapped in after launch
+	swappedStats := &models.TeamStatsDTO{Speed: 10.0, Luck: 9.0, Power: 20}
+
+	// Launch with low luck gear
+	var launched *repositories.ExpeditionEntity
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockExpeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(launchStats).Once()
+	mockGameCoreService.On("ApplySpecialization", launchStats, team.Specialization, "desert").Return(launchStats).Once()
+	mockGameCoreService.On("CalculateExpeditionDuration", launchStats, 60).Return(50)
+	mockGameCoreService.On("GetRecommendedPower", "medium").Return(25)
+	mockExpeditionRepo.On("CreateExpedition", int64(1), int64(1), int64(1), 50, 20, 25, 2.0, testLootSeed).
+		Run(func(args mock.Arguments) {
+			luck := args.Get(6).(float64)
+			launched = &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50, LootLuck: &luck}
+		}).
+		Return(&repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, DurationMinutes: 50}, nil)
+
+	_, err := service.StartExpedition(1, 1, 1)
+	assert.NoError(t, err)
+
+	// Any stats read from now on see the luck gear
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(swappedStats)
+	mockGameCoreService.On("ApplySpecialization", swappedStats, team.Specialization, "desert").Return(swappedStats)
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(launched, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return([]*repositories.LootDropTableEntity{}, nil)
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 2.0).Return([]*repositories.LootDropTableEntity{})
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), 2.0, 1.0).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), false).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
 	mockExpeditionRepo.AssertExpectations(t)
+	mockGameCoreService.AssertNotCalled(t, "AdjustDropRates", mock.Anything, 9.0)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_LootWriteErrorLeavesExpeditionDue(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	eventHub := NewEventHubService()
+	subscription := eventHub.Subscribe(1, 0)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		eventHub,
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 1, MaxQuantity: 1},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(nil, errors.New("database error"))
+	mockExpeditionRepo.On("MarkProcessFailed", int64(1)).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	// The expedition is only claimed by the row lock inside the failed transaction, so
+	// it is never marked completed and the next run picks it up again
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, []int64{1}, failed)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkCompleted", mock.Anything)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+	assertNoEvent(t, subscription)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_PublishesUnlockedRifts(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	mockRiftService := new(MockRiftService)
+	eventHub := NewEventHubService()
+	subscription := eventHub.Subscribe(1, 0)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		nil,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		mockRiftService,
+		NewSeededRandomService(testRandomSeed),
+		eventHub,
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "desert"}
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20}
+
+	// The expedition unlocks rift 2, rift 3 stays locked. The rifts are only loaded once,
+	// afterwards just the locked ones are re-checked.
+	mockRiftService.On("GetAllRifts", int64(1)).Return([]*models.RiftResponseDTO{
+		{ID: 1, Name: "Test Rift", IsUnlocked: true},
+		{ID: 2, Name: "Fire Rift", IsUnlocked: false},
+		{ID: 3, Name: "Ice Rift", IsUnlocked: false},
+	}, nil).Once()
+	mockRiftService.On("GetUnlockedRiftIds", int64(1), []int64{2, 3}).Return(map[int64]bool{2: true}, nil).Once()
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return([]*repositories.LootDropTableEntity{}, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 5.0).Return([]*repositories.LootDropTableEntity{})
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), 5.0, 1.0).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), false).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
+	mockRiftService.AssertExpectations(t)
+	assert.Equal(t, models.GameEventExpeditionCompleted, receive(t, subscription).Type)
+	event := receive(t, subscription)
+	assert.Equal(t, models.GameEventRiftUnlocked, event.Type)
+	assert.Equal(t, &models.RiftUnlockedEventDTO{RiftID: 2, RiftName: "Fire Rift"}, event.Data)
+	assertNoEvent(t, subscription)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_EchoEncounter(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockEchoEncounterRepo := new(MockEchoEncounterRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	eventHub := NewEventHubService()
+	subscription := eventHub.Subscribe(1, 0)
+	echoSubscription := eventHub.Subscribe(2, 0)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		mockEchoEncounterRepo,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testEchoRandomSeed),
+		eventHub,
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50}
+	echoLuck, echoMultiplier := 3.0, 1.0
+	echoExpedition := &repositories.ExpeditionEntity{ID: 9, UserID: 2, TeamID: 4, RiftID: 1, LootLuck: &echoLuck, LootMultiplier: &echoMultiplier}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Fire Rift", WorldType: "fire"}
+	stats := &models.TeamStatsDTO{Luck: 5.0}
+	// Four guaranteed commons become one bonus item each at EchoEncounterLootBonus
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 4, MaxQuantity: 4},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	// The expedition's own loot comes up empty so only the bonus loot is written
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 5.0).Return([]*repositories.LootDropTableEntity{}).Once()
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 5.0).Return(dropTables).Once()
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, echoLuck).Return(dropTables).Once()
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), 5.0, 1.0).Return(nil)
+	mockEchoEncounterRepo.On("GetEchoExpedition", int64(1), int64(1), EchoEncounterWindowHours).Return(echoExpedition, nil)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil).Once()
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), int64(100), 1).Return(nil).Once()
+	mockEchoEncounterRepo.On("CreateEchoEncounter", int64(1), int64(9), int64(1), int64(2), int64(1)).Return(&repositories.EchoEncounterEntity{
+		ID: 3, ExpeditionID1: 1, ExpeditionID2: 9, UserID1: 1, UserID2: 2, RiftID: 1,
+	}, nil)
+	// The echoed player's bonus goes to their inventory and the encounter, not their expedition's loot
+	mockInventoryRepo.On("AddLoot", int64(2), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 8}, nil).Once()
+	mockEchoEncounterRepo.On("CreateEchoEncounterLoot", int64(3), int64(2), int64(100), 1).Return(nil).Once()
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), false).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
+	mockEchoEncounterRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertNotCalled(t, "CreateExpeditionLoot", int64(9), mock.Anything, mock.Anything)
+
+	assert.Equal(t, models.GameEventExpeditionCompleted, receive(t, subscription).Type)
+	event := receive(t, subscription)
+	assert.Equal(t, models.GameEventEchoEncounter, event.Type)
+	assert.Equal(t, &models.EchoEncounterEventDTO{EncounterID: 3, ExpeditionID: 1, RiftID: 1, RiftName: "Fire Rift", OtherUserID: 2, BonusItems: 1}, event.Data)
+	event = receive(t, echoSubscription)
+	assert.Equal(t, models.GameEventEchoEncounter, event.Type)
+	assert.Equal(t, &models.EchoEncounterEventDTO{EncounterID: 3, ExpeditionID: 9, RiftID: 1, RiftName: "Fire Rift", OtherUserID: 1, BonusItems: 1}, event.Data)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_PartialFailureReducesLoot(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50, EffectivePower: 5, RecommendedPower: 50}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 2, MaxQuantity: 2},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	// Guaranteed failure so the 2 rolled items are halved to exactly 1
+	mockGameCoreService.On("CalculatePowerOutcome", 5, 50).Return(&models.PowerOutcomeDTO{PowerRatio: 0.1, LootMultiplier: 1.0, FailureChance: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil)
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), int64(100), 1).Return(nil)
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), mock.Anything, mock.Anything).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), true).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
+	mockInventoryRepo.AssertNumberOfCalls(t, "AddLoot", 1)
+	mockExpeditionRepo.AssertExpectations(t)
+}
+
+func TestScaleQuantity(t *testing.T) {
+	random := NewSeededRandomService(testRandomSeed).NewRandom(testLootSeed)
+
+	assert.Equal(t, 2, scaleQuantity(random, 2, 1.0))
+	assert.Equal(t, 3, scaleQuantity(random, 2, 1.5))
+	assert.Equal(t, 1, scaleQuantity(random, 4, 0.25))
+	assert.Equal(t, 0, scaleQuantity(random, 3, 0.0))
+
+	// Fractional results round to one of the two neighbouring whole numbers
+	for i := 0; i < 100; i++ {
+		scaled := scaleQuantity(random, 3, 0.5)
+		assert.True(t, scaled == 1 || scaled == 2)
+	}
+}
+
+// processLootWithSeed processes one expedition with the given loot seed against a fixed
+// drop table and returns the roll log that was written for it
+func processLootWithSeed(t *testing.T, lootSeed int64) []*repositories.ExpeditionLootRollEntity {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50, LootSeed: lootSeed}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 60, MinQuantity: 1, MaxQuantity: 3},
+		{RiftID: 1, Rarity: "rare", DropRatePercent: 30, MinQuantity: 1, MaxQuantity: 1},
+	}
+	commonItems := []*repositories.LootItemEntity{
+		{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"},
+		{ID: 101, Name: "Ash Dust", Rarity: "common", ItemType: "consumable"},
+	}
+	rareItems := []*repositories.LootItemEntity{
+		{ID: 200, Name: "Flame Core", Rarity: "rare", ItemType: "consumable"},
+	}
+
+	var rolls []*repositories.ExpeditionLootRollEntity
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0, FailureChance: 0.2})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return(commonItems, nil)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "rare", "fire").Return(rareItems, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), mock.Anything, "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil)
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), mock.Anything, 1).Return(nil).Maybe()
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Run(func(args mock.Arguments) {
+		rolls = args.Get(1).([]*repositories.ExpeditionLootRollEntity)
+	}).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), 0.0, mock.Anything).Return(nil)
+	mockExpeditionRepo.On("MarkCompleted", int64(1)).Return(nil)
+	mockExpeditionRepo.On("MarkProcessed", int64(1), mock.Anything).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Empty(t, failed)
+	mockExpeditionLootRepo.AssertExpectations(t)
+	mockExpeditionRepo.AssertExpectations(t)
+	return rolls
+}
+
+func TestExpeditionService_ProcessDueExpeditions_SameSeedRollsSameLoot(t *testing.T) {
+	// Act
+	first := processLootWithSeed(t, testLootSeed)
+	second := processLootWithSeed(t, testLootSeed)
+
+	// Assert
+	assert.Equal(t, first, second)
+
+	// The failure roll comes first, then one roll per rarity tier with an item roll
+	// for every item that tier drops
+	assert.Equal(t, string(models.LootRollTypeFailure), first[0].RollType)
+	assert.Equal(t, 0.2, *first[0].Threshold)
+	rarityRolls := 0
+	for i, roll := range first {
+		assert.Equal(t, i+1, roll.RollOrder)
+		if roll.RollType == string(models.LootRollTypeRarity) {
+			rarityRolls++
+		}
+		if roll.RollType == string(models.LootRollTypeItem) {
+			assert.NotNil(t, roll.LootItemID)
+		}
+	}
+	assert.Equal(t, 2, rarityRolls)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_DifferentSeedsRollDifferently(t *testing.T) {
+	// Act
+	first := processLootWithSeed(t, testLootSeed)
+	second := processLootWithSeed(t, testLootSeed+1)
+
+	// Assert
+	assert.NotEqual(t, first[0].Roll, second[0].Roll)
+}
+
+func newReplayTestService(
+	mockExpeditionRepo *MockExpeditionRepositoryForExpedition,
+	mockExpeditionLootRepo *MockExpeditionLootRepository,
+	mockRiftRepo *MockRiftRepository,
+	mockLootItemRepo *MockLootItemRepository,
+	mockDropTableRepo *MockLootDropTableRepository,
+	mockGameCoreService *MockGameCoreService,
+) IExpeditionService {
+	return NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		nil,
+		mockRiftRepo,
+		nil,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+}
+
+func TestExpeditionService_ReplayExpeditionLoot_MatchesRecordedRolls(t *testing.T) {
+	// Arrange
+	recorded := processLootWithSeed(t, testLootSeed)
+
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	service := newReplayTestService(mockExpeditionRepo, mockExpeditionLootRepo, mockRiftRepo, mockLootItemRepo, mockDropTableRepo, mockGameCoreService)
+
+	luck := 0.0
+	lootMultiplier := 1.0
+	expedition := &repositories.ExpeditionEntity{ID: 1, RiftID: 1, Status: string(models.ExpeditionStatusCompleted), LootSeed: testLootSeed, LootLuck: &luck, LootMultiplier: &lootMultiplier}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 60, MinQuantity: 1, MaxQuantity: 3},
+		{RiftID: 1, Rarity: "rare", DropRatePercent: 30, MinQuantity: 1, MaxQuantity: 1},
+	}
+
+	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionLootRepo.On("GetLootRollsByExpeditionId", int64(1)).Return(recorded, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0, FailureChance: 0.2})
+	mockGameCoreService.On("AdjustDropRates", dropTables, 0.0).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{
+		{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"},
+		{ID: 101, Name: "Ash Dust", Rarity: "common", ItemType: "consumable"},
+	}, nil)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "rare", "fire").Return([]*repositories.LootItemEntity{
+		{ID: 200, Name: "Flame Core", Rarity: "rare", ItemType: "consumable"},
+	}, nil)
+
+	// Act
+	result, err := service.ReplayExpeditionLoot(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.Matches)
+	assert.Equal(t, testLootSeed, result.Seed)
+	assert.Equal(t, len(recorded), len(result.Replayed))
+	assert.Equal(t, result.Recorded, result.Replayed)
+}
+
+func TestExpeditionService_ReplayExpeditionLoot_ReportsMismatch(t *testing.T) {
+	// Arrange
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	service := newReplayTestService(mockExpeditionRepo, mockExpeditionLootRepo, mockRiftRepo, nil, mockDropTableRepo, mockGameCoreService)
+
+	luck := 0.0
+	lootMultiplier := 1.0
+	threshold := 0.2
+	expedition := &repositories.ExpeditionEntity{ID: 1, RiftID: 1, Status: string(models.ExpeditionStatusCompleted), LootSeed: testLootSeed, LootLuck: &luck, LootMultiplier: &lootMultiplier}
+	// A roll that the stored seed could never have produced
+	recorded := []*repositories.ExpeditionLootRollEntity{
+		{ExpeditionID: 1, RollOrder: 1, RollType: string(models.LootRollTypeFailure), Roll: 2.0, Threshold: &threshold},
+	}
+
+	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+	mockExpeditionLootRepo.On("GetLootRollsByExpeditionId", int64(1)).Return(recorded, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(&repositories.RiftEntity{ID: 1, WorldType: "fire"}, nil)
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return([]*repositories.LootDropTableEntity{}, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0, FailureChance: 0.2})
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, 0.0).Return([]*repositories.LootDropTableEntity{})
+
+	// Act
+	result, err := service.ReplayExpeditionLoot(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.Matches)
+	assert.Len(t, result.Replayed, 1)
+}
+
+func TestExpeditionService_ReplayExpeditionLoot_NoRollLog(t *testing.T) {
+	// Arrange
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	service := newReplayTestService(mockExpeditionRepo, mockExpeditionLootRepo, nil, nil, nil, nil)
+
+	// Loot has not been rolled yet, so no inputs were saved
+	expedition := &repositories.ExpeditionEntity{ID: 1, RiftID: 1, LootSeed: testLootSeed}
+	mockExpeditionRepo.On("GetExpeditionById", int64(1)).Return(expedition, nil)
+
+	// Act
+	result, err := service.ReplayExpeditionLoot(1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNoLootRollLog)
+	assert.Nil(t, result)
+	mockExpeditionLootRepo.AssertNotCalled(t, "GetLootRollsByExpeditionId", mock.Anything)
+}
+
+func TestExpeditionService_ReplayExpeditionLoot_NotFound(t *testing.T) {
+	// Arrange
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	service := newReplayTestService(mockExpeditionRepo, nil, nil, nil, nil, nil)
+
+	mockExpeditionRepo.On("GetExpeditionById", int64(99)).Return(nil, sql.ErrNoRows)
+
+	// Act
+	result, err := service.ReplayExpeditionLoot(99)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrExpeditionNotFound)
+	assert.Nil(t, result)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_SkipsLockedExpedition(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	// Another replica already holds the row
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(nil, nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Empty(t, failed)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+	mockExpeditionRepo.AssertExpectations(t)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_SkipsFailedExpedition(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockTeamRepo := new(MockTeamRepository)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		mockTeamRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{ID: 1, UserID: 1, TeamID: 1, RiftID: 1, StartTime: time.Now().Add(-2 * time.Hour), DurationMinutes: 50}
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return([]int64{1}, nil)
+	mockExpeditionRepo.On("LockDueExpeditionById", int64(1)).Return(expedition, nil)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(nil, errors.New("database error"))
+	// Sent to the back of the queue so it can't hold up later runs
+	mockExpeditionRepo.On("MarkProcessFailed", int64(1)).Return(nil)
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, []int64{1}, failed)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+	mockExpeditionRepo.AssertExpectations(t)
+}
+
+func TestExpeditionService_ProcessDueExpeditions_RepositoryError(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockExpeditionRepo.On("GetDueExpeditionIds", 10, []int64(nil)).Return(nil, errors.New("database error"))
+
+	processed, failed, err := service.ProcessDueExpeditions(10, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Nil(t, failed)
+	mockExpeditionRepo.AssertExpectations(t)
+}
+
+// Tests for RecallExpedition
+func TestExpeditionService_RecallExpedition_GrantsProratedLoot(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		newAllRiftsUnlockedService(),
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	// Halfway through a one hour expedition
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-30 * time.Minute),
+		DurationMinutes: 60,
+		Status:          string(models.ExpeditionStatusActive),
+	}
+	team := &repositories.TeamEntity{ID: 1, TeamNumber: 1}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Test Rift", WorldType: "fire"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 100, MinQuantity: 2, MaxQuantity: 2},
+	}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "common", ItemType: "consumable"}
+	stats := &models.TeamStatsDTO{}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+	mockGameCoreService.On("CalculateRecallLootMultiplier", mock.Anything).Return(0.5)
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockGameCoreService.On("CalculatePowerOutcome", 0, 0).Return(&models.PowerOutcomeDTO{PowerRatio: 1.0, LootMultiplier: 1.0})
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", mock.Anything, team.Specialization, mock.Anything).Return(stats)
+	mockGameCoreService.On("AdjustDropRates", mock.Anything, mock.Anything).Return(dropTables)
+	mockLootItemRepo.On("GetLootItemsByRarityAndWorldType", "common", "fire").Return([]*repositories.LootItemEntity{lootItem}, nil)
+	mockInventoryRepo.On("AddLoot", int64(1), int64(100), "consumable").Return(&repositories.UserInventoryEntity{ID: 7}, nil).Once()
+	mockExpeditionLootRepo.On("CreateExpeditionLoot", int64(1), int64(100), 1).Return(nil).Once()
+	mockExpeditionLootRepo.On("CreateLootRolls", int64(1), mock.Anything).Return(nil)
+	mockExpeditionRepo.On("SaveLootRollInputs", int64(1), mock.Anything, mock.Anything).Return(nil)
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return([]*repositories.ExpeditionLootEntity{
+		{ExpeditionID: 1, LootItemID: 100, Quantity: 1},
+	}, nil)
+	mockExpeditionRepo.On("MarkRecalled", int64(1)).Return(nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Loot, 1)
+	assert.Equal(t, "Ember Shard", result.Loot[0].Name)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockExpeditionLootRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertExpectations(t)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkCompleted", mock.Anything)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_RecallExpedition_TooEarlyGrantsNoLoot(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+	mockExpeditionLootRepo := new(MockExpeditionLootRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		mockExpeditionLootRepo,
+		nil,
+		nil,
+		mockInventoryRepo,
+		nil,
+		nil,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-5 * time.Minute),
+		DurationMinutes: 60,
+		Status:          string(models.ExpeditionStatusActive),
+	}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+	mockGameCoreService.On("CalculateRecallLootMultiplier", mock.Anything).Return(0.0)
+	mockExpeditionLootRepo.On("GetLootByExpeditionId", int64(1)).Return([]*repositories.ExpeditionLootEntity{}, nil)
+	mockExpeditionRepo.On("MarkRecalled", int64(1)).Return(nil)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Loot)
+	mockExpeditionRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertNotCalled(t, "AddLoot", mock.Anything, mock.Anything, mock.Anything)
+	mockGameCoreService.AssertNotCalled(t, "CalculatePowerOutcome", mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_RecallExpedition_AlreadyFinished(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	// Timer has run out, so the expedition has to be claimed instead
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-2 * time.Hour),
+		DurationMinutes: 60,
+	}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.ErrorIs(t, err, ErrExpeditionFinished)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkRecalled", mock.Anything)
+}
+
+func TestExpeditionService_RecallExpedition_AlreadyRecalled(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          1,
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-10 * time.Minute),
+		DurationMinutes: 60,
+		Processed:       true,
+		Claimed:         true,
+		Status:          string(models.ExpeditionStatusRecalled),
+	}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.ErrorIs(t, err, ErrExpeditionFinished)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkRecalled", mock.Anything)
+}
+
+func TestExpeditionService_RecallExpedition_ExpeditionDoesntBelongToUser(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	expedition := &repositories.ExpeditionEntity{
+		ID:              1,
+		UserID:          999, // Different user
+		TeamID:          1,
+		RiftID:          1,
+		StartTime:       time.Now().Add(-10 * time.Minute),
+		DurationMinutes: 60,
+	}
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(expedition, nil)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.ErrorIs(t, err, ErrExpeditionNotOwned)
+	assert.Nil(t, result)
+	mockExpeditionRepo.AssertNotCalled(t, "MarkRecalled", mock.Anything)
+}
+
+func TestExpeditionService_RecallExpedition_NotFound(t *testing.T) {
+	mockExpeditionRepo := new(MockExpeditionRepositoryForExpedition)
+
+	service := NewExpeditionService(
+		mockExpeditionRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockExpeditionRepo.On("GetExpeditionByIdForUpdate", int64(1)).Return(nil, sql.ErrNoRows)
+
+	result, err := service.RecallExpedition(1, 1)
+
+	assert.ErrorIs(t, err, ErrExpeditionNotFound)
+	assert.Nil(t, result)
+}
+
+func TestExpeditionService_GetRiftLootTable_BaseOddsWithoutTeam(t *testing.T) {
+	// Arrange
+	mockRiftRepo := new(MockRiftRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		nil,
+		nil,
+		nil,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	rift := &repositories.RiftEntity{ID: 1, Name: "Ember Rift", WorldType: "fire", Difficulty: "easy", WeakToElement: "water"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 80, MinQuantity: 1, MaxQuantity: 3},
+		{RiftID: 1, Rarity: "rare", DropRatePercent: 10, MinQuantity: 1, MaxQuantity: 1},
+	}
+	items := []*repositories.LootItemEntity{
+		{ID: 100, Name: "Ember Shard", Rarity: "common", WorldType: "fire"},
+		{ID: 200, Name: "Flame Core", Rarity: "rare", WorldType: "fire"},
+		{ID: 300, Name: "Phoenix Feather", Rarity: "legendary", WorldType: "fire"},
+	}
+
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("GetRecommendedPower", "easy").Return(10)
+	mockLootItemRepo.On("GetLootItemsByWorldType", "fire").Return(items, nil)
+	mockInventoryRepo.On("GetCollectedLootItemIds", int64(1)).Return([]int64{200}, nil)
+
+	// Act
+	result, err := service.GetRiftLootTable(1, 1, nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, result.TeamID)
+	assert.Equal(t, 10, result.RecommendedPower)
+	assert.Equal(t, 1.0, result.LootMultiplier)
+	assert.Len(t, result.Rarities, 2)
+	assert.Equal(t, 80.0, result.Rarities[0].DropRatePercent)
+	assert.Equal(t, 1, result.Rarities[0].AdjustedMinQuantity)
+	assert.Equal(t, 3, result.Rarities[0].AdjustedMaxQuantity)
+
+	// The legendary can't drop from this rift, so it isn't listed
+	assert.Len(t, result.Items, 2)
+	assert.False(t, result.Items[0].Collected)
+	assert.True(t, result.Items[1].Collected)
+	assert.Equal(t, 1, result.CollectedCount)
+	mockGameCoreService.AssertNotCalled(t, "AdjustDropRates", mock.Anything, mock.Anything)
+}
+
+func TestExpeditionService_GetRiftLootTable_AdjustsForTeamAndRelic(t *testing.T) {
+	// Arrange
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		nil,
+		nil,
+		mockTeamRepo,
+		mockRiftRepo,
+		mockInventoryRepo,
+		mockLootItemRepo,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	relicSlot := int64(7)
+	team := &repositories.TeamEntity{ID: 2, UserID: 1, TeamNumber: 1, EquippedRelicSlot: &relicSlot}
+	relic := &repositories.LootItemEntity{ID: 500, Name: "Tide Stone", Rarity: "epic", ElementalAffinity: "water"}
+	rift := &repositories.RiftEntity{ID: 1, Name: "Ember Rift", WorldType: "fire", Difficulty: "easy", WeakToElement: "water"}
+	dropTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 80, MinQuantity: 2, MaxQuantity: 3},
+	}
+	adjustedTables := []*repositories.LootDropTableEntity{
+		{RiftID: 1, Rarity: "common", DropRatePercent: 72, MinQuantity: 2, MaxQuantity: 3},
+	}
+	stats := &models.TeamStatsDTO{Speed: 0, Luck: 10.0, Power: 10}
+
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return(dropTables, nil)
+	mockGameCoreService.On("GetRecommendedPower", "easy").Return(10)
+	mockTeamRepo.On("GetTeamById", int64(2)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(7)).Return(&repositories.UserInventoryEntity{ID: 7, LootItemID: 500}, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(500)).Return(relic, nil)
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(stats)
+	mockGameCoreService.On("ApplySpecialization", stats, team.Specialization, "fire").Return(stats)
+	mockGameCoreService.On("GetElementalBonus", "water", "water").Return(0.20)
+	// The relic's 20% bonus takes power from 10 to 12
+	mockGameCoreService.On("CalculatePowerOutcome", 12, 10).Return(&models.PowerOutcomeDTO{PowerRatio: 1.2, LootMultiplier: 1.1})
+	mockGameCoreService.On("AdjustDropRates", dropTables, 10.0).Return(adjustedTables)
+	mockLootItemRepo.On("GetLootItemsByWorldType", "fire").Return([]*repositories.LootItemEntity{}, nil)
+	mockInventoryRepo.On("GetCollectedLootItemIds", int64(1)).Return([]int64{}, nil)
+
+	// Act
+	teamId := int64(2)
+	result, err := service.GetRiftLootTable(1, 1, &teamId)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, teamId, *result.TeamID)
+	assert.Equal(t, 10.0, result.TeamLuck)
+	assert.Equal(t, 12, result.EffectivePower)
+	assert.Equal(t, 0.20, result.ElementalBonus)
+	assert.Equal(t, 1.1, result.LootMultiplier)
+	assert.Equal(t, 80.0, result.Rarities[0].BaseDropRatePercent)
+	assert.Equal(t, 72.0, result.Rarities[0].DropRatePercent)
+	assert.Equal(t, 2, result.Rarities[0].AdjustedMinQuantity)
+	assert.Equal(t, 4, result.Rarities[0].AdjustedMaxQuantity)
+	mockGameCoreService.AssertExpectations(t)
+}
+
+func TestExpeditionService_GetRiftLootTable_TeamDoesntBelongToUser(t *testing.T) {
+	// Arrange
+	mockTeamRepo := new(MockTeamRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockDropTableRepo := new(MockLootDropTableRepository)
+	mockGameCoreService := new(MockGameCoreService)
+
+	service := NewExpeditionService(
+		nil,
+		nil,
+		mockTeamRepo,
+		mockRiftRepo,
+		nil,
+		nil,
+		mockDropTableRepo,
+		nil,
+		mockGameCoreService,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(&repositories.RiftEntity{ID: 1, WorldType: "fire", Difficulty: "easy"}, nil)
+	mockDropTableRepo.On("GetDropTablesByRiftId", int64(1)).Return([]*repositories.LootDropTableEntity{}, nil)
+	mockGameCoreService.On("GetRecommendedPower", "easy").Return(10)
+	mockTeamRepo.On("GetTeamById", int64(2)).Return(&repositories.TeamEntity{ID: 2, UserID: 999}, nil)
+
+	// Act
+	teamId := int64(2)
+	result, err := service.GetRiftLootTable(1, 1, &teamId)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamNotOwned)
+	assert.Nil(t, result)
+}
+
+func TestExpeditionService_GetRiftLootTable_RiftNotFound(t *testing.T) {
+	// Arrange
+	mockRiftRepo := new(MockRiftRepository)
+
+	service := NewExpeditionService(
+		nil,
+		nil,
+		nil,
+		mockRiftRepo,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		new(MockUnitOfWork),
+		nil,
+		NewSeededRandomService(testRandomSeed),
+		NewEventHubService(),
+	)
+
+	mockRiftRepo.On("GetRiftById", int64(99)).Return(nil, sql.ErrNoRows)
+
+	// Act
+	result, err := service.GetRiftLootTable(1, 99, nil)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrRiftNotFound)
+	assert.Nil(t, result)
+}
+
+func TestAdjustQuantityRange(t *testing.T) {
+	// Test 1 - No adjustment
+	minQuantity, maxQuantity := adjustQuantityRange(1, 3, 1.0, 0.0)
+	assert.Equal(t, 1, minQuantity)
+	assert.Equal(t, 3, maxQuantity)
+
+	// Test 2 - Over-powered teams can round up to an extra item
+	minQuantity, maxQuantity = adjustQuantityRange(2, 3, 1.5, 0.0)
+	assert.Equal(t, 3, minQuantity)
+	assert.Equal(t, 5, maxQuantity)
+
+	// Test 3 - A chance of partial failure halves the minimum
+	minQuantity, maxQuantity = adjustQuantityRange(2, 4, 0.5, 0.25)
+	assert.Equal(t, 0, minQuantity)
+	assert.Equal(t, 2, maxQuantity)
 }
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// ExpeditionProcessorInterval is how often the processor checks for finished expeditions
+	ExpeditionProcessorInterval = 1 * time.Minute
+
+	// ExpeditionProcessorBatchSize is the max number of expeditions acquired per query
+	ExpeditionProcessorBatchSize = 50
+)
+
+// IExpeditionProcessorService finalizes completed expeditions, starts queued launches and
+// expires stale trades in the background
+type IExpeditionProcessorService interface {
+	Run(ctx context.Context)
+	RunOnce() (int, error)
+}
+
+// ExpeditionProcessorService periodically rolls loot for expeditions whose timers have
+// run out so players don't have to click claim before their loot exists. It is safe to
+// run on several replicas at once because expeditions are acquired with row locking.
+// Once expeditions are processed, teams with a launch queue are sent out again and
+// trades nobody answered in time hand their items back.
+type ExpeditionProcessorService struct {
+	expeditionService  IExpeditionService
+	launchQueueService ILaunchQueueService
+	tradeService       ITradeService
+	interval           time.Duration
+	batchSize          int
+}
+
+// NewExpeditionProcessorService creates a new expedition processor
+func NewExpeditionProcessorService(expeditionService IExpeditionService, launchQueueService ILaunchQueueService, tradeService ITradeService, interval time.Duration, batchSize int) IExpeditionProcessorService {
+	return &ExpeditionProcessorService{
+		expeditionService:  expeditionService,
+		launchQueueService: launchQueueService,
+		tradeService:       tradeService,
+		interval:           interval,
+		batchSize:          batchSize,
+	}
+}
+
+// Run processes due expeditions on every tick until the context is cancelled
+func (s *ExpeditionProcessorService) Run(ctx context.Context) {
+	util.LogInfo(fmt.Sprintf("Expedition processor started (interval %s)", s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(); err != nil {
+			util.LogError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			util.LogInfo("Expedition processor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce drains all currently due expeditions in batches, then starts the next
+// queued launch for teams that are now idle and expires a batch of stale trades
+// Returns the total number of expeditions processed
+func (s *ExpeditionProcessorService) RunOnce() (int, error) {
+	total := 0
+	// Expeditions that fail are left for the next run, otherwise they would come back in
+	// every batch and could fill them
+	var failedIds []int64
+	for {
+		processed, failed, err := s.expeditionService.ProcessDueExpeditions(s.batchSize, failedIds)
+		if err != nil {
+			return total, fmt.Errorf("failed to process expeditions: %w", err)
+		}
+		total += processed
+		failedIds = append(failedIds, failed...)
+
+		// A short batch means there is nothing left to pick up
+		if processed+len(failed) < s.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		util.LogInfo(fmt.Sprintf("Processed %d expeditions", total))
+	}
+
+	// Teams that keep failing stay idle, so take a single batch per run rather than draining
+	launched, err := s.launchQueueService.LaunchQueuedExpeditions(s.batchSize)
+	if err != nil {
+		return total, fmt.Errorf("failed to launch queued expeditions: %w", err)
+	}
+	if launched > 0 {
+		util.LogInfo(fmt.Sprintf("Launched %d queued expeditions", launched))
+	}
+
+	expired, err := s.tradeService.ExpireTrades(s.batchSize)
+	if err != nil {
+		return total, fmt.Errorf("failed to expire trades: %w", err)
+	}
+	if expired > 0 {
+		util.LogInfo(fmt.Sprintf("Expired %d trades", expired))
+	}
+
+	return total, nil
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExpeditionService for ExpeditionProcessorService tests
+type MockExpeditionService struct {
+	mock.Mock
+}
+
+func (m *MockExpeditionService) StartExpedition(userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error) {
+	args := m.Called(userId, teamId, riftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExpeditionResponseDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) StartExpeditionWithTx(tx *database.AppDataSource, userId, teamId, riftId int64) (*models.ExpeditionResponseDTO, error) {
+	args := m.Called(tx, userId, teamId, riftId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExpeditionResponseDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) GetActiveExpeditions(userId int64) ([]*models.ExpeditionResponseDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ExpeditionResponseDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) GetExpeditionHistory(userId int64, limit int) ([]*models.ExpeditionResponseDTO, error) {
+	args := m.Called(userId, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ExpeditionResponseDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) ClaimExpeditionRewards(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error) {
+	args := m.Called(userId, expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExpeditionRewardsDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) RecallExpedition(userId, expeditionId int64) (*models.ExpeditionRewardsDTO, error) {
+	args := m.Called(userId, expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExpeditionRewardsDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) ProcessDueExpeditions(limit int, skipIds []int64) (int, []int64, error) {
+	args := m.Called(limit, skipIds)
+	if args.Get(1) == nil {
+		return args.Int(0), nil, args.Error(2)
+	}
+	return args.Int(0), args.Get(1).([]int64), args.Error(2)
+}
+
+func (m *MockExpeditionService) GetRiftLootTable(userId, riftId int64, teamId *int64) (*models.RiftLootTableDTO, error) {
+	args := m.Called(userId, riftId, teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RiftLootTableDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) ReplayExpeditionLoot(expeditionId int64) (*models.LootReplayDTO, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LootReplayDTO), args.Error(1)
+}
+
+func (m *MockExpeditionService) GetEchoEncounterCount(userId int64) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+// MockLaunchQueueService for ExpeditionProcessorService tests
+type MockLaunchQueueService struct {
+	mock.Mock
+}
+
+func (m *MockLaunchQueueService) GetTeamQueue(userId, teamId int64) (*models.TeamQueueDTO, error) {
+	args := m.Called(userId, teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamQueueDTO), args.Error(1)
+}
+
+func (m *MockLaunchQueueService) QueueLaunch(userId, teamId int64, dto *models.QueueLaunchDTO) (*models.TeamQueueDTO, error) {
+	args := m.Called(userId, teamId, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamQueueDTO), args.Error(1)
+}
+
+func (m *MockLaunchQueueService) RemoveQueueEntry(userId, teamId, entryId int64) (*models.TeamQueueDTO, error) {
+	args := m.Called(userId, teamId, entryId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamQueueDTO), args.Error(1)
+}
+
+func (m *MockLaunchQueueService) ClearTeamQueue(userId, teamId int64) error {
+	args := m.Called(userId, teamId)
+	return args.Error(0)
+}
+
+func (m *MockLaunchQueueService) LaunchQueuedExpeditions(limit int) (int, error) {
+	args := m.Called(limit)
+	return args.Int(0), args.Error(1)
+}
+
+// MockTradeService for ExpeditionProcessorService tests
+type MockTradeService struct {
+	mock.Mock
+}
+
+func (m *MockTradeService) GetTrades(userId int64) (*models.TradeListDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeListDTO), args.Error(1)
+}
+
+func (m *MockTradeService) GetTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	args := m.Called(userId, tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeResponseDTO), args.Error(1)
+}
+
+func (m *MockTradeService) GetTradeableItems(userId int64) (*models.TradePartnerDTO, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradePartnerDTO), args.Error(1)
+}
+
+func (m *MockTradeService) ProposeTrade(userId int64, dto *models.ProposeTradeDTO) (*models.TradeResponseDTO, error) {
+	args := m.Called(userId, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeResponseDTO), args.Error(1)
+}
+
+func (m *MockTradeService) AcceptTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	args := m.Called(userId, tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeResponseDTO), args.Error(1)
+}
+
+func (m *MockTradeService) DeclineTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	args := m.Called(userId, tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeResponseDTO), args.Error(1)
+}
+
+func (m *MockTradeService) CancelTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	args := m.Called(userId, tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TradeResponseDTO), args.Error(1)
+}
+
+func (m *MockTradeService) ExpireTrades(limit int) (int, error) {
+	args := m.Called(limit)
+	return args.Int(0), args.Error(1)
+}
+
+func TestExpeditionProcessorService_RunOnce_DrainsFullBatches(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 2)
+
+	// First batch is full so the processor should ask again, second batch is short
+	mockExpeditionService.On("ProcessDueExpeditions", 2, []int64(nil)).Return(2, nil, nil).Once()
+	mockExpeditionService.On("ProcessDueExpeditions", 2, []int64(nil)).Return(1, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 2).Return(0, nil).Once()
+	mockTradeService.On("ExpireTrades", 2).Return(0, nil).Once()
+
+	total, err := service.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	mockExpeditionService.AssertExpectations(t)
+	mockExpeditionService.AssertNumberOfCalls(t, "ProcessDueExpeditions", 2)
+}
+
+func TestExpeditionProcessorService_RunOnce_FailedExpeditionDoesNotBlockTheRest(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 2)
+
+	// Expedition 7 fails, which fills the first batch, so the processor asks again
+	// without it and keeps going with the expeditions behind it
+	mockExpeditionService.On("ProcessDueExpeditions", 2, []int64(nil)).Return(1, []int64{7}, nil).Once()
+	mockExpeditionService.On("ProcessDueExpeditions", 2, []int64{7}).Return(2, nil, nil).Once()
+	mockExpeditionService.On("ProcessDueExpeditions", 2, []int64{7}).Return(1, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 2).Return(0, nil).Once()
+	mockTradeService.On("ExpireTrades", 2).Return(0, nil).Once()
+
+	total, err := service.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	mockExpeditionService.AssertExpectations(t)
+	mockExpeditionService.AssertNumberOfCalls(t, "ProcessDueExpeditions", 3)
+}
+
+func TestExpeditionProcessorService_RunOnce_NothingDue(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(0, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(0, nil).Once()
+	mockTradeService.On("ExpireTrades", 50).Return(0, nil).Once()
+
+	total, err := service.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	mockExpeditionService.AssertNumberOfCalls(t, "ProcessDueExpeditions", 1)
+}
+
+func TestExpeditionProcessorService_RunOnce_Error(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(0, nil, errors.New("database error"))
+
+	total, err := service.RunOnce()
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestExpeditionProcessorService_RunOnce_LaunchesQueuedExpeditions(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(3, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(2, nil).Once()
+	mockTradeService.On("ExpireTrades", 50).Return(0, nil).Once()
+
+	total, err := service.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	mockLaunchQueueService.AssertExpectations(t)
+}
+
+func TestExpeditionProcessorService_RunOnce_LaunchError(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(1, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(0, errors.New("database error"))
+
+	total, err := service.RunOnce()
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestExpeditionProcessorService_Run_StopsWhenContextCancelled(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Hour, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(0, nil, nil)
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(0, nil)
+	mockTradeService.On("ExpireTrades", 50).Return(0, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		service.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("processor did not stop after context was cancelled")
+	}
+
+	// Runs one pass immediately on startup before checking the context
+	mockExpeditionService.AssertNumberOfCalls(t, "ProcessDueExpeditions", 1)
+}
+
+func TestExpeditionProcessorService_RunOnce_ExpiresTrades(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(0, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(0, nil).Once()
+	mockTradeService.On("ExpireTrades", 50).Return(4, nil).Once()
+
+	total, err := service.RunOnce()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	mockTradeService.AssertExpectations(t)
+}
+
+func TestExpeditionProcessorService_RunOnce_ExpireTradesError(t *testing.T) {
+	mockExpeditionService := new(MockExpeditionService)
+	mockLaunchQueueService := new(MockLaunchQueueService)
+	mockTradeService := new(MockTradeService)
+	service := NewExpeditionProcessorService(mockExpeditionService, mockLaunchQueueService, mockTradeService, time.Minute, 50)
+
+	mockExpeditionService.On("ProcessDueExpeditions", 50, []int64(nil)).Return(2, nil, nil).Once()
+	mockLaunchQueueService.On("LaunchQueuedExpeditions", 50).Return(0, nil).Once()
+	mockTradeService.On("ExpireTrades", 50).Return(0, errors.New("database error"))
+
+	total, err := service.RunOnce()
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, total)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"math"
 
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
@@ -11,15 +13,121 @@ type IGameCoreService interface {
 	CalculateTeamStats(team *repositories.TeamEntity, equippedItems map[string]*repositories.LootItemEntity) *models.TeamStatsDTO
 	CalculateExpeditionDuration(baseStats *models.TeamStatsDTO, baseDuration int) int
 	GetElementalBonus(relicAffinity string, riftWeakness string) float64
+	AdjustDropRates(dropTables []*repositories.LootDropTableEntity, luck float64) []*repositories.LootDropTableEntity
+	GetRecommendedPower(difficulty string) int
+	CalculatePowerOutcome(effectivePower int, recommendedPower int) *models.PowerOutcomeDTO
+	GetSpecializationBonuses(specialization string) []*models.SpecializationBonusDTO
+	ApplySpecialization(stats *models.TeamStatsDTO, specialization string, worldType string) *models.TeamStatsDTO
+	CalculateStatUpgrade(stat string, currentValue float64, increase float64, recipeMax float64) (float64, error)
+	CalculateRecallLootMultiplier(progress float64) float64
+	GetLaunchQueueLimit(teamNumber int) int
+}
+
+// ErrStatAtCap is returned by CalculateStatUpgrade when the stat can't be raised any further
+var ErrStatAtCap = errors.New("stat is already at its maximum")
+
+const (
+	// MaxTeamSpeed caps a team's total speed so expeditions always take at least a quarter of their base duration
+	MaxTeamSpeed = 75.0
+	// MaxTeamLuck caps a team's total luck at the most the luck model can use
+	MaxTeamLuck = 100.0
+	// LaunchQueueBaseLimit is added to a team's number to get how many launches it can queue
+	LaunchQueueBaseLimit = 1
+)
+
+// RecommendedPowerByDifficulty is the team power a rift expects before loot is reduced
+var RecommendedPowerByDifficulty = map[models.DifficultyLevel]int{
+	models.DifficultyTutorial:  0,
+	models.DifficultyEasy:      10,
+	models.DifficultyMedium:    25,
+	models.DifficultyHard:      50,
+	models.DifficultyLegendary: 90,
+}
+
+// SpecializationAllWorlds is the world type for specialization bonuses that apply everywhere
+const SpecializationAllWorlds = "all"
+
+// SpecializationBonuses follows GDD Appendix B. The "success rate" bonuses are applied as
+// a power bonus, which raises the team's power ratio and so its loot and survival odds.
+var SpecializationBonuses = map[models.Specialization][]*models.SpecializationBonusDTO{
+	models.SpecializationTank: {
+		{WorldType: string(models.WorldTypeFire), PowerPercent: 15.0},
+		{WorldType: string(models.WorldTypeNature), PowerPercent: 10.0},
+	},
+	models.SpecializationScout: {
+		{WorldType: string(models.WorldTypeIce), PowerPercent: 15.0},
+		{WorldType: string(models.WorldTypeVoid), PowerPercent: 10.0},
+		{WorldType: SpecializationAllWorlds, LuckBonus: 5.0},
+	},
+	models.SpecializationScientist: {
+		{WorldType: string(models.WorldTypeTech), PowerPercent: 15.0},
+		{WorldType: string(models.WorldTypeVoid), PowerPercent: 10.0},
+		{WorldType: SpecializationAllWorlds, SpeedBonus: 5.0},
+	},
+}
+
+const (
+	// MaxOverpoweredLootMultiplier caps the loot bonus for teams stronger than the rift
+	MaxOverpoweredLootMultiplier = 1.5
+	// MinUnderpoweredLootMultiplier is the least loot an under-powered team can be reduced to
+	MinUnderpoweredLootMultiplier = 0.25
+	// MaxPartialFailureChance is the chance of partial failure for a team with no power at all
+	MaxPartialFailureChance = 0.5
+	// PartialFailureLootMultiplier is applied on top of the power multiplier when an expedition partially fails
+	PartialFailureLootMultiplier = 0.5
+	// MinRecallProgress is how far through an expedition a team must be before a recall brings back any loot
+	MinRecallProgress = 0.25
+)
+
+// LuckModel controls how team Luck shifts drop rates toward higher rarities
+type LuckModel struct {
+	// MaxLuck caps the luck value fed into the model
+	MaxLuck float64
+	// RateChangePerLuck is the fractional change in a rarity's drop rate per point of luck.
+	// Negative values make a rarity rarer as luck goes up (e.g. -0.01 = -1% per point).
+	RateChangePerLuck map[string]float64
+	// MinRateMultiplier is the lowest fraction of its base rate a rarity can be pushed down to
+	MinRateMultiplier float64
+	// MaxRatePercent is the highest drop rate percent luck can push a rarity up to
+	MaxRatePercent map[string]float64
+}
+
+// DefaultLuckModel follows the GDD: luck takes drop chance away from common/uncommon
+// and hands it to rare and above, with hard caps so high-luck teams can't farm legendaries
+func DefaultLuckModel() *LuckModel {
+	return &LuckModel{
+		MaxLuck: 100.0,
+		RateChangePerLuck: map[string]float64{
+			string(models.ItemRarityCommon):    -0.010,
+			string(models.ItemRarityUncommon):  -0.005,
+			string(models.ItemRarityRare):      0.015,
+			string(models.ItemRarityEpic):      0.040,
+			string(models.ItemRarityLegendary): 0.050,
+		},
+		MinRateMultiplier: 0.5,
+		MaxRatePercent: map[string]float64{
+			string(models.ItemRarityCommon):    100.0,
+			string(models.ItemRarityUncommon):  100.0,
+			string(models.ItemRarityRare):      60.0,
+			string(models.ItemRarityEpic):      40.0,
+			string(models.ItemRarityLegendary): 10.0,
+		},
+	}
 }
 
 type GameCoreService struct {
 	lootItemRepository repositories.ILootItemRepository
+	luckModel          *LuckModel
 }
 
 func NewGameCoreService(lootItemRepository repositories.ILootItemRepository) IGameCoreService {
+	return NewGameCoreServiceWithLuckModel(lootItemRepository, DefaultLuckModel())
+}
+
+func NewGameCoreServiceWithLuckModel(lootItemRepository repositories.ILootItemRepository, luckModel *LuckModel) IGameCoreService {
 	return &GameCoreService{
 		lootItemRepository: lootItemRepository,
+		luckModel:          luckModel,
 	}
 }
 
@@ -40,12 +148,12 @@ func (s *GameCoreService) CalculateTeamStats(team *repositories.TeamEntity, equi
 		}
 	}
 
-	return stats
+	return capTeamStats(stats)
 }
 
-// CalculateExpeditionDuration applies speed bonus reduction (min 5 minutes)
+// CalculateExpeditionDuration applies speed bonus reduction (speed capped at MaxTeamSpeed, min 1 minute)
 func (s *GameCoreService) CalculateExpeditionDuration(totalStats *models.TeamStatsDTO, baseDuration int) int {
-	speedModifier := totalStats.Speed / 100.0
+	speedModifier := math.Min(totalStats.Speed, MaxTeamSpeed) / 100.0
 	actualDuration := float64(baseDuration) * (1.0 - speedModifier)
 
 	// Floor at 1 minutes
@@ -61,3 +169,136 @@ func (s *GameCoreService) GetElementalBonus(relicAffinity string, riftWeakness s
 	}
 	return 0.0
 }
+
+// AdjustDropRates returns copies of the drop tables with drop_rate_percent scaled by luck.
+// The input tables are not modified. Rates never drop below MinRateMultiplier of their
+// base value, never exceed the per-rarity cap, and always stay within 0-100.
+func (s *GameCoreService) AdjustDropRates(dropTables []*repositories.LootDropTableEntity, luck float64) []*repositories.LootDropTableEntity {
+	luck = math.Max(0.0, math.Min(luck, s.luckModel.MaxLuck))
+
+	adjusted := make([]*repositories.LootDropTableEntity, len(dropTables))
+	for i, dropTable := range dropTables {
+		copied := *dropTable
+		baseRate := dropTable.DropRatePercent
+
+		multiplier := 1.0 + s.luckModel.RateChangePerLuck[dropTable.Rarity]*luck
+		multiplier = math.Max(multiplier, s.luckModel.MinRateMultiplier)
+		rate := baseRate * multiplier
+
+		// Luck can only push a rarity up to its cap, but a base rate that is already
+		// above the cap is left alone rather than being cut down
+		if maxRate, exists := s.luckModel.MaxRatePercent[dropTable.Rarity]; exists && rate > baseRate {
+			rate = math.Min(rate, math.Max(maxRate, baseRate))
+		}
+
+		copied.DropRatePercent = math.Max(0.0, math.Min(100.0, rate))
+		adjusted[i] = &copied
+	}
+
+	return adjusted
+}
+
+// GetRecommendedPower returns the recommended team power for a rift difficulty (0 if unknown)
+func (s *GameCoreService) GetRecommendedPower(difficulty string) int {
+	return RecommendedPowerByDifficulty[models.DifficultyLevel(difficulty)]
+}
+
+// CalculatePowerOutcome compares a team's effective power against a rift's recommended power.
+// Teams at or above the recommendation get up to 50% more loot; teams below it get
+// proportionally less loot and a chance that the expedition partially fails.
+func (s *GameCoreService) CalculatePowerOutcome(effectivePower int, recommendedPower int) *models.PowerOutcomeDTO {
+	if recommendedPower <= 0 {
+		return &models.PowerOutcomeDTO{
+			PowerRatio:     1.0,
+			LootMultiplier: 1.0,
+			FailureChance:  0.0,
+		}
+	}
+
+	ratio := math.Max(0.0, float64(effectivePower)/float64(recommendedPower))
+
+	if ratio >= 1.0 {
+		return &models.PowerOutcomeDTO{
+			PowerRatio:     ratio,
+			LootMultiplier: math.Min(1.0+(ratio-1.0)*0.5, MaxOverpoweredLootMultiplier),
+			FailureChance:  0.0,
+		}
+	}
+
+	return &models.PowerOutcomeDTO{
+		PowerRatio:     ratio,
+		LootMultiplier: math.Max(ratio, MinUnderpoweredLootMultiplier),
+		FailureChance:  (1.0 - ratio) * MaxPartialFailureChance,
+	}
+}
+
+// GetSpecializationBonuses returns the per-world bonuses for a specialization (nil if none)
+func (s *GameCoreService) GetSpecializationBonuses(specialization string) []*models.SpecializationBonusDTO {
+	return SpecializationBonuses[models.Specialization(specialization)]
+}
+
+// ApplySpecialization returns a copy of the stats with the specialization's bonuses for
+// the given world added. Speed and luck bonuses are flat, power bonuses are a percentage.
+func (s *GameCoreService) ApplySpecialization(stats *models.TeamStatsDTO, specialization string, worldType string) *models.TeamStatsDTO {
+	adjusted := *stats
+
+	powerPercent := 0.0
+	for _, bonus := range s.GetSpecializationBonuses(specialization) {
+		if bonus.WorldType != worldType && bonus.WorldType != SpecializationAllWorlds {
+			continue
+		}
+		adjusted.Speed += bonus.SpeedBonus
+		adjusted.Luck += bonus.LuckBonus
+		powerPercent += bonus.PowerPercent
+	}
+
+	if powerPercent > 0 {
+		adjusted.Power = int(math.Round(float64(stats.Power) * (1.0 + powerPercent/100.0)))
+	}
+
+	return capTeamStats(&adjusted)
+}
+
+// CalculateStatUpgrade returns the value a team's base stat ends up at after an upgrade.
+// The result never goes past the recipe's max or the hard cap for the stat, so the last
+// upgrade before the cap may give less than its full increase.
+func (s *GameCoreService) CalculateStatUpgrade(stat string, currentValue float64, increase float64, recipeMax float64) (float64, error) {
+	maxValue := recipeMax
+	switch stat {
+	case "speed":
+		maxValue = math.Min(maxValue, MaxTeamSpeed)
+	case "luck":
+		maxValue = math.Min(maxValue, MaxTeamLuck)
+	default:
+		return 0, fmt.Errorf("unknown upgrade stat %q", stat)
+	}
+
+	if currentValue >= maxValue {
+		return currentValue, ErrStatAtCap
+	}
+
+	return math.Min(currentValue+increase, maxValue), nil
+}
+
+// CalculateRecallLootMultiplier returns the share of loot a recalled expedition brings back.
+// Progress is the fraction of the expedition's duration that had passed when it was recalled.
+// Teams recalled before MinRecallProgress come back empty handed, later recalls are prorated.
+func (s *GameCoreService) CalculateRecallLootMultiplier(progress float64) float64 {
+	if progress < MinRecallProgress {
+		return 0.0
+	}
+	return math.Min(progress, 1.0)
+}
+
+// GetLaunchQueueLimit returns how many launches a team can hold in its queue.
+// Later teams are unlocked further into the game and get a longer queue.
+func (s *GameCoreService) GetLaunchQueueLimit(teamNumber int) int {
+	return LaunchQueueBaseLimit + teamNumber
+}
+
+// capTeamStats clamps speed and luck to their hard caps
+func capTeamStats(stats *models.TeamStatsDTO) *models.TeamStatsDTO {
+	stats.Speed = math.Max(0.0, math.Min(stats.Speed, MaxTeamSpeed))
+	stats.Luck = math.Max(0.0, math.Min(stats.Luck, MaxTeamLuck))
+	return stats
+}
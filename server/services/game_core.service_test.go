@@ -246,7 +246,7 @@ func TestGameCoreService_CalculateExpeditionDuration_FiftyPercentSpeedBonus(t *t
 	assert.Equal(t, 30, result) // 60 * (1 - 0.50) = 30
 }
 
-// Test CalculateExpeditionDuration - 100% Speed Bonus (Should Cap at 5 Minutes)
+// Test CalculateExpeditionDuration - 100% Speed Bonus (Speed Capped at MaxTeamSpeed)
 func TestGameCoreService_CalculateExpeditionDuration_HundredPercentSpeedBonus(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockLootItemRepository)
@@ -264,7 +264,7 @@ func TestGameCoreService_CalculateExpeditionDuration_HundredPercentSpeedBonus(t
 	result := service.CalculateExpeditionDuration(stats, baseDuration)
 
 	// Assert
-	assert.Equal(t, 1, result) // Should be capped at minimum 1 minute
+	assert.Equal(t, 15, result) // Speed is capped at 75%, so 60 * (1 - 0.75) = 15
 }
 
 // Test CalculateExpeditionDuration - Speed Bonus That Would Go Below 5 Minutes
@@ -279,13 +279,13 @@ func TestGameCoreService_CalculateExpeditionDuration_BelowMinimum(t *testing.T)
 		Power: 20,
 	}
 
-	baseDuration := 10 // 10 minutes
+	baseDuration := 2 // 2 minutes
 
 	// Act
 	result := service.CalculateExpeditionDuration(stats, baseDuration)
 
 	// Assert
-	assert.Equal(t, 1, result) // 10 * (1 - 0.95) = 0.5, but capped at 1
+	assert.Equal(t, 1, result) // 2 * (1 - 0.75) = 0.5, but floored at 1
 }
 
 // Test CalculateExpeditionDuration - Nil Stats
@@ -381,3 +381,423 @@ func TestGameCoreService_GetElementalBonus_EmptyRiftWeakness(t *testing.T) {
 	// Assert
 	assert.Equal(t, 0.0, result) // No bonus
 }
+
+func newTestDropTables() []*repositories.LootDropTableEntity {
+	return []*repositories.LootDropTableEntity{
+		{ID: 1, RiftID: 1, Rarity: "common", DropRatePercent: 80.0, MinQuantity: 1, MaxQuantity: 3},
+		{ID: 2, RiftID: 1, Rarity: "uncommon", DropRatePercent: 40.0, MinQuantity: 1, MaxQuantity: 2},
+		{ID: 3, RiftID: 1, Rarity: "rare", DropRatePercent: 15.0, MinQuantity: 1, MaxQuantity: 1},
+		{ID: 4, RiftID: 1, Rarity: "epic", DropRatePercent: 4.0, MinQuantity: 1, MaxQuantity: 1},
+		{ID: 5, RiftID: 1, Rarity: "legendary", DropRatePercent: 0.5, MinQuantity: 1, MaxQuantity: 1},
+	}
+}
+
+// Test AdjustDropRates - Zero Luck
+func TestGameCoreService_AdjustDropRates_ZeroLuckUnchanged(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := newTestDropTables()
+
+	// Act
+	result := service.AdjustDropRates(dropTables, 0.0)
+
+	// Assert
+	assert.Len(t, result, len(dropTables))
+	for i := range dropTables {
+		assert.Equal(t, dropTables[i].DropRatePercent, result[i].DropRatePercent)
+		assert.Equal(t, dropTables[i].Rarity, result[i].Rarity)
+	}
+}
+
+// Test AdjustDropRates - Luck Shifts Toward Higher Rarities
+func TestGameCoreService_AdjustDropRates_LuckFavorsHigherRarities(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := newTestDropTables()
+
+	// Act
+	result := service.AdjustDropRates(dropTables, 10.0)
+
+	// Assert
+	assert.InDelta(t, 72.0, result[0].DropRatePercent, 0.0001)  // common -10%
+	assert.InDelta(t, 38.0, result[1].DropRatePercent, 0.0001)  // uncommon -5%
+	assert.InDelta(t, 17.25, result[2].DropRatePercent, 0.0001) // rare +15%
+	assert.InDelta(t, 5.6, result[3].DropRatePercent, 0.0001)   // epic +40%
+	assert.InDelta(t, 0.75, result[4].DropRatePercent, 0.0001)  // legendary +50%
+}
+
+// Test AdjustDropRates - Caps and Floor
+func TestGameCoreService_AdjustDropRates_RespectsCapsAndFloor(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := newTestDropTables()
+
+	// Act - well beyond MaxLuck
+	result := service.AdjustDropRates(dropTables, 10000.0)
+
+	// Assert
+	assert.InDelta(t, 40.0, result[0].DropRatePercent, 0.0001) // floored at 50% of base
+	assert.InDelta(t, 20.0, result[1].DropRatePercent, 0.0001) // floored at 50% of base
+	assert.InDelta(t, 37.5, result[2].DropRatePercent, 0.0001) // 15 * (1 + 0.015*100)
+	assert.InDelta(t, 20.0, result[3].DropRatePercent, 0.0001) // 4 * (1 + 0.04*100)
+	assert.InDelta(t, 3.0, result[4].DropRatePercent, 0.0001)  // 0.5 * (1 + 0.05*100)
+	for _, dropTable := range result {
+		assert.GreaterOrEqual(t, dropTable.DropRatePercent, 0.0)
+		assert.LessOrEqual(t, dropTable.DropRatePercent, 100.0)
+	}
+}
+
+// Test AdjustDropRates - Rarity Cap
+func TestGameCoreService_AdjustDropRates_CapsLegendaryRate(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := []*repositories.LootDropTableEntity{
+		{ID: 1, Rarity: "legendary", DropRatePercent: 5.0},
+		{ID: 2, Rarity: "legendary", DropRatePercent: 12.0},
+	}
+
+	// Act
+	result := service.AdjustDropRates(dropTables, 100.0)
+
+	// Assert
+	assert.Equal(t, 10.0, result[0].DropRatePercent) // capped
+	assert.Equal(t, 12.0, result[1].DropRatePercent) // already above cap, not reduced
+}
+
+// Test AdjustDropRates - Negative Luck
+func TestGameCoreService_AdjustDropRates_NegativeLuckClamped(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := newTestDropTables()
+
+	// Act
+	result := service.AdjustDropRates(dropTables, -50.0)
+
+	// Assert
+	for i := range dropTables {
+		assert.Equal(t, dropTables[i].DropRatePercent, result[i].DropRatePercent)
+	}
+}
+
+// Test AdjustDropRates - Input Not Modified
+func TestGameCoreService_AdjustDropRates_DoesNotMutateInput(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	dropTables := newTestDropTables()
+
+	// Act
+	result := service.AdjustDropRates(dropTables, 50.0)
+
+	// Assert
+	assert.Equal(t, 80.0, dropTables[0].DropRatePercent)
+	assert.Equal(t, 0.5, dropTables[4].DropRatePercent)
+	assert.NotSame(t, dropTables[0], result[0])
+}
+
+// Test AdjustDropRates - Custom Luck Model
+func TestGameCoreService_AdjustDropRates_CustomLuckModel(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreServiceWithLuckModel(mockRepo, &LuckModel{
+		MaxLuck:           10.0,
+		RateChangePerLuck: map[string]float64{"rare": 0.1},
+		MinRateMultiplier: 0.0,
+		MaxRatePercent:    map[string]float64{},
+	})
+	dropTables := []*repositories.LootDropTableEntity{
+		{ID: 1, Rarity: "common", DropRatePercent: 80.0},
+		{ID: 2, Rarity: "rare", DropRatePercent: 60.0},
+	}
+
+	// Act
+	result := service.AdjustDropRates(dropTables, 50.0)
+
+	// Assert
+	assert.Equal(t, 80.0, result[0].DropRatePercent)  // no scaling configured
+	assert.Equal(t, 100.0, result[1].DropRatePercent) // 60 * 2 clamped to 100
+}
+
+// Test GetRecommendedPower - Known Difficulties
+func TestGameCoreService_GetRecommendedPower(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act & Assert
+	assert.Equal(t, 0, service.GetRecommendedPower("tutorial"))
+	assert.Equal(t, 10, service.GetRecommendedPower("easy"))
+	assert.Equal(t, 25, service.GetRecommendedPower("medium"))
+	assert.Equal(t, 50, service.GetRecommendedPower("hard"))
+	assert.Equal(t, 90, service.GetRecommendedPower("legendary"))
+	assert.Equal(t, 0, service.GetRecommendedPower("unknown"))
+}
+
+// Test CalculatePowerOutcome - No Recommendation
+func TestGameCoreService_CalculatePowerOutcome_NoRecommendedPower(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result := service.CalculatePowerOutcome(0, 0)
+
+	// Assert
+	assert.Equal(t, 1.0, result.PowerRatio)
+	assert.Equal(t, 1.0, result.LootMultiplier)
+	assert.Equal(t, 0.0, result.FailureChance)
+}
+
+// Test CalculatePowerOutcome - At Recommended Power
+func TestGameCoreService_CalculatePowerOutcome_AtRecommendedPower(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result := service.CalculatePowerOutcome(50, 50)
+
+	// Assert
+	assert.Equal(t, 1.0, result.PowerRatio)
+	assert.Equal(t, 1.0, result.LootMultiplier)
+	assert.Equal(t, 0.0, result.FailureChance)
+}
+
+// Test CalculatePowerOutcome - Overpowered
+func TestGameCoreService_CalculatePowerOutcome_Overpowered(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result := service.CalculatePowerOutcome(75, 50)
+	capped := service.CalculatePowerOutcome(500, 50)
+
+	// Assert
+	assert.Equal(t, 1.5, result.PowerRatio)
+	assert.Equal(t, 1.25, result.LootMultiplier)
+	assert.Equal(t, 0.0, result.FailureChance)
+	assert.Equal(t, 1.5, capped.LootMultiplier) // capped at +50%
+}
+
+// Test CalculatePowerOutcome - Under-powered
+func TestGameCoreService_CalculatePowerOutcome_Underpowered(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result := service.CalculatePowerOutcome(25, 50)
+	noPower := service.CalculatePowerOutcome(0, 50)
+
+	// Assert
+	assert.Equal(t, 0.5, result.PowerRatio)
+	assert.Equal(t, 0.5, result.LootMultiplier)
+	assert.Equal(t, 0.25, result.FailureChance)
+	assert.Equal(t, 0.25, noPower.LootMultiplier) // floored
+	assert.Equal(t, 0.5, noPower.FailureChance)
+}
+
+// Test ApplySpecialization - Bonus World
+func TestGameCoreService_ApplySpecialization_BonusWorld(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 40}
+
+	// Act
+	result := service.ApplySpecialization(stats, "tank", "fire")
+
+	// Assert
+	assert.Equal(t, 46, result.Power) // +15%
+	assert.Equal(t, 10.0, result.Speed)
+	assert.Equal(t, 5.0, result.Luck)
+	assert.Equal(t, 40, stats.Power) // input is not modified
+}
+
+// Test ApplySpecialization - Other World
+func TestGameCoreService_ApplySpecialization_OtherWorld(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 40}
+
+	// Act
+	result := service.ApplySpecialization(stats, "tank", "ice")
+
+	// Assert
+	assert.Equal(t, *stats, *result)
+}
+
+// Test ApplySpecialization - All Worlds Bonus
+func TestGameCoreService_ApplySpecialization_AllWorldsBonus(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 40}
+
+	// Act
+	scoutInFire := service.ApplySpecialization(stats, "scout", "fire")
+	scoutInVoid := service.ApplySpecialization(stats, "scout", "void")
+	scientistInTech := service.ApplySpecialization(stats, "scientist", "tech")
+
+	// Assert
+	assert.Equal(t, 10.0, scoutInFire.Luck)
+	assert.Equal(t, 40, scoutInFire.Power)
+	assert.Equal(t, 10.0, scoutInVoid.Luck)
+	assert.Equal(t, 44, scoutInVoid.Power) // +10%
+	assert.Equal(t, 15.0, scientistInTech.Speed)
+	assert.Equal(t, 46, scientistInTech.Power)
+}
+
+// Test ApplySpecialization - No Specialization
+func TestGameCoreService_ApplySpecialization_None(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+	stats := &models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 40}
+
+	// Act
+	result := service.ApplySpecialization(stats, "none", "fire")
+
+	// Assert
+	assert.Equal(t, *stats, *result)
+	assert.Nil(t, service.GetSpecializationBonuses("none"))
+}
+
+// Test CalculateTeamStats - Total Stats Capped
+func TestGameCoreService_CalculateTeamStats_Capped(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	team := &repositories.TeamEntity{SpeedBonus: 60.0, LuckBonus: 90.0, PowerBonus: 10}
+	equippedItems := map[string]*repositories.LootItemEntity{
+		"weapon": {SpeedBonus: 30.0, LuckBonus: 20.0, PowerBonus: 500},
+	}
+
+	// Act
+	result := service.CalculateTeamStats(team, equippedItems)
+
+	// Assert
+	assert.Equal(t, MaxTeamSpeed, result.Speed)
+	assert.Equal(t, MaxTeamLuck, result.Luck)
+	assert.Equal(t, 510, result.Power) // Power is not capped
+}
+
+// Test CalculateStatUpgrade - Full Increase
+func TestGameCoreService_CalculateStatUpgrade_FullIncrease(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result, err := service.CalculateStatUpgrade("speed", 10.0, 5.0, 50.0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 15.0, result)
+}
+
+// Test CalculateStatUpgrade - Partial Increase Up To Recipe Max
+func TestGameCoreService_CalculateStatUpgrade_PartialIncrease(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result, err := service.CalculateStatUpgrade("luck", 28.0, 3.0, 30.0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 30.0, result)
+}
+
+// Test CalculateStatUpgrade - Recipe Max Above Hard Cap
+func TestGameCoreService_CalculateStatUpgrade_HardCap(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result, err := service.CalculateStatUpgrade("speed", 73.0, 5.0, 200.0)
+	_, capErr := service.CalculateStatUpgrade("speed", 75.0, 5.0, 200.0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, MaxTeamSpeed, result)
+	assert.ErrorIs(t, capErr, ErrStatAtCap)
+}
+
+// Test CalculateStatUpgrade - Already At Recipe Max
+func TestGameCoreService_CalculateStatUpgrade_AtMax(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result, err := service.CalculateStatUpgrade("speed", 50.0, 5.0, 50.0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrStatAtCap)
+	assert.Equal(t, 50.0, result)
+}
+
+// Test CalculateStatUpgrade - Unknown Stat
+func TestGameCoreService_CalculateStatUpgrade_UnknownStat(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	_, err := service.CalculateStatUpgrade("power", 0.0, 5.0, 50.0)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+// Test CalculateRecallLootMultiplier - Recalled Too Early
+func TestGameCoreService_CalculateRecallLootMultiplier_TooEarly(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	result := service.CalculateRecallLootMultiplier(0.2)
+
+	// Assert
+	assert.Equal(t, 0.0, result)
+}
+
+// Test CalculateRecallLootMultiplier - Prorated By Progress
+func TestGameCoreService_CalculateRecallLootMultiplier_Prorated(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act
+	atThreshold := service.CalculateRecallLootMultiplier(MinRecallProgress)
+	halfway := service.CalculateRecallLootMultiplier(0.5)
+	overdue := service.CalculateRecallLootMultiplier(1.3)
+
+	// Assert
+	assert.Equal(t, MinRecallProgress, atThreshold)
+	assert.Equal(t, 0.5, halfway)
+	assert.Equal(t, 1.0, overdue)
+}
+
+// Test GetLaunchQueueLimit - Later Teams Queue More
+func TestGameCoreService_GetLaunchQueueLimit(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockLootItemRepository)
+	service := NewGameCoreService(mockRepo)
+
+	// Act & Assert
+	assert.Equal(t, 2, service.GetLaunchQueueLimit(1))
+	assert.Equal(t, 4, service.GetLaunchQueueLimit(3))
+}
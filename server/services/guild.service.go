@@ -0,0 +1,503 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+const (
+	// GuildMaxMembers is how many players a guild can hold
+	GuildMaxMembers = 20
+
+	GuildNameMinLength        = 3
+	GuildNameMaxLength        = 30
+	GuildDescriptionMaxLength = 200
+
+	// GuildInviteCodeLength is the length of generated invite codes
+	GuildInviteCodeLength = 8
+)
+
+// guildInviteCodeAlphabet leaves out characters that are easy to mix up (0/O, 1/I).
+// It has 32 characters so every random byte maps onto it evenly.
+const guildInviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Errors returned by GuildService that callers can check with errors.Is
+var (
+	ErrNotInGuild              = errors.New("you are not in a guild")
+	ErrAlreadyInGuild          = errors.New("you are already in a guild")
+	ErrGuildFull               = errors.New("guild is full")
+	ErrGuildNameTaken          = errors.New("guild name is already taken")
+	ErrInvalidGuildName        = errors.New("guild names must be 3 to 30 characters")
+	ErrInvalidGuildDescription = errors.New("guild descriptions can be at most 200 characters")
+	ErrInvalidInviteCode       = errors.New("invalid invite code")
+	ErrGuildPermission         = errors.New("your guild role doesn't allow this")
+	ErrGuildMemberNotFound     = errors.New("guild member not found")
+	ErrInvalidGuildRole        = errors.New("invalid guild role")
+	ErrInvalidDepositQuantity  = errors.New("deposit quantity must be at least 1")
+	ErrNotEnoughToDeposit      = errors.New("not enough items to deposit")
+	ErrCannotDepositEquipped   = errors.New("cannot deposit an equipped item")
+)
+
+type IGuildService interface {
+	GetMyGuild(userId int64) (*models.GuildResponseDTO, error)
+	CreateGuild(userId int64, name, description string) (*models.GuildResponseDTO, error)
+	JoinGuild(userId int64, inviteCode string) (*models.GuildResponseDTO, error)
+	LeaveGuild(userId int64) error
+	KickMember(userId, memberUserId int64) (*models.GuildResponseDTO, error)
+	SetMemberRole(userId, memberUserId int64, role string) (*models.GuildResponseDTO, error)
+	RegenerateInviteCode(userId int64) (*models.GuildResponseDTO, error)
+	DepositLoot(userId, inventoryId int64, quantity int) (*models.GuildResponseDTO, error)
+}
+
+type GuildService struct {
+	guildRepository     repositories.IGuildRepository
+	inventoryRepository repositories.IUserInventoryRepository
+	lootItemRepository  repositories.ILootItemRepository
+	teamRepository      repositories.ITeamRepository
+	unitOfWork          database.IUnitOfWork
+}
+
+func NewGuildService(
+	guildRepository repositories.IGuildRepository,
+	inventoryRepository repositories.IUserInventoryRepository,
+	lootItemRepository repositories.ILootItemRepository,
+	teamRepository repositories.ITeamRepository,
+	unitOfWork database.IUnitOfWork,
+) IGuildService {
+	return &GuildService{
+		guildRepository:     guildRepository,
+		inventoryRepository: inventoryRepository,
+		lootItemRepository:  lootItemRepository,
+		teamRepository:      teamRepository,
+		unitOfWork:          unitOfWork,
+	}
+}
+
+// GetMyGuild returns the guild the user is in, with its members, treasury and rift progress
+func (s *GuildService) GetMyGuild(userId int64) (*models.GuildResponseDTO, error) {
+	member, err := s.guildRepository.GetMemberByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotInGuild
+	}
+
+	guild, err := s.guildRepository.GetGuildById(member.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	if guild == nil {
+		return nil, ErrNotInGuild
+	}
+
+	members, err := s.guildRepository.GetMembers(guild.ID)
+	if err != nil {
+		return nil, err
+	}
+	treasury, err := s.guildRepository.GetTreasury(guild.ID)
+	if err != nil {
+		return nil, err
+	}
+	riftProgress, err := s.guildRepository.GetRiftProgress(guild.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.GuildResponseDTO{
+		ID:           guild.ID,
+		Name:         guild.Name,
+		Description:  guild.Description,
+		MaxMembers:   GuildMaxMembers,
+		MyRole:       member.Role,
+		Members:      make([]*models.GuildMemberDTO, 0, len(members)),
+		Treasury:     make([]*models.GuildTreasuryItemDTO, 0, len(treasury)),
+		RiftProgress: make([]*models.GuildRiftProgressDTO, 0, len(riftProgress)),
+		CreatedAt:    guild.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if canManageGuild(member.Role) {
+		response.InviteCode = &guild.InviteCode
+	}
+
+	for _, m := range members {
+		response.Members = append(response.Members, &models.GuildMemberDTO{
+			UserID:      m.UserID,
+			DisplayName: m.DisplayName,
+			Role:        m.Role,
+			JoinedAt:    m.JoinedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	for _, item := range treasury {
+		lootItem, err := s.lootItemRepository.GetLootItemById(item.LootItemID)
+		if err != nil {
+			return nil, err
+		}
+		response.Treasury = append(response.Treasury, &models.GuildTreasuryItemDTO{
+			LootItemID: item.LootItemID,
+			Name:       lootItem.Name,
+			Icon:       lootItem.Icon,
+			Rarity:     lootItem.Rarity,
+			Quantity:   item.Quantity,
+		})
+	}
+
+	for _, progress := range riftProgress {
+		response.RiftProgress = append(response.RiftProgress, &models.GuildRiftProgressDTO{
+			RiftID:               progress.RiftID,
+			RiftName:             progress.RiftName,
+			CompletedExpeditions: progress.CompletedExpeditions,
+			MembersCompleted:     progress.MembersCompleted,
+		})
+	}
+
+	return response, nil
+}
+
+// CreateGuild creates a guild led by the user
+func (s *GuildService) CreateGuild(userId int64, name, description string) (*models.GuildResponseDTO, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if nameLength := utf8.RuneCountInString(name); nameLength < GuildNameMinLength || nameLength > GuildNameMaxLength {
+		return nil, ErrInvalidGuildName
+	}
+	if utf8.RuneCountInString(description) > GuildDescriptionMaxLength {
+		return nil, ErrInvalidGuildDescription
+	}
+
+	inviteCode, err := generateGuildInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the guild and its leader together so there's never a guild without one
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		guildRepository := s.guildRepository.WithTx(tx)
+
+		existing, err := guildRepository.GetMemberByUserId(userId)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return ErrAlreadyInGuild
+		}
+
+		guild, err := guildRepository.CreateGuild(name, description, inviteCode)
+		if err != nil {
+			if errors.Is(err, repositories.ErrGuildNameTaken) {
+				return ErrGuildNameTaken
+			}
+			return err
+		}
+
+		_, err = guildRepository.AddMember(guild.ID, userId, string(models.GuildRoleLeader))
+		if errors.Is(err, repositories.ErrAlreadyInGuild) {
+			// Lost a race with another create or join by the same user
+			return ErrAlreadyInGuild
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// JoinGuild adds the user to the guild with the invite code, if it has room
+func (s *GuildService) JoinGuild(userId int64, inviteCode string) (*models.GuildResponseDTO, error) {
+	inviteCode = strings.ToUpper(strings.TrimSpace(inviteCode))
+	if inviteCode == "" {
+		return nil, ErrInvalidInviteCode
+	}
+
+	guild, err := s.guildRepository.GetGuildByInviteCode(inviteCode)
+	if err != nil {
+		return nil, err
+	}
+	if guild == nil {
+		return nil, ErrInvalidInviteCode
+	}
+
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		guildRepository := s.guildRepository.WithTx(tx)
+
+		// Lock the guild so concurrent joins can't both take the last place
+		lockedGuild, err := guildRepository.GetGuildByIdForUpdate(guild.ID)
+		if err != nil {
+			return err
+		}
+		if lockedGuild == nil || lockedGuild.InviteCode != inviteCode {
+			// Disbanded, or the code was regenerated, since it was looked up
+			return ErrInvalidInviteCode
+		}
+
+		memberCount, err := guildRepository.GetMemberCount(guild.ID)
+		if err != nil {
+			return err
+		}
+		if memberCount >= GuildMaxMembers {
+			return ErrGuildFull
+		}
+
+		_, err = guildRepository.AddMember(guild.ID, userId, string(models.GuildRoleMember))
+		if errors.Is(err, repositories.ErrAlreadyInGuild) {
+			return ErrAlreadyInGuild
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// LeaveGuild removes the user from their guild. A leader who leaves hands the guild to the
+// longest standing officer, or the longest standing member if there are no officers, and
+// the last member to leave disbands it.
+func (s *GuildService) LeaveGuild(userId int64) error {
+	return s.withLockedGuild(userId, func(guildRepository repositories.IGuildRepository, actor *repositories.GuildMemberEntity) error {
+		if _, err := guildRepository.RemoveMember(actor.GuildID, userId); err != nil {
+			return err
+		}
+		if actor.Role != string(models.GuildRoleLeader) {
+			return nil
+		}
+
+		// GetMembers lists officers before members, longest standing first
+		remaining, err := guildRepository.GetMembers(actor.GuildID)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return guildRepository.ArchiveGuild(actor.GuildID)
+		}
+		return guildRepository.UpdateMemberRole(actor.GuildID, remaining[0].UserID, string(models.GuildRoleLeader))
+	})
+}
+
+// KickMember removes another member from the user's guild. The leader can kick anyone,
+// officers can only kick members.
+func (s *GuildService) KickMember(userId, memberUserId int64) (*models.GuildResponseDTO, error) {
+	if userId == memberUserId {
+		// Leaving goes through LeaveGuild so leadership is handed over
+		return nil, ErrGuildPermission
+	}
+
+	err := s.withLockedGuild(userId, func(guildRepository repositories.IGuildRepository, actor *repositories.GuildMemberEntity) error {
+		target, err := s.getGuildMember(guildRepository, actor.GuildID, memberUserId)
+		if err != nil {
+			return err
+		}
+
+		canKick := actor.Role == string(models.GuildRoleLeader) ||
+			(actor.Role == string(models.GuildRoleOfficer) && target.Role == string(models.GuildRoleMember))
+		if !canKick {
+			return ErrGuildPermission
+		}
+
+		_, err = guildRepository.RemoveMember(actor.GuildID, memberUserId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// SetMemberRole changes another member's role. Only the leader can change roles, and
+// making someone else leader hands them the guild, leaving the old leader an officer.
+func (s *GuildService) SetMemberRole(userId, memberUserId int64, role string) (*models.GuildResponseDTO, error) {
+	switch models.GuildRole(role) {
+	case models.GuildRoleLeader, models.GuildRoleOfficer, models.GuildRoleMember:
+	default:
+		return nil, ErrInvalidGuildRole
+	}
+	if userId == memberUserId {
+		// The leader steps down by making someone else leader
+		return nil, ErrGuildPermission
+	}
+
+	err := s.withLockedGuild(userId, func(guildRepository repositories.IGuildRepository, actor *repositories.GuildMemberEntity) error {
+		if actor.Role != string(models.GuildRoleLeader) {
+			return ErrGuildPermission
+		}
+		if _, err := s.getGuildMember(guildRepository, actor.GuildID, memberUserId); err != nil {
+			return err
+		}
+
+		if err := guildRepository.UpdateMemberRole(actor.GuildID, memberUserId, role); err != nil {
+			return err
+		}
+		if models.GuildRole(role) == models.GuildRoleLeader {
+			return guildRepository.UpdateMemberRole(actor.GuildID, userId, string(models.GuildRoleOfficer))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// RegenerateInviteCode gives the guild a new invite code so the old one stops working.
+// Only the leader and officers can do this.
+func (s *GuildService) RegenerateInviteCode(userId int64) (*models.GuildResponseDTO, error) {
+	inviteCode, err := generateGuildInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withLockedGuild(userId, func(guildRepository repositories.IGuildRepository, actor *repositories.GuildMemberEntity) error {
+		if !canManageGuild(actor.Role) {
+			return ErrGuildPermission
+		}
+		return guildRepository.UpdateInviteCode(actor.GuildID, inviteCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// DepositLoot moves quantity of one of the user's unequipped inventory rows into their
+// guild's treasury. Deposits can't be taken back out.
+func (s *GuildService) DepositLoot(userId, inventoryId int64, quantity int) (*models.GuildResponseDTO, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidDepositQuantity
+	}
+
+	// Check the item, spend it and fill the treasury together so neither happens without
+	// the other. SpendLoot checks again that the item is free, in case it was equipped or
+	// escrowed after these checks.
+	err := s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		inventoryRepository := s.inventoryRepository.WithTx(tx)
+		invItem, err := inventoryRepository.GetInventoryById(inventoryId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInventoryItemNotFound
+			}
+			return err
+		}
+		if invItem.UserID != userId {
+			return ErrInventoryItemNotOwned
+		}
+		if invItem.EscrowTradeID != nil {
+			return ErrItemInEscrow
+		}
+		if invItem.Quantity < quantity {
+			return ErrNotEnoughToDeposit
+		}
+
+		equippedTeam, _, err := s.teamRepository.WithTx(tx).GetTeamsByUserIdWithSlot(userId, inventoryId)
+		if err != nil {
+			return err
+		}
+		if equippedTeam != nil {
+			return ErrCannotDepositEquipped
+		}
+
+		member, err := s.guildRepository.WithTx(tx).GetMemberByUserId(userId)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return ErrNotInGuild
+		}
+
+		err = inventoryRepository.SpendLoot(inventoryId, quantity)
+		if errors.Is(err, repositories.ErrNotEnoughItems) {
+			// Spent, equipped or escrowed by another request since it was checked
+			return ErrNotEnoughToDeposit
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = s.guildRepository.WithTx(tx).AddToTreasury(member.GuildID, invItem.LootItemID, quantity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMyGuild(userId)
+}
+
+// withLockedGuild runs fn in a transaction holding the lock on the user's guild, so
+// membership changes to a guild happen one at a time. actor is the user's membership,
+// read after the lock is taken.
+func (s *GuildService) withLockedGuild(userId int64, fn func(guildRepository repositories.IGuildRepository, actor *repositories.GuildMemberEntity) error) error {
+	return s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		guildRepository := s.guildRepository.WithTx(tx)
+
+		member, err := guildRepository.GetMemberByUserId(userId)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return ErrNotInGuild
+		}
+
+		guild, err := guildRepository.GetGuildByIdForUpdate(member.GuildID)
+		if err != nil {
+			return err
+		}
+		if guild == nil {
+			return ErrNotInGuild
+		}
+
+		// The user's role may have changed while waiting for the lock
+		actor, err := guildRepository.GetMemberByUserId(userId)
+		if err != nil {
+			return err
+		}
+		if actor == nil || actor.GuildID != guild.ID {
+			return ErrNotInGuild
+		}
+
+		return fn(guildRepository, actor)
+	})
+}
+
+// getGuildMember loads another user's membership and checks that it's in the guild
+func (s *GuildService) getGuildMember(guildRepository repositories.IGuildRepository, guildId, userId int64) (*repositories.GuildMemberEntity, error) {
+	member, err := guildRepository.GetMemberByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || member.GuildID != guildId {
+		return nil, ErrGuildMemberNotFound
+	}
+	return member, nil
+}
+
+// canManageGuild checks if a role can see and regenerate the invite code
+func canManageGuild(role string) bool {
+	return role == string(models.GuildRoleLeader) || role == string(models.GuildRoleOfficer)
+}
+
+// generateGuildInviteCode returns a random invite code from guildInviteCodeAlphabet
+func generateGuildInviteCode() (string, error) {
+	randomBytes := make([]byte, GuildInviteCodeLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, GuildInviteCodeLength)
+	for i, b := range randomBytes {
+		code[i] = guildInviteCodeAlphabet[int(b)%len(guildInviteCodeAlphabet)]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,725 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock GuildRepository
+type MockGuildRepository struct {
+	mock.Mock
+}
+
+func (m *MockGuildRepository) CreateGuild(name, description, inviteCode string) (*repositories.GuildEntity, error) {
+	args := m.Called(name, description, inviteCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetGuildById(guildId int64) (*repositories.GuildEntity, error) {
+	args := m.Called(guildId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetGuildByIdForUpdate(guildId int64) (*repositories.GuildEntity, error) {
+	args := m.Called(guildId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetGuildByInviteCode(inviteCode string) (*repositories.GuildEntity, error) {
+	args := m.Called(inviteCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) UpdateInviteCode(guildId int64, inviteCode string) error {
+	args := m.Called(guildId, inviteCode)
+	return args.Error(0)
+}
+
+func (m *MockGuildRepository) ArchiveGuild(guildId int64) error {
+	args := m.Called(guildId)
+	return args.Error(0)
+}
+
+func (m *MockGuildRepository) AddMember(guildId, userId int64, role string) (*repositories.GuildMemberEntity, error) {
+	args := m.Called(guildId, userId, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildMemberEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetMemberByUserId(userId int64) (*repositories.GuildMemberEntity, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildMemberEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetMembers(guildId int64) ([]*repositories.GuildMemberEntity, error) {
+	args := m.Called(guildId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.GuildMemberEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetMemberCount(guildId int64) (int, error) {
+	args := m.Called(guildId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockGuildRepository) UpdateMemberRole(guildId, userId int64, role string) error {
+	args := m.Called(guildId, userId, role)
+	return args.Error(0)
+}
+
+func (m *MockGuildRepository) RemoveMember(guildId, userId int64) (bool, error) {
+	args := m.Called(guildId, userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGuildRepository) AddToTreasury(guildId, lootItemId int64, quantity int) (*repositories.GuildTreasuryItemEntity, error) {
+	args := m.Called(guildId, lootItemId, quantity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.GuildTreasuryItemEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetTreasury(guildId int64) ([]*repositories.GuildTreasuryItemEntity, error) {
+	args := m.Called(guildId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.GuildTreasuryItemEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) GetRiftProgress(guildId int64) ([]*repositories.GuildRiftProgressEntity, error) {
+	args := m.Called(guildId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.GuildRiftProgressEntity), args.Error(1)
+}
+
+func (m *MockGuildRepository) WithTx(tx *database.AppDataSource) repositories.IGuildRepository {
+	return m
+}
+
+func newGuildTestService() (IGuildService, *MockGuildRepository, *MockUserInventoryRepository, *MockLootItemRepository, *MockTeamRepository) {
+	mockGuildRepo := new(MockGuildRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockTeamRepo := new(MockTeamRepository)
+	service := NewGuildService(mockGuildRepo, mockInventoryRepo, mockLootItemRepo, mockTeamRepo, new(MockUnitOfWork))
+	return service, mockGuildRepo, mockInventoryRepo, mockLootItemRepo, mockTeamRepo
+}
+
+var testGuild = &repositories.GuildEntity{ID: 1, Name: "Void Seekers", Description: "We seek the void", InviteCode: "ABCD2345", CreatedAt: time.Now()}
+
+func guildMember(userId int64, role string) *repositories.GuildMemberEntity {
+	return &repositories.GuildMemberEntity{ID: userId, GuildID: testGuild.ID, UserID: userId, Role: role, JoinedAt: time.Now()}
+}
+
+// expectGuildView sets up what GetMyGuild reads for the test guild
+func expectGuildView(mockGuildRepo *MockGuildRepository, members []*repositories.GuildMemberEntity) {
+	mockGuildRepo.On("GetGuildById", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMembers", testGuild.ID).Return(members, nil)
+	mockGuildRepo.On("GetTreasury", testGuild.ID).Return([]*repositories.GuildTreasuryItemEntity{}, nil)
+	mockGuildRepo.On("GetRiftProgress", testGuild.ID).Return([]*repositories.GuildRiftProgressEntity{}, nil)
+}
+
+// Tests for GetMyGuild
+func TestGuildService_GetMyGuild_Success(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, mockLootItemRepo, _ := newGuildTestService()
+
+	leader := guildMember(1, "leader")
+	leader.DisplayName = "Aria"
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(leader, nil)
+	mockGuildRepo.On("GetGuildById", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMembers", testGuild.ID).Return([]*repositories.GuildMemberEntity{leader}, nil)
+	mockGuildRepo.On("GetTreasury", testGuild.ID).Return([]*repositories.GuildTreasuryItemEntity{
+		{GuildID: testGuild.ID, LootItemID: 100, Quantity: 3},
+	}, nil)
+	mockGuildRepo.On("GetRiftProgress", testGuild.ID).Return([]*repositories.GuildRiftProgressEntity{
+		{RiftID: 2, RiftName: "Shattered Peaks", CompletedExpeditions: 5, MembersCompleted: 1},
+	}, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(&repositories.LootItemEntity{ID: 100, Name: "Void Shard", Rarity: "rare"}, nil)
+
+	// Act
+	result, err := service.GetMyGuild(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "Void Seekers", result.Name)
+	assert.Equal(t, "leader", result.MyRole)
+	assert.Equal(t, GuildMaxMembers, result.MaxMembers)
+	if assert.NotNil(t, result.InviteCode) {
+		assert.Equal(t, "ABCD2345", *result.InviteCode)
+	}
+	assert.Len(t, result.Members, 1)
+	assert.Equal(t, "Aria", result.Members[0].DisplayName)
+	assert.Len(t, result.Treasury, 1)
+	assert.Equal(t, "Void Shard", result.Treasury[0].Name)
+	assert.Equal(t, 3, result.Treasury[0].Quantity)
+	assert.Len(t, result.RiftProgress, 1)
+	assert.Equal(t, 5, result.RiftProgress[0].CompletedExpeditions)
+}
+
+func TestGuildService_GetMyGuild_HidesInviteCodeFromMembers(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	member := guildMember(2, "member")
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(member, nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{guildMember(1, "leader"), member})
+
+	// Act
+	result, err := service.GetMyGuild(2)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, result.InviteCode)
+}
+
+func TestGuildService_GetMyGuild_NotInGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(nil, nil)
+
+	// Act
+	result, err := service.GetMyGuild(1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotInGuild)
+	assert.Nil(t, result)
+}
+
+// Tests for CreateGuild
+func TestGuildService_CreateGuild_Success(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	leader := guildMember(1, "leader")
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(nil, nil).Once()
+	mockGuildRepo.On("CreateGuild", "Void Seekers", "We seek the void", mock.AnythingOfType("string")).Return(testGuild, nil)
+	mockGuildRepo.On("AddMember", testGuild.ID, int64(1), "leader").Return(leader, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(leader, nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{leader})
+
+	// Act
+	result, err := service.CreateGuild(1, "  Void Seekers ", "We seek the void")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "leader", result.MyRole)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_CreateGuild_InvalidName(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	for _, name := range []string{"", "ab", "   ab   ", strings.Repeat("x", GuildNameMaxLength+1)} {
+		// Act
+		result, err := service.CreateGuild(1, name, "")
+
+		// Assert
+		assert.ErrorIs(t, err, ErrInvalidGuildName, "name %q", name)
+		assert.Nil(t, result)
+	}
+	mockGuildRepo.AssertNotCalled(t, "CreateGuild", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGuildService_CreateGuild_NameTaken(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(nil, nil)
+	mockGuildRepo.On("CreateGuild", "Void Seekers", "", mock.AnythingOfType("string")).Return(nil, repositories.ErrGuildNameTaken)
+
+	// Act
+	result, err := service.CreateGuild(1, "Void Seekers", "")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildNameTaken)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGuildService_CreateGuild_AlreadyInGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(guildMember(1, "member"), nil)
+
+	// Act
+	result, err := service.CreateGuild(1, "Void Seekers", "")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAlreadyInGuild)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "CreateGuild", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Tests for JoinGuild
+func TestGuildService_JoinGuild_Success(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	member := guildMember(2, "member")
+	mockGuildRepo.On("GetGuildByInviteCode", "ABCD2345").Return(testGuild, nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMemberCount", testGuild.ID).Return(GuildMaxMembers-1, nil)
+	mockGuildRepo.On("AddMember", testGuild.ID, int64(2), "member").Return(member, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(member, nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{guildMember(1, "leader"), member})
+
+	// Act - codes are matched without regard to case or surrounding space
+	result, err := service.JoinGuild(2, " abcd2345 ")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "member", result.MyRole)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_JoinGuild_InvalidInviteCode(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+	mockGuildRepo.On("GetGuildByInviteCode", "NOPE2345").Return(nil, nil)
+
+	// Act
+	result, err := service.JoinGuild(2, "NOPE2345")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidInviteCode)
+	assert.Nil(t, result)
+}
+
+func TestGuildService_JoinGuild_GuildFull(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetGuildByInviteCode", "ABCD2345").Return(testGuild, nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMemberCount", testGuild.ID).Return(GuildMaxMembers, nil)
+
+	// Act
+	result, err := service.JoinGuild(2, "ABCD2345")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildFull)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGuildService_JoinGuild_AlreadyInGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetGuildByInviteCode", "ABCD2345").Return(testGuild, nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMemberCount", testGuild.ID).Return(1, nil)
+	mockGuildRepo.On("AddMember", testGuild.ID, int64(2), "member").Return(nil, repositories.ErrAlreadyInGuild)
+
+	// Act
+	result, err := service.JoinGuild(2, "ABCD2345")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAlreadyInGuild)
+	assert.Nil(t, result)
+}
+
+// Tests for LeaveGuild
+func TestGuildService_LeaveGuild_Member(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("RemoveMember", testGuild.ID, int64(2)).Return(true, nil)
+
+	// Act
+	err := service.LeaveGuild(2)
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything)
+	mockGuildRepo.AssertNotCalled(t, "ArchiveGuild", mock.Anything)
+}
+
+func TestGuildService_LeaveGuild_LeaderHandsOverGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(guildMember(1, "leader"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("RemoveMember", testGuild.ID, int64(1)).Return(true, nil)
+	mockGuildRepo.On("GetMembers", testGuild.ID).Return([]*repositories.GuildMemberEntity{
+		guildMember(3, "officer"),
+		guildMember(2, "member"),
+	}, nil)
+	mockGuildRepo.On("UpdateMemberRole", testGuild.ID, int64(3), "leader").Return(nil)
+
+	// Act
+	err := service.LeaveGuild(1)
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertExpectations(t)
+	mockGuildRepo.AssertNotCalled(t, "ArchiveGuild", mock.Anything)
+}
+
+func TestGuildService_LeaveGuild_LastMemberDisbandsGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(guildMember(1, "leader"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("RemoveMember", testGuild.ID, int64(1)).Return(true, nil)
+	mockGuildRepo.On("GetMembers", testGuild.ID).Return([]*repositories.GuildMemberEntity{}, nil)
+	mockGuildRepo.On("ArchiveGuild", testGuild.ID).Return(nil)
+
+	// Act
+	err := service.LeaveGuild(1)
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_LeaveGuild_NotInGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(nil, nil)
+
+	// Act
+	err := service.LeaveGuild(1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotInGuild)
+}
+
+// Tests for KickMember
+func TestGuildService_KickMember_OfficerKicksMember(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	officer := guildMember(3, "officer")
+	mockGuildRepo.On("GetMemberByUserId", int64(3)).Return(officer, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("RemoveMember", testGuild.ID, int64(2)).Return(true, nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{guildMember(1, "leader"), officer})
+
+	// Act
+	result, err := service.KickMember(3, 2)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result.Members, 2)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_KickMember_OfficerCannotKickOfficer(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(3)).Return(guildMember(3, "officer"), nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(4)).Return(guildMember(4, "officer"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+
+	// Act
+	result, err := service.KickMember(3, 4)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildPermission)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+}
+
+func TestGuildService_KickMember_MemberOfAnotherGuild(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	otherGuildMember := guildMember(5, "member")
+	otherGuildMember.GuildID = 99
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(guildMember(1, "leader"), nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(5)).Return(otherGuildMember, nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+
+	// Act
+	result, err := service.KickMember(1, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildMemberNotFound)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+}
+
+// Tests for SetMemberRole
+func TestGuildService_SetMemberRole_PromoteToOfficer(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	leader := guildMember(1, "leader")
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(leader, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("UpdateMemberRole", testGuild.ID, int64(2), "officer").Return(nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{leader, guildMember(2, "officer")})
+
+	// Act
+	_, err := service.SetMemberRole(1, 2, "officer")
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertExpectations(t)
+	mockGuildRepo.AssertNotCalled(t, "UpdateMemberRole", testGuild.ID, int64(1), mock.Anything)
+}
+
+func TestGuildService_SetMemberRole_HandOverLeadership(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(guildMember(1, "leader"), nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("UpdateMemberRole", testGuild.ID, int64(2), "leader").Return(nil)
+	mockGuildRepo.On("UpdateMemberRole", testGuild.ID, int64(1), "officer").Return(nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{guildMember(2, "leader"), guildMember(1, "officer")})
+
+	// Act
+	_, err := service.SetMemberRole(1, 2, "leader")
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_SetMemberRole_OnlyLeader(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(3)).Return(guildMember(3, "officer"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+
+	// Act
+	result, err := service.SetMemberRole(3, 2, "officer")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildPermission)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGuildService_SetMemberRole_InvalidRole(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	// Act
+	result, err := service.SetMemberRole(1, 2, "emperor")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidGuildRole)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "GetMemberByUserId", mock.Anything)
+}
+
+// Tests for RegenerateInviteCode
+func TestGuildService_RegenerateInviteCode_Success(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	officer := guildMember(3, "officer")
+	mockGuildRepo.On("GetMemberByUserId", int64(3)).Return(officer, nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("UpdateInviteCode", testGuild.ID, mock.MatchedBy(func(code string) bool {
+		return len(code) == GuildInviteCodeLength
+	})).Return(nil)
+	expectGuildView(mockGuildRepo, []*repositories.GuildMemberEntity{guildMember(1, "leader"), officer})
+
+	// Act
+	_, err := service.RegenerateInviteCode(3)
+
+	// Assert
+	assert.NoError(t, err)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_RegenerateInviteCode_MemberCannot(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockGuildRepo.On("GetGuildByIdForUpdate", testGuild.ID).Return(testGuild, nil)
+
+	// Act
+	result, err := service.RegenerateInviteCode(2)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrGuildPermission)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "UpdateInviteCode", mock.Anything, mock.Anything)
+}
+
+// Tests for DepositLoot
+func TestGuildService_DepositLoot_Success(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, mockInventoryRepo, mockLootItemRepo, mockTeamRepo := newGuildTestService()
+
+	member := guildMember(2, "member")
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100, Quantity: 5}
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(2), int64(10)).Return(nil, nil, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(member, nil)
+	mockInventoryRepo.On("SpendLoot", int64(10), 3).Return(nil)
+	mockGuildRepo.On("AddToTreasury", testGuild.ID, int64(100), 3).Return(&repositories.GuildTreasuryItemEntity{}, nil)
+	mockGuildRepo.On("GetGuildById", testGuild.ID).Return(testGuild, nil)
+	mockGuildRepo.On("GetMembers", testGuild.ID).Return([]*repositories.GuildMemberEntity{member}, nil)
+	mockGuildRepo.On("GetTreasury", testGuild.ID).Return([]*repositories.GuildTreasuryItemEntity{
+		{GuildID: testGuild.ID, LootItemID: 100, Quantity: 3},
+	}, nil)
+	mockGuildRepo.On("GetRiftProgress", testGuild.ID).Return([]*repositories.GuildRiftProgressEntity{}, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(&repositories.LootItemEntity{ID: 100, Name: "Void Shard", Rarity: "rare"}, nil)
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 3)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Treasury[0].Quantity)
+	mockInventoryRepo.AssertExpectations(t)
+	mockGuildRepo.AssertExpectations(t)
+}
+
+func TestGuildService_DepositLoot_InvalidQuantity(t *testing.T) {
+	// Arrange
+	service, _, mockInventoryRepo, _, _ := newGuildTestService()
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidDepositQuantity)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "GetInventoryById", mock.Anything)
+}
+
+func TestGuildService_DepositLoot_NotEnoughItems(t *testing.T) {
+	// Arrange
+	service, _, mockInventoryRepo, _, _ := newGuildTestService()
+
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100, Quantity: 2}
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 3)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotEnoughToDeposit)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "SpendLoot", mock.Anything, mock.Anything)
+}
+
+func TestGuildService_DepositLoot_NotOwned(t *testing.T) {
+	// Arrange
+	service, _, mockInventoryRepo, _, _ := newGuildTestService()
+
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 7, LootItemID: 100, Quantity: 5}
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInventoryItemNotOwned)
+	assert.Nil(t, result)
+}
+
+func TestGuildService_DepositLoot_Equipped(t *testing.T) {
+	// Arrange
+	service, _, mockInventoryRepo, _, mockTeamRepo := newGuildTestService()
+
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100, Quantity: 1}
+	slot := "weapon"
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(2), int64(10)).Return(&repositories.TeamEntity{ID: 1}, &slot, nil)
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCannotDepositEquipped)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "SpendLoot", mock.Anything, mock.Anything)
+}
+
+func TestGuildService_DepositLoot_SpentConcurrently(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, mockInventoryRepo, _, mockTeamRepo := newGuildTestService()
+
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100, Quantity: 5}
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(2), int64(10)).Return(nil, nil, nil)
+	mockGuildRepo.On("GetMemberByUserId", int64(2)).Return(guildMember(2, "member"), nil)
+	mockInventoryRepo.On("SpendLoot", int64(10), 5).Return(repositories.ErrNotEnoughItems)
+
+	// Act
+	result, err := service.DepositLoot(2, 10, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotEnoughToDeposit)
+	assert.Nil(t, result)
+	mockGuildRepo.AssertNotCalled(t, "AddToTreasury", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGenerateGuildInviteCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateGuildInviteCode()
+		assert.NoError(t, err)
+		assert.Len(t, code, GuildInviteCodeLength)
+		for _, c := range code {
+			assert.True(t, strings.ContainsRune(guildInviteCodeAlphabet, c), "unexpected character %q in %s", c, code)
+		}
+		seen[code] = true
+	}
+	assert.Len(t, seen, 100, "codes should not repeat")
+}
+
+// errGuildTest is returned by mocks to check errors are passed through
+var errGuildTest = errors.New("database unavailable")
+
+func TestGuildService_LeaveGuild_RepositoryError(t *testing.T) {
+	// Arrange
+	service, mockGuildRepo, _, _, _ := newGuildTestService()
+	mockGuildRepo.On("GetMemberByUserId", int64(1)).Return(nil, errGuildTest)
+
+	// Act
+	err := service.LeaveGuild(1)
+
+	// Assert
+	assert.ErrorIs(t, err, errGuildTest)
+}
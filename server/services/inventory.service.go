@@ -69,6 +69,7 @@ func (s *InventoryService) GetEquipment(userId int64) ([]models.InventoryItemRes
 			AcquiredAt:           invItem.AcquiredAt.Format("2006-01-02T15:04:05Z"),
 			IsEquipped:           isEquipped,
 			EquippedByTeamNumber: teamNumber,
+			IsInEscrow:           invItem.EscrowTradeID != nil,
 			LootItem:             s.mapLootItemDTO(lootItem),
 		}
 	}
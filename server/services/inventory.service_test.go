@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -68,11 +69,58 @@ func (m *MockUserInventoryRepository) ConsumeLoot(inventoryId int64) error {
 	return args.Error(0)
 }
 
+func (m *MockUserInventoryRepository) SpendLoot(inventoryId int64, quantity int) error {
+	args := m.Called(inventoryId, quantity)
+	return args.Error(0)
+}
+
+func (m *MockUserInventoryRepository) EscrowItem(inventoryId, userId, tradeId int64) (bool, error) {
+	args := m.Called(inventoryId, userId, tradeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserInventoryRepository) ReleaseEscrow(tradeId int64) error {
+	args := m.Called(tradeId)
+	return args.Error(0)
+}
+
+func (m *MockUserInventoryRepository) TransferEscrowedItem(inventoryId, tradeId, toUserId int64) (bool, error) {
+	args := m.Called(inventoryId, tradeId, toUserId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserInventoryRepository) WithTx(tx *database.AppDataSource) repositories.IUserInventoryRepository {
+	return m
+}
+
 func (m *MockUserInventoryRepository) HasItemByName(userId int64, itemName string) (bool, error) {
 	args := m.Called(userId, itemName)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockUserInventoryRepository) GetCollectedLootItemIds(userId int64) ([]int64, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockUserInventoryRepository) CountItemsByRarity(userId int64, rarity string) (int, error) {
+	args := m.Called(userId, rarity)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserInventoryRepository) CountUnequippedItemsByRarity(userId int64, rarity string) (int, error) {
+	args := m.Called(userId, rarity)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserInventoryRepository) ConsumeItemsByRarity(userId int64, rarity string, quantity int) error {
+	args := m.Called(userId, rarity, quantity)
+	return args.Error(0)
+}
+
 // Test GetUserInventory - Success With Both Types
 func TestInventoryService_GetUserInventory_Success(t *testing.T) {
 	// Arrange
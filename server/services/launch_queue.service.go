@@ -0,0 +1,347 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+// MaxQueueRepeatCount is the most times a single queue entry can be set to launch
+const MaxQueueRepeatCount = 50
+
+// Errors returned by LaunchQueueService that callers can check with errors.Is
+var (
+	ErrLaunchQueueFull    = errors.New("team launch queue is full")
+	ErrInvalidRepeatCount = errors.New("invalid repeat count")
+	ErrQueueEntryNotFound = errors.New("queue entry not found")
+)
+
+// errNothingLaunched rolls back launchNext's transaction when none of the team's queued
+// launches could start, so the team's finished expedition is left unclaimed
+var errNothingLaunched = errors.New("no queued launch could start")
+
+type ILaunchQueueService interface {
+	GetTeamQueue(userId, teamId int64) (*models.TeamQueueDTO, error)
+	QueueLaunch(userId, teamId int64, dto *models.QueueLaunchDTO) (*models.TeamQueueDTO, error)
+	RemoveQueueEntry(userId, teamId, entryId int64) (*models.TeamQueueDTO, error)
+	ClearTeamQueue(userId, teamId int64) error
+	LaunchQueuedExpeditions(limit int) (int, error)
+}
+
+// LaunchQueueService manages each team's queue of rift launches and starts the next
+// launch once the team's current expedition has been processed
+type LaunchQueueService struct {
+	launchQueueRepository repositories.ILaunchQueueRepository
+	teamRepository        repositories.ITeamRepository
+	riftRepository        repositories.IRiftRepository
+	expeditionRepository  repositories.IExpeditionRepository
+	expeditionService     IExpeditionService
+	riftService           IRiftService
+	gameCoreService       IGameCoreService
+	unitOfWork            database.IUnitOfWork
+}
+
+func NewLaunchQueueService(
+	launchQueueRepository repositories.ILaunchQueueRepository,
+	teamRepository repositories.ITeamRepository,
+	riftRepository repositories.IRiftRepository,
+	expeditionRepository repositories.IExpeditionRepository,
+	expeditionService IExpeditionService,
+	riftService IRiftService,
+	gameCoreService IGameCoreService,
+	unitOfWork database.IUnitOfWork,
+) ILaunchQueueService {
+	return &LaunchQueueService{
+		launchQueueRepository: launchQueueRepository,
+		teamRepository:        teamRepository,
+		riftRepository:        riftRepository,
+		expeditionRepository:  expeditionRepository,
+		expeditionService:     expeditionService,
+		riftService:           riftService,
+		gameCoreService:       gameCoreService,
+		unitOfWork:            unitOfWork,
+	}
+}
+
+func (s *LaunchQueueService) GetTeamQueue(userId, teamId int64) (*models.TeamQueueDTO, error) {
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildQueueDTO(team)
+}
+
+// QueueLaunch adds a rift launch to the end of a team's queue. If the team is idle
+// the launch starts right away instead of waiting for the background processor.
+func (s *LaunchQueueService) QueueLaunch(userId, teamId int64, dto *models.QueueLaunchDTO) (*models.TeamQueueDTO, error) {
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+	if !team.IsUnlocked {
+		return nil, ErrTeamLocked
+	}
+
+	var repeatCount *int
+	if !dto.UntilStopped {
+		count := dto.RepeatCount
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 || count > MaxQueueRepeatCount {
+			return nil, ErrInvalidRepeatCount
+		}
+		repeatCount = &count
+	}
+
+	// Archived rifts are not returned
+	_, err = s.riftRepository.GetRiftById(dto.RiftID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRiftNotFound
+		}
+		return nil, err
+	}
+
+	isUnlocked, err := s.riftService.IsRiftUnlockedForUser(userId, dto.RiftID)
+	if err != nil {
+		return nil, err
+	}
+	if !isUnlocked {
+		return nil, ErrRiftLocked
+	}
+
+	// Lock the team so concurrent requests can't push the queue past its limit
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		_, err := s.teamRepository.WithTx(tx).GetTeamByIdForUpdate(teamId)
+		if err != nil {
+			return err
+		}
+
+		launchQueueRepository := s.launchQueueRepository.WithTx(tx)
+		entries, err := launchQueueRepository.GetEntriesByTeamId(teamId)
+		if err != nil {
+			return err
+		}
+		if len(entries) >= s.gameCoreService.GetLaunchQueueLimit(team.TeamNumber) {
+			return ErrLaunchQueueFull
+		}
+
+		_, err = launchQueueRepository.AddEntry(userId, teamId, dto.RiftID, repeatCount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The queue is saved either way, so a failed launch is picked up by the processor later
+	_, err = s.launchNext(team)
+	if err != nil {
+		util.LogError(fmt.Errorf("failed to launch queued expedition for team %d: %w", teamId, err))
+	}
+
+	return s.buildQueueDTO(team)
+}
+
+func (s *LaunchQueueService) RemoveQueueEntry(userId, teamId, entryId int64) (*models.TeamQueueDTO, error) {
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.launchQueueRepository.GetEntriesByTeamId(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, entry := range entries {
+		if entry.ID == entryId {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrQueueEntryNotFound
+	}
+
+	err = s.launchQueueRepository.RemoveEntry(entryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildQueueDTO(team)
+}
+
+// ClearTeamQueue stops all queued and repeating launches. The current expedition keeps going.
+func (s *LaunchQueueService) ClearTeamQueue(userId, teamId int64) error {
+	_, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return err
+	}
+
+	return s.launchQueueRepository.ClearTeamQueue(teamId)
+}
+
+// LaunchQueuedExpeditions starts the next queued launch for up to limit idle teams.
+// Each team is handled on its own so a failure only affects that team, and every team
+// tried is sent to the back of the line so teams that keep failing can't fill the batch.
+// Returns the number of expeditions that were launched.
+func (s *LaunchQueueService) LaunchQueuedExpeditions(limit int) (int, error) {
+	teamIds, err := s.launchQueueRepository.GetIdleQueuedTeamIds(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	launched := 0
+	for _, teamId := range teamIds {
+		err := s.launchQueueRepository.MarkLaunchAttempted(teamId)
+		if err != nil {
+			util.LogError(fmt.Errorf("failed to record queued launch attempt for team %d: %w", teamId, err))
+		}
+
+		team, err := s.teamRepository.GetTeamById(teamId)
+		if err != nil {
+			util.LogError(fmt.Errorf("failed to load team %d for queued launch: %w", teamId, err))
+			continue
+		}
+
+		wasLaunched, err := s.launchNext(team)
+		if err != nil {
+			// Keep going so one bad team doesn't stall the rest of the batch
+			util.LogError(fmt.Errorf("failed to launch queued expedition for team %d: %w", teamId, err))
+			continue
+		}
+		if wasLaunched {
+			launched++
+		}
+	}
+
+	return launched, nil
+}
+
+// launchNext claims the team's processed expedition and starts the first queued launch
+// that can still run. Both happen in one transaction, so the expedition is only claimed
+// if the next one starts. Entries for rifts the player can no longer launch are dropped,
+// and the whole queue is dropped if the team has been locked.
+// Returns false without an error if the team is still out or nothing is queued.
+func (s *LaunchQueueService) launchNext(team *repositories.TeamEntity) (bool, error) {
+	entries, err := s.launchQueueRepository.GetEntriesByTeamId(team.ID)
+	if err != nil || len(entries) == 0 {
+		return false, err
+	}
+
+	launched := false
+	var dropped []*repositories.LaunchQueueEntryEntity
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		expeditionRepository := s.expeditionRepository.WithTx(tx)
+		activeExpedition, err := expeditionRepository.GetActiveExpeditionByTeamId(team.ID)
+		if err != nil {
+			return err
+		}
+		if activeExpedition != nil {
+			if !activeExpedition.Processed {
+				return nil
+			}
+
+			// Its loot went into the player's inventory when it was processed, so claiming it
+			// here only frees the team for the next launch
+			err = expeditionRepository.MarkClaimed(activeExpedition.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range entries {
+			_, err := s.expeditionService.StartExpeditionWithTx(tx, team.UserID, team.ID, entry.RiftID)
+			if err == nil {
+				launched = true
+				return s.launchQueueRepository.WithTx(tx).ConsumeEntry(entry.ID)
+			}
+			if !errors.Is(err, ErrRiftNotFound) && !errors.Is(err, ErrRiftLocked) {
+				return err
+			}
+			dropped = append(dropped, entry)
+		}
+		return errNothingLaunched
+	})
+
+	// Entries for rifts that can't be launched never will be, whatever happened to the rest
+	for _, entry := range dropped {
+		util.LogInfo(fmt.Sprintf("Dropping queued launch %d for team %d: rift %d can't be launched", entry.ID, team.ID, entry.RiftID))
+		if err := s.launchQueueRepository.RemoveEntry(entry.ID); err != nil {
+			return false, err
+		}
+	}
+
+	switch {
+	case err == nil:
+		return launched, nil
+	case errors.Is(err, errNothingLaunched):
+		return false, nil
+	case errors.Is(err, ErrTeamBusy):
+		// The player or another processor launched the team first
+		return false, nil
+	case errors.Is(err, ErrTeamLocked):
+		// A locked team can't launch anything, so stop trying until it's queued again
+		util.LogInfo(fmt.Sprintf("Clearing launch queue for locked team %d", team.ID))
+		return false, s.launchQueueRepository.ClearTeamQueue(team.ID)
+	default:
+		return false, err
+	}
+}
+
+func (s *LaunchQueueService) getOwnedTeam(userId, teamId int64) (*repositories.TeamEntity, error) {
+	team, err := s.teamRepository.GetTeamById(teamId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+	if team.UserID != userId {
+		return nil, ErrTeamNotOwned
+	}
+	return team, nil
+}
+
+func (s *LaunchQueueService) buildQueueDTO(team *repositories.TeamEntity) (*models.TeamQueueDTO, error) {
+	entries, err := s.launchQueueRepository.GetEntriesByTeamId(team.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entryDTOs := make([]*models.LaunchQueueEntryDTO, 0, len(entries))
+	for _, entry := range entries {
+		// Entries for archived rifts are dropped on their next launch attempt
+		riftName := ""
+		rift, err := s.riftRepository.GetRiftById(entry.RiftID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if rift != nil {
+			riftName = rift.Name
+		}
+
+		entryDTOs = append(entryDTOs, &models.LaunchQueueEntryDTO{
+			ID:           entry.ID,
+			RiftID:       entry.RiftID,
+			RiftName:     riftName,
+			Position:     entry.Position,
+			RepeatCount:  entry.RepeatCount,
+			UntilStopped: entry.RepeatCount == nil,
+		})
+	}
+
+	return &models.TeamQueueDTO{
+		TeamID:     team.ID,
+		MaxEntries: s.gameCoreService.GetLaunchQueueLimit(team.TeamNumber),
+		Entries:    entryDTOs,
+	}, nil
+}
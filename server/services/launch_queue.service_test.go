@@ -0,0 +1,425 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLaunchQueueRepository for LaunchQueueService tests
+type MockLaunchQueueRepository struct {
+	mock.Mock
+}
+
+func (m *MockLaunchQueueRepository) GetEntriesByTeamId(teamId int64) ([]*repositories.LaunchQueueEntryEntity, error) {
+	args := m.Called(teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.LaunchQueueEntryEntity), args.Error(1)
+}
+
+func (m *MockLaunchQueueRepository) AddEntry(userId, teamId, riftId int64, repeatCount *int) (*repositories.LaunchQueueEntryEntity, error) {
+	args := m.Called(userId, teamId, riftId, repeatCount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.LaunchQueueEntryEntity), args.Error(1)
+}
+
+func (m *MockLaunchQueueRepository) ConsumeEntry(entryId int64) error {
+	args := m.Called(entryId)
+	return args.Error(0)
+}
+
+func (m *MockLaunchQueueRepository) RemoveEntry(entryId int64) error {
+	args := m.Called(entryId)
+	return args.Error(0)
+}
+
+func (m *MockLaunchQueueRepository) ClearTeamQueue(teamId int64) error {
+	args := m.Called(teamId)
+	return args.Error(0)
+}
+
+func (m *MockLaunchQueueRepository) GetIdleQueuedTeamIds(limit int) ([]int64, error) {
+	args := m.Called(limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockLaunchQueueRepository) MarkLaunchAttempted(teamId int64) error {
+	args := m.Called(teamId)
+	return args.Error(0)
+}
+
+func (m *MockLaunchQueueRepository) WithTx(tx *database.AppDataSource) repositories.ILaunchQueueRepository {
+	return m
+}
+
+// rollbackTrackingUnitOfWork runs transactions against the mocks like MockUnitOfWork and
+// counts the ones that would have been rolled back
+type rollbackTrackingUnitOfWork struct {
+	MockUnitOfWork
+	rollbacks int
+}
+
+func (u *rollbackTrackingUnitOfWork) WithinTransaction(fn func(tx *database.AppDataSource) error) error {
+	err := u.MockUnitOfWork.WithinTransaction(fn)
+	if err != nil {
+		u.rollbacks++
+	}
+	return err
+}
+
+type launchQueueTestMocks struct {
+	launchQueueRepo   *MockLaunchQueueRepository
+	teamRepo          *MockTeamRepository
+	riftRepo          *MockRiftRepository
+	expeditionRepo    *MockExpeditionRepository
+	expeditionService *MockExpeditionService
+	riftService       *MockRiftService
+	gameCoreService   *MockGameCoreService
+	unitOfWork        *rollbackTrackingUnitOfWork
+}
+
+func newLaunchQueueTestService() (ILaunchQueueService, *launchQueueTestMocks) {
+	mocks := &launchQueueTestMocks{
+		launchQueueRepo:   new(MockLaunchQueueRepository),
+		teamRepo:          new(MockTeamRepository),
+		riftRepo:          new(MockRiftRepository),
+		expeditionRepo:    new(MockExpeditionRepository),
+		expeditionService: new(MockExpeditionService),
+		riftService:       new(MockRiftService),
+		gameCoreService:   new(MockGameCoreService),
+		unitOfWork:        new(rollbackTrackingUnitOfWork),
+	}
+	service := NewLaunchQueueService(
+		mocks.launchQueueRepo,
+		mocks.teamRepo,
+		mocks.riftRepo,
+		mocks.expeditionRepo,
+		mocks.expeditionService,
+		mocks.riftService,
+		mocks.gameCoreService,
+		mocks.unitOfWork,
+	)
+	return service, mocks
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+var queueTestTeam = &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 2, IsUnlocked: true}
+var queueTestRift = &repositories.RiftEntity{ID: 5, Name: "Ember Wastes", WorldType: "fire"}
+
+// Test QueueLaunch - Idle Team Launches Right Away
+func TestLaunchQueueService_QueueLaunch_LaunchesIdleTeam(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1, RepeatCount: intPtr(3)}
+
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.riftRepo.On("GetRiftById", int64(5)).Return(queueTestRift, nil)
+	mocks.riftService.On("IsRiftUnlockedForUser", int64(1), int64(5)).Return(true, nil)
+	mocks.teamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{}, nil).Once()
+	mocks.gameCoreService.On("GetLaunchQueueLimit", 2).Return(3)
+	mocks.launchQueueRepo.On("AddEntry", int64(1), int64(1), int64(5), intPtr(3)).Return(entry, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil).Once()
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(&models.ExpeditionResponseDTO{ID: 99}, nil)
+	mocks.launchQueueRepo.On("ConsumeEntry", int64(10)).Return(nil)
+	remaining := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1, RepeatCount: intPtr(2)}
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{remaining}, nil).Once()
+
+	// Act
+	result, err := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5, RepeatCount: 3})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.MaxEntries)
+	assert.Len(t, result.Entries, 1)
+	assert.Equal(t, "Ember Wastes", result.Entries[0].RiftName)
+	assert.Equal(t, 2, *result.Entries[0].RepeatCount)
+	assert.False(t, result.Entries[0].UntilStopped)
+	mocks.launchQueueRepo.AssertExpectations(t)
+	mocks.expeditionService.AssertExpectations(t)
+}
+
+// Test QueueLaunch - Until Stopped Has No Repeat Count
+func TestLaunchQueueService_QueueLaunch_UntilStopped(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1}
+	busyExpedition := &repositories.ExpeditionEntity{ID: 7, TeamID: 1, Processed: false}
+
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.riftRepo.On("GetRiftById", int64(5)).Return(queueTestRift, nil)
+	mocks.riftService.On("IsRiftUnlockedForUser", int64(1), int64(5)).Return(true, nil)
+	mocks.teamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{}, nil).Once()
+	mocks.gameCoreService.On("GetLaunchQueueLimit", 2).Return(3)
+	mocks.launchQueueRepo.On("AddEntry", int64(1), int64(1), int64(5), (*int)(nil)).Return(entry, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(busyExpedition, nil)
+
+	// Act
+	result, err := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5, UntilStopped: true})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result.Entries, 1)
+	assert.Nil(t, result.Entries[0].RepeatCount)
+	assert.True(t, result.Entries[0].UntilStopped)
+	mocks.expeditionService.AssertNotCalled(t, "StartExpedition", mock.Anything, mock.Anything, mock.Anything) // Team is still out
+}
+
+// Test QueueLaunch - Queue Full
+func TestLaunchQueueService_QueueLaunch_QueueFull(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entries := []*repositories.LaunchQueueEntryEntity{
+		{ID: 1, TeamID: 1, RiftID: 5, Position: 1},
+		{ID: 2, TeamID: 1, RiftID: 5, Position: 2},
+		{ID: 3, TeamID: 1, RiftID: 5, Position: 3},
+	}
+
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.riftRepo.On("GetRiftById", int64(5)).Return(queueTestRift, nil)
+	mocks.riftService.On("IsRiftUnlockedForUser", int64(1), int64(5)).Return(true, nil)
+	mocks.teamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return(entries, nil)
+	mocks.gameCoreService.On("GetLaunchQueueLimit", 2).Return(3)
+
+	// Act
+	result, err := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrLaunchQueueFull)
+	assert.Nil(t, result)
+	mocks.launchQueueRepo.AssertNotCalled(t, "AddEntry", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Test QueueLaunch - Invalid Repeat Count
+func TestLaunchQueueService_QueueLaunch_InvalidRepeatCount(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+
+	// Act
+	_, negativeErr := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5, RepeatCount: -1})
+	_, tooManyErr := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5, RepeatCount: MaxQueueRepeatCount + 1})
+
+	// Assert
+	assert.ErrorIs(t, negativeErr, ErrInvalidRepeatCount)
+	assert.ErrorIs(t, tooManyErr, ErrInvalidRepeatCount)
+}
+
+// Test QueueLaunch - Rift Locked
+func TestLaunchQueueService_QueueLaunch_RiftLocked(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.riftRepo.On("GetRiftById", int64(5)).Return(queueTestRift, nil)
+	mocks.riftService.On("IsRiftUnlockedForUser", int64(1), int64(5)).Return(false, nil)
+
+	// Act
+	result, err := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrRiftLocked)
+	assert.Nil(t, result)
+}
+
+// Test QueueLaunch - Team Not Owned
+func TestLaunchQueueService_QueueLaunch_TeamNotOwned(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	otherTeam := &repositories.TeamEntity{ID: 1, UserID: 999, TeamNumber: 1, IsUnlocked: true}
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(otherTeam, nil)
+
+	// Act
+	result, err := service.QueueLaunch(1, 1, &models.QueueLaunchDTO{RiftID: 5})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamNotOwned)
+	assert.Nil(t, result)
+}
+
+// Test RemoveQueueEntry - Entry From Another Team
+func TestLaunchQueueService_RemoveQueueEntry_NotFound(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{
+		{ID: 10, TeamID: 1, RiftID: 5, Position: 1},
+	}, nil)
+
+	// Act
+	result, err := service.RemoveQueueEntry(1, 1, 42)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrQueueEntryNotFound)
+	assert.Nil(t, result)
+	mocks.launchQueueRepo.AssertNotCalled(t, "RemoveEntry", mock.Anything)
+}
+
+// Test LaunchQueuedExpeditions - Claims Finished Expedition And Launches Next
+func TestLaunchQueueService_LaunchQueuedExpeditions_ClaimsAndLaunches(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1, RepeatCount: intPtr(1)}
+	finishedExpedition := &repositories.ExpeditionEntity{ID: 7, TeamID: 1, Processed: true}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(finishedExpedition, nil)
+	mocks.expeditionRepo.On("MarkClaimed", int64(7)).Return(nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(&models.ExpeditionResponseDTO{ID: 8}, nil)
+	mocks.launchQueueRepo.On("ConsumeEntry", int64(10)).Return(nil)
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, launched)
+	mocks.expeditionRepo.AssertExpectations(t)
+	mocks.launchQueueRepo.AssertExpectations(t)
+}
+
+// Test LaunchQueuedExpeditions - Drops Entries For Locked Rifts
+func TestLaunchQueueService_LaunchQueuedExpeditions_DropsLockedRift(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	lockedEntry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 6, Position: 1}
+	nextEntry := &repositories.LaunchQueueEntryEntity{ID: 11, UserID: 1, TeamID: 1, RiftID: 5, Position: 2, RepeatCount: intPtr(2)}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{lockedEntry, nextEntry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(6)).Return(nil, ErrRiftLocked)
+	mocks.launchQueueRepo.On("RemoveEntry", int64(10)).Return(nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(&models.ExpeditionResponseDTO{ID: 8}, nil)
+	mocks.launchQueueRepo.On("ConsumeEntry", int64(11)).Return(nil)
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, launched)
+	mocks.launchQueueRepo.AssertExpectations(t)
+	mocks.launchQueueRepo.AssertNotCalled(t, "ConsumeEntry", int64(10))
+}
+
+// Test LaunchQueuedExpeditions - Team Launched By Someone Else
+func TestLaunchQueueService_LaunchQueuedExpeditions_TeamBusy(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1, RepeatCount: intPtr(1)}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(nil, ErrTeamBusy)
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	mocks.launchQueueRepo.AssertNotCalled(t, "ConsumeEntry", mock.Anything) // Entry stays queued
+	mocks.launchQueueRepo.AssertNotCalled(t, "RemoveEntry", mock.Anything)
+}
+
+// Test LaunchQueuedExpeditions - Failed Launch Leaves The Finished Expedition Unclaimed
+func TestLaunchQueueService_LaunchQueuedExpeditions_StartErrorRollsBackClaim(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1, RepeatCount: intPtr(1)}
+	finishedExpedition := &repositories.ExpeditionEntity{ID: 7, TeamID: 1, Processed: true}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(finishedExpedition, nil)
+	mocks.expeditionRepo.On("MarkClaimed", int64(7)).Return(nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(nil, errors.New("database error"))
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	assert.Equal(t, 1, mocks.unitOfWork.rollbacks) // The claim is rolled back with the failed start
+	mocks.launchQueueRepo.AssertExpectations(t)
+	mocks.launchQueueRepo.AssertNotCalled(t, "ConsumeEntry", mock.Anything)
+}
+
+// Test LaunchQueuedExpeditions - Nothing Launchable Leaves The Finished Expedition Unclaimed
+func TestLaunchQueueService_LaunchQueuedExpeditions_AllEntriesDroppedRollsBackClaim(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 6, Position: 1}
+	finishedExpedition := &repositories.ExpeditionEntity{ID: 7, TeamID: 1, Processed: true}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(finishedExpedition, nil)
+	mocks.expeditionRepo.On("MarkClaimed", int64(7)).Return(nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(6)).Return(nil, ErrRiftNotFound)
+	mocks.launchQueueRepo.On("RemoveEntry", int64(10)).Return(nil)
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	assert.Equal(t, 1, mocks.unitOfWork.rollbacks)
+	mocks.launchQueueRepo.AssertExpectations(t) // The dropped entry is still removed
+}
+
+// Test LaunchQueuedExpeditions - Locked Team Has Its Queue Cleared
+func TestLaunchQueueService_LaunchQueuedExpeditions_LockedTeamClearsQueue(t *testing.T) {
+	// Arrange
+	service, mocks := newLaunchQueueTestService()
+	entry := &repositories.LaunchQueueEntryEntity{ID: 10, UserID: 1, TeamID: 1, RiftID: 5, Position: 1}
+
+	mocks.launchQueueRepo.On("GetIdleQueuedTeamIds", 50).Return([]int64{1}, nil)
+	mocks.launchQueueRepo.On("MarkLaunchAttempted", int64(1)).Return(nil)
+	mocks.teamRepo.On("GetTeamById", int64(1)).Return(queueTestTeam, nil)
+	mocks.launchQueueRepo.On("GetEntriesByTeamId", int64(1)).Return([]*repositories.LaunchQueueEntryEntity{entry}, nil)
+	mocks.expeditionRepo.On("GetActiveExpeditionByTeamId", int64(1)).Return(nil, nil)
+	mocks.expeditionService.On("StartExpeditionWithTx", mock.Anything, int64(1), int64(1), int64(5)).Return(nil, ErrTeamLocked)
+	mocks.launchQueueRepo.On("ClearTeamQueue", int64(1)).Return(nil)
+
+	// Act
+	launched, err := service.LaunchQueuedExpeditions(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, launched)
+	mocks.launchQueueRepo.AssertExpectations(t)
+}
@@ -23,6 +23,10 @@ const (
 	LeaderboardTypeLegendary   = "legendary"
 	LeaderboardTypePower       = "power"
 	LeaderboardTypeExpeditions = "expeditions"
+	LeaderboardTypeEchoes      = "echoes"
+
+	// LeaderboardTypeGuildPower ranks guilds rather than players
+	LeaderboardTypeGuildPower = "guild_power"
 )
 
 // ILeaderboardService defines the interface for leaderboard operations
@@ -32,15 +36,17 @@ type ILeaderboardService interface {
 
 // LeaderboardService implements leaderboard business logic
 type LeaderboardService struct {
-	repo  repositories.ILeaderboardRepository
-	mutex sync.Mutex // Prevents concurrent cache rebuilds for same type
+	repo            repositories.ILeaderboardRepository
+	eventHubService IEventHubService
+	mutex           sync.Mutex // Prevents concurrent cache rebuilds for same type
 }
 
 // NewLeaderboardService creates a new leaderboard service
-func NewLeaderboardService(repo repositories.ILeaderboardRepository) ILeaderboardService {
+func NewLeaderboardService(repo repositories.ILeaderboardRepository, eventHubService IEventHubService) ILeaderboardService {
 	return &LeaderboardService{
-		repo:  repo,
-		mutex: sync.Mutex{},
+		repo:            repo,
+		eventHubService: eventHubService,
+		mutex:           sync.Mutex{},
 	}
 }
 
@@ -76,8 +82,14 @@ func (s *LeaderboardService) GetLeaderboard(leaderboardType string, currentUserI
 		return nil, fmt.Errorf("failed to get top rankings: %w", err)
 	}
 
-	// Fetch current user's rank
-	userRank, err := s.repo.GetUserRank(leaderboardType, currentUserID)
+	// Fetch current user's rank, or their guild's on a guild leaderboard
+	isGuildLeaderboard := isGuildLeaderboardType(leaderboardType)
+	var userRank *repositories.LeaderboardCacheItemEntity
+	if isGuildLeaderboard {
+		userRank, err = s.repo.GetUserGuildRank(leaderboardType, currentUserID)
+	} else {
+		userRank, err = s.repo.GetUserRank(leaderboardType, currentUserID)
+	}
 	if err != nil && err != sql.ErrNoRows {
 		util.LogError(err)
 		return nil, fmt.Errorf("failed to get user rank: %w", err)
@@ -95,6 +107,9 @@ func (s *LeaderboardService) GetLeaderboard(leaderboardType string, currentUserI
 	userInTopPlayers := false
 	for _, player := range topPlayers {
 		isCurrentUser := player.UserID == currentUserID
+		if isGuildLeaderboard {
+			isCurrentUser = userRank != nil && player.GuildID == userRank.GuildID
+		}
 		if isCurrentUser {
 			userInTopPlayers = true
 		}
@@ -102,6 +117,7 @@ func (s *LeaderboardService) GetLeaderboard(leaderboardType string, currentUserI
 		response.TopPlayers = append(response.TopPlayers, models.LeaderboardEntry{
 			Rank:          player.Rank,
 			UserID:        player.UserID,
+			GuildID:       player.GuildID,
 			Username:      player.Username,
 			Score:         player.Score,
 			IsCurrentUser: isCurrentUser,
@@ -113,6 +129,7 @@ func (s *LeaderboardService) GetLeaderboard(leaderboardType string, currentUserI
 		response.CurrentUserRank = &models.LeaderboardEntry{
 			Rank:          userRank.Rank,
 			UserID:        userRank.UserID,
+			GuildID:       userRank.GuildID,
 			Username:      userRank.Username,
 			Score:         userRank.Score,
 			IsCurrentUser: true,
@@ -160,6 +177,10 @@ func (s *LeaderboardService) rebuildCache(leaderboardType string) error {
 		rawData, err = s.repo.GetPowerScores()
 	case LeaderboardTypeExpeditions:
 		rawData, err = s.repo.GetExpeditionCounts()
+	case LeaderboardTypeEchoes:
+		rawData, err = s.repo.GetEchoEncounterCounts()
+	case LeaderboardTypeGuildPower:
+		rawData, err = s.repo.GetGuildPowerScores()
 	default:
 		return fmt.Errorf("invalid leaderboard type: %s", leaderboardType)
 	}
@@ -168,9 +189,21 @@ func (s *LeaderboardService) rebuildCache(leaderboardType string) error {
 		return fmt.Errorf("failed to get raw data: %w", err)
 	}
 
-	// Assign ranks with deterministic tie-breaking (by user_id)
+	// Assign ranks with deterministic tie-breaking (by user_id, or guild_id)
 	rankedData := s.assignRanks(rawData, leaderboardType)
 
+	// Remember the old top players so they can be told about rank changes. Not knowing
+	// them only skips the events, so it doesn't fail the rebuild. Guilds aren't players,
+	// so guild leaderboards don't send them.
+	var previousTop []*repositories.LeaderboardCacheItemEntity
+	if !isGuildLeaderboardType(leaderboardType) {
+		previousTop, err = s.repo.GetTopRankings(leaderboardType, TopPlayersLimit)
+		if err != nil {
+			util.LogError(fmt.Errorf("failed to get previous top rankings: %w", err))
+			previousTop = nil
+		}
+	}
+
 	// Truncate old cache
 	if err := s.repo.TruncateCache(leaderboardType); err != nil {
 		return fmt.Errorf("failed to truncate cache: %w", err)
@@ -188,11 +221,52 @@ func (s *LeaderboardService) rebuildCache(leaderboardType string) error {
 
 	util.LogInfo("Cache rebuilt successfully")
 
+	if previousTop != nil {
+		s.publishRankChanges(leaderboardType, previousTop, rankedData)
+	}
+
 	return nil
 }
 
+// publishRankChanges tells every player who is in the top players, or was before the
+// rebuild, that their rank has changed
+func (s *LeaderboardService) publishRankChanges(leaderboardType string, previousTop, rankedData []*repositories.LeaderboardCacheItemEntity) {
+	oldRanks := make(map[int]int, len(previousTop))
+	for _, item := range previousTop {
+		oldRanks[item.UserID] = item.Rank
+	}
+
+	for _, item := range rankedData {
+		oldRank, wasTop := oldRanks[item.UserID]
+		delete(oldRanks, item.UserID)
+		if (!wasTop && item.Rank > TopPlayersLimit) || (wasTop && oldRank == item.Rank) {
+			continue
+		}
+
+		event := &models.LeaderboardRankChangedEventDTO{
+			LeaderboardType: leaderboardType,
+			NewRank:         &item.Rank,
+			Score:           item.Score,
+		}
+		if wasTop {
+			event.OldRank = &oldRank
+		}
+		s.eventHubService.Publish(int64(item.UserID), models.GameEventLeaderboardRankChanged, event)
+	}
+
+	// Anyone left was in the top players but no longer has a score
+	for _, item := range previousTop {
+		if _, unranked := oldRanks[item.UserID]; unranked {
+			s.eventHubService.Publish(int64(item.UserID), models.GameEventLeaderboardRankChanged, &models.LeaderboardRankChangedEventDTO{
+				LeaderboardType: leaderboardType,
+				OldRank:         &item.Rank,
+			})
+		}
+	}
+}
+
 // assignRanks assigns ranks to the raw data with deterministic tie-breaking
-// Tied players are sorted by user_id for consistent ordering
+// Tied players are sorted by user_id, and tied guilds by guild_id, for consistent ordering
 func (s *LeaderboardService) assignRanks(items []*repositories.LeaderboardCacheItemEntity, leaderboardType string) []*repositories.LeaderboardCacheItemEntity {
 	if len(items) == 0 {
 		return items
@@ -201,7 +275,10 @@ func (s *LeaderboardService) assignRanks(items []*repositories.LeaderboardCacheI
 	// Sort by score descending, then by user_id ascending for tie-breaking
 	sort.Slice(items, func(i, j int) bool {
 		if items[i].Score == items[j].Score {
-			return items[i].UserID < items[j].UserID
+			if items[i].UserID != items[j].UserID {
+				return items[i].UserID < items[j].UserID
+			}
+			return items[i].GuildID < items[j].GuildID
 		}
 		return items[i].Score > items[j].Score
 	})
@@ -225,6 +302,13 @@ func isValidLeaderboardType(leaderboardType string) bool {
 		LeaderboardTypeLegendary:   true,
 		LeaderboardTypePower:       true,
 		LeaderboardTypeExpeditions: true,
+		LeaderboardTypeEchoes:      true,
+		LeaderboardTypeGuildPower:  true,
 	}
 	return validTypes[leaderboardType]
 }
+
+// isGuildLeaderboardType checks if the leaderboard ranks guilds rather than players
+func isGuildLeaderboardType(leaderboardType string) bool {
+	return leaderboardType == LeaderboardTypeGuildPower
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
 )
 
 // ============================================================================
@@ -14,16 +15,19 @@ import (
 // ============================================================================
 
 type mockLeaderboardRepository struct {
-	getCacheMetadataFunc    func(leaderboardType string) (*repositories.LeaderboardCacheEntity, error)
-	setSyncInProgressFunc   func(leaderboardType string, inProgress bool) error
-	updateLastSyncedFunc    func(leaderboardType string) error
-	getTopRankingsFunc      func(leaderboardType string, limit int) ([]*repositories.LeaderboardCacheItemEntity, error)
-	getUserRankFunc         func(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error)
-	truncateCacheFunc       func(leaderboardType string) error
-	insertCacheItemsFunc    func(items []*repositories.LeaderboardCacheItemEntity) error
-	getLegendaryItemsFunc   func() ([]*repositories.LeaderboardCacheItemEntity, error)
-	getPowerScoresFunc      func() ([]*repositories.LeaderboardCacheItemEntity, error)
-	getExpeditionCountsFunc func() ([]*repositories.LeaderboardCacheItemEntity, error)
+	getCacheMetadataFunc       func(leaderboardType string) (*repositories.LeaderboardCacheEntity, error)
+	setSyncInProgressFunc      func(leaderboardType string, inProgress bool) error
+	updateLastSyncedFunc       func(leaderboardType string) error
+	getTopRankingsFunc         func(leaderboardType string, limit int) ([]*repositories.LeaderboardCacheItemEntity, error)
+	getUserRankFunc            func(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error)
+	truncateCacheFunc          func(leaderboardType string) error
+	insertCacheItemsFunc       func(items []*repositories.LeaderboardCacheItemEntity) error
+	getLegendaryItemsFunc      func() ([]*repositories.LeaderboardCacheItemEntity, error)
+	getPowerScoresFunc         func() ([]*repositories.LeaderboardCacheItemEntity, error)
+	getExpeditionCountsFunc    func() ([]*repositories.LeaderboardCacheItemEntity, error)
+	getEchoEncounterCountsFunc func() ([]*repositories.LeaderboardCacheItemEntity, error)
+	getUserGuildRankFunc       func(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error)
+	getGuildPowerScoresFunc    func() ([]*repositories.LeaderboardCacheItemEntity, error)
 }
 
 func (m *mockLeaderboardRepository) GetCacheMetadata(leaderboardType string) (*repositories.LeaderboardCacheEntity, error) {
@@ -96,6 +100,27 @@ func (m *mockLeaderboardRepository) GetExpeditionCounts() ([]*repositories.Leade
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockLeaderboardRepository) GetEchoEncounterCounts() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	if m.getEchoEncounterCountsFunc != nil {
+		return m.getEchoEncounterCountsFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockLeaderboardRepository) GetUserGuildRank(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error) {
+	if m.getUserGuildRankFunc != nil {
+		return m.getUserGuildRankFunc(leaderboardType, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockLeaderboardRepository) GetGuildPowerScores() ([]*repositories.LeaderboardCacheItemEntity, error) {
+	if m.getGuildPowerScoresFunc != nil {
+		return m.getGuildPowerScoresFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
 // ============================================================================
 // Test Data Helpers
 // ============================================================================
@@ -169,7 +194,7 @@ func TestGetLeaderboard_Success_UserInTop20(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", currentUserID)
@@ -237,7 +262,7 @@ func TestGetLeaderboard_Success_UserOutsideTop20(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", currentUserID)
@@ -289,7 +314,7 @@ func TestGetLeaderboard_Success_UserNotRanked(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", currentUserID)
@@ -314,7 +339,7 @@ func TestGetLeaderboard_Success_UserNotRanked(t *testing.T) {
 func TestGetLeaderboard_InvalidType(t *testing.T) {
 	// Setup
 	mockRepo := &mockLeaderboardRepository{}
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("invalid_type", 1)
@@ -337,7 +362,7 @@ func TestGetLeaderboard_MetadataError(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", 1)
@@ -365,7 +390,7 @@ func TestGetLeaderboard_TopRankingsError(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", 1)
@@ -397,7 +422,7 @@ func TestGetLeaderboard_StaleCache_TriggersRebuild(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", 1)
@@ -432,7 +457,7 @@ func TestGetLeaderboard_CacheSyncing_DoesNotTriggerRebuild(t *testing.T) {
 		},
 	}
 
-	service := NewLeaderboardService(mockRepo)
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 	// Execute
 	result, err := service.GetLeaderboard("legendary", 1)
@@ -478,7 +503,8 @@ func TestRebuildCache_Legendary_Success(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -545,7 +571,8 @@ func TestRebuildCache_Power_Success(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -583,7 +610,8 @@ func TestRebuildCache_Expeditions_Success(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -595,6 +623,181 @@ func TestRebuildCache_Expeditions_Success(t *testing.T) {
 	}
 }
 
+func TestRebuildCache_GuildPower_Success(t *testing.T) {
+	// Setup
+	var inserted []*repositories.LeaderboardCacheItemEntity
+
+	mockRepo := &mockLeaderboardRepository{
+		getCacheMetadataFunc: func(leaderboardType string) (*repositories.LeaderboardCacheEntity, error) {
+			return createTestMetadata(leaderboardType, time.Now(), false), nil
+		},
+		setSyncInProgressFunc: func(leaderboardType string, inProgress bool) error {
+			return nil
+		},
+		getGuildPowerScoresFunc: func() ([]*repositories.LeaderboardCacheItemEntity, error) {
+			return []*repositories.LeaderboardCacheItemEntity{
+				{GuildID: 3, Username: "Rift Walkers", Score: 200},
+				{GuildID: 2, Username: "Void Seekers", Score: 500},
+				{GuildID: 1, Username: "Echo Hunters", Score: 200},
+			}, nil
+		},
+		truncateCacheFunc: func(leaderboardType string) error {
+			return nil
+		},
+		insertCacheItemsFunc: func(items []*repositories.LeaderboardCacheItemEntity) error {
+			inserted = items
+			return nil
+		},
+		updateLastSyncedFunc: func(leaderboardType string) error {
+			return nil
+		},
+		// getTopRankingsFunc is unset: guild leaderboards don't publish rank changes
+	}
+
+	service := &LeaderboardService{
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
+	}
+
+	// Execute
+	err := service.rebuildCache("guild_power")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []struct {
+		guildID int
+		rank    int
+	}{{2, 1}, {1, 2}, {3, 2}} // Tied guilds are ordered by guild_id
+	if len(inserted) != len(expected) {
+		t.Fatalf("Expected %d items, got: %d", len(expected), len(inserted))
+	}
+	for i, want := range expected {
+		if inserted[i].GuildID != want.guildID || inserted[i].Rank != want.rank {
+			t.Errorf("Position %d: expected guild %d at rank %d, got guild %d at rank %d",
+				i, want.guildID, want.rank, inserted[i].GuildID, inserted[i].Rank)
+		}
+	}
+}
+
+func TestGetLeaderboard_GuildPower_MarksCurrentUsersGuild(t *testing.T) {
+	// Setup
+	currentTime := time.Now()
+	currentUserID := 7
+	guilds := []*repositories.LeaderboardCacheItemEntity{
+		{GuildID: 2, Username: "Void Seekers", Score: 500, Rank: 1},
+		{GuildID: 1, Username: "Echo Hunters", Score: 200, Rank: 2},
+	}
+
+	mockRepo := &mockLeaderboardRepository{
+		getCacheMetadataFunc: func(leaderboardType string) (*repositories.LeaderboardCacheEntity, error) {
+			return createTestMetadata(leaderboardType, currentTime, false), nil
+		},
+		getTopRankingsFunc: func(leaderboardType string, limit int) ([]*repositories.LeaderboardCacheItemEntity, error) {
+			return guilds, nil
+		},
+		getUserGuildRankFunc: func(leaderboardType string, userID int) (*repositories.LeaderboardCacheItemEntity, error) {
+			if userID != currentUserID {
+				t.Errorf("Expected guild rank lookup for user %d, got: %d", currentUserID, userID)
+			}
+			return guilds[1], nil
+		},
+		// getUserRankFunc is unset: guild leaderboards look up the user's guild instead
+	}
+
+	service := NewLeaderboardService(mockRepo, NewEventHubService())
+
+	// Execute
+	result, err := service.GetLeaderboard("guild_power", currentUserID)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.TopPlayers[0].IsCurrentUser {
+		t.Error("Expected other guild not to be marked as current user")
+	}
+	if !result.TopPlayers[1].IsCurrentUser || result.TopPlayers[1].GuildID != 1 {
+		t.Errorf("Expected user's guild to be marked as current user, got: %+v", result.TopPlayers[1])
+	}
+	if result.CurrentUserRank != nil {
+		t.Error("Expected CurrentUserRank to be nil when the user's guild is in the top 20")
+	}
+}
+
+func TestRebuildCache_PublishesRankChanges(t *testing.T) {
+	// Setup
+	previousTop := []*repositories.LeaderboardCacheItemEntity{
+		{UserID: 1, Rank: 1, Score: 400},
+		{UserID: 3, Rank: 2, Score: 300},
+		{UserID: 9, Rank: 3, Score: 10}, // No longer has a score
+	}
+
+	mockRepo := &mockLeaderboardRepository{
+		getCacheMetadataFunc: func(leaderboardType string) (*repositories.LeaderboardCacheEntity, error) {
+			return createTestMetadata(leaderboardType, time.Now(), false), nil
+		},
+		setSyncInProgressFunc: func(leaderboardType string, inProgress bool) error {
+			return nil
+		},
+		getLegendaryItemsFunc: func() ([]*repositories.LeaderboardCacheItemEntity, error) {
+			return createTestRawData(), nil
+		},
+		getTopRankingsFunc: func(leaderboardType string, limit int) ([]*repositories.LeaderboardCacheItemEntity, error) {
+			return previousTop, nil
+		},
+		truncateCacheFunc: func(leaderboardType string) error {
+			return nil
+		},
+		insertCacheItemsFunc: func(items []*repositories.LeaderboardCacheItemEntity) error {
+			return nil
+		},
+		updateLastSyncedFunc: func(leaderboardType string) error {
+			return nil
+		},
+	}
+
+	eventHub := NewEventHubService()
+	subscriptions := map[int64]*EventSubscription{}
+	for _, userId := range []int64{1, 2, 3, 9} {
+		subscriptions[userId] = eventHub.Subscribe(userId, 0)
+	}
+
+	service := &LeaderboardService{
+		repo:            mockRepo,
+		eventHubService: eventHub,
+	}
+
+	// Execute
+	err := service.rebuildCache("legendary")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// User 1 kept rank 1
+	assertNoEvent(t, subscriptions[1])
+
+	newToTop := receive(t, subscriptions[2]).Data.(*models.LeaderboardRankChangedEventDTO)
+	if newToTop.OldRank != nil || newToTop.NewRank == nil || *newToTop.NewRank != 1 || newToTop.Score != 500 {
+		t.Errorf("Expected user 2 to enter the top at rank 1, got: %+v", newToTop)
+	}
+
+	moved := receive(t, subscriptions[3]).Data.(*models.LeaderboardRankChangedEventDTO)
+	if moved.OldRank == nil || *moved.OldRank != 2 || moved.NewRank == nil || *moved.NewRank != 3 {
+		t.Errorf("Expected user 3 to move from rank 2 to 3, got: %+v", moved)
+	}
+
+	unranked := receive(t, subscriptions[9]).Data.(*models.LeaderboardRankChangedEventDTO)
+	if unranked.OldRank == nil || *unranked.OldRank != 3 || unranked.NewRank != nil {
+		t.Errorf("Expected user 9 to lose rank 3, got: %+v", unranked)
+	}
+}
+
 func TestRebuildCache_AlreadySyncing_Skips(t *testing.T) {
 	// Setup
 	mockRepo := &mockLeaderboardRepository{
@@ -604,7 +807,8 @@ func TestRebuildCache_AlreadySyncing_Skips(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -628,7 +832,8 @@ func TestRebuildCache_InvalidType(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -655,7 +860,8 @@ func TestRebuildCache_GetRawDataError(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -687,7 +893,8 @@ func TestRebuildCache_TruncateError(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -722,7 +929,8 @@ func TestRebuildCache_InsertError(t *testing.T) {
 	}
 
 	service := &LeaderboardService{
-		repo: mockRepo,
+		repo:            mockRepo,
+		eventHubService: NewEventHubService(),
 	}
 
 	// Execute
@@ -845,6 +1053,8 @@ func TestIsValidLeaderboardType(t *testing.T) {
 		{"legendary", true},
 		{"power", true},
 		{"expeditions", true},
+		{"echoes", true},
+		{"guild_power", true},
 		{"invalid", false},
 		{"", false},
 		{"LEGENDARY", false}, // Case sensitive
@@ -879,7 +1089,7 @@ func TestGetLeaderboard_AllLeaderboardTypes(t *testing.T) {
 				},
 			}
 
-			service := NewLeaderboardService(mockRepo)
+			service := NewLeaderboardService(mockRepo, NewEventHubService())
 
 			// Execute
 			result, err := service.GetLeaderboard(leaderboardType, 1)
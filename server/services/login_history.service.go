@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/snowlynxsoftware/parallax-game/config"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+// loginHistoryLimit is how many recent logins a user can see
+const loginHistoryLimit = 20
+
+type ILoginHistoryService interface {
+	RecordLogin(userId int, method models.LoginMethod, outcome models.LoginOutcome, client *models.ClientInfoDTO)
+	GetRecentLogins(userId int) ([]*models.UserLoginDTO, error)
+}
+
+// LoginHistoryService keeps a record of every login attempt against an account, and
+// emails the user when their account is logged in to from a new device
+type LoginHistoryService struct {
+	loginHistoryRepository repositories.IUserLoginHistoryRepository
+	userRepository         repositories.IUserRepository
+	emailService           IEmailService
+	configService          config.IAppConfig
+}
+
+func NewLoginHistoryService(
+	loginHistoryRepository repositories.IUserLoginHistoryRepository,
+	userRepository repositories.IUserRepository,
+	emailService IEmailService,
+	configService config.IAppConfig,
+) ILoginHistoryService {
+	return &LoginHistoryService{
+		loginHistoryRepository: loginHistoryRepository,
+		userRepository:         userRepository,
+		emailService:           emailService,
+		configService:          configService,
+	}
+}
+
+// RecordLogin adds a login attempt to the user's history. A successful login from a
+// user agent and IP the user hasn't logged in from before gets them an email, unless
+// it's their first login. Errors are only logged, a login doesn't fail because it
+// couldn't be recorded.
+func (s *LoginHistoryService) RecordLogin(userId int, method models.LoginMethod, outcome models.LoginOutcome, client *models.ClientInfoDTO) {
+	userAgent, ipAddress := describeClient(client)
+
+	// Checked before the login is recorded, or it would always have been seen
+	isNewDevice := false
+	if outcome == models.LoginOutcomeSuccess {
+		var err error
+		isNewDevice, err = s.isNewDevice(int64(userId), userAgent, ipAddress)
+		if err != nil {
+			util.LogError(err)
+		}
+	}
+
+	login, err := s.loginHistoryRepository.CreateLoginHistory(int64(userId), string(method), string(outcome), ipAddress, userAgent)
+	if err != nil {
+		util.LogErrorWithStackTrace(err)
+		return
+	}
+
+	if isNewDevice {
+		s.sendNewDeviceEmail(login)
+	}
+}
+
+// GetRecentLogins returns the user's most recent login attempts, newest first
+func (s *LoginHistoryService) GetRecentLogins(userId int) ([]*models.UserLoginDTO, error) {
+	logins, err := s.loginHistoryRepository.GetRecentLoginHistory(int64(userId), loginHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.UserLoginDTO, len(logins))
+	for i, login := range logins {
+		results[i] = &models.UserLoginDTO{
+			ID:        login.ID,
+			Method:    models.LoginMethod(login.Method),
+			Outcome:   models.LoginOutcome(login.Outcome),
+			IPAddress: login.IPAddress,
+			UserAgent: login.UserAgent,
+			CreatedAt: login.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return results, nil
+}
+
+func (s *LoginHistoryService) isNewDevice(userId int64, userAgent, ipAddress string) (bool, error) {
+	hasLoggedIn, err := s.loginHistoryRepository.HasSuccessfulLogin(userId)
+	if err != nil || !hasLoggedIn {
+		return false, err
+	}
+	seen, err := s.loginHistoryRepository.HasSuccessfulLoginFrom(userId, userAgent, ipAddress)
+	if err != nil {
+		return false, err
+	}
+	return !seen, nil
+}
+
+func (s *LoginHistoryService) sendNewDeviceEmail(login *repositories.UserLoginHistoryEntity) {
+	user, err := s.userRepository.GetUserById(int(login.UserID))
+	if err != nil {
+		util.LogError(err)
+		return
+	}
+
+	var emailOptions = &EmailSendOptions{}
+	emailOptions.FromEmail = "do-not-reply@snowlynxsoftware.net"
+	emailOptions.ToEmail = user.Email
+	emailOptions.Subject = "Parallax - New Login to Your Account"
+	emailOptions.HTMLContent = s.emailService.GetTemplates().GetNewDeviceLoginEmailTemplate(s.configService.GetBaseURL(), login.UserAgent, login.IPAddress, login.CreatedAt)
+	if !s.emailService.SendEmail(emailOptions) {
+		util.LogWarning(fmt.Sprintf("Failed to send the new device email for user %d", login.UserID))
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUserLoginHistoryRepository is a mock implementation of IUserLoginHistoryRepository
+type MockUserLoginHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserLoginHistoryRepository) CreateLoginHistory(userId int64, method, outcome, ipAddress, userAgent string) (*repositories.UserLoginHistoryEntity, error) {
+	args := m.Called(userId, method, outcome, ipAddress, userAgent)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserLoginHistoryEntity), args.Error(1)
+}
+
+func (m *MockUserLoginHistoryRepository) GetRecentLoginHistory(userId int64, limit int) ([]*repositories.UserLoginHistoryEntity, error) {
+	args := m.Called(userId, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.UserLoginHistoryEntity), args.Error(1)
+}
+
+func (m *MockUserLoginHistoryRepository) HasSuccessfulLogin(userId int64) (bool, error) {
+	args := m.Called(userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserLoginHistoryRepository) HasSuccessfulLoginFrom(userId int64, userAgent, ipAddress string) (bool, error) {
+	args := m.Called(userId, userAgent, ipAddress)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserLoginHistoryRepository) WithTx(tx *database.AppDataSource) repositories.IUserLoginHistoryRepository {
+	return m
+}
+
+func newLoginHistoryTestService() (ILoginHistoryService, *MockUserLoginHistoryRepository, *MockUserRepository, *MockEmailService) {
+	mockLoginHistoryRepo := new(MockUserLoginHistoryRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockEmailService := new(MockEmailService)
+	mockConfigService := new(MockConfigService)
+	mockConfigService.On("GetBaseURL").Return("http://localhost:3000")
+	service := NewLoginHistoryService(mockLoginHistoryRepo, mockUserRepo, mockEmailService, mockConfigService)
+	return service, mockLoginHistoryRepo, mockUserRepo, mockEmailService
+}
+
+// testLogin is the row recorded for testClient logging in as user 1
+func testLogin(outcome models.LoginOutcome) *repositories.UserLoginHistoryEntity {
+	return &repositories.UserLoginHistoryEntity{
+		ID:        5,
+		CreatedAt: time.Date(2025, 12, 8, 14, 30, 0, 0, time.UTC),
+		UserID:    1,
+		Method:    string(models.LoginMethodPassword),
+		Outcome:   string(outcome),
+		IPAddress: testClient.IPAddress,
+		UserAgent: testClient.UserAgent,
+	}
+}
+
+func TestLoginHistoryService_RecordLogin_NewDeviceSendsEmail(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, mockUserRepo, mockEmailService := newLoginHistoryTestService()
+	mockTemplates := new(MockEmailTemplates)
+	login := testLogin(models.LoginOutcomeSuccess)
+
+	mockLoginHistoryRepo.On("HasSuccessfulLogin", int64(1)).Return(true, nil)
+	mockLoginHistoryRepo.On("HasSuccessfulLoginFrom", int64(1), testClient.UserAgent, testClient.IPAddress).Return(false, nil)
+	mockLoginHistoryRepo.On("CreateLoginHistory", int64(1), "password", "success", testClient.IPAddress, testClient.UserAgent).Return(login, nil)
+	mockUserRepo.On("GetUserById", 1).Return(&repositories.UserEntity{ID: 1, Email: "player@example.com"}, nil)
+	mockEmailService.On("GetTemplates").Return(mockTemplates)
+	mockTemplates.On("GetNewDeviceLoginEmailTemplate", "http://localhost:3000", testClient.UserAgent, testClient.IPAddress, login.CreatedAt).Return("<html>new device</html>")
+	mockEmailService.On("SendEmail", mock.MatchedBy(func(options *EmailSendOptions) bool {
+		return options.ToEmail == "player@example.com" && options.HTMLContent == "<html>new device</html>"
+	})).Return(true)
+
+	// Act
+	service.RecordLogin(1, models.LoginMethodPassword, models.LoginOutcomeSuccess, testClient)
+
+	// Assert
+	mockLoginHistoryRepo.AssertExpectations(t)
+	mockEmailService.AssertExpectations(t)
+}
+
+func TestLoginHistoryService_RecordLogin_KnownDeviceSendsNoEmail(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, mockEmailService := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("HasSuccessfulLogin", int64(1)).Return(true, nil)
+	mockLoginHistoryRepo.On("HasSuccessfulLoginFrom", int64(1), testClient.UserAgent, testClient.IPAddress).Return(true, nil)
+	mockLoginHistoryRepo.On("CreateLoginHistory", int64(1), "password", "success", testClient.IPAddress, testClient.UserAgent).Return(testLogin(models.LoginOutcomeSuccess), nil)
+
+	// Act
+	service.RecordLogin(1, models.LoginMethodPassword, models.LoginOutcomeSuccess, testClient)
+
+	// Assert
+	mockLoginHistoryRepo.AssertExpectations(t)
+	mockEmailService.AssertNotCalled(t, "SendEmail", mock.Anything)
+}
+
+func TestLoginHistoryService_RecordLogin_FirstLoginSendsNoEmail(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, mockEmailService := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("HasSuccessfulLogin", int64(1)).Return(false, nil)
+	mockLoginHistoryRepo.On("CreateLoginHistory", int64(1), "email_link", "success", testClient.IPAddress, testClient.UserAgent).Return(testLogin(models.LoginOutcomeSuccess), nil)
+
+	// Act
+	service.RecordLogin(1, models.LoginMethodEmailLink, models.LoginOutcomeSuccess, testClient)
+
+	// Assert
+	mockLoginHistoryRepo.AssertNotCalled(t, "HasSuccessfulLoginFrom", mock.Anything, mock.Anything, mock.Anything)
+	mockEmailService.AssertNotCalled(t, "SendEmail", mock.Anything)
+}
+
+func TestLoginHistoryService_RecordLogin_FailedLoginSendsNoEmail(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, mockEmailService := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("CreateLoginHistory", int64(1), "password", "invalid_password", testClient.IPAddress, testClient.UserAgent).Return(testLogin(models.LoginOutcomeInvalidPassword), nil)
+
+	// Act
+	service.RecordLogin(1, models.LoginMethodPassword, models.LoginOutcomeInvalidPassword, testClient)
+
+	// Assert
+	mockLoginHistoryRepo.AssertExpectations(t)
+	mockLoginHistoryRepo.AssertNotCalled(t, "HasSuccessfulLogin", mock.Anything)
+	mockEmailService.AssertNotCalled(t, "SendEmail", mock.Anything)
+}
+
+func TestLoginHistoryService_RecordLogin_DatabaseErrorSendsNoEmail(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, mockEmailService := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("HasSuccessfulLogin", int64(1)).Return(true, nil)
+	mockLoginHistoryRepo.On("HasSuccessfulLoginFrom", int64(1), testClient.UserAgent, testClient.IPAddress).Return(false, nil)
+	mockLoginHistoryRepo.On("CreateLoginHistory", int64(1), "password", "success", testClient.IPAddress, testClient.UserAgent).Return(nil, errors.New("database error"))
+
+	// Act
+	service.RecordLogin(1, models.LoginMethodPassword, models.LoginOutcomeSuccess, testClient)
+
+	// Assert
+	mockEmailService.AssertNotCalled(t, "SendEmail", mock.Anything)
+}
+
+func TestLoginHistoryService_GetRecentLogins(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, _ := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("GetRecentLoginHistory", int64(1), loginHistoryLimit).Return([]*repositories.UserLoginHistoryEntity{
+		testLogin(models.LoginOutcomeInvalidPassword),
+	}, nil)
+
+	// Act
+	logins, err := service.GetRecentLogins(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, logins, 1)
+	assert.Equal(t, int64(5), logins[0].ID)
+	assert.Equal(t, models.LoginMethodPassword, logins[0].Method)
+	assert.Equal(t, models.LoginOutcomeInvalidPassword, logins[0].Outcome)
+	assert.Equal(t, testClient.IPAddress, logins[0].IPAddress)
+	assert.Equal(t, testClient.UserAgent, logins[0].UserAgent)
+	assert.Equal(t, "2025-12-08T14:30:00Z", logins[0].CreatedAt)
+}
+
+func TestLoginHistoryService_GetRecentLogins_DatabaseError(t *testing.T) {
+	// Arrange
+	service, mockLoginHistoryRepo, _, _ := newLoginHistoryTestService()
+
+	mockLoginHistoryRepo.On("GetRecentLoginHistory", int64(1), loginHistoryLimit).Return(nil, errors.New("database error"))
+
+	// Act
+	logins, err := service.GetRecentLogins(1)
+
+	// Assert
+	assert.EqualError(t, err, "database error")
+	assert.Nil(t, logins)
+}
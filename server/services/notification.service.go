@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// DefaultNotificationsPageSize is the page size used when none is requested
+	DefaultNotificationsPageSize = 20
+
+	// MaxNotificationsPageSize is the largest page of notifications returned at once
+	MaxNotificationsPageSize = 100
+)
+
+// Errors returned by NotificationService that callers can check with errors.Is
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+type INotificationService interface {
+	GetNotifications(userId int64, pageSize int, page int) (*models.PaginatedResponse, error)
+	GetUnreadCount(userId int64) (*models.UnreadNotificationsDTO, error)
+	MarkRead(userId, notificationId int64) error
+	MarkAllRead(userId int64) error
+
+	// HandleEvent stores a notification for the game events players should hear about
+	// while they are away. Register it with IEventHubService.AddListener.
+	HandleEvent(event *models.GameEvent)
+}
+
+// NotificationService keeps each player's notification inbox. Notifications are made
+// from game events, and each new one is also published to the player's event streams.
+type NotificationService struct {
+	notificationRepository repositories.INotificationRepository
+	riftRepository         repositories.IRiftRepository
+	userRepository         repositories.IUserRepository
+	eventHubService        IEventHubService
+}
+
+func NewNotificationService(
+	notificationRepository repositories.INotificationRepository,
+	riftRepository repositories.IRiftRepository,
+	userRepository repositories.IUserRepository,
+	eventHubService IEventHubService,
+) INotificationService {
+	return &NotificationService{
+		notificationRepository: notificationRepository,
+		riftRepository:         riftRepository,
+		userRepository:         userRepository,
+		eventHubService:        eventHubService,
+	}
+}
+
+// GetNotifications returns a page of the player's notifications, newest first. Pages start at 1.
+func (s *NotificationService) GetNotifications(userId int64, pageSize int, page int) (*models.PaginatedResponse, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultNotificationsPageSize
+	}
+	pageSize = min(pageSize, MaxNotificationsPageSize)
+	page = max(page, 1)
+
+	notifications, err := s.notificationRepository.GetNotificationsByUserId(userId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.notificationRepository.GetNotificationsCount(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]any, len(notifications))
+	for i, notification := range notifications {
+		results[i] = s.mapNotificationToDTO(notification)
+	}
+
+	return &models.PaginatedResponse{
+		PageSize: pageSize,
+		Page:     page,
+		Total:    total,
+		Results:  results,
+	}, nil
+}
+
+func (s *NotificationService) GetUnreadCount(userId int64) (*models.UnreadNotificationsDTO, error) {
+	count, err := s.notificationRepository.GetUnreadCount(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UnreadNotificationsDTO{UnreadCount: count}, nil
+}
+
+func (s *NotificationService) MarkRead(userId, notificationId int64) error {
+	marked, err := s.notificationRepository.MarkRead(userId, notificationId)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *NotificationService) MarkAllRead(userId int64) error {
+	return s.notificationRepository.MarkAllRead(userId)
+}
+
+func (s *NotificationService) HandleEvent(event *models.GameEvent) {
+	notificationType, message, ok := s.describeEvent(event)
+	if !ok {
+		return
+	}
+
+	notification, err := s.notificationRepository.CreateNotification(event.UserID, string(notificationType), message)
+	if err != nil {
+		util.LogError(fmt.Errorf("failed to create notification for user %d: %w", event.UserID, err))
+		return
+	}
+
+	s.eventHubService.Publish(event.UserID, models.GameEventNotification, s.mapNotificationToDTO(notification))
+}
+
+// describeEvent returns the notification for a game event, or false if the event
+// doesn't need one
+func (s *NotificationService) describeEvent(event *models.GameEvent) (models.NotificationType, string, bool) {
+	switch data := event.Data.(type) {
+	case *models.ExpeditionCompletedEventDTO:
+		riftName := "the rift"
+		rift, err := s.riftRepository.GetRiftById(data.RiftID)
+		if err != nil {
+			util.LogError(err)
+		} else {
+			riftName = rift.Name
+		}
+		return models.NotificationExpeditionReady, fmt.Sprintf("Your team is back from %s with its loot.", riftName), true
+
+	case *models.TeamUnlockedEventDTO:
+		// Players who unlock a team themselves don't need telling
+		if !data.Automatic {
+			return "", "", false
+		}
+		return models.NotificationTeamUnlocked, fmt.Sprintf("Team %d has been unlocked!", data.TeamNumber), true
+
+	case *models.RiftUnlockedEventDTO:
+		return models.NotificationRiftAvailable, fmt.Sprintf("%s is now available!", data.RiftName), true
+
+	case *models.EchoEncounterEventDTO:
+		otherName := "another player"
+		otherUser, err := s.userRepository.GetUserById(int(data.OtherUserID))
+		if err != nil {
+			util.LogError(err)
+		} else if otherUser != nil {
+			otherName = otherUser.DisplayName
+		}
+		return models.NotificationEchoEncounter,
+			fmt.Sprintf("Your team encountered echoes of %s's expedition in %s and found %d bonus items!", otherName, data.RiftName, data.BonusItems), true
+
+	case *models.TradeUpdatedEventDTO:
+		switch models.TradeStatus(data.Status) {
+		case models.TradeStatusPending:
+			return models.NotificationTradeOffered, fmt.Sprintf("%s offered you a trade.", data.OtherUserName), true
+		case models.TradeStatusCompleted:
+			return models.NotificationTradeCompleted, fmt.Sprintf("%s accepted your trade!", data.OtherUserName), true
+		case models.TradeStatusDeclined:
+			return models.NotificationTradeDeclined, fmt.Sprintf("%s declined your trade.", data.OtherUserName), true
+		case models.TradeStatusExpired:
+			return models.NotificationTradeExpired, fmt.Sprintf("Your trade with %s expired. Any items you offered are back in your inventory.", data.OtherUserName), true
+		}
+		// Trades withdrawn before they were answered aren't worth a notification
+		return "", "", false
+
+	case *models.LeaderboardRankChangedEventDTO:
+		// Only entering the top players is worth a notification, not moving within it
+		if data.OldRank != nil || data.NewRank == nil || *data.NewRank > TopPlayersLimit {
+			return "", "", false
+		}
+		return models.NotificationLeaderboardTop,
+			fmt.Sprintf("You made the top %d of the %s leaderboard at rank %d!", TopPlayersLimit, data.LeaderboardType, *data.NewRank), true
+	}
+
+	return "", "", false
+}
+
+func (s *NotificationService) mapNotificationToDTO(notification *repositories.NotificationEntity) *models.NotificationResponseDTO {
+	dto := &models.NotificationResponseDTO{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Message:   notification.Message,
+		IsRead:    notification.IsRead,
+		CreatedAt: notification.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if notification.ReadAt != nil {
+		readAt := notification.ReadAt.Format("2006-01-02T15:04:05Z")
+		dto.ReadAt = &readAt
+	}
+	return dto
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationRepository) CreateNotification(userId int64, notificationType string, message string) (*repositories.NotificationEntity, error) {
+	args := m.Called(userId, notificationType, message)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.NotificationEntity), args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetNotificationsByUserId(userId int64, limit int, offset int) ([]*repositories.NotificationEntity, error) {
+	args := m.Called(userId, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.NotificationEntity), args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetNotificationsCount(userId int64) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetUnreadCount(userId int64) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkRead(userId, notificationId int64) (bool, error) {
+	args := m.Called(userId, notificationId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkAllRead(userId int64) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepository) WithTx(tx *database.AppDataSource) repositories.INotificationRepository {
+	return m
+}
+
+func newNotificationTestService() (INotificationService, *MockNotificationRepository, *MockRiftRepository, *MockUserRepository, IEventHubService) {
+	mockNotificationRepo := new(MockNotificationRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockUserRepo := new(MockUserRepository)
+	eventHub := NewEventHubService()
+	service := NewNotificationService(mockNotificationRepo, mockRiftRepo, mockUserRepo, eventHub)
+	return service, mockNotificationRepo, mockRiftRepo, mockUserRepo, eventHub
+}
+
+func TestNotificationService_GetNotifications_Paginates(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	createdAt := time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC)
+	notifications := []*repositories.NotificationEntity{
+		{ID: 12, UserID: 1, Type: string(models.NotificationRiftAvailable), Message: "Fire Rift is now available!", CreatedAt: createdAt},
+	}
+	mockNotificationRepo.On("GetNotificationsByUserId", int64(1), 10, 10).Return(notifications, nil)
+	mockNotificationRepo.On("GetNotificationsCount", int64(1)).Return(11, nil)
+
+	result, err := service.GetNotifications(1, 10, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Page)
+	assert.Equal(t, 10, result.PageSize)
+	assert.Equal(t, 11, result.Total)
+	assert.Equal(t, []any{&models.NotificationResponseDTO{
+		ID:        12,
+		Type:      string(models.NotificationRiftAvailable),
+		Message:   "Fire Rift is now available!",
+		CreatedAt: "2025-11-28T12:00:00Z",
+	}}, result.Results)
+	mockNotificationRepo.AssertExpectations(t)
+}
+
+func TestNotificationService_GetNotifications_ClampsPage(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	mockNotificationRepo.On("GetNotificationsByUserId", int64(1), MaxNotificationsPageSize, 0).Return([]*repositories.NotificationEntity{}, nil)
+	mockNotificationRepo.On("GetNotificationsCount", int64(1)).Return(0, nil)
+
+	result, err := service.GetNotifications(1, MaxNotificationsPageSize+1, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Page)
+	assert.Equal(t, MaxNotificationsPageSize, result.PageSize)
+	mockNotificationRepo.AssertExpectations(t)
+}
+
+func TestNotificationService_MarkRead_NotFound(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	mockNotificationRepo.On("MarkRead", int64(1), int64(5)).Return(false, nil)
+
+	err := service.MarkRead(1, 5)
+
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+}
+
+func TestNotificationService_HandleEvent_ExpeditionCompleted(t *testing.T) {
+	service, mockNotificationRepo, mockRiftRepo, _, eventHub := newNotificationTestService()
+	eventHub.AddListener(service.HandleEvent)
+	subscription := eventHub.Subscribe(1, 0)
+
+	message := "Your team is back from Test Rift with its loot."
+	notification := &repositories.NotificationEntity{ID: 3, UserID: 1, Type: string(models.NotificationExpeditionReady), Message: message}
+	mockRiftRepo.On("GetRiftById", int64(2)).Return(&repositories.RiftEntity{ID: 2, Name: "Test Rift"}, nil)
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationExpeditionReady), message).Return(notification, nil)
+
+	eventHub.Publish(1, models.GameEventExpeditionCompleted, &models.ExpeditionCompletedEventDTO{ExpeditionID: 7, TeamID: 1, RiftID: 2})
+
+	mockNotificationRepo.AssertExpectations(t)
+	assert.Equal(t, models.GameEventExpeditionCompleted, receive(t, subscription).Type)
+	event := receive(t, subscription)
+	assert.Equal(t, models.GameEventNotification, event.Type)
+	assert.Equal(t, int64(3), event.Data.(*models.NotificationResponseDTO).ID)
+}
+
+func TestNotificationService_HandleEvent_OnlyAutomaticTeamUnlocks(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	notification := &repositories.NotificationEntity{ID: 1, UserID: 1}
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationTeamUnlocked), "Team 2 has been unlocked!").Return(notification, nil).Once()
+
+	service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventTeamUnlocked, Data: &models.TeamUnlockedEventDTO{TeamID: 4, TeamNumber: 2, Automatic: true}})
+	service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventTeamUnlocked, Data: &models.TeamUnlockedEventDTO{TeamID: 5, TeamNumber: 3}})
+
+	mockNotificationRepo.AssertExpectations(t)
+}
+
+func TestNotificationService_HandleEvent_OnlyLeaderboardEntries(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	oldRank, newRank, outsideTop := 4, 2, TopPlayersLimit+1
+	notification := &repositories.NotificationEntity{ID: 1, UserID: 1}
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationLeaderboardTop), "You made the top 20 of the power leaderboard at rank 2!").Return(notification, nil).Once()
+
+	for _, data := range []*models.LeaderboardRankChangedEventDTO{
+		{LeaderboardType: LeaderboardTypePower, NewRank: &newRank},
+		{LeaderboardType: LeaderboardTypePower, OldRank: &oldRank, NewRank: &newRank},
+		{LeaderboardType: LeaderboardTypePower, NewRank: &outsideTop},
+		{LeaderboardType: LeaderboardTypePower, OldRank: &oldRank},
+	} {
+		service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventLeaderboardRankChanged, Data: data})
+	}
+
+	mockNotificationRepo.AssertExpectations(t)
+}
+
+func TestNotificationService_HandleEvent_EchoEncounter(t *testing.T) {
+	service, mockNotificationRepo, _, mockUserRepo, _ := newNotificationTestService()
+
+	message := "Your team encountered echoes of Wanderer's expedition in Fire Rift and found 2 bonus items!"
+	notification := &repositories.NotificationEntity{ID: 1, UserID: 1}
+	mockUserRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2, DisplayName: "Wanderer"}, nil)
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationEchoEncounter), message).Return(notification, nil)
+
+	service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventEchoEncounter, Data: &models.EchoEncounterEventDTO{
+		EncounterID: 5, ExpeditionID: 7, RiftID: 3, RiftName: "Fire Rift", OtherUserID: 2, BonusItems: 2,
+	}})
+
+	mockNotificationRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestNotificationService_HandleEvent_Trades(t *testing.T) {
+	service, mockNotificationRepo, _, _, _ := newNotificationTestService()
+
+	notification := &repositories.NotificationEntity{ID: 1, UserID: 1}
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationTradeOffered), "Wanderer offered you a trade.").Return(notification, nil).Once()
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationTradeCompleted), "Wanderer accepted your trade!").Return(notification, nil).Once()
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationTradeExpired), "Your trade with Wanderer expired. Any items you offered are back in your inventory.").Return(notification, nil).Once()
+
+	for _, status := range []models.TradeStatus{
+		models.TradeStatusPending,
+		models.TradeStatusCompleted,
+		models.TradeStatusCancelled,
+		models.TradeStatusExpired,
+	} {
+		service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventTradeUpdated, Data: &models.TradeUpdatedEventDTO{
+			TradeID: 3, Status: string(status), OtherUserID: 2, OtherUserName: "Wanderer",
+		}})
+	}
+
+	// Cancelled trades don't get a notification
+	mockNotificationRepo.AssertExpectations(t)
+	mockNotificationRepo.AssertNumberOfCalls(t, "CreateNotification", 3)
+}
+
+func TestNotificationService_HandleEvent_CreateError(t *testing.T) {
+	service, mockNotificationRepo, _, _, eventHub := newNotificationTestService()
+	subscription := eventHub.Subscribe(1, 0)
+
+	mockNotificationRepo.On("CreateNotification", int64(1), string(models.NotificationRiftAvailable), "Fire Rift is now available!").Return(nil, errors.New("database error"))
+
+	service.HandleEvent(&models.GameEvent{UserID: 1, Type: models.GameEventRiftUnlocked, Data: &models.RiftUnlockedEventDTO{RiftID: 2, RiftName: "Fire Rift"}})
+
+	assertNoEvent(t, subscription)
+}
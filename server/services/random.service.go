@@ -0,0 +1,49 @@
+package services
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IRandom is a source of random numbers for game rolls. *rand.Rand satisfies it.
+type IRandom interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+// IRandomService hands out seeds and the random sources built from them.
+// A source built from a stored seed always produces the same rolls, which is
+// what lets an expedition's loot be replayed.
+type IRandomService interface {
+	NewSeed() int64
+	NewRandom(seed int64) IRandom
+}
+
+type RandomService struct {
+	mutex sync.Mutex
+	seeds *rand.Rand
+}
+
+// NewRandomService creates a random service whose seeds are themselves random
+func NewRandomService() IRandomService {
+	return NewSeededRandomService(time.Now().UnixNano())
+}
+
+// NewSeededRandomService creates a random service that hands out the same sequence of
+// seeds every time. Used where results need to be repeatable, such as tests.
+func NewSeededRandomService(seed int64) IRandomService {
+	return &RandomService{
+		seeds: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *RandomService) NewSeed() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.seeds.Int63()
+}
+
+func (s *RandomService) NewRandom(seed int64) IRandom {
+	return rand.New(rand.NewSource(seed))
+}
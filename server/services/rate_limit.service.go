@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// lockoutThreshold is how many failed passwords in a row lock an email out
+	lockoutThreshold = 5
+
+	// Each failed password past the threshold doubles the lockout, up to the maximum
+	lockoutBaseDuration = 1 * time.Minute
+	lockoutMaxDuration  = 1 * time.Hour
+
+	// failedLoginWindow is how long failed passwords are remembered after the last one
+	failedLoginWindow = 24 * time.Hour
+
+	// rateLimitPruneInterval is how often the in-memory store forgets idle keys
+	rateLimitPruneInterval = 5 * time.Minute
+)
+
+// Errors returned by RateLimitService that callers can check with errors.Is. They're
+// wrapped in a RetryAfterError saying when to try again.
+var (
+	ErrRateLimited   = errors.New("too many requests, please try again later")
+	ErrAccountLocked = errors.New("too many failed login attempts, please try again later")
+)
+
+// RetryAfterError is a request refused for now, that can be tried again after RetryAfter
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimit is a token bucket. A key can make Burst requests straight away, then gets
+// one more every Interval.
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// IRateLimitStore holds rate limit and lockout state. The in-memory store only counts
+// requests made to one server, running more than one needs a shared store.
+type IRateLimitStore interface {
+	// Take removes a token from the key's bucket. If the bucket is empty it returns
+	// false and how long until the next token.
+	Take(key string, limit RateLimit) (bool, time.Duration, error)
+
+	// AddFailure counts a failure against the key and returns the count. The count is
+	// forgotten once window passes without another failure.
+	AddFailure(key string, window time.Duration) (int, error)
+
+	// Lock locks the key for duration
+	Lock(key string, duration time.Duration) error
+
+	// LockedFor returns how much longer the key is locked, or zero
+	LockedFor(key string) (time.Duration, error)
+
+	// Clear forgets the key's failures and lock
+	Clear(key string) error
+}
+
+type IRateLimitService interface {
+	Allow(key string, limit RateLimit) error
+	CheckLockout(email string) error
+	RecordFailedLogin(email string) error
+	ClearFailedLogins(email string)
+}
+
+// RateLimitService limits how often expensive requests can be made, and locks an email
+// out of password logins after repeated failed passwords. Login links still work while
+// an email is locked, so the lockout can't keep the owner out of their account.
+type RateLimitService struct {
+	store IRateLimitStore
+}
+
+func NewRateLimitService(store IRateLimitStore) IRateLimitService {
+	return &RateLimitService{
+		store: store,
+	}
+}
+
+// Allow returns a RetryAfterError wrapping ErrRateLimited once the key has used up its limit
+func (s *RateLimitService) Allow(key string, limit RateLimit) error {
+	allowed, retryAfter, err := s.store.Take(key, limit)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &RetryAfterError{Err: ErrRateLimited, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// CheckLockout returns a RetryAfterError wrapping ErrAccountLocked while the email is locked out
+func (s *RateLimitService) CheckLockout(email string) error {
+	lockedFor, err := s.store.LockedFor(lockoutKey(email))
+	if err != nil {
+		return err
+	}
+	if lockedFor > 0 {
+		return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: lockedFor}
+	}
+	return nil
+}
+
+// RecordFailedLogin counts a failed password for the email, and locks it out once there
+// have been too many. If this failure locked the email, the lockout is returned.
+func (s *RateLimitService) RecordFailedLogin(email string) error {
+	key := lockoutKey(email)
+	failures, err := s.store.AddFailure(key, failedLoginWindow)
+	if err != nil {
+		return err
+	}
+	if failures < lockoutThreshold {
+		return nil
+	}
+
+	duration := lockoutDuration(failures)
+	if err := s.store.Lock(key, duration); err != nil {
+		return err
+	}
+	util.LogWarning(fmt.Sprintf("Login for %s locked for %s after %d failed passwords", email, duration, failures))
+	return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: duration}
+}
+
+// ClearFailedLogins forgets the email's failed passwords after it logs in
+func (s *RateLimitService) ClearFailedLogins(email string) {
+	if err := s.store.Clear(lockoutKey(email)); err != nil {
+		util.LogError(err)
+	}
+}
+
+// lockoutDuration doubles with each failure past the threshold, up to lockoutMaxDuration
+func lockoutDuration(failures int) time.Duration {
+	duration := lockoutBaseDuration
+	for i := lockoutThreshold; i < failures && duration < lockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, lockoutMaxDuration)
+}
+
+func lockoutKey(email string) string {
+	return "lockout:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// MemoryRateLimitStore keeps rate limits and lockouts in memory. Keys that have
+// nothing left to remember are pruned every few minutes.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*rateLimitBucket
+	failures  map[string]*rateLimitFailures
+	locks     map[string]time.Time
+	lastPrune time.Time
+}
+
+type rateLimitBucket struct {
+	tokens    float64
+	updatedAt time.Time
+
+	// fullAt is when the bucket will have refilled, after which it can be forgotten
+	fullAt time.Time
+}
+
+type rateLimitFailures struct {
+	count     int
+	expiresAt time.Time
+}
+
+func NewMemoryRateLimitStore() IRateLimitStore {
+	return newMemoryRateLimitStore(time.Now)
+}
+
+func newMemoryRateLimitStore(now func() time.Time) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		now:       now,
+		buckets:   map[string]*rateLimitBucket{},
+		failures:  map[string]*rateLimitFailures{},
+		locks:     map[string]time.Time{},
+		lastPrune: now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.prune(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	// Refill for the time since the bucket was last used
+	refill := float64(now.Sub(bucket.updatedAt)) / float64(limit.Interval)
+	bucket.tokens = min(bucket.tokens+refill, float64(limit.Burst))
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(limit.Interval)), nil
+	}
+	bucket.tokens--
+	bucket.fullAt = now.Add(time.Duration((float64(limit.Burst) - bucket.tokens) * float64(limit.Interval)))
+	return true, 0, nil
+}
+
+func (s *MemoryRateLimitStore) AddFailure(key string, window time.Duration) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	failures, ok := s.failures[key]
+	if !ok || !now.Before(failures.expiresAt) {
+		failures = &rateLimitFailures{}
+		s.failures[key] = failures
+	}
+	failures.count++
+	failures.expiresAt = now.Add(window)
+	return failures.count, nil
+}
+
+func (s *MemoryRateLimitStore) Lock(key string, duration time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.locks[key] = s.now().Add(duration)
+	return nil
+}
+
+func (s *MemoryRateLimitStore) LockedFor(key string) (time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lockedUntil, ok := s.locks[key]
+	if !ok {
+		return 0, nil
+	}
+	return max(lockedUntil.Sub(s.now()), 0), nil
+}
+
+func (s *MemoryRateLimitStore) Clear(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.failures, key)
+	delete(s.locks, key)
+	return nil
+}
+
+// prune forgets full buckets, expired failures and finished locks. Must be called with
+// the mutex held.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, failures := range s.failures {
+		if !now.Before(failures.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+	for key, lockedUntil := range s.locks {
+		if !now.Before(lockedUntil) {
+			delete(s.locks, key)
+		}
+	}
+}
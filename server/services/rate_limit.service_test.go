@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClock is a clock the tests move forward by hand
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestRateLimitStore() (*MemoryRateLimitStore, *testClock) {
+	clock := &testClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	return newMemoryRateLimitStore(clock.Now), clock
+}
+
+func TestMemoryRateLimitStore_Take_AllowsBurstThenRefills(t *testing.T) {
+	// Arrange
+	store, clock := newTestRateLimitStore()
+	limit := RateLimit{Burst: 3, Interval: 10 * time.Second}
+
+	// Act
+	for i := 0; i < 3; i++ {
+		allowed, _, err := store.Take("ip:1", limit)
+		assert.NoError(t, err)
+		assert.True(t, allowed, "request %d", i)
+	}
+	allowed, retryAfter, err := store.Take("ip:1", limit)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	// Part of the way through the interval there's still no token
+	clock.Advance(4 * time.Second)
+	allowed, retryAfter, _ = store.Take("ip:1", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, 6*time.Second, retryAfter)
+
+	clock.Advance(6 * time.Second)
+	allowed, _, _ = store.Take("ip:1", limit)
+	assert.True(t, allowed)
+}
+
+func TestMemoryRateLimitStore_Take_KeysAreSeparate(t *testing.T) {
+	// Arrange
+	store, _ := newTestRateLimitStore()
+	limit := RateLimit{Burst: 1, Interval: time.Minute}
+
+	// Act
+	first, _, _ := store.Take("ip:1", limit)
+	again, _, _ := store.Take("ip:1", limit)
+	other, _, _ := store.Take("ip:2", limit)
+
+	// Assert
+	assert.True(t, first)
+	assert.False(t, again)
+	assert.True(t, other)
+}
+
+func TestMemoryRateLimitStore_Take_PrunesFullBuckets(t *testing.T) {
+	// Arrange
+	store, clock := newTestRateLimitStore()
+	limit := RateLimit{Burst: 2, Interval: time.Second}
+	store.Take("ip:1", limit)
+
+	// Act
+	clock.Advance(rateLimitPruneInterval)
+	store.Take("ip:2", limit)
+
+	// Assert
+	assert.NotContains(t, store.buckets, "ip:1")
+	assert.Contains(t, store.buckets, "ip:2")
+}
+
+func TestMemoryRateLimitStore_AddFailure_ForgetsAfterWindow(t *testing.T) {
+	// Arrange
+	store, clock := newTestRateLimitStore()
+
+	// Act
+	first, _ := store.AddFailure("lockout:a", time.Hour)
+	clock.Advance(59 * time.Minute)
+	second, _ := store.AddFailure("lockout:a", time.Hour)
+	clock.Advance(time.Hour)
+	afterWindow, _ := store.AddFailure("lockout:a", time.Hour)
+
+	// Assert
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 2, second)
+	assert.Equal(t, 1, afterWindow)
+}
+
+func TestMemoryRateLimitStore_LockAndClear(t *testing.T) {
+	// Arrange
+	store, clock := newTestRateLimitStore()
+	store.AddFailure("lockout:a", time.Hour)
+
+	// Act
+	err := store.Lock("lockout:a", time.Minute)
+	clock.Advance(20 * time.Second)
+	lockedFor, _ := store.LockedFor("lockout:a")
+	otherLockedFor, _ := store.LockedFor("lockout:b")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 40*time.Second, lockedFor)
+	assert.Zero(t, otherLockedFor)
+
+	store.Clear("lockout:a")
+	lockedFor, _ = store.LockedFor("lockout:a")
+	failures, _ := store.AddFailure("lockout:a", time.Hour)
+	assert.Zero(t, lockedFor)
+	assert.Equal(t, 1, failures)
+
+	// Locks run out on their own
+	store.Lock("lockout:b", time.Minute)
+	clock.Advance(time.Minute)
+	lockedFor, _ = store.LockedFor("lockout:b")
+	assert.Zero(t, lockedFor)
+}
+
+func TestRateLimitService_Allow(t *testing.T) {
+	// Arrange
+	store, _ := newTestRateLimitStore()
+	service := NewRateLimitService(store)
+	limit := RateLimit{Burst: 1, Interval: 30 * time.Second}
+
+	// Act
+	first := service.Allow("ip:1", limit)
+	second := service.Allow("ip:1", limit)
+
+	// Assert
+	var retryErr *RetryAfterError
+	assert.NoError(t, first)
+	assert.ErrorIs(t, second, ErrRateLimited)
+	assert.True(t, errors.As(second, &retryErr))
+	assert.Equal(t, 30*time.Second, retryErr.RetryAfter)
+}
+
+func TestRateLimitService_RecordFailedLogin_LocksProgressively(t *testing.T) {
+	// Arrange
+	store, clock := newTestRateLimitStore()
+	service := NewRateLimitService(store)
+	email := "player@example.com"
+
+	// Act
+	for i := 1; i < lockoutThreshold; i++ {
+		assert.NoError(t, service.RecordFailedLogin(email))
+	}
+	assert.NoError(t, service.CheckLockout(email))
+	lockErr := service.RecordFailedLogin(email)
+
+	// Assert
+	var retryErr *RetryAfterError
+	assert.ErrorIs(t, lockErr, ErrAccountLocked)
+	assert.True(t, errors.As(lockErr, &retryErr))
+	assert.Equal(t, lockoutBaseDuration, retryErr.RetryAfter)
+	assert.ErrorIs(t, service.CheckLockout(email), ErrAccountLocked)
+
+	// The email is checked case-insensitively
+	assert.ErrorIs(t, service.CheckLockout("Player@Example.com"), ErrAccountLocked)
+
+	// The next failure after the lockout doubles it
+	clock.Advance(lockoutBaseDuration)
+	assert.NoError(t, service.CheckLockout(email))
+	lockErr = service.RecordFailedLogin(email)
+	assert.True(t, errors.As(lockErr, &retryErr))
+	assert.Equal(t, 2*lockoutBaseDuration, retryErr.RetryAfter)
+}
+
+func TestRateLimitService_ClearFailedLogins(t *testing.T) {
+	// Arrange
+	store, _ := newTestRateLimitStore()
+	service := NewRateLimitService(store)
+	email := "player@example.com"
+	for i := 1; i < lockoutThreshold; i++ {
+		service.RecordFailedLogin(email)
+	}
+
+	// Act
+	service.ClearFailedLogins(email)
+
+	// Assert
+	assert.NoError(t, service.RecordFailedLogin(email))
+	assert.NoError(t, service.CheckLockout(email))
+}
+
+func TestLockoutDuration(t *testing.T) {
+	assert.Equal(t, lockoutBaseDuration, lockoutDuration(lockoutThreshold))
+	assert.Equal(t, 4*lockoutBaseDuration, lockoutDuration(lockoutThreshold+2))
+	assert.Equal(t, lockoutMaxDuration, lockoutDuration(lockoutThreshold+10))
+	assert.Equal(t, lockoutMaxDuration, lockoutDuration(1000))
+}
@@ -9,17 +9,18 @@ type IRiftService interface {
 	GetAllRifts(userId int64) ([]*models.RiftResponseDTO, error)
 	GetRiftById(riftId int64) (*models.RiftResponseDTO, error)
 	IsRiftUnlockedForUser(userId, riftId int64) (bool, error)
+	GetUnlockedRiftIds(userId int64, riftIds []int64) (map[int64]bool, error)
 }
 
 type RiftService struct {
-	riftRepository       repositories.IRiftRepository
-	expeditionRepository repositories.IExpeditionRepository
+	riftRepository    repositories.IRiftRepository
+	unlockRuleService IUnlockRuleService
 }
 
-func NewRiftService(riftRepository repositories.IRiftRepository, expeditionRepository repositories.IExpeditionRepository) IRiftService {
+func NewRiftService(riftRepository repositories.IRiftRepository, unlockRuleService IUnlockRuleService) IRiftService {
 	return &RiftService{
-		riftRepository:       riftRepository,
-		expeditionRepository: expeditionRepository,
+		riftRepository:    riftRepository,
+		unlockRuleService: unlockRuleService,
 	}
 }
 
@@ -29,26 +30,19 @@ func (s *RiftService) GetAllRifts(userId int64) ([]*models.RiftResponseDTO, erro
 		return nil, err
 	}
 
-	completedCount, err := s.expeditionRepository.GetCompletedExpeditionsCount(userId)
+	riftIds := make([]int64, len(rifts))
+	for i, rift := range rifts {
+		riftIds[i] = rift.ID
+	}
+
+	unlockStatuses, err := s.unlockRuleService.GetUnlockStatuses(userId, models.UnlockTargetRift, riftIds)
 	if err != nil {
 		return nil, err
 	}
 
 	response := make([]*models.RiftResponseDTO, len(rifts))
 	for i, rift := range rifts {
-		isUnlocked := s.checkRiftUnlock(rift, completedCount)
-		response[i] = &models.RiftResponseDTO{
-			ID:                    rift.ID,
-			Name:                  rift.Name,
-			Description:           rift.Description,
-			WorldType:             rift.WorldType,
-			DurationMinutes:       rift.DurationMinutes,
-			Difficulty:            rift.Difficulty,
-			WeakToElement:         rift.WeakToElement,
-			UnlockRequirementText: rift.UnlockRequirementText,
-			Icon:                  rift.Icon,
-			IsUnlocked:            isUnlocked,
-		}
+		response[i] = s.mapRiftToDTO(rift, unlockStatuses[rift.ID])
 	}
 
 	return response, nil
@@ -60,57 +54,62 @@ func (s *RiftService) GetRiftById(riftId int64) (*models.RiftResponseDTO, error)
 		return nil, err
 	}
 
-	return &models.RiftResponseDTO{
-		ID:                    rift.ID,
-		Name:                  rift.Name,
-		Description:           rift.Description,
-		WorldType:             rift.WorldType,
-		DurationMinutes:       rift.DurationMinutes,
-		Difficulty:            rift.Difficulty,
-		WeakToElement:         rift.WeakToElement,
-		UnlockRequirementText: rift.UnlockRequirementText,
-		Icon:                  rift.Icon,
-		IsUnlocked:            true, // Assume unlocked if querying by ID
-	}, nil
+	// Assume unlocked if querying by ID
+	return s.mapRiftToDTO(rift, &models.UnlockStatusDTO{IsUnlocked: true}), nil
 }
 
 func (s *RiftService) IsRiftUnlockedForUser(userId, riftId int64) (bool, error) {
-	rift, err := s.riftRepository.GetRiftById(riftId)
+	// Make sure the rift exists before checking its rule
+	_, err := s.riftRepository.GetRiftById(riftId)
 	if err != nil {
 		return false, err
 	}
 
-	completedCount, err := s.expeditionRepository.GetCompletedExpeditionsCount(userId)
+	status, err := s.unlockRuleService.GetUnlockStatus(userId, models.UnlockTargetRift, riftId)
 	if err != nil {
 		return false, err
 	}
 
-	return s.checkRiftUnlock(rift, completedCount), nil
+	return status.IsUnlocked, nil
+}
+
+// GetUnlockedRiftIds re-checks the unlock rules for rifts that have already been loaded,
+// returning the ones the user has unlocked
+func (s *RiftService) GetUnlockedRiftIds(userId int64, riftIds []int64) (map[int64]bool, error) {
+	unlockStatuses, err := s.unlockRuleService.GetUnlockStatuses(userId, models.UnlockTargetRift, riftIds)
+	if err != nil {
+		return nil, err
+	}
+
+	unlocked := make(map[int64]bool)
+	for _, riftId := range riftIds {
+		// Rifts without a rule are always unlocked
+		if status := unlockStatuses[riftId]; status == nil || status.IsUnlocked {
+			unlocked[riftId] = true
+		}
+	}
+	return unlocked, nil
 }
 
-// checkRiftUnlock determines if a rift is unlocked based on completed expeditions
-func (s *RiftService) checkRiftUnlock(rift *repositories.RiftEntity, completedCount int) bool {
-	// Tutorial and first rift always unlocked
-	if rift.Difficulty == "tutorial" || rift.ID == 1 {
-		return true
+// mapRiftToDTO converts a RiftEntity to a RiftResponseDTO, using the generated unlock text
+func (s *RiftService) mapRiftToDTO(rift *repositories.RiftEntity, unlockStatus *models.UnlockStatusDTO) *models.RiftResponseDTO {
+	dto := &models.RiftResponseDTO{
+		ID:              rift.ID,
+		Name:            rift.Name,
+		Description:     rift.Description,
+		WorldType:       rift.WorldType,
+		DurationMinutes: rift.DurationMinutes,
+		Difficulty:      rift.Difficulty,
+		WeakToElement:   rift.WeakToElement,
+		Icon:            rift.Icon,
+		IsUnlocked:      true,
 	}
 
-	// Based on unlock requirements in seed data:
-	// Easy (Crimson Wastes): Tutorial completed (implicitly true if user exists)
-	// Medium (Frozen/Neon): 5 completions
-	// Hard (Verdant): 15 completions
-	// Legendary (Void/Light): 30 completions
-
-	switch rift.Difficulty {
-	case "easy":
-		return true // Always unlocked after tutorial
-	case "medium":
-		return completedCount >= 5
-	case "hard":
-		return completedCount >= 15
-	case "legendary":
-		return completedCount >= 30
-	default:
-		return false
+	if unlockStatus != nil {
+		dto.IsUnlocked = unlockStatus.IsUnlocked
+		dto.UnlockRequirementText = unlockStatus.RequirementText
+		dto.UnlockProgress = unlockStatus.ProgressText
 	}
+
+	return dto
 }
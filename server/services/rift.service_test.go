@@ -4,7 +4,9 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -48,8 +50,8 @@ type MockExpeditionRepository struct {
 	mock.Mock
 }
 
-func (m *MockExpeditionRepository) CreateExpedition(userId, teamId, riftId int64, durationMinutes int) (*repositories.ExpeditionEntity, error) {
-	args := m.Called(userId, teamId, riftId, durationMinutes)
+func (m *MockExpeditionRepository) CreateExpedition(userId, teamId, riftId int64, durationMinutes, effectivePower, recommendedPower int, lootLuck float64, lootSeed int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(userId, teamId, riftId, durationMinutes, effectivePower, recommendedPower, lootLuck, lootSeed)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -72,7 +74,7 @@ func (m *MockExpeditionRepository) GetActiveExpeditionsByUserId(userId int64) ([
 	return args.Get(0).([]*repositories.ExpeditionEntity), args.Error(1)
 }
 
-func (m *MockExpeditionRepository) GetCompletedExpeditionsByUserId(userId int64, limit int) ([]*repositories.ExpeditionEntity, error) {
+func (m *MockExpeditionRepository) GetFinishedExpeditionsByUserId(userId int64, limit int) ([]*repositories.ExpeditionEntity, error) {
 	args := m.Called(userId, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -90,82 +92,135 @@ func (m *MockExpeditionRepository) MarkCompleted(expeditionId int64) error {
 	return args.Error(0)
 }
 
-func (m *MockExpeditionRepository) MarkProcessed(expeditionId int64) error {
+func (m *MockExpeditionRepository) MarkRecalled(expeditionId int64) error {
 	args := m.Called(expeditionId)
 	return args.Error(0)
 }
 
-func (m *MockExpeditionRepository) MarkClaimed(expeditionId int64) error {
+func (m *MockExpeditionRepository) SaveLootRollInputs(expeditionId int64, luck, lootMultiplier float64) error {
+	args := m.Called(expeditionId, luck, lootMultiplier)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionRepository) GetCompletedExpeditionsCountByRift(userId, riftId int64) (int, error) {
+	args := m.Called(userId, riftId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExpeditionRepository) GetActiveExpeditionByTeamId(teamId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(teamId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
+
+func (m *MockExpeditionRepository) MarkProcessed(expeditionId int64, partialFailure bool) error {
+	args := m.Called(expeditionId, partialFailure)
+	return args.Error(0)
+}
+
+func (m *MockExpeditionRepository) GetDueExpeditionIds(limit int, skipIds []int64) ([]int64, error) {
+	args := m.Called(limit, skipIds)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockExpeditionRepository) MarkProcessFailed(expeditionId int64) error {
 	args := m.Called(expeditionId)
 	return args.Error(0)
 }
 
-// Test GetAllRifts - Success With Zero Completed Expeditions
-func TestRiftService_GetAllRifts_ZeroCompletedExpeditions(t *testing.T) {
-	// Arrange
-	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+func (m *MockExpeditionRepository) LockDueExpeditionById(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
 
-	rifts := []*repositories.RiftEntity{
-		{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", Description: "Test", WorldType: "neon", DurationMinutes: 10, WeakToElement: "fire", UnlockRequirementText: stringPtr("Always available"), Icon: "icon.png"},
-		{ID: 2, Name: "Easy Rift", Difficulty: "easy", Description: "Test", WorldType: "crimson", DurationMinutes: 20, WeakToElement: "ice", UnlockRequirementText: stringPtr("Complete tutorial"), Icon: "icon.png"},
-		{ID: 3, Name: "Medium Rift", Difficulty: "medium", Description: "Test", WorldType: "frozen", DurationMinutes: 30, WeakToElement: "lightning", UnlockRequirementText: stringPtr("5 completions"), Icon: "icon.png"},
+func (m *MockExpeditionRepository) GetExpeditionByIdForUpdate(expeditionId int64) (*repositories.ExpeditionEntity, error) {
+	args := m.Called(expeditionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
+	return args.Get(0).(*repositories.ExpeditionEntity), args.Error(1)
+}
 
-	mockRiftRepo.On("GetAllRifts").Return(rifts, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, nil)
+func (m *MockExpeditionRepository) WithTx(tx *database.AppDataSource) repositories.IExpeditionRepository {
+	return m
+}
 
-	// Act
-	result, err := service.GetAllRifts(1)
+func (m *MockExpeditionRepository) MarkClaimed(expeditionId int64) error {
+	args := m.Called(expeditionId)
+	return args.Error(0)
+}
 
-	// Assert
-	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, 3, len(result))
-	assert.True(t, result[0].IsUnlocked)  // Tutorial always unlocked
-	assert.True(t, result[1].IsUnlocked)  // Easy always unlocked
-	assert.False(t, result[2].IsUnlocked) // Medium requires 5 completions
-	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+// MockUnlockRuleService for RiftService and TeamService tests
+type MockUnlockRuleService struct {
+	mock.Mock
+}
+
+func (m *MockUnlockRuleService) GetUnlockStatus(userId int64, targetType models.UnlockTargetType, targetKey int64) (*models.UnlockStatusDTO, error) {
+	args := m.Called(userId, targetType, targetKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UnlockStatusDTO), args.Error(1)
 }
 
-// Test GetAllRifts - Success With 5+ Completed Expeditions
-func TestRiftService_GetAllRifts_FiveCompletedExpeditions(t *testing.T) {
+func (m *MockUnlockRuleService) GetUnlockStatuses(userId int64, targetType models.UnlockTargetType, targetKeys []int64) (map[int64]*models.UnlockStatusDTO, error) {
+	args := m.Called(userId, targetType, targetKeys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64]*models.UnlockStatusDTO), args.Error(1)
+}
+
+// Test GetAllRifts - Success With Mixed Unlock Statuses
+func TestRiftService_GetAllRifts_MixedUnlockStatuses(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	rifts := []*repositories.RiftEntity{
-		{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", Description: "Test", WorldType: "neon", DurationMinutes: 10, WeakToElement: "fire", UnlockRequirementText: stringPtr("Always available"), Icon: "icon.png"},
-		{ID: 2, Name: "Medium Rift", Difficulty: "medium", Description: "Test", WorldType: "frozen", DurationMinutes: 30, WeakToElement: "lightning", UnlockRequirementText: stringPtr("5 completions"), Icon: "icon.png"},
-		{ID: 3, Name: "Hard Rift", Difficulty: "hard", Description: "Test", WorldType: "verdant", DurationMinutes: 45, WeakToElement: "shadow", UnlockRequirementText: stringPtr("15 completions"), Icon: "icon.png"},
+		{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", Description: "Test", WorldType: "neon", DurationMinutes: 10, WeakToElement: "fire", Icon: "icon.png"},
+		{ID: 2, Name: "Easy Rift", Difficulty: "easy", Description: "Test", WorldType: "crimson", DurationMinutes: 20, WeakToElement: "ice", Icon: "icon.png"},
+		{ID: 5, Name: "Hard Rift", Difficulty: "hard", Description: "Test", WorldType: "verdant", DurationMinutes: 45, WeakToElement: "shadow", UnlockRequirementText: stringPtr("Old free text"), Icon: "icon.png"},
 	}
 
 	mockRiftRepo.On("GetAllRifts").Return(rifts, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(5, nil)
+	mockUnlockRuleService.On("GetUnlockStatuses", int64(1), models.UnlockTargetRift, []int64{1, 2, 5}).Return(map[int64]*models.UnlockStatusDTO{
+		1: {IsUnlocked: true},
+		2: {IsUnlocked: true, RequirementText: stringPtr("Complete Tutorial Rift"), ProgressText: stringPtr("1/1 Tutorial Rift")},
+		5: {IsUnlocked: false, RequirementText: stringPtr("Complete 10 expeditions"), ProgressText: stringPtr("3/10 expeditions")},
+	}, nil)
 
 	// Act
 	result, err := service.GetAllRifts(1)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.NotNil(t, result)
 	assert.Equal(t, 3, len(result))
-	assert.True(t, result[0].IsUnlocked)  // Tutorial always unlocked
-	assert.True(t, result[1].IsUnlocked)  // Medium unlocked at 5
-	assert.False(t, result[2].IsUnlocked) // Hard requires 15
+	assert.True(t, result[0].IsUnlocked)
+	assert.Nil(t, result[0].UnlockRequirementText) // No rule
+	assert.True(t, result[1].IsUnlocked)
+	assert.False(t, result[2].IsUnlocked)
+	assert.Equal(t, "Complete 10 expeditions", *result[2].UnlockRequirementText) // Generated text replaces free text
+	assert.Equal(t, "3/10 expeditions", *result[2].UnlockProgress)
 	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertExpectations(t)
 }
 
 // Test GetAllRifts - Rift Repository Error
 func TestRiftService_GetAllRifts_RiftRepositoryError(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	mockRiftRepo.On("GetAllRifts").Return(nil, errors.New("database error"))
 
@@ -178,19 +233,19 @@ func TestRiftService_GetAllRifts_RiftRepositoryError(t *testing.T) {
 	mockRiftRepo.AssertExpectations(t)
 }
 
-// Test GetAllRifts - Expedition Repository Error
-func TestRiftService_GetAllRifts_ExpeditionRepositoryError(t *testing.T) {
+// Test GetAllRifts - Unlock Rule Error
+func TestRiftService_GetAllRifts_UnlockRuleError(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	rifts := []*repositories.RiftEntity{
-		{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", Description: "Test", WorldType: "neon", DurationMinutes: 10, WeakToElement: "fire", UnlockRequirementText: stringPtr("Always available"), Icon: "icon.png"},
+		{ID: 1, Name: "Tutorial Rift", Difficulty: "tutorial", Description: "Test", WorldType: "neon", DurationMinutes: 10, WeakToElement: "fire", Icon: "icon.png"},
 	}
 
 	mockRiftRepo.On("GetAllRifts").Return(rifts, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, errors.New("database error"))
+	mockUnlockRuleService.On("GetUnlockStatuses", int64(1), models.UnlockTargetRift, []int64{1}).Return(nil, errors.New("database error"))
 
 	// Act
 	result, err := service.GetAllRifts(1)
@@ -199,26 +254,47 @@ func TestRiftService_GetAllRifts_ExpeditionRepositoryError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertExpectations(t)
+}
+
+// Test GetUnlockedRiftIds - Only Re-checks The Given Rifts
+func TestRiftService_GetUnlockedRiftIds(t *testing.T) {
+	// Arrange
+	mockRiftRepo := new(MockRiftRepository)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
+
+	mockUnlockRuleService.On("GetUnlockStatuses", int64(1), models.UnlockTargetRift, []int64{2, 5}).Return(map[int64]*models.UnlockStatusDTO{
+		2: {IsUnlocked: true},
+		5: {IsUnlocked: false},
+	}, nil)
+
+	// Act
+	result, err := service.GetUnlockedRiftIds(1, []int64{2, 5})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]bool{2: true}, result)
+	mockRiftRepo.AssertNotCalled(t, "GetAllRifts")
+	mockUnlockRuleService.AssertExpectations(t)
 }
 
 // Test GetRiftById - Success
 func TestRiftService_GetRiftById_Success(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	rift := &repositories.RiftEntity{
-		ID:                    1,
-		Name:                  "Tutorial Rift",
-		Description:           "Test description",
-		WorldType:             "neon",
-		DurationMinutes:       10,
-		Difficulty:            "tutorial",
-		WeakToElement:         "fire",
-		UnlockRequirementText: stringPtr("Always available"),
-		Icon:                  "icon.png",
+		ID:              1,
+		Name:            "Tutorial Rift",
+		Description:     "Test description",
+		WorldType:       "neon",
+		DurationMinutes: 10,
+		Difficulty:      "tutorial",
+		WeakToElement:   "fire",
+		Icon:            "icon.png",
 	}
 
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
@@ -239,8 +315,8 @@ func TestRiftService_GetRiftById_Success(t *testing.T) {
 func TestRiftService_GetRiftById_NotFound(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	mockRiftRepo.On("GetRiftById", int64(999)).Return(nil, errors.New("rift not found"))
 
@@ -257,8 +333,8 @@ func TestRiftService_GetRiftById_NotFound(t *testing.T) {
 func TestRiftService_GetRiftById_RepositoryError(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	mockRiftRepo.On("GetRiftById", int64(1)).Return(nil, errors.New("database connection failed"))
 
@@ -271,45 +347,17 @@ func TestRiftService_GetRiftById_RepositoryError(t *testing.T) {
 	mockRiftRepo.AssertExpectations(t)
 }
 
-// Test IsRiftUnlockedForUser - No Requirement (Always Unlocked)
-func TestRiftService_IsRiftUnlockedForUser_NoRequirement(t *testing.T) {
+// Test IsRiftUnlockedForUser - Rule Met
+func TestRiftService_IsRiftUnlockedForUser_Unlocked(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
-	rift := &repositories.RiftEntity{
-		ID:         1,
-		Difficulty: "tutorial",
-	}
-
-	mockRiftRepo.On("GetRiftById", int64(1)).Return(rift, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, nil)
-
-	// Act
-	result, err := service.IsRiftUnlockedForUser(1, 1)
-
-	// Assert
-	assert.NoError(t, err)
-	assert.True(t, result)
-	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
-}
-
-// Test IsRiftUnlockedForUser - Requirement Met
-func TestRiftService_IsRiftUnlockedForUser_RequirementMet(t *testing.T) {
-	// Arrange
-	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
-
-	rift := &repositories.RiftEntity{
-		ID:         2,
-		Difficulty: "medium",
-	}
+	rift := &repositories.RiftEntity{ID: 2, Difficulty: "easy"}
 
 	mockRiftRepo.On("GetRiftById", int64(2)).Return(rift, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(10, nil) // 10 > 5
+	mockUnlockRuleService.On("GetUnlockStatus", int64(1), models.UnlockTargetRift, int64(2)).Return(&models.UnlockStatusDTO{IsUnlocked: true}, nil)
 
 	// Act
 	result, err := service.IsRiftUnlockedForUser(1, 2)
@@ -318,40 +366,37 @@ func TestRiftService_IsRiftUnlockedForUser_RequirementMet(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, result)
 	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertExpectations(t)
 }
 
-// Test IsRiftUnlockedForUser - Requirement Not Met
-func TestRiftService_IsRiftUnlockedForUser_RequirementNotMet(t *testing.T) {
+// Test IsRiftUnlockedForUser - Rule Not Met
+func TestRiftService_IsRiftUnlockedForUser_Locked(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
-	rift := &repositories.RiftEntity{
-		ID:         3,
-		Difficulty: "hard",
-	}
+	rift := &repositories.RiftEntity{ID: 6, Difficulty: "legendary"}
 
-	mockRiftRepo.On("GetRiftById", int64(3)).Return(rift, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(5, nil) // 5 < 15
+	mockRiftRepo.On("GetRiftById", int64(6)).Return(rift, nil)
+	mockUnlockRuleService.On("GetUnlockStatus", int64(1), models.UnlockTargetRift, int64(6)).Return(&models.UnlockStatusDTO{IsUnlocked: false}, nil)
 
 	// Act
-	result, err := service.IsRiftUnlockedForUser(1, 3)
+	result, err := service.IsRiftUnlockedForUser(1, 6)
 
 	// Assert
 	assert.NoError(t, err)
 	assert.False(t, result)
 	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertExpectations(t)
 }
 
 // Test IsRiftUnlockedForUser - Rift Not Found
 func TestRiftService_IsRiftUnlockedForUser_RiftNotFound(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
 	mockRiftRepo.On("GetRiftById", int64(999)).Return(nil, errors.New("rift not found"))
 
@@ -362,22 +407,20 @@ func TestRiftService_IsRiftUnlockedForUser_RiftNotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.False(t, result)
 	mockRiftRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertNotCalled(t, "GetUnlockStatus", mock.Anything, mock.Anything, mock.Anything)
 }
 
-// Test IsRiftUnlockedForUser - Expedition Count Error
-func TestRiftService_IsRiftUnlockedForUser_ExpeditionCountError(t *testing.T) {
+// Test IsRiftUnlockedForUser - Unlock Rule Error
+func TestRiftService_IsRiftUnlockedForUser_UnlockRuleError(t *testing.T) {
 	// Arrange
 	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewRiftService(mockRiftRepo, mockUnlockRuleService)
 
-	rift := &repositories.RiftEntity{
-		ID:         2,
-		Difficulty: "medium",
-	}
+	rift := &repositories.RiftEntity{ID: 2, Difficulty: "medium"}
 
 	mockRiftRepo.On("GetRiftById", int64(2)).Return(rift, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, errors.New("database error"))
+	mockUnlockRuleService.On("GetUnlockStatus", int64(1), models.UnlockTargetRift, int64(2)).Return(nil, errors.New("database error"))
 
 	// Act
 	result, err := service.IsRiftUnlockedForUser(1, 2)
@@ -386,30 +429,5 @@ func TestRiftService_IsRiftUnlockedForUser_ExpeditionCountError(t *testing.T) {
 	assert.Error(t, err)
 	assert.False(t, result)
 	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
-}
-
-// Test IsRiftUnlockedForUser - Legendary Difficulty
-func TestRiftService_IsRiftUnlockedForUser_LegendaryDifficulty(t *testing.T) {
-	// Arrange
-	mockRiftRepo := new(MockRiftRepository)
-	mockExpeditionRepo := new(MockExpeditionRepository)
-	service := NewRiftService(mockRiftRepo, mockExpeditionRepo)
-
-	rift := &repositories.RiftEntity{
-		ID:         4,
-		Difficulty: "legendary",
-	}
-
-	mockRiftRepo.On("GetRiftById", int64(4)).Return(rift, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(35, nil) // 35 >= 30
-
-	// Act
-	result, err := service.IsRiftUnlockedForUser(1, 4)
-
-	// Assert
-	assert.NoError(t, err)
-	assert.True(t, result)
-	mockRiftRepo.AssertExpectations(t)
-	mockExpeditionRepo.AssertExpectations(t)
+	mockUnlockRuleService.AssertExpectations(t)
 }
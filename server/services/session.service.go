@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// SessionExpiry is how long a session lasts without being refreshed
+	SessionExpiry = refreshTokenExpirationInHours * time.Hour
+
+	// maxUserAgentLength matches the user_agent column
+	maxUserAgentLength = 512
+)
+
+// Errors returned by SessionService that callers can check with errors.Is
+var (
+	ErrInvalidRefreshToken = errors.New("the refresh token is invalid or has expired")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionRevoked      = errors.New("the session has been revoked or has expired")
+)
+
+type ISessionService interface {
+	StartSession(userId int, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error)
+	RefreshSession(refreshToken string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error)
+	ValidateSession(userId int, sessionId int64) error
+	GetSessions(userId int, currentSessionId int64) ([]*models.UserSessionDTO, error)
+	RevokeSession(userId int, sessionId int64) error
+	RevokeSessionByRefreshToken(refreshToken string) error
+	RevokeAllSessions(userId int) error
+}
+
+// SessionService keeps track of each device a user is logged in on. Every session has
+// a refresh token, which is swapped for a new one each time it's used. Access tokens
+// carry their session, so revoking a session logs the device out straight away.
+type SessionService struct {
+	sessionRepository repositories.IUserSessionRepository
+	userRepository    repositories.IUserRepository
+	tokenService      ITokenService
+}
+
+func NewSessionService(
+	sessionRepository repositories.IUserSessionRepository,
+	userRepository repositories.IUserRepository,
+	tokenService ITokenService,
+) ISessionService {
+	return &SessionService{
+		sessionRepository: sessionRepository,
+		userRepository:    userRepository,
+		tokenService:      tokenService,
+	}
+}
+
+// StartSession logs the user in on a new device and returns its first pair of tokens
+func (s *SessionService) StartSession(userId int, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	refreshToken, err := s.tokenService.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent, ipAddress := describeClient(client)
+	session, err := s.sessionRepository.CreateSession(int64(userId), hashRefreshToken(*refreshToken), userAgent, ipAddress, time.Now().Add(SessionExpiry))
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokenService.GenerateAccessToken(userId, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserLoginResponseDTO{
+		AccessToken:  *accessToken,
+		RefreshToken: *refreshToken,
+	}, nil
+}
+
+// RefreshSession swaps a refresh token for a new access token and refresh token. The old
+// refresh token stops working. If it's presented again after that, someone else has a
+// copy of it, so the whole session is revoked.
+func (s *SessionService) RefreshSession(refreshToken string, client *models.ClientInfoDTO) (*models.UserLoginResponseDTO, error) {
+	tokenHash := hashRefreshToken(refreshToken)
+
+	session, err := s.sessionRepository.GetSessionByTokenHash(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, s.handleUnknownRefreshToken(tokenHash)
+	}
+	if session.RevokedAt != nil || !time.Now().Before(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepository.GetUserById(int(session.UserID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if user.IsArchived || !user.IsVerified {
+		if _, err := s.sessionRepository.RevokeSession(session.UserID, session.ID); err != nil {
+			util.LogError(err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := s.tokenService.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent, ipAddress := describeClient(client)
+	rotated, err := s.sessionRepository.RotateSession(session.ID, tokenHash, hashRefreshToken(*newRefreshToken), userAgent, ipAddress, time.Now().Add(SessionExpiry))
+	if err != nil {
+		return nil, err
+	}
+	// Another request used the same token first
+	if !rotated {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	accessToken, err := s.tokenService.GenerateAccessToken(int(session.UserID), session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserLoginResponseDTO{
+		AccessToken:  *accessToken,
+		RefreshToken: *newRefreshToken,
+	}, nil
+}
+
+// ValidateSession checks an access token's session still belongs to the user and is active
+func (s *SessionService) ValidateSession(userId int, sessionId int64) error {
+	session, err := s.sessionRepository.GetSessionById(sessionId)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != int64(userId) {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt != nil || !time.Now().Before(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// GetSessions returns the devices the user is logged in on, most recently used first
+func (s *SessionService) GetSessions(userId int, currentSessionId int64) ([]*models.UserSessionDTO, error) {
+	sessions, err := s.sessionRepository.GetActiveSessionsByUserId(int64(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.UserSessionDTO, len(sessions))
+	for i, session := range sessions {
+		results[i] = &models.UserSessionDTO{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			LastUsedAt: session.LastUsedAt.Format("2006-01-02T15:04:05Z"),
+			ExpiresAt:  session.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+			IsCurrent:  session.ID == currentSessionId,
+		}
+	}
+	return results, nil
+}
+
+func (s *SessionService) RevokeSession(userId int, sessionId int64) error {
+	revoked, err := s.sessionRepository.RevokeSession(int64(userId), sessionId)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSessionByRefreshToken ends the session a refresh token belongs to. Unknown
+// tokens are ignored, there's nothing left to log out.
+func (s *SessionService) RevokeSessionByRefreshToken(refreshToken string) error {
+	session, err := s.sessionRepository.GetSessionByTokenHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return nil
+	}
+	_, err = s.sessionRepository.RevokeSession(session.UserID, session.ID)
+	return err
+}
+
+func (s *SessionService) RevokeAllSessions(userId int) error {
+	return s.sessionRepository.RevokeAllSessions(int64(userId))
+}
+
+// handleUnknownRefreshToken revokes the session if the token is one it has already
+// rotated away from. Either way the token is rejected.
+func (s *SessionService) handleUnknownRefreshToken(tokenHash string) error {
+	session, err := s.sessionRepository.GetSessionByPreviousTokenHash(tokenHash)
+	if err != nil {
+		return err
+	}
+	if session != nil && session.RevokedAt == nil {
+		util.LogWarning(fmt.Sprintf("Refresh token for session %d was reused, revoking the session", session.ID))
+		if _, err := s.sessionRepository.RevokeSession(session.UserID, session.ID); err != nil {
+			return err
+		}
+	}
+	return ErrInvalidRefreshToken
+}
+
+// describeClient returns the user agent and IP address to store, cut down to fit their columns
+func describeClient(client *models.ClientInfoDTO) (string, string) {
+	if client == nil {
+		return "", ""
+	}
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return userAgent, client.IPAddress
+}
+
+// hashRefreshToken is what's stored in place of a refresh token. The tokens are long
+// and random, so a fast hash is enough.
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserSessionRepository) CreateSession(userId int64, refreshTokenHash, userAgent, ipAddress string, expiresAt time.Time) (*repositories.UserSessionEntity, error) {
+	args := m.Called(userId, refreshTokenHash, userAgent, ipAddress, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserSessionEntity), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) GetSessionById(sessionId int64) (*repositories.UserSessionEntity, error) {
+	args := m.Called(sessionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserSessionEntity), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) GetSessionByTokenHash(refreshTokenHash string) (*repositories.UserSessionEntity, error) {
+	args := m.Called(refreshTokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserSessionEntity), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) GetSessionByPreviousTokenHash(refreshTokenHash string) (*repositories.UserSessionEntity, error) {
+	args := m.Called(refreshTokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserSessionEntity), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) RotateSession(sessionId int64, oldTokenHash, newTokenHash, userAgent, ipAddress string, expiresAt time.Time) (bool, error) {
+	args := m.Called(sessionId, oldTokenHash, newTokenHash, userAgent, ipAddress, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) GetActiveSessionsByUserId(userId int64) ([]*repositories.UserSessionEntity, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.UserSessionEntity), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) RevokeSession(userId, sessionId int64) (bool, error) {
+	args := m.Called(userId, sessionId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserSessionRepository) RevokeAllSessions(userId int64) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockUserSessionRepository) WithTx(tx *database.AppDataSource) repositories.IUserSessionRepository {
+	return m
+}
+
+func newSessionTestService() (ISessionService, *MockUserSessionRepository, *MockUserRepository, *MockTokenService) {
+	mockSessionRepo := new(MockUserSessionRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockTokenService := new(MockTokenService)
+	service := NewSessionService(mockSessionRepo, mockUserRepo, mockTokenService)
+	return service, mockSessionRepo, mockUserRepo, mockTokenService
+}
+
+// activeSession is session 9 for user 1, holding the refresh token "old-refresh-token"
+func activeSession() *repositories.UserSessionEntity {
+	return &repositories.UserSessionEntity{
+		ID:               9,
+		UserID:           1,
+		RefreshTokenHash: hashRefreshToken("old-refresh-token"),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+}
+
+func TestSessionService_StartSession(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, mockTokenService := newSessionTestService()
+
+	refreshToken := "new-refresh-token"
+	accessToken := "access-token"
+	mockTokenService.On("GenerateRefreshToken").Return(&refreshToken, nil)
+	mockSessionRepo.On("CreateSession", int64(1), hashRefreshToken(refreshToken), "Mozilla/5.0", "203.0.113.7", mock.AnythingOfType("time.Time")).
+		Return(&repositories.UserSessionEntity{ID: 9, UserID: 1}, nil)
+	mockTokenService.On("GenerateAccessToken", 1, int64(9)).Return(&accessToken, nil)
+
+	// Act
+	result, err := service.StartSession(1, &models.ClientInfoDTO{UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.7"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: refreshToken}, result)
+	mockSessionRepo.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestSessionService_StartSession_TruncatesUserAgent(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, mockTokenService := newSessionTestService()
+
+	refreshToken := "new-refresh-token"
+	accessToken := "access-token"
+	userAgent := string(make([]byte, maxUserAgentLength+10))
+	mockTokenService.On("GenerateRefreshToken").Return(&refreshToken, nil)
+	mockSessionRepo.On("CreateSession", int64(1), hashRefreshToken(refreshToken), userAgent[:maxUserAgentLength], "", mock.AnythingOfType("time.Time")).
+		Return(&repositories.UserSessionEntity{ID: 9, UserID: 1}, nil)
+	mockTokenService.On("GenerateAccessToken", 1, int64(9)).Return(&accessToken, nil)
+
+	// Act
+	_, err := service.StartSession(1, &models.ClientInfoDTO{UserAgent: userAgent})
+
+	// Assert
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestSessionService_RefreshSession_RotatesToken(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, mockUserRepo, mockTokenService := newSessionTestService()
+
+	newRefreshToken := "new-refresh-token"
+	accessToken := "access-token"
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockUserRepo.On("GetUserById", 1).Return(&repositories.UserEntity{ID: 1, IsVerified: true}, nil)
+	mockTokenService.On("GenerateRefreshToken").Return(&newRefreshToken, nil)
+	mockSessionRepo.On("RotateSession", int64(9), hashRefreshToken("old-refresh-token"), hashRefreshToken(newRefreshToken), "Mozilla/5.0", "203.0.113.7", mock.AnythingOfType("time.Time")).
+		Return(true, nil)
+	mockTokenService.On("GenerateAccessToken", 1, int64(9)).Return(&accessToken, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.UserLoginResponseDTO{AccessToken: accessToken, RefreshToken: newRefreshToken}, result)
+	mockSessionRepo.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestSessionService_RefreshSession_UnknownToken(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("unknown")).Return(nil, nil)
+	mockSessionRepo.On("GetSessionByPreviousTokenHash", hashRefreshToken("unknown")).Return(nil, nil)
+
+	// Act
+	result, err := service.RefreshSession("unknown", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockSessionRepo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestSessionService_RefreshSession_ReusedTokenRevokesSession(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(nil, nil)
+	mockSessionRepo.On("GetSessionByPreviousTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockSessionRepo.On("RevokeSession", int64(1), int64(9)).Return(true, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestSessionService_RefreshSession_RevokedSession(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, mockTokenService := newSessionTestService()
+
+	session := activeSession()
+	revokedAt := time.Now()
+	session.RevokedAt = &revokedAt
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(session, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockTokenService.AssertNotCalled(t, "GenerateRefreshToken")
+}
+
+func TestSessionService_RefreshSession_ExpiredSession(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, mockTokenService := newSessionTestService()
+
+	session := activeSession()
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(session, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockTokenService.AssertNotCalled(t, "GenerateRefreshToken")
+}
+
+func TestSessionService_RefreshSession_DeletedUser(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, mockUserRepo, _ := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockUserRepo.On("GetUserById", 1).Return(nil, sql.ErrNoRows)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+}
+
+func TestSessionService_RefreshSession_ArchivedUserRevokesSession(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, mockUserRepo, mockTokenService := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockUserRepo.On("GetUserById", 1).Return(&repositories.UserEntity{ID: 1, IsVerified: true, IsArchived: true}, nil)
+	mockSessionRepo.On("RevokeSession", int64(1), int64(9)).Return(true, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockSessionRepo.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "GenerateRefreshToken")
+}
+
+func TestSessionService_RefreshSession_LostRotation(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, mockUserRepo, mockTokenService := newSessionTestService()
+
+	newRefreshToken := "new-refresh-token"
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockUserRepo.On("GetUserById", 1).Return(&repositories.UserEntity{ID: 1, IsVerified: true}, nil)
+	mockTokenService.On("GenerateRefreshToken").Return(&newRefreshToken, nil)
+	mockSessionRepo.On("RotateSession", int64(9), mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+	// Act
+	result, err := service.RefreshSession("old-refresh-token", testClient)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+	mockTokenService.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestSessionService_ValidateSession(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		session  *repositories.UserSessionEntity
+		expected error
+	}{
+		{name: "active", session: activeSession()},
+		{name: "missing", session: nil, expected: ErrSessionNotFound},
+		{name: "other user", session: &repositories.UserSessionEntity{ID: 9, UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}, expected: ErrSessionNotFound},
+		{name: "revoked", session: &repositories.UserSessionEntity{ID: 9, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, expected: ErrSessionRevoked},
+		{name: "expired", session: &repositories.UserSessionEntity{ID: 9, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, expected: ErrSessionRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockSessionRepo, _, _ := newSessionTestService()
+			if tt.session == nil {
+				mockSessionRepo.On("GetSessionById", int64(9)).Return(nil, nil)
+			} else {
+				mockSessionRepo.On("GetSessionById", int64(9)).Return(tt.session, nil)
+			}
+
+			err := service.ValidateSession(1, 9)
+
+			if tt.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSessionService_GetSessions_MarksCurrent(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	usedAt := time.Date(2025, 12, 6, 12, 0, 0, 0, time.UTC)
+	sessions := []*repositories.UserSessionEntity{
+		{ID: 9, UserID: 1, UserAgent: "Firefox", IPAddress: "203.0.113.7", CreatedAt: usedAt, LastUsedAt: usedAt, ExpiresAt: usedAt.Add(SessionExpiry)},
+		{ID: 4, UserID: 1, UserAgent: "Safari", IPAddress: "198.51.100.2", CreatedAt: usedAt, LastUsedAt: usedAt, ExpiresAt: usedAt.Add(SessionExpiry)},
+	}
+	mockSessionRepo.On("GetActiveSessionsByUserId", int64(1)).Return(sessions, nil)
+
+	// Act
+	result, err := service.GetSessions(1, 4)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.False(t, result[0].IsCurrent)
+	assert.True(t, result[1].IsCurrent)
+	assert.Equal(t, "Safari", result[1].UserAgent)
+	assert.Equal(t, "2025-12-06T12:00:00Z", result[1].LastUsedAt)
+}
+
+func TestSessionService_RevokeSession_NotFound(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("RevokeSession", int64(1), int64(9)).Return(false, nil)
+
+	// Act
+	err := service.RevokeSession(1, 9)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionService_RevokeSessionByRefreshToken(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("old-refresh-token")).Return(activeSession(), nil)
+	mockSessionRepo.On("RevokeSession", int64(1), int64(9)).Return(true, nil)
+
+	// Act
+	err := service.RevokeSessionByRefreshToken("old-refresh-token")
+
+	// Assert
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestSessionService_RevokeSessionByRefreshToken_UnknownToken(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("GetSessionByTokenHash", hashRefreshToken("unknown")).Return(nil, nil)
+
+	// Act
+	err := service.RevokeSessionByRefreshToken("unknown")
+
+	// Assert
+	assert.NoError(t, err)
+	mockSessionRepo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestSessionService_RevokeAllSessions_Error(t *testing.T) {
+	// Arrange
+	service, mockSessionRepo, _, _ := newSessionTestService()
+
+	mockSessionRepo.On("RevokeAllSessions", int64(1)).Return(errors.New("database error"))
+
+	// Act
+	err := service.RevokeAllSessions(1)
+
+	// Assert
+	assert.EqualError(t, err, "database error")
+}
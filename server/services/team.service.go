@@ -1,13 +1,28 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/snowlynxsoftware/parallax-game/server/database"
 	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
 	"github.com/snowlynxsoftware/parallax-game/server/models"
 )
 
+// Errors returned by TeamService that callers can check with errors.Is
+var (
+	ErrInvalidSpecialization   = errors.New("invalid specialization")
+	ErrTeamAlreadySpecialized  = errors.New("team already has a specialization")
+	ErrInventoryItemNotFound   = errors.New("inventory item not found")
+	ErrInventoryItemNotOwned   = errors.New("inventory item does not belong to user")
+	ErrSpecializationNeedsEpic = errors.New("specializations cost one epic item")
+	ErrCannotConsumeEquipped   = errors.New("cannot consume an equipped item")
+	ErrUpgradeRecipeNotFound   = errors.New("upgrade recipe not found")
+	ErrNotEnoughItems          = errors.New("not enough items for this upgrade")
+)
+
 type ITeamService interface {
 	GetUserTeams(userId int64) ([]*models.TeamResponseDTO, error)
 	GetTeamById(teamId int64) (*models.TeamResponseDTO, error)
@@ -15,15 +30,22 @@ type ITeamService interface {
 	UnequipItemFromTeam(userId, teamId int64, slot string) (*models.TeamResponseDTO, error)
 	ConsumeItemOnTeam(userId, teamId, inventoryId int64) (*models.TeamResponseDTO, error)
 	UnlockTeam(userId, teamId int64) error
+	SpecializeTeam(userId, teamId int64, specialization string, inventoryId int64) (*models.TeamResponseDTO, error)
+	GetUpgradePreviews(userId, teamId int64) ([]*models.UpgradePreviewDTO, error)
+	UpgradeTeam(userId, teamId, recipeId int64) (*models.TeamResponseDTO, error)
 }
 
 type TeamService struct {
-	teamRepository       repositories.ITeamRepository
-	inventoryRepository  repositories.IUserInventoryRepository
-	lootItemRepository   repositories.ILootItemRepository
-	expeditionRepository repositories.IExpeditionRepository
-	riftRepository       repositories.IRiftRepository
-	gameCoreService      IGameCoreService
+	teamRepository          repositories.ITeamRepository
+	inventoryRepository     repositories.IUserInventoryRepository
+	lootItemRepository      repositories.ILootItemRepository
+	expeditionRepository    repositories.IExpeditionRepository
+	riftRepository          repositories.IRiftRepository
+	upgradeRecipeRepository repositories.IUpgradeRecipeRepository
+	gameCoreService         IGameCoreService
+	unlockRuleService       IUnlockRuleService
+	unitOfWork              database.IUnitOfWork
+	eventHubService         IEventHubService
 }
 
 func NewTeamService(
@@ -32,15 +54,23 @@ func NewTeamService(
 	lootItemRepository repositories.ILootItemRepository,
 	expeditionRepository repositories.IExpeditionRepository,
 	riftRepository repositories.IRiftRepository,
+	upgradeRecipeRepository repositories.IUpgradeRecipeRepository,
 	gameCoreService IGameCoreService,
+	unlockRuleService IUnlockRuleService,
+	unitOfWork database.IUnitOfWork,
+	eventHubService IEventHubService,
 ) ITeamService {
 	return &TeamService{
-		teamRepository:       teamRepository,
-		inventoryRepository:  inventoryRepository,
-		lootItemRepository:   lootItemRepository,
-		expeditionRepository: expeditionRepository,
-		riftRepository:       riftRepository,
-		gameCoreService:      gameCoreService,
+		teamRepository:          teamRepository,
+		inventoryRepository:     inventoryRepository,
+		lootItemRepository:      lootItemRepository,
+		expeditionRepository:    expeditionRepository,
+		riftRepository:          riftRepository,
+		upgradeRecipeRepository: upgradeRecipeRepository,
+		gameCoreService:         gameCoreService,
+		unlockRuleService:       unlockRuleService,
+		unitOfWork:              unitOfWork,
+		eventHubService:         eventHubService,
 	}
 }
 
@@ -62,10 +92,19 @@ func (s *TeamService) GetUserTeams(userId int64) ([]*models.TeamResponseDTO, err
 		expeditionByTeam[expedition.TeamID] = expedition
 	}
 
-	// Get total completed expeditions for unlock requirements
-	completedCount, err := s.expeditionRepository.GetCompletedExpeditionsCount(userId)
-	if err != nil {
-		completedCount = 0 // Default to 0 if error
+	// Evaluate unlock rules for locked teams (keyed by team number)
+	lockedTeamNumbers := make([]int64, 0)
+	for _, team := range teams {
+		if !team.IsUnlocked {
+			lockedTeamNumbers = append(lockedTeamNumbers, int64(team.TeamNumber))
+		}
+	}
+	unlockStatuses := make(map[int64]*models.UnlockStatusDTO)
+	if len(lockedTeamNumbers) > 0 {
+		unlockStatuses, err = s.unlockRuleService.GetUnlockStatuses(userId, models.UnlockTargetTeam, lockedTeamNumbers)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	response := make([]*models.TeamResponseDTO, len(teams))
@@ -89,23 +128,23 @@ func (s *TeamService) GetUserTeams(userId int64) ([]*models.TeamResponseDTO, err
 
 		// Check and unlock teams if requirements are met
 		if !team.IsUnlocked {
-			shouldUnlock := s.shouldUnlockTeam(team.TeamNumber, completedCount)
-			if shouldUnlock {
+			unlockStatus := unlockStatuses[int64(team.TeamNumber)]
+			if unlockStatus != nil && unlockStatus.IsUnlocked {
 				// Unlock the team
 				err := s.teamRepository.UnlockTeam(team.ID)
 				if err == nil {
 					// Update the DTO to reflect the unlock
 					teamDTO.IsUnlocked = true
-					teamDTO.UnlockRequirement = nil
+					s.publishTeamUnlocked(team, true)
 				} else {
 					// If unlock fails, still show requirement
-					requirement := s.getUnlockRequirement(team.TeamNumber, completedCount)
-					teamDTO.UnlockRequirement = &requirement
+					teamDTO.UnlockRequirement = unlockStatus.RequirementText
+					teamDTO.UnlockProgress = unlockStatus.ProgressText
 				}
-			} else {
+			} else if unlockStatus != nil {
 				// Still locked, show requirement
-				requirement := s.getUnlockRequirement(team.TeamNumber, completedCount)
-				teamDTO.UnlockRequirement = &requirement
+				teamDTO.UnlockRequirement = unlockStatus.RequirementText
+				teamDTO.UnlockProgress = unlockStatus.ProgressText
 			}
 		}
 
@@ -142,6 +181,9 @@ func (s *TeamService) EquipItemToTeam(userId, teamId int64, slot string, invento
 	if invItem.UserID != userId {
 		return nil, fmt.Errorf("inventory item does not belong to user")
 	}
+	if invItem.EscrowTradeID != nil {
+		return nil, ErrItemInEscrow
+	}
 
 	// Get loot item details to validate slot
 	lootItem, err := s.lootItemRepository.GetLootItemById(invItem.LootItemID)
@@ -265,7 +307,276 @@ func (s *TeamService) UnlockTeam(userId, teamId int64) error {
 		return fmt.Errorf("team does not belong to user")
 	}
 
-	return s.teamRepository.UnlockTeam(teamId)
+	err = s.teamRepository.UnlockTeam(teamId)
+	if err != nil {
+		return err
+	}
+
+	s.publishTeamUnlocked(team, false)
+	return nil
+}
+
+// publishTeamUnlocked tells the team's owner it has been unlocked
+func (s *TeamService) publishTeamUnlocked(team *repositories.TeamEntity, automatic bool) {
+	s.eventHubService.Publish(team.UserID, models.GameEventTeamUnlocked, &models.TeamUnlockedEventDTO{
+		TeamID:     team.ID,
+		TeamNumber: team.TeamNumber,
+		Automatic:  automatic,
+	})
+}
+
+// SpecializeTeam permanently gives a team a specialization in exchange for one Epic item
+func (s *TeamService) SpecializeTeam(userId, teamId int64, specialization string, inventoryId int64) (*models.TeamResponseDTO, error) {
+	if _, exists := SpecializationBonuses[models.Specialization(specialization)]; !exists {
+		return nil, ErrInvalidSpecialization
+	}
+
+	// Validate team belongs to user and isn't specialized yet
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+	if !team.IsUnlocked {
+		return nil, ErrTeamLocked
+	}
+	if isSpecialized(team) {
+		return nil, ErrTeamAlreadySpecialized
+	}
+
+	// Validate the Epic item being spent
+	invItem, err := s.inventoryRepository.GetInventoryById(inventoryId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInventoryItemNotFound
+		}
+		return nil, err
+	}
+	if invItem.UserID != userId {
+		return nil, ErrInventoryItemNotOwned
+	}
+	if invItem.EscrowTradeID != nil {
+		return nil, ErrItemInEscrow
+	}
+
+	lootItem, err := s.lootItemRepository.GetLootItemById(invItem.LootItemID)
+	if err != nil {
+		return nil, err
+	}
+	if lootItem.Rarity != string(models.ItemRarityEpic) {
+		return nil, ErrSpecializationNeedsEpic
+	}
+
+	equippedTeam, _, err := s.teamRepository.GetTeamsByUserIdWithSlot(userId, inventoryId)
+	if err != nil {
+		return nil, err
+	}
+	if equippedTeam != nil {
+		return nil, ErrCannotConsumeEquipped
+	}
+
+	// Set the specialization and spend the item together so neither happens without the other
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		updated, err := s.teamRepository.WithTx(tx).SetSpecialization(teamId, specialization)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Another request specialized the team first
+			return ErrTeamAlreadySpecialized
+		}
+		inventoryRepository := s.inventoryRepository.WithTx(tx)
+		err = inventoryRepository.SpendLoot(inventoryId, 1)
+		if errors.Is(err, repositories.ErrNotEnoughItems) {
+			// Spent, equipped or escrowed by another request since it was checked
+			return s.unspendableItemError(tx, userId, inventoryId)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return updated team
+	return s.GetTeamById(teamId)
+}
+
+// unspendableItemError works out why SpendLoot refused an item that passed the checks
+// outside the transaction
+func (s *TeamService) unspendableItemError(tx *database.AppDataSource, userId, inventoryId int64) error {
+	invItem, err := s.inventoryRepository.WithTx(tx).GetInventoryById(inventoryId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInventoryItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if invItem.EscrowTradeID != nil {
+		return ErrItemInEscrow
+	}
+
+	equippedTeam, _, err := s.teamRepository.WithTx(tx).GetTeamsByUserIdWithSlot(userId, inventoryId)
+	if err != nil {
+		return err
+	}
+	if equippedTeam != nil {
+		return ErrCannotConsumeEquipped
+	}
+	return ErrInventoryItemNotFound
+}
+
+// GetUpgradePreviews lists every upgrade recipe with its cost, what it would do to the team,
+// and whether the player owns enough unequipped items to pay for it
+func (s *TeamService) GetUpgradePreviews(userId, teamId int64) ([]*models.UpgradePreviewDTO, error) {
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	recipes, err := s.upgradeRecipeRepository.GetRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	ownedByRarity := make(map[string]int)
+	previews := make([]*models.UpgradePreviewDTO, 0, len(recipes))
+	for _, recipe := range recipes {
+		currentValue := getBaseStat(team, recipe.Stat)
+		newValue, err := s.gameCoreService.CalculateStatUpgrade(recipe.Stat, currentValue, recipe.StatIncrease, recipe.MaxValue)
+		isMaxed := errors.Is(err, ErrStatAtCap)
+		if err != nil && !isMaxed {
+			return nil, err
+		}
+
+		costs, err := s.upgradeRecipeRepository.GetCostsByRecipeId(recipe.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		canAfford := !isMaxed
+		costDTOs := make([]*models.UpgradeCostDTO, len(costs))
+		for i, cost := range costs {
+			owned, exists := ownedByRarity[cost.Rarity]
+			if !exists {
+				owned, err = s.inventoryRepository.CountUnequippedItemsByRarity(userId, cost.Rarity)
+				if err != nil {
+					return nil, err
+				}
+				ownedByRarity[cost.Rarity] = owned
+			}
+
+			if owned < cost.Quantity {
+				canAfford = false
+			}
+			costDTOs[i] = &models.UpgradeCostDTO{
+				Rarity:   cost.Rarity,
+				Quantity: cost.Quantity,
+				Owned:    owned,
+			}
+		}
+
+		previews = append(previews, &models.UpgradePreviewDTO{
+			RecipeID:     recipe.ID,
+			Name:         recipe.Name,
+			Description:  recipe.Description,
+			Stat:         recipe.Stat,
+			StatIncrease: recipe.StatIncrease,
+			MaxValue:     recipe.MaxValue,
+			CurrentValue: currentValue,
+			NewValue:     newValue,
+			IsMaxed:      isMaxed,
+			CanAfford:    canAfford,
+			Costs:        costDTOs,
+		})
+	}
+
+	return previews, nil
+}
+
+// UpgradeTeam spends the items a recipe costs and raises the team's base stat.
+// Items and stats are updated in one transaction so a failed upgrade costs nothing.
+func (s *TeamService) UpgradeTeam(userId, teamId, recipeId int64) (*models.TeamResponseDTO, error) {
+	team, err := s.getOwnedTeam(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+	if !team.IsUnlocked {
+		return nil, ErrTeamLocked
+	}
+
+	recipe, err := s.upgradeRecipeRepository.GetRecipeById(recipeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUpgradeRecipeNotFound
+		}
+		return nil, err
+	}
+
+	costs, err := s.upgradeRecipeRepository.GetCostsByRecipeId(recipe.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		teamRepository := s.teamRepository.WithTx(tx)
+		inventoryRepository := s.inventoryRepository.WithTx(tx)
+
+		// Lock the team so concurrent upgrades can't both pass the cap check
+		lockedTeam, err := teamRepository.GetTeamByIdForUpdate(teamId)
+		if err != nil {
+			return err
+		}
+
+		currentValue := getBaseStat(lockedTeam, recipe.Stat)
+		newValue, err := s.gameCoreService.CalculateStatUpgrade(recipe.Stat, currentValue, recipe.StatIncrease, recipe.MaxValue)
+		if err != nil {
+			return err
+		}
+
+		for _, cost := range costs {
+			err := inventoryRepository.ConsumeItemsByRarity(userId, cost.Rarity, cost.Quantity)
+			if errors.Is(err, repositories.ErrNotEnoughItems) {
+				return ErrNotEnoughItems
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		increase := newValue - currentValue
+		if recipe.Stat == "speed" {
+			return teamRepository.UpdateTeamStats(teamId, increase, 0, 0)
+		}
+		return teamRepository.UpdateTeamStats(teamId, 0, increase, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return updated team
+	return s.GetTeamById(teamId)
+}
+
+// getOwnedTeam loads a team and checks that it belongs to the user
+func (s *TeamService) getOwnedTeam(userId, teamId int64) (*repositories.TeamEntity, error) {
+	team, err := s.teamRepository.GetTeamById(teamId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+	if team.UserID != userId {
+		return nil, ErrTeamNotOwned
+	}
+	return team, nil
+}
+
+// getBaseStat returns the team's permanent bonus for an upgrade stat
+func getBaseStat(team *repositories.TeamEntity, stat string) float64 {
+	if stat == "speed" {
+		return team.SpeedBonus
+	}
+	return team.LuckBonus
 }
 
 // mapTeamToDTO converts TeamEntity to TeamResponseDTO with all equipment details
@@ -305,20 +616,35 @@ func (s *TeamService) mapTeamToDTO(team *repositories.TeamEntity) (*models.TeamR
 	// Calculate total stats
 	totalStats := s.gameCoreService.CalculateTeamStats(team, equippedItems)
 
+	// Specialization bonuses depend on the rift's world, so they are listed rather than
+	// added to the total stats
+	specialization := string(models.SpecializationNone)
+	var specializationBonuses []*models.SpecializationBonusDTO
+	if isSpecialized(team) {
+		specialization = team.Specialization
+		specializationBonuses = s.gameCoreService.GetSpecializationBonuses(team.Specialization)
+	}
+
 	return &models.TeamResponseDTO{
-		ID:                team.ID,
-		TeamNumber:        team.TeamNumber,
-		IsUnlocked:        team.IsUnlocked,
-		BaseStats:         *baseStats,
-		TotalStats:        *totalStats,
-		EquippedWeapon:    s.mapEquippedItemDTO(team.EquippedWeaponSlot, weapon),
-		EquippedArmor:     s.mapEquippedItemDTO(team.EquippedArmorSlot, armor),
-		EquippedAccessory: s.mapEquippedItemDTO(team.EquippedAccessorySlot, accessory),
-		EquippedArtifact:  s.mapEquippedItemDTO(team.EquippedArtifactSlot, artifact),
-		EquippedRelic:     s.mapEquippedItemDTO(team.EquippedRelicSlot, relic),
+		ID:                    team.ID,
+		TeamNumber:            team.TeamNumber,
+		IsUnlocked:            team.IsUnlocked,
+		Specialization:        specialization,
+		SpecializationBonuses: specializationBonuses,
+		BaseStats:             *baseStats,
+		TotalStats:            *totalStats,
+		EquippedWeapon:        s.mapEquippedItemDTO(team.EquippedWeaponSlot, weapon),
+		EquippedArmor:         s.mapEquippedItemDTO(team.EquippedArmorSlot, armor),
+		EquippedAccessory:     s.mapEquippedItemDTO(team.EquippedAccessorySlot, accessory),
+		EquippedArtifact:      s.mapEquippedItemDTO(team.EquippedArtifactSlot, artifact),
+		EquippedRelic:         s.mapEquippedItemDTO(team.EquippedRelicSlot, relic),
 	}, nil
 }
 
+func isSpecialized(team *repositories.TeamEntity) bool {
+	return team.Specialization != "" && team.Specialization != string(models.SpecializationNone)
+}
+
 func (s *TeamService) getEquippedItem(inventoryId *int64) (*repositories.LootItemEntity, error) {
 	if inventoryId == nil {
 		return nil, nil
@@ -388,35 +714,6 @@ func (s *TeamService) mapExpeditionToDTO(expedition *repositories.ExpeditionEnti
 		TimeRemaining:   timeRemaining,
 		IsCompleted:     expedition.Completed,
 		IsClaimed:       expedition.Claimed,
-	}
-}
-
-func (s *TeamService) getUnlockRequirement(teamNumber int, completedExpeditions int) string {
-	switch teamNumber {
-	case 2:
-		return "Complete 1 expedition to unlock"
-	case 3:
-		return "Complete 3 expeditions to unlock"
-	case 4:
-		return "Complete 25 expeditions to unlock"
-	case 5:
-		return "Complete 50 expeditions to unlock"
-	default:
-		return "Locked"
-	}
-}
-
-func (s *TeamService) shouldUnlockTeam(teamNumber int, completedExpeditions int) bool {
-	switch teamNumber {
-	case 2:
-		return completedExpeditions >= 1
-	case 3:
-		return completedExpeditions >= 3
-	case 4:
-		return completedExpeditions >= 25
-	case 5:
-		return completedExpeditions >= 50
-	default:
-		return false
+		Status:          expedition.Status,
 	}
 }
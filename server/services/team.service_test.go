@@ -1,6 +1,7 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
 	"testing"
 
@@ -34,6 +35,49 @@ func (m *MockGameCoreService) GetElementalBonus(relicAffinity string, riftWeakne
 	return args.Get(0).(float64)
 }
 
+func (m *MockGameCoreService) GetRecommendedPower(difficulty string) int {
+	args := m.Called(difficulty)
+	return args.Int(0)
+}
+
+func (m *MockGameCoreService) CalculatePowerOutcome(effectivePower int, recommendedPower int) *models.PowerOutcomeDTO {
+	args := m.Called(effectivePower, recommendedPower)
+	return args.Get(0).(*models.PowerOutcomeDTO)
+}
+
+func (m *MockGameCoreService) AdjustDropRates(dropTables []*repositories.LootDropTableEntity, luck float64) []*repositories.LootDropTableEntity {
+	args := m.Called(dropTables, luck)
+	return args.Get(0).([]*repositories.LootDropTableEntity)
+}
+
+func (m *MockGameCoreService) GetSpecializationBonuses(specialization string) []*models.SpecializationBonusDTO {
+	args := m.Called(specialization)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).([]*models.SpecializationBonusDTO)
+}
+
+func (m *MockGameCoreService) CalculateStatUpgrade(stat string, currentValue float64, increase float64, recipeMax float64) (float64, error) {
+	args := m.Called(stat, currentValue, increase, recipeMax)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockGameCoreService) CalculateRecallLootMultiplier(progress float64) float64 {
+	args := m.Called(progress)
+	return args.Get(0).(float64)
+}
+
+func (m *MockGameCoreService) GetLaunchQueueLimit(teamNumber int) int {
+	args := m.Called(teamNumber)
+	return args.Int(0)
+}
+
+func (m *MockGameCoreService) ApplySpecialization(stats *models.TeamStatsDTO, specialization string, worldType string) *models.TeamStatsDTO {
+	args := m.Called(stats, specialization, worldType)
+	return args.Get(0).(*models.TeamStatsDTO)
+}
+
 // Test GetUserTeams - Success
 func TestTeamService_GetUserTeams_Success(t *testing.T) {
 	// Arrange
@@ -43,7 +87,8 @@ func TestTeamService_GetUserTeams_Success(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, mockUnlockRuleService, nil, NewEventHubService())
 
 	teams := []*repositories.TeamEntity{
 		{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, SpeedBonus: 10.0, LuckBonus: 5.0, PowerBonus: 20},
@@ -52,7 +97,9 @@ func TestTeamService_GetUserTeams_Success(t *testing.T) {
 
 	mockTeamRepo.On("GetTeamsByUserId", int64(1)).Return(teams, nil)
 	mockExpeditionRepo.On("GetActiveExpeditionsByUserId", int64(1)).Return([]*repositories.ExpeditionEntity{}, nil)
-	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, nil)
+	mockUnlockRuleService.On("GetUnlockStatuses", int64(1), models.UnlockTargetTeam, []int64{2}).Return(map[int64]*models.UnlockStatusDTO{
+		2: {IsUnlocked: false, RequirementText: stringPtr("Complete 1 expedition"), ProgressText: stringPtr("0/1 expeditions")},
+	}, nil)
 	mockGameCoreService.On("CalculateTeamStats", mock.Anything, mock.Anything).Return(&models.TeamStatsDTO{Speed: 10.0, Luck: 5.0, Power: 20})
 
 	// Act
@@ -64,6 +111,45 @@ func TestTeamService_GetUserTeams_Success(t *testing.T) {
 	assert.Equal(t, 2, len(result))
 	assert.Equal(t, 1, result[0].TeamNumber)
 	assert.True(t, result[0].IsUnlocked)
+	assert.False(t, result[1].IsUnlocked)
+	assert.Equal(t, "Complete 1 expedition", *result[1].UnlockRequirement)
+	assert.Equal(t, "0/1 expeditions", *result[1].UnlockProgress)
+	mockTeamRepo.AssertExpectations(t)
+	mockTeamRepo.AssertNotCalled(t, "UnlockTeam", mock.Anything)
+}
+
+// Test GetUserTeams - Unlocks Team When Rule Is Met
+func TestTeamService_GetUserTeams_UnlocksTeamWhenRuleMet(t *testing.T) {
+	// Arrange
+	mockTeamRepo := new(MockTeamRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	mockExpeditionRepo := new(MockExpeditionRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockUnlockRuleService := new(MockUnlockRuleService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, mockUnlockRuleService, nil, NewEventHubService())
+
+	teams := []*repositories.TeamEntity{
+		{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true},
+		{ID: 2, UserID: 1, TeamNumber: 2, IsUnlocked: false},
+	}
+
+	mockTeamRepo.On("GetTeamsByUserId", int64(1)).Return(teams, nil)
+	mockTeamRepo.On("UnlockTeam", int64(2)).Return(nil)
+	mockExpeditionRepo.On("GetActiveExpeditionsByUserId", int64(1)).Return([]*repositories.ExpeditionEntity{}, nil)
+	mockUnlockRuleService.On("GetUnlockStatuses", int64(1), models.UnlockTargetTeam, []int64{2}).Return(map[int64]*models.UnlockStatusDTO{
+		2: {IsUnlocked: true, RequirementText: stringPtr("Complete 1 expedition"), ProgressText: stringPtr("1/1 expeditions")},
+	}, nil)
+	mockGameCoreService.On("CalculateTeamStats", mock.Anything, mock.Anything).Return(&models.TeamStatsDTO{})
+
+	// Act
+	result, err := service.GetUserTeams(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result[1].IsUnlocked)
+	assert.Nil(t, result[1].UnlockRequirement)
 	mockTeamRepo.AssertExpectations(t)
 }
 
@@ -76,7 +162,7 @@ func TestTeamService_GetUserTeams_RepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamsByUserId", int64(1)).Return(nil, errors.New("database error"))
 
@@ -98,7 +184,7 @@ func TestTeamService_GetTeamById_SuccessWithEquippedItems(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	weaponInvId := int64(10)
 	team := &repositories.TeamEntity{
@@ -148,7 +234,7 @@ func TestTeamService_GetTeamById_SuccessNoEquippedItems(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{
 		ID:         1,
@@ -183,7 +269,7 @@ func TestTeamService_GetTeamById_TeamNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(999)).Return(nil, errors.New("team not found"))
 
@@ -205,7 +291,7 @@ func TestTeamService_GetTeamById_RepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(nil, errors.New("database connection failed"))
 
@@ -227,7 +313,7 @@ func TestTeamService_EquipItemToTeam_Success(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -261,7 +347,7 @@ func TestTeamService_EquipItemToTeam_ItemNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 
@@ -287,7 +373,7 @@ func TestTeamService_EquipItemToTeam_InventoryDoesntBelongToUser(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100} // Different user!
@@ -315,7 +401,7 @@ func TestTeamService_EquipItemToTeam_ItemIsConsumable(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -346,7 +432,7 @@ func TestTeamService_EquipItemToTeam_SlotTypeMismatch(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -378,7 +464,7 @@ func TestTeamService_EquipItemToTeam_ItemEquippedOnDifferentTeam(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team1 := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	team2 := &repositories.TeamEntity{ID: 2, UserID: 1, TeamNumber: 2}
@@ -414,7 +500,7 @@ func TestTeamService_EquipItemToTeam_TeamNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(999)).Return(nil, errors.New("team not found"))
 
@@ -436,7 +522,7 @@ func TestTeamService_EquipItemToTeam_TeamDoesntBelongToUser(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 2, TeamNumber: 1} // Different user!
 
@@ -461,7 +547,7 @@ func TestTeamService_EquipItemToTeam_EquipRepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -494,7 +580,7 @@ func TestTeamService_UnequipItemFromTeam_Success(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 
@@ -520,7 +606,7 @@ func TestTeamService_UnequipItemFromTeam_TeamNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(999)).Return(nil, errors.New("team not found"))
 
@@ -542,7 +628,7 @@ func TestTeamService_UnequipItemFromTeam_TeamDoesntBelongToUser(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 2, TeamNumber: 1} // Different user!
 
@@ -567,7 +653,7 @@ func TestTeamService_UnequipItemFromTeam_RepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 
@@ -592,7 +678,7 @@ func TestTeamService_ConsumeItemOnTeam_Success(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -626,7 +712,7 @@ func TestTeamService_ConsumeItemOnTeam_NotAConsumable(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -658,7 +744,7 @@ func TestTeamService_ConsumeItemOnTeam_InventoryDoesntBelongToUser(t *testing.T)
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100} // Different user!
@@ -686,7 +772,7 @@ func TestTeamService_ConsumeItemOnTeam_InventoryNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 
@@ -712,7 +798,7 @@ func TestTeamService_ConsumeItemOnTeam_TeamNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(999)).Return(nil, errors.New("team not found"))
 
@@ -734,7 +820,7 @@ func TestTeamService_ConsumeItemOnTeam_ConsumeRepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1}
 	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
@@ -767,7 +853,9 @@ func TestTeamService_UnlockTeam_Success(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	eventHub := NewEventHubService()
+	subscription := eventHub.Subscribe(1, 0)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, eventHub)
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 2, IsUnlocked: false}
 
@@ -780,6 +868,9 @@ func TestTeamService_UnlockTeam_Success(t *testing.T) {
 	// Assert
 	assert.NoError(t, err)
 	mockTeamRepo.AssertExpectations(t)
+	event := receive(t, subscription)
+	assert.Equal(t, models.GameEventTeamUnlocked, event.Type)
+	assert.Equal(t, &models.TeamUnlockedEventDTO{TeamID: 1, TeamNumber: 2}, event.Data)
 }
 
 // Test UnlockTeam - Team Not Found
@@ -791,7 +882,7 @@ func TestTeamService_UnlockTeam_TeamNotFound(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(999)).Return(nil, errors.New("team not found"))
 
@@ -812,7 +903,7 @@ func TestTeamService_UnlockTeam_TeamDoesntBelongToUser(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 2, TeamNumber: 2} // Different user!
 
@@ -836,7 +927,7 @@ func TestTeamService_UnlockTeam_RepositoryError(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 2}
 
@@ -860,7 +951,7 @@ func TestTeamService_UnlockTeam_NilTeamEntity(t *testing.T) {
 	mockGameCoreService := new(MockGameCoreService)
 	mockExpeditionRepo := new(MockExpeditionRepository)
 	mockRiftRepo := new(MockRiftRepository)
-	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockGameCoreService)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, nil, NewEventHubService())
 
 	mockTeamRepo.On("GetTeamById", int64(1)).Return(nil, nil) // Nil team
 
@@ -869,3 +960,446 @@ func TestTeamService_UnlockTeam_NilTeamEntity(t *testing.T) {
 		service.UnlockTeam(1, 1)
 	})
 }
+
+func newSpecializeTeamTestService() (ITeamService, *MockTeamRepository, *MockUserInventoryRepository, *MockLootItemRepository, *MockGameCoreService) {
+	mockTeamRepo := new(MockTeamRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	mockExpeditionRepo := new(MockExpeditionRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, nil, mockGameCoreService, nil, &MockUnitOfWork{}, NewEventHubService())
+	return service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockGameCoreService
+}
+
+// Test SpecializeTeam - Success
+func TestTeamService_SpecializeTeam_Success(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockGameCoreService := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	specializedTeam := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "tank"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Inferno Heart", Rarity: "epic"}
+	bonuses := SpecializationBonuses[models.SpecializationTank]
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil).Once()
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(specializedTeam, nil).Once()
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(nil, nil, nil)
+	mockTeamRepo.On("SetSpecialization", int64(1), "tank").Return(true, nil)
+	mockInventoryRepo.On("SpendLoot", int64(10), 1).Return(nil)
+	mockGameCoreService.On("CalculateTeamStats", specializedTeam, mock.Anything).Return(&models.TeamStatsDTO{})
+	mockGameCoreService.On("GetSpecializationBonuses", "tank").Return(bonuses)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "tank", result.Specialization)
+	assert.Equal(t, bonuses, result.SpecializationBonuses)
+	mockTeamRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertExpectations(t)
+}
+
+// Test SpecializeTeam - Invalid Specialization
+func TestTeamService_SpecializeTeam_InvalidSpecialization(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, _, _, _ := newSpecializeTeamTestService()
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "wizard", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidSpecialization)
+	assert.Nil(t, result)
+	mockTeamRepo.AssertNotCalled(t, "GetTeamById", mock.Anything)
+}
+
+// Test SpecializeTeam - Already Specialized
+func TestTeamService_SpecializeTeam_AlreadySpecialized(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, _, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "scout"}
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamAlreadySpecialized)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "SpendLoot", mock.Anything, mock.Anything)
+}
+
+// Test SpecializeTeam - Team Not Owned
+func TestTeamService_SpecializeTeam_TeamNotOwned(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, _, _, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 2, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamNotOwned)
+	assert.Nil(t, result)
+}
+
+// Test SpecializeTeam - Item Not Epic
+func TestTeamService_SpecializeTeam_ItemNotEpic(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Ember Shard", Rarity: "rare"}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrSpecializationNeedsEpic)
+	assert.Nil(t, result)
+	mockTeamRepo.AssertNotCalled(t, "SetSpecialization", mock.Anything, mock.Anything)
+}
+
+// Test SpecializeTeam - Item Not Owned
+func TestTeamService_SpecializeTeam_ItemNotOwned(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, _, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 2, LootItemID: 100}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInventoryItemNotOwned)
+	assert.Nil(t, result)
+}
+
+// Test SpecializeTeam - Item Equipped
+func TestTeamService_SpecializeTeam_ItemEquipped(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Inferno Heart", Rarity: "epic"}
+	slot := "relic"
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(team, &slot, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCannotConsumeEquipped)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "SpendLoot", mock.Anything, mock.Anything)
+}
+
+// Test SpecializeTeam - Lost Race To Another Request
+func TestTeamService_SpecializeTeam_ConcurrentSpecialize(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Inferno Heart", Rarity: "epic"}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(nil, nil, nil)
+	mockTeamRepo.On("SetSpecialization", int64(1), "tank").Return(false, nil)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamAlreadySpecialized)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "SpendLoot", mock.Anything, mock.Anything) // item is not spent
+}
+
+// Test SpecializeTeam - Item Escrowed By A Trade After It Was Checked
+func TestTeamService_SpecializeTeam_EscrowedAfterCheck(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	tradeId := int64(5)
+	escrowedItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100, EscrowTradeID: &tradeId}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Inferno Heart", Rarity: "epic"}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil).Once()
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(escrowedItem, nil).Once()
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(nil, nil, nil)
+	mockTeamRepo.On("SetSpecialization", int64(1), "tank").Return(true, nil)
+	mockInventoryRepo.On("SpendLoot", int64(10), 1).Return(repositories.ErrNotEnoughItems)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrItemInEscrow)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertExpectations(t)
+}
+
+// Test SpecializeTeam - Item Equipped After It Was Checked
+func TestTeamService_SpecializeTeam_EquippedAfterCheck(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockLootItemRepo, _ := newSpecializeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, Specialization: "none"}
+	invItem := &repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}
+	lootItem := &repositories.LootItemEntity{ID: 100, Name: "Inferno Heart", Rarity: "epic"}
+	slot := "relic"
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockInventoryRepo.On("GetInventoryById", int64(10)).Return(invItem, nil)
+	mockLootItemRepo.On("GetLootItemById", int64(100)).Return(lootItem, nil)
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(nil, nil, nil).Once()
+	mockTeamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(team, &slot, nil).Once()
+	mockTeamRepo.On("SetSpecialization", int64(1), "tank").Return(true, nil)
+	mockInventoryRepo.On("SpendLoot", int64(10), 1).Return(repositories.ErrNotEnoughItems)
+
+	// Act
+	result, err := service.SpecializeTeam(1, 1, "tank", 10)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCannotConsumeEquipped)
+	assert.Nil(t, result)
+	mockTeamRepo.AssertExpectations(t)
+}
+
+// MockUpgradeRecipeRepository for TeamService upgrade tests
+type MockUpgradeRecipeRepository struct {
+	mock.Mock
+}
+
+func (m *MockUpgradeRecipeRepository) GetRecipes() ([]*repositories.UpgradeRecipeEntity, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.UpgradeRecipeEntity), args.Error(1)
+}
+
+func (m *MockUpgradeRecipeRepository) GetRecipeById(recipeId int64) (*repositories.UpgradeRecipeEntity, error) {
+	args := m.Called(recipeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UpgradeRecipeEntity), args.Error(1)
+}
+
+func (m *MockUpgradeRecipeRepository) GetCostsByRecipeId(recipeId int64) ([]*repositories.UpgradeRecipeCostEntity, error) {
+	args := m.Called(recipeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.UpgradeRecipeCostEntity), args.Error(1)
+}
+
+func newUpgradeTeamTestService() (ITeamService, *MockTeamRepository, *MockUserInventoryRepository, *MockUpgradeRecipeRepository, *MockGameCoreService) {
+	mockTeamRepo := new(MockTeamRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockLootItemRepo := new(MockLootItemRepository)
+	mockGameCoreService := new(MockGameCoreService)
+	mockExpeditionRepo := new(MockExpeditionRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	mockUpgradeRecipeRepo := new(MockUpgradeRecipeRepository)
+	service := NewTeamService(mockTeamRepo, mockInventoryRepo, mockLootItemRepo, mockExpeditionRepo, mockRiftRepo, mockUpgradeRecipeRepo, mockGameCoreService, nil, &MockUnitOfWork{}, NewEventHubService())
+	return service, mockTeamRepo, mockInventoryRepo, mockUpgradeRecipeRepo, mockGameCoreService
+}
+
+var speedRecipe = &repositories.UpgradeRecipeEntity{ID: 1, Name: "Speed Upgrade", Stat: "speed", StatIncrease: 5.0, MaxValue: 50.0}
+var luckRecipe = &repositories.UpgradeRecipeEntity{ID: 2, Name: "Luck Upgrade", Stat: "luck", StatIncrease: 3.0, MaxValue: 30.0}
+var speedRecipeCosts = []*repositories.UpgradeRecipeCostEntity{
+	{RecipeID: 1, Rarity: "uncommon", Quantity: 10},
+	{RecipeID: 1, Rarity: "rare", Quantity: 5},
+}
+var luckRecipeCosts = []*repositories.UpgradeRecipeCostEntity{
+	{RecipeID: 2, Rarity: "uncommon", Quantity: 15},
+	{RecipeID: 2, Rarity: "rare", Quantity: 3},
+}
+
+// Test GetUpgradePreviews - Success
+func TestTeamService_GetUpgradePreviews_Success(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockUpgradeRecipeRepo, mockGameCoreService := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, SpeedBonus: 10.0, LuckBonus: 30.0}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockUpgradeRecipeRepo.On("GetRecipes").Return([]*repositories.UpgradeRecipeEntity{speedRecipe, luckRecipe}, nil)
+	mockUpgradeRecipeRepo.On("GetCostsByRecipeId", int64(1)).Return(speedRecipeCosts, nil)
+	mockUpgradeRecipeRepo.On("GetCostsByRecipeId", int64(2)).Return(luckRecipeCosts, nil)
+	mockGameCoreService.On("CalculateStatUpgrade", "speed", 10.0, 5.0, 50.0).Return(15.0, nil)
+	mockGameCoreService.On("CalculateStatUpgrade", "luck", 30.0, 3.0, 30.0).Return(30.0, ErrStatAtCap)
+	mockInventoryRepo.On("CountUnequippedItemsByRarity", int64(1), "uncommon").Return(20, nil)
+	mockInventoryRepo.On("CountUnequippedItemsByRarity", int64(1), "rare").Return(5, nil)
+
+	// Act
+	result, err := service.GetUpgradePreviews(1, 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, 10.0, result[0].CurrentValue)
+	assert.Equal(t, 15.0, result[0].NewValue)
+	assert.True(t, result[0].CanAfford)
+	assert.False(t, result[0].IsMaxed)
+	assert.Equal(t, 20, result[0].Costs[0].Owned)
+	assert.True(t, result[1].IsMaxed)
+	assert.False(t, result[1].CanAfford) // Maxed upgrades can't be bought
+	mockInventoryRepo.AssertNumberOfCalls(t, "CountUnequippedItemsByRarity", 2)
+}
+
+// Test GetUpgradePreviews - Team Not Owned
+func TestTeamService_GetUpgradePreviews_TeamNotOwned(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, _, mockUpgradeRecipeRepo, _ := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 2, TeamNumber: 1, IsUnlocked: true}
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+
+	// Act
+	result, err := service.GetUpgradePreviews(1, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamNotOwned)
+	assert.Nil(t, result)
+	mockUpgradeRecipeRepo.AssertNotCalled(t, "GetRecipes")
+}
+
+// Test UpgradeTeam - Success
+func TestTeamService_UpgradeTeam_Success(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockUpgradeRecipeRepo, mockGameCoreService := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, SpeedBonus: 48.0}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockUpgradeRecipeRepo.On("GetRecipeById", int64(1)).Return(speedRecipe, nil)
+	mockUpgradeRecipeRepo.On("GetCostsByRecipeId", int64(1)).Return(speedRecipeCosts, nil)
+	mockTeamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(team, nil)
+	mockGameCoreService.On("CalculateStatUpgrade", "speed", 48.0, 5.0, 50.0).Return(50.0, nil)
+	mockInventoryRepo.On("ConsumeItemsByRarity", int64(1), "uncommon", 10).Return(nil)
+	mockInventoryRepo.On("ConsumeItemsByRarity", int64(1), "rare", 5).Return(nil)
+	mockTeamRepo.On("UpdateTeamStats", int64(1), 2.0, 0.0, 0).Return(nil) // Only raised up to the max
+	mockGameCoreService.On("CalculateTeamStats", team, mock.Anything).Return(&models.TeamStatsDTO{Speed: 50.0})
+
+	// Act
+	result, err := service.UpgradeTeam(1, 1, 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	mockTeamRepo.AssertExpectations(t)
+	mockInventoryRepo.AssertExpectations(t)
+}
+
+// Test UpgradeTeam - Not Enough Items
+func TestTeamService_UpgradeTeam_NotEnoughItems(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockUpgradeRecipeRepo, mockGameCoreService := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, LuckBonus: 0.0}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockUpgradeRecipeRepo.On("GetRecipeById", int64(2)).Return(luckRecipe, nil)
+	mockUpgradeRecipeRepo.On("GetCostsByRecipeId", int64(2)).Return(luckRecipeCosts, nil)
+	mockTeamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(team, nil)
+	mockGameCoreService.On("CalculateStatUpgrade", "luck", 0.0, 3.0, 30.0).Return(3.0, nil)
+	mockInventoryRepo.On("ConsumeItemsByRarity", int64(1), "uncommon", 15).Return(nil)
+	mockInventoryRepo.On("ConsumeItemsByRarity", int64(1), "rare", 3).Return(repositories.ErrNotEnoughItems)
+
+	// Act
+	result, err := service.UpgradeTeam(1, 1, 2)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotEnoughItems)
+	assert.Nil(t, result)
+	mockTeamRepo.AssertNotCalled(t, "UpdateTeamStats", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Test UpgradeTeam - Stat At Cap
+func TestTeamService_UpgradeTeam_StatAtCap(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, mockInventoryRepo, mockUpgradeRecipeRepo, mockGameCoreService := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true, SpeedBonus: 50.0}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockUpgradeRecipeRepo.On("GetRecipeById", int64(1)).Return(speedRecipe, nil)
+	mockUpgradeRecipeRepo.On("GetCostsByRecipeId", int64(1)).Return(speedRecipeCosts, nil)
+	mockTeamRepo.On("GetTeamByIdForUpdate", int64(1)).Return(team, nil)
+	mockGameCoreService.On("CalculateStatUpgrade", "speed", 50.0, 5.0, 50.0).Return(50.0, ErrStatAtCap)
+
+	// Act
+	result, err := service.UpgradeTeam(1, 1, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrStatAtCap)
+	assert.Nil(t, result)
+	mockInventoryRepo.AssertNotCalled(t, "ConsumeItemsByRarity", mock.Anything, mock.Anything, mock.Anything) // Nothing spent
+}
+
+// Test UpgradeTeam - Recipe Not Found
+func TestTeamService_UpgradeTeam_RecipeNotFound(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, _, mockUpgradeRecipeRepo, _ := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 1, IsUnlocked: true}
+
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+	mockUpgradeRecipeRepo.On("GetRecipeById", int64(99)).Return(nil, sql.ErrNoRows)
+
+	// Act
+	result, err := service.UpgradeTeam(1, 1, 99)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUpgradeRecipeNotFound)
+	assert.Nil(t, result)
+}
+
+// Test UpgradeTeam - Team Locked
+func TestTeamService_UpgradeTeam_TeamLocked(t *testing.T) {
+	// Arrange
+	service, mockTeamRepo, _, mockUpgradeRecipeRepo, _ := newUpgradeTeamTestService()
+
+	team := &repositories.TeamEntity{ID: 1, UserID: 1, TeamNumber: 3, IsUnlocked: false}
+	mockTeamRepo.On("GetTeamById", int64(1)).Return(team, nil)
+
+	// Act
+	result, err := service.UpgradeTeam(1, 1, 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTeamLocked)
+	assert.Nil(t, result)
+	mockUpgradeRecipeRepo.AssertNotCalled(t, "GetRecipeById", mock.Anything)
+}
@@ -107,6 +107,8 @@ func (ts *TemplateService) loadTemplates() {
 		"templates/pages/privacy.html",
 		"templates/pages/dungeons.html",
 		"templates/pages/leaderboards.html",
+		"templates/pages/guild.html",
+		"templates/pages/trades.html",
 	}
 
 	for _, file := range pageFiles {
@@ -1,134 +1,240 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
 )
 
 const (
 	accessTokenExpirationInMinutes         = 60
 	verificationTokenExpirationInHours     = 3
 	loginWithEmailTokenExpirationInMinutes = 10
+	passwordResetTokenExpirationInMinutes  = 60
+	twoFactorTokenExpirationInMinutes      = 5
 	refreshTokenExpirationInHours          = 160
+	refreshTokenBytes                      = 32
+	tokenIdBytes                           = 16
 	claimIssuer                            = "https://parallax.com"
+	claimAudience                          = "parallax-game"
 )
 
+// TokenPurpose is what a token may be used for. It's carried in the sub claim, and a
+// token is only accepted where its purpose is expected.
+type TokenPurpose string
+
+const (
+	TokenPurposeAccess         TokenPurpose = "api_access_token"
+	TokenPurposeVerification   TokenPurpose = "api_verification_token"
+	TokenPurposeLoginWithEmail TokenPurpose = "loginwithemail_token"
+	TokenPurposePasswordReset  TokenPurpose = "password_reset_token"
+	// Issued after the password or email link for users with two-factor authentication,
+	// and swapped for a session once they've entered a code
+	TokenPurposeTwoFactor TokenPurpose = "two_factor_pending_token"
+)
+
+// Errors returned by TokenService that callers can check with errors.Is
+var (
+	ErrInvalidToken       = errors.New("JWT could not be validated")
+	ErrInvalidTokenClaims = errors.New("JWT claims could not be validated")
+	ErrWrongTokenPurpose  = errors.New("JWT is not valid for this purpose")
+	ErrTokenAlreadyUsed   = errors.New("the token has already been used")
+)
+
+// TokenClaims are the claims in every token the service issues. SessionID is only set
+// on access tokens, and LoginMethod on two-factor tokens.
+type TokenClaims struct {
+	UserID      int                `json:"user"`
+	SessionID   int64              `json:"sid,omitempty"`
+	LoginMethod models.LoginMethod `json:"login_method,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Purpose returns what the token was issued for
+func (c *TokenClaims) Purpose() TokenPurpose {
+	return TokenPurpose(c.Subject)
+}
+
 type ITokenService interface {
-	GenerateAccessToken(id int) (*string, error)
+	GenerateAccessToken(id int, sessionId int64) (*string, error)
 	GenerateLoginWithEmailToken(id int) (*string, error)
 	GenerateVerificationToken(id int) (*string, error)
-	GenerateRefreshToken(id int) (*string, error)
-	ValidateToken(tokenToVerify *string) (*int, error)
+	GeneratePasswordResetToken(id int) (*string, error)
+	GenerateTwoFactorToken(id int, method models.LoginMethod) (*string, error)
+	GenerateRefreshToken() (*string, error)
+	ValidateToken(tokenToVerify *string, expectedPurpose TokenPurpose) (*TokenClaims, error)
+
+	// ConsumeToken marks a validated token as used, so it can't be used again.
+	// Login-with-email, password reset and two-factor tokens are single use and must be
+	// consumed.
+	ConsumeToken(claims *TokenClaims) error
 }
 
+// TokenService signs tokens with the current secret key. Tokens name their key in the
+// kid header, so tokens signed with a previous key keep working until they expire
+// while the key is rotated.
 type TokenService struct {
-	jwtSecretKey string
+	signingKeyId        string
+	keys                map[string][]byte
+	usedTokenRepository repositories.IUsedTokenRepository
 }
 
-func NewTokenService(jwtSecretKey string) ITokenService {
+func NewTokenService(jwtSecretKey string, previousSecretKeys []string, usedTokenRepository repositories.IUsedTokenRepository) ITokenService {
+	keys := map[string][]byte{}
+	for _, key := range previousSecretKeys {
+		keys[signingKeyId(key)] = []byte(key)
+	}
+	keys[signingKeyId(jwtSecretKey)] = []byte(jwtSecretKey)
+
 	return &TokenService{
-		jwtSecretKey: jwtSecretKey,
+		signingKeyId:        signingKeyId(jwtSecretKey),
+		keys:                keys,
+		usedTokenRepository: usedTokenRepository,
 	}
 }
 
-func (s *TokenService) GenerateAccessToken(id int) (*string, error) {
+// GenerateAccessToken issues an access token for one of the user's sessions. It stops
+// working as soon as the session is revoked.
+func (s *TokenService) GenerateAccessToken(id int, sessionId int64) (*string, error) {
+	return s.generateToken(TokenPurposeAccess, &TokenClaims{UserID: id, SessionID: sessionId}, accessTokenExpirationInMinutes*time.Minute)
+}
 
-	expirationTime := time.Now().Add(accessTokenExpirationInMinutes * time.Minute).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512,
-		jwt.MapClaims{
-			"iss":  claimIssuer,
-			"sub":  "api_access_token",
-			"exp":  expirationTime,
-			"user": id,
-		})
-	signedToken, err := token.SignedString([]byte(s.jwtSecretKey))
-	if err != nil {
+func (s *TokenService) GenerateLoginWithEmailToken(id int) (*string, error) {
+	return s.generateToken(TokenPurposeLoginWithEmail, &TokenClaims{UserID: id}, loginWithEmailTokenExpirationInMinutes*time.Minute)
+}
+
+func (s *TokenService) GenerateVerificationToken(id int) (*string, error) {
+	return s.generateToken(TokenPurposeVerification, &TokenClaims{UserID: id}, verificationTokenExpirationInHours*time.Hour)
+}
+
+func (s *TokenService) GeneratePasswordResetToken(id int) (*string, error) {
+	return s.generateToken(TokenPurposePasswordReset, &TokenClaims{UserID: id}, passwordResetTokenExpirationInMinutes*time.Minute)
+}
+
+// GenerateTwoFactorToken issues the token a user with two-factor authentication gets
+// after the first step of logging in. method is how they took that step.
+func (s *TokenService) GenerateTwoFactorToken(id int, method models.LoginMethod) (*string, error) {
+	return s.generateToken(TokenPurposeTwoFactor, &TokenClaims{UserID: id, LoginMethod: method}, twoFactorTokenExpirationInMinutes*time.Minute)
+}
+
+// GenerateRefreshToken returns a random, opaque refresh token. It means nothing on its
+// own, the session it's stored against says who it belongs to.
+func (s *TokenService) GenerateRefreshToken() (*string, error) {
+	bytes := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(bytes); err != nil {
 		return nil, err
 	}
-	return &signedToken, nil
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+	return &token, nil
 }
 
-func (s *TokenService) GenerateLoginWithEmailToken(id int) (*string, error) {
-
-	expirationTime := time.Now().Add(loginWithEmailTokenExpirationInMinutes * time.Minute).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512,
-		jwt.MapClaims{
-			"iss":  claimIssuer,
-			"sub":  "loginwithemail_token",
-			"exp":  expirationTime,
-			"user": id,
-		})
-	signedToken, err := token.SignedString([]byte(s.jwtSecretKey))
+// ValidateToken checks the token's signature, issuer, audience and expiry, and that it
+// was issued for expectedPurpose. It doesn't check whether a single-use token has
+// been used, ConsumeToken does that.
+func (s *TokenService) ValidateToken(tokenToVerify *string, expectedPurpose TokenPurpose) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(*tokenToVerify, claims, s.lookupKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}),
+		jwt.WithIssuer(claimIssuer),
+		jwt.WithAudience(claimAudience),
+		jwt.WithSubject(string(expectedPurpose)),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, jwt.ErrTokenInvalidSubject) {
+			return nil, ErrWrongTokenPurpose
+		}
+		return nil, ErrInvalidToken
 	}
-	return &signedToken, nil
+
+	if claims.UserID <= 0 || claims.ID == "" {
+		return nil, ErrInvalidTokenClaims
+	}
+	// Access tokens stop working when their session is revoked, so they need one
+	if expectedPurpose == TokenPurposeAccess && claims.SessionID <= 0 {
+		return nil, ErrInvalidTokenClaims
+	}
+	if expectedPurpose == TokenPurposeTwoFactor && claims.LoginMethod == "" {
+		return nil, ErrInvalidTokenClaims
+	}
+
+	return claims, nil
 }
 
-func (s *TokenService) GenerateVerificationToken(id int) (*string, error) {
+func (s *TokenService) ConsumeToken(claims *TokenClaims) error {
+	marked, err := s.usedTokenRepository.MarkTokenUsed(claims.ID, int64(claims.UserID), claims.Subject, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrTokenAlreadyUsed
+	}
+
+	// Tidy up while we're here, expired tokens are rejected without the denylist
+	if err := s.usedTokenRepository.DeleteExpiredTokens(); err != nil {
+		util.LogError(err)
+	}
+	return nil
+}
 
-	expirationTime := time.Now().Add(verificationTokenExpirationInHours * time.Hour).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512,
-		jwt.MapClaims{
-			"iss":  claimIssuer,
-			"sub":  "api_verification_token",
-			"exp":  expirationTime,
-			"user": id,
-		})
-	signedToken, err := token.SignedString([]byte(s.jwtSecretKey))
+// generateToken signs claims, filling in the registered claims for purpose
+func (s *TokenService) generateToken(purpose TokenPurpose, claims *TokenClaims, lifetime time.Duration) (*string, error) {
+	tokenId, err := randomTokenId()
 	if err != nil {
 		return nil, err
 	}
-	return &signedToken, nil
-}
 
-func (s *TokenService) GenerateRefreshToken(id int) (*string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    claimIssuer,
+		Subject:   string(purpose),
+		Audience:  jwt.ClaimStrings{claimAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ID:        tokenId,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+	token.Header["kid"] = s.signingKeyId
 
-	expirationTime := time.Now().Add(refreshTokenExpirationInHours * time.Hour).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512,
-		jwt.MapClaims{
-			"iss":  claimIssuer,
-			"sub":  "api_refresh_token",
-			"exp":  expirationTime,
-			"user": id,
-		})
-	signedToken, err := token.SignedString([]byte(s.jwtSecretKey))
+	signedToken, err := token.SignedString(s.keys[s.signingKeyId])
 	if err != nil {
 		return nil, err
 	}
 	return &signedToken, nil
 }
 
-func (s *TokenService) ValidateToken(tokenToVerify *string) (*int, error) {
-
-	parsedToken, err := jwt.Parse(*tokenToVerify, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-
-		return []byte(s.jwtSecretKey), nil
-	})
-	if err != nil {
-		return nil, errors.New("JWT could not be validated")
+// lookupKey returns the key named by the token's kid header
+func (s *TokenService) lookupKey(token *jwt.Token) (interface{}, error) {
+	keyId, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("JWT has no key id")
 	}
+	key, ok := s.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", keyId)
+	}
+	return key, nil
+}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
-		userClaim, exists := claims["user"]
-		if !exists {
-			return nil, errors.New("JWT claims could not be validated")
-		}
-
-		userFloat, ok := userClaim.(float64)
-		if !ok {
-			return nil, errors.New("JWT claims could not be validated")
-		}
+// signingKeyId names a secret key in the kid header without giving the key away
+func signingKeyId(secretKey string) string {
+	hash := sha256.Sum256([]byte("parallax-kid:" + secretKey))
+	return hex.EncodeToString(hash[:8])
+}
 
-		userId := int(userFloat)
-		return &userId, nil
-	} else {
-		return nil, errors.New("JWT claims could not be validated")
+func randomTokenId() (string, error) {
+	bytes := make([]byte, tokenIdBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(bytes), nil
 }
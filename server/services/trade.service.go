@@ -0,0 +1,576 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/util"
+)
+
+const (
+	// TradeExpiry is how long a trade stays open for the recipient to answer
+	TradeExpiry = 48 * time.Hour
+
+	// TradeMaxItemsPerSide is how many items each player can put into one trade
+	TradeMaxItemsPerSide = 10
+
+	// TradeMaxPending is how many open trades a player can have proposed at once
+	TradeMaxPending = 10
+
+	// TradeHistoryLimit is how many resolved trades are listed
+	TradeHistoryLimit = 20
+)
+
+// Errors returned by TradeService that callers can check with errors.Is
+var (
+	ErrTradeNotFound          = errors.New("trade not found")
+	ErrTradeRecipientNotFound = errors.New("player not found")
+	ErrCannotTradeWithSelf    = errors.New("you can't trade with yourself")
+	ErrEmptyTrade             = errors.New("a trade needs at least one item")
+	ErrTooManyTradeItems      = errors.New("too many items in trade")
+	ErrDuplicateTradeItem     = errors.New("an item can only be in a trade once")
+	ErrTooManyPendingTrades   = errors.New("too many open trades")
+	ErrItemNotTradeable       = errors.New("only equipment can be traded")
+	ErrItemInEscrow           = errors.New("item is held in escrow for a trade")
+	ErrTradeItemUnavailable   = errors.New("an item in this trade is no longer available")
+	ErrTradeNotPending        = errors.New("trade is no longer open")
+	ErrTradeExpired           = errors.New("trade has expired")
+	ErrTradePermission        = errors.New("only the other player can do this")
+)
+
+type ITradeService interface {
+	GetTrades(userId int64) (*models.TradeListDTO, error)
+	GetTrade(userId, tradeId int64) (*models.TradeResponseDTO, error)
+	GetTradeableItems(userId int64) (*models.TradePartnerDTO, error)
+	ProposeTrade(userId int64, dto *models.ProposeTradeDTO) (*models.TradeResponseDTO, error)
+	AcceptTrade(userId, tradeId int64) (*models.TradeResponseDTO, error)
+	DeclineTrade(userId, tradeId int64) (*models.TradeResponseDTO, error)
+	CancelTrade(userId, tradeId int64) (*models.TradeResponseDTO, error)
+	ExpireTrades(limit int) (int, error)
+}
+
+// TradeService swaps equipment between players. The proposer's items are held in
+// escrow from the moment a trade is proposed, so they can't be equipped, spent, or
+// promised to someone else, and are taken off their team so they stop adding to its
+// stats. The recipient's items are only checked when they accept,
+// when everything changes hands in one transaction.
+type TradeService struct {
+	tradeRepository     repositories.ITradeRepository
+	inventoryRepository repositories.IUserInventoryRepository
+	lootItemRepository  repositories.ILootItemRepository
+	teamRepository      repositories.ITeamRepository
+	userRepository      repositories.IUserRepository
+	unitOfWork          database.IUnitOfWork
+	eventHubService     IEventHubService
+}
+
+func NewTradeService(
+	tradeRepository repositories.ITradeRepository,
+	inventoryRepository repositories.IUserInventoryRepository,
+	lootItemRepository repositories.ILootItemRepository,
+	teamRepository repositories.ITeamRepository,
+	userRepository repositories.IUserRepository,
+	unitOfWork database.IUnitOfWork,
+	eventHubService IEventHubService,
+) ITradeService {
+	return &TradeService{
+		tradeRepository:     tradeRepository,
+		inventoryRepository: inventoryRepository,
+		lootItemRepository:  lootItemRepository,
+		teamRepository:      teamRepository,
+		userRepository:      userRepository,
+		unitOfWork:          unitOfWork,
+		eventHubService:     eventHubService,
+	}
+}
+
+// GetTrades returns the user's open trades and their most recently resolved ones
+func (s *TradeService) GetTrades(userId int64) (*models.TradeListDTO, error) {
+	pending, err := s.tradeRepository.GetPendingTradesByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.tradeRepository.GetTradeHistoryByUserId(userId, TradeHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.TradeListDTO{
+		Pending: make([]*models.TradeResponseDTO, len(pending)),
+		History: make([]*models.TradeResponseDTO, len(history)),
+	}
+	for i, trade := range pending {
+		if list.Pending[i], err = s.mapTradeToDTO(userId, trade); err != nil {
+			return nil, err
+		}
+	}
+	for i, trade := range history {
+		if list.History[i], err = s.mapTradeToDTO(userId, trade); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// GetTrade returns a trade the user is part of
+func (s *TradeService) GetTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	trade, err := s.tradeRepository.GetTradeById(tradeId)
+	if err != nil {
+		return nil, err
+	}
+	if trade == nil || (trade.ProposerID != userId && trade.RecipientID != userId) {
+		return nil, ErrTradeNotFound
+	}
+	return s.mapTradeToDTO(userId, trade)
+}
+
+// GetTradeableItems lists a player's equipment that can be asked for in a trade
+func (s *TradeService) GetTradeableItems(userId int64) (*models.TradePartnerDTO, error) {
+	user, err := s.getTradePartner(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	equipment, err := s.inventoryRepository.GetEquipmentByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*models.TradeItemDTO{}
+	for _, invItem := range equipment {
+		if invItem.EscrowTradeID != nil {
+			continue
+		}
+		item, err := s.mapTradeItemToDTO(invItem.ID, invItem.LootItemID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &models.TradePartnerDTO{
+		UserID:      user.ID,
+		DisplayName: user.DisplayName,
+		Items:       items,
+	}, nil
+}
+
+// ProposeTrade offers the user's items for the recipient's and puts the offered items
+// in escrow until the trade is resolved, unequipping any that are equipped
+func (s *TradeService) ProposeTrade(userId int64, dto *models.ProposeTradeDTO) (*models.TradeResponseDTO, error) {
+	if dto.RecipientID == userId {
+		return nil, ErrCannotTradeWithSelf
+	}
+	if len(dto.OfferedInventoryIDs) == 0 && len(dto.RequestedInventoryIDs) == 0 {
+		return nil, ErrEmptyTrade
+	}
+	if len(dto.OfferedInventoryIDs) > TradeMaxItemsPerSide || len(dto.RequestedInventoryIDs) > TradeMaxItemsPerSide {
+		return nil, ErrTooManyTradeItems
+	}
+	seen := make(map[int64]bool)
+	for _, inventoryId := range append(append([]int64{}, dto.OfferedInventoryIDs...), dto.RequestedInventoryIDs...) {
+		if seen[inventoryId] {
+			return nil, ErrDuplicateTradeItem
+		}
+		seen[inventoryId] = true
+	}
+
+	if _, err := s.getTradePartner(dto.RecipientID); err != nil {
+		return nil, err
+	}
+
+	offered, err := s.getTradeableItems(userId, dto.OfferedInventoryIDs, false)
+	if err != nil {
+		return nil, err
+	}
+	requested, err := s.getTradeableItems(dto.RecipientID, dto.RequestedInventoryIDs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.tradeRepository.CountPendingTradesByProposer(userId)
+	if err != nil {
+		return nil, err
+	}
+	if pending >= TradeMaxPending {
+		return nil, ErrTooManyPendingTrades
+	}
+
+	var trade *repositories.TradeEntity
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		tradeRepository := s.tradeRepository.WithTx(tx)
+		inventoryRepository := s.inventoryRepository.WithTx(tx)
+		teamRepository := s.teamRepository.WithTx(tx)
+
+		var err error
+		trade, err = tradeRepository.CreateTrade(userId, dto.RecipientID, time.Now().Add(TradeExpiry))
+		if err != nil {
+			return err
+		}
+
+		for _, invItem := range offered {
+			escrowed, err := inventoryRepository.EscrowItem(invItem.ID, userId, trade.ID)
+			if err != nil {
+				return err
+			}
+			if !escrowed {
+				// Spent, traded or escrowed by another request since it was checked
+				return ErrTradeItemUnavailable
+			}
+			if err := unequipTradeItem(teamRepository, userId, invItem.ID); err != nil {
+				return err
+			}
+			if _, err := tradeRepository.AddTradeItem(trade.ID, invItem.ID, userId, invItem.LootItemID); err != nil {
+				return err
+			}
+		}
+		for _, invItem := range requested {
+			if _, err := tradeRepository.AddTradeItem(trade.ID, invItem.ID, dto.RecipientID, invItem.LootItemID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.GetTrade(userId, trade.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishTradeUpdated(dto.RecipientID, trade.ID, models.TradeStatusPending, userId, response.ProposerName)
+	return response, nil
+}
+
+// AcceptTrade completes a trade offered to the user. Every item is unequipped and
+// handed to the other player in a single transaction, so either the whole trade
+// happens or none of it does.
+func (s *TradeService) AcceptTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	err := s.withLockedTrade(userId, tradeId, false, func(tx *database.AppDataSource, trade *repositories.TradeEntity) error {
+		if !time.Now().Before(trade.ExpiresAt) {
+			return ErrTradeExpired
+		}
+
+		tradeRepository := s.tradeRepository.WithTx(tx)
+		inventoryRepository := s.inventoryRepository.WithTx(tx)
+		teamRepository := s.teamRepository.WithTx(tx)
+
+		items, err := tradeRepository.GetTradeItems(trade.ID)
+		if err != nil {
+			return err
+		}
+
+		// Hold the user's side too, so nothing can be done with it mid-swap
+		for _, item := range items {
+			if item.FromUserID != userId {
+				continue
+			}
+			escrowed, err := inventoryRepository.EscrowItem(item.InventoryID, userId, trade.ID)
+			if err != nil {
+				return err
+			}
+			if !escrowed {
+				return ErrTradeItemUnavailable
+			}
+		}
+
+		for _, item := range items {
+			toUserId := trade.RecipientID
+			if item.FromUserID == trade.RecipientID {
+				toUserId = trade.ProposerID
+			}
+
+			if err := unequipTradeItem(teamRepository, item.FromUserID, item.InventoryID); err != nil {
+				return err
+			}
+
+			transferred, err := inventoryRepository.TransferEscrowedItem(item.InventoryID, trade.ID, toUserId)
+			if err != nil {
+				return err
+			}
+			if !transferred {
+				return ErrTradeItemUnavailable
+			}
+		}
+
+		return s.resolveTrade(tradeRepository, trade.ID, models.TradeStatusCompleted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getResolvedTrade(userId, tradeId)
+}
+
+// DeclineTrade turns down a trade offered to the user and returns the proposer's items
+func (s *TradeService) DeclineTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	err := s.withLockedTrade(userId, tradeId, false, func(tx *database.AppDataSource, trade *repositories.TradeEntity) error {
+		if err := s.inventoryRepository.WithTx(tx).ReleaseEscrow(trade.ID); err != nil {
+			return err
+		}
+		return s.resolveTrade(s.tradeRepository.WithTx(tx), trade.ID, models.TradeStatusDeclined)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getResolvedTrade(userId, tradeId)
+}
+
+// CancelTrade withdraws a trade the user proposed and returns their items
+func (s *TradeService) CancelTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	err := s.withLockedTrade(userId, tradeId, true, func(tx *database.AppDataSource, trade *repositories.TradeEntity) error {
+		if err := s.inventoryRepository.WithTx(tx).ReleaseEscrow(trade.ID); err != nil {
+			return err
+		}
+		return s.resolveTrade(s.tradeRepository.WithTx(tx), trade.ID, models.TradeStatusCancelled)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getResolvedTrade(userId, tradeId)
+}
+
+// ExpireTrades closes up to limit open trades whose time has run out and returns the
+// proposers' items. Called by the background processor.
+// Returns the number of trades expired
+func (s *TradeService) ExpireTrades(limit int) (int, error) {
+	tradeIds, err := s.tradeRepository.GetExpiredTradeIds(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, tradeId := range tradeIds {
+		var trade *repositories.TradeEntity
+		err := s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+			tradeRepository := s.tradeRepository.WithTx(tx)
+
+			locked, err := tradeRepository.GetTradeByIdForUpdate(tradeId)
+			if err != nil || locked == nil {
+				return err
+			}
+			// Accepted, declined or cancelled while waiting for the lock
+			if locked.Status != string(models.TradeStatusPending) {
+				return nil
+			}
+
+			if err := s.inventoryRepository.WithTx(tx).ReleaseEscrow(tradeId); err != nil {
+				return err
+			}
+			if err := s.resolveTrade(tradeRepository, tradeId, models.TradeStatusExpired); err != nil {
+				return err
+			}
+			trade = locked
+			return nil
+		})
+		if err != nil {
+			// Keep going so one bad trade doesn't stall the rest of the batch
+			util.LogError(fmt.Errorf("failed to expire trade %d: %w", tradeId, err))
+			continue
+		}
+		if trade != nil {
+			expired++
+			s.publishTradeResolved(trade, models.TradeStatusExpired)
+		}
+	}
+
+	return expired, nil
+}
+
+// withLockedTrade runs fn in a transaction holding the lock on a pending trade.
+// byProposer says which side of the trade the user has to be on.
+func (s *TradeService) withLockedTrade(userId, tradeId int64, byProposer bool, fn func(tx *database.AppDataSource, trade *repositories.TradeEntity) error) error {
+	return s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		trade, err := s.tradeRepository.WithTx(tx).GetTradeByIdForUpdate(tradeId)
+		if err != nil {
+			return err
+		}
+		if trade == nil || (trade.ProposerID != userId && trade.RecipientID != userId) {
+			return ErrTradeNotFound
+		}
+		if (trade.ProposerID == userId) != byProposer {
+			return ErrTradePermission
+		}
+		if trade.Status != string(models.TradeStatusPending) {
+			return ErrTradeNotPending
+		}
+		return fn(tx, trade)
+	})
+}
+
+// resolveTrade moves the locked trade out of pending
+func (s *TradeService) resolveTrade(tradeRepository repositories.ITradeRepository, tradeId int64, status models.TradeStatus) error {
+	resolved, err := tradeRepository.ResolveTrade(tradeId, string(status))
+	if err != nil {
+		return err
+	}
+	if !resolved {
+		return ErrTradeNotPending
+	}
+	return nil
+}
+
+// getResolvedTrade returns the trade the user just resolved and tells the other player
+func (s *TradeService) getResolvedTrade(userId, tradeId int64) (*models.TradeResponseDTO, error) {
+	trade, err := s.tradeRepository.GetTradeById(tradeId)
+	if err != nil {
+		return nil, err
+	}
+	if trade == nil {
+		return nil, ErrTradeNotFound
+	}
+	s.publishTradeResolved(trade, models.TradeStatus(trade.Status))
+	return s.mapTradeToDTO(userId, trade)
+}
+
+// getTradePartner loads a player who can be traded with
+func (s *TradeService) getTradePartner(userId int64) (*repositories.UserEntity, error) {
+	user, err := s.userRepository.GetUserById(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTradeRecipientNotFound
+		}
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, ErrTradeRecipientNotFound
+	}
+	return user, nil
+}
+
+// getTradeableItems loads the inventory rows one side of a trade puts in and checks the
+// owner can trade them. Items asked of the other player are reported as unavailable
+// rather than saying why.
+func (s *TradeService) getTradeableItems(ownerId int64, inventoryIds []int64, requested bool) ([]*repositories.UserInventoryEntity, error) {
+	items := make([]*repositories.UserInventoryEntity, len(inventoryIds))
+	for i, inventoryId := range inventoryIds {
+		invItem, err := s.inventoryRepository.GetInventoryById(inventoryId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				if requested {
+					return nil, ErrTradeItemUnavailable
+				}
+				return nil, ErrInventoryItemNotFound
+			}
+			return nil, err
+		}
+		if invItem.UserID != ownerId {
+			if requested {
+				return nil, ErrTradeItemUnavailable
+			}
+			return nil, ErrInventoryItemNotOwned
+		}
+		if invItem.EscrowTradeID != nil {
+			if requested {
+				return nil, ErrTradeItemUnavailable
+			}
+			return nil, ErrItemInEscrow
+		}
+
+		lootItem, err := s.lootItemRepository.GetLootItemById(invItem.LootItemID)
+		if err != nil {
+			return nil, err
+		}
+		if lootItem.ItemType != string(models.ItemTypeEquipment) {
+			return nil, ErrItemNotTradeable
+		}
+		items[i] = invItem
+	}
+	return items, nil
+}
+
+// unequipTradeItem takes an item going into a trade off the team it is equipped on, if any
+func unequipTradeItem(teamRepository repositories.ITeamRepository, userId, inventoryId int64) error {
+	team, slot, err := teamRepository.GetTeamsByUserIdWithSlot(userId, inventoryId)
+	if err != nil {
+		return err
+	}
+	if team == nil || slot == nil {
+		return nil
+	}
+	return teamRepository.UnequipItem(team.ID, *slot)
+}
+
+// publishTradeResolved tells the other player how a trade ended, or both players when
+// it expired
+func (s *TradeService) publishTradeResolved(trade *repositories.TradeEntity, status models.TradeStatus) {
+	switch status {
+	case models.TradeStatusCompleted, models.TradeStatusDeclined:
+		s.publishTradeUpdated(trade.ProposerID, trade.ID, status, trade.RecipientID, trade.RecipientName)
+	case models.TradeStatusCancelled:
+		s.publishTradeUpdated(trade.RecipientID, trade.ID, status, trade.ProposerID, trade.ProposerName)
+	case models.TradeStatusExpired:
+		s.publishTradeUpdated(trade.ProposerID, trade.ID, status, trade.RecipientID, trade.RecipientName)
+		s.publishTradeUpdated(trade.RecipientID, trade.ID, status, trade.ProposerID, trade.ProposerName)
+	}
+}
+
+func (s *TradeService) publishTradeUpdated(userId, tradeId int64, status models.TradeStatus, otherUserId int64, otherUserName string) {
+	s.eventHubService.Publish(userId, models.GameEventTradeUpdated, &models.TradeUpdatedEventDTO{
+		TradeID:       tradeId,
+		Status:        string(status),
+		OtherUserID:   otherUserId,
+		OtherUserName: otherUserName,
+	})
+}
+
+func (s *TradeService) mapTradeToDTO(userId int64, trade *repositories.TradeEntity) (*models.TradeResponseDTO, error) {
+	items, err := s.tradeRepository.GetTradeItems(trade.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := &models.TradeResponseDTO{
+		ID:             trade.ID,
+		Status:         trade.Status,
+		ProposerID:     trade.ProposerID,
+		ProposerName:   trade.ProposerName,
+		RecipientID:    trade.RecipientID,
+		RecipientName:  trade.RecipientName,
+		IsProposer:     trade.ProposerID == userId,
+		OfferedItems:   []*models.TradeItemDTO{},
+		RequestedItems: []*models.TradeItemDTO{},
+		CreatedAt:      trade.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ExpiresAt:      trade.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if trade.ResolvedAt != nil {
+		resolvedAt := trade.ResolvedAt.Format("2006-01-02T15:04:05Z")
+		dto.ResolvedAt = &resolvedAt
+	}
+
+	for _, item := range items {
+		itemDTO, err := s.mapTradeItemToDTO(item.InventoryID, item.LootItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item.FromUserID == trade.ProposerID {
+			dto.OfferedItems = append(dto.OfferedItems, itemDTO)
+		} else {
+			dto.RequestedItems = append(dto.RequestedItems, itemDTO)
+		}
+	}
+	return dto, nil
+}
+
+func (s *TradeService) mapTradeItemToDTO(inventoryId, lootItemId int64) (*models.TradeItemDTO, error) {
+	lootItem, err := s.lootItemRepository.GetLootItemById(lootItemId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TradeItemDTO{
+		InventoryID:   inventoryId,
+		LootItemID:    lootItem.ID,
+		Name:          lootItem.Name,
+		Icon:          lootItem.Icon,
+		Rarity:        lootItem.Rarity,
+		EquipmentSlot: lootItem.EquipmentSlot,
+	}, nil
+}
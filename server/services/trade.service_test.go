@@ -0,0 +1,511 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock TradeRepository
+type MockTradeRepository struct {
+	mock.Mock
+}
+
+func (m *MockTradeRepository) CreateTrade(proposerId, recipientId int64, expiresAt time.Time) (*repositories.TradeEntity, error) {
+	args := m.Called(proposerId, recipientId, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.TradeEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) AddTradeItem(tradeId, inventoryId, fromUserId, lootItemId int64) (*repositories.TradeItemEntity, error) {
+	args := m.Called(tradeId, inventoryId, fromUserId, lootItemId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.TradeItemEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetTradeById(tradeId int64) (*repositories.TradeEntity, error) {
+	args := m.Called(tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.TradeEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetTradeByIdForUpdate(tradeId int64) (*repositories.TradeEntity, error) {
+	args := m.Called(tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.TradeEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetTradeItems(tradeId int64) ([]*repositories.TradeItemEntity, error) {
+	args := m.Called(tradeId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.TradeItemEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetPendingTradesByUserId(userId int64) ([]*repositories.TradeEntity, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.TradeEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetTradeHistoryByUserId(userId int64, limit int) ([]*repositories.TradeEntity, error) {
+	args := m.Called(userId, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.TradeEntity), args.Error(1)
+}
+
+func (m *MockTradeRepository) CountPendingTradesByProposer(proposerId int64) (int, error) {
+	args := m.Called(proposerId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTradeRepository) GetExpiredTradeIds(limit int) ([]int64, error) {
+	args := m.Called(limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockTradeRepository) ResolveTrade(tradeId int64, status string) (bool, error) {
+	args := m.Called(tradeId, status)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTradeRepository) WithTx(tx *database.AppDataSource) repositories.ITradeRepository {
+	return m
+}
+
+type tradeTestMocks struct {
+	tradeRepo     *MockTradeRepository
+	inventoryRepo *MockUserInventoryRepository
+	lootItemRepo  *MockLootItemRepository
+	teamRepo      *MockTeamRepository
+	userRepo      *MockUserRepository
+	eventHub      IEventHubService
+}
+
+func newTradeTestService() (ITradeService, *tradeTestMocks) {
+	mocks := &tradeTestMocks{
+		tradeRepo:     new(MockTradeRepository),
+		inventoryRepo: new(MockUserInventoryRepository),
+		lootItemRepo:  new(MockLootItemRepository),
+		teamRepo:      new(MockTeamRepository),
+		userRepo:      new(MockUserRepository),
+		eventHub:      NewEventHubService(),
+	}
+	service := NewTradeService(mocks.tradeRepo, mocks.inventoryRepo, mocks.lootItemRepo, mocks.teamRepo, mocks.userRepo, new(MockUnitOfWork), mocks.eventHub)
+	return service, mocks
+}
+
+// pendingTrade is user 1 offering inventory row 10 (a sword) for user 2's row 20 (a shield)
+func pendingTrade() *repositories.TradeEntity {
+	return &repositories.TradeEntity{
+		ID:            5,
+		ProposerID:    1,
+		RecipientID:   2,
+		ProposerName:  "Aria",
+		RecipientName: "Brom",
+		Status:        string(models.TradeStatusPending),
+		CreatedAt:     time.Now(),
+		ExpiresAt:     time.Now().Add(TradeExpiry),
+	}
+}
+
+var testTradeItems = []*repositories.TradeItemEntity{
+	{TradeID: 5, InventoryID: 10, FromUserID: 1, LootItemID: 100},
+	{TradeID: 5, InventoryID: 20, FromUserID: 2, LootItemID: 200},
+}
+
+func (m *tradeTestMocks) expectTradeLootItems() {
+	m.lootItemRepo.On("GetLootItemById", int64(100)).Return(&repositories.LootItemEntity{ID: 100, Name: "Rift Blade", Rarity: "rare", ItemType: string(models.ItemTypeEquipment)}, nil)
+	m.lootItemRepo.On("GetLootItemById", int64(200)).Return(&repositories.LootItemEntity{ID: 200, Name: "Aegis", Rarity: "epic", ItemType: string(models.ItemTypeEquipment)}, nil)
+}
+
+// expectReadBack sets up reading the trade back once it's been resolved
+func (m *tradeTestMocks) expectReadBack(status models.TradeStatus) {
+	resolved := pendingTrade()
+	resolved.Status = string(status)
+	resolvedAt := time.Now()
+	resolved.ResolvedAt = &resolvedAt
+	m.tradeRepo.On("GetTradeById", int64(5)).Return(resolved, nil)
+	m.tradeRepo.On("GetTradeItems", int64(5)).Return(testTradeItems, nil)
+	m.expectTradeLootItems()
+}
+
+// Tests for ProposeTrade
+func TestTradeService_ProposeTrade_Success(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	recipientEvents := mocks.eventHub.Subscribe(2, 0)
+
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2, DisplayName: "Brom"}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(10)).Return(&repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(20)).Return(&repositories.UserInventoryEntity{ID: 20, UserID: 2, LootItemID: 200}, nil)
+	mocks.tradeRepo.On("CountPendingTradesByProposer", int64(1)).Return(0, nil)
+	mocks.tradeRepo.On("CreateTrade", int64(1), int64(2), mock.AnythingOfType("time.Time")).Return(&repositories.TradeEntity{ID: 5}, nil)
+	mocks.inventoryRepo.On("EscrowItem", int64(10), int64(1), int64(5)).Return(true, nil)
+	// The offered sword comes off the proposer's team while it is held
+	weapon := "weapon"
+	mocks.teamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(&repositories.TeamEntity{ID: 7}, &weapon, nil)
+	mocks.teamRepo.On("UnequipItem", int64(7), "weapon").Return(nil)
+	mocks.tradeRepo.On("AddTradeItem", int64(5), int64(10), int64(1), int64(100)).Return(testTradeItems[0], nil)
+	mocks.tradeRepo.On("AddTradeItem", int64(5), int64(20), int64(2), int64(200)).Return(testTradeItems[1], nil)
+	mocks.tradeRepo.On("GetTradeById", int64(5)).Return(pendingTrade(), nil)
+	mocks.tradeRepo.On("GetTradeItems", int64(5)).Return(testTradeItems, nil)
+	mocks.expectTradeLootItems()
+
+	// Act
+	result, err := service.ProposeTrade(1, &models.ProposeTradeDTO{
+		RecipientID:           2,
+		OfferedInventoryIDs:   []int64{10},
+		RequestedInventoryIDs: []int64{20},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.IsProposer)
+	assert.Equal(t, "pending", result.Status)
+	assert.Equal(t, "Rift Blade", result.OfferedItems[0].Name)
+	assert.Equal(t, "Aegis", result.RequestedItems[0].Name)
+	// Only the proposer's side is held until the recipient answers
+	mocks.inventoryRepo.AssertNumberOfCalls(t, "EscrowItem", 1)
+	mocks.tradeRepo.AssertExpectations(t)
+	mocks.teamRepo.AssertExpectations(t)
+
+	event := receive(t, recipientEvents)
+	assert.Equal(t, models.GameEventTradeUpdated, event.Type)
+	assert.Equal(t, &models.TradeUpdatedEventDTO{TradeID: 5, Status: "pending", OtherUserID: 1, OtherUserName: "Aria"}, event.Data)
+}
+
+func TestTradeService_ProposeTrade_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		dto      *models.ProposeTradeDTO
+		expected error
+	}{
+		{"with self", &models.ProposeTradeDTO{RecipientID: 1, OfferedInventoryIDs: []int64{10}}, ErrCannotTradeWithSelf},
+		{"no items", &models.ProposeTradeDTO{RecipientID: 2}, ErrEmptyTrade},
+		{"same item twice", &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10, 10}}, ErrDuplicateTradeItem},
+		{"too many items", &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: make([]int64, TradeMaxItemsPerSide+1)}, ErrTooManyTradeItems},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, mocks := newTradeTestService()
+
+			result, err := service.ProposeTrade(1, test.dto)
+
+			assert.ErrorIs(t, err, test.expected)
+			assert.Nil(t, result)
+			mocks.tradeRepo.AssertNotCalled(t, "CreateTrade", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTradeService_ProposeTrade_RecipientNotFound(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2, IsArchived: true}, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeRecipientNotFound)
+}
+
+func TestTradeService_ProposeTrade_ConsumableNotTradeable(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(10)).Return(&repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 300}, nil)
+	mocks.lootItemRepo.On("GetLootItemById", int64(300)).Return(&repositories.LootItemEntity{ID: 300, ItemType: string(models.ItemTypeConsumable)}, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrItemNotTradeable)
+}
+
+func TestTradeService_ProposeTrade_OfferedItemInEscrow(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	otherTrade := int64(4)
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(10)).Return(&repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100, EscrowTradeID: &otherTrade}, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrItemInEscrow)
+}
+
+func TestTradeService_ProposeTrade_RequestedItemNotOwnedByRecipient(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(20)).Return(&repositories.UserInventoryEntity{ID: 20, UserID: 3, LootItemID: 200}, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, RequestedInventoryIDs: []int64{20}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeItemUnavailable)
+}
+
+func TestTradeService_ProposeTrade_TooManyPending(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(10)).Return(&repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}, nil)
+	mocks.expectTradeLootItems()
+	mocks.tradeRepo.On("CountPendingTradesByProposer", int64(1)).Return(TradeMaxPending, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTooManyPendingTrades)
+	mocks.inventoryRepo.AssertNotCalled(t, "EscrowItem", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTradeService_ProposeTrade_OfferedItemTakenMeanwhile(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.userRepo.On("GetUserById", 2).Return(&repositories.UserEntity{ID: 2}, nil)
+	mocks.inventoryRepo.On("GetInventoryById", int64(10)).Return(&repositories.UserInventoryEntity{ID: 10, UserID: 1, LootItemID: 100}, nil)
+	mocks.expectTradeLootItems()
+	mocks.tradeRepo.On("CountPendingTradesByProposer", int64(1)).Return(0, nil)
+	mocks.tradeRepo.On("CreateTrade", int64(1), int64(2), mock.AnythingOfType("time.Time")).Return(&repositories.TradeEntity{ID: 5}, nil)
+	// Escrowed by another request between the check and the transaction
+	mocks.inventoryRepo.On("EscrowItem", int64(10), int64(1), int64(5)).Return(false, nil)
+
+	// Act
+	_, err := service.ProposeTrade(1, &models.ProposeTradeDTO{RecipientID: 2, OfferedInventoryIDs: []int64{10}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeItemUnavailable)
+	mocks.tradeRepo.AssertNotCalled(t, "AddTradeItem", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Tests for AcceptTrade
+func TestTradeService_AcceptTrade_SwapsItems(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	proposerEvents := mocks.eventHub.Subscribe(1, 0)
+	weapon := "weapon"
+
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+	mocks.inventoryRepo.On("EscrowItem", int64(20), int64(2), int64(5)).Return(true, nil)
+	// The proposer's sword is still equipped on their team
+	mocks.teamRepo.On("GetTeamsByUserIdWithSlot", int64(1), int64(10)).Return(&repositories.TeamEntity{ID: 7}, &weapon, nil)
+	mocks.teamRepo.On("UnequipItem", int64(7), "weapon").Return(nil)
+	mocks.teamRepo.On("GetTeamsByUserIdWithSlot", int64(2), int64(20)).Return(nil, nil, nil)
+	mocks.inventoryRepo.On("TransferEscrowedItem", int64(10), int64(5), int64(2)).Return(true, nil)
+	mocks.inventoryRepo.On("TransferEscrowedItem", int64(20), int64(5), int64(1)).Return(true, nil)
+	mocks.tradeRepo.On("ResolveTrade", int64(5), "completed").Return(true, nil)
+	mocks.expectReadBack(models.TradeStatusCompleted)
+
+	// Act
+	result, err := service.AcceptTrade(2, 5)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", result.Status)
+	assert.False(t, result.IsProposer)
+	assert.NotNil(t, result.ResolvedAt)
+	mocks.teamRepo.AssertExpectations(t)
+	mocks.inventoryRepo.AssertExpectations(t)
+	mocks.tradeRepo.AssertExpectations(t)
+
+	event := receive(t, proposerEvents)
+	assert.Equal(t, &models.TradeUpdatedEventDTO{TradeID: 5, Status: "completed", OtherUserID: 2, OtherUserName: "Brom"}, event.Data)
+}
+
+func TestTradeService_AcceptTrade_ByProposer(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+
+	// Act
+	_, err := service.AcceptTrade(1, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradePermission)
+	mocks.tradeRepo.AssertNotCalled(t, "ResolveTrade", mock.Anything, mock.Anything)
+}
+
+func TestTradeService_AcceptTrade_NotParticipant(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+
+	// Act
+	_, err := service.AcceptTrade(3, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeNotFound)
+}
+
+func TestTradeService_AcceptTrade_AlreadyResolved(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	trade := pendingTrade()
+	trade.Status = string(models.TradeStatusCancelled)
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(trade, nil)
+
+	// Act
+	_, err := service.AcceptTrade(2, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeNotPending)
+}
+
+func TestTradeService_AcceptTrade_Expired(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	trade := pendingTrade()
+	trade.ExpiresAt = time.Now().Add(-time.Minute)
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(trade, nil)
+
+	// Act
+	_, err := service.AcceptTrade(2, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeExpired)
+	mocks.inventoryRepo.AssertNotCalled(t, "TransferEscrowedItem", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTradeService_AcceptTrade_RequestedItemGone(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+	mocks.tradeRepo.On("GetTradeItems", int64(5)).Return(testTradeItems, nil)
+	// The recipient spent or traded away the shield after the trade was proposed
+	mocks.inventoryRepo.On("EscrowItem", int64(20), int64(2), int64(5)).Return(false, nil)
+
+	// Act
+	_, err := service.AcceptTrade(2, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeItemUnavailable)
+	mocks.inventoryRepo.AssertNotCalled(t, "TransferEscrowedItem", mock.Anything, mock.Anything, mock.Anything)
+	mocks.tradeRepo.AssertNotCalled(t, "ResolveTrade", mock.Anything, mock.Anything)
+}
+
+// Tests for DeclineTrade and CancelTrade
+func TestTradeService_DeclineTrade_ReleasesEscrow(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	proposerEvents := mocks.eventHub.Subscribe(1, 0)
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+	mocks.inventoryRepo.On("ReleaseEscrow", int64(5)).Return(nil)
+	mocks.tradeRepo.On("ResolveTrade", int64(5), "declined").Return(true, nil)
+	mocks.expectReadBack(models.TradeStatusDeclined)
+
+	// Act
+	result, err := service.DeclineTrade(2, 5)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "declined", result.Status)
+	mocks.inventoryRepo.AssertExpectations(t)
+	assert.Equal(t, "declined", receive(t, proposerEvents).Data.(*models.TradeUpdatedEventDTO).Status)
+}
+
+func TestTradeService_CancelTrade_Success(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	recipientEvents := mocks.eventHub.Subscribe(2, 0)
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+	mocks.inventoryRepo.On("ReleaseEscrow", int64(5)).Return(nil)
+	mocks.tradeRepo.On("ResolveTrade", int64(5), "cancelled").Return(true, nil)
+	mocks.expectReadBack(models.TradeStatusCancelled)
+
+	// Act
+	result, err := service.CancelTrade(1, 5)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", result.Status)
+	assert.Equal(t, "cancelled", receive(t, recipientEvents).Data.(*models.TradeUpdatedEventDTO).Status)
+}
+
+func TestTradeService_CancelTrade_ByRecipient(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+
+	// Act
+	_, err := service.CancelTrade(2, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradePermission)
+	mocks.inventoryRepo.AssertNotCalled(t, "ReleaseEscrow", mock.Anything)
+}
+
+// Tests for ExpireTrades
+func TestTradeService_ExpireTrades_SkipsResolvedTrades(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	proposerEvents := mocks.eventHub.Subscribe(1, 0)
+	recipientEvents := mocks.eventHub.Subscribe(2, 0)
+
+	accepted := pendingTrade()
+	accepted.ID = 6
+	accepted.Status = string(models.TradeStatusCompleted)
+	mocks.tradeRepo.On("GetExpiredTradeIds", 50).Return([]int64{5, 6}, nil)
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(5)).Return(pendingTrade(), nil)
+	// Accepted while the processor was waiting for the lock
+	mocks.tradeRepo.On("GetTradeByIdForUpdate", int64(6)).Return(accepted, nil)
+	mocks.inventoryRepo.On("ReleaseEscrow", int64(5)).Return(nil)
+	mocks.tradeRepo.On("ResolveTrade", int64(5), "expired").Return(true, nil)
+
+	// Act
+	expired, err := service.ExpireTrades(50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mocks.inventoryRepo.AssertNotCalled(t, "ReleaseEscrow", int64(6))
+	mocks.tradeRepo.AssertNotCalled(t, "ResolveTrade", int64(6), mock.Anything)
+
+	// Both players hear about it
+	assert.Equal(t, int64(2), receive(t, proposerEvents).Data.(*models.TradeUpdatedEventDTO).OtherUserID)
+	assert.Equal(t, int64(1), receive(t, recipientEvents).Data.(*models.TradeUpdatedEventDTO).OtherUserID)
+	assertNoEvent(t, proposerEvents)
+}
+
+// Tests for GetTrade
+func TestTradeService_GetTrade_NotParticipant(t *testing.T) {
+	// Arrange
+	service, mocks := newTradeTestService()
+	mocks.tradeRepo.On("GetTradeById", int64(5)).Return(pendingTrade(), nil)
+
+	// Act
+	_, err := service.GetTrade(3, 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTradeNotFound)
+}
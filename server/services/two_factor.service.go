@@ -0,0 +1,327 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+const (
+	totpIssuer      = "Parallax"
+	totpPeriod      = 30 * time.Second
+	totpSecretBytes = 20
+	// totpSkewSteps lets through codes for the steps either side of now, for phones
+	// whose clocks have drifted
+	totpSkewSteps = 1
+	// qrCodeSize is the width and height of the enrollment QR code in pixels
+	qrCodeSize = 256
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+// Errors returned by TwoFactorService that callers can check with errors.Is
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling   = errors.New("two-factor enrollment has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("the two-factor code is invalid")
+)
+
+// totpEncoding is how TOTP secrets are shown to authenticator apps
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ITwoFactorService interface {
+	GetStatus(userId int) (*models.TwoFactorStatusDTO, error)
+	IsEnabled(userId int) (bool, error)
+	StartEnrollment(userId int, email string) (*models.TwoFactorEnrollmentDTO, error)
+	ConfirmEnrollment(userId int, code string) (*models.TwoFactorRecoveryCodesDTO, error)
+
+	// VerifyCode checks a code from the user's authenticator app, or one of their
+	// recovery codes. Either only works once.
+	VerifyCode(userId int, code string) error
+	Disable(userId int) error
+}
+
+// TwoFactorService manages TOTP two-factor authentication (RFC 6238). Users enroll by
+// adding a secret to their authenticator app and confirming with a code from it, which
+// also gets them a set of one-time recovery codes for when they lose their phone.
+type TwoFactorService struct {
+	twoFactorRepository repositories.IUserTwoFactorRepository
+	unitOfWork          database.IUnitOfWork
+	cryptoService       ICryptoService
+	now                 func() time.Time
+}
+
+func NewTwoFactorService(
+	twoFactorRepository repositories.IUserTwoFactorRepository,
+	unitOfWork database.IUnitOfWork,
+	cryptoService ICryptoService,
+) ITwoFactorService {
+	return newTwoFactorService(twoFactorRepository, unitOfWork, cryptoService, time.Now)
+}
+
+func newTwoFactorService(
+	twoFactorRepository repositories.IUserTwoFactorRepository,
+	unitOfWork database.IUnitOfWork,
+	cryptoService ICryptoService,
+	now func() time.Time,
+) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepository: twoFactorRepository,
+		unitOfWork:          unitOfWork,
+		cryptoService:       cryptoService,
+		now:                 now,
+	}
+}
+
+func (s *TwoFactorService) GetStatus(userId int) (*models.TwoFactorStatusDTO, error) {
+	enabled, err := s.IsEnabled(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return &models.TwoFactorStatusDTO{}, nil
+	}
+
+	remaining, err := s.twoFactorRepository.CountRecoveryCodes(int64(userId))
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorStatusDTO{
+		Enabled:                true,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+func (s *TwoFactorService) IsEnabled(userId int) (bool, error) {
+	twoFactor, err := s.twoFactorRepository.GetTwoFactor(int64(userId))
+	if err != nil {
+		return false, err
+	}
+	return twoFactor != nil && twoFactor.EnabledAt != nil, nil
+}
+
+// StartEnrollment creates a new secret for the user to add to their authenticator app.
+// Two-factor isn't on until they confirm it with a code.
+func (s *TwoFactorService) StartEnrollment(userId int, email string) (*models.TwoFactorEnrollmentDTO, error) {
+	secretBytes := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, err
+	}
+	secret := totpEncoding.EncodeToString(secretBytes)
+
+	encryptedSecret, err := s.cryptoService.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.twoFactorRepository.SaveTwoFactorSecret(int64(userId), encryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	uri := otpAuthURI(secret, email)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollmentDTO{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmEnrollment turns two-factor on once the user has shown their app generates
+// the right codes. The recovery codes it returns can't be seen again.
+func (s *TwoFactorService) ConfirmEnrollment(userId int, code string) (*models.TwoFactorRecoveryCodesDTO, error) {
+	twoFactor, err := s.twoFactorRepository.GetTwoFactor(int64(userId))
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+	if twoFactor.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, err := s.matchTOTP(twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, codeHashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.unitOfWork.WithinTransaction(func(tx *database.AppDataSource) error {
+		twoFactorRepository := s.twoFactorRepository.WithTx(tx)
+		enabled, err := twoFactorRepository.EnableTwoFactor(int64(userId), step)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return ErrTwoFactorAlreadyEnabled
+		}
+		return twoFactorRepository.ReplaceRecoveryCodes(int64(userId), codeHashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorRecoveryCodesDTO{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *TwoFactorService) VerifyCode(userId int, code string) error {
+	twoFactor, err := s.twoFactorRepository.GetTwoFactor(int64(userId))
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil || twoFactor.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeTwoFactorCode(code)
+	if !isTOTPCode(code) {
+		used, err := s.twoFactorRepository.UseRecoveryCode(int64(userId), s.cryptoService.HashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	step, err := s.matchTOTP(twoFactor, code)
+	if err != nil {
+		return err
+	}
+	// Each code only works once, even while it's still current
+	used, err := s.twoFactorRepository.UseTwoFactorStep(int64(userId), step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Disable turns two-factor off, or abandons enrollment. Callers must have
+// re-authenticated the user first.
+func (s *TwoFactorService) Disable(userId int) error {
+	return s.twoFactorRepository.DeleteTwoFactor(int64(userId))
+}
+
+// matchTOTP returns the time step the code is for, if it's for one close enough to now
+func (s *TwoFactorService) matchTOTP(twoFactor *repositories.UserTwoFactorEntity, code string) (int64, error) {
+	code = normalizeTwoFactorCode(code)
+	if !isTOTPCode(code) {
+		return 0, ErrInvalidTwoFactorCode
+	}
+
+	secret, err := s.cryptoService.DecryptSecret(twoFactor.Secret)
+	if err != nil {
+		return 0, err
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now().Unix() / int64(totpPeriod/time.Second)
+	for step := now - totpSkewSteps; step <= now+totpSkewSteps; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+	return 0, ErrInvalidTwoFactorCode
+}
+
+// generateRecoveryCodes returns new recovery codes to show the user, and the hashes to store
+func (s *TwoFactorService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(bytes))
+		codes[i] = code[:len(code)/2] + "-" + code[len(code)/2:]
+		hashes[i] = s.cryptoService.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// totpCode is the 6 digit code for a time step. TOTP is HOTP (RFC 4226) with the time
+// step as the counter.
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// otpAuthURI is the URI authenticator apps scan to add the account
+func otpAuthURI(secret string, email string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", "6")
+	query.Set("period", fmt.Sprint(int(totpPeriod/time.Second)))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + email,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// normalizeTwoFactorCode drops the spaces and dashes people type codes with, and
+// lowercases recovery codes
+func normalizeTwoFactorCode(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	return strings.ToLower(code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUserTwoFactorRepository is a mock implementation of IUserTwoFactorRepository
+type MockUserTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserTwoFactorRepository) GetTwoFactor(userId int64) (*repositories.UserTwoFactorEntity, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UserTwoFactorEntity), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) SaveTwoFactorSecret(userId int64, secret string) (bool, error) {
+	args := m.Called(userId, secret)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) EnableTwoFactor(userId int64, step int64) (bool, error) {
+	args := m.Called(userId, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) UseTwoFactorStep(userId int64, step int64) (bool, error) {
+	args := m.Called(userId, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) DeleteTwoFactor(userId int64) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockUserTwoFactorRepository) ReplaceRecoveryCodes(userId int64, codeHashes []string) error {
+	args := m.Called(userId, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockUserTwoFactorRepository) UseRecoveryCode(userId int64, codeHash string) (bool, error) {
+	args := m.Called(userId, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) CountRecoveryCodes(userId int64) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserTwoFactorRepository) WithTx(tx *database.AppDataSource) repositories.IUserTwoFactorRepository {
+	return m
+}
+
+const (
+	// testTOTPSecret is "12345678901234567890", the key from the RFC 6238 test vectors
+	testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	// testTOTPStep is the time step of testTOTPTime
+	testTOTPStep = 56666666
+)
+
+var testTOTPTime = time.Unix(testTOTPStep*30+10, 0)
+
+func newTwoFactorTestService() (*TwoFactorService, *MockUserTwoFactorRepository, ICryptoService) {
+	mockTwoFactorRepo := new(MockUserTwoFactorRepository)
+	cryptoService := NewCryptoService("testPepper")
+	service := newTwoFactorService(mockTwoFactorRepo, new(MockUnitOfWork), cryptoService, func() time.Time {
+		return testTOTPTime
+	})
+	return service, mockTwoFactorRepo, cryptoService
+}
+
+// testTwoFactor is the stored row for testTOTPSecret, enabled unless enabledAt is nil
+func testTwoFactor(t *testing.T, cryptoService ICryptoService, enabledAt *time.Time) *repositories.UserTwoFactorEntity {
+	t.Helper()
+	encryptedSecret, err := cryptoService.EncryptSecret(testTOTPSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &repositories.UserTwoFactorEntity{UserID: 1, Secret: encryptedSecret, EnabledAt: enabledAt}
+}
+
+func testTOTPCode(t *testing.T, step int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(testTOTPSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(key, step)
+}
+
+func TestTOTPCode_RFC6238TestVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	// The RFC's 8 digit SHA-1 codes, cut down to the last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unixTime, want := range vectors {
+		assert.Equal(t, want, totpCode(key, unixTime/30), unixTime)
+	}
+}
+
+func TestTwoFactorService_StartEnrollment(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	var savedSecret string
+	mockTwoFactorRepo.On("SaveTwoFactorSecret", int64(1), mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		savedSecret = args.String(1)
+	}).Return(true, nil)
+
+	// Act
+	enrollment, err := service.StartEnrollment(1, "player@example.com")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, enrollment.Secret, 32)
+	assert.True(t, strings.HasPrefix(enrollment.OTPAuthURI, "otpauth://totp/Parallax:player@example.com?"), enrollment.OTPAuthURI)
+	assert.Contains(t, enrollment.OTPAuthURI, "secret="+enrollment.Secret)
+	assert.Contains(t, enrollment.OTPAuthURI, "issuer=Parallax")
+	assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+	// The secret is stored encrypted
+	assert.NotEqual(t, enrollment.Secret, savedSecret)
+	decrypted, err := cryptoService.DecryptSecret(savedSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, decrypted)
+}
+
+func TestTwoFactorService_StartEnrollment_AlreadyEnabled(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, _ := newTwoFactorTestService()
+	mockTwoFactorRepo.On("SaveTwoFactorSecret", int64(1), mock.AnythingOfType("string")).Return(false, nil)
+
+	// Act
+	enrollment, err := service.StartEnrollment(1, "player@example.com")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTwoFactorAlreadyEnabled)
+	assert.Nil(t, enrollment)
+}
+
+func TestTwoFactorService_ConfirmEnrollment(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	var codeHashes []string
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, nil), nil)
+	mockTwoFactorRepo.On("EnableTwoFactor", int64(1), int64(testTOTPStep)).Return(true, nil)
+	mockTwoFactorRepo.On("ReplaceRecoveryCodes", int64(1), mock.Anything).Run(func(args mock.Arguments) {
+		codeHashes = args.Get(1).([]string)
+	}).Return(nil)
+
+	// Act
+	result, err := service.ConfirmEnrollment(1, testTOTPCode(t, testTOTPStep))
+
+	// Assert
+	assert.NoError(t, err)
+	mockTwoFactorRepo.AssertExpectations(t)
+	assert.Len(t, result.RecoveryCodes, recoveryCodeCount)
+	assert.Len(t, codeHashes, recoveryCodeCount)
+	for i, code := range result.RecoveryCodes {
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		assert.Equal(t, cryptoService.HashRecoveryCode(strings.ReplaceAll(code, "-", "")), codeHashes[i])
+	}
+}
+
+func TestTwoFactorService_ConfirmEnrollment_InvalidCode(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, nil), nil)
+
+	// Act
+	result, err := service.ConfirmEnrollment(1, testTOTPCode(t, testTOTPStep-2))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	assert.Nil(t, result)
+	mockTwoFactorRepo.AssertNotCalled(t, "EnableTwoFactor", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_ConfirmEnrollment_NotEnrolling(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, _ := newTwoFactorTestService()
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(nil, nil)
+
+	// Act
+	result, err := service.ConfirmEnrollment(1, "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTwoFactorNotEnrolling)
+	assert.Nil(t, result)
+}
+
+func TestTwoFactorService_VerifyCode_AllowsClockDrift(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	enabledAt := testTOTPTime.Add(-time.Hour)
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, &enabledAt), nil)
+	mockTwoFactorRepo.On("UseTwoFactorStep", int64(1), int64(testTOTPStep-1)).Return(true, nil)
+
+	// Act
+	err := service.VerifyCode(1, testTOTPCode(t, testTOTPStep-1))
+
+	// Assert
+	assert.NoError(t, err)
+	mockTwoFactorRepo.AssertExpectations(t)
+}
+
+func TestTwoFactorService_VerifyCode_RejectsReusedCode(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	enabledAt := testTOTPTime.Add(-time.Hour)
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, &enabledAt), nil)
+	mockTwoFactorRepo.On("UseTwoFactorStep", int64(1), int64(testTOTPStep)).Return(false, nil)
+
+	// Act
+	err := service.VerifyCode(1, testTOTPCode(t, testTOTPStep))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+}
+
+func TestTwoFactorService_VerifyCode_RecoveryCode(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	enabledAt := testTOTPTime.Add(-time.Hour)
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, &enabledAt), nil)
+	mockTwoFactorRepo.On("UseRecoveryCode", int64(1), cryptoService.HashRecoveryCode("abcdefgh")).Return(true, nil)
+
+	// Act
+	err := service.VerifyCode(1, " ABCD-EFGH ")
+
+	// Assert
+	assert.NoError(t, err)
+	mockTwoFactorRepo.AssertExpectations(t)
+	mockTwoFactorRepo.AssertNotCalled(t, "UseTwoFactorStep", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_VerifyCode_UnknownRecoveryCode(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	enabledAt := testTOTPTime.Add(-time.Hour)
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, &enabledAt), nil)
+	mockTwoFactorRepo.On("UseRecoveryCode", int64(1), mock.AnythingOfType("string")).Return(false, nil)
+
+	// Act
+	err := service.VerifyCode(1, "abcd-efgh")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+}
+
+func TestTwoFactorService_VerifyCode_NotEnabled(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, nil), nil)
+
+	// Act
+	err := service.VerifyCode(1, testTOTPCode(t, testTOTPStep))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTwoFactorNotEnabled)
+}
+
+func TestTwoFactorService_GetStatus(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, cryptoService := newTwoFactorTestService()
+	enabledAt := testTOTPTime.Add(-time.Hour)
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(testTwoFactor(t, cryptoService, &enabledAt), nil)
+	mockTwoFactorRepo.On("CountRecoveryCodes", int64(1)).Return(7, nil)
+
+	// Act
+	status, err := service.GetStatus(1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 7, status.RecoveryCodesRemaining)
+}
+
+func TestTwoFactorService_GetStatus_DatabaseError(t *testing.T) {
+	// Arrange
+	service, mockTwoFactorRepo, _ := newTwoFactorTestService()
+	mockTwoFactorRepo.On("GetTwoFactor", int64(1)).Return(nil, errors.New("database error"))
+
+	// Act
+	status, err := service.GetStatus(1)
+
+	// Assert
+	assert.EqualError(t, err, "database error")
+	assert.Nil(t, status)
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+type IUnlockRuleService interface {
+	GetUnlockStatus(userId int64, targetType models.UnlockTargetType, targetKey int64) (*models.UnlockStatusDTO, error)
+	GetUnlockStatuses(userId int64, targetType models.UnlockTargetType, targetKeys []int64) (map[int64]*models.UnlockStatusDTO, error)
+}
+
+// UnlockRuleService evaluates the unlock_rules condition trees against a player's progress
+// and generates the requirement and progress text shown in the UI
+type UnlockRuleService struct {
+	unlockRuleRepository repositories.IUnlockRuleRepository
+	expeditionRepository repositories.IExpeditionRepository
+	inventoryRepository  repositories.IUserInventoryRepository
+	riftRepository       repositories.IRiftRepository
+}
+
+func NewUnlockRuleService(
+	unlockRuleRepository repositories.IUnlockRuleRepository,
+	expeditionRepository repositories.IExpeditionRepository,
+	inventoryRepository repositories.IUserInventoryRepository,
+	riftRepository repositories.IRiftRepository,
+) IUnlockRuleService {
+	return &UnlockRuleService{
+		unlockRuleRepository: unlockRuleRepository,
+		expeditionRepository: expeditionRepository,
+		inventoryRepository:  inventoryRepository,
+		riftRepository:       riftRepository,
+	}
+}
+
+// GetUnlockStatus evaluates the rule for a single rift or team
+// Targets without a rule are always unlocked
+func (s *UnlockRuleService) GetUnlockStatus(userId int64, targetType models.UnlockTargetType, targetKey int64) (*models.UnlockStatusDTO, error) {
+	rule, err := s.unlockRuleRepository.GetRuleByTarget(string(targetType), targetKey)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return &models.UnlockStatusDTO{IsUnlocked: true}, nil
+	}
+
+	return s.newEvaluator(userId).evaluateRule(rule)
+}
+
+// GetUnlockStatuses evaluates the rules for several targets of the same type at once.
+// Player progress is only loaded once and shared between the rules.
+func (s *UnlockRuleService) GetUnlockStatuses(userId int64, targetType models.UnlockTargetType, targetKeys []int64) (map[int64]*models.UnlockStatusDTO, error) {
+	rules, err := s.unlockRuleRepository.GetRulesByTargetType(string(targetType))
+	if err != nil {
+		return nil, err
+	}
+
+	ruleByTarget := make(map[int64]*repositories.UnlockRuleEntity)
+	for _, rule := range rules {
+		ruleByTarget[rule.TargetKey] = rule
+	}
+
+	evaluator := s.newEvaluator(userId)
+	statuses := make(map[int64]*models.UnlockStatusDTO, len(targetKeys))
+	for _, targetKey := range targetKeys {
+		rule, exists := ruleByTarget[targetKey]
+		if !exists {
+			statuses[targetKey] = &models.UnlockStatusDTO{IsUnlocked: true}
+			continue
+		}
+
+		status, err := evaluator.evaluateRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		statuses[targetKey] = status
+	}
+
+	return statuses, nil
+}
+
+func (s *UnlockRuleService) newEvaluator(userId int64) *unlockEvaluator {
+	return &unlockEvaluator{
+		service:        s,
+		userId:         userId,
+		riftCounts:     make(map[int64]int),
+		riftNames:      make(map[int64]string),
+		rarityCounts:   make(map[string]int),
+		ownedItemNames: make(map[string]bool),
+	}
+}
+
+// unlockEvaluator evaluates rules for one player and caches the progress it looks up,
+// so evaluating every rift only counts the player's expeditions once
+type unlockEvaluator struct {
+	service        *UnlockRuleService
+	userId         int64
+	completedCount *int
+	riftCounts     map[int64]int
+	riftNames      map[int64]string
+	rarityCounts   map[string]int
+	ownedItemNames map[string]bool
+}
+
+// conditionResult is the outcome of evaluating one node of a rule tree
+type conditionResult struct {
+	met       bool
+	text      string
+	progress  string
+	composite bool
+}
+
+func (e *unlockEvaluator) evaluateRule(rule *repositories.UnlockRuleEntity) (*models.UnlockStatusDTO, error) {
+	condition := &models.UnlockCondition{}
+	if err := json.Unmarshal(rule.Rule, condition); err != nil {
+		return nil, fmt.Errorf("invalid unlock rule %d: %w", rule.ID, err)
+	}
+
+	result, err := e.evaluate(condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unlock rule %d: %w", rule.ID, err)
+	}
+
+	requirementText := capitalize(result.text)
+	progressText := result.progress
+	return &models.UnlockStatusDTO{
+		IsUnlocked:      result.met,
+		RequirementText: &requirementText,
+		ProgressText:    &progressText,
+	}, nil
+}
+
+func (e *unlockEvaluator) evaluate(condition *models.UnlockCondition) (*conditionResult, error) {
+	switch condition.Type {
+	case models.UnlockConditionAll, models.UnlockConditionAny:
+		return e.evaluateComposite(condition)
+
+	case models.UnlockConditionCompletedExpeditions:
+		completed, err := e.getCompletedCount()
+		if err != nil {
+			return nil, err
+		}
+		need := atLeastOne(condition.Count)
+		return &conditionResult{
+			met:      completed >= need,
+			text:     fmt.Sprintf("complete %d %s", need, pluralize(need, "expedition", "expeditions")),
+			progress: fmt.Sprintf("%d/%d expeditions", min(completed, need), need),
+		}, nil
+
+	case models.UnlockConditionCompletedRift:
+		completed, err := e.getRiftCount(condition.RiftID)
+		if err != nil {
+			return nil, err
+		}
+		riftName, err := e.getRiftName(condition.RiftID)
+		if err != nil {
+			return nil, err
+		}
+		need := atLeastOne(condition.Count)
+		text := fmt.Sprintf("complete %s", riftName)
+		if need > 1 {
+			text = fmt.Sprintf("complete %s %d times", riftName, need)
+		}
+		return &conditionResult{
+			met:      completed >= need,
+			text:     text,
+			progress: fmt.Sprintf("%d/%d %s", min(completed, need), need, riftName),
+		}, nil
+
+	case models.UnlockConditionOwnsRarity:
+		if condition.Rarity == "" {
+			return nil, fmt.Errorf("owns_rarity condition is missing a rarity")
+		}
+		owned, err := e.getRarityCount(condition.Rarity)
+		if err != nil {
+			return nil, err
+		}
+		need := atLeastOne(condition.Count)
+		return &conditionResult{
+			met:      owned >= need,
+			text:     fmt.Sprintf("own %d %s %s", need, condition.Rarity, pluralize(need, "item", "items")),
+			progress: fmt.Sprintf("%d/%d %s items", min(owned, need), need, condition.Rarity),
+		}, nil
+
+	case models.UnlockConditionOwnsItem:
+		if condition.ItemName == "" {
+			return nil, fmt.Errorf("owns_item condition is missing an item name")
+		}
+		owned, err := e.ownsItem(condition.ItemName)
+		if err != nil {
+			return nil, err
+		}
+		have := 0
+		if owned {
+			have = 1
+		}
+		return &conditionResult{
+			met:      owned,
+			text:     fmt.Sprintf("own %s", condition.ItemName),
+			progress: fmt.Sprintf("%d/1 %s", have, condition.ItemName),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown unlock condition type %q", condition.Type)
+	}
+}
+
+func (e *unlockEvaluator) evaluateComposite(condition *models.UnlockCondition) (*conditionResult, error) {
+	if len(condition.Conditions) == 0 {
+		return nil, fmt.Errorf("%s condition has no child conditions", condition.Type)
+	}
+
+	isAll := condition.Type == models.UnlockConditionAll
+	met := isAll
+	texts := make([]string, 0, len(condition.Conditions))
+	progresses := make([]string, 0, len(condition.Conditions))
+	for _, child := range condition.Conditions {
+		result, err := e.evaluate(child)
+		if err != nil {
+			return nil, err
+		}
+
+		if isAll {
+			met = met && result.met
+		} else {
+			met = met || result.met
+		}
+
+		// Parenthesize nested groups so "a and (b or c)" reads correctly
+		if result.composite {
+			texts = append(texts, "("+result.text+")")
+			progresses = append(progresses, "("+result.progress+")")
+		} else {
+			texts = append(texts, result.text)
+			progresses = append(progresses, result.progress)
+		}
+	}
+
+	separator := " or "
+	if isAll {
+		separator = " and "
+	}
+
+	return &conditionResult{
+		met:       met,
+		text:      strings.Join(texts, separator),
+		progress:  strings.Join(progresses, ", "),
+		composite: true,
+	}, nil
+}
+
+func (e *unlockEvaluator) getCompletedCount() (int, error) {
+	if e.completedCount == nil {
+		count, err := e.service.expeditionRepository.GetCompletedExpeditionsCount(e.userId)
+		if err != nil {
+			return 0, err
+		}
+		e.completedCount = &count
+	}
+	return *e.completedCount, nil
+}
+
+func (e *unlockEvaluator) getRiftCount(riftId int64) (int, error) {
+	if count, exists := e.riftCounts[riftId]; exists {
+		return count, nil
+	}
+	count, err := e.service.expeditionRepository.GetCompletedExpeditionsCountByRift(e.userId, riftId)
+	if err != nil {
+		return 0, err
+	}
+	e.riftCounts[riftId] = count
+	return count, nil
+}
+
+func (e *unlockEvaluator) getRiftName(riftId int64) (string, error) {
+	if name, exists := e.riftNames[riftId]; exists {
+		return name, nil
+	}
+	rift, err := e.service.riftRepository.GetRiftById(riftId)
+	if err != nil {
+		return "", err
+	}
+	e.riftNames[riftId] = rift.Name
+	return rift.Name, nil
+}
+
+func (e *unlockEvaluator) getRarityCount(rarity string) (int, error) {
+	if count, exists := e.rarityCounts[rarity]; exists {
+		return count, nil
+	}
+	count, err := e.service.inventoryRepository.CountItemsByRarity(e.userId, rarity)
+	if err != nil {
+		return 0, err
+	}
+	e.rarityCounts[rarity] = count
+	return count, nil
+}
+
+func (e *unlockEvaluator) ownsItem(itemName string) (bool, error) {
+	if owned, exists := e.ownedItemNames[itemName]; exists {
+		return owned, nil
+	}
+	owned, err := e.service.inventoryRepository.HasItemByName(e.userId, itemName)
+	if err != nil {
+		return false, err
+	}
+	e.ownedItemNames[itemName] = owned
+	return owned, nil
+}
+
+func atLeastOne(count int) int {
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUnlockRuleRepository for UnlockRuleService tests
+type MockUnlockRuleRepository struct {
+	mock.Mock
+}
+
+func (m *MockUnlockRuleRepository) GetRulesByTargetType(targetType string) ([]*repositories.UnlockRuleEntity, error) {
+	args := m.Called(targetType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repositories.UnlockRuleEntity), args.Error(1)
+}
+
+func (m *MockUnlockRuleRepository) GetRuleByTarget(targetType string, targetKey int64) (*repositories.UnlockRuleEntity, error) {
+	args := m.Called(targetType, targetKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.UnlockRuleEntity), args.Error(1)
+}
+
+func newUnlockRuleTestService() (IUnlockRuleService, *MockUnlockRuleRepository, *MockExpeditionRepository, *MockUserInventoryRepository, *MockRiftRepository) {
+	mockUnlockRuleRepo := new(MockUnlockRuleRepository)
+	mockExpeditionRepo := new(MockExpeditionRepository)
+	mockInventoryRepo := new(MockUserInventoryRepository)
+	mockRiftRepo := new(MockRiftRepository)
+	service := NewUnlockRuleService(mockUnlockRuleRepo, mockExpeditionRepo, mockInventoryRepo, mockRiftRepo)
+	return service, mockUnlockRuleRepo, mockExpeditionRepo, mockInventoryRepo, mockRiftRepo
+}
+
+func riftRule(targetKey int64, rule string) *repositories.UnlockRuleEntity {
+	return &repositories.UnlockRuleEntity{ID: targetKey, TargetType: "rift", TargetKey: targetKey, Rule: []byte(rule)}
+}
+
+// Test GetUnlockStatus - No Rule
+func TestUnlockRuleService_GetUnlockStatus_NoRule(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, _, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(1)).Return(nil, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.IsUnlocked)
+	assert.Nil(t, result.RequirementText)
+	assert.Nil(t, result.ProgressText)
+}
+
+// Test GetUnlockStatus - Completed Expeditions Not Met
+func TestUnlockRuleService_GetUnlockStatus_CompletedExpeditionsNotMet(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(5)).Return(riftRule(5, `{"type": "completed_expeditions", "count": 10}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(3, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 5)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.IsUnlocked)
+	assert.Equal(t, "Complete 10 expeditions", *result.RequirementText)
+	assert.Equal(t, "3/10 expeditions", *result.ProgressText)
+}
+
+// Test GetUnlockStatus - Completed Expeditions Met
+func TestUnlockRuleService_GetUnlockStatus_CompletedExpeditionsMet(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "team", int64(2)).Return(&repositories.UnlockRuleEntity{ID: 1, TargetType: "team", TargetKey: 2, Rule: []byte(`{"type": "completed_expeditions", "count": 1}`)}, nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(4, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetTeam, 2)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.IsUnlocked)
+	assert.Equal(t, "Complete 1 expedition", *result.RequirementText)
+	assert.Equal(t, "1/1 expeditions", *result.ProgressText) // Progress is capped at the target
+}
+
+// Test GetUnlockStatus - Completed Rift
+func TestUnlockRuleService_GetUnlockStatus_CompletedRift(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, _, mockRiftRepo := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(2)).Return(riftRule(2, `{"type": "completed_rift", "rift_id": 1, "count": 1}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCountByRift", int64(1), int64(1)).Return(0, nil)
+	mockRiftRepo.On("GetRiftById", int64(1)).Return(&repositories.RiftEntity{ID: 1, Name: "Tutorial Rift"}, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 2)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.IsUnlocked)
+	assert.Equal(t, "Complete Tutorial Rift", *result.RequirementText)
+	assert.Equal(t, "0/1 Tutorial Rift", *result.ProgressText)
+}
+
+// Test GetUnlockStatus - All Conditions
+func TestUnlockRuleService_GetUnlockStatus_AllConditions(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, mockInventoryRepo, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(6)).Return(riftRule(6, `{"type": "all", "conditions": [
+		{"type": "completed_expeditions", "count": 25},
+		{"type": "owns_rarity", "rarity": "epic", "count": 1}
+	]}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(30, nil)
+	mockInventoryRepo.On("CountItemsByRarity", int64(1), "epic").Return(0, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 6)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.IsUnlocked) // Expeditions met, epic item missing
+	assert.Equal(t, "Complete 25 expeditions and own 1 epic item", *result.RequirementText)
+	assert.Equal(t, "25/25 expeditions, 0/1 epic items", *result.ProgressText)
+}
+
+// Test GetUnlockStatus - Any Condition
+func TestUnlockRuleService_GetUnlockStatus_AnyCondition(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, mockInventoryRepo, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(3)).Return(riftRule(3, `{"type": "any", "conditions": [
+		{"type": "completed_expeditions", "count": 50},
+		{"type": "owns_item", "item_name": "Explorers Compass"}
+	]}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(2, nil)
+	mockInventoryRepo.On("HasItemByName", int64(1), "Explorers Compass").Return(true, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 3)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.IsUnlocked)
+	assert.Equal(t, "Complete 50 expeditions or own Explorers Compass", *result.RequirementText)
+	assert.Equal(t, "2/50 expeditions, 1/1 Explorers Compass", *result.ProgressText)
+}
+
+// Test GetUnlockStatus - Nested Conditions
+func TestUnlockRuleService_GetUnlockStatus_NestedConditions(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, mockInventoryRepo, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(7)).Return(riftRule(7, `{"type": "all", "conditions": [
+		{"type": "completed_expeditions", "count": 10},
+		{"type": "any", "conditions": [
+			{"type": "owns_rarity", "rarity": "rare", "count": 3},
+			{"type": "owns_rarity", "rarity": "epic", "count": 1}
+		]}
+	]}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(10, nil)
+	mockInventoryRepo.On("CountItemsByRarity", int64(1), "rare").Return(3, nil)
+	mockInventoryRepo.On("CountItemsByRarity", int64(1), "epic").Return(0, nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 7)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.IsUnlocked)
+	assert.Equal(t, "Complete 10 expeditions and (own 3 rare items or own 1 epic item)", *result.RequirementText)
+	assert.Equal(t, "10/10 expeditions, (3/3 rare items, 0/1 epic items)", *result.ProgressText)
+}
+
+// Test GetUnlockStatus - Invalid Rule JSON
+func TestUnlockRuleService_GetUnlockStatus_InvalidRule(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, _, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(2)).Return(riftRule(2, `{"type": `), nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 2)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+// Test GetUnlockStatus - Unknown Condition Type
+func TestUnlockRuleService_GetUnlockStatus_UnknownConditionType(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, _, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(2)).Return(riftRule(2, `{"type": "owns_castle"}`), nil)
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 2)
+
+	// Assert
+	assert.ErrorContains(t, err, "unknown unlock condition type")
+	assert.Nil(t, result)
+}
+
+// Test GetUnlockStatus - Repository Error
+func TestUnlockRuleService_GetUnlockStatus_ProgressError(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRuleByTarget", "rift", int64(5)).Return(riftRule(5, `{"type": "completed_expeditions", "count": 10}`), nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(0, errors.New("database error"))
+
+	// Act
+	result, err := service.GetUnlockStatus(1, models.UnlockTargetRift, 5)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+// Test GetUnlockStatuses - Shares Progress Between Rules
+func TestUnlockRuleService_GetUnlockStatuses_SharesProgress(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, mockExpeditionRepo, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRulesByTargetType", "rift").Return([]*repositories.UnlockRuleEntity{
+		riftRule(5, `{"type": "completed_expeditions", "count": 10}`),
+		riftRule(6, `{"type": "completed_expeditions", "count": 25}`),
+	}, nil)
+	mockExpeditionRepo.On("GetCompletedExpeditionsCount", int64(1)).Return(12, nil)
+
+	// Act
+	result, err := service.GetUnlockStatuses(1, models.UnlockTargetRift, []int64{1, 5, 6})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.True(t, result[1].IsUnlocked) // No rule
+	assert.True(t, result[5].IsUnlocked)
+	assert.False(t, result[6].IsUnlocked)
+	assert.Equal(t, "12/25 expeditions", *result[6].ProgressText)
+	mockExpeditionRepo.AssertNumberOfCalls(t, "GetCompletedExpeditionsCount", 1)
+}
+
+// Test GetUnlockStatuses - Repository Error
+func TestUnlockRuleService_GetUnlockStatuses_RepositoryError(t *testing.T) {
+	// Arrange
+	service, mockUnlockRuleRepo, _, _, _ := newUnlockRuleTestService()
+	mockUnlockRuleRepo.On("GetRulesByTargetType", "team").Return(nil, errors.New("database error"))
+
+	// Act
+	result, err := service.GetUnlockStatuses(1, models.UnlockTargetTeam, []int64{2, 3})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,169 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+)
+
+// rarities are reported in this order, including ones the rift never drops
+var rarities = []string{
+	string(models.ItemRarityCommon),
+	string(models.ItemRarityUncommon),
+	string(models.ItemRarityRare),
+	string(models.ItemRarityEpic),
+	string(models.ItemRarityLegendary),
+}
+
+// Report summarizes the loot from a simulation. Runs are treated as back to back
+// expeditions, so hours are the total time the team spent in the rift.
+type Report struct {
+	RiftName         string  `json:"rift_name"`
+	Runs             int     `json:"runs"`
+	DurationMinutes  int     `json:"duration_minutes"`
+	EffectivePower   int     `json:"effective_power"`
+	RecommendedPower int     `json:"recommended_power"`
+	SimulatedHours   float64 `json:"simulated_hours"`
+	PartialFailures  int     `json:"partial_failures"`
+	TotalItems       int     `json:"total_items"`
+	ItemsPerHour     float64 `json:"items_per_hour"`
+	// PowerScorePerHour is how fast the loot raises the power score leaderboard
+	PowerScorePerHour float64 `json:"power_score_per_hour"`
+	// HoursToFirstLegendary is when the first legendary came back in this simulation,
+	// nil if none did
+	HoursToFirstLegendary *float64 `json:"hours_to_first_legendary"`
+	// ExpectedHoursToLegendary is the average wait for a legendary given how often runs
+	// brought one back, nil if none did
+	ExpectedHoursToLegendary *float64        `json:"expected_hours_to_legendary"`
+	Rarities                 []*RarityReport `json:"rarities"`
+
+	simulatedMinutes int
+	powerScore       int64
+}
+
+// RarityReport is the loot of one rarity. Histogram[n] is the number of runs that
+// brought back exactly n items of the rarity.
+type RarityReport struct {
+	Rarity       string  `json:"rarity"`
+	Total        int     `json:"total"`
+	PerRun       float64 `json:"per_run"`
+	PerHour      float64 `json:"per_hour"`
+	RunsWithDrop int     `json:"runs_with_drop"`
+	Histogram    []int   `json:"histogram"`
+}
+
+func newReport(riftName string, runs int) *Report {
+	report := &Report{
+		RiftName: riftName,
+		Runs:     runs,
+	}
+	for _, rarity := range rarities {
+		report.Rarities = append(report.Rarities, &RarityReport{
+			Rarity:    rarity,
+			Histogram: []int{},
+		})
+	}
+	return report
+}
+
+func (r *Report) addRun(result *runResult) {
+	r.DurationMinutes = result.durationMinutes
+	r.EffectivePower = result.effectivePower
+	r.RecommendedPower = result.recommendedPower
+	r.simulatedMinutes += result.durationMinutes
+	r.SimulatedHours = float64(r.simulatedMinutes) / 60
+	if result.partialFailure {
+		r.PartialFailures++
+	}
+
+	for _, rarityReport := range r.Rarities {
+		count := result.rarityCounts[rarityReport.Rarity]
+		for len(rarityReport.Histogram) <= count {
+			rarityReport.Histogram = append(rarityReport.Histogram, 0)
+		}
+		rarityReport.Histogram[count]++
+		rarityReport.Total += count
+		if count > 0 {
+			rarityReport.RunsWithDrop++
+		}
+		r.TotalItems += count
+		r.powerScore += int64(count) * repositories.PowerScoreRarityWeights[rarityReport.Rarity]
+	}
+
+	if r.HoursToFirstLegendary == nil && result.rarityCounts[string(models.ItemRarityLegendary)] > 0 {
+		hours := r.SimulatedHours
+		r.HoursToFirstLegendary = &hours
+	}
+}
+
+// finish turns the totals collected by addRun into rates
+func (r *Report) finish() {
+	if r.SimulatedHours == 0 {
+		return
+	}
+	r.ItemsPerHour = float64(r.TotalItems) / r.SimulatedHours
+	r.PowerScorePerHour = float64(r.powerScore) / r.SimulatedHours
+	for _, rarityReport := range r.Rarities {
+		rarityReport.PerRun = float64(rarityReport.Total) / float64(r.Runs)
+		rarityReport.PerHour = float64(rarityReport.Total) / r.SimulatedHours
+		if rarityReport.Rarity == string(models.ItemRarityLegendary) && rarityReport.RunsWithDrop > 0 {
+			// Runs until the first legendary are geometric, so the mean wait is 1/p runs
+			expected := r.SimulatedHours / float64(rarityReport.RunsWithDrop)
+			r.ExpectedHoursToLegendary = &expected
+		}
+	}
+}
+
+// WriteTable writes the report as plain text tables
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Rift\t%s\n", r.RiftName)
+	fmt.Fprintf(tw, "Runs\t%d\n", r.Runs)
+	fmt.Fprintf(tw, "Duration\t%d min\n", r.DurationMinutes)
+	fmt.Fprintf(tw, "Power\t%d / %d recommended\n", r.EffectivePower, r.RecommendedPower)
+	fmt.Fprintf(tw, "Simulated time\t%.1f h\n", r.SimulatedHours)
+	fmt.Fprintf(tw, "Partial failures\t%d (%.1f%%)\n", r.PartialFailures, 100*float64(r.PartialFailures)/float64(r.Runs))
+	fmt.Fprintf(tw, "Items per hour\t%.2f\n", r.ItemsPerHour)
+	fmt.Fprintf(tw, "Power score per hour\t%.1f\n", r.PowerScorePerHour)
+	fmt.Fprintf(tw, "First legendary\t%s\n", formatHours(r.HoursToFirstLegendary))
+	fmt.Fprintf(tw, "Expected time to legendary\t%s\n", formatHours(r.ExpectedHoursToLegendary))
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Rarity\tTotal\tPer run\tPer hour\tRuns with drop\tHistogram (runs by items per run)")
+	for _, rarityReport := range r.Rarities {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%d\t%s\n",
+			rarityReport.Rarity,
+			rarityReport.Total,
+			rarityReport.PerRun,
+			rarityReport.PerHour,
+			rarityReport.RunsWithDrop,
+			formatHistogram(rarityReport.Histogram),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func formatHours(hours *float64) string {
+	if hours == nil {
+		return "never"
+	}
+	return fmt.Sprintf("%.1f h", *hours)
+}
+
+// formatHistogram writes each bucket as items:runs, e.g. "0:812 1:150 2:38"
+func formatHistogram(histogram []int) string {
+	buckets := make([]string, 0, len(histogram))
+	for count, runs := range histogram {
+		if runs > 0 {
+			buckets = append(buckets, strconv.Itoa(count)+":"+strconv.Itoa(runs))
+		}
+	}
+	return strings.Join(buckets, " ")
+}
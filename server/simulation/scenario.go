@@ -0,0 +1,79 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+)
+
+// Scenario describes what to simulate: a rift and its drop tables, the loot items that
+// can drop or be equipped, and the team that is sent on every run.
+// Rows use the same JSON fields as the database entities; IDs are assigned by the simulator.
+type Scenario struct {
+	Rift       *repositories.RiftEntity            `json:"rift"`
+	DropTables []*repositories.LootDropTableEntity `json:"drop_tables"`
+	LootItems  []*repositories.LootItemEntity      `json:"loot_items"`
+	Team       ScenarioTeam                        `json:"team"`
+}
+
+// ScenarioTeam is the team's upgrades and gear. Equipment maps an equipment slot to the
+// name of one of the scenario's loot items.
+type ScenarioTeam struct {
+	TeamNumber     int               `json:"team_number"`
+	SpeedBonus     float64           `json:"speed_bonus"`
+	LuckBonus      float64           `json:"luck_bonus"`
+	PowerBonus     int               `json:"power_bonus"`
+	Specialization string            `json:"specialization"`
+	Equipment      map[string]string `json:"equipment"`
+}
+
+// LoadScenario reads and validates a scenario JSON file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scenario := &Scenario{}
+	if err := json.Unmarshal(data, scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// Validate checks the parts of a scenario the database would otherwise enforce
+func (s *Scenario) Validate() error {
+	if s.Rift == nil {
+		return errors.New("rift is required")
+	}
+	if s.Rift.DurationMinutes <= 0 {
+		return errors.New("rift duration_minutes must be positive")
+	}
+	if len(s.DropTables) == 0 {
+		return errors.New("at least one drop table is required")
+	}
+	if s.Team.TeamNumber < 0 || s.Team.TeamNumber > 5 {
+		return errors.New("team_number must be between 1 and 5")
+	}
+	for slot, itemName := range s.Team.Equipment {
+		if s.findLootItem(itemName) == nil {
+			return fmt.Errorf("equipped %s %q is not one of the loot items", slot, itemName)
+		}
+	}
+	return nil
+}
+
+func (s *Scenario) findLootItem(name string) *repositories.LootItemEntity {
+	for _, item := range s.LootItems {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories/memory"
+	"github.com/snowlynxsoftware/parallax-game/server/models"
+	"github.com/snowlynxsoftware/parallax-game/server/services"
+)
+
+// simulatedUserId owns the team in every run
+const simulatedUserId int64 = 1
+
+// simulationStart is the store's clock when each run's expedition is launched
+var simulationStart = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Simulator runs expeditions for a scenario through the real ExpeditionService and
+// GameCoreService, backed by in-memory repositories instead of Postgres
+type Simulator struct {
+	scenario      *Scenario
+	randomService services.IRandomService
+}
+
+// NewSimulator creates a simulator whose loot rolls are decided by seed, so the same
+// scenario, seed and number of runs always give the same report
+func NewSimulator(scenario *Scenario, seed int64) *Simulator {
+	return &Simulator{
+		scenario:      scenario,
+		randomService: services.NewSeededRandomService(seed),
+	}
+}
+
+// runResult is what one expedition brought back
+type runResult struct {
+	durationMinutes  int
+	effectivePower   int
+	recommendedPower int
+	partialFailure   bool
+	rarityCounts     map[string]int
+}
+
+// Run simulates runs expeditions back to back and summarizes their loot
+func (s *Simulator) Run(runs int) (*Report, error) {
+	if runs <= 0 {
+		return nil, errors.New("runs must be positive")
+	}
+
+	report := newReport(s.scenario.Rift.Name, runs)
+	for i := 0; i < runs; i++ {
+		result, err := s.runExpedition()
+		if err != nil {
+			return nil, fmt.Errorf("run %d: %w", i+1, err)
+		}
+		report.addRun(result)
+	}
+	report.finish()
+	return report, nil
+}
+
+// world is a freshly seeded in-memory database with the services built on top of it.
+// Each run gets its own world so inventory from earlier runs doesn't slow later ones down.
+type world struct {
+	store                    *memory.Store
+	expeditionRepository     repositories.IExpeditionRepository
+	expeditionLootRepository repositories.IExpeditionLootRepository
+	expeditionService        services.IExpeditionService
+	riftId                   int64
+	teamId                   int64
+	lootRarities             map[int64]string
+}
+
+// runExpedition launches the scenario's team into the rift, moves the clock to the end
+// of the expedition and lets the processor roll its loot
+func (s *Simulator) runExpedition() (*runResult, error) {
+	w, err := s.newWorld()
+	if err != nil {
+		return nil, err
+	}
+
+	w.store.SetClock(func() time.Time { return simulationStart })
+	launched, err := w.expeditionService.StartExpedition(simulatedUserId, w.teamId, w.riftId)
+	if err != nil {
+		return nil, err
+	}
+
+	finish := simulationStart.Add(time.Duration(launched.DurationMinutes) * time.Minute)
+	w.store.SetClock(func() time.Time { return finish })
+	processed, _, err := w.expeditionService.ProcessDueExpeditions(1, nil)
+	if err != nil {
+		return nil, err
+	}
+	if processed != 1 {
+		return nil, fmt.Errorf("expedition %d was not processed", launched.ID)
+	}
+
+	expedition, err := w.expeditionRepository.GetExpeditionById(launched.ID)
+	if err != nil {
+		return nil, err
+	}
+	loot, err := w.expeditionLootRepository.GetLootByExpeditionId(launched.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &runResult{
+		durationMinutes:  launched.DurationMinutes,
+		effectivePower:   launched.EffectivePower,
+		recommendedPower: launched.RecommendedPower,
+		partialFailure:   expedition.PartialFailure,
+		rarityCounts:     make(map[string]int),
+	}
+	for _, item := range loot {
+		result.rarityCounts[w.lootRarities[item.LootItemID]] += item.Quantity
+	}
+	return result, nil
+}
+
+// newWorld seeds the scenario into a new store and wires the services the same way the
+// app server does
+func (s *Simulator) newWorld() (*world, error) {
+	store := memory.NewStore()
+	store.SetClock(func() time.Time { return simulationStart })
+
+	rift := store.AddRift(s.scenario.Rift)
+	for _, dropTable := range s.scenario.DropTables {
+		row := *dropTable
+		row.RiftID = rift.ID
+		store.AddDropTable(&row)
+	}
+	lootRarities := make(map[int64]string)
+	lootItemIds := make(map[string]int64)
+	for _, item := range s.scenario.LootItems {
+		row := store.AddLootItem(item)
+		lootRarities[row.ID] = row.Rarity
+		lootItemIds[row.Name] = row.ID
+	}
+
+	teamRepository := memory.NewTeamRepository(store)
+	inventoryRepository := memory.NewUserInventoryRepository(store)
+	expeditionRepository := memory.NewExpeditionRepository(store)
+	expeditionLootRepository := memory.NewExpeditionLootRepository(store)
+	riftRepository := memory.NewRiftRepository(store)
+	lootItemRepository := memory.NewLootItemRepository(store)
+
+	teamId, err := s.seedTeam(teamRepository, inventoryRepository, lootItemIds)
+	if err != nil {
+		return nil, err
+	}
+
+	unlockRuleService := services.NewUnlockRuleService(memory.NewUnlockRuleRepository(store), expeditionRepository, inventoryRepository, riftRepository)
+	expeditionService := services.NewExpeditionService(
+		expeditionRepository,
+		expeditionLootRepository,
+		teamRepository,
+		riftRepository,
+		inventoryRepository,
+		lootItemRepository,
+		memory.NewLootDropTableRepository(store),
+		memory.NewEchoEncounterRepository(store),
+		services.NewGameCoreService(lootItemRepository),
+		memory.NewUnitOfWork(store),
+		services.NewRiftService(riftRepository, unlockRuleService),
+		s.randomService,
+		services.NewEventHubService(),
+	)
+
+	return &world{
+		store:                    store,
+		expeditionRepository:     expeditionRepository,
+		expeditionLootRepository: expeditionLootRepository,
+		expeditionService:        expeditionService,
+		riftId:                   rift.ID,
+		teamId:                   teamId,
+		lootRarities:             lootRarities,
+	}, nil
+}
+
+// seedTeam creates the simulated user's teams and sets up the scenario's team, returning its id
+func (s *Simulator) seedTeam(
+	teamRepository repositories.ITeamRepository,
+	inventoryRepository repositories.IUserInventoryRepository,
+	lootItemIds map[string]int64,
+) (int64, error) {
+	if err := teamRepository.CreateTeamsForUser(simulatedUserId); err != nil {
+		return 0, err
+	}
+	teams, err := teamRepository.GetTeamsByUserId(simulatedUserId)
+	if err != nil {
+		return 0, err
+	}
+
+	teamNumber := s.scenario.Team.TeamNumber
+	if teamNumber == 0 {
+		teamNumber = 1
+	}
+	team := teams[teamNumber-1]
+
+	if !team.IsUnlocked {
+		if err := teamRepository.UnlockTeam(team.ID); err != nil {
+			return 0, err
+		}
+	}
+	err = teamRepository.UpdateTeamStats(team.ID, s.scenario.Team.SpeedBonus, s.scenario.Team.LuckBonus, s.scenario.Team.PowerBonus)
+	if err != nil {
+		return 0, err
+	}
+	specialization := s.scenario.Team.Specialization
+	if specialization != "" && specialization != string(models.SpecializationNone) {
+		if _, err := teamRepository.SetSpecialization(team.ID, specialization); err != nil {
+			return 0, err
+		}
+	}
+
+	for slot, itemName := range s.scenario.Team.Equipment {
+		// Scenarios built in code skip Validate, so a typo would otherwise equip loot item 0
+		lootItemId, exists := lootItemIds[itemName]
+		if !exists {
+			return 0, fmt.Errorf("equipped %s %q is not one of the loot items", slot, itemName)
+		}
+		inventoryItem, err := inventoryRepository.AddLoot(simulatedUserId, lootItemId, string(models.ItemTypeEquipment))
+		if err != nil {
+			return 0, err
+		}
+		if err := teamRepository.EquipItem(team.ID, slot, &inventoryItem.ID); err != nil {
+			return 0, err
+		}
+	}
+	return team.ID, nil
+}
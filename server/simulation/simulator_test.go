@@ -0,0 +1,139 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/snowlynxsoftware/parallax-game/server/database/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScenario(legendaryRate float64) *Scenario {
+	slot := "weapon"
+	return &Scenario{
+		Rift: &repositories.RiftEntity{
+			Name:            "Test Rift",
+			WorldType:       "fire",
+			DurationMinutes: 30,
+			Difficulty:      "easy",
+			WeakToElement:   "water",
+		},
+		DropTables: []*repositories.LootDropTableEntity{
+			{Rarity: "common", DropRatePercent: 100, MinQuantity: 1, MaxQuantity: 3},
+			{Rarity: "legendary", DropRatePercent: legendaryRate, MinQuantity: 1, MaxQuantity: 1},
+		},
+		LootItems: []*repositories.LootItemEntity{
+			{Name: "Scorched Blade", Rarity: "common", WorldType: "fire", ItemType: "equipment", EquipmentSlot: &slot, PowerBonus: 10},
+			{Name: "Fire Elixir", Rarity: "common", WorldType: "fire", ItemType: "consumable"},
+			{Name: "Inferno Stone", Rarity: "legendary", WorldType: "fire", ItemType: "equipment"},
+		},
+		Team: ScenarioTeam{
+			TeamNumber: 2,
+			Equipment:  map[string]string{"weapon": "Scorched Blade"},
+		},
+	}
+}
+
+func TestSimulator_Run(t *testing.T) {
+	// Arrange
+	scenario := newTestScenario(0)
+
+	// Act
+	report, err := NewSimulator(scenario, 1).Run(200)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Rift", report.RiftName)
+	assert.Equal(t, 30, report.DurationMinutes)
+	assert.Equal(t, 10, report.EffectivePower)
+	assert.Equal(t, 10, report.RecommendedPower)
+	assert.Equal(t, 100.0, report.SimulatedHours)
+
+	common := report.Rarities[0]
+	assert.Equal(t, "common", common.Rarity)
+	assert.Equal(t, 200, common.RunsWithDrop)
+	assert.Equal(t, common.Total, report.TotalItems)
+	assert.Equal(t, float64(common.Total)/100, report.ItemsPerHour)
+	assert.Equal(t, report.ItemsPerHour, report.PowerScorePerHour, "common items are worth 1 point each")
+
+	// Every run lands in exactly one histogram bucket
+	for _, rarityReport := range report.Rarities {
+		runs := 0
+		for _, count := range rarityReport.Histogram {
+			runs += count
+		}
+		assert.Equal(t, 200, runs, rarityReport.Rarity)
+	}
+	assert.Equal(t, 0, common.Histogram[0])
+
+	assert.Nil(t, report.HoursToFirstLegendary)
+	assert.Nil(t, report.ExpectedHoursToLegendary)
+}
+
+func TestSimulator_Run_TimeToLegendary(t *testing.T) {
+	// Arrange
+	scenario := newTestScenario(100)
+
+	// Act
+	report, err := NewSimulator(scenario, 1).Run(10)
+
+	// Assert
+	assert.NoError(t, err)
+	legendary := report.Rarities[4]
+	assert.Equal(t, 10, legendary.Total)
+	assert.Equal(t, []int{0, 10}, legendary.Histogram)
+	assert.Equal(t, 0.5, *report.HoursToFirstLegendary)
+	assert.Equal(t, 0.5, *report.ExpectedHoursToLegendary)
+	assert.Equal(t, float64(report.Rarities[0].Total+10*1000)/5, report.PowerScorePerHour)
+}
+
+func TestSimulator_Run_SameSeedSameReport(t *testing.T) {
+	// Arrange
+	scenario := newTestScenario(5)
+
+	// Act
+	first, err := NewSimulator(scenario, 7).Run(100)
+	assert.NoError(t, err)
+	second, err := NewSimulator(scenario, 7).Run(100)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, first, second)
+}
+
+func TestSimulator_Run_UnknownEquippedItem(t *testing.T) {
+	// Arrange
+	scenario := newTestScenario(0)
+	scenario.Team.Equipment["armor"] = "Missing Plate"
+
+	// Act
+	report, err := NewSimulator(scenario, 1).Run(1)
+
+	// Assert
+	assert.ErrorContains(t, err, `equipped armor "Missing Plate" is not one of the loot items`)
+	assert.Nil(t, report)
+}
+
+func TestScenario_Validate(t *testing.T) {
+	// Test 1 - Valid scenario
+	assert.NoError(t, newTestScenario(1).Validate())
+
+	// Test 2 - Missing rift
+	scenario := newTestScenario(1)
+	scenario.Rift = nil
+	assert.Error(t, scenario.Validate())
+
+	// Test 3 - No drop tables
+	scenario = newTestScenario(1)
+	scenario.DropTables = nil
+	assert.Error(t, scenario.Validate())
+
+	// Test 4 - Equipped item that isn't in the loot items
+	scenario = newTestScenario(1)
+	scenario.Team.Equipment["armor"] = "Missing Plate"
+	assert.Error(t, scenario.Validate())
+
+	// Test 5 - Team number out of range
+	scenario = newTestScenario(1)
+	scenario.Team.TeamNumber = 6
+	assert.Error(t, scenario.Validate())
+}